@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// activityResources maps the collections with an activity timeline to the
+// resource type their events are recorded under
+var activityResources = map[string]string{
+	"organizations":      "organization",
+	"folders":            "folder",
+	"projects":           "project",
+	"instances":          "instance",
+	"instance-templates": "instance_template",
+	"autoscaling-groups": "autoscaling_group",
+	"budgets":            "budget",
+	"secrets":            "secret",
+	"databases":          "database",
+	"topics":             "topic",
+	"subscriptions":      "subscription",
+}
+
+// activityCollections returns a route pattern matching the collections in
+// activityResources
+func activityCollections() string {
+	collections := make([]string, 0, len(activityResources))
+	for collection := range activityResources {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+	return strings.Join(collections, "|")
+}
+
+// ListActivity handles GET /v1/{collection}/{id}/activity, listing the
+// events and logged requests of one resource oldest first. Like the event
+// log it needs full access.
+func (h *Handler) ListActivity(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	opts := domain.ActivityListOptions{
+		ResourceType: activityResources[vars["collection"]],
+		ResourceID:   vars["id"],
+		RequestPath:  "/v1/" + vars["collection"] + "/" + vars["id"],
+	}
+
+	var v domain.FieldViolations
+	opts.Since = parseTimeParam(&v, query.Get("since"), "since")
+	opts.Until = parseTimeParam(&v, query.Get("until"), "until")
+	opts.Limit = int(parseIntParam(&v, query.Get("limit"), "limit"))
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	entries, err := h.service.ListActivity(opts)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeList(w, r, entries, nil)
+}
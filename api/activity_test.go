@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ListActivity(t *testing.T) {
+	svc := newTestService(t)
+	router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{Token: "secret", RequestLogSize: 100}))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "activity"})
+	require.NoError(t, err)
+	w := do("POST", "/v1/instances", `{"project_id":"`+project.ID+`","name":"vm-1","cpu":1,"memory_mb":512,"image":"ubuntu","status":"stopped"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var instance domain.Instance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instance))
+
+	require.Equal(t, http.StatusOK, do("POST", "/v1/instances/"+instance.ID+"/resize", `{"cpu":2}`).Code)
+	require.Equal(t, http.StatusOK, do("GET", "/v1/instances/"+instance.ID, "").Code)
+	require.Equal(t, http.StatusOK, do("GET", "/v1/projects/"+project.ID, "").Code)
+
+	w = do("GET", "/v1/instances/"+instance.ID+"/activity", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var entries []domain.ActivityEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+
+	var summaries []string
+	for i, entry := range entries {
+		if i > 0 {
+			assert.False(t, entry.Time.Before(entries[i-1].Time), "entries are in chronological order")
+		}
+		summaries = append(summaries, entry.Kind+": "+entry.Summary)
+	}
+	// Requests are logged at the time they arrived, before the events they
+	// caused
+	assert.Equal(t, []string{
+		"event: instance vm-1 created stopped (user_request)",
+		"request: POST /v1/instances/" + instance.ID + "/resize returned 200",
+		"event: instance vm-1 resized from 1 CPU/512 MB to 2 CPU/512 MB",
+		"request: GET /v1/instances/" + instance.ID + " returned 200",
+	}, summaries)
+
+	w = do("GET", "/v1/instances/"+instance.ID+"/activity?limit=1", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, domain.ActivityRequest, entries[0].Kind)
+	require.NotNil(t, entries[0].Request)
+	assert.Equal(t, "GET", entries[0].Request.Method)
+
+	w = do("GET", "/v1/projects/"+project.ID+"/activity", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 1, "instance activity is not project activity")
+}
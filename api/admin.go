@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+)
+
+// Admin handlers. These operate on the whole database, so chaos is never
+// applied to them.
+
+// CreateBackup handles POST /v1/admin/backup
+func (h *Handler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	backup, err := h.service.CreateBackup()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, backup)
+}
+
+// ListBackups handles GET /v1/admin/backups
+func (h *Handler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	backups, err := h.service.ListBackups()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, backups)
+}
+
+// RestoreBackup handles POST /v1/admin/restore
+func (h *Handler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.RestoreBackupRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.RestoreBackup(req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetData handles POST /v1/admin/reset
+func (h *Handler) ResetData(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.ResetData(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Assert handles POST /v1/admin/assert. The response reports each assertion;
+// failed assertions do not make the request fail.
+func (h *Handler) Assert(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.AssertRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	resp, err := h.service.Assert(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// CheckIntegrity handles GET /v1/admin/integrity, which reports problems in
+// the stored data without changing anything
+func (h *Handler) CheckIntegrity(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	report, err := h.service.CheckIntegrity(false)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, report)
+}
+
+// RepairIntegrity handles POST /v1/admin/integrity:repair. The report marks
+// the issues that were repaired.
+func (h *Handler) RepairIntegrity(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	report, err := h.service.CheckIntegrity(true)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, report)
+}
+
+// ListQuarantined handles GET /v1/admin/quarantine, which lists the
+// resources taken out of service at startup for breaking validation rules
+func (h *Handler) ListQuarantined(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	quarantined, err := h.service.ListQuarantined()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, quarantined)
+}
+
+// maxTrafficBytes bounds the recorded traffic a seed is generated from,
+// which is typically much larger than an API request
+const maxTrafficBytes = 64 << 20
+
+// GenerateSeed handles POST /v1/admin/seed:fromTraffic. The body is traffic
+// recorded by the request mirror, one request per line.
+func (h *Handler) GenerateSeed(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var traffic []*domain.MirroredRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTrafficBytes))
+	for {
+		var req domain.MirroredRequest
+		err := decoder.Decode(&req)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				h.writeError(w, domain.InvalidInputError("request body too large", map[string]interface{}{
+					"max_bytes": maxErr.Limit,
+				}))
+				return
+			}
+			h.writeError(w, domain.InvalidInputError(fmt.Sprintf("recorded request %d is not valid JSON", len(traffic)+1), nil))
+			return
+		}
+		traffic = append(traffic, &req)
+	}
+
+	h.writeJSON(w, http.StatusOK, service.GenerateSeed(traffic))
+}
+
+// LoadSeed handles POST /v1/admin/seed
+func (h *Handler) LoadSeed(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var seed domain.Seed
+	if err := h.decodeJSON(w, r, &seed); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.LoadSeed(seed); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetData(t *testing.T) {
+	svc := newTestService(t)
+	router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{Token: "secret"}))
+
+	_, err := svc.CreateProject(domain.CreateProjectRequest{Name: "doomed"})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("POST", "/v1/admin/reset", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "reset requires the admin token")
+
+	r = httptest.NewRequest("POST", "/v1/admin/reset", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+	projects, err := svc.ListProjects(domain.ProjectListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestChaosToggle(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	do := func(method, body string) chaos.Status {
+		r := httptest.NewRequest(method, "/v1/chaos", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var status chaos.Status
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		return status
+	}
+
+	assert.False(t, do("GET", "").Enabled)
+	assert.True(t, do("PUT", `{"enabled": true}`).Enabled)
+	assert.True(t, do("GET", "").Enabled)
+	assert.False(t, do("PUT", `{"enabled": false}`).Enabled)
+}
+
+func TestAssert(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "checked"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedPassed bool
+	}{
+		{
+			name:           "passing",
+			body:           `{"assertions":[{"type":"project","where":{"name":"checked"},"equals":{"id":"` + project.ID + `"}}]}`,
+			expectedStatus: http.StatusOK,
+			expectedPassed: true,
+		},
+		{
+			name:           "failing",
+			body:           `{"assertions":[{"type":"instance","where":{"project_id":"` + project.ID + `"},"count":2}]}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown type",
+			body:           `{"assertions":[{"type":"volume"}]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/v1/admin/assert", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			require.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var resp domain.AssertResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expectedPassed, resp.Passed)
+			require.Len(t, resp.Results, 1)
+			assert.Equal(t, tt.expectedPassed, len(resp.Results[0].Failures) == 0)
+		})
+	}
+}
+
+func TestSeedFromTraffic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.ndjson")
+	mirror, err := NewMirror(MirrorConfig{File: path})
+	require.NoError(t, err)
+
+	recording := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{Mirror: mirror}))
+	send := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := send(recording, "POST", "/v1/projects", `{"name":"recorded"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var project domain.Project
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &project))
+
+	w = send(recording, "POST", "/v1/instances", `{"project_id":"`+project.ID+`","name":"vm","cpu":1,"memory_mb":512,"image":"ubuntu"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = send(recording, "PATCH", "/v1/projects/"+project.ID, `{"labels":{"env":"final"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, mirror.Close())
+
+	traffic, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	replay := SetupRouter(newTestHandler(t))
+	w = send(replay, "POST", "/v1/admin/seed:fromTraffic", string(traffic))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var seed domain.Seed
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &seed))
+	require.Len(t, seed.Resources, 2)
+	assert.Equal(t, "project", seed.Resources[0].Type)
+	assert.Equal(t, "instance", seed.Resources[1].Type)
+
+	w = send(replay, "POST", "/v1/admin/seed", w.Body.String())
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+	w = send(replay, "GET", "/v1/projects/"+project.ID, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"env":"final"`)
+
+	w = send(replay, "POST", "/v1/admin/seed:fromTraffic", "{not json")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestIntegrity(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	_, err := h.service.CreateMetadata(domain.CreateMetadataRequest{Path: "/app/config", Value: "v"})
+	require.NoError(t, err)
+
+	check := func(method, path string) domain.IntegrityReport {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var report domain.IntegrityReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return report
+	}
+
+	report := check("GET", "/v1/admin/integrity")
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, domain.IntegrityMetadataPath, report.Issues[0].Check)
+	assert.False(t, report.Issues[0].Repaired, "checking changes nothing")
+
+	report = check("POST", "/v1/admin/integrity:repair")
+	assert.Equal(t, 1, report.Repaired)
+
+	report = check("GET", "/v1/admin/integrity")
+	assert.Empty(t, report.Issues)
+}
+
+func TestAdminToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		method   string
+		path     string
+		expected int
+	}{
+		{"admin token on admin route", "admin", "GET", "/v1/admin/requests", http.StatusOK},
+		{"admin token on chaos route", "admin", "GET", "/v1/chaos", http.StatusOK},
+		{"admin token on API route", "admin", "GET", "/v1/projects", http.StatusOK},
+		{"API token on admin route", "api", "POST", "/v1/admin/reset", http.StatusForbidden},
+		{"API token on chaos route", "api", "PUT", "/v1/chaos", http.StatusForbidden},
+		{"API token on integrity repair", "api", "POST", "/v1/admin/integrity:repair", http.StatusForbidden},
+		{"API token on API route", "api", "GET", "/v1/projects", http.StatusOK},
+		{"no token on admin route", "", "GET", "/v1/admin/backups", http.StatusUnauthorized},
+		{"unknown token on admin route", "guess", "GET", "/v1/admin/backups", http.StatusUnauthorized},
+	}
+
+	router := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{Token: "api", AdminToken: "admin"}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"enabled":false}`))
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			assert.Equal(t, tt.expected, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("open API", func(t *testing.T) {
+		router := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{AdminToken: "admin"}))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/projects", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/admin/reset", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Alias handlers. Aliases can refer to resources in any project, so project
+// members cannot use them.
+
+// CreateAlias handles POST /v1/aliases
+func (h *Handler) CreateAlias(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateAliasRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	alias, err := h.service.CreateAlias(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, alias)
+}
+
+// ListAliases handles GET /v1/aliases
+func (h *Handler) ListAliases(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	aliases, err := h.service.ListAliases(domain.AliasListOptions{
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, aliases)
+}
+
+// DeleteAlias handles DELETE /v1/aliases/{external_id}. External IDs may
+// contain slashes.
+func (h *Handler) DeleteAlias(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteAlias(mux.Vars(r)["external_id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResolveAlias handles GET /v1/resolve?external_id=..., which returns the
+// resource an external ID refers to
+func (h *Handler) ResolveAlias(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	alias, err := h.service.ResolveAlias(r.URL.Query().Get("external_id"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, alias)
+}
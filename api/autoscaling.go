@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Autoscaling group handlers
+
+// CreateAutoscalingGroup handles POST /v1/autoscaling-groups
+func (h *Handler) CreateAutoscalingGroup(w http.ResponseWriter, r *http.Request) {
+	member, err := h.principal(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateAutoscalingGroupRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.requireRole(r, member, req.ProjectID, domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.projectChaos(r, req.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	group, err := h.service.CreateAutoscalingGroup(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, group.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var defaults defaultsApplied
+	if req.DesiredSize == nil {
+		defaults.add("desired_size", group.DesiredSize)
+	}
+	defaults.setHeader(w)
+
+	h.writeJSON(w, http.StatusCreated, group)
+}
+
+// GetAutoscalingGroup handles GET /v1/autoscaling-groups/{id}
+func (h *Handler) GetAutoscalingGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAutoscalingGroup(r, mux.Vars(r)["id"], domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(resourceChaos(r), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	group, err := h.service.GetAutoscalingGroup(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, group, group.UpdatedAt)
+}
+
+// ListAutoscalingGroups handles GET /v1/autoscaling-groups
+func (h *Handler) ListAutoscalingGroups(w http.ResponseWriter, r *http.Request) {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if err := h.chaosService.ApplyInstancesChaos(h.projectChaos(r, query.Get("project_id")), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	groups, err := h.service.ListAutoscalingGroups(domain.AutoscalingGroupListOptions{
+		ProjectID: query.Get("project_id"),
+		Name:      query.Get("name"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if visible != nil {
+		var allowed []*domain.AutoscalingGroup
+		for _, group := range groups {
+			ok, err := visible(group.ProjectID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, group)
+			}
+		}
+		groups = allowed
+	}
+
+	h.writeJSON(w, http.StatusOK, groups)
+}
+
+// UpdateAutoscalingGroup handles PATCH /v1/autoscaling-groups/{id}
+func (h *Handler) UpdateAutoscalingGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAutoscalingGroup(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(resourceChaos(r), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateAutoscalingGroupRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	group, err := h.service.UpdateAutoscalingGroup(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, group)
+}
+
+// DeleteAutoscalingGroup handles DELETE /v1/autoscaling-groups/{id}
+func (h *Handler) DeleteAutoscalingGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAutoscalingGroup(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(resourceChaos(r), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.WithActor(h.actor(r)).DeleteAutoscalingGroup(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
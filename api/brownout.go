@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// brownout delays requests by the chaos brownout curve for the number of
+// requests in flight. WebSocket upgrades are not counted since they stay
+// open for the life of the connection.
+func (h *Handler) brownout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) || controlRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		release := h.chaosService.Brownout(r.Context(), r)
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
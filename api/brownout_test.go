@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrownout(t *testing.T) {
+	t.Setenv("DIRT_CHAOS_ENABLED", "true")
+	t.Setenv("DIRT_CHAOS_BROWNOUT_CURVE", chaos.BrownoutLinear)
+	t.Setenv("DIRT_CHAOS_BROWNOUT_THRESHOLD", "0")
+	t.Setenv("DIRT_CHAOS_BROWNOUT_STEP_MS", "100")
+
+	router := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{}))
+
+	serve := func(path string) time.Duration {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		return time.Since(start)
+	}
+
+	assert.GreaterOrEqual(t, serve("/v1/projects"), 100*time.Millisecond)
+	assert.Less(t, serve("/v1/chaos"), 100*time.Millisecond, "chaos routes are not slowed")
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Budget handlers
+
+// CreateBudget handles POST /v1/budgets
+func (h *Handler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	member, err := h.principal(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateBudgetRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.requireRole(r, member, req.ProjectID, domain.RoleAdmin); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, req.ProjectID), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	budget, err := h.service.CreateBudget(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, budget.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var defaults defaultsApplied
+	if req.Thresholds == nil {
+		defaults.add("thresholds", budget.Thresholds)
+	}
+	defaults.setHeader(w)
+
+	h.writeJSON(w, http.StatusCreated, budget)
+}
+
+// GetBudget handles GET /v1/budgets/{id}
+func (h *Handler) GetBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeBudget(r, mux.Vars(r)["id"], domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	budget, err := h.service.GetBudget(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, budget, budget.UpdatedAt)
+}
+
+// ListBudgets handles GET /v1/budgets
+func (h *Handler) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, query.Get("project_id")), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	budgets, err := h.service.ListBudgets(domain.BudgetListOptions{
+		ProjectID: query.Get("project_id"),
+		Status:    query.Get("status"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if visible != nil {
+		var allowed []*domain.Budget
+		for _, budget := range budgets {
+			ok, err := visible(budget.ProjectID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, budget)
+			}
+		}
+		budgets = allowed
+	}
+
+	h.writeJSON(w, http.StatusOK, budgets)
+}
+
+// UpdateBudget handles PATCH /v1/budgets/{id}
+func (h *Handler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeBudget(r, mux.Vars(r)["id"], domain.RoleAdmin); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateBudgetRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	budget, err := h.service.UpdateBudget(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, budget)
+}
+
+// DeleteBudget handles DELETE /v1/budgets/{id}
+func (h *Handler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeBudget(r, mux.Vars(r)["id"], domain.RoleAdmin); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteBudget(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
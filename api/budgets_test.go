@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetRoutes(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "budgets"})
+	require.NoError(t, err)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("POST", "/v1/budgets", `{"project_id": "`+project.ID+`", "name": "monthly", "amount": 25}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "thresholds=[0.5 0.9 1]", w.Header().Get(DefaultsAppliedHeader))
+
+	var budget domain.Budget
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &budget))
+	assert.Equal(t, domain.BudgetOK, budget.Status)
+
+	w = do("PATCH", "/v1/budgets/"+budget.ID, `{"amount": 50, "thresholds": [1, 0.8]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &budget))
+	assert.Equal(t, 50.0, budget.Amount)
+	assert.Equal(t, []float64{0.8, 1}, budget.Thresholds)
+
+	w = do("GET", "/v1/budgets?project_id="+project.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var budgets []*domain.Budget
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &budgets))
+	require.Len(t, budgets, 1)
+
+	assert.Equal(t, http.StatusBadRequest, do("PATCH", "/v1/budgets/"+budget.ID, `{"amount": -1}`).Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/v1/budgets/"+budget.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/budgets/"+budget.ID, "").Code)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// Server-wide chaos handlers
+
+// GetChaos handles GET /v1/chaos
+func (h *Handler) GetChaos(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.Status())
+}
+
+// PutChaos handles PUT /v1/chaos
+func (h *Handler) PutChaos(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var status chaos.Status
+	if err := h.decodeJSON(w, r, &status); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.chaosService.SetEnabled(status.Enabled)
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.Status())
+}
+
+// replayCreate runs create a second time when chaos replays a create request.
+// Where names may repeat the replay leaves a duplicate with the same name and
+// a new ID. Where they are unique it fails, and its error is returned for the
+// client to see in place of the first create's response, as it would behind
+// a retrying proxy; the first create is kept. Only instance creates are
+// replayed.
+func (h *Handler) replayCreate(ctx context.Context, r *http.Request, create func() error) error {
+	if !h.chaosService.ReplayCreate(ctx, r) {
+		return nil
+	}
+	return create()
+}
+
+// controlRoute reports whether a request is for the chaos or admin routes.
+// These are exempt from failures simulated for the server as a whole, such as
+// dropped connections or maintenance windows, so that those can always be
+// inspected and switched off.
+func controlRoute(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/v1/chaos") || strings.HasPrefix(r.URL.Path, "/v1/admin")
+}
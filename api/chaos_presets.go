@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Chaos preset handlers
+
+// ListChaosPresets handles GET /v1/chaos/presets
+func (h *Handler) ListChaosPresets(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.ListPresets())
+}
+
+// GetChaosPreset handles GET /v1/chaos/presets/{name}
+func (h *Handler) GetChaosPreset(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	preset, err := h.chaosService.GetPreset(mux.Vars(r)["name"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, preset)
+}
+
+// ActivateChaosPreset handles POST /v1/chaos/presets/{name}:activate
+func (h *Handler) ActivateChaosPreset(w http.ResponseWriter, r *http.Request) {
+	h.setChaosPresetActive(w, r, true)
+}
+
+// DeactivateChaosPreset handles POST /v1/chaos/presets/{name}:deactivate
+func (h *Handler) DeactivateChaosPreset(w http.ResponseWriter, r *http.Request) {
+	h.setChaosPresetActive(w, r, false)
+}
+
+// setChaosPresetActive switches the preset named in the path on or off
+func (h *Handler) setChaosPresetActive(w http.ResponseWriter, r *http.Request, active bool) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	preset, err := h.chaosService.SetPresetActive(mux.Vars(r)["name"], active)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, preset)
+}
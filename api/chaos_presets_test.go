@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosPresets(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do("GET", "/v1/chaos/presets")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"slow-control-plane"`)
+
+	w = do("POST", "/v1/chaos/presets/rate-limited:activate")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"active":true`)
+
+	// Some requests are now throttled
+	throttled := 0
+	for i := 0; i < 30; i++ {
+		if code := do("GET", "/v1/projects").Code; code == http.StatusTooManyRequests {
+			throttled++
+		} else {
+			assert.Equal(t, http.StatusOK, code)
+		}
+	}
+	assert.Greater(t, throttled, 0)
+
+	w = do("POST", "/v1/chaos/presets/rate-limited:deactivate")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":false`)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, do("GET", "/v1/projects").Code)
+	}
+
+	assert.Equal(t, http.StatusNotFound, do("POST", "/v1/chaos/presets/missing:activate").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/chaos/presets/missing").Code)
+}
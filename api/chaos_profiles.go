@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// projectChaos returns a context carrying the chaos profile of the given
+// project. Lookup failures are ignored; the handler reports them itself.
+func (h *Handler) projectChaos(r *http.Request, projectID string) context.Context {
+	if projectID == "" {
+		return r.Context()
+	}
+
+	project, err := h.service.GetProject(projectID)
+	if err != nil || project.ChaosProfile == "" {
+		return r.Context()
+	}
+
+	return chaos.WithProfile(r.Context(), project.ChaosProfile)
+}
+
+// projectResourceChaos returns the context of a request addressing the given
+// project: it carries the project's chaos profile and marks the project as
+// the addressed resource, so a chaos target for it applies
+func (h *Handler) projectResourceChaos(r *http.Request, projectID string) context.Context {
+	return chaos.WithResource(h.projectChaos(r, projectID), projectID)
+}
+
+// instanceChaos returns the context of a request addressing the given
+// instance: it carries the chaos profile of the project that owns the
+// instance and marks the instance as the addressed resource
+func (h *Handler) instanceChaos(r *http.Request, instanceID string) context.Context {
+	ctx := chaos.WithResource(r.Context(), instanceID)
+
+	instance, err := h.service.GetInstance(instanceID)
+	if err != nil {
+		return ctx
+	}
+
+	return chaos.WithResource(h.projectChaos(r, instance.ProjectID), instanceID)
+}
+
+// resourceChaos returns the context of a request addressing the resource in
+// its {id} path variable, marking it so a chaos target for it applies
+func resourceChaos(r *http.Request) context.Context {
+	return chaos.WithResource(r.Context(), mux.Vars(r)["id"])
+}
+
+// checkChaosProfile verifies that a profile referenced by a project exists
+func (h *Handler) checkChaosProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	if _, err := h.chaosService.GetProfile(name); err != nil {
+		return domain.ValidationError([]domain.FieldViolation{
+			{Field: "chaos_profile", Message: "references an unknown chaos profile"},
+		})
+	}
+
+	return nil
+}
+
+// Chaos profile handlers
+
+// ListChaosProfiles handles GET /v1/chaos/profiles
+func (h *Handler) ListChaosProfiles(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.ListProfiles())
+}
+
+// GetChaosProfile handles GET /v1/chaos/profiles/{name}
+func (h *Handler) GetChaosProfile(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	profile, err := h.chaosService.GetProfile(mux.Vars(r)["name"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, profile)
+}
+
+// PutChaosProfile handles PUT /v1/chaos/profiles/{name}
+func (h *Handler) PutChaosProfile(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var profile chaos.Profile
+	if err := h.decodeJSON(w, r, &profile); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// The path is authoritative for the profile name
+	profile.Name = mux.Vars(r)["name"]
+
+	if err := h.chaosService.SetProfile(&profile); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, &profile)
+}
+
+// DeleteChaosProfile handles DELETE /v1/chaos/profiles/{name}
+func (h *Handler) DeleteChaosProfile(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.DeleteProfile(mux.Vars(r)["name"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// Chaos target handlers
+
+// ListChaosTargets handles GET /v1/chaos/targets
+func (h *Handler) ListChaosTargets(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.ListTargets())
+}
+
+// GetChaosTarget handles GET /v1/chaos/targets/{resource_id}
+func (h *Handler) GetChaosTarget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	target, err := h.chaosService.GetTarget(mux.Vars(r)["resource_id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, target)
+}
+
+// PutChaosTarget handles PUT /v1/chaos/targets/{resource_id}
+func (h *Handler) PutChaosTarget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var target chaos.Target
+	if err := h.decodeJSON(w, r, &target); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// The path is authoritative for the resource ID
+	target.ResourceID = mux.Vars(r)["resource_id"]
+
+	if err := h.chaosService.SetTarget(&target); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, &target)
+}
+
+// DeleteChaosTarget handles DELETE /v1/chaos/targets/{resource_id}
+func (h *Handler) DeleteChaosTarget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.DeleteTarget(mux.Vars(r)["resource_id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosTargets(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	var instances []*domain.Instance
+	for _, name := range []string{"vm-1", "vm-2"} {
+		instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu",
+		})
+		require.NoError(t, err)
+		instances = append(instances, instance)
+	}
+	broken := instances[0]
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("PUT", "/v1/chaos/targets/"+broken.ID, `{"methods":["GET"],"error_rate":1,"error_types":[500]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"resource_id":"`+broken.ID+`","methods":["GET"],"error_rate":1,"error_types":[500]}`, w.Body.String())
+
+	// Only GETs of the targeted instance fail
+	assert.Equal(t, http.StatusInternalServerError, do("GET", "/v1/instances/"+broken.ID, "").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/instances/"+instances[1].ID, "").Code)
+	assert.Equal(t, http.StatusOK, do("PATCH", "/v1/instances/"+broken.ID, `{"labels":{"env":"dev"}}`).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/projects/"+project.ID, "").Code)
+
+	w = do("GET", "/v1/chaos/targets", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), broken.ID)
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/v1/chaos/targets/"+broken.ID, `{"error_rate":3}`).Code)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/v1/chaos/targets/"+broken.ID, "").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/instances/"+broken.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/chaos/targets/"+broken.ID, "").Code)
+}
+
+func TestChaosTargets_Window(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// A target scheduled for later does not apply yet
+	startsAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	w := do("PUT", "/v1/chaos/targets/"+project.ID, `{"error_rate":1,"starts_at":"`+startsAt+`","schedule":"0 * * * *","duration":"15m"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"schedule":"0 * * * *"`)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/projects/"+project.ID, "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/v1/chaos/targets/"+project.ID, `{"error_rate":1,"schedule":"0 * * *","duration":"15m"}`).Code)
+
+	// One that has ended is cleaned up
+	endsAt := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	require.Equal(t, http.StatusOK, do("PUT", "/v1/chaos/targets/"+project.ID, `{"error_rate":1,"ends_at":"`+endsAt+`"}`).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/projects/"+project.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/chaos/targets/"+project.ID, "").Code)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// writeResource writes a resource fetched by a GET with an ETag and a
+// Last-Modified header. When the request's If-None-Match or If-Modified-Since
+// precondition shows the client already has this version it gets an empty
+// 304 Not Modified instead.
+func (h *Handler) writeResource(w http.ResponseWriter, r *http.Request, resource interface{}, updatedAt time.Time) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	etag := resourceETag(data)
+	w.Header().Set("ETag", etag)
+	if !updatedAt.IsZero() {
+		w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, updatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(data, '\n'))
+}
+
+// resourceETag derives a strong entity tag from a resource's JSON encoding
+func resourceETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// notModified evaluates a GET's cache preconditions. If-None-Match takes
+// precedence, and If-Modified-Since is only consulted without it.
+func notModified(r *http.Request, etag string, updatedAt time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return etagMatches(header, etag)
+	}
+
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !updatedAt.IsZero() {
+		since, err := http.ParseTime(header)
+		if err != nil {
+			return false
+		}
+		// Last-Modified only has second precision
+		return !updatedAt.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalGet(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/v1/projects/"+project.ID, nil)
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	first := get(nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	lastModified := first.Header().Get("Last-Modified")
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, lastModified)
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	past := project.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected int
+	}{
+		{name: "matching etag", headers: map[string]string{"If-None-Match": etag}, expected: http.StatusNotModified},
+		{name: "weak etag in a list", headers: map[string]string{"If-None-Match": `"other", W/` + etag}, expected: http.StatusNotModified},
+		{name: "wildcard", headers: map[string]string{"If-None-Match": "*"}, expected: http.StatusNotModified},
+		{name: "stale etag", headers: map[string]string{"If-None-Match": `"other"`}, expected: http.StatusOK},
+		{name: "not modified since", headers: map[string]string{"If-Modified-Since": lastModified}, expected: http.StatusNotModified},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": past}, expected: http.StatusOK},
+		{name: "unparseable date", headers: map[string]string{"If-Modified-Since": "yesterday"}, expected: http.StatusOK},
+		{
+			name:     "etag takes precedence",
+			headers:  map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": future},
+			expected: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.headers)
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+			if tt.expected == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+
+	// An update changes the etag
+	r := httptest.NewRequest("PATCH", "/v1/projects/"+project.ID, strings.NewReader(`{"labels":{"env":"prod"}}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = get(map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// dropConnections drops the connection of requests chaos picks for a
+// connection fault, without handling them or sending any response
+func (h *Handler) dropConnections(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if controlRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fault, err := h.chaosService.ConnectionFault(r)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		if fault == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			// HTTP/2 connections cannot be taken over; let the request through
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			log.Printf("chaos: failed to hijack connection: %v", err)
+			return
+		}
+		if err := chaos.DropConnection(conn, fault); err != nil {
+			log.Printf("chaos: failed to drop connection: %v", err)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionFaults(t *testing.T) {
+	h := newTestHandler(t)
+	server := httptest.NewServer(SetupRouter(h))
+	defer server.Close()
+
+	// A fresh connection per request keeps the transport from retrying
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	for _, fault := range []string{chaos.ConnectionReset, chaos.ConnectionClose} {
+		t.Run(fault, func(t *testing.T) {
+			req, err := http.NewRequest("POST", server.URL+"/v1/projects", strings.NewReader(`{"name":"`+fault+`"}`))
+			require.NoError(t, err)
+			req.Header.Set(chaos.ConnectionFaultHeader, fault)
+
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			require.Error(t, err)
+
+			// The request was dropped before it was handled
+			_, err = h.service.ResolveProjectID(fault)
+			assert.True(t, domain.IsNotFound(err))
+		})
+	}
+
+	// Chaos routes are exempt
+	req, err := http.NewRequest("GET", server.URL+"/v1/chaos", nil)
+	require.NoError(t, err)
+	req.Header.Set(chaos.ConnectionFaultHeader, chaos.ConnectionReset)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+const (
+	// defaultConsolePollInterval is how often an attached console checks for
+	// instance state transitions
+	defaultConsolePollInterval = 500 * time.Millisecond
+
+	// wsPingInterval keeps idle WebSocket connections alive
+	wsPingInterval = 30 * time.Second
+
+	wsWriteTimeout = 10 * time.Second
+)
+
+// wsUpgrader returns an upgrader for WebSocket requests. Browsers do not
+// apply CORS to WebSockets, so the upgrader checks the Origin against the
+// CORS policy in effect itself. Requests without an Origin do not come from
+// a browser page and are accepted.
+func (h *Handler) wsUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || allowedOrigin(h.cors.get(), origin) != ""
+		},
+	}
+}
+
+// InstanceConsole handles GET /v1/instances/{id}/console. The connection is
+// upgraded to a WebSocket that streams fake serial console output, one line
+// per text message, as the instance boots, stops, and is deleted.
+func (h *Handler) InstanceConsole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeInstance(r, id, domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.instanceChaos(r, id), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	instance, err := h.service.GetInstance(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	conn, err := h.wsUpgrader().Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has already replied to the client
+	}
+	defer conn.Close()
+
+	h.streamConsole(conn, instance)
+}
+
+// streamConsole writes console output for the instance until the client
+// disconnects or the instance is deleted
+func (h *Handler) streamConsole(conn *websocket.Conn, instance *domain.Instance) {
+	// Reading is required to process control frames and notice disconnects
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(lines []string) error {
+		for _, line := range lines {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(line)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := write(consoleAttachLog(instance)); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(h.consolePollInterval)
+	defer poll.Stop()
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case <-poll.C:
+			current, err := h.service.GetInstance(instance.ID)
+			if domain.IsNotFound(err) {
+				write([]string{fmt.Sprintf("[console] instance %s deleted", instance.Name)})
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "instance deleted"),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if err != nil {
+				continue // Transient lookup failures do not end the session
+			}
+			if current.Status != instance.Status {
+				if err := write(consoleTransitionLog(instance.Status, current)); err != nil {
+					return
+				}
+			}
+			instance = current
+		}
+	}
+}
+
+// consoleAttachLog returns the output shown when a client attaches
+func consoleAttachLog(instance *domain.Instance) []string {
+	if instance.Status == domain.StatusRunning {
+		return consoleBootLog(instance)
+	}
+	return []string{fmt.Sprintf("[console] instance %s is %s", instance.Name, instance.Status)}
+}
+
+// consoleTransitionLog returns the output for a status change
+func consoleTransitionLog(from string, instance *domain.Instance) []string {
+	switch {
+	case instance.Status == domain.StatusRunning:
+		return consoleBootLog(instance)
+	case from == domain.StatusRunning && instance.Status == domain.StatusStopped:
+		return consoleShutdownLog(instance)
+	default:
+		return []string{fmt.Sprintf("[console] instance %s is now %s", instance.Name, instance.Status)}
+	}
+}
+
+// consoleBootLog returns fake kernel and init output for a booting instance
+func consoleBootLog(instance *domain.Instance) []string {
+	return []string{
+		"[    0.000000] Linux version 6.1.0-dirtcloud (builder@dirtcloud) #1 SMP PREEMPT_DYNAMIC",
+		fmt.Sprintf("[    0.000000] Command line: BOOT_IMAGE=/boot/vmlinuz image=%s root=/dev/vda1 console=ttyS0", instance.Image),
+		fmt.Sprintf("[    0.004211] Memory: %dK available", instance.MemoryMB*1024),
+		fmt.Sprintf("[    0.012877] smpboot: Allowing %d CPUs, 0 hotplug CPUs", instance.CPU),
+		"[    0.183402] virtio_blk virtio1: [vda] 20971520 512-byte logical blocks",
+		"[    0.421950] EXT4-fs (vda1): mounted filesystem with ordered data mode",
+		"[    1.020318] systemd[1]: Detected virtualization dirtcloud.",
+		fmt.Sprintf("[    1.100562] systemd[1]: Set hostname to <%s>.", instance.Name),
+		"[  OK  ] Started Journal Service.",
+		"[  OK  ] Reached target Network.",
+		"[  OK  ] Reached target Multi-User System.",
+		"",
+		fmt.Sprintf("%s %s ttyS0", instance.Image, instance.Name),
+		"",
+		fmt.Sprintf("%s login: ", instance.Name),
+	}
+}
+
+// consoleShutdownLog returns fake init output for a stopping instance
+func consoleShutdownLog(instance *domain.Instance) []string {
+	return []string{
+		"[  OK  ] Stopped target Multi-User System.",
+		"[  OK  ] Stopped target Network.",
+		"         Stopping Journal Service...",
+		"[  OK  ] Stopped Journal Service.",
+		"[  OK  ] Reached target Power-Off.",
+		fmt.Sprintf("[   %d.000000] reboot: Power down", 2+instance.CPU),
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readUntil reads console lines until one contains substr
+func readUntil(t *testing.T, conn *websocket.Conn, substr string) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		require.NoError(t, err, "waiting for %q after %v", substr, lines)
+		lines = append(lines, string(msg))
+		if strings.Contains(string(msg), substr) {
+			return lines
+		}
+	}
+}
+
+func TestHandler_InstanceConsole(t *testing.T) {
+	h := newTestHandler(t)
+	h.consolePollInterval = 10 * time.Millisecond
+	server := httptest.NewServer(SetupRouter(h))
+	defer server.Close()
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "console"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 2, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/instances/" + instance.ID + "/console"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	lines := readUntil(t, conn, "web login:")
+	assert.Contains(t, lines, "[    0.004211] Memory: 524288K available")
+	assert.Contains(t, lines, "[    0.012877] smpboot: Allowing 2 CPUs, 0 hotplug CPUs")
+
+	stopped := domain.StatusStopped
+	_, err = h.service.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Status: &stopped})
+	require.NoError(t, err)
+	readUntil(t, conn, "reboot: Power down")
+
+	require.NoError(t, h.service.DeleteInstance(instance.ID))
+	readUntil(t, conn, "[console] instance web deleted")
+
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+}
+
+func TestHandler_InstanceConsole_NotFound(t *testing.T) {
+	h := newTestHandler(t)
+	server := httptest.NewServer(SetupRouter(h))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/instances/missing/console"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
+func TestHandler_InstanceConsole_Origin(t *testing.T) {
+	h := NewHandler(newTestService(t), chaos.NewChaosService(), Config{
+		CORS: &domain.CORSPolicy{AllowedOrigins: []string{"https://console.example.com"}},
+	})
+	server := httptest.NewServer(SetupRouter(h))
+	defer server.Close()
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "console"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/instances/" + instance.ID + "/console"
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "allowed origin", origin: "https://console.example.com", allowed: true},
+		{name: "no origin", allowed: true},
+		{name: "other origin", origin: "https://evil.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+			if !tt.allowed {
+				require.Error(t, err)
+				assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+				return
+			}
+			require.NoError(t, err)
+			conn.Close()
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// The Consul KV facade serves /v1/kv/{key} the way a Consul agent does, over
+// the metadata store, so that code written against Consul KV can be tested
+// against DirtCloud unchanged. Entry indexes are derived from the entry's
+// timestamps. Sessions, and so locks, are not supported, and neither are
+// blocking queries: an index parameter is ignored and reads return at once.
+
+// consulEntry is an entry as the Consul KV API returns it
+type consulEntry struct {
+	LockIndex   uint64
+	Key         string
+	Flags       uint64
+	Value       *string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+func newConsulEntry(m *domain.Metadata) consulEntry {
+	entry := consulEntry{
+		Key:         m.Path,
+		Flags:       m.Flags,
+		CreateIndex: m.CreateIndex(),
+		ModifyIndex: m.ModifyIndex(),
+	}
+	// Consul stores an empty value as nil
+	if m.Value != "" {
+		value := base64.StdEncoding.EncodeToString([]byte(m.Value))
+		entry.Value = &value
+	}
+	return entry
+}
+
+// consulAuth accepts a Consul ACL token, from the X-Consul-Token header or
+// the token query parameter, as the request's bearer token
+func consulAuth(r *http.Request) {
+	if r.Header.Get("Authorization") != "" {
+		return
+	}
+	token := r.Header.Get("X-Consul-Token")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// setConsulHeaders sets the query metadata Consul clients read from every
+// response. This server is its own leader and never lags behind one.
+func setConsulHeaders(w http.ResponseWriter, index uint64) {
+	if index == 0 {
+		index = 1
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+	w.Header().Set("X-Consul-KnownLeader", "true")
+	w.Header().Set("X-Consul-LastContact", "0")
+}
+
+// hasFlag reports whether a Consul query flag such as ?recurse is present.
+// Consul flags take no value.
+func hasFlag(r *http.Request, name string) bool {
+	_, ok := r.URL.Query()[name]
+	return ok
+}
+
+// parseUintParam parses an optional unsigned integer query parameter, which
+// is nil when absent
+func parseUintParam(v *domain.FieldViolations, value, field string) *uint64 {
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		v.Add(field, "must be a non-negative integer")
+		return nil
+	}
+	return &n
+}
+
+// consulPreamble authenticates a KV request, applies metadata chaos and
+// rejects session parameters, returning the key
+func (h *Handler) consulPreamble(r *http.Request) (string, error) {
+	consulAuth(r)
+	if err := h.authenticate(r); err != nil {
+		return "", err
+	}
+
+	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+		return "", err
+	}
+
+	if hasFlag(r, "acquire") || hasFlag(r, "release") {
+		return "", domain.InvalidInputError("sessions are not supported", nil)
+	}
+	return mux.Vars(r)["key"], nil
+}
+
+// GetKV handles GET /v1/kv/{key}. It returns the entry at key, every entry
+// under it with ?recurse, or the keys under it with ?keys, which ?separator
+// rolls up as Consul does. ?raw returns the bare value of a single entry.
+func (h *Handler) GetKV(w http.ResponseWriter, r *http.Request) {
+	key, err := h.consulPreamble(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	recurse, keysOnly := hasFlag(r, "recurse"), hasFlag(r, "keys")
+	if key == "" && !recurse && !keysOnly {
+		h.writeError(w, domain.InvalidInputError("missing key name", nil))
+		return
+	}
+
+	var entries []*domain.Metadata
+	if recurse || keysOnly {
+		entries, err = h.service.ListMetadata(domain.MetadataListOptions{Prefix: key})
+	} else {
+		var m *domain.Metadata
+		if m, err = h.service.GetMetadataByPath(key); err == nil {
+			entries = []*domain.Metadata{m}
+		} else if domain.IsNotFound(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// The index of a read is that of its newest entry, which needs every
+	// entry read before the headers go out
+	var index uint64
+	for _, m := range entries {
+		if m.ModifyIndex() > index {
+			index = m.ModifyIndex()
+		}
+	}
+	setConsulHeaders(w, index)
+
+	// Consul answers a read that matched nothing with an empty 404
+	if len(entries) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case keysOnly:
+		h.writeJSON(w, http.StatusOK, consulKeys(entries, key, r.URL.Query().Get("separator")))
+	case hasFlag(r, "raw") && !recurse:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(entries[0].Value))
+	default:
+		out := make([]consulEntry, 0, len(entries))
+		for _, m := range entries {
+			out = append(out, newConsulEntry(m))
+		}
+		h.writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// consulKeys lists the paths of entries, which all start with prefix. With a
+// separator, paths that continue past one after the prefix are cut just
+// after it, listing each such folder once.
+func consulKeys(entries []*domain.Metadata, prefix, separator string) []string {
+	seen := make(map[string]bool, len(entries))
+	keys := make([]string, 0, len(entries))
+	for _, m := range entries {
+		key := m.Path
+		if separator != "" {
+			if i := strings.Index(key[len(prefix):], separator); i >= 0 {
+				key = key[:len(prefix)+i+len(separator)]
+			}
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PutKV handles PUT /v1/kv/{key}, storing the request body as the value at
+// key. ?flags sets the entry's flags. With ?cas the write only happens if the
+// entry's ModifyIndex matches, or for 0 if there is no entry. The response
+// is true or false, as to whether the write happened.
+func (h *Handler) PutKV(w http.ResponseWriter, r *http.Request) {
+	key, err := h.consulPreamble(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if key == "" {
+		h.writeError(w, domain.InvalidInputError("missing key name", nil))
+		return
+	}
+
+	query := r.URL.Query()
+	var v domain.FieldViolations
+	flags := parseUintParam(&v, query.Get("flags"), "flags")
+	cas := parseUintParam(&v, query.Get("cas"), "cas")
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	body, err := h.readBody(w, r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if !utf8.Valid(body) {
+		h.writeError(w, domain.InvalidInputError("value must be UTF-8 text", nil))
+		return
+	}
+
+	req := domain.CreateMetadataRequest{Path: key, Value: string(body)}
+	if flags != nil {
+		req.Flags = *flags
+	}
+	ok, err := h.service.PutMetadata(req, domain.MetadataWriteOptions{CAS: cas})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	setConsulHeaders(w, 0)
+	h.writeJSON(w, http.StatusOK, ok)
+}
+
+// DeleteKV handles DELETE /v1/kv/{key}, deleting the entry at key, or with
+// ?recurse every entry under it. ?cas makes the delete of a single entry
+// conditional, as it does a put.
+func (h *Handler) DeleteKV(w http.ResponseWriter, r *http.Request) {
+	key, err := h.consulPreamble(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var v domain.FieldViolations
+	cas := parseUintParam(&v, r.URL.Query().Get("cas"), "cas")
+	recurse := hasFlag(r, "recurse")
+	if cas != nil && recurse {
+		v.Add("cas", "cannot be combined with recurse")
+	}
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	ok := true
+	switch {
+	case recurse:
+		_, err = h.service.DeleteMetadataPrefix(key)
+	case key == "":
+		err = domain.InvalidInputError("missing key name", nil)
+	default:
+		ok, err = h.service.DeleteMetadataByPath(key, domain.MetadataWriteOptions{CAS: cas})
+	}
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	setConsulHeaders(w, 0)
+	h.writeJSON(w, http.StatusOK, ok)
+}
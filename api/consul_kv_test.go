@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulKV_PutGet(t *testing.T) {
+	h := newTestHandler(t)
+
+	w := doRequest(t, h, "PUT", "/v1/kv/app/config?flags=42", "hello")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `true`, w.Body.String())
+
+	w = doRequest(t, h, "GET", "/v1/kv/app/config", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var entries []consulEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "app/config", entries[0].Key)
+	assert.Equal(t, uint64(42), entries[0].Flags)
+	require.NotNil(t, entries[0].Value)
+	assert.Equal(t, "aGVsbG8=", *entries[0].Value)
+	assert.NotZero(t, entries[0].CreateIndex)
+	assert.Equal(t, strconv.FormatUint(entries[0].ModifyIndex, 10), w.Header().Get("X-Consul-Index"))
+	assert.Equal(t, "true", w.Header().Get("X-Consul-KnownLeader"))
+
+	w = doRequest(t, h, "GET", "/v1/kv/app/config?raw", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+
+	// Consul stores an empty value as null
+	doRequest(t, h, "PUT", "/v1/kv/app/empty", "")
+	w = doRequest(t, h, "GET", "/v1/kv/app/empty", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"Value":null`)
+
+	w = doRequest(t, h, "GET", "/v1/kv/app/missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("X-Consul-Index"))
+}
+
+func TestConsulKV_CAS(t *testing.T) {
+	h := newTestHandler(t)
+
+	// cas=0 only creates
+	w := doRequest(t, h, "PUT", "/v1/kv/lock?cas=0", "a")
+	assert.JSONEq(t, `true`, w.Body.String())
+	w = doRequest(t, h, "PUT", "/v1/kv/lock?cas=0", "b")
+	assert.JSONEq(t, `false`, w.Body.String())
+
+	m, err := h.service.GetMetadataByPath("lock")
+	require.NoError(t, err)
+	index := strconv.FormatUint(m.ModifyIndex(), 10)
+
+	w = doRequest(t, h, "PUT", "/v1/kv/lock?cas=1", "c")
+	assert.JSONEq(t, `false`, w.Body.String())
+	w = doRequest(t, h, "PUT", "/v1/kv/lock?cas="+index, "d")
+	assert.JSONEq(t, `true`, w.Body.String())
+
+	// The write moved the index on, so a delete against the old one fails
+	w = doRequest(t, h, "DELETE", "/v1/kv/lock?cas="+index, "")
+	assert.JSONEq(t, `false`, w.Body.String())
+
+	m, err = h.service.GetMetadataByPath("lock")
+	require.NoError(t, err)
+	assert.Equal(t, "d", m.Value)
+
+	w = doRequest(t, h, "DELETE", "/v1/kv/lock?cas="+strconv.FormatUint(m.ModifyIndex(), 10), "")
+	assert.JSONEq(t, `true`, w.Body.String())
+	w = doRequest(t, h, "GET", "/v1/kv/lock", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestConsulKV_Recurse(t *testing.T) {
+	h := newTestHandler(t)
+	for _, key := range []string{"app/a", "app/b/c", "app/b/d", "apple", "other"} {
+		w := doRequest(t, h, "PUT", "/v1/kv/"+key, key)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		expected []string
+	}{
+		{name: "keys", target: "/v1/kv/app/?keys", expected: []string{"app/a", "app/b/c", "app/b/d"}},
+		{name: "keys with separator", target: "/v1/kv/app/?keys&separator=/", expected: []string{"app/a", "app/b/"}},
+		{name: "all keys", target: "/v1/kv/?keys&separator=/", expected: []string{"app/", "apple", "other"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(t, h, "GET", tt.target, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var keys []string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+			assert.Equal(t, tt.expected, keys)
+		})
+	}
+
+	w := doRequest(t, h, "GET", "/v1/kv/app?recurse", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var entries []consulEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Len(t, entries, 4)
+
+	w = doRequest(t, h, "DELETE", "/v1/kv/app/?recurse", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(t, h, "GET", "/v1/kv/?keys", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `["apple","other"]`, w.Body.String())
+}
+
+func TestConsulKV_InvalidRequests(t *testing.T) {
+	h := newTestHandler(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+	}{
+		{name: "missing key", method: "GET", target: "/v1/kv/"},
+		{name: "put missing key", method: "PUT", target: "/v1/kv/"},
+		{name: "bad flags", method: "PUT", target: "/v1/kv/a?flags=-1"},
+		{name: "flags out of range", method: "PUT", target: "/v1/kv/a?flags=18446744073709551615"},
+		{name: "bad cas", method: "PUT", target: "/v1/kv/a?cas=x"},
+		{name: "session", method: "PUT", target: "/v1/kv/a?acquire=abc"},
+		{name: "cas with recurse", method: "DELETE", target: "/v1/kv/a?recurse&cas=0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(t, h, tt.method, tt.target, "value")
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestConsulKV_ConsulToken(t *testing.T) {
+	h := newTestHandler(t)
+	h.token = "secret"
+
+	w := doRequest(t, h, "GET", "/v1/kv/a", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("PUT", "/v1/kv/a", strings.NewReader("1"))
+	r.Header.Set("X-Consul-Token", "secret")
+	SetupRouter(h).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = doRequest(t, h, "GET", "/v1/kv/a?token=secret", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// DefaultCORSPolicy returns the policy used when none is configured: any
+// origin may call the API without credentials
+func DefaultCORSPolicy() domain.CORSPolicy {
+	return domain.CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{
+			"Accept", "Authorization", "Content-Type", "X-CSRF-Token",
+			"X-Dirt-No-Chaos", "X-Dirt-Latency", "X-Dirt-Force-Status", "X-Dirt-Force-Body",
+			"X-Dirt-Chaos-Profile", "X-Dirt-Tenant", "X-Dirt-Flaky-Create", "X-Dirt-Replay-Create",
+			"X-Dirt-Malformed-Response", "X-Dirt-Connection-Fault", "X-Dirt-Debug", "X-Fields",
+			"X-Dirt-Date", "X-Dirt-Content-SHA256", "X-Dirt-Read-Preference",
+			"If-None-Match", "If-Modified-Since", "X-Request-Id", "traceparent", "tracestate",
+		},
+		ExposedHeaders: []string{
+			"ETag", "Last-Modified", "Retry-After", "X-Dirt-Debug-Info", "X-Dirt-Defaults-Applied",
+			"X-Dirt-Next-Page-Token", "X-Dirt-Replica-Lag", "X-Request-Id",
+		},
+	}
+}
+
+// withCORSDefaults fills the fields of a policy that were left out
+func withCORSDefaults(policy domain.CORSPolicy) domain.CORSPolicy {
+	defaults := DefaultCORSPolicy()
+	if policy.AllowedOrigins == nil {
+		policy.AllowedOrigins = defaults.AllowedOrigins
+	}
+	if policy.AllowedMethods == nil {
+		policy.AllowedMethods = defaults.AllowedMethods
+	}
+	if policy.AllowedHeaders == nil {
+		policy.AllowedHeaders = defaults.AllowedHeaders
+	}
+	if policy.ExposedHeaders == nil {
+		policy.ExposedHeaders = defaults.ExposedHeaders
+	}
+	return policy
+}
+
+// ValidateCORSPolicy checks a policy, with its left out fields defaulted
+func ValidateCORSPolicy(policy domain.CORSPolicy) error {
+	policy = withCORSDefaults(policy)
+
+	var v domain.FieldViolations
+	for i, origin := range policy.AllowedOrigins {
+		if origin == "*" {
+			if policy.AllowCredentials {
+				v.Add(fmt.Sprintf("allowed_origins[%d]", i), `cannot be "*" when allow_credentials is set; list the origins instead`)
+			}
+			continue
+		}
+		if !validOrigin(origin) {
+			v.Add(fmt.Sprintf("allowed_origins[%d]", i), fmt.Sprintf(`must be "*" or an http(s) origin such as "https://app.example.com" or "https://*.example.com" (got %q)`, origin))
+		}
+	}
+	for i, method := range policy.AllowedMethods {
+		if !validToken(method) || strings.ToUpper(method) != method {
+			v.Add(fmt.Sprintf("allowed_methods[%d]", i), fmt.Sprintf("must be an uppercase HTTP method (got %q)", method))
+		}
+	}
+	for i, header := range policy.AllowedHeaders {
+		if !validToken(header) {
+			v.Add(fmt.Sprintf("allowed_headers[%d]", i), fmt.Sprintf("must be a header name (got %q)", header))
+		}
+	}
+	for i, header := range policy.ExposedHeaders {
+		if !validToken(header) {
+			v.Add(fmt.Sprintf("exposed_headers[%d]", i), fmt.Sprintf("must be a header name (got %q)", header))
+		}
+	}
+	if policy.MaxAgeSeconds < 0 {
+		v.Add("max_age_seconds", "must not be negative")
+	}
+	return v.Err()
+}
+
+// validOrigin reports whether s is a scheme and host with an optional port
+// and an optional leading "*." wildcard label
+func validOrigin(s string) bool {
+	u, err := url.Parse(strings.Replace(s, "://*.", "://wildcard.", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.User == nil && u.Path == "" && u.RawQuery == "" && u.Fragment == "" && !strings.HasSuffix(s, "?")
+}
+
+// validToken reports whether s is an HTTP token, as methods and header
+// names are
+func validToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// corsPolicy holds the CORS policy in effect, which the admin API can
+// replace while the server runs
+type corsPolicy struct {
+	mu     sync.RWMutex
+	policy domain.CORSPolicy
+}
+
+func (p *corsPolicy) get() domain.CORSPolicy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.policy
+}
+
+func (p *corsPolicy) set(policy domain.CORSPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.policy = policy
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request
+// from origin, or "" if the origin is not allowed
+func allowedOrigin(policy domain.CORSPolicy, origin string) string {
+	for _, allowed := range policy.AllowedOrigins {
+		switch {
+		case allowed == "*" && !policy.AllowCredentials:
+			return "*"
+		case origin == "":
+		case strings.EqualFold(allowed, origin):
+			return origin
+		case strings.Contains(allowed, "://*."):
+			scheme, suffix, _ := strings.Cut(allowed, "://*")
+			rest, ok := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://")
+			if ok && len(rest) > len(suffix) && strings.HasSuffix(rest, strings.ToLower(suffix)) {
+				return origin
+			}
+		}
+	}
+	return ""
+}
+
+// applyCORS adds the CORS headers the policy in effect grants a request and
+// answers OPTIONS requests, including preflights, itself
+func (h *Handler) applyCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := h.cors.get()
+		header := w.Header()
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		allowOrigin := allowedOrigin(policy, r.Header.Get("Origin"))
+		if allowOrigin != "*" {
+			// The response depends on the origin, so caches must key on it
+			header.Add("Vary", "Origin")
+		}
+		if allowOrigin != "" {
+			header.Set("Access-Control-Allow-Origin", allowOrigin)
+			if policy.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				header.Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+				header.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+				if policy.MaxAgeSeconds > 0 {
+					header.Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAgeSeconds))
+				}
+			} else if len(policy.ExposedHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+			}
+		}
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetCORS handles GET /v1/admin/cors
+func (h *Handler) GetCORS(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.cors.get())
+}
+
+// PutCORS handles PUT /v1/admin/cors. The policy replaces the one in effect,
+// with fields left out taking their defaults.
+func (h *Handler) PutCORS(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var policy domain.CORSPolicy
+	if err := h.decodeJSON(w, r, &policy); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if err := ValidateCORSPolicy(policy); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	policy = withCORSDefaults(policy)
+	h.cors.set(policy)
+	h.writeJSON(w, http.StatusOK, policy)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/signer"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_applyCORS(t *testing.T) {
+	strict := &domain.CORSPolicy{
+		AllowedOrigins:   []string{"https://console.example.com", "https://*.tools.example.com"},
+		AllowCredentials: true,
+		MaxAgeSeconds:    600,
+	}
+
+	tests := []struct {
+		name      string
+		policy    *domain.CORSPolicy
+		method    string
+		origin    string
+		preflight bool
+		expected  map[string]string
+	}{
+		{
+			name: "default policy", method: "GET", origin: "https://anywhere.test",
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":   "*",
+				"Access-Control-Expose-Headers": "ETag, Last-Modified, Retry-After, X-Dirt-Debug-Info, X-Dirt-Defaults-Applied, X-Dirt-Next-Page-Token, X-Dirt-Replica-Lag, X-Request-Id",
+			},
+		},
+		{
+			name: "default preflight", method: "OPTIONS", origin: "https://anywhere.test", preflight: true,
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+				"Access-Control-Max-Age":       "",
+			},
+		},
+		{
+			name: "listed origin", policy: strict, method: "GET", origin: "https://console.example.com",
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":      "https://console.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			name: "wildcard subdomain", policy: strict, method: "OPTIONS", origin: "https://ci.tools.example.com", preflight: true,
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":  "https://ci.tools.example.com",
+				"Access-Control-Max-Age":       "600",
+				"Access-Control-Allow-Headers": strings.Join(DefaultCORSPolicy().AllowedHeaders, ", "),
+			},
+		},
+		{
+			name: "wildcard needs a subdomain", policy: strict, method: "GET", origin: "https://tools.example.com",
+			expected: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "unlisted origin", policy: strict, method: "OPTIONS", origin: "https://evil.test", preflight: true,
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Allow-Methods":     "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{CORS: tt.policy}))
+
+			r := httptest.NewRequest(tt.method, "/v1/projects", nil)
+			r.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			for header, value := range tt.expected {
+				assert.Equal(t, value, w.Header().Get(header), header)
+			}
+			if tt.policy != nil {
+				assert.Contains(t, w.Header().Values("Vary"), "Origin")
+			}
+		})
+	}
+}
+
+func TestDefaultCORSPolicy_DirtHeaders(t *testing.T) {
+	policy := DefaultCORSPolicy()
+
+	// Browsers may only send the request headers the policy allows
+	requestHeaders := []string{
+		chaos.NoChaosHeader, chaos.LatencyHeader, chaos.ForceStatusHeader, chaos.ForceBodyHeader,
+		chaos.FlakyCreateHeader, chaos.ReplayCreateHeader, chaos.MalformedResponseHeader,
+		chaos.ConnectionFaultHeader, chaos.ProfileHeader,
+		TenantHeader, DebugHeader, ReadPreferenceHeader,
+		signer.DateHeader, signer.ContentHashHeader,
+	}
+	for _, header := range requestHeaders {
+		assert.Contains(t, policy.AllowedHeaders, header)
+	}
+
+	// and may only read the response headers it exposes
+	responseHeaders := []string{DebugInfoHeader, DefaultsAppliedHeader, NextPageTokenHeader, ReplicaLagHeader}
+	for _, header := range responseHeaders {
+		assert.Contains(t, policy.ExposedHeaders, header)
+	}
+}
+
+func TestValidateCORSPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy domain.CORSPolicy
+		field  string
+	}{
+		{"defaults", domain.CORSPolicy{}, ""},
+		{"no origins", domain.CORSPolicy{AllowedOrigins: []string{}}, ""},
+		{"credentials with any origin", domain.CORSPolicy{AllowCredentials: true}, "allowed_origins[0]"},
+		{"origin with path", domain.CORSPolicy{AllowedOrigins: []string{"https://app.example.com/"}}, "allowed_origins[0]"},
+		{"origin without scheme", domain.CORSPolicy{AllowedOrigins: []string{"*", "app.example.com"}}, "allowed_origins[1]"},
+		{"lowercase method", domain.CORSPolicy{AllowedMethods: []string{"get"}}, "allowed_methods[0]"},
+		{"header list", domain.CORSPolicy{AllowedHeaders: []string{"Accept, Authorization"}}, "allowed_headers[0]"},
+		{"negative max age", domain.CORSPolicy{MaxAgeSeconds: -1}, "max_age_seconds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCORSPolicy(tt.policy)
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.True(t, domain.IsInvalidInput(err), "got %v", err)
+			assert.Contains(t, err.Error(), tt.field)
+		})
+	}
+}
+
+func TestHandler_PutCORS(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	put := httptest.NewRecorder()
+	router.ServeHTTP(put, httptest.NewRequest("PUT", "/v1/admin/cors",
+		strings.NewReader(`{"allowed_origins":["https://console.example.com"],"allow_credentials":true}`)))
+	require.Equal(t, http.StatusOK, put.Code, put.Body.String())
+
+	var policy domain.CORSPolicy
+	require.NoError(t, json.Unmarshal(put.Body.Bytes(), &policy))
+	assert.Equal(t, DefaultCORSPolicy().AllowedMethods, policy.AllowedMethods, "left out fields take their defaults")
+
+	r := httptest.NewRequest("GET", "/v1/admin/cors", nil)
+	r.Header.Set("Origin", "https://other.test")
+	get := httptest.NewRecorder()
+	router.ServeHTTP(get, r)
+	require.Equal(t, http.StatusOK, get.Code)
+	assert.Contains(t, get.Body.String(), `"allowed_origins":["https://console.example.com"]`)
+	assert.Empty(t, get.Header().Get("Access-Control-Allow-Origin"), "the new policy applies")
+
+	invalid := httptest.NewRecorder()
+	router.ServeHTTP(invalid, httptest.NewRequest("PUT", "/v1/admin/cors", strings.NewReader(`{"allow_credentials":true}`)))
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Database handlers
+
+// CreateDatabase handles POST /v1/databases
+func (h *Handler) CreateDatabase(w http.ResponseWriter, r *http.Request) {
+	member, err := h.principal(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateDatabaseRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.requireRole(r, member, req.ProjectID, domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, req.ProjectID), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	database, err := h.service.CreateDatabase(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, database.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var defaults defaultsApplied
+	if req.Version == "" {
+		defaults.add("version", database.Version)
+	}
+	if req.Size == "" {
+		defaults.add("size", database.Size)
+	}
+	defaults.setHeader(w)
+
+	h.writeJSON(w, http.StatusCreated, database)
+}
+
+// GetDatabase handles GET /v1/databases/{id}
+func (h *Handler) GetDatabase(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeDatabase(r, mux.Vars(r)["id"], domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	database, err := h.service.GetDatabase(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, database, database.UpdatedAt)
+}
+
+// ListDatabases handles GET /v1/databases
+func (h *Handler) ListDatabases(w http.ResponseWriter, r *http.Request) {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, query.Get("project_id")), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	databases, err := h.service.ListDatabases(domain.DatabaseListOptions{
+		ProjectID: query.Get("project_id"),
+		Engine:    query.Get("engine"),
+		Status:    query.Get("status"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if visible != nil {
+		var allowed []*domain.Database
+		for _, database := range databases {
+			ok, err := visible(database.ProjectID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, database)
+			}
+		}
+		databases = allowed
+	}
+
+	h.writeJSON(w, http.StatusOK, databases)
+}
+
+// UpdateDatabase handles PATCH /v1/databases/{id}
+func (h *Handler) UpdateDatabase(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeDatabase(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateDatabaseRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	database, err := h.service.UpdateDatabase(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, database)
+}
+
+// DeleteDatabase handles DELETE /v1/databases/{id}
+func (h *Handler) DeleteDatabase(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeDatabase(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteDatabase(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseRoutes(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "databases"})
+	require.NoError(t, err)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("POST", "/v1/databases", `{"project_id": "`+project.ID+`", "name": "orders-db", "engine": "mysql"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "version=8.0, size=small", w.Header().Get(DefaultsAppliedHeader))
+
+	var database domain.Database
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &database))
+	assert.Equal(t, domain.DatabaseProvisioning, database.Status)
+	assert.Equal(t, "orders_db", database.DatabaseName)
+	assert.Equal(t, "mysql://dirtadmin:"+database.Password+"@"+database.ID+".db.dirtcloud.internal:3306/orders_db?tls=true", database.ConnectionString)
+
+	// The test service provisions without delay, so the next read is available
+	w = do("GET", "/v1/databases/"+database.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &database))
+	assert.Equal(t, domain.DatabaseAvailable, database.Status)
+
+	w = do("GET", "/v1/databases?project_id="+project.ID+"&engine=mysql", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var databases []*domain.Database
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &databases))
+	require.Len(t, databases, 1)
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/v1/databases", `{"project_id": "`+project.ID+`", "name": "x", "engine": "oracle"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("PATCH", "/v1/databases/"+database.ID, `{"size": "huge"}`).Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/v1/databases/"+database.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/databases/"+database.ID, "").Code)
+}
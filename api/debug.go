@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// DebugHeader, set to true, adds a _debug object to a request's JSON
+// response describing how it was served
+const DebugHeader = "X-Dirt-Debug"
+
+// DebugInfoHeader carries the debug object of JSON responses that are not
+// objects, such as lists, which have nowhere to hold a _debug field
+const DebugInfoHeader = "X-Dirt-Debug-Info"
+
+// traceHeaders are the tracing headers echoed back on API responses, so
+// clients can match responses to the requests they traced
+var traceHeaders = []string{"X-Request-Id", "traceparent", "tracestate"}
+
+// debugInfo is the _debug object of a response
+type debugInfo struct {
+	RequestID string                   `json:"request_id,omitempty"`
+	Chaos     []chaos.Decision         `json:"chaos"`
+	SQL       []domain.TracedStatement `json:"sql"`
+	Timing    debugTiming              `json:"timing"`
+}
+
+// debugTiming breaks down the time taken to serve a request. Other is the
+// time spent neither running SQL nor held up by injected latency.
+type debugTiming struct {
+	TotalMS float64 `json:"total_ms"`
+	SQLMS   float64 `json:"sql_ms"`
+	ChaosMS float64 `json:"chaos_ms"`
+	OtherMS float64 `json:"other_ms"`
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// debugRequests echoes tracing headers on API responses and, for requests
+// with X-Dirt-Debug: true, adds a _debug object to JSON responses listing the
+// chaos applied, the SQL statements run and where the time went.
+//
+// Debugged requests are served by a router of their own, over a copy of the
+// handler whose service records its statements, so that concurrent requests
+// do not show up in each other's traces. It must be the first middleware, so
+// that the other middleware run once, inside that router.
+func (h *Handler) debugRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !apiRoute(r.URL.Path) && r.URL.Path != "/graphql" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, name := range traceHeaders {
+			if value := r.Header.Get(name); value != "" {
+				w.Header().Set(name, value)
+			}
+		}
+		if h.debugged || r.Header.Get(DebugHeader) != "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		trace := &domain.StatementTrace{}
+		ctx, applied := chaos.WithApplied(r.Context())
+		r = r.WithContext(ctx)
+
+		traced := *h
+		traced.service = h.service.WithTrace(trace)
+		traced.debugged = true
+
+		rw := newJSONRewriter(w, func(w http.ResponseWriter, header http.Header, status int, body []byte) {
+			total := time.Since(start)
+			info := debugInfo{
+				RequestID: r.Header.Get("X-Request-Id"),
+				Chaos:     applied.Decisions(),
+				SQL:       trace.Statements(),
+				Timing: debugTiming{
+					TotalMS: milliseconds(total),
+					SQLMS:   milliseconds(trace.Duration()),
+					ChaosMS: milliseconds(applied.Delay()),
+				},
+			}
+			if other := total - trace.Duration() - applied.Delay(); other > 0 {
+				info.Timing.OtherMS = milliseconds(other)
+			}
+			writeDebugResponse(w, header, status, body, &info)
+		})
+		defer rw.finish()
+
+		SetupRouter(&traced).ServeHTTP(rw, r)
+	})
+}
+
+// writeDebugResponse adds info to a JSON response and writes it. Objects get
+// it as their _debug field; other bodies, including ones that are not valid
+// JSON, are sent as is with info in DebugInfoHeader.
+func writeDebugResponse(w http.ResponseWriter, header http.Header, status int, body []byte, info *debugInfo) {
+	for key, values := range header {
+		w.Header()[key] = values
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if !json.Valid(trimmed) || len(trimmed) < 2 || trimmed[0] != '{' {
+		w.Header().Set(DebugInfoHeader, string(data))
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+
+	// Insert the field before the closing brace, keeping the order of the
+	// others
+	var out bytes.Buffer
+	out.Write(trimmed[:len(trimmed)-1])
+	if len(bytes.TrimSpace(trimmed[1:len(trimmed)-1])) > 0 {
+		out.WriteByte(',')
+	}
+	out.WriteString(`"_debug":`)
+	out.Write(data)
+	out.WriteString("}\n")
+
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	w.Write(out.Bytes())
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugEnvelope(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	r := httptest.NewRequest("POST", "/v1/projects", strings.NewReader(`{"name":"web"}`))
+	r.Header.Set(DebugHeader, "true")
+	r.Header.Set("X-Request-Id", "req-1")
+	r.Header.Set(chaos.LatencyHeader, "5")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "req-1", w.Header().Get("X-Request-Id"))
+
+	var project struct {
+		Name  string    `json:"name"`
+		Debug debugInfo `json:"_debug"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &project), w.Body.String())
+	assert.Equal(t, "web", project.Name)
+	assert.Equal(t, "req-1", project.Debug.RequestID)
+	assert.Equal(t, []chaos.Decision{{Kind: chaos.AppliedLatency, Detail: "5ms"}}, project.Debug.Chaos)
+	assert.GreaterOrEqual(t, project.Debug.Timing.ChaosMS, 5.0)
+	assert.GreaterOrEqual(t, project.Debug.Timing.TotalMS, project.Debug.Timing.ChaosMS)
+
+	inserted := false
+	for _, s := range project.Debug.SQL {
+		inserted = inserted || strings.HasPrefix(s.SQL, "INSERT INTO projects")
+	}
+	assert.True(t, inserted, "statements: %+v", project.Debug.SQL)
+
+	// Lists carry the debug object in a header
+	r = httptest.NewRequest("GET", "/v1/projects", nil)
+	r.Header.Set(DebugHeader, "true")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var projects []map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projects))
+	assert.Len(t, projects, 1)
+	var info debugInfo
+	require.NoError(t, json.Unmarshal([]byte(w.Header().Get(DebugInfoHeader)), &info))
+	assert.NotEmpty(t, info.SQL)
+
+	// Without the header responses are left alone
+	r = httptest.NewRequest("GET", "/v1/projects/"+projects[0]["id"].(string), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.NotContains(t, w.Body.String(), "_debug")
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// DefaultMaxBodyBytes is the request body limit used when none is configured
+const DefaultMaxBodyBytes int64 = 1 << 20 // 1 MiB
+
+// decodeJSON reads a size-limited request body and strictly decodes it into v.
+// Unknown, output-only and wrongly typed fields are reported together under
+// details.fields in a single INVALID_INPUT error so clients can fix every
+// problem at once.
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	body, err := h.readBody(w, r)
+	if err != nil {
+		return err
+	}
+	return decodeBody(body, v)
+}
+
+// readBody reads a request body up to the configured size limit
+func (h *Handler) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodyBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, domain.InvalidInputError("request body too large", map[string]interface{}{
+				"max_bytes": maxErr.Limit,
+			})
+		}
+		return nil, domain.InvalidInputError("failed to read request body", nil)
+	}
+	return body, nil
+}
+
+// decodeBody strictly decodes a request body into v, as decodeJSON does
+func decodeBody(body []byte, v interface{}) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return domain.InvalidInputError("request body is required", nil)
+	}
+
+	// Inspect the raw object first so every offending field can be listed
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return syntaxError(err)
+	}
+
+	if err := inspectFields(raw, v).Err(); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return syntaxError(err)
+	}
+	if dec.More() {
+		return domain.InvalidInputError("invalid JSON", map[string]interface{}{
+			"reason": "unexpected data after JSON object",
+		})
+	}
+
+	return nil
+}
+
+// outputOnlyFields are resource fields the server populates. Requests that
+// do not accept them get a specific error rather than an unknown field one.
+var outputOnlyFields = map[string]bool{
+	"id":                   true,
+	"created_at":           true,
+	"updated_at":           true,
+	"autoscaling_group_id": true,
+	"current_size":         true,
+}
+
+// syntaxError converts a JSON decoding error into an INVALID_INPUT error
+func syntaxError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return domain.InvalidInputError("invalid JSON", map[string]interface{}{
+			"offset": syntaxErr.Offset,
+		})
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field == "" {
+		return domain.InvalidInputError("invalid JSON", map[string]interface{}{
+			"reason": fmt.Sprintf("expected a JSON object, got %s", typeErr.Value),
+		})
+	}
+	return domain.InvalidInputError("invalid JSON", nil)
+}
+
+// inspectFields compares the keys of a raw JSON object against the JSON fields
+// of the struct pointed to by v, returning a violation for each unknown or
+// output-only key and each type mismatch, ordered by field
+func inspectFields(raw map[string]json.RawMessage, v interface{}) domain.FieldViolations {
+	fields := jsonFields(v)
+
+	var invalid domain.FieldViolations
+
+	for key, value := range raw {
+		fieldType, ok := fields[key]
+		if !ok {
+			if outputOnlyFields[key] {
+				invalid.Add(key, "is output-only and cannot be set")
+			} else {
+				invalid.Add(key, "is not a known field")
+			}
+			continue
+		}
+
+		target := reflect.New(fieldType).Interface()
+		if err := json.Unmarshal(value, target); err != nil {
+			message := "invalid value"
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				message = fmt.Sprintf("expected %s, got %s", typeName(fieldType), typeErr.Value)
+			}
+			invalid.Add(key, message)
+		}
+	}
+
+	sort.Slice(invalid, func(i, j int) bool {
+		return invalid[i].Field < invalid[j].Field
+	})
+
+	return invalid
+}
+
+// jsonFields maps JSON field names to Go types for the struct pointed to by v
+func jsonFields(v interface{}) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+
+	addJSONFields(fields, t)
+	return fields
+}
+
+// addJSONFields adds the fields of struct type t to fields. As with
+// encoding/json, the fields of untagged embedded structs are promoted, and
+// lose to fields of the same name in the outer struct.
+func addJSONFields(fields map[string]reflect.Type, t reflect.Type) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			embedded = append(embedded, f.Type)
+			continue
+		}
+		if name, ok := jsonName(f); ok {
+			fields[name] = f.Type
+		}
+	}
+
+	for _, e := range embedded {
+		promoted := make(map[string]reflect.Type)
+		addJSONFields(promoted, e)
+		for name, typ := range promoted {
+			if _, ok := fields[name]; !ok {
+				fields[name] = typ
+			}
+		}
+	}
+}
+
+// jsonName returns the JSON name of a struct field, or false if the field is
+// not encoded
+func jsonName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false // unexported
+	}
+	name := f.Name
+	if tag := f.Tag.Get("json"); tag != "" {
+		tagName := strings.Split(tag, ",")[0]
+		if tagName == "-" {
+			return "", false
+		}
+		if tagName != "" {
+			name = tagName
+		}
+	}
+	return name, true
+}
+
+// typeName returns a JSON-oriented name for a Go type
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "RFC 3339 timestamp"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return t.String()
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_decodeJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		maxBodyBytes   int64
+		expectError    bool
+		expectDetails  map[string]interface{}
+		expectInstance domain.CreateInstanceRequest
+	}{
+		{
+			name:           "valid body",
+			body:           `{"project_id":"p1","name":"web","cpu":2,"memory_mb":512,"image":"ubuntu"}`,
+			expectInstance: domain.CreateInstanceRequest{ProjectID: "p1", Name: "web", CPU: 2, MemoryMB: 512, Image: "ubuntu"},
+		},
+		{
+			name:        "unknown fields are enumerated",
+			body:        `{"name":"web","rack":"a","colour":"red"}`,
+			expectError: true,
+			expectDetails: map[string]interface{}{
+				"fields": []domain.FieldViolation{
+					{Field: "colour", Message: "is not a known field"},
+					{Field: "rack", Message: "is not a known field"},
+				},
+			},
+		},
+		{
+			name:        "output-only fields are named",
+			body:        `{"id":"i1","name":"web","created_at":"2024-01-01T00:00:00Z"}`,
+			expectError: true,
+			expectDetails: map[string]interface{}{
+				"fields": []domain.FieldViolation{
+					{Field: "created_at", Message: "is output-only and cannot be set"},
+					{Field: "id", Message: "is output-only and cannot be set"},
+				},
+			},
+		},
+		{
+			name:        "output-only fields are listed with unknown fields",
+			body:        `{"name":"web","updated_at":"2024-01-01T00:00:00Z","colour":"red"}`,
+			expectError: true,
+			expectDetails: map[string]interface{}{
+				"fields": []domain.FieldViolation{
+					{Field: "colour", Message: "is not a known field"},
+					{Field: "updated_at", Message: "is output-only and cannot be set"},
+				},
+			},
+		},
+		{
+			name:        "invalid field types are enumerated",
+			body:        `{"name":1,"cpu":"two"}`,
+			expectError: true,
+			expectDetails: map[string]interface{}{
+				"fields": []domain.FieldViolation{
+					{Field: "cpu", Message: "expected integer, got string"},
+					{Field: "name", Message: "expected string, got number"},
+				},
+			},
+		},
+		{
+			name:          "body too large",
+			body:          `{"name":"a-very-long-instance-name"}`,
+			maxBodyBytes:  10,
+			expectError:   true,
+			expectDetails: map[string]interface{}{"max_bytes": int64(10)},
+		},
+		{
+			name:          "malformed JSON",
+			body:          `{"name":`,
+			expectError:   true,
+			expectDetails: map[string]interface{}{"offset": int64(8)},
+		},
+		{
+			name:        "empty body",
+			body:        ``,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(nil, nil, Config{MaxBodyBytes: tt.maxBodyBytes})
+			r := httptest.NewRequest("POST", "/v1/instances", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			var req domain.CreateInstanceRequest
+			err := h.decodeJSON(w, r, &req)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.True(t, domain.IsInvalidInput(err))
+				if tt.expectDetails != nil {
+					assert.Equal(t, tt.expectDetails, err.(*domain.DirtError).Details)
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectInstance, req)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultsAppliedHeader lists the values the server filled in for fields a
+// create request left unset, as comma separated field=value pairs such as
+// "status=running, desired_size=2"
+const DefaultsAppliedHeader = "X-Dirt-Defaults-Applied"
+
+// defaultsApplied collects the server defaults applied to a request
+type defaultsApplied []string
+
+// add records that field was defaulted to value
+func (d *defaultsApplied) add(field string, value interface{}) {
+	*d = append(*d, fmt.Sprintf("%s=%v", field, value))
+}
+
+// setHeader sets DefaultsAppliedHeader if any defaults were applied. It must
+// be called before the response status is written.
+func (d defaultsApplied) setHeader(w http.ResponseWriter) {
+	if len(d) > 0 {
+		w.Header().Set(DefaultsAppliedHeader, strings.Join(d, ", "))
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultsAppliedHeader(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	tmpl, err := h.service.CreateInstanceTemplate(domain.CreateInstanceTemplateRequest{
+		Name: "worker", CPU: 1, MemoryMB: 512, Image: "alpine",
+	})
+	require.NoError(t, err)
+	source, err := h.service.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "source", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "instance status",
+			path:     "/v1/instances",
+			body:     `{"project_id":"` + project.ID + `","name":"vm-1","cpu":1,"memory_mb":512,"image":"ubuntu"}`,
+			expected: "status=running, zone=dirt-1a, class=standard",
+		},
+		{
+			name: "nothing defaulted",
+			path: "/v1/instances",
+			body: `{"project_id":"` + project.ID + `","name":"vm-2","cpu":1,"memory_mb":512,"image":"ubuntu","status":"stopped","zone":"dirt-1b","class":"spot"}`,
+		},
+		{
+			name:     "clone project and status",
+			path:     "/v1/instances/" + source.ID + "/clone",
+			body:     `{"name":"vm-3"}`,
+			expected: "project_id=" + project.ID + ", status=running, zone=dirt-1a",
+		},
+		{
+			name:     "autoscaling group desired size",
+			path:     "/v1/autoscaling-groups",
+			body:     `{"project_id":"` + project.ID + `","name":"workers","template_id":"` + tmpl.ID + `","min_size":2,"max_size":3}`,
+			expected: "desired_size=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			assert.Equal(t, tt.expected, w.Header().Get(DefaultsAppliedHeader))
+		})
+	}
+}
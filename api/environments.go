@@ -0,0 +1,216 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Environment handlers. Environments can span projects, so project members
+// cannot use them.
+
+// CreateEnvironment handles POST /v1/environments
+func (h *Handler) CreateEnvironment(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateEnvironmentRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	env, err := h.service.CreateEnvironment(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, env)
+}
+
+// GetEnvironment handles GET /v1/environments/{id}
+func (h *Handler) GetEnvironment(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	env, err := h.service.GetEnvironment(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, env, env.UpdatedAt)
+}
+
+// ListEnvironments handles GET /v1/environments
+func (h *Handler) ListEnvironments(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	envs, err := h.service.ListEnvironments(domain.EnvironmentListOptions{
+		Name: r.URL.Query().Get("name"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, envs)
+}
+
+// UpdateEnvironment handles PATCH /v1/environments/{id}
+func (h *Handler) UpdateEnvironment(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateEnvironmentRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	env, err := h.service.UpdateEnvironment(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, env)
+}
+
+// DeleteEnvironment handles DELETE /v1/environments/{id}. With
+// ?cascade=true the environment's resources are torn down with it.
+func (h *Handler) DeleteEnvironment(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var v domain.FieldViolations
+	opts := domain.DeleteEnvironmentOptions{
+		Cascade: parseBoolParam(&v, r.URL.Query().Get("cascade"), "cascade"),
+	}
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.WithActor(h.actor(r)).DeleteEnvironment(mux.Vars(r)["id"], opts); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddEnvironmentResources handles POST /v1/environments/{id}/resources
+func (h *Handler) AddEnvironmentResources(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.AddEnvironmentResourcesRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	env, err := h.service.AddEnvironmentResources(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, env)
+}
+
+// RemoveEnvironmentResource handles
+// DELETE /v1/environments/{id}/resources/{type}/{resource_id}, which leaves
+// the resource itself in place
+func (h *Handler) RemoveEnvironmentResource(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	ref := domain.ResourceRef{Type: vars["type"], ID: vars["resource_id"]}
+	if err := h.service.RemoveEnvironmentResource(vars["id"], ref); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetEnvironmentSummary handles GET /v1/environments/{id}/summary
+func (h *Handler) GetEnvironmentSummary(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	summary, err := h.service.GetEnvironmentSummary(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, summary)
+}
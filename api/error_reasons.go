@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Error reference handlers. They need no credentials, so that the help_url
+// of any error can be followed.
+
+// ListErrorReasons handles GET /v1/errors
+func (h *Handler) ListErrorReasons(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, domain.Reasons())
+}
+
+// GetErrorReason handles GET /v1/errors/{reason}
+func (h *Handler) GetErrorReason(w http.ResponseWriter, r *http.Request) {
+	reason := mux.Vars(r)["reason"]
+	info, ok := domain.LookupReason(reason)
+	if !ok {
+		h.writeError(w, domain.NotFoundError("error reason", reason))
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, info)
+}
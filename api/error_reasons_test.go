@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorReasons(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	do := func(path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w, body
+	}
+
+	w, body := do("/v1/projects/missing")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, domain.ReasonResourceNotFound, body["reason"])
+	assert.Equal(t, false, body["retryable"])
+	assert.Equal(t, "/v1/errors/RESOURCE_NOT_FOUND", body["help_url"])
+
+	// The help URL can be followed
+	w, body = do(body["help_url"].(string))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "NOT_FOUND", body["code"])
+	assert.NotEmpty(t, body["description"])
+
+	w, _ = do("/v1/errors/NOPE")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/errors", nil))
+	var reasons []domain.ReasonInfo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reasons))
+	assert.Len(t, reasons, len(domain.Reasons()))
+}
+
+func TestErrorHelpURL(t *testing.T) {
+	router := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{ErrorHelpURL: "https://docs.example.com/errors/{reason}"}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/projects/missing", nil))
+	assert.Contains(t, w.Body.String(), `"help_url":"https://docs.example.com/errors/RESOURCE_NOT_FOUND"`)
+}
+
+func TestWriteError_HidesCauses(t *testing.T) {
+	h := newTestHandler(t)
+	cause := errors.New("UNIQUE constraint failed: projects.name")
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"wrapped domain error", fmt.Errorf("creating: %w", domain.AlreadyExistsError("project", "name", "web").WithCause(cause)), http.StatusConflict, "project with name 'web' already exists"},
+		{"plain error", cause, http.StatusInternalServerError, "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.writeError(w, tt.err)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.NotContains(t, w.Body.String(), "constraint")
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.message, body["message"])
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+const (
+	// defaultEventStreamPollInterval is how often a project event stream
+	// checks for events it was not notified of, such as those recorded by a
+	// transaction that had not committed when it notified
+	defaultEventStreamPollInterval = time.Second
+
+	// eventStreamPongWait is how long an event stream waits for a pong to
+	// its ping before dropping the client
+	eventStreamPongWait = 2 * wsPingInterval
+)
+
+// eventFilter selects the events a stream subscriber receives
+type eventFilter struct {
+	resourceTypes []string
+	verbs         []string
+}
+
+// matches reports whether event passes the filter. Empty lists match all.
+func (f eventFilter) matches(event *domain.Event) bool {
+	return matchesAny(f.resourceTypes, event.ResourceType) && matchesAny(f.verbs, event.Verb())
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectEventStream handles GET /v1/projects/{id}/events/ws. The connection
+// is upgraded to a WebSocket that sends each new event of the project as a
+// JSON text message. The resource_type and verbs query parameters take comma
+// separated lists that restrict which events are sent, and after resumes the
+// stream following that event sequence; without it only events recorded
+// after the connection opens are sent.
+func (h *Handler) ProjectEventStream(w http.ResponseWriter, r *http.Request) {
+	id, err := h.service.ResolveProjectID(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.authorizeProject(r, id, domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, id), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := eventFilter{
+		resourceTypes: splitList(query.Get("resource_type")),
+		verbs:         splitList(query.Get("verbs")),
+	}
+
+	var v domain.FieldViolations
+	after := parseIntParam(&v, query.Get("after"), "after")
+	if after < 0 {
+		v.Add("after", "must not be negative")
+	}
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if query.Get("after") == "" {
+		after, err = h.latestEventSequence(id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+	}
+
+	// Subscribe before upgrading so no notice is missed in between
+	notices, unsubscribe := h.service.SubscribeProjectEvents(id)
+	defer unsubscribe()
+
+	conn, err := h.wsUpgrader().Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has already replied to the client
+	}
+	defer conn.Close()
+
+	h.streamProjectEvents(conn, id, filter, after, notices)
+}
+
+// latestEventSequence returns the sequence of the project's latest event, or
+// 0 if it has none
+func (h *Handler) latestEventSequence(projectID string) (int64, error) {
+	events, err := h.service.ListEvents(domain.EventListOptions{ProjectID: projectID})
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, event := range events {
+		if event.Sequence > latest {
+			latest = event.Sequence
+		}
+	}
+	return latest, nil
+}
+
+// streamProjectEvents sends the project's events after sequence after until
+// the client disconnects or the project is deleted
+func (h *Handler) streamProjectEvents(conn *websocket.Conn, projectID string, filter eventFilter, after int64, notices <-chan struct{}) {
+	// Reading is required to process control frames and notice disconnects.
+	// Clients that stop answering pings are dropped.
+	conn.SetReadDeadline(time.Now().Add(eventStreamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(eventStreamPongWait))
+	})
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// send writes the events recorded since the last one sent
+	send := func() error {
+		events, err := h.service.ListEvents(domain.EventListOptions{ProjectID: projectID, AfterSequence: after})
+		if err != nil {
+			return nil // Transient lookup failures do not end the stream
+		}
+
+		sort.Slice(events, func(i, j int) bool { return events[i].Sequence < events[j].Sequence })
+		for _, event := range events {
+			after = event.Sequence
+			if !filter.matches(event) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := send(); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(h.eventStreamPollInterval)
+	defer poll.Stop()
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case <-notices:
+			if err := send(); err != nil {
+				return
+			}
+		case <-poll.C:
+			if _, err := h.service.GetProject(projectID); domain.IsNotFound(err) {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "project deleted"),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if err := send(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvent reads the next event from an event stream
+func readEvent(t *testing.T, conn *websocket.Conn) *domain.Event {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event domain.Event
+	require.NoError(t, conn.ReadJSON(&event))
+	return &event
+}
+
+func TestEventFilter(t *testing.T) {
+	resized := &domain.Event{Type: domain.EventInstanceResized, ResourceType: "instance"}
+	scaleOut := &domain.Event{Type: domain.EventAutoscalingScaleOut, ResourceType: "autoscaling_group"}
+
+	tests := []struct {
+		name     string
+		filter   eventFilter
+		expected []bool
+	}{
+		{name: "empty", expected: []bool{true, true}},
+		{name: "resource type", filter: eventFilter{resourceTypes: []string{"instance"}}, expected: []bool{true, false}},
+		{name: "verbs", filter: eventFilter{verbs: []string{"expired", "scale_out"}}, expected: []bool{false, true}},
+		{
+			name:     "both",
+			filter:   eventFilter{resourceTypes: []string{"instance"}, verbs: []string{"scale_out"}},
+			expected: []bool{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, []bool{tt.filter.matches(resized), tt.filter.matches(scaleOut)})
+		})
+	}
+}
+
+func TestHandler_ProjectEventStream(t *testing.T) {
+	h := newTestHandler(t)
+	h.eventStreamPollInterval = 10 * time.Millisecond
+	server := httptest.NewServer(SetupRouter(h))
+	defer server.Close()
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "events"})
+	require.NoError(t, err)
+	other, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "other"})
+	require.NoError(t, err)
+
+	newInstance := func(projectID, name string) *domain.Instance {
+		instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: projectID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusStopped,
+		})
+		require.NoError(t, err)
+		return instance
+	}
+	resize := func(instance *domain.Instance, cpu int) {
+		_, err := h.service.ResizeInstance(instance.ID, domain.ResizeInstanceRequest{CPU: &cpu})
+		require.NoError(t, err)
+	}
+
+	before := newInstance(project.ID, "before")
+	resize(before, 2)
+
+	base := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/projects/"
+	conn, _, err := websocket.DefaultDialer.Dial(base+project.Name+"/events/ws?verbs=resized", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Events of other projects and events recorded before connecting are
+	// not sent
+	resize(newInstance(other.ID, "elsewhere"), 2)
+	instance := newInstance(project.ID, "web")
+	resize(instance, 4)
+
+	event := readEvent(t, conn)
+	assert.Equal(t, domain.EventInstanceResized, event.Type)
+	assert.Equal(t, instance.ID, event.ResourceID)
+	assert.Equal(t, project.ID, event.ProjectID)
+
+	// Resuming after the first event replays what followed it
+	events, err := h.service.ListEvents(domain.EventListOptions{ProjectID: project.ID, Type: domain.EventInstanceResized})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	resumed, _, err := websocket.DefaultDialer.Dial(base+project.ID+"/events/ws?verbs=resized&after="+strconv.FormatInt(events[0].Sequence, 10), nil)
+	require.NoError(t, err)
+	defer resumed.Close()
+	assert.Equal(t, event.Sequence, readEvent(t, resumed).Sequence)
+
+	// Deleting the project ends the stream
+	require.NoError(t, h.service.DeleteProject(project.ID, domain.DeleteProjectOptions{Cascade: true}))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+}
+
+func TestHandler_ProjectEventStream_Errors(t *testing.T) {
+	h := newTestHandler(t)
+	server := httptest.NewServer(SetupRouter(h))
+	defer server.Close()
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "events"})
+	require.NoError(t, err)
+
+	base := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/projects/"
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{name: "missing project", path: "missing/events/ws", expected: 404},
+		{name: "bad sequence", path: project.ID + "/events/ws?after=latest", expected: 400},
+		{name: "negative sequence", path: project.ID + "/events/ws?after=-1", expected: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp, err := websocket.DefaultDialer.Dial(base+tt.path, nil)
+			require.Error(t, err)
+			assert.Equal(t, tt.expected, resp.StatusCode)
+		})
+	}
+}
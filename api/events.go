@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Event handlers
+
+// ListEvents handles GET /v1/events
+func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := domain.EventListOptions{
+		Type:         query.Get("type"),
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+		ProjectID:    query.Get("project_id"),
+		Actor:        query.Get("actor"),
+		Reason:       query.Get("reason"),
+	}
+
+	var v domain.FieldViolations
+	opts.Since = parseTimeParam(&v, query.Get("since"), "since")
+	opts.Until = parseTimeParam(&v, query.Get("until"), "until")
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	events, err := h.service.ListEvents(opts)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeList(w, r, events, nil)
+}
+
+// ListInstanceEvents handles GET /v1/instances/{id}/events, listing the
+// events of an instance, including why its status changed. The events of a
+// deleted instance can still be listed.
+func (h *Handler) ListInstanceEvents(w http.ResponseWriter, r *http.Request) {
+	member, err := h.principal(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	query := r.URL.Query()
+	opts := domain.EventListOptions{
+		Type:         query.Get("type"),
+		ResourceType: "instance",
+		ResourceID:   id,
+		Actor:        query.Get("actor"),
+		Reason:       query.Get("reason"),
+	}
+
+	var v domain.FieldViolations
+	opts.Since = parseTimeParam(&v, query.Get("since"), "since")
+	opts.Until = parseTimeParam(&v, query.Get("until"), "until")
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	projectID, err := h.instanceEventsProject(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.requireRole(r, member, projectID, domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.projectChaos(r, projectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	events, err := h.service.ListEvents(opts)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeList(w, r, events, nil)
+}
+
+// instanceEventsProject returns the project of an instance or, once it is
+// deleted, the project its events were recorded in
+func (h *Handler) instanceEventsProject(id string) (string, error) {
+	instance, err := h.service.GetInstance(id)
+	if err == nil {
+		return instance.ProjectID, nil
+	}
+	if !domain.IsNotFound(err) {
+		return "", err
+	}
+
+	events, listErr := h.service.ListEvents(domain.EventListOptions{ResourceType: "instance", ResourceID: id})
+	if listErr != nil {
+		return "", listErr
+	}
+	if len(events) == 0 {
+		return "", err
+	}
+	return events[0].ProjectID, nil
+}
+
+// parseTimeParam parses an optional RFC 3339 time query parameter
+func parseTimeParam(v *domain.FieldViolations, value, field string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		v.Add(field, "must be an RFC 3339 time")
+	}
+	return t
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListEvents_ActorAndTimeFilters(t *testing.T) {
+	svc := newTestService(t)
+	router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{Token: "secret"}))
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "events"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusStopped})
+	require.NoError(t, err)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("POST", "/v1/instances/"+instance.ID+"/resize", `{"cpu": 2}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{name: "by actor", query: "actor=admin", expected: 1},
+		{name: "other actor", query: "actor=" + domain.ActorReaper, expected: 0},
+		{name: "since the past", query: "type=instance.resized&since=2000-01-01T00:00:00Z", expected: 1},
+		{name: "until the past", query: "until=2000-01-01T00:00:00Z", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do("GET", "/v1/events?"+tt.query, "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var events []domain.Event
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+			assert.Len(t, events, tt.expected)
+		})
+	}
+
+	w = do("GET", "/v1/events?since=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_ListInstanceEvents(t *testing.T) {
+	svc := newTestService(t)
+	router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{Token: "secret"}))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	list := func(path string) []domain.Event {
+		w := do("GET", path, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var events []domain.Event
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+		return events
+	}
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "events"})
+	require.NoError(t, err)
+	w := do("POST", "/v1/instances", `{"project_id":"`+project.ID+`","name":"vm-1","cpu":1,"memory_mb":512,"image":"ubuntu"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var instance domain.Instance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instance))
+
+	require.Equal(t, http.StatusOK, do("PATCH", "/v1/instances/"+instance.ID, `{"status":"stopped"}`).Code)
+	require.Equal(t, http.StatusNoContent, do("DELETE", "/v1/instances/"+instance.ID, "").Code)
+
+	// The events outlive the instance
+	events := list("/v1/instances/" + instance.ID + "/events")
+	require.Len(t, events, 3)
+	last := events[2]
+	assert.Equal(t, domain.EventInstanceStatusChanged, last.Type)
+	assert.Equal(t, domain.StatusStopped, last.PreviousStatus)
+	assert.Equal(t, domain.StatusTerminated, last.Status)
+	assert.Equal(t, domain.ReasonUserRequest, last.Reason)
+	assert.Equal(t, domain.ActorAdmin, last.Actor)
+
+	assert.Empty(t, list("/v1/instances/"+instance.ID+"/events?reason="+domain.ReasonTTLExpired))
+	assert.Len(t, list("/v1/events?reason="+domain.ReasonUserRequest), 3)
+
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/instances/missing/events", "").Code)
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// List export formats negotiated through the Accept header
+const (
+	ContentTypeJSON   = "application/json"
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// listFormat picks the response format of a list endpoint from the Accept
+// header. Media types are tried in order; JSON is the fallback.
+func listFormat(r *http.Request) string {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case ContentTypeCSV, ContentTypeNDJSON:
+			return mediaType
+		case ContentTypeJSON, "application/*", "*/*":
+			return ContentTypeJSON
+		}
+	}
+	return ContentTypeJSON
+}
+
+// writeList writes a list response in the format negotiated by the request,
+// reduced to fields when any are given. items must be a slice of structs or
+// struct pointers. CSV and NDJSON are written row by row as they are encoded.
+func (h *Handler) writeList(w http.ResponseWriter, r *http.Request, items interface{}, fields []string) {
+	w.Header().Add("Vary", "Accept")
+
+	format := listFormat(r)
+	if format == ContentTypeJSON {
+		result, err := selectFields(items, fields)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		h.writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	list := reflect.ValueOf(items)
+	lw := newListWriter(w, format, listColumns(list.Type().Elem(), fields))
+	for i := 0; i < list.Len(); i++ {
+		if err := lw.write(list.Index(i).Interface()); err != nil {
+			// The status line is already written; all that is left is to stop
+			log.Printf("Failed to export list row: %v", err)
+			return
+		}
+	}
+	lw.close()
+}
+
+// streamList writes a list response as each produces its items, without
+// holding the list in memory. It is what ?stream=true selects: rows are
+// written as CSV when the request accepts it and as NDJSON otherwise.
+//
+// An error before the first row is written as a normal error response. Once
+// rows have been sent the status can no longer change, so a later error
+// aborts the connection instead, leaving the client a truncated body rather
+// than one that looks complete.
+func streamList[T any](h *Handler, w http.ResponseWriter, r *http.Request, fields []string, each func(emit func(T) error) error) {
+	w.Header().Add("Vary", "Accept")
+
+	format := listFormat(r)
+	if format == ContentTypeJSON {
+		format = ContentTypeNDJSON
+	}
+	lw := newListWriter(w, format, listColumns(reflect.TypeOf((*T)(nil)).Elem(), fields))
+
+	err := each(func(item T) error {
+		return lw.write(item)
+	})
+	if err != nil {
+		if !lw.started {
+			h.writeError(w, err)
+			return
+		}
+		log.Printf("Failed to stream list: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+	lw.close()
+}
+
+// listWriter writes the rows of a CSV or NDJSON list response, flushing
+// each so that clients see rows as they are written. The status line and
+// CSV header go out with the first row.
+type listWriter struct {
+	w       http.ResponseWriter
+	format  string
+	columns []string
+	csv     *csv.Writer
+	flusher http.Flusher
+	started bool
+}
+
+func newListWriter(w http.ResponseWriter, format string, columns []string) *listWriter {
+	flusher, _ := w.(http.Flusher)
+	return &listWriter{w: w, format: format, columns: columns, csv: csv.NewWriter(w), flusher: flusher}
+}
+
+// start writes the headers, status line and, for CSV, the header row
+func (lw *listWriter) start() {
+	if lw.started {
+		return
+	}
+	lw.started = true
+
+	if lw.format == ContentTypeCSV {
+		lw.w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+	} else {
+		lw.w.Header().Set("Content-Type", ContentTypeNDJSON)
+	}
+	lw.w.WriteHeader(http.StatusOK)
+
+	if lw.format == ContentTypeCSV {
+		lw.csv.Write(lw.columns)
+	}
+}
+
+// write writes one item as a row
+func (lw *listWriter) write(item interface{}) error {
+	row, err := exportRow(item, lw.columns)
+	if err != nil {
+		return err
+	}
+	lw.start()
+
+	if lw.format == ContentTypeCSV {
+		cells := make([]string, len(lw.columns))
+		for j, column := range lw.columns {
+			cells[j] = csvCell(row[column])
+		}
+		lw.csv.Write(cells)
+		lw.csv.Flush()
+	} else {
+		line, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		lw.w.Write(append(line, '\n'))
+	}
+
+	if lw.flusher != nil {
+		lw.flusher.Flush()
+	}
+	return nil
+}
+
+// close finishes the response, writing the headers of an empty list
+func (lw *listWriter) close() {
+	lw.start()
+	lw.csv.Flush()
+}
+
+// listColumns returns the export columns of a list element type: the id
+// followed by the requested fields, or every JSON field in declaration order
+func listColumns(elem reflect.Type, fields []string) []string {
+	if len(fields) > 0 {
+		columns := []string{"id"}
+		for _, f := range fields {
+			if f != "id" {
+				columns = append(columns, f)
+			}
+		}
+		return columns
+	}
+
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+
+	var columns []string
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+// exportRow encodes an item as JSON and keeps the given columns
+func exportRow(item interface{}, columns []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]json.RawMessage, len(columns))
+	for _, column := range columns {
+		if v, ok := all[column]; ok {
+			row[column] = v
+		}
+	}
+	return row, nil
+}
+
+// csvCell renders a JSON value as a CSV cell. Strings are unquoted, missing
+// and null values are empty, and objects and arrays stay JSON.
+func csvCell(value json.RawMessage) string {
+	if len(value) == 0 || bytes.Equal(value, []byte("null")) {
+		return ""
+	}
+
+	var s string
+	if value[0] == '"' && json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	return string(value)
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFormat(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{accept: "", expected: ContentTypeJSON},
+		{accept: "text/csv", expected: ContentTypeCSV},
+		{accept: "application/x-ndjson", expected: ContentTypeNDJSON},
+		{accept: "text/csv; charset=utf-8", expected: ContentTypeCSV},
+		{accept: "text/html, application/x-ndjson;q=0.9", expected: ContentTypeNDJSON},
+		{accept: "*/*, text/csv", expected: ContentTypeJSON},
+		{accept: "text/html", expected: ContentTypeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/projects", nil)
+			r.Header.Set("Accept", tt.accept)
+			assert.Equal(t, tt.expected, listFormat(r))
+		})
+	}
+}
+
+func TestListExport(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "export", Labels: map[string]string{"team": "data"}})
+	require.NoError(t, err)
+	for _, name := range []string{"web-1", "web-2"} {
+		_, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+		require.NoError(t, err)
+	}
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		w := get("/v1/projects", "text/csv")
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, []string{"id", "name", "organization_id", "folder_id", "labels", "chaos_profile", "created_at", "updated_at", "deletion_protection"}, records[0])
+		assert.Equal(t, project.ID, records[1][0])
+		assert.Equal(t, "export", records[1][1])
+		assert.Equal(t, "", records[1][2])
+		assert.JSONEq(t, `{"team":"data"}`, records[1][4])
+	})
+
+	t.Run("csv with fields", func(t *testing.T) {
+		w := get("/v1/instances?fields=name,cpu&sort=name", "text/csv")
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []string{"id", "name", "cpu"}, records[0])
+		assert.Equal(t, []string{"web-1", "1"}, records[1][1:])
+		assert.Equal(t, []string{"web-2", "1"}, records[2][1:])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := get("/v1/instances?fields=name&sort=-name", "application/x-ndjson")
+		assert.Equal(t, ContentTypeNDJSON, w.Header().Get("Content-Type"))
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		var first map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+		assert.Equal(t, "web-2", first["name"])
+		assert.Len(t, first, 2, "only id and the requested fields are exported")
+	})
+
+	t.Run("events", func(t *testing.T) {
+		w := get("/v1/events", "text/csv")
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.NotEmpty(t, records)
+		assert.Equal(t, []string{"id", "type", "resource_type", "resource_id", "project_id", "message", "created_at", "actor", "previous_status", "status", "reason", "sequence"}, records[0])
+	})
+
+	t.Run("json by default", func(t *testing.T) {
+		w := get("/v1/projects", "")
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	})
+}
+
+func TestListStream(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "stream"})
+	require.NoError(t, err)
+	for _, name := range []string{"web-1", "web-2"} {
+		_, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+		require.NoError(t, err)
+	}
+	for i := 0; i < 100; i++ {
+		_, err := h.service.CreateMetadata(domain.CreateMetadataRequest{Path: fmt.Sprintf("app/key-%03d", i), Value: "v"})
+		require.NoError(t, err)
+	}
+	_, err = h.service.CreateMetadata(domain.CreateMetadataRequest{Path: "other/key", Value: "v"})
+	require.NoError(t, err)
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("metadata as ndjson", func(t *testing.T) {
+		w := get("/v1/metadata?prefix=app/&stream=true", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, ContentTypeNDJSON, w.Header().Get("Content-Type"))
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 100)
+		var first domain.Metadata
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+		assert.Equal(t, "app/key-000", first.Path)
+	})
+
+	t.Run("instances as csv", func(t *testing.T) {
+		w := get("/v1/instances?stream=true&fields=name&sort=-name", "text/csv")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []string{"id", "name"}, records[0])
+		assert.Equal(t, "web-2", records[1][1])
+	})
+
+	t.Run("projects", func(t *testing.T) {
+		w := get("/v1/projects?stream=1", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var got domain.Project
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, project.ID, got.ID)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		w := get("/v1/metadata?prefix=none/&stream=true", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, ContentTypeNDJSON, w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("errors before the first row", func(t *testing.T) {
+		w := get("/v1/instances?stream=true&fields=bogus", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = get("/v1/projects?stream=maybe", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("errors after the first row abort", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/projects?stream=true", nil)
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			streamList(h, w, r, nil, func(emit func(*domain.Project) error) error {
+				if err := emit(project); err != nil {
+					return err
+				}
+				return errors.New("cursor failed")
+			})
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), project.ID)
+	})
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// spendFailureBudget fails the requests chaos picks under its failure budget.
+// Clients are told apart by who is calling and from where, so that suites
+// sharing a token on different machines each get their own streak.
+func (h *Handler) spendFailureBudget(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if controlRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := h.actor(r) + "@" + clientIP(r)
+		if err := h.chaosService.SpendFailureBudget(r.Context(), r, client); err != nil {
+			h.writeError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetChaosFailureBudget handles GET /v1/chaos/failure-budget
+func (h *Handler) GetChaosFailureBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.FailureBudget())
+}
+
+// PutChaosFailureBudget handles PUT /v1/chaos/failure-budget
+func (h *Handler) PutChaosFailureBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var budget chaos.FailureBudget
+	if err := h.decodeJSON(w, r, &budget); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.SetFailureBudget(&budget); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.chaosService.FailureBudget())
+}
+
+// DeleteChaosFailureBudget handles DELETE /v1/chaos/failure-budget
+func (h *Handler) DeleteChaosFailureBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.chaosService.SetFailureBudget(nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureBudget(t *testing.T) {
+	t.Setenv("DIRT_CHAOS_ENABLED", "true")
+	t.Setenv("DIRT_ERROR_TYPES", "503")
+
+	router := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{}))
+
+	do := func(method, path, body, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if remoteAddr != "" {
+			r.RemoteAddr = remoteAddr
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("PUT", "/v1/chaos/failure-budget", `{"rate":1,"max_consecutive":3}`, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"budget":{"rate":1,"max_consecutive":3},"requests":0,"failed":0,"spared":0}`, w.Body.String())
+
+	// Every request would fail, but each client gets through on its fourth try
+	for _, addr := range []string{"10.0.0.1:1234", "10.0.0.2:1234"} {
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusServiceUnavailable, do("GET", "/v1/projects", "", addr).Code)
+		}
+		assert.Equal(t, http.StatusOK, do("GET", "/v1/projects", "", addr).Code)
+	}
+
+	// Chaos routes are exempt
+	w = do("GET", "/v1/chaos/failure-budget", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"budget":{"rate":1,"max_consecutive":3},"requests":8,"failed":6,"spared":2}`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/v1/chaos/failure-budget", `{"rate":1,"max_consecutive":0}`, "").Code)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/v1/chaos/failure-budget", "", "").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/projects", "", "").Code)
+}
@@ -0,0 +1,526 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// federationRoutePrefix is where the federation API is served. Its requests
+// are never routed to other regions or registered with peers.
+const federationRoutePrefix = "/v1/federation/"
+
+// federationQueueSize is how many registrations may wait for slow peers
+// before new ones are dropped
+const federationQueueSize = 1000
+
+// FederationConfig places a server in a federation of regions
+type FederationConfig struct {
+	// Region is the region this server serves; empty disables federation
+	Region string
+
+	// Peers maps the other regions of the federation to the base URLs of
+	// their servers
+	Peers map[string]string
+
+	// PeerToken is sent as the bearer token of requests to peers, which
+	// serve the federation API to admins only
+	PeerToken string
+}
+
+// Federation lets several servers act as the regions of one cloud. Each
+// server registers the resources it creates, and unregisters those it
+// deletes, with every peer, so that a request for a resource sent to the
+// wrong region is refused with the region it lives in. Registrations are
+// sent in the background so a slow peer never delays the API.
+type Federation struct {
+	region string
+	token  string
+	client *http.Client
+
+	mu        sync.Mutex
+	peers     map[string]string
+	resources map[string]domain.FederatedResource
+
+	queue     chan federationCall
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// federationCall is a request to a peer of the federation API
+type federationCall struct {
+	region string
+	method string
+	path   string
+	body   interface{}
+}
+
+// NewFederation joins the configured federation. It returns nil when no
+// region is configured.
+func NewFederation(config FederationConfig) (*Federation, error) {
+	if config.Region == "" {
+		return nil, nil
+	}
+
+	f := &Federation{
+		region:    config.Region,
+		token:     config.PeerToken,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		peers:     make(map[string]string),
+		resources: make(map[string]domain.FederatedResource),
+		queue:     make(chan federationCall, federationQueueSize),
+		done:      make(chan struct{}),
+	}
+	for region, peerURL := range config.Peers {
+		if err := f.validatePeer(region, peerURL); err != nil {
+			return nil, fmt.Errorf("invalid federation peer %s: %w", region, err)
+		}
+		f.peers[region] = strings.TrimSuffix(peerURL, "/")
+	}
+
+	go f.run()
+	return f, nil
+}
+
+// Close sends the registrations still queued. It must not be called while
+// requests are being served.
+func (f *Federation) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.queue)
+		<-f.done
+	})
+	return nil
+}
+
+// Region returns the region this server serves
+func (f *Federation) Region() string {
+	return f.region
+}
+
+// Peers returns every region of the federation, this one included, ordered
+// by region
+func (f *Federation) Peers() []domain.FederationPeer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	peers := []domain.FederationPeer{{Region: f.region, Local: true}}
+	for region, peerURL := range f.peers {
+		peers = append(peers, domain.FederationPeer{Region: region, URL: peerURL})
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Region < peers[j].Region })
+	return peers
+}
+
+// PutPeer adds or moves a peer, then registers the resources created in
+// this region with it
+func (f *Federation) PutPeer(region, peerURL string) (domain.FederationPeer, error) {
+	if err := f.validatePeer(region, peerURL); err != nil {
+		return domain.FederationPeer{}, err
+	}
+	peer := domain.FederationPeer{Region: region, URL: strings.TrimSuffix(peerURL, "/")}
+
+	f.mu.Lock()
+	f.peers[region] = peer.URL
+	var local []domain.FederatedResource
+	for _, resource := range f.resources {
+		if resource.Region == f.region {
+			local = append(local, resource)
+		}
+	}
+	f.mu.Unlock()
+
+	for _, resource := range local {
+		f.enqueue(federationCall{region: region, method: http.MethodPut, path: resourcePath(resource.ID), body: resource})
+	}
+	return peer, nil
+}
+
+// DeletePeer removes a peer. The resources registered in its region stay
+// registered, but requests for them are no longer refused.
+func (f *Federation) DeletePeer(region string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.peers[region]; !ok {
+		return domain.NotFoundError("federation peer", region)
+	}
+	delete(f.peers, region)
+	return nil
+}
+
+// Resources returns the registered resources, ordered by ID, optionally
+// only those in one region
+func (f *Federation) Resources(region string) []domain.FederatedResource {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resources := []domain.FederatedResource{}
+	for _, resource := range f.resources {
+		if region == "" || resource.Region == region {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].ID < resources[j].ID })
+	return resources
+}
+
+// PutResource registers a resource a peer created in its region
+func (f *Federation) PutResource(resource domain.FederatedResource) (domain.FederatedResource, error) {
+	var v domain.FieldViolations
+	if resource.ResourceType == "" {
+		v.Add("resource_type", "is required")
+	}
+	switch resource.Region {
+	case "":
+		v.Add("region", "is required")
+	case f.region:
+		v.Add("region", "must be a peer's region; resources in this region are registered by this server")
+	}
+	if err := v.Err(); err != nil {
+		return domain.FederatedResource{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if existing, ok := f.resources[resource.ID]; ok && existing.Region == f.region {
+		return domain.FederatedResource{}, domain.AlreadyExistsError("federated resource", "id", resource.ID)
+	}
+	f.resources[resource.ID] = resource
+	return resource, nil
+}
+
+// DeleteResource unregisters a resource a peer deleted
+func (f *Federation) DeleteResource(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resource, ok := f.resources[id]
+	if !ok || resource.Region == f.region {
+		return domain.NotFoundError("federated resource", id)
+	}
+	delete(f.resources, id)
+	return nil
+}
+
+// route refuses a request for the resource id when it lives in another
+// region the federation knows, pointing the client at that region's server
+func (f *Federation) route(r *http.Request, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resource, ok := f.resources[id]
+	if !ok || resource.Region == f.region {
+		return nil
+	}
+	peerURL, ok := f.peers[resource.Region]
+	if !ok {
+		return nil
+	}
+	return domain.WrongRegionError(resource.ResourceType, id, resource.Region, peerURL+r.URL.RequestURI())
+}
+
+// created registers a resource created in this region, given the body of
+// the response creating it, locally and with every peer
+func (f *Federation) created(resourceType string, body []byte) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil || created.ID == "" {
+		return
+	}
+	resource := domain.FederatedResource{ID: created.ID, ResourceType: resourceType, Region: f.region}
+
+	f.mu.Lock()
+	f.resources[resource.ID] = resource
+	f.mu.Unlock()
+
+	f.broadcast(http.MethodPut, resourcePath(resource.ID), resource)
+}
+
+// deleted unregisters a resource deleted in this region, locally and with
+// every peer
+func (f *Federation) deleted(id string) {
+	f.mu.Lock()
+	resource, ok := f.resources[id]
+	if ok && resource.Region == f.region {
+		delete(f.resources, id)
+	}
+	f.mu.Unlock()
+
+	if ok && resource.Region == f.region {
+		f.broadcast(http.MethodDelete, resourcePath(id), nil)
+	}
+}
+
+// broadcast queues a call to every peer
+func (f *Federation) broadcast(method, path string, body interface{}) {
+	f.mu.Lock()
+	regions := make([]string, 0, len(f.peers))
+	for region := range f.peers {
+		regions = append(regions, region)
+	}
+	f.mu.Unlock()
+
+	for _, region := range regions {
+		f.enqueue(federationCall{region: region, method: method, path: path, body: body})
+	}
+}
+
+// enqueue queues a call to a peer, dropping it if the queue is full
+func (f *Federation) enqueue(call federationCall) {
+	select {
+	case f.queue <- call:
+	default:
+		log.Printf("Federation queue full, dropping %s %s to %s", call.method, call.path, call.region)
+	}
+}
+
+func (f *Federation) run() {
+	defer close(f.done)
+	for call := range f.queue {
+		f.send(call)
+	}
+}
+
+// send makes a call to a peer, unless it has left the federation since the
+// call was queued
+func (f *Federation) send(call federationCall) {
+	f.mu.Lock()
+	peerURL, ok := f.peers[call.region]
+	f.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	var body io.Reader
+	if call.body != nil {
+		data, err := json.Marshal(call.body)
+		if err != nil {
+			log.Printf("Failed to encode federation request: %v", err)
+			return
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(call.method, peerURL+call.path, body)
+	if err != nil {
+		log.Printf("Failed to create federation request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		log.Printf("Failed to send %s %s to region %s: %v", call.method, call.path, call.region, err)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		log.Printf("Region %s responded %s to %s %s", call.region, resp.Status, call.method, call.path)
+	}
+}
+
+// validatePeer checks the region and URL of a peer
+func (f *Federation) validatePeer(region, peerURL string) error {
+	var v domain.FieldViolations
+	if region == f.region {
+		v.Add("region", "must not be this server's region")
+	}
+	u, err := url.Parse(peerURL)
+	if peerURL == "" {
+		v.Add("url", "is required")
+	} else if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.Add("url", fmt.Sprintf("must be an http(s) URL (got %q)", peerURL))
+	}
+	return v.Err()
+}
+
+// resourcePath is the federation API path registering resource id
+func resourcePath(id string) string {
+	return federationRoutePrefix + "resources/" + url.PathEscape(id)
+}
+
+// federatedResourceType returns the type of the resources created by a POST
+// to path: the singular of its collection, as events record them
+func federatedResourceType(path string) string {
+	collection := strings.TrimPrefix(path, "/v1/")
+	if i := strings.IndexAny(collection, "/:"); i >= 0 {
+		collection = collection[:i]
+	}
+	if resourceType, ok := activityResources[collection]; ok {
+		return resourceType
+	}
+	return collection
+}
+
+// federate routes requests for resources living in other regions and
+// registers the resources this region creates and deletes with its peers.
+// Requests are only refused once the caller has authenticated, so the
+// region of a resource is never revealed to anyone else.
+func (h *Handler) federate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f := h.federation
+		if f == nil || strings.HasPrefix(r.URL.Path, federationRoutePrefix) || websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		if id != "" {
+			if err := f.route(r, id); err != nil {
+				if _, authErr := h.principal(r); authErr == nil {
+					h.writeError(w, err)
+					return
+				}
+			}
+		}
+
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &mirrorRecorder{statusRecorder: statusRecorder{ResponseWriter: w}}
+		next.ServeHTTP(rec, r)
+
+		switch {
+		case r.Method == http.MethodPost && rec.status == http.StatusCreated:
+			f.created(federatedResourceType(r.URL.Path), rec.body.Bytes())
+		case r.Method == http.MethodDelete && rec.status == http.StatusNoContent && id != "" &&
+			strings.Count(r.URL.Path, "/") == 3 && strings.HasSuffix(r.URL.Path, "/"+id):
+			// Only deleting the resource itself, not something under it
+			f.deleted(id)
+		}
+	})
+}
+
+// requireFederation authenticates an admin and returns the federation, or
+// fails when this server is not federated
+func (h *Handler) requireFederation(r *http.Request) (*Federation, error) {
+	if err := h.authenticateAdmin(r); err != nil {
+		return nil, err
+	}
+	if h.federation == nil {
+		return nil, domain.FailedPreconditionError("federation is not enabled on this server; set DIRT_REGION to enable it", nil)
+	}
+	return h.federation, nil
+}
+
+// ListFederationPeers handles GET /v1/federation/peers
+func (h *Handler) ListFederationPeers(w http.ResponseWriter, r *http.Request) {
+	f, err := h.requireFederation(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, f.Peers())
+}
+
+// PutFederationPeer handles PUT /v1/federation/peers/{region}
+func (h *Handler) PutFederationPeer(w http.ResponseWriter, r *http.Request) {
+	f, err := h.requireFederation(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.FederationPeer
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	peer, err := f.PutPeer(mux.Vars(r)["region"], req.URL)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, peer)
+}
+
+// DeleteFederationPeer handles DELETE /v1/federation/peers/{region}
+func (h *Handler) DeleteFederationPeer(w http.ResponseWriter, r *http.Request) {
+	f, err := h.requireFederation(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := f.DeletePeer(mux.Vars(r)["region"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListFederatedResources handles GET /v1/federation/resources
+func (h *Handler) ListFederatedResources(w http.ResponseWriter, r *http.Request) {
+	f, err := h.requireFederation(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, f.Resources(r.URL.Query().Get("region")))
+}
+
+// PutFederatedResource handles PUT /v1/federation/resources/{id}, which
+// peers call to register the resources they create
+func (h *Handler) PutFederatedResource(w http.ResponseWriter, r *http.Request) {
+	f, err := h.requireFederation(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.FederatedResource
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	req.ID = mux.Vars(r)["id"]
+
+	resource, err := f.PutResource(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, resource)
+}
+
+// DeleteFederatedResource handles DELETE /v1/federation/resources/{id},
+// which peers call to unregister the resources they delete
+func (h *Handler) DeleteFederatedResource(w http.ResponseWriter, r *http.Request) {
+	f, err := h.requireFederation(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := f.DeleteResource(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
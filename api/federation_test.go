@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFederatedServer serves a region of a federation, returning its
+// federation to have peers added to
+func newFederatedServer(t *testing.T, region string) (*httptest.Server, *Federation) {
+	t.Helper()
+
+	federation, err := NewFederation(FederationConfig{Region: region})
+	require.NoError(t, err)
+	t.Cleanup(func() { federation.Close() })
+
+	handler := NewHandler(newTestService(t), chaos.NewChaosService(), Config{Federation: federation})
+	server := httptest.NewServer(SetupRouter(handler))
+	t.Cleanup(server.Close)
+	return server, federation
+}
+
+func TestFederation_WrongRegion(t *testing.T) {
+	east, eastFederation := newFederatedServer(t, "us-east")
+	west, westFederation := newFederatedServer(t, "us-west")
+	_, err := eastFederation.PutPeer("us-west", west.URL)
+	require.NoError(t, err)
+	_, err = westFederation.PutPeer("us-east", east.URL)
+	require.NoError(t, err)
+
+	do := func(method, url string, body interface{}) *http.Response {
+		var data []byte
+		if body != nil {
+			data, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		req, err := http.NewRequest(method, url, bytes.NewReader(data))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := do("POST", east.URL+"/v1/projects", domain.CreateProjectRequest{Name: "east-project"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var project domain.Project
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&project))
+
+	// The project is registered with the west region, which refuses
+	// requests for it
+	require.Eventually(t, func() bool {
+		return len(westFederation.Resources("us-east")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []domain.FederatedResource{{ID: project.ID, ResourceType: "project", Region: "us-east"}}, westFederation.Resources(""))
+
+	resp = do("GET", west.URL+"/v1/projects/"+project.ID, nil)
+	require.Equal(t, http.StatusMisdirectedRequest, resp.StatusCode)
+	var apiErr domain.DirtError
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiErr))
+	assert.Equal(t, domain.ErrorCodeWrongRegion, apiErr.Code)
+	assert.Equal(t, "us-east", apiErr.Details["region"])
+	assert.Equal(t, east.URL+"/v1/projects/"+project.ID, apiErr.Details["url"])
+
+	resp = do("GET", east.URL+"/v1/projects/"+project.ID, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Deleting the project unregisters it, leaving the west region to
+	// report it missing
+	resp = do("DELETE", east.URL+"/v1/projects/"+project.ID, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Eventually(t, func() bool {
+		return len(westFederation.Resources("")) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	resp = do("GET", west.URL+"/v1/projects/"+project.ID, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestFederation_Peers(t *testing.T) {
+	server, _ := newFederatedServer(t, "eu-central")
+	router := server.Config.Handler
+
+	put := func(region, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/federation/peers/"+region, bytes.NewBufferString(`{"url": "`+url+`"}`)))
+		return w
+	}
+	assert.Equal(t, http.StatusOK, put("eu-west", "http://eu-west.example.com/").Code)
+	assert.Equal(t, http.StatusBadRequest, put("eu-central", "http://eu-central.example.com").Code)
+	assert.Equal(t, http.StatusBadRequest, put("ap-south", "ftp://ap-south.example.com").Code)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/federation/peers", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var peers []domain.FederationPeer
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &peers))
+	assert.Equal(t, []domain.FederationPeer{
+		{Region: "eu-central", Local: true},
+		{Region: "eu-west", URL: "http://eu-west.example.com"},
+	}, peers)
+
+	// Peers cannot register resources in this server's own region
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/federation/resources/abc", bytes.NewBufferString(`{"resource_type": "instance", "region": "eu-central"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/v1/federation/peers/eu-west", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Without a region, the server is not federated
+	w = httptest.NewRecorder()
+	SetupRouter(newTestHandler(t)).ServeHTTP(w, httptest.NewRequest("GET", "/v1/federation/peers", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
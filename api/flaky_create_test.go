@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlakyCreate(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		outcome  string
+		expected int
+	}{
+		{name: "error", outcome: chaos.FlakyCreateError, expected: http.StatusInternalServerError},
+		{name: "timeout", outcome: chaos.FlakyCreateTimeout, expected: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"project_id":"` + project.ID + `","name":"vm-` + tt.name + `","cpu":1,"memory_mb":512,"image":"ubuntu"}`
+			r := httptest.NewRequest("POST", "/v1/instances", strings.NewReader(body))
+			r.Header.Set(chaos.FlakyCreateHeader, tt.outcome)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			assert.Equal(t, tt.expected, w.Code)
+
+			// The instance was created despite the error
+			instances, err := h.service.ListInstances(domain.InstanceListOptions{ProjectID: project.ID, Name: "vm-" + tt.name})
+			require.NoError(t, err)
+			assert.Len(t, instances, 1)
+		})
+	}
+
+	// Invalid outcomes are rejected before anything is created
+	r := httptest.NewRequest("POST", "/v1/projects", strings.NewReader(`{"name":"never"}`))
+	r.Header.Set(chaos.FlakyCreateHeader, "maybe")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, err = h.service.ResolveProjectID("never")
+	assert.True(t, domain.IsNotFound(err))
+}
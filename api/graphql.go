@@ -0,0 +1,916 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+)
+
+// graphQLRequest is a GraphQL request, as a POST body or GET parameters
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// graphQLResponse is the result of a GraphQL request. Data is left out for
+// requests that could not be executed at all.
+type graphQLResponse struct {
+	Data   *gqlResult     `json:"data,omitempty"`
+	Errors []graphQLError `json:"errors,omitempty"`
+}
+
+// graphQLError is an error in a GraphQL response. Errors of the service
+// carry their error code in the extensions.
+type graphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []graphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// graphQLLocation is where in the document an error is
+type graphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// gqlResult is an object in a response, which keeps its fields in the order
+// they were selected
+type gqlResult struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newGQLResult() *gqlResult {
+	return &gqlResult{values: map[string]interface{}{}}
+}
+
+func (r *gqlResult) set(key string, value interface{}) {
+	if _, ok := r.values[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.values[key] = value
+}
+
+// MarshalJSON writes the fields in selection order
+func (r *gqlResult) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(r.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// gqlType is an object type of the schema. Its scalar fields are those of
+// the JSON encoding of its Go type, in camelCase; fields holds the rest.
+type gqlType struct {
+	name    string
+	scalars map[string]bool
+	fields  map[string]*gqlField
+}
+
+// gqlField is a field of an object type that resolvers compute. Fields of
+// an object type name it in typ; the rest are scalars.
+type gqlField struct {
+	typ     string
+	args    []string
+	resolve func(ctx *gqlContext, source interface{}, args gqlArgs) (interface{}, error)
+}
+
+// hasField reports whether a type has a field, and whether it is an object
+func (t *gqlType) hasField(name string) (exists, object bool) {
+	if name == "__typename" || t.scalars[name] {
+		return true, false
+	}
+	if field, ok := t.fields[name]; ok {
+		return true, field.typ != ""
+	}
+	return false, false
+}
+
+// newGQLType creates an object type whose scalar fields come from value's
+// JSON encoding
+func newGQLType(name string, value interface{}, fields map[string]*gqlField) *gqlType {
+	t := &gqlType{name: name, scalars: map[string]bool{}, fields: fields}
+	typ := reflect.TypeOf(value)
+	for i := 0; i < typ.NumField(); i++ {
+		tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if tag != "" && tag != "-" {
+			t.scalars[snakeToCamel(tag)] = true
+		}
+	}
+	return t
+}
+
+// gqlArgs are the arguments of a field, with variables resolved
+type gqlArgs map[string]interface{}
+
+// string returns an optional string argument
+func (a gqlArgs) string(name string) (string, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", domain.InvalidInputError(fmt.Sprintf("argument %s must be a string", name), nil)
+	}
+}
+
+// requiredString returns a string argument that must be given
+func (a gqlArgs) requiredString(name string) (string, error) {
+	value, err := a.string(name)
+	if err == nil && value == "" {
+		err = domain.InvalidInputError(fmt.Sprintf("argument %s is required", name), nil)
+	}
+	return value, err
+}
+
+// int returns an optional integer argument
+func (a gqlArgs) int(name string) (int, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		if n, err := strconv.Atoi(v.String()); err == nil {
+			return n, nil
+		}
+	}
+	return 0, domain.InvalidInputError(fmt.Sprintf("argument %s must be an integer", name), nil)
+}
+
+// bool returns an optional boolean argument
+func (a gqlArgs) bool(name string) (bool, error) {
+	switch v := a[name].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, domain.InvalidInputError(fmt.Sprintf("argument %s must be a boolean", name), nil)
+	}
+}
+
+// input decodes an input object argument into a request, strictly, as the
+// REST API decodes request bodies. Its fields are camelCase.
+func (a gqlArgs) input(name string, req interface{}) error {
+	if _, ok := a[name].(map[string]interface{}); !ok {
+		return domain.InvalidInputError(fmt.Sprintf("argument %s must be an input object", name), nil)
+	}
+	body, err := json.Marshal(convertKeys(a[name], camelToSnake, camelToSnake))
+	if err != nil {
+		return domain.InvalidInputError(fmt.Sprintf("argument %s is invalid: %v", name, err), nil)
+	}
+	return decodeBody(body, req)
+}
+
+// gqlContext is the state of one request's execution
+type gqlContext struct {
+	svc       *service.Service
+	doc       *gqlDocument
+	variables map[string]interface{}
+	errors    []graphQLError
+}
+
+// listOf converts a slice of resources to a list result
+func listOf[T any](items []T) []interface{} {
+	list := make([]interface{}, len(items))
+	for i, item := range items {
+		list[i] = item
+	}
+	return list
+}
+
+var instanceListArgs = []string{"name", "status", "search", "limit", "offset"}
+
+// listInstances resolves a list of instances, within a project if one is
+// given
+func listInstances(ctx *gqlContext, projectID string, args gqlArgs) (interface{}, error) {
+	opts := domain.InstanceListOptions{ProjectID: projectID}
+	var err error
+	if projectID == "" {
+		if opts.ProjectID, err = args.string("projectId"); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Name, err = args.string("name"); err != nil {
+		return nil, err
+	}
+	if opts.Status, err = args.string("status"); err != nil {
+		return nil, err
+	}
+	if opts.Search, err = args.string("search"); err != nil {
+		return nil, err
+	}
+	if opts.Limit, err = args.int("limit"); err != nil {
+		return nil, err
+	}
+	if opts.Offset, err = args.int("offset"); err != nil {
+		return nil, err
+	}
+
+	instances, err := ctx.svc.ListInstances(opts)
+	return listOf(instances), err
+}
+
+// setInstanceStatus resolves the mutations starting and stopping instances
+func setInstanceStatus(status string) func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+	return func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+		id, err := args.requiredString("id")
+		if err != nil {
+			return nil, err
+		}
+		return ctx.svc.UpdateInstance(id, domain.UpdateInstanceRequest{Status: &status})
+	}
+}
+
+// graphQLTypes is the schema of the GraphQL endpoint
+var graphQLTypes = map[string]*gqlType{
+	"Query": {name: "Query", fields: map[string]*gqlField{
+		"projects": {
+			typ:  "Project",
+			args: []string{"name", "search", "organizationId", "folderId", "limit", "offset"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				var opts domain.ProjectListOptions
+				var err error
+				if opts.Name, err = args.string("name"); err != nil {
+					return nil, err
+				}
+				if opts.Search, err = args.string("search"); err != nil {
+					return nil, err
+				}
+				if opts.OrganizationID, err = args.string("organizationId"); err != nil {
+					return nil, err
+				}
+				if opts.FolderID, err = args.string("folderId"); err != nil {
+					return nil, err
+				}
+				if opts.Limit, err = args.int("limit"); err != nil {
+					return nil, err
+				}
+				if opts.Offset, err = args.int("offset"); err != nil {
+					return nil, err
+				}
+				projects, err := ctx.svc.ListProjects(opts)
+				return listOf(projects), err
+			},
+		},
+		"project": {
+			typ:  "Project",
+			args: []string{"id"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				idOrName, err := args.requiredString("id")
+				if err != nil {
+					return nil, err
+				}
+				id, err := ctx.svc.ResolveProjectID(idOrName)
+				if err != nil {
+					return nil, err
+				}
+				return ctx.svc.GetProject(id)
+			},
+		},
+		"instances": {
+			typ:  "Instance",
+			args: append([]string{"projectId"}, instanceListArgs...),
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				return listInstances(ctx, "", args)
+			},
+		},
+		"instance": {
+			typ:  "Instance",
+			args: []string{"id"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				id, err := args.requiredString("id")
+				if err != nil {
+					return nil, err
+				}
+				return ctx.svc.GetInstance(id)
+			},
+		},
+		"metadata": {
+			typ:  "Metadata",
+			args: []string{"prefix"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				prefix, err := args.string("prefix")
+				if err != nil {
+					return nil, err
+				}
+				entries, err := ctx.svc.ListMetadata(domain.MetadataListOptions{Prefix: prefix})
+				return listOf(entries), err
+			},
+		},
+		"metadataEntry": {
+			typ:  "Metadata",
+			args: []string{"id", "path"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				id, err := args.string("id")
+				if err != nil {
+					return nil, err
+				}
+				if id != "" {
+					return ctx.svc.GetMetadata(id)
+				}
+				path, err := args.requiredString("path")
+				if err != nil {
+					return nil, domain.InvalidInputError("argument id or path is required", nil)
+				}
+				return ctx.svc.GetMetadataByPath(path)
+			},
+		},
+	}},
+
+	"Mutation": {name: "Mutation", fields: map[string]*gqlField{
+		"createProject": {
+			typ:  "Project",
+			args: []string{"input"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				var req domain.CreateProjectRequest
+				if err := args.input("input", &req); err != nil {
+					return nil, err
+				}
+				return ctx.svc.CreateProject(req)
+			},
+		},
+		"updateProject": {
+			typ:  "Project",
+			args: []string{"id", "input"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				id, err := args.requiredString("id")
+				if err != nil {
+					return nil, err
+				}
+				var req domain.UpdateProjectRequest
+				if err := args.input("input", &req); err != nil {
+					return nil, err
+				}
+				return ctx.svc.UpdateProject(id, req)
+			},
+		},
+		"deleteProject": {
+			args: []string{"id", "cascade"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				id, err := args.requiredString("id")
+				if err != nil {
+					return nil, err
+				}
+				cascade, err := args.bool("cascade")
+				if err != nil {
+					return nil, err
+				}
+				return true, ctx.svc.DeleteProject(id, domain.DeleteProjectOptions{Cascade: cascade})
+			},
+		},
+		"createInstance": {
+			typ:  "Instance",
+			args: []string{"input"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				var req domain.CreateInstanceRequest
+				if err := args.input("input", &req); err != nil {
+					return nil, err
+				}
+				return ctx.svc.CreateInstance(req)
+			},
+		},
+		"updateInstance": {
+			typ:  "Instance",
+			args: []string{"id", "input"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				id, err := args.requiredString("id")
+				if err != nil {
+					return nil, err
+				}
+				var req domain.UpdateInstanceRequest
+				if err := args.input("input", &req); err != nil {
+					return nil, err
+				}
+				return ctx.svc.UpdateInstance(id, req)
+			},
+		},
+		"startInstance": {typ: "Instance", args: []string{"id"}, resolve: setInstanceStatus(domain.StatusRunning)},
+		"stopInstance":  {typ: "Instance", args: []string{"id"}, resolve: setInstanceStatus(domain.StatusStopped)},
+		"deleteInstance": {
+			args: []string{"id"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				id, err := args.requiredString("id")
+				if err != nil {
+					return nil, err
+				}
+				return true, ctx.svc.DeleteInstance(id)
+			},
+		},
+		"createMetadata": {
+			typ:  "Metadata",
+			args: []string{"input"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				var req domain.CreateMetadataRequest
+				if err := args.input("input", &req); err != nil {
+					return nil, err
+				}
+				return ctx.svc.CreateMetadata(req)
+			},
+		},
+		"updateMetadata": {
+			typ:  "Metadata",
+			args: []string{"id", "input"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				id, err := args.requiredString("id")
+				if err != nil {
+					return nil, err
+				}
+				var req domain.UpdateMetadataRequest
+				if err := args.input("input", &req); err != nil {
+					return nil, err
+				}
+				return ctx.svc.UpdateMetadata(id, req)
+			},
+		},
+		"deleteMetadata": {
+			args: []string{"id"},
+			resolve: func(ctx *gqlContext, _ interface{}, args gqlArgs) (interface{}, error) {
+				id, err := args.requiredString("id")
+				if err != nil {
+					return nil, err
+				}
+				return true, ctx.svc.DeleteMetadata(id)
+			},
+		},
+	}},
+
+	"Project": newGQLType("Project", domain.Project{}, map[string]*gqlField{
+		"instances": {
+			typ:  "Instance",
+			args: instanceListArgs,
+			resolve: func(ctx *gqlContext, source interface{}, args gqlArgs) (interface{}, error) {
+				return listInstances(ctx, source.(*domain.Project).ID, args)
+			},
+		},
+	}),
+
+	"Instance": newGQLType("Instance", domain.Instance{}, map[string]*gqlField{
+		"project": {
+			typ: "Project",
+			resolve: func(ctx *gqlContext, source interface{}, _ gqlArgs) (interface{}, error) {
+				return ctx.svc.GetProject(source.(*domain.Instance).ProjectID)
+			},
+		},
+	}),
+
+	"Metadata": newGQLType("Metadata", domain.Metadata{}, nil),
+}
+
+// GraphQL handles GET and POST /graphql, running queries and mutations over
+// projects, instances and metadata. Like the REST API's full access routes,
+// it needs a caller with full access. GET requests can only run queries.
+func (h *Handler) GraphQL(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeGraphQLError(w, err)
+		return
+	}
+
+	var req graphQLRequest
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := decodeGraphQLVariables([]byte(variables), &req.Variables); err != nil {
+				h.writeGraphQLError(w, err)
+				return
+			}
+		}
+	} else {
+		body, err := h.readBody(w, r)
+		if err != nil {
+			h.writeGraphQLError(w, err)
+			return
+		}
+		if err := decodeGraphQLVariables(body, &req); err != nil {
+			h.writeGraphQLError(w, err)
+			return
+		}
+	}
+
+	doc, err := parseGraphQL(req.Query)
+	if err != nil {
+		h.writeGraphQLError(w, err)
+		return
+	}
+	op, err := doc.operation(req.OperationName)
+	if err == nil && op.kind == "mutation" && r.Method == http.MethodGet {
+		err = domain.InvalidInputError("mutations must be sent with POST", nil)
+	}
+	if err != nil {
+		h.writeGraphQLError(w, err)
+		return
+	}
+
+	ctx := &gqlContext{svc: h.service.WithActor(h.actor(r)), doc: doc}
+	if ctx.variables, err = op.coerceVariables(req.Variables); err != nil {
+		h.writeGraphQLError(w, err)
+		return
+	}
+
+	root := graphQLTypes["Query"]
+	if op.kind == "mutation" {
+		root = graphQLTypes["Mutation"]
+	}
+	if err := ctx.validate(root, op.selectionSet, map[string]bool{}); err != nil {
+		h.writeGraphQLError(w, err)
+		return
+	}
+
+	data := ctx.executeSelections(root, nil, op.selectionSet, nil)
+	h.writeJSON(w, http.StatusOK, graphQLResponse{Data: data, Errors: ctx.errors})
+}
+
+// decodeGraphQLVariables decodes JSON keeping numbers exact
+func decodeGraphQLVariables(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return domain.InvalidInputError("invalid JSON", map[string]interface{}{"reason": err.Error()})
+	}
+	return nil
+}
+
+// writeGraphQLError writes an error that kept a request from being executed
+func (h *Handler) writeGraphQLError(w http.ResponseWriter, err error) {
+	buf := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+	h.writeError(buf, err)
+
+	gqlErr := graphQLError{Message: err.Error()}
+	switch e := err.(type) {
+	case *domain.DirtError:
+		gqlErr.Message = e.Message
+		gqlErr.Extensions = map[string]interface{}{"code": e.Code, "reason": e.Reason, "retryable": e.Retryable}
+	case *gqlSyntaxError:
+		buf.status = http.StatusBadRequest
+		gqlErr.Message = "Syntax Error: " + e.message
+		gqlErr.Locations = []graphQLLocation{{Line: e.line, Column: e.column}}
+		gqlErr.Extensions = map[string]interface{}{"code": "GRAPHQL_PARSE_FAILED"}
+	case *gqlValidationError:
+		buf.status = http.StatusBadRequest
+		gqlErr.Message = e.message
+		gqlErr.Locations = []graphQLLocation{{Line: e.line, Column: e.column}}
+		gqlErr.Extensions = map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"}
+	}
+
+	h.writeJSON(w, buf.status, graphQLResponse{Errors: []graphQLError{gqlErr}})
+}
+
+// gqlValidationError is a document that does not fit the schema
+type gqlValidationError struct {
+	message      string
+	line, column int
+}
+
+func (e *gqlValidationError) Error() string { return e.message }
+
+// operation picks the operation to run: the one named, or the only one
+func (d *gqlDocument) operation(name string) (*gqlOperation, error) {
+	for _, op := range d.operations {
+		if name == "" && len(d.operations) == 1 || op.name == name && name != "" {
+			if op.kind == "subscription" {
+				return nil, domain.InvalidInputError("subscriptions are not supported", nil)
+			}
+			return op, nil
+		}
+	}
+	if name == "" {
+		return nil, domain.InvalidInputError("operationName is required for documents with several operations", nil)
+	}
+	return nil, domain.InvalidInputError(fmt.Sprintf("unknown operation %q", name), nil)
+}
+
+// coerceVariables applies the defaults of the operation's variables and
+// checks that non-null ones are given
+func (op *gqlOperation) coerceVariables(given map[string]interface{}) (map[string]interface{}, error) {
+	variables := map[string]interface{}{}
+	for _, v := range op.variables {
+		value, ok := given[v.name]
+		if !ok {
+			value = v.defaults
+		}
+		if value == nil && v.nonNull {
+			return nil, domain.InvalidInputError(fmt.Sprintf("variable $%s is required", v.name), nil)
+		}
+		variables[v.name] = value
+	}
+	return variables, nil
+}
+
+// validate checks a selection set against a type before anything runs, so a
+// mistake in a mutation's selection does not leave it half done. visiting
+// holds the fragments being expanded, which must not spread themselves.
+func (ctx *gqlContext) validate(t *gqlType, selections []gqlSelection, visiting map[string]bool) error {
+	for _, s := range selections {
+		fail := func(format string, args ...interface{}) error {
+			return &gqlValidationError{message: fmt.Sprintf(format, args...), line: s.line, column: s.column}
+		}
+
+		switch {
+		case s.fragment != "":
+			fragment, ok := ctx.doc.fragments[s.fragment]
+			if !ok {
+				return fail("Unknown fragment %q.", s.fragment)
+			}
+			if visiting[s.fragment] {
+				return fail("Fragment %q spreads itself.", s.fragment)
+			}
+			if fragment.typeCondition != t.name {
+				return fail("Fragment %q on %s cannot be spread on type %s.", s.fragment, fragment.typeCondition, t.name)
+			}
+			visiting[s.fragment] = true
+			err := ctx.validate(t, fragment.selectionSet, visiting)
+			delete(visiting, s.fragment)
+			if err != nil {
+				return err
+			}
+			continue
+		case s.inline:
+			if s.typeCondition != "" && s.typeCondition != t.name {
+				return fail("Inline fragment on %s cannot be spread on type %s.", s.typeCondition, t.name)
+			}
+			if err := ctx.validate(t, s.selectionSet, visiting); err != nil {
+				return err
+			}
+			continue
+		}
+
+		exists, object := t.hasField(s.name)
+		switch {
+		case !exists:
+			return fail("Cannot query field %q on type %q.", s.name, t.name)
+		case object && s.selectionSet == nil:
+			return fail("Field %q of type %q must have a selection of subfields.", s.name, t.fields[s.name].typ)
+		case !object && s.selectionSet != nil:
+			return fail("Field %q must not have a selection since it has no subfields.", s.name)
+		}
+
+		var known []string
+		if field, ok := t.fields[s.name]; ok {
+			known = field.args
+		}
+		for arg := range s.arguments {
+			if !containsString(known, arg) {
+				return fail("Unknown argument %q on field %q of type %q.", arg, s.name, t.name)
+			}
+		}
+
+		if object {
+			if err := ctx.validate(graphQLTypes[t.fields[s.name].typ], s.selectionSet, visiting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveValue replaces the variables in a value with theirs, and enums
+// with their names
+func (ctx *gqlContext) resolveValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case gqlVariableRef:
+		resolved, ok := ctx.variables[string(v)]
+		if !ok {
+			return nil, domain.InvalidInputError(fmt.Sprintf("variable $%s is not defined", v), nil)
+		}
+		return resolved, nil
+	case gqlEnum:
+		return string(v), nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := ctx.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return list, nil
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := ctx.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = resolved
+		}
+		return object, nil
+	default:
+		return value, nil
+	}
+}
+
+// included applies the @skip and @include directives of a selection
+func (ctx *gqlContext) included(directives []gqlDirective) (bool, error) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			continue
+		}
+		value, err := ctx.resolveValue(d.arguments["if"])
+		if err != nil {
+			return false, err
+		}
+		condition, ok := value.(bool)
+		if !ok {
+			return false, domain.InvalidInputError(fmt.Sprintf("@%s needs a boolean if argument", d.name), nil)
+		}
+		if condition == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// gqlFieldGroup is the selections of one response key, merged
+type gqlFieldGroup struct {
+	key        string
+	selections []gqlSelection
+}
+
+// collectFields flattens fragments into the fields of a selection set,
+// grouped by response key in order of first appearance
+func (ctx *gqlContext) collectFields(selections []gqlSelection, groups []gqlFieldGroup) ([]gqlFieldGroup, error) {
+	for _, s := range selections {
+		ok, err := ctx.included(s.directives)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		switch {
+		case s.fragment != "":
+			fragment := ctx.doc.fragments[s.fragment]
+			if ok, err := ctx.included(fragment.directives); err != nil || !ok {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if groups, err = ctx.collectFields(fragment.selectionSet, groups); err != nil {
+				return nil, err
+			}
+		case s.inline:
+			if groups, err = ctx.collectFields(s.selectionSet, groups); err != nil {
+				return nil, err
+			}
+		default:
+			merged := false
+			for i := range groups {
+				if groups[i].key == s.responseKey() {
+					groups[i].selections = append(groups[i].selections, s)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				groups = append(groups, gqlFieldGroup{key: s.responseKey(), selections: []gqlSelection{s}})
+			}
+		}
+	}
+	return groups, nil
+}
+
+// executeSelections resolves the selections on a source value of type t,
+// one after another. Fields that fail are null, with their error recorded.
+func (ctx *gqlContext) executeSelections(t *gqlType, source interface{}, selections []gqlSelection, path []interface{}) *gqlResult {
+	result := newGQLResult()
+
+	groups, err := ctx.collectFields(selections, nil)
+	if err != nil {
+		ctx.addError(err, selections[0], path)
+		return result
+	}
+
+	var scalars map[string]interface{}
+	for _, group := range groups {
+		s := group.selections[0]
+		fieldPath := append(append([]interface{}{}, path...), group.key)
+
+		if s.name == "__typename" {
+			result.set(group.key, t.name)
+			continue
+		}
+
+		field, ok := t.fields[s.name]
+		if !ok {
+			if scalars == nil {
+				scalars = scalarFields(source)
+			}
+			result.set(group.key, scalars[s.name])
+			continue
+		}
+
+		var err error
+		args := gqlArgs{}
+		for name, value := range s.arguments {
+			if args[name], err = ctx.resolveValue(value); err != nil {
+				break
+			}
+		}
+		var value interface{}
+		if err == nil {
+			value, err = field.resolve(ctx, source, args)
+		}
+		if err != nil {
+			ctx.addError(err, s, fieldPath)
+			result.set(group.key, nil)
+			continue
+		}
+
+		result.set(group.key, ctx.complete(field, value, group.selections, fieldPath))
+	}
+	return result
+}
+
+// complete turns a resolved value into its result: objects are executed
+// against their merged selections and lists item by item
+func (ctx *gqlContext) complete(field *gqlField, value interface{}, selections []gqlSelection, path []interface{}) interface{} {
+	if field.typ == "" {
+		return value
+	}
+
+	var subselections []gqlSelection
+	for _, s := range selections {
+		subselections = append(subselections, s.selectionSet...)
+	}
+
+	t := graphQLTypes[field.typ]
+	if list, ok := value.([]interface{}); ok {
+		results := make([]interface{}, len(list))
+		for i, item := range list {
+			results[i] = ctx.executeSelections(t, item, subselections, append(append([]interface{}{}, path...), i))
+		}
+		return results
+	}
+	if rv := reflect.ValueOf(value); !rv.IsValid() || rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	return ctx.executeSelections(t, value, subselections, path)
+}
+
+// scalarFields returns the camelCase JSON fields of a resource
+func scalarFields(source interface{}) map[string]interface{} {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil
+	}
+	value, err := decodeAny(data)
+	if err != nil {
+		return nil
+	}
+	fields, _ := convertKeys(value, snakeToCamel, func(name string) string { return name }).(map[string]interface{})
+	return fields
+}
+
+// addError records the error of a field
+func (ctx *gqlContext) addError(err error, s gqlSelection, path []interface{}) {
+	gqlErr := graphQLError{
+		Message:   err.Error(),
+		Locations: []graphQLLocation{{Line: s.line, Column: s.column}},
+		Path:      path,
+	}
+	if dirtErr, ok := domain.AsDirtError(err); ok {
+		gqlErr.Message = dirtErr.Message
+		gqlErr.Extensions = map[string]interface{}{"code": dirtErr.Code, "reason": dirtErr.Reason, "retryable": dirtErr.Retryable}
+		if len(dirtErr.Details) > 0 {
+			gqlErr.Extensions["details"] = dirtErr.Details
+		}
+	}
+	ctx.errors = append(ctx.errors, gqlErr)
+}
@@ -0,0 +1,684 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The GraphQL endpoint understands executable documents: operations with
+// variables, fields with aliases and arguments, fragments, and the @skip and
+// @include directives. Schema definitions are not accepted.
+
+// gqlDocument is a parsed GraphQL document
+type gqlDocument struct {
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+}
+
+// gqlOperation is a query or mutation
+type gqlOperation struct {
+	kind         string
+	name         string
+	variables    []gqlVariable
+	selectionSet []gqlSelection
+}
+
+// gqlVariable is a variable an operation declares
+type gqlVariable struct {
+	name     string
+	nonNull  bool
+	defaults interface{}
+}
+
+// gqlFragment is a named fragment
+type gqlFragment struct {
+	typeCondition string
+	directives    []gqlDirective
+	selectionSet  []gqlSelection
+}
+
+// gqlSelection is a field, a fragment spread (fragment set) or an inline
+// fragment (inline set)
+type gqlSelection struct {
+	alias        string
+	name         string
+	arguments    map[string]interface{}
+	directives   []gqlDirective
+	selectionSet []gqlSelection
+
+	fragment      string
+	inline        bool
+	typeCondition string
+
+	line, column int
+}
+
+// responseKey is the key a field's result is returned under
+func (s gqlSelection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// gqlDirective is a directive applied to a selection
+type gqlDirective struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+// gqlVariableRef is a variable used as a value, resolved on execution
+type gqlVariableRef string
+
+// gqlEnum is an enum value
+type gqlEnum string
+
+// gqlToken kinds
+const (
+	gqlEOF = iota
+	gqlPunct
+	gqlName
+	gqlInt
+	gqlFloat
+	gqlString
+)
+
+// gqlToken is a lexical token of a document
+type gqlToken struct {
+	kind         int
+	value        string
+	line, column int
+}
+
+// gqlSyntaxError is a document the parser rejects
+type gqlSyntaxError struct {
+	message      string
+	line, column int
+}
+
+func (e *gqlSyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d:%d: %s", e.line, e.column, e.message)
+}
+
+// gqlLexer splits a document into tokens
+type gqlLexer struct {
+	src          string
+	pos          int
+	line, column int
+}
+
+// next returns the next token, skipping whitespace, commas and comments
+func (l *gqlLexer) next() (gqlToken, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.pos++
+			l.line++
+			l.column = 1
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			l.advance(1)
+			continue
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+			continue
+		}
+		break
+	}
+
+	tok := gqlToken{line: l.line, column: l.column}
+	if l.pos >= len(l.src) {
+		tok.kind = gqlEOF
+		return tok, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		tok.kind, tok.value = gqlPunct, "..."
+		l.advance(3)
+	case strings.ContainsRune("!$()&:=@[]{}|", rune(c)):
+		tok.kind, tok.value = gqlPunct, string(c)
+		l.advance(1)
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+		tok.kind, tok.value = gqlName, l.src[start:l.pos]
+	case c == '-' || isDigit(c):
+		return l.number(tok)
+	case c == '"':
+		return l.string(tok)
+	default:
+		return tok, &gqlSyntaxError{message: fmt.Sprintf("unexpected character %q", c), line: l.line, column: l.column}
+	}
+	return tok, nil
+}
+
+func (l *gqlLexer) advance(n int) {
+	l.pos += n
+	l.column += n
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// number lexes an Int or Float
+func (l *gqlLexer) number(tok gqlToken) (gqlToken, error) {
+	start := l.pos
+	tok.kind = gqlInt
+	if l.src[l.pos] == '-' {
+		l.advance(1)
+	}
+	digits := func() {
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.advance(1)
+		}
+	}
+	digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		tok.kind = gqlFloat
+		l.advance(1)
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		tok.kind = gqlFloat
+		l.advance(1)
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.advance(1)
+		}
+		digits()
+	}
+	tok.value = l.src[start:l.pos]
+	if _, err := strconv.ParseFloat(tok.value, 64); err != nil {
+		return tok, &gqlSyntaxError{message: fmt.Sprintf("invalid number %q", tok.value), line: tok.line, column: tok.column}
+	}
+	return tok, nil
+}
+
+// string lexes a string, or a block string with its indentation removed
+func (l *gqlLexer) string(tok gqlToken) (gqlToken, error) {
+	tok.kind = gqlString
+	unterminated := &gqlSyntaxError{message: "unterminated string", line: tok.line, column: tok.column}
+
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return tok, unterminated
+		}
+		raw := l.src[l.pos+3 : l.pos+3+end]
+		for _, c := range l.src[l.pos : l.pos+6+end] {
+			if c == '\n' {
+				l.line++
+				l.column = 0
+			}
+			l.column++
+		}
+		l.pos += 6 + end
+		tok.value = blockString(raw)
+		return tok, nil
+	}
+
+	start := l.pos
+	l.advance(1)
+	for l.pos < len(l.src) && l.src[l.pos] != '"' {
+		switch l.src[l.pos] {
+		case '\n':
+			return tok, unterminated
+		case '\\':
+			l.advance(2)
+		default:
+			_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			l.advance(size)
+		}
+	}
+	if l.pos >= len(l.src) {
+		return tok, unterminated
+	}
+	l.advance(1)
+
+	// GraphQL string escapes are a subset of JSON's
+	if err := json.Unmarshal([]byte(l.src[start:l.pos]), &tok.value); err != nil {
+		return tok, &gqlSyntaxError{message: "invalid string escape", line: tok.line, column: tok.column}
+	}
+	return tok, nil
+}
+
+// blockString removes the common indentation and blank first and last lines
+// of a block string
+func blockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, `\"""`, `"""`), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gqlParser parses a document from its tokens
+type gqlParser struct {
+	lexer *gqlLexer
+	tok   gqlToken
+}
+
+// parseGraphQL parses an executable GraphQL document
+func parseGraphQL(src string) (*gqlDocument, error) {
+	p := &gqlParser{lexer: &gqlLexer{src: src, line: 1, column: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &gqlDocument{fragments: map[string]*gqlFragment{}}
+	for p.tok.kind != gqlEOF {
+		switch {
+		case p.peek(gqlPunct, "{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &gqlOperation{kind: "query", selectionSet: selections})
+		case p.peek(gqlName, "query"), p.peek(gqlName, "mutation"), p.peek(gqlName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peek(gqlName, "fragment"):
+			name, fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[name]; ok {
+				return nil, p.errorf("fragment %q is defined more than once", name)
+			}
+			doc.fragments[name] = fragment
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, p.errorf("the document has no operations")
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// peek reports whether the current token is the given one
+func (p *gqlParser) peek(kind int, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+// skip consumes the current token if it is the given punctuator
+func (p *gqlParser) skip(value string) (bool, error) {
+	if !p.peek(gqlPunct, value) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+// expect consumes the given punctuator
+func (p *gqlParser) expect(value string) error {
+	if !p.peek(gqlPunct, value) {
+		return p.errorf("expected %q, found %s", value, p.describe())
+	}
+	return p.advance()
+}
+
+// name consumes a name
+func (p *gqlParser) name() (string, error) {
+	if p.tok.kind != gqlName {
+		return "", p.errorf("expected a name, found %s", p.describe())
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *gqlParser) describe() string {
+	if p.tok.kind == gqlEOF {
+		return "the end of the document"
+	}
+	return strconv.Quote(p.tok.value)
+}
+
+func (p *gqlParser) unexpected() error {
+	return p.errorf("unexpected %s", p.describe())
+}
+
+func (p *gqlParser) errorf(format string, args ...interface{}) error {
+	return &gqlSyntaxError{message: fmt.Sprintf(format, args...), line: p.tok.line, column: p.tok.column}
+}
+
+// operation parses an operation with its type keyword
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	op := &gqlOperation{kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == gqlName {
+		op.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(gqlPunct, ")") {
+			variable, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.variables = append(op.variables, variable)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selectionSet = selections
+	return op, nil
+}
+
+// variableDefinition parses "$name: Type = default"
+func (p *gqlParser) variableDefinition() (gqlVariable, error) {
+	var variable gqlVariable
+	if err := p.expect("$"); err != nil {
+		return variable, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return variable, err
+	}
+	variable.name = name
+	if err := p.expect(":"); err != nil {
+		return variable, err
+	}
+	if variable.nonNull, err = p.typeRef(); err != nil {
+		return variable, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return variable, err
+	} else if ok {
+		if variable.defaults, err = p.value(true); err != nil {
+			return variable, err
+		}
+	}
+	_, err = p.directives()
+	return variable, err
+}
+
+// typeRef parses a type reference, reporting whether it is non-null. The
+// type itself is not checked: arguments are checked by their resolvers.
+func (p *gqlParser) typeRef() (bool, error) {
+	if ok, err := p.skip("["); err != nil {
+		return false, err
+	} else if ok {
+		if _, err := p.typeRef(); err != nil {
+			return false, err
+		}
+		if err := p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.name(); err != nil {
+		return false, err
+	}
+	return p.skip("!")
+}
+
+// fragment parses "fragment Name on Type { ... }"
+func (p *gqlParser) fragment() (string, *gqlFragment, error) {
+	if err := p.advance(); err != nil {
+		return "", nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+	if !p.peek(gqlName, "on") {
+		return "", nil, p.errorf("expected \"on\", found %s", p.describe())
+	}
+	if err := p.advance(); err != nil {
+		return "", nil, err
+	}
+
+	fragment := &gqlFragment{}
+	if fragment.typeCondition, err = p.name(); err != nil {
+		return "", nil, err
+	}
+	if fragment.directives, err = p.directives(); err != nil {
+		return "", nil, err
+	}
+	if fragment.selectionSet, err = p.selectionSet(); err != nil {
+		return "", nil, err
+	}
+	return name, fragment, nil
+}
+
+// selectionSet parses "{ selection ... }"
+func (p *gqlParser) selectionSet() ([]gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []gqlSelection
+	for !p.peek(gqlPunct, "}") {
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, p.errorf("selection sets cannot be empty")
+	}
+	return selections, p.advance()
+}
+
+// selection parses a field, a fragment spread or an inline fragment
+func (p *gqlParser) selection() (gqlSelection, error) {
+	s := gqlSelection{line: p.tok.line, column: p.tok.column}
+	var err error
+
+	if ok, err := p.skip("..."); err != nil {
+		return s, err
+	} else if ok {
+		switch {
+		case p.peek(gqlName, "on"):
+			if err := p.advance(); err != nil {
+				return s, err
+			}
+			if s.typeCondition, err = p.name(); err != nil {
+				return s, err
+			}
+			s.inline = true
+		case p.tok.kind == gqlName:
+			if s.fragment, err = p.name(); err != nil {
+				return s, err
+			}
+		default:
+			s.inline = true
+		}
+		if s.directives, err = p.directives(); err != nil {
+			return s, err
+		}
+		if s.inline {
+			s.selectionSet, err = p.selectionSet()
+		}
+		return s, err
+	}
+
+	if s.name, err = p.name(); err != nil {
+		return s, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return s, err
+	} else if ok {
+		s.alias = s.name
+		if s.name, err = p.name(); err != nil {
+			return s, err
+		}
+	}
+	if s.arguments, err = p.arguments(); err != nil {
+		return s, err
+	}
+	if s.directives, err = p.directives(); err != nil {
+		return s, err
+	}
+	if p.peek(gqlPunct, "{") {
+		s.selectionSet, err = p.selectionSet()
+	}
+	return s, err
+}
+
+// arguments parses "(name: value ...)", if present
+func (p *gqlParser) arguments() (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if ok, err := p.skip("("); err != nil || !ok {
+		return args, err
+	}
+
+	for !p.peek(gqlPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if _, ok := args[name]; ok {
+			return nil, p.errorf("argument %q is given more than once", name)
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+// directives parses "@name(args) ...", if present
+func (p *gqlParser) directives() ([]gqlDirective, error) {
+	var directives []gqlDirective
+	for p.peek(gqlPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, gqlDirective{name: name, arguments: args})
+	}
+	return directives, nil
+}
+
+// value parses a value. Constant values, such as variable defaults, cannot
+// use variables. Numbers are kept as json.Number, like decoded variables.
+func (p *gqlParser) value(constant bool) (interface{}, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == gqlPunct && tok.value == "$" && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return gqlVariableRef(name), err
+	case tok.kind == gqlInt, tok.kind == gqlFloat:
+		return json.Number(tok.value), p.advance()
+	case tok.kind == gqlString:
+		return tok.value, p.advance()
+	case tok.kind == gqlName:
+		var value interface{}
+		switch tok.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			value = gqlEnum(tok.value)
+		}
+		return value, p.advance()
+	case tok.kind == gqlPunct && tok.value == "[":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.peek(gqlPunct, "]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, p.advance()
+	case tok.kind == gqlPunct && tok.value == "{":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		object := map[string]interface{}{}
+		for !p.peek(gqlPunct, "}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return object, p.advance()
+	default:
+		return nil, p.unexpected()
+	}
+}
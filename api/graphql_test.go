@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func graphQLDo(t *testing.T, router http.Handler, query string, variables map[string]interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	body, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body))))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc), w.Body.String())
+	return w, doc
+}
+
+func TestGraphQL_MutationsAndNestedQueries(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	w, doc := graphQLDo(t, router, `mutation($name: String!) {
+		createProject(input: {name: $name, labels: {cost_center: "a1"}}) { id name labels }
+	}`, map[string]interface{}{"name": "web"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Nil(t, doc["errors"], w.Body.String())
+	project := doc["data"].(map[string]interface{})["createProject"].(map[string]interface{})
+	assert.Equal(t, "web", project["name"])
+	assert.Equal(t, map[string]interface{}{"cost_center": "a1"}, project["labels"])
+	projectID := project["id"].(string)
+
+	_, doc = graphQLDo(t, router, `mutation {
+		vm: createInstance(input: {projectId: "`+projectID+`", name: "vm", cpu: 1, memoryMb: 512, image: "ubuntu"}) { id status }
+	}`, nil)
+	require.Nil(t, doc["errors"], doc)
+	instanceID := doc["data"].(map[string]interface{})["vm"].(map[string]interface{})["id"].(string)
+
+	_, doc = graphQLDo(t, router, `mutation { stopInstance(id: "`+instanceID+`") { status } }`, nil)
+	require.Nil(t, doc["errors"], doc)
+
+	w, doc = graphQLDo(t, router, `
+		query Projects($withProject: Boolean = false) {
+			projects { ...summary instances(status: "stopped") { name memoryMb project @include(if: $withProject) { name } } }
+		}
+		fragment summary on Project { __typename name }`, map[string]interface{}{"withProject": true})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Nil(t, doc["errors"], doc)
+	assert.JSONEq(t, `{"data":{"projects":[{
+		"__typename": "Project",
+		"name": "web",
+		"instances": [{"name": "vm", "memoryMb": 512, "project": {"name": "web"}}]
+	}]}}`, w.Body.String())
+	assert.True(t, strings.HasPrefix(w.Body.String(), `{"data":{"projects":[{"__typename":"Project","name":"web"`), "fields come in selection order")
+
+	// Queries can also be sent with GET
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(`{ instance(id: "`+instanceID+`") { name } }`), nil))
+	assert.JSONEq(t, `{"data":{"instance":{"name":"vm"}}}`, w.Body.String())
+}
+
+func TestGraphQL_Errors(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	w, doc := graphQLDo(t, router, `{ project(id: "missing") { name } projects { name } }`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{"project": nil, "projects": []interface{}{}}, doc["data"])
+	errs := doc["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, []interface{}{"project"}, errs[0].(map[string]interface{})["path"])
+	assert.Equal(t, "NOT_FOUND", errs[0].(map[string]interface{})["extensions"].(map[string]interface{})["code"])
+
+	w, doc = graphQLDo(t, router, `mutation { createProject(input: {name: "web"}) { nmae } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotContains(t, doc, "data")
+	_, doc = graphQLDo(t, router, `{ projects { name } }`, nil)
+	assert.Equal(t, []interface{}{}, doc["data"].(map[string]interface{})["projects"], "an invalid mutation does not run")
+
+	w, doc = graphQLDo(t, router, `{ projects { name }`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	errs = doc["errors"].([]interface{})
+	assert.Equal(t, "GRAPHQL_PARSE_FAILED", errs[0].(map[string]interface{})["extensions"].(map[string]interface{})["code"])
+}
+
+func TestParseGraphQL(t *testing.T) {
+	doc, err := parseGraphQL(`
+		# Comments and commas are ignored
+		query Q($id: ID! = "x", $tags: [String!]) {
+			a: project(id: $id) { name, ... on Project { id } }
+			b: metadataEntry(path: """
+				/config
+			""") { value }
+		}`)
+	require.NoError(t, err)
+	require.Len(t, doc.operations, 1)
+
+	op := doc.operations[0]
+	assert.Equal(t, "Q", op.name)
+	assert.Equal(t, []gqlVariable{{name: "id", nonNull: true, defaults: "x"}, {name: "tags"}}, op.variables)
+	require.Len(t, op.selectionSet, 2)
+	assert.Equal(t, "a", op.selectionSet[0].alias)
+	assert.Equal(t, gqlVariableRef("id"), op.selectionSet[0].arguments["id"])
+	assert.True(t, op.selectionSet[0].selectionSet[1].inline)
+	assert.Equal(t, "/config", op.selectionSet[1].arguments["path"])
+
+	_, err = parseGraphQL(`{ project(id: "x) { name } }`)
+	assert.Error(t, err)
+}
@@ -1,9 +1,14 @@
 package api
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"log"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/hypertf/dirtcloud-server/domain"
@@ -16,20 +21,236 @@ type Handler struct {
 	service      *service.Service
 	chaosService *chaos.ChaosService
 	token        string
+	adminToken   string
+	jwt          *JWTVerifier
+	maxBodyBytes int64
+	limiter      *inflightLimiter
+	maintenance  *maintenanceWindow
+
+	// trustedProxies may set the X-Forwarded-* headers of requests
+	trustedProxies []*net.IPNet
+
+	// cors is the CORS policy in effect
+	cors *corsPolicy
+
+	// lockout tracks failed authentication per source address
+	lockout *authLockout
+
+	// signingKeys maps access keys to the secrets requests are signed with
+	signingKeys      map[string]string
+	signingClockSkew time.Duration
+
+	// requestLogSize is how many requests the request log keeps
+	requestLogSize int
+
+	// writeTimeout is the server's response write timeout, which injected
+	// latency is fitted to
+	writeTimeout time.Duration
+
+	// mirror receives copies of API requests; nil disables mirroring
+	mirror *Mirror
+
+	// federation places the server in a federation of regions; nil when
+	// it serves a region of its own
+	federation *Federation
+
+	// cacheStats reports repository cache reads for /metrics
+	cacheStats func() []domain.CacheStats
+
+	webInsecureCookies bool
+
+	// jsonAPI formats every /v1 response as a JSON:API document
+	jsonAPI bool
+
+	// translator words error messages for the languages clients accept;
+	// nil keeps the default wording
+	translator domain.Translator
+
+	// errorHelpURL, when set, is the help URL of errors, with {reason}
+	// replaced by their reason
+	errorHelpURL string
+
+	// debugged is set on the copies of the handler serving requests with
+	// X-Dirt-Debug, whose service records its SQL statements
+	debugged bool
+
+	// replicated is set on the copies of the handler serving requests with
+	// X-Dirt-Read-Preference: replica, whose service reads the replica
+	replicated bool
+
+	consolePollInterval     time.Duration
+	eventStreamPollInterval time.Duration
+}
+
+// Config holds HTTP handler configuration
+type Config struct {
+	Token        string
+	MaxBodyBytes int64
+
+	// AdminToken, when set, is the only credential accepted by the admin
+	// and chaos routes. It is also accepted everywhere Token is.
+	AdminToken string
+
+	// JWT, when set, also accepts JWT bearer tokens it validates. Callers
+	// must then authenticate even when no Token is configured.
+	JWT *JWTVerifier
+
+	// SigningKeys maps access keys to secrets for HMAC request signing.
+	// Callers must then authenticate even when no Token is configured.
+	SigningKeys map[string]string
+
+	// SigningClockSkew is how far the time a request was signed may be from
+	// the server clock; defaults to DefaultSigningClockSkew
+	SigningClockSkew time.Duration
+
+	// MaxAuthFailures failed authentication attempts from one address
+	// within AuthFailureWindow lock it out for AuthLockout; 0 disables
+	// lockout, though failures are still counted and recorded as events
+	MaxAuthFailures   int
+	AuthFailureWindow time.Duration
+	AuthLockout       time.Duration
+
+	// TrustedProxies are the proxies whose X-Forwarded-For,
+	// X-Forwarded-Proto and X-Forwarded-Host headers are believed
+	TrustedProxies []*net.IPNet
+
+	// CORS is the initial CORS policy, with fields left out taking their
+	// defaults; nil uses DefaultCORSPolicy
+	CORS *domain.CORSPolicy
+
+	// In-flight request limits for the API; 0 means unlimited
+	MaxInFlight         int
+	MaxInFlightPerToken int
+	MaxInFlightPerRoute int
+
+	// RequestLogSize is how many recent API requests are kept in the
+	// request log; 0 disables request logging
+	RequestLogSize int
+
+	// Mirror receives sanitized copies of API requests and their
+	// responses; nil disables mirroring
+	Mirror *Mirror
+
+	// Federation, when set, registers the resources the server creates
+	// with the other regions of a federation and refuses requests for
+	// resources living in them
+	Federation *Federation
+
+	// CacheStats reports the reads of the repository cache, if one is in
+	// use, for /metrics
+	CacheStats func() []domain.CacheStats
+
+	// WebInsecureCookies serves web console session cookies without the
+	// Secure attribute
+	WebInsecureCookies bool
+
+	// JSONAPI formats every /v1 response as a JSON:API document, not only
+	// those of requests accepting application/vnd.api+json
+	JSONAPI bool
+
+	// Translator, when set, words error messages for the languages
+	// requests accept in their Accept-Language header
+	Translator domain.Translator
+
+	// ErrorHelpURL points the help_url of errors at a deployment's own
+	// documentation, with {reason} replaced by the error's reason. Empty
+	// keeps the help served under /v1/errors.
+	ErrorHelpURL string
+
+	// WriteTimeout is the HTTP server's write timeout. Chaos latency that
+	// would outlast it extends the response's deadline or is capped; 0
+	// means responses have no deadline.
+	WriteTimeout time.Duration
 }
 
 // NewHandler creates a new HTTP handler
-func NewHandler(svc *service.Service, chaosService *chaos.ChaosService, token string) *Handler {
+func NewHandler(svc *service.Service, chaosService *chaos.ChaosService, config Config) *Handler {
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if config.SigningClockSkew <= 0 {
+		config.SigningClockSkew = DefaultSigningClockSkew
+	}
+	cors := DefaultCORSPolicy()
+	if config.CORS != nil {
+		cors = withCORSDefaults(*config.CORS)
+	}
+
 	return &Handler{
 		service:      svc,
 		chaosService: chaosService,
-		token:        token,
+		token:        config.Token,
+		adminToken:   config.AdminToken,
+		jwt:          config.JWT,
+		maxBodyBytes: config.MaxBodyBytes,
+		limiter:      newInflightLimiter(config.MaxInFlight, config.MaxInFlightPerToken, config.MaxInFlightPerRoute),
+		maintenance:  &maintenanceWindow{},
+
+		trustedProxies: config.TrustedProxies,
+		cors:           &corsPolicy{policy: cors},
+		lockout:        newAuthLockout(config.MaxAuthFailures, config.AuthFailureWindow, config.AuthLockout),
+
+		signingKeys:      config.SigningKeys,
+		signingClockSkew: config.SigningClockSkew,
+
+		requestLogSize: config.RequestLogSize,
+		mirror:         config.Mirror,
+		federation:     config.Federation,
+		writeTimeout:   config.WriteTimeout,
+		cacheStats:     config.CacheStats,
+
+		webInsecureCookies: config.WebInsecureCookies,
+		jsonAPI:            config.JSONAPI,
+		translator:         config.Translator,
+		errorHelpURL:       config.ErrorHelpURL,
+
+		consolePollInterval:     defaultConsolePollInterval,
+		eventStreamPollInterval: defaultEventStreamPollInterval,
 	}
 }
 
-// authenticate checks bearer token authentication
+// authenticate checks that the caller has full access. IAM principals are
+// only granted access to project resources, so they are denied here.
 func (h *Handler) authenticate(r *http.Request) error {
-	if h.token == "" {
+	member, err := h.principal(r)
+	if err != nil {
+		return err
+	}
+	if member != "" {
+		return domain.PermissionDeniedError(member+" may only access project resources", map[string]interface{}{
+			"member": member,
+		})
+	}
+	return nil
+}
+
+// authenticateAdmin checks that the caller may use the admin and chaos
+// routes. Once an admin token is configured, the API token no longer grants
+// access to them.
+func (h *Handler) authenticateAdmin(r *http.Request) error {
+	if h.adminToken == "" {
+		return h.authenticate(r)
+	}
+	if h.isAdminToken(bearerToken(r)) {
+		return nil
+	}
+	if bearerToken(r) == "" && signedCaller(r) == nil {
+		return domain.UnauthorizedError("missing authorization header")
+	}
+	if err := h.authenticate(r); err != nil && !domain.IsPermissionDenied(err) {
+		return err
+	}
+	return domain.PermissionDeniedError("the admin token is required", nil)
+}
+
+// isAdminToken reports whether token is the configured admin token
+func (h *Handler) isAdminToken(token string) bool {
+	return h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
+// checkToken checks bearer token authentication
+func (h *Handler) checkToken(r *http.Request) error {
+	if h.token == "" && h.jwt == nil && len(h.signingKeys) == 0 {
 		return nil // No authentication required
 	}
 
@@ -43,27 +264,36 @@ func (h *Handler) authenticate(r *http.Request) error {
 		return domain.UnauthorizedError("invalid authorization header format")
 	}
 
-	if parts[1] != h.token {
+	if parts[1] != h.token && !h.isAdminToken(parts[1]) {
 		return domain.UnauthorizedError("invalid token")
 	}
 
 	return nil
 }
 
-// writeError writes a domain error as JSON response
+// writeError writes a domain error as JSON response. Errors that are not
+// domain errors, and the causes domain errors wrap, are only logged, so that
+// SQL and other internal details never reach clients.
 func (h *Handler) writeError(w http.ResponseWriter, err error) {
 	var statusCode int
-	var dirtErr *domain.DirtError
+	var forced *chaos.ForcedError
 
-	if de, ok := err.(*domain.DirtError); ok {
-		dirtErr = de
-		switch de.Code {
+	if errors.As(err, &forced) {
+		h.writeForcedError(w, forced)
+		return
+	}
+
+	dirtErr, ok := domain.AsDirtError(err)
+	if ok {
+		switch dirtErr.Code {
 		case domain.ErrorCodeNotFound:
 			statusCode = http.StatusNotFound
 		case domain.ErrorCodeAlreadyExists:
 			statusCode = http.StatusConflict
 		case domain.ErrorCodeInvalidInput:
 			statusCode = http.StatusBadRequest
+		case domain.ErrorCodeImmutableField:
+			statusCode = http.StatusBadRequest
 		case domain.ErrorCodeForeignKeyViolation:
 			statusCode = http.StatusBadRequest
 		case domain.ErrorCodeUnauthorized:
@@ -72,12 +302,35 @@ func (h *Handler) writeError(w http.ResponseWriter, err error) {
 			statusCode = http.StatusTooManyRequests
 		case domain.ErrorCodeServiceUnavailable:
 			statusCode = http.StatusServiceUnavailable
+		case domain.ErrorCodeResourceExhausted:
+			statusCode = http.StatusServiceUnavailable
+		case domain.ErrorCodeQuotaExceeded:
+			statusCode = http.StatusForbidden
+		case domain.ErrorCodePermissionDenied:
+			statusCode = http.StatusForbidden
+		case domain.ErrorCodeFailedPrecondition:
+			statusCode = http.StatusConflict
+		case domain.ErrorCodeAmbiguousMatch:
+			statusCode = http.StatusConflict
+		case domain.ErrorCodeDeadlineExceeded:
+			statusCode = http.StatusGatewayTimeout
+		case domain.ErrorCodeWrongRegion:
+			statusCode = http.StatusMisdirectedRequest
 		default:
 			statusCode = http.StatusInternalServerError
 		}
 	} else {
 		statusCode = http.StatusInternalServerError
-		dirtErr = domain.InternalError(err.Error())
+		dirtErr = domain.InternalError("internal server error").WithCause(err)
+	}
+	if statusCode == http.StatusInternalServerError && errors.Unwrap(dirtErr) != nil {
+		log.Printf("Internal error: %v", dirtErr)
+	}
+	dirtErr = h.localize(w, dirtErr)
+	if h.errorHelpURL != "" && dirtErr.Reason != "" {
+		withHelp := *dirtErr
+		withHelp.HelpURL = strings.ReplaceAll(h.errorHelpURL, "{reason}", dirtErr.Reason)
+		dirtErr = &withHelp
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -85,6 +338,18 @@ func (h *Handler) writeError(w http.ResponseWriter, err error) {
 	json.NewEncoder(w).Encode(dirtErr)
 }
 
+// writeForcedError writes an error response forced by a client's chaos headers
+func (h *Handler) writeForcedError(w http.ResponseWriter, forced *chaos.ForcedError) {
+	if forced.Body == "" {
+		h.writeJSON(w, forced.Status, forced.Err)
+		return
+	}
+
+	w.Header().Set("Content-Type", forced.ContentType())
+	w.WriteHeader(forced.Status)
+	w.Write([]byte(forced.Body))
+}
+
 // writeJSON writes a JSON response
 func (h *Handler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -114,8 +379,13 @@ func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req domain.CreateProjectRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.checkChaosProfile(req.ChaosProfile); err != nil {
+		h.writeError(w, err)
 		return
 	}
 
@@ -125,23 +395,33 @@ func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, project.ID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
 	h.writeJSON(w, http.StatusCreated, project)
 }
 
-// GetProject handles GET /v1/projects/{id}
+// GetProject handles GET /v1/projects/{id}. The project can also be addressed
+// by name.
 func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+	vars := mux.Vars(r)
+	id, err := h.service.ResolveProjectID(vars["id"])
+	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "GET"); err != nil {
+	if err := h.authorizeProject(r, id, domain.RoleViewer); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, id), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
 
 	project, err := h.service.GetProject(id)
 	if err != nil {
@@ -149,12 +429,13 @@ func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, project)
+	h.writeResource(w, r, project, project.UpdatedAt)
 }
 
 // ListProjects handles GET /v1/projects
 func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
 		h.writeError(w, err)
 		return
 	}
@@ -164,40 +445,91 @@ func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query := r.URL.Query()
 	opts := domain.ProjectListOptions{
-		Name: r.URL.Query().Get("name"),
+		Name:   query.Get("name"),
+		Sort:   parseSort(query.Get("sort")),
+		Fields: splitList(query.Get("fields")),
 	}
 
-	projects, err := h.service.ListProjects(opts)
+	var v domain.FieldViolations
+	stream := parseBoolParam(&v, query.Get("stream"), "stream")
+	opts.Limit, opts.PageToken = parsePageParams(&v, query)
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	loadOpts := opts
+	loadOpts.Fields = pageFields(opts.Fields, opts.Limit, opts.Sort)
+
+	if stream {
+		streamList(h, w, r, opts.Fields, func(emit func(*domain.Project) error) error {
+			return h.service.StreamProjects(loadOpts, func(project *domain.Project) error {
+				if visible != nil {
+					if ok, err := visible(project.ID); err != nil || !ok {
+						return err
+					}
+				}
+				return emit(project)
+			})
+		})
+		return
+	}
+
+	projects, err := h.service.ListProjects(loadOpts)
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
+	setNextPageToken(w, projects, opts.Limit, opts.Sort)
+
+	if visible != nil {
+		var allowed []*domain.Project
+		for _, project := range projects {
+			ok, err := visible(project.ID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, project)
+			}
+		}
+		projects = allowed
+	}
 
-	h.writeJSON(w, http.StatusOK, projects)
+	h.writeList(w, r, projects, opts.Fields)
 }
 
 // UpdateProject handles PATCH /v1/projects/{id}
 func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeProject(r, id, domain.RoleAdmin); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "PATCH"); err != nil {
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, id), r, "PATCH"); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-
 	var req domain.UpdateProjectRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
 		return
 	}
 
+	if req.ChaosProfile != nil {
+		if err := h.checkChaosProfile(*req.ChaosProfile); err != nil {
+			h.writeError(w, err)
+			return
+		}
+	}
+
 	project, err := h.service.UpdateProject(id, req)
 	if err != nil {
 		h.writeError(w, err)
@@ -207,22 +539,32 @@ func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusOK, project)
 }
 
-// DeleteProject handles DELETE /v1/projects/{id}
+// DeleteProject handles DELETE /v1/projects/{id}. With ?cascade=true the
+// project's instances and autoscaling groups are deleted along with it.
 func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeProject(r, id, domain.RoleAdmin); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "DELETE"); err != nil {
+	var v domain.FieldViolations
+	opts := domain.DeleteProjectOptions{
+		Cascade: parseBoolParam(&v, r.URL.Query().Get("cascade"), "cascade"),
+	}
+	if err := v.Err(); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, id), r, "DELETE"); err != nil {
+		h.writeError(w, err)
+		return
+	}
 
-	err := h.service.DeleteProject(id)
+	err := h.service.WithActor(h.actor(r)).DeleteProject(id, opts)
 	if err != nil {
 		h.writeError(w, err)
 		return
@@ -235,45 +577,83 @@ func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 
 // CreateInstance handles POST /v1/instances
 func (h *Handler) CreateInstance(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+	member, err := h.principal(r)
+	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	// Decode first so chaos can follow the target project's profile
+	var req domain.CreateInstanceRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	var req domain.CreateInstanceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+	if err := h.requireRole(r, member, req.ProjectID, domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.projectChaos(r, req.ProjectID), r); err != nil {
+		h.writeError(w, err)
 		return
 	}
 
-	instance, err := h.service.CreateInstance(req)
+	svc := h.service.WithActor(h.actor(r))
+	instance, err := svc.CreateInstance(req)
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
+	if err := h.replayCreate(h.projectChaos(r, instance.ProjectID), r, func() error {
+		_, err := svc.WithReason(domain.ReasonChaos).CreateInstance(req)
+		return err
+	}); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, instance.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var defaults defaultsApplied
+	if req.Status == "" {
+		defaults.add("status", instance.Status)
+	}
+	if req.Zone == "" {
+		defaults.add("zone", instance.Zone)
+	}
+	if req.Class == "" {
+		defaults.add("class", instance.Class)
+	}
+	defaults.setHeader(w)
+
 	h.writeJSON(w, http.StatusCreated, instance)
 }
 
-// GetInstance handles GET /v1/instances/{id}
+// GetInstance handles GET /v1/instances/{id}. The instance can also be
+// addressed by name together with ?project= naming or identifying its project.
 func (h *Handler) GetInstance(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+	vars := mux.Vars(r)
+	id, err := h.service.ResolveInstanceID(vars["id"], r.URL.Query().Get("project"))
+	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	if err := h.authorizeInstance(r, id, domain.RoleViewer); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["id"]
+	if err := h.chaosService.ApplyInstancesChaos(h.instanceChaos(r, id), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
 
 	instance, err := h.service.GetInstance(id)
 	if err != nil {
@@ -281,58 +661,143 @@ func (h *Handler) GetInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, instance)
+	h.writeResource(w, r, instance, instance.UpdatedAt)
 }
 
 // ListInstances handles GET /v1/instances
 func (h *Handler) ListInstances(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	query := r.URL.Query()
+
+	if err := h.chaosService.ApplyInstancesChaos(h.projectChaos(r, query.Get("project_id")), r); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
 	opts := domain.InstanceListOptions{
-		ProjectID: r.URL.Query().Get("project_id"),
-		Name:      r.URL.Query().Get("name"),
-		Status:    r.URL.Query().Get("status"),
+		ProjectID: query.Get("project_id"),
+		Name:      query.Get("name"),
+		Status:    query.Get("status"),
+		Zone:      query.Get("zone"),
+		Class:     query.Get("class"),
+		Sort:      parseSort(query.Get("sort")),
+		Fields:    splitList(query.Get("fields")),
+
+		AutoscalingGroupID: query.Get("autoscaling_group_id"),
+	}
+
+	var v domain.FieldViolations
+	stream := parseBoolParam(&v, query.Get("stream"), "stream")
+	opts.Limit, opts.PageToken = parsePageParams(&v, query)
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
 	}
 
-	instances, err := h.service.ListInstances(opts)
+	// IAM principals only see instances of projects they can view, so the
+	// owning project is always loaded for them
+	loadOpts := opts
+	loadOpts.Fields = pageFields(opts.Fields, opts.Limit, opts.Sort)
+	if visible != nil && len(opts.Fields) > 0 {
+		loadOpts.Fields = append(append([]string{}, loadOpts.Fields...), "project_id")
+	}
+
+	if stream {
+		streamList(h, w, r, opts.Fields, func(emit func(*domain.Instance) error) error {
+			return h.service.StreamInstances(loadOpts, func(instance *domain.Instance) error {
+				if visible != nil {
+					if ok, err := visible(instance.ProjectID); err != nil || !ok {
+						return err
+					}
+				}
+				return emit(instance)
+			})
+		})
+		return
+	}
+
+	instances, err := h.service.ListInstances(loadOpts)
 	if err != nil {
 		h.writeError(w, err)
 		return
 	}
+	setNextPageToken(w, instances, opts.Limit, opts.Sort)
+
+	if visible != nil {
+		var allowed []*domain.Instance
+		for _, instance := range instances {
+			ok, err := visible(instance.ProjectID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, instance)
+			}
+		}
+		instances = allowed
+	}
 
-	h.writeJSON(w, http.StatusOK, instances)
+	h.writeList(w, r, instances, opts.Fields)
 }
 
 // UpdateInstance handles PATCH /v1/instances/{id}
 func (h *Handler) UpdateInstance(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeInstance(r, id, domain.RoleEditor); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	if err := h.chaosService.ApplyInstancesChaos(h.instanceChaos(r, id), r); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
+	var req domain.UpdateInstanceRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	instance, err := h.service.WithActor(h.actor(r)).UpdateInstance(id, req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, instance)
+}
+
+// ResizeInstance handles POST /v1/instances/{id}/resize
+func (h *Handler) ResizeInstance(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	var req domain.UpdateInstanceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+	if err := h.authorizeInstance(r, id, domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.instanceChaos(r, id), r); err != nil {
+		h.writeError(w, err)
 		return
 	}
 
-	instance, err := h.service.UpdateInstance(id, req)
+	var req domain.ResizeInstanceRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	instance, err := h.service.WithActor(h.actor(r)).ResizeInstance(id, req)
 	if err != nil {
 		h.writeError(w, err)
 		return
@@ -341,22 +806,52 @@ func (h *Handler) UpdateInstance(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusOK, instance)
 }
 
-// DeleteInstance handles DELETE /v1/instances/{id}
-func (h *Handler) DeleteInstance(w http.ResponseWriter, r *http.Request) {
-	if err := h.authenticate(r); err != nil {
+// DiffInstance handles POST /v1/instances/{id}:diff, comparing a desired
+// instance spec with the instance's current state
+func (h *Handler) DiffInstance(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.authorizeInstance(r, id, domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.instanceChaos(r, id), r); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
-	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+	var req domain.InstanceDiffRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
 		h.writeError(w, err)
 		return
 	}
 
+	diff, err := h.service.DiffInstance(id, req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, diff)
+}
+
+// DeleteInstance handles DELETE /v1/instances/{id}
+func (h *Handler) DeleteInstance(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	err := h.service.DeleteInstance(id)
+	if err := h.authorizeInstance(r, id, domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.instanceChaos(r, id), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	err := h.service.WithActor(h.actor(r)).DeleteInstance(id)
 	if err != nil {
 		h.writeError(w, err)
 		return
@@ -380,8 +875,8 @@ func (h *Handler) CreateMetadata(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req domain.CreateMetadataRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
 		return
 	}
 
@@ -391,6 +886,11 @@ func (h *Handler) CreateMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.chaosService.ApplyCreateChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
 	h.writeJSON(w, http.StatusCreated, metadata)
 }
 
@@ -401,7 +901,7 @@ func (h *Handler) GetMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+	if err := h.chaosService.ApplyMetadataChaos(resourceChaos(r), r); err != nil {
 		h.writeError(w, err)
 		return
 	}
@@ -415,7 +915,7 @@ func (h *Handler) GetMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, metadata)
+	h.writeResource(w, r, metadata, metadata.UpdatedAt)
 }
 
 // ListMetadata handles GET /v1/metadata with prefix query parameter
@@ -430,8 +930,25 @@ func (h *Handler) ListMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query := r.URL.Query()
 	opts := domain.MetadataListOptions{
-		Prefix: r.URL.Query().Get("prefix"),
+		Prefix: query.Get("prefix"),
+	}
+
+	var v domain.FieldViolations
+	stream := parseBoolParam(&v, query.Get("stream"), "stream")
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// Metadata is where lists get largest, hundreds of thousands of keys
+	// under a prefix, so it streams too
+	if stream {
+		streamList(h, w, r, nil, func(emit func(*domain.Metadata) error) error {
+			return h.service.StreamMetadata(opts, emit)
+		})
+		return
 	}
 
 	metadata, err := h.service.ListMetadata(opts)
@@ -443,6 +960,27 @@ func (h *Handler) ListMetadata(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusOK, metadata)
 }
 
+// GetMetadataUsage handles GET /v1/metadata:usage
+func (h *Handler) GetMetadataUsage(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	usage, err := h.service.GetMetadataUsage()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, usage)
+}
+
 // UpdateMetadata handles PATCH /v1/metadata/{id}
 func (h *Handler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
 	if err := h.authenticate(r); err != nil {
@@ -450,7 +988,7 @@ func (h *Handler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+	if err := h.chaosService.ApplyMetadataChaos(resourceChaos(r), r); err != nil {
 		h.writeError(w, err)
 		return
 	}
@@ -459,8 +997,8 @@ func (h *Handler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	var req domain.UpdateMetadataRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, domain.InvalidInputError("invalid JSON", nil))
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
 		return
 	}
 
@@ -480,7 +1018,7 @@ func (h *Handler) DeleteMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+	if err := h.chaosService.ApplyMetadataChaos(resourceChaos(r), r); err != nil {
 		h.writeError(w, err)
 		return
 	}
@@ -495,4 +1033,4 @@ func (h *Handler) DeleteMetadata(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// SetProbeFailures handles PUT /v1/admin/instances/{id}/probe-failures,
+// overriding how often the instance's health probes fail
+func (h *Handler) SetProbeFailures(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var failures domain.ProbeFailures
+	if err := h.decodeJSON(w, r, &failures); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	instance, err := h.service.SetProbeFailures(mux.Vars(r)["id"], failures)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, instance)
+}
+
+// ClearProbeFailures handles DELETE /v1/admin/instances/{id}/probe-failures,
+// returning the instance's health probes to the server's failure rate
+func (h *Handler) ClearProbeFailures(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	instance, err := h.service.ClearProbeFailures(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, instance)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeFailures(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "health"})
+	require.NoError(t, err)
+
+	body := `{"project_id":"` + project.ID + `","name":"vm","cpu":1,"memory_mb":512,"image":"ubuntu","auto_repair":true}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/instances", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var instance domain.Instance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instance))
+	assert.True(t, instance.AutoRepair)
+	assert.Equal(t, domain.HealthHealthy, instance.Health)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/admin/instances/"+instance.ID+"/probe-failures", strings.NewReader(`{"failure_rate": 1.5}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/admin/instances/"+instance.ID+"/probe-failures", strings.NewReader(`{"failure_rate": 1}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	for i := 0; i < 3; i++ {
+		_, err := h.service.CheckInstanceHealth()
+		require.NoError(t, err)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/instances/"+instance.ID, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/v1/admin/instances/"+instance.ID+"/probe-failures", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Organization handlers
+
+// CreateOrganization handles POST /v1/organizations
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "POST"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateOrganizationRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	org, err := h.service.CreateOrganization(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, org)
+}
+
+// GetOrganization handles GET /v1/organizations/{id}
+func (h *Handler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	org, err := h.service.GetOrganization(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, org, org.UpdatedAt)
+}
+
+// ListOrganizations handles GET /v1/organizations
+func (h *Handler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	orgs, err := h.service.ListOrganizations(domain.OrganizationListOptions{
+		Name: r.URL.Query().Get("name"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, orgs)
+}
+
+// UpdateOrganization handles PATCH /v1/organizations/{id}
+func (h *Handler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "PATCH"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateOrganizationRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	org, err := h.service.UpdateOrganization(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, org)
+}
+
+// DeleteOrganization handles DELETE /v1/organizations/{id}
+func (h *Handler) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "DELETE"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteOrganization(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListOrganizationChildren handles GET /v1/organizations/{id}/children
+func (h *Handler) ListOrganizationChildren(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	children, err := h.service.ListOrganizationChildren(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, children)
+}
+
+// Folder handlers
+
+// CreateFolder handles POST /v1/folders
+func (h *Handler) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "POST"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateFolderRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	folder, err := h.service.CreateFolder(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, folder)
+}
+
+// GetFolder handles GET /v1/folders/{id}
+func (h *Handler) GetFolder(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	folder, err := h.service.GetFolder(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, folder, folder.UpdatedAt)
+}
+
+// ListFolders handles GET /v1/folders
+func (h *Handler) ListFolders(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	folders, err := h.service.ListFolders(domain.FolderListOptions{
+		OrganizationID: query.Get("organization_id"),
+		ParentID:       query.Get("parent_id"),
+		Name:           query.Get("name"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, folders)
+}
+
+// UpdateFolder handles PATCH /v1/folders/{id}
+func (h *Handler) UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "PATCH"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateFolderRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	folder, err := h.service.UpdateFolder(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, folder)
+}
+
+// DeleteFolder handles DELETE /v1/folders/{id}
+func (h *Handler) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "DELETE"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteFolder(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveFolder handles POST /v1/folders/{id}:move
+func (h *Handler) MoveFolder(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "POST"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.MoveRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	folder, err := h.service.MoveFolder(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, folder)
+}
+
+// ListFolderChildren handles GET /v1/folders/{id}/children
+func (h *Handler) ListFolderChildren(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	children, err := h.service.ListFolderChildren(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, children)
+}
+
+// Project hierarchy handlers
+
+// MoveProject handles POST /v1/projects/{id}:move
+func (h *Handler) MoveProject(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, mux.Vars(r)["id"]), r, "POST"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.MoveRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	project, err := h.service.MoveProject(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, project)
+}
+
+// GetProjectAncestry handles GET /v1/projects/{id}/ancestry
+func (h *Handler) GetProjectAncestry(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, mux.Vars(r)["id"]), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	ancestry, err := h.service.GetProjectAncestry(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, ancestry)
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+)
+
+// bearerToken returns the bearer token of a request, or "" if there is none
+func bearerToken(r *http.Request) string {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return ""
+	}
+	return parts[1]
+}
+
+// memberForToken maps a bearer token to the IAM member it authenticates as
+func memberForToken(token string) string {
+	if strings.HasPrefix(token, domain.MemberPrefixServiceAccount) {
+		return token
+	}
+	return domain.MemberPrefixToken + token
+}
+
+// principal resolves the caller of a request. It returns the IAM member for a
+// bearer token or signing access key bound in some project policy or for a
+// JWT, or "" for a caller with full access: the configured API or admin token,
+// a JWT with the admin scope, any other signing access key, or anyone when no
+// authentication is configured.
+func (h *Handler) principal(r *http.Request) (string, error) {
+	if caller, err := h.jwtCaller(r); err != nil || caller != nil {
+		if err != nil || caller.admin {
+			return "", err
+		}
+		return caller.member, nil
+	}
+
+	if signed := signedCaller(r); signed != nil {
+		if signed.err != nil {
+			return "", signed.err
+		}
+		return h.boundMember(signed.accessKey)
+	}
+
+	if token := bearerToken(r); token != "" && token != h.token && !h.isAdminToken(token) {
+		member, err := h.boundMember(token)
+		if err != nil || member != "" {
+			return member, err
+		}
+	}
+
+	return "", h.checkToken(r)
+}
+
+// boundMember returns the IAM member for a token or access key if it is bound
+// in some project policy, or "" if it is not
+func (h *Handler) boundMember(token string) (string, error) {
+	member := memberForToken(token)
+	bound, err := h.service.IsIAMMember(member)
+	if err != nil || !bound {
+		return "", err
+	}
+	return member, nil
+}
+
+// actor names the caller of a request on the events it causes
+func (h *Handler) actor(r *http.Request) string {
+	if caller, _ := h.jwtCaller(r); caller != nil {
+		return caller.member
+	}
+
+	member, err := h.principal(r)
+	switch {
+	case err != nil:
+		return domain.ActorAnonymous
+	case member != "":
+		return member
+	case h.token != "" || h.isAdminToken(bearerToken(r)) || signedCaller(r) != nil:
+		return domain.ActorAdmin
+	default:
+		return domain.ActorAnonymous
+	}
+}
+
+// requireRole checks that member holds at least role on a project, through
+// an IAM binding or the JWT of the request. A caller with full access is
+// represented by an empty member and always passes.
+func (h *Handler) requireRole(r *http.Request, member, projectID, role string) error {
+	if member == "" || service.RoleGrants(h.claimedRole(r, projectID), role) {
+		return nil
+	}
+	return h.service.CheckProjectPermission(projectID, member, role)
+}
+
+// claimedRole returns the role the JWT of a request grants on a project, or
+// "" if none
+func (h *Handler) claimedRole(r *http.Request, projectID string) string {
+	caller, _ := h.jwtCaller(r)
+	if caller == nil {
+		return ""
+	}
+	return caller.roles[projectID]
+}
+
+// authorizeProject checks that the caller holds at least role on a project
+func (h *Handler) authorizeProject(r *http.Request, projectID, role string) error {
+	member, err := h.principal(r)
+	if err != nil {
+		return err
+	}
+	return h.requireRole(r, member, projectID, role)
+}
+
+// authorizeInstance checks that the caller holds at least role on the project
+// owning an instance
+func (h *Handler) authorizeInstance(r *http.Request, instanceID, role string) error {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return err
+	}
+	instance, err := h.service.GetInstance(instanceID)
+	if err != nil {
+		return err
+	}
+	return h.requireRole(r, member, instance.ProjectID, role)
+}
+
+// authorizeAutoscalingGroup checks that the caller holds at least role on the
+// project owning an autoscaling group
+func (h *Handler) authorizeAutoscalingGroup(r *http.Request, groupID, role string) error {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return err
+	}
+	group, err := h.service.GetAutoscalingGroup(groupID)
+	if err != nil {
+		return err
+	}
+	return h.requireRole(r, member, group.ProjectID, role)
+}
+
+// authorizeBudget checks that the caller holds at least role on the project
+// owning a budget
+func (h *Handler) authorizeBudget(r *http.Request, budgetID, role string) error {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return err
+	}
+	budget, err := h.service.GetBudget(budgetID)
+	if err != nil {
+		return err
+	}
+	return h.requireRole(r, member, budget.ProjectID, role)
+}
+
+// authorizeDatabase checks that the caller holds at least role on the project
+// owning a database
+func (h *Handler) authorizeDatabase(r *http.Request, databaseID, role string) error {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return err
+	}
+	database, err := h.service.GetDatabase(databaseID)
+	if err != nil {
+		return err
+	}
+	return h.requireRole(r, member, database.ProjectID, role)
+}
+
+// authorizeTopic checks that the caller holds at least role on the project
+// owning a topic
+func (h *Handler) authorizeTopic(r *http.Request, topicID, role string) error {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return err
+	}
+	topic, err := h.service.GetTopic(topicID)
+	if err != nil {
+		return err
+	}
+	return h.requireRole(r, member, topic.ProjectID, role)
+}
+
+// authorizeSubscription checks that the caller holds at least role on the
+// project owning a subscription
+func (h *Handler) authorizeSubscription(r *http.Request, subscriptionID, role string) error {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return err
+	}
+	subscription, err := h.service.GetSubscription(subscriptionID)
+	if err != nil {
+		return err
+	}
+	return h.requireRole(r, member, subscription.ProjectID, role)
+}
+
+// authorizeSecret checks that the caller holds at least role on the project
+// owning a secret
+func (h *Handler) authorizeSecret(r *http.Request, secretID, role string) error {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return err
+	}
+	secret, err := h.service.GetSecret(secretID)
+	if err != nil {
+		return err
+	}
+	return h.requireRole(r, member, secret.ProjectID, role)
+}
+
+// authorizeSecretAccess checks that the caller may read the payloads of a
+// secret. Viewing a secret is not enough: the token must be granted the
+// secret accessor role, or admin, on the secret's project.
+func (h *Handler) authorizeSecretAccess(r *http.Request, secretID string) error {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return err
+	}
+	secret, err := h.service.GetSecret(secretID)
+	if err != nil {
+		return err
+	}
+	if role := h.claimedRole(r, secret.ProjectID); role == domain.RoleSecretAccessor || role == domain.RoleAdmin {
+		return nil
+	}
+	return h.service.CheckSecretAccess(secret.ProjectID, member)
+}
+
+// projectVisibility returns a predicate reporting whether the caller may view a
+// project, or nil when the caller may view every project
+func (h *Handler) projectVisibility(r *http.Request) (func(projectID string) (bool, error), error) {
+	member, err := h.principal(r)
+	if err != nil || member == "" {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	return func(projectID string) (bool, error) {
+		if visible, ok := seen[projectID]; ok {
+			return visible, nil
+		}
+		if service.RoleGrants(h.claimedRole(r, projectID), domain.RoleViewer) {
+			seen[projectID] = true
+			return true, nil
+		}
+		role, err := h.service.ProjectRole(projectID, member)
+		if err != nil {
+			return false, err
+		}
+		seen[projectID] = role != ""
+		return seen[projectID], nil
+	}, nil
+}
+
+// IAM handlers
+
+// GetProjectIAMPolicy handles GET /v1/projects/{id}/iam
+func (h *Handler) GetProjectIAMPolicy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.authorizeProject(r, id, domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, id), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	policy, err := h.service.GetProjectIAMPolicy(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, policy)
+}
+
+// SetProjectIAMPolicy handles PUT /v1/projects/{id}/iam
+func (h *Handler) SetProjectIAMPolicy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.authorizeProject(r, id, domain.RoleAdmin); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, id), r, "PUT"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.SetIAMPolicyRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	policy, err := h.service.SetProjectIAMPolicy(id, req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, policy)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_IAMEnforcement(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	bound, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "bound"})
+	require.NoError(t, err)
+	other, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "other"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: bound.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	_, err = h.service.SetProjectIAMPolicy(bound.ID, domain.SetIAMPolicyRequest{Bindings: []domain.IAMBinding{
+		{Role: domain.RoleViewer, Members: []string{"token:alice"}},
+		{Role: domain.RoleEditor, Members: []string{"token:bob"}},
+		{Role: domain.RoleAdmin, Members: []string{"serviceAccount:ci@dirt"}},
+	}})
+	require.NoError(t, err)
+
+	createBody := func(projectID string) string {
+		return `{"project_id":"` + projectID + `","name":"api","cpu":1,"memory_mb":256,"image":"alpine"}`
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		body           string
+		expectedStatus int
+	}{
+		{"viewer reads project", "GET", "/v1/projects/" + bound.ID, "alice", "", http.StatusOK},
+		{"viewer cannot read unbound project", "GET", "/v1/projects/" + other.ID, "alice", "", http.StatusForbidden},
+		{"viewer cannot update project", "PATCH", "/v1/projects/" + bound.ID, "alice", `{"name":"renamed"}`, http.StatusForbidden},
+		{"viewer reads instance", "GET", "/v1/instances/" + instance.ID, "alice", "", http.StatusOK},
+		{"viewer cannot delete instance", "DELETE", "/v1/instances/" + instance.ID, "alice", "", http.StatusForbidden},
+		{"viewer reads policy", "GET", "/v1/projects/" + bound.ID + "/iam", "alice", "", http.StatusOK},
+		{"viewer cannot use global resources", "GET", "/v1/metadata", "alice", "", http.StatusForbidden},
+		{"editor creates instance", "POST", "/v1/instances", "bob", createBody(bound.ID), http.StatusCreated},
+		{"editor cannot create in unbound project", "POST", "/v1/instances", "bob", createBody(other.ID), http.StatusForbidden},
+		{"editor cannot set policy", "PUT", "/v1/projects/" + bound.ID + "/iam", "bob", `{"bindings":[]}`, http.StatusForbidden},
+		{"service account admin sets policy", "PUT", "/v1/projects/" + bound.ID + "/iam", "serviceAccount:ci@dirt",
+			`{"bindings":[{"role":"admin","members":["serviceAccount:ci@dirt"]},{"role":"viewer","members":["token:alice"]}]}`, http.StatusOK},
+		{"unbound token keeps full access", "GET", "/v1/projects/" + other.ID, "carol", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("list only shows viewable projects", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/v1/projects", nil)
+		r.Header.Set("Authorization", "Bearer alice")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var projects []domain.Project
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projects))
+		require.Len(t, projects, 1)
+		assert.Equal(t, bound.ID, projects[0].ID)
+	})
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// ContentTypeJSONAPI is the media type of JSON:API documents
+const ContentTypeJSONAPI = "application/vnd.api+json"
+
+// jsonAPICollections are the collections, besides those with an activity
+// timeline, whose items are JSON:API resource objects. Resource objects are
+// typed by the name of their collection.
+var jsonAPICollections = map[string]bool{
+	"environments": true,
+	"events":       true,
+	"metadata":     true,
+}
+
+// jsonAPIRelationships maps the fields referring to other resources to the
+// collection of the resource they refer to. They become relationships named
+// after the field without its _id suffix.
+var jsonAPIRelationships = map[string]string{
+	"organization_id":      "organizations",
+	"folder_id":            "folders",
+	"project_id":           "projects",
+	"autoscaling_group_id": "autoscaling-groups",
+	"template_id":          "instance-templates",
+	"environment_id":       "environments",
+	"topic_id":             "topics",
+	"secret_id":            "secrets",
+}
+
+// acceptsJSONAPI reports whether a request asks for JSON:API documents
+func acceptsJSONAPI(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == ContentTypeJSONAPI {
+			return true
+		}
+	}
+	return false
+}
+
+// formatJSONAPI rewrites /v1 JSON responses as JSON:API documents, for every
+// request when the handler is configured to and otherwise for requests that
+// accept application/vnd.api+json. Requests serving /v2 and facades keep
+// their own formats.
+func (h *Handler) formatJSONAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/") || facadeRoute(r.URL.Path) || isV2Request(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !h.jsonAPI && !acceptsJSONAPI(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept")
+		self, collection := r.URL.RequestURI(), jsonAPICollection(r.URL.Path)
+		rw := newJSONRewriter(w, func(w http.ResponseWriter, header http.Header, status int, body []byte) {
+			writeJSONAPIResponse(w, header, status, body, self, collection)
+		})
+		defer rw.finish()
+
+		// The handlers negotiate plain JSON
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept", ContentTypeJSON)
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// jsonAPICollection returns the collection a /v1 path lists or addresses an
+// item of: the last of its segments naming one, without a custom method
+func jsonAPICollection(path string) string {
+	collection := ""
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/v1/"), "/") {
+		segment, _, _ = strings.Cut(segment, ":")
+		if _, ok := activityResources[segment]; ok || jsonAPICollections[segment] {
+			collection = segment
+		}
+	}
+	return collection
+}
+
+// jsonAPIResource is a JSON:API resource object
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]interface{}         `json:"attributes"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+}
+
+// jsonAPIRelationship is a to-one JSON:API relationship
+type jsonAPIRelationship struct {
+	Data  jsonAPIIdentifier `json:"data"`
+	Links map[string]string `json:"links"`
+}
+
+// jsonAPIIdentifier identifies a JSON:API resource
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// jsonAPIError is a JSON:API error object
+type jsonAPIError struct {
+	Status string                 `json:"status"`
+	Code   string                 `json:"code"`
+	Title  string                 `json:"title"`
+	Detail string                 `json:"detail,omitempty"`
+	Source map[string]string      `json:"source,omitempty"`
+	Links  map[string]string      `json:"links,omitempty"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+}
+
+// jsonAPIResourceObject converts an item of a collection to a resource
+// object. Items without a string ID are not resources.
+func jsonAPIResourceObject(item interface{}, collection string) (*jsonAPIResource, bool) {
+	fields, ok := item.(map[string]interface{})
+	if !ok || collection == "" {
+		return nil, false
+	}
+	id, ok := fields["id"].(string)
+	if !ok || id == "" {
+		return nil, false
+	}
+
+	resource := &jsonAPIResource{
+		Type:       collection,
+		ID:         id,
+		Attributes: map[string]interface{}{},
+		Links:      map[string]string{"self": "/v1/" + collection + "/" + id},
+	}
+	for key, value := range fields {
+		if key == "id" {
+			continue
+		}
+		related, _ := value.(string)
+		if target, ok := jsonAPIRelationships[key]; ok && related != "" {
+			if resource.Relationships == nil {
+				resource.Relationships = map[string]jsonAPIRelationship{}
+			}
+			resource.Relationships[strings.TrimSuffix(key, "_id")] = jsonAPIRelationship{
+				Data:  jsonAPIIdentifier{Type: target, ID: related},
+				Links: map[string]string{"related": "/v1/" + target + "/" + related},
+			}
+			continue
+		}
+		resource.Attributes[key] = value
+	}
+	return resource, true
+}
+
+// jsonAPIDocument converts a successful /v1 response body to a JSON:API
+// document. Resources and lists of them become primary data; anything else,
+// such as reports and settings, is returned as meta.
+func jsonAPIDocument(value interface{}, self, collection string) map[string]interface{} {
+	doc := map[string]interface{}{
+		"jsonapi": map[string]string{"version": "1.0"},
+		"links":   map[string]string{"self": self},
+	}
+
+	if resource, ok := jsonAPIResourceObject(value, collection); ok {
+		doc["data"] = resource
+		return doc
+	}
+
+	// Empty lists are written as null
+	if value == nil && collection != "" {
+		doc["data"] = []*jsonAPIResource{}
+		return doc
+	}
+
+	if items, ok := value.([]interface{}); ok && collection != "" {
+		resources := make([]*jsonAPIResource, 0, len(items))
+		for _, item := range items {
+			resource, ok := jsonAPIResourceObject(item, collection)
+			if !ok {
+				resources = nil
+				break
+			}
+			resources = append(resources, resource)
+		}
+		if resources != nil {
+			doc["data"] = resources
+			return doc
+		}
+	}
+
+	if fields, ok := value.(map[string]interface{}); ok {
+		doc["meta"] = fields
+	} else {
+		doc["meta"] = map[string]interface{}{"value": value}
+	}
+	return doc
+}
+
+// jsonAPIErrors converts a /v1 error to JSON:API error objects, linking to
+// the help of its reason. Validation errors get one per invalid field,
+// pointing at its attribute.
+func jsonAPIErrors(status int, dirtErr *domain.DirtError) []jsonAPIError {
+	var links map[string]string
+	if dirtErr.HelpURL != "" {
+		links = map[string]string{"about": dirtErr.HelpURL}
+	}
+	base := jsonAPIError{
+		Status: strconv.Itoa(status),
+		Code:   dirtErr.Code,
+		Title:  dirtErr.Message,
+		Links:  links,
+		Meta:   dirtErr.Details,
+	}
+
+	violations, _ := dirtErr.Details["fields"].([]interface{})
+	if len(violations) == 0 {
+		return []jsonAPIError{base}
+	}
+
+	errs := make([]jsonAPIError, 0, len(violations))
+	for _, violation := range violations {
+		fields, _ := violation.(map[string]interface{})
+		field, _ := fields["field"].(string)
+		message, _ := fields["message"].(string)
+		errs = append(errs, jsonAPIError{
+			Status: base.Status,
+			Code:   base.Code,
+			Title:  dirtErr.Message,
+			Detail: field + " " + message,
+			Source: map[string]string{"pointer": "/data/attributes/" + strings.ReplaceAll(field, ".", "/")},
+			Links:  links,
+		})
+	}
+	return errs
+}
+
+// writeJSONAPIResponse converts a /v1 JSON response to a JSON:API document
+// and writes it. Bodies that cannot be parsed, such as malformed ones chaos
+// forced, are sent as is.
+func writeJSONAPIResponse(w http.ResponseWriter, header http.Header, status int, body []byte, self, collection string) {
+	for key, values := range header {
+		w.Header()[key] = values
+	}
+	w.Header().Del("Content-Length")
+
+	if value, err := decodeAny(body); err == nil {
+		var doc interface{}
+		var dirtErr domain.DirtError
+		if status >= 400 && json.Unmarshal(body, &dirtErr) == nil && dirtErr.Code != "" {
+			doc = map[string]interface{}{"errors": jsonAPIErrors(status, &dirtErr)}
+		} else {
+			doc = jsonAPIDocument(value, self, collection)
+		}
+		if data, err := json.Marshal(doc); err == nil {
+			w.Header().Set("Content-Type", ContentTypeJSONAPI)
+			body = append(data, '\n')
+		}
+	}
+
+	w.WriteHeader(status)
+	w.Write(body)
+}
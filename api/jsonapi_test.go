@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonAPIDo(t *testing.T, router http.Handler, method, target, accept, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc), w.Body.String())
+	return w, doc
+}
+
+func TestJSONAPI_Resources(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	w, doc := jsonAPIDo(t, router, "POST", "/v1/projects", ContentTypeJSONAPI, `{"name":"web"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, ContentTypeJSONAPI, w.Header().Get("Content-Type"))
+	project := doc["data"].(map[string]interface{})
+	projectID := project["id"].(string)
+	assert.Equal(t, "projects", project["type"])
+	assert.Equal(t, "web", project["attributes"].(map[string]interface{})["name"])
+	assert.Equal(t, "/v1/projects/"+projectID, project["links"].(map[string]interface{})["self"])
+
+	w, _ = jsonAPIDo(t, router, "POST", "/v1/instances", ContentTypeJSONAPI, `{"project_id":"`+projectID+`","name":"vm","cpu":1,"memory_mb":512,"image":"ubuntu"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w, doc = jsonAPIDo(t, router, "GET", "/v1/projects/"+projectID+"/instances", ContentTypeJSONAPI, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	instances := doc["data"].([]interface{})
+	require.Len(t, instances, 1)
+	instance := instances[0].(map[string]interface{})
+	assert.Equal(t, "instances", instance["type"])
+	assert.NotContains(t, instance["attributes"], "project_id")
+	assert.Equal(t, map[string]interface{}{
+		"data":  map[string]interface{}{"type": "projects", "id": projectID},
+		"links": map[string]interface{}{"related": "/v1/projects/" + projectID},
+	}, instance["relationships"].(map[string]interface{})["project"])
+
+	// Without the media type, responses stay plain JSON
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/projects/"+projectID, nil))
+	assert.Equal(t, ContentTypeJSON, w.Header().Get("Content-Type"))
+}
+
+func TestJSONAPI_ConfiguredErrors(t *testing.T) {
+	router := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{JSONAPI: true}))
+
+	w, doc := jsonAPIDo(t, router, "POST", "/v1/projects", "", `{"name":"bad name!"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	errs := doc["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "400", errs[0].(map[string]interface{})["status"])
+	assert.Equal(t, "INVALID_INPUT", errs[0].(map[string]interface{})["code"])
+	assert.Equal(t, map[string]interface{}{"pointer": "/data/attributes/name"}, errs[0].(map[string]interface{})["source"])
+
+	// Documents that are not resources are returned as meta
+	w, doc = jsonAPIDo(t, router, "GET", "/v1/chaos", "", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, doc, "meta")
+	assert.NotContains(t, doc, "data")
+
+	// /v2 keeps its own format
+	w, doc = jsonAPIDo(t, router, "GET", "/v2/projects", "", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, doc, "data")
+	assert.NotContains(t, doc, "jsonapi")
+}
@@ -0,0 +1,436 @@
+package api
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// DefaultJWTAdminScope is the scope that gives a JWT caller full access
+const DefaultJWTAdminScope = "dirt.admin"
+
+// jwksRefreshInterval limits how often an unknown key ID makes the verifier
+// fetch the key set again
+const jwksRefreshInterval = 30 * time.Second
+
+// jwtLeeway tolerates clock skew between the issuer and the server
+const jwtLeeway = 30 * time.Second
+
+// JWTConfig configures validation of JWT bearer tokens. Keys come from a
+// JWKS URL or a static key; Key is a PEM public key or certificate, or
+// otherwise an HMAC secret.
+type JWTConfig struct {
+	JWKSURL string
+	Key     []byte
+
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+
+	// AdminScope in the scope or scp claim gives full access
+	AdminScope string
+
+	// ProjectsClaim maps project IDs to the role the caller holds on them
+	ProjectsClaim string
+
+	// TenantClaim names the caller's tenant under claim tenancy
+	TenantClaim string
+}
+
+// JWTVerifier validates JWT bearer tokens, such as OIDC access tokens
+type JWTVerifier struct {
+	config JWTConfig
+	client *http.Client
+
+	// static is the configured key, if any
+	static interface{}
+
+	mu      sync.Mutex
+	keys    map[string]interface{}
+	fetched time.Time
+}
+
+// jwtCaller is the caller a verified token authenticates
+type jwtCaller struct {
+	member string
+	admin  bool
+
+	// roles are the project roles granted by the token's projects claim
+	roles map[string]string
+
+	tenant string
+}
+
+// NewJWTVerifier creates a verifier. Key sets are fetched on first use.
+func NewJWTVerifier(config JWTConfig) (*JWTVerifier, error) {
+	if (config.JWKSURL == "") == (len(config.Key) == 0) {
+		return nil, errors.New("exactly one of a JWKS URL or a static key is required")
+	}
+	if config.AdminScope == "" {
+		config.AdminScope = DefaultJWTAdminScope
+	}
+	if config.ProjectsClaim == "" {
+		config.ProjectsClaim = "projects"
+	}
+	if config.TenantClaim == "" {
+		config.TenantClaim = "tenant"
+	}
+
+	v := &JWTVerifier{config: config, client: &http.Client{Timeout: 10 * time.Second}}
+	if len(config.Key) > 0 {
+		key, err := parseStaticKey(config.Key)
+		if err != nil {
+			return nil, err
+		}
+		v.static = key
+	}
+	return v, nil
+}
+
+// parseStaticKey parses a PEM public key or certificate. Anything else is
+// taken as an HMAC secret.
+func parseStaticKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return bytes.TrimSpace(data), nil
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// looksLikeJWT reports whether a bearer token has the shape of a JWT
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// verify checks a token's signature and claims and returns its caller
+func (v *JWTVerifier) verify(token string) (*jwtCaller, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	return v.caller(claims, time.Now())
+}
+
+// caller checks the registered claims and maps the rest to a caller
+func (v *JWTVerifier) caller(claims map[string]interface{}, now time.Time) (*jwtCaller, error) {
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not yet valid")
+	}
+	if v.config.Issuer != "" && claims["iss"] != v.config.Issuer {
+		return nil, fmt.Errorf("token issuer must be %q", v.config.Issuer)
+	}
+	if v.config.Audience != "" && !containsClaim(claims["aud"], v.config.Audience) {
+		return nil, fmt.Errorf("token audience must include %q", v.config.Audience)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	caller := &jwtCaller{
+		member: domain.MemberPrefixUser + subject,
+		admin:  containsClaim(scopes(claims), v.config.AdminScope),
+	}
+	if projects, ok := claims[v.config.ProjectsClaim].(map[string]interface{}); ok {
+		caller.roles = make(map[string]string, len(projects))
+		for projectID, role := range projects {
+			if role, ok := role.(string); ok {
+				caller.roles[projectID] = role
+			}
+		}
+	}
+	caller.tenant, _ = claims[v.config.TenantClaim].(string)
+	return caller, nil
+}
+
+// scopes returns the scopes of a token, from an OAuth scope string or an
+// scp list
+func scopes(claims map[string]interface{}) interface{} {
+	if scope, ok := claims["scope"].(string); ok {
+		fields := strings.Fields(scope)
+		list := make([]interface{}, len(fields))
+		for i, field := range fields {
+			list[i] = field
+		}
+		return list
+	}
+	return claims["scp"]
+}
+
+// containsClaim reports whether a string or list claim holds want
+func containsClaim(claim interface{}, want string) bool {
+	switch c := claim.(type) {
+	case string:
+		return c == want
+	case []interface{}:
+		for _, item := range c {
+			if item == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jwtHashes are the digests of the supported signature algorithms
+var jwtHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+	"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+}
+
+// verifySignature checks a JWS signature. The key type must suit the
+// algorithm, so a public key can never be used as an HMAC secret.
+func verifySignature(alg string, key interface{}, signed, signature []byte) error {
+	hash, ok := jwtHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") {
+			if rsa.VerifyPKCS1v15(k, hash, digest, signature) != nil {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if strings.HasPrefix(alg, "ES") {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			if len(signature) != 2*size {
+				return errors.New("invalid token signature")
+			}
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if !ecdsa.Verify(k, digest, r, s) {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		}
+	case []byte:
+		if strings.HasPrefix(alg, "HS") {
+			mac := hmac.New(hash.New, k)
+			mac.Write(signed)
+			if !hmac.Equal(mac.Sum(nil), signature) {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("token algorithm %q does not match the key", alg)
+}
+
+// key returns the verification key with a key ID. An unknown ID makes the
+// key set be fetched again, so keys rotated in at the issuer are picked up.
+func (v *JWTVerifier) key(kid string) (interface{}, error) {
+	if v.static != nil {
+		return v.static, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(v.fetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown token key %q", kid)
+	}
+
+	keys, err := v.fetch()
+	v.fetched = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown token key %q", kid)
+}
+
+// lookup finds a cached key. Tokens without a key ID match a lone key.
+func (v *JWTVerifier) lookup(kid string) (interface{}, bool) {
+	if key, ok := v.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// jwk is a JSON Web Key, as far as signature verification needs it
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch downloads the key set. Keys that are not for signatures or cannot
+// be parsed are skipped.
+func (v *JWTVerifier) fetch() (map[string]interface{}, error) {
+	resp, err := v.client.Get(v.config.JWKSURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey decodes an RSA or EC key
+func (k jwk) publicKey() (interface{}, error) {
+	decode := func(s string) (*big.Int, error) {
+		data, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(data), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// jwtCaller returns the caller authenticated by a JWT bearer token, or nil
+// when JWT validation is off or the request does not carry a JWT
+func (h *Handler) jwtCaller(r *http.Request) (*jwtCaller, error) {
+	token := bearerToken(r)
+	if h.jwt == nil || !looksLikeJWT(token) || token == h.token || h.isAdminToken(token) {
+		return nil, nil
+	}
+
+	caller, err := h.jwt.verify(token)
+	if err != nil {
+		return nil, domain.UnauthorizedError("invalid token: " + err.Error())
+	}
+	return caller, nil
+}
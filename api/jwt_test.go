@@ -0,0 +1,258 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signJWT creates a token signed with key: an *rsa.PrivateKey for RS256, an
+// *ecdsa.PrivateKey for ES256 or a []byte secret for HS256
+func signJWT(t *testing.T, key interface{}, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	alg := "RS256"
+	switch key.(type) {
+	case *ecdsa.PrivateKey:
+		alg = "ES256"
+	case []byte:
+		alg = "HS256"
+	}
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// jwksServer serves the public half of key as a one-key JWKS
+func jwksServer(t *testing.T, key *rsa.PrivateKey, kid string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := NewJWTVerifier(JWTConfig{JWKSURL: jwksServer(t, rsaKey, "k1").URL, Issuer: "https://idp", Audience: "dirt"})
+	require.NoError(t, err)
+
+	ecDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	ecStatic, err := NewJWTVerifier(JWTConfig{Key: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecDER})})
+	require.NoError(t, err)
+
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaDER})
+	rsaStatic, err := NewJWTVerifier(JWTConfig{Key: rsaPEM})
+	require.NoError(t, err)
+
+	hmacStatic, err := NewJWTVerifier(JWTConfig{Key: []byte("shared-secret\n")})
+	require.NoError(t, err)
+
+	valid := func(extra map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{"sub": "alice", "iss": "https://idp", "aud": []string{"dirt"}, "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		verifier *JWTVerifier
+		token    string
+		errorMsg string
+	}{
+		{"JWKS key", jwks, signJWT(t, rsaKey, "k1", valid(nil)), ""},
+		{"static EC key", ecStatic, signJWT(t, ecKey, "", valid(nil)), ""},
+		{"HMAC secret", hmacStatic, signJWT(t, []byte("shared-secret"), "", valid(nil)), ""},
+		{"wrong key", jwks, signJWT(t, otherKey, "k1", valid(nil)), "invalid token signature"},
+		{"unknown key ID", jwks, signJWT(t, rsaKey, "k2", valid(nil)), `unknown token key "k2"`},
+		{"expired", jwks, signJWT(t, rsaKey, "k1", valid(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})), "token expired"},
+		{"not yet valid", jwks, signJWT(t, rsaKey, "k1", valid(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})), "token not yet valid"},
+		{"wrong issuer", jwks, signJWT(t, rsaKey, "k1", valid(map[string]interface{}{"iss": "https://evil"})), "token issuer"},
+		{"wrong audience", jwks, signJWT(t, rsaKey, "k1", valid(map[string]interface{}{"aud": "other"})), "token audience"},
+		{"no subject", jwks, signJWT(t, rsaKey, "k1", valid(map[string]interface{}{"sub": ""})), "token has no subject"},
+		{"public key as HMAC secret", rsaStatic, signJWT(t, rsaPEM, "", valid(nil)), "does not match the key"},
+		{"unsigned", hmacStatic, strings.Join(strings.Split(signJWT(t, []byte("shared-secret"), "", valid(nil)), ".")[:2], ".") + ".", "invalid token signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, err := tt.verifier.verify(tt.token)
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user:alice", caller.member)
+		})
+	}
+
+	_, err = NewJWTVerifier(JWTConfig{})
+	assert.Error(t, err, "a key source is required")
+}
+
+func TestJWTVerifier_Claims(t *testing.T) {
+	v, err := NewJWTVerifier(JWTConfig{Key: []byte("secret"), TenantClaim: "org"})
+	require.NoError(t, err)
+
+	caller, err := v.caller(map[string]interface{}{
+		"sub":      "ci",
+		"scope":    "openid dirt.admin",
+		"projects": map[string]interface{}{"p1": "editor", "p2": 3},
+		"org":      "acme",
+	}, time.Now())
+	require.NoError(t, err)
+	assert.True(t, caller.admin)
+	assert.Equal(t, map[string]string{"p1": "editor"}, caller.roles)
+	assert.Equal(t, "acme", caller.tenant)
+
+	caller, err = v.caller(map[string]interface{}{"sub": "ci", "scp": []interface{}{"read"}}, time.Now())
+	require.NoError(t, err)
+	assert.False(t, caller.admin)
+}
+
+func TestHandler_JWTAuthorization(t *testing.T) {
+	secret := []byte("secret")
+	verifier, err := NewJWTVerifier(JWTConfig{Key: secret})
+	require.NoError(t, err)
+
+	svc := newTestService(t)
+	router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{JWT: verifier}))
+
+	granted, err := svc.CreateProject(domain.CreateProjectRequest{Name: "granted"})
+	require.NoError(t, err)
+	bound, err := svc.CreateProject(domain.CreateProjectRequest{Name: "bound"})
+	require.NoError(t, err)
+	other, err := svc.CreateProject(domain.CreateProjectRequest{Name: "other"})
+	require.NoError(t, err)
+	_, err = svc.SetProjectIAMPolicy(bound.ID, domain.SetIAMPolicyRequest{Bindings: []domain.IAMBinding{
+		{Role: domain.RoleViewer, Members: []string{"user:dev"}},
+	}})
+	require.NoError(t, err)
+
+	dev := "Bearer " + signJWT(t, secret, "", map[string]interface{}{
+		"sub":      "dev",
+		"projects": map[string]interface{}{granted.ID: domain.RoleEditor},
+	})
+	admin := "Bearer " + signJWT(t, secret, "", map[string]interface{}{"sub": "ops", "scope": DefaultJWTAdminScope})
+	forged := "Bearer " + signJWT(t, []byte("guess"), "", map[string]interface{}{"sub": "ops", "scope": DefaultJWTAdminScope})
+
+	tests := []struct {
+		name     string
+		auth     string
+		method   string
+		path     string
+		body     string
+		expected int
+	}{
+		{"claimed role allows writes", dev, "POST", "/v1/instances", `{"project_id":"` + granted.ID + `","name":"vm","cpu":1,"memory_mb":512,"image":"ubuntu"}`, http.StatusCreated},
+		{"IAM binding allows reads", dev, "GET", "/v1/projects/" + bound.ID, "", http.StatusOK},
+		{"IAM binding role is enforced", dev, "POST", "/v1/instances", `{"project_id":"` + bound.ID + `","name":"vm","cpu":1,"memory_mb":512,"image":"ubuntu"}`, http.StatusForbidden},
+		{"ungranted project", dev, "GET", "/v1/projects/" + other.ID, "", http.StatusForbidden},
+		{"admin routes need full access", dev, "GET", "/v1/chaos", "", http.StatusForbidden},
+		{"admin scope", admin, "GET", "/v1/chaos", "", http.StatusOK},
+		{"forged token", forged, "GET", "/v1/projects", "", http.StatusUnauthorized},
+		{"no token", "", "GET", "/v1/projects", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			assert.Equal(t, tt.expected, w.Code, w.Body.String())
+		})
+	}
+
+	assert.ElementsMatch(t, []string{"granted", "bound"}, listProjectNames(t, router, "Authorization", dev))
+	assert.Len(t, listProjectNames(t, router, "Authorization", admin), 3)
+}
+
+func TestTenantRouter_ClaimTenancy(t *testing.T) {
+	secret := []byte("secret")
+	verifier, err := NewJWTVerifier(JWTConfig{Key: secret})
+	require.NoError(t, err)
+
+	var opened []string
+	base := NewHandler(newTestService(t), chaos.NewChaosService(), Config{JWT: verifier})
+	tenants, err := NewTenantRouter(TenancyClaim, base, func(tenant string) (*service.Service, error) {
+		opened = append(opened, tenant)
+		return newTestService(t), nil
+	}, 0)
+	require.NoError(t, err)
+
+	for _, tenant := range []string{"acme", "globex"} {
+		token := signJWT(t, secret, "", map[string]interface{}{"sub": "ci", "tenant": tenant, "scope": DefaultJWTAdminScope})
+		r := httptest.NewRequest("POST", "/v1/projects", strings.NewReader(`{"name":"shared-name"}`))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		tenants.ServeHTTP(w, r)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	assert.Equal(t, []string{"acme", "globex"}, opened)
+
+	_, err = NewTenantRouter(TenancyClaim, newTestHandler(t), nil, 0)
+	assert.Error(t, err, "claim tenancy requires JWT validation")
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Scopes of in-flight request limits
+const (
+	limitScopeGlobal = "global"
+	limitScopeToken  = "token"
+	limitScopeRoute  = "route"
+)
+
+// inflightLimiter bounds the number of requests served at once, globally, per
+// bearer token and per route. A limit of zero means unlimited.
+type inflightLimiter struct {
+	maxGlobal   int
+	maxPerToken int
+	maxPerRoute int
+
+	mu       sync.Mutex
+	global   int
+	byToken  map[string]int
+	byRoute  map[string]int
+	rejected map[string]uint64
+}
+
+// newInflightLimiter creates a limiter with the given limits
+func newInflightLimiter(maxGlobal, maxPerToken, maxPerRoute int) *inflightLimiter {
+	return &inflightLimiter{
+		maxGlobal:   maxGlobal,
+		maxPerToken: maxPerToken,
+		maxPerRoute: maxPerRoute,
+		byToken:     make(map[string]int),
+		byRoute:     make(map[string]int),
+		rejected:    make(map[string]uint64),
+	}
+}
+
+// acquire reserves a slot for a request. It returns a release function, or the
+// scope of the limit that was reached.
+func (l *inflightLimiter) acquire(token, route string) (func(), string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	scope := ""
+	switch {
+	case l.maxGlobal > 0 && l.global >= l.maxGlobal:
+		scope = limitScopeGlobal
+	case l.maxPerToken > 0 && l.byToken[token] >= l.maxPerToken:
+		scope = limitScopeToken
+	case l.maxPerRoute > 0 && l.byRoute[route] >= l.maxPerRoute:
+		scope = limitScopeRoute
+	}
+	if scope != "" {
+		l.rejected[scope]++
+		return nil, scope
+	}
+
+	l.global++
+	l.byToken[token]++
+	l.byRoute[route]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			l.global--
+			if l.byToken[token]--; l.byToken[token] == 0 {
+				delete(l.byToken, token)
+			}
+			if l.byRoute[route]--; l.byRoute[route] == 0 {
+				delete(l.byRoute, route)
+			}
+		})
+	}, ""
+}
+
+// limit returns the configured limit for a scope
+func (l *inflightLimiter) limit(scope string) int {
+	switch scope {
+	case limitScopeGlobal:
+		return l.maxGlobal
+	case limitScopeToken:
+		return l.maxPerToken
+	default:
+		return l.maxPerRoute
+	}
+}
+
+// limiterSnapshot is a point-in-time copy of limiter state for metrics
+type limiterSnapshot struct {
+	global   int
+	byRoute  map[string]int
+	rejected map[string]uint64
+}
+
+// snapshot copies the current limiter state
+func (l *inflightLimiter) snapshot() limiterSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := limiterSnapshot{
+		global:   l.global,
+		byRoute:  make(map[string]int, len(l.byRoute)),
+		rejected: make(map[string]uint64, len(l.rejected)),
+	}
+	for route, n := range l.byRoute {
+		s.byRoute[route] = n
+	}
+	for scope, n := range l.rejected {
+		s.rejected[scope] = n
+	}
+	return s
+}
+
+// routeKey identifies the matched route of a request, e.g. "GET /v1/instances/{id}"
+func routeKey(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return r.Method + " " + tmpl
+		}
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+// limitConcurrency rejects requests with SERVICE_UNAVAILABLE while an in-flight
+// limit is reached. WebSocket upgrades are not counted since they stay open
+// for the life of the connection.
+func (h *Handler) limitConcurrency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		release, scope := h.limiter.acquire(bearerToken(r), routeKey(r))
+		if scope != "" {
+			w.Header().Set("Retry-After", "1")
+			h.writeError(w, domain.NewError(domain.ErrorCodeServiceUnavailable,
+				fmt.Sprintf("too many concurrent requests: %s limit of %d reached", scope, h.limiter.limit(scope)),
+				map[string]interface{}{
+					"scope": scope,
+					"limit": h.limiter.limit(scope),
+				}))
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInflightLimiter(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxGlobal     int
+		maxPerToken   int
+		maxPerRoute   int
+		held          [][2]string // token, route of requests already in flight
+		token, route  string
+		expectedScope string
+	}{
+		{name: "unlimited", held: [][2]string{{"a", "GET /v1/x"}, {"a", "GET /v1/x"}}, token: "a", route: "GET /v1/x"},
+		{name: "global", maxGlobal: 2, held: [][2]string{{"a", "GET /v1/x"}, {"b", "GET /v1/y"}}, token: "c", route: "GET /v1/z", expectedScope: limitScopeGlobal},
+		{name: "token", maxPerToken: 1, held: [][2]string{{"a", "GET /v1/x"}}, token: "a", route: "GET /v1/y", expectedScope: limitScopeToken},
+		{name: "other token", maxPerToken: 1, held: [][2]string{{"a", "GET /v1/x"}}, token: "b", route: "GET /v1/x"},
+		{name: "route", maxPerRoute: 1, held: [][2]string{{"a", "GET /v1/x"}}, token: "b", route: "GET /v1/x", expectedScope: limitScopeRoute},
+		{name: "other route", maxPerRoute: 1, held: [][2]string{{"a", "GET /v1/x"}}, token: "a", route: "POST /v1/x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newInflightLimiter(tt.maxGlobal, tt.maxPerToken, tt.maxPerRoute)
+			for _, held := range tt.held {
+				_, scope := l.acquire(held[0], held[1])
+				require.Empty(t, scope)
+			}
+
+			release, scope := l.acquire(tt.token, tt.route)
+			assert.Equal(t, tt.expectedScope, scope)
+			if tt.expectedScope == "" {
+				require.NotNil(t, release)
+				release()
+				release() // releasing twice is harmless
+				assert.Equal(t, len(tt.held), l.snapshot().global)
+			} else {
+				assert.Equal(t, uint64(1), l.snapshot().rejected[tt.expectedScope])
+			}
+		})
+	}
+}
+
+func TestHandler_limitConcurrency(t *testing.T) {
+	h := NewHandler(newTestService(t), nil, Config{MaxInFlightPerToken: 1})
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	limited := h.limitConcurrency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func() *http.Request {
+		r := httptest.NewRequest("GET", "/v1/projects", nil)
+		r.Header.Set("Authorization", "Bearer ci")
+		return r
+	}
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		limited.ServeHTTP(first, request())
+		close(done)
+	}()
+	<-entered
+
+	second := httptest.NewRecorder()
+	limited.ServeHTTP(second, request())
+	assert.Equal(t, http.StatusServiceUnavailable, second.Code)
+	assert.Equal(t, "1", second.Header().Get("Retry-After"))
+	assert.Contains(t, second.Body.String(), `"scope":"token"`)
+
+	metrics := httptest.NewRecorder()
+	h.Metrics(metrics, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, metrics.Body.String(), "dirt_requests_in_flight 1\n")
+	assert.Contains(t, metrics.Body.String(), `dirt_requests_rejected_total{scope="token"} 1`)
+	assert.Contains(t, metrics.Body.String(), `dirt_requests_in_flight_limit{scope="token"} 1`)
+
+	close(unblock)
+	<-done
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, 0, h.limiter.snapshot().global)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Listener roles. An API listener serves every route except those of the
+// other roles when they have listeners of their own.
+const (
+	ListenerAPI     = "api"
+	ListenerAdmin   = "admin"
+	ListenerMetrics = "metrics"
+)
+
+// routeRole returns the listener role whose routes include a request
+func routeRole(r *http.Request) string {
+	switch {
+	case controlRoute(r):
+		return ListenerAdmin
+	case r.URL.Path == "/metrics":
+		return ListenerMetrics
+	default:
+		return ListenerAPI
+	}
+}
+
+// ListenerHandler returns the part of next that a listener with role serves.
+// Separate lists the roles that have their own listeners; an API listener
+// does not serve their routes. Other requests get NOT_FOUND, as if the route
+// did not exist.
+func ListenerHandler(next http.Handler, role string, separate ...string) http.Handler {
+	serves := func(r *http.Request) bool {
+		routeRole := routeRole(r)
+		if role != ListenerAPI || routeRole == ListenerAPI {
+			return routeRole == role
+		}
+		for _, s := range separate {
+			if s == routeRole {
+				return false
+			}
+		}
+		return true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !serves(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(domain.NotFoundError("route", r.URL.Path))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
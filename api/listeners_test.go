@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenerHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name     string
+		role     string
+		separate []string
+		path     string
+		expected int
+	}{
+		{"api serves everything", ListenerAPI, nil, "/v1/admin/cors", http.StatusOK},
+		{"api serves metrics without a metrics listener", ListenerAPI, []string{ListenerAdmin}, "/metrics", http.StatusOK},
+		{"api leaves admin to its listener", ListenerAPI, []string{ListenerAdmin}, "/v1/admin/cors", http.StatusNotFound},
+		{"api leaves chaos to the admin listener", ListenerAPI, []string{ListenerAdmin}, "/v1/chaos", http.StatusNotFound},
+		{"api serves projects", ListenerAPI, []string{ListenerAdmin, ListenerMetrics}, "/v1/projects", http.StatusOK},
+		{"admin serves admin", ListenerAdmin, nil, "/v1/admin/requests", http.StatusOK},
+		{"admin does not serve projects", ListenerAdmin, nil, "/v1/projects", http.StatusNotFound},
+		{"metrics serves metrics", ListenerMetrics, nil, "/metrics", http.StatusOK},
+		{"metrics does not serve the console", ListenerMetrics, nil, "/web", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ListenerHandler(next, tt.role, tt.separate...).ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.expected == http.StatusNotFound {
+				assert.Contains(t, w.Body.String(), `"error":"NOT_FOUND"`)
+			}
+		})
+	}
+}
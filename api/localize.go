@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// localizedWriter carries the languages a request accepts to writeError,
+// which only sees the response writer
+type localizedWriter struct {
+	http.ResponseWriter
+	languages []string
+}
+
+// Flush implements http.Flusher for streamed lists
+func (lw *localizedWriter) Flush() {
+	if f, ok := lw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for WebSocket upgrades and dropped
+// connections
+func (lw *localizedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := lw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (lw *localizedWriter) Unwrap() http.ResponseWriter { return lw.ResponseWriter }
+
+// localizeErrors records the languages a request accepts so its errors are
+// worded in them. It does nothing unless a translator is configured.
+func (h *Handler) localizeErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.translator == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		languages := acceptedLanguages(r.Header.Get("Accept-Language"))
+		next.ServeHTTP(&localizedWriter{ResponseWriter: w, languages: languages}, r)
+	})
+}
+
+// localize words err for the languages accepted by the request w responds
+// to, if it is known
+func (h *Handler) localize(w http.ResponseWriter, err *domain.DirtError) *domain.DirtError {
+	if h.translator == nil {
+		return err
+	}
+
+	for {
+		if lw, ok := w.(*localizedWriter); ok {
+			return err.Localize(h.translator, lw.languages)
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			// Requests outside the API accept no particular language
+			return err.Localize(h.translator, nil)
+		}
+		w = u.Unwrap()
+	}
+}
+
+// acceptedLanguages parses an Accept-Language header into its language tags
+// by decreasing preference. The wildcard and tags with q=0 are left out.
+func acceptedLanguages(header string) []string {
+	type accepted struct {
+		tag string
+		q   float64
+	}
+
+	var langs []accepted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		langs = append(langs, accepted{tag, q})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	tags := make([]string, len(langs))
+	for i, lang := range langs {
+		tags[i] = lang.tag
+	}
+	return tags
+}
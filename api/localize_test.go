@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalizedErrors(t *testing.T) {
+	catalogs := domain.Catalogs{"fr": {domain.MessageNotFound: "{resource} introuvable"}}
+	router := SetupRouter(NewHandler(newTestService(t), chaos.NewChaosService(), Config{Translator: catalogs}))
+
+	get := func(language string) map[string]interface{} {
+		r := httptest.NewRequest("GET", "/v1/projects/missing", nil)
+		if language != "" {
+			r.Header.Set("Accept-Language", language)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "NOT_FOUND", body["error"], "the code is never translated")
+		return body
+	}
+
+	assert.Equal(t, "project introuvable", get("en;q=0.5, fr-FR")["message"])
+	assert.Equal(t, "project not found", get("de")["message"])
+	assert.Equal(t, "project not found", get("")["message"])
+}
+
+func TestAcceptedLanguages(t *testing.T) {
+	assert.Equal(t, []string{"fr-ch", "fr", "en", "de"}, acceptedLanguages("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5"))
+	assert.Equal(t, []string{"de", "en"}, acceptedLanguages("en;q=0.5, de, it;q=0"))
+	assert.Empty(t, acceptedLanguages(""))
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Defaults for brute-force lockout
+const (
+	DefaultAuthFailureWindow = time.Minute
+	DefaultAuthLockout       = 5 * time.Minute
+)
+
+// authLockout counts failed authentication attempts per source and locks out
+// sources with too many failures in a window. With maxFailures of zero,
+// failures are only counted.
+type authLockout struct {
+	maxFailures int
+	window      time.Duration
+	duration    time.Duration
+
+	mu       sync.Mutex
+	sources  map[string]*authSource
+	failures uint64
+	lockouts uint64
+	rejected uint64
+}
+
+// authSource is the failure history of one source
+type authSource struct {
+	failures    int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// newAuthLockout creates a lockout with the given limits
+func newAuthLockout(maxFailures int, window, duration time.Duration) *authLockout {
+	if window <= 0 {
+		window = DefaultAuthFailureWindow
+	}
+	if duration <= 0 {
+		duration = DefaultAuthLockout
+	}
+	return &authLockout{
+		maxFailures: maxFailures,
+		window:      window,
+		duration:    duration,
+		sources:     make(map[string]*authSource),
+	}
+}
+
+// lockedUntil returns when the lockout of source ends, or the zero time if
+// it is not locked out. Rejected requests are counted.
+func (l *authLockout) lockedUntil(source string, now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.sources[source]; ok && now.Before(s.lockedUntil) {
+		l.rejected++
+		return s.lockedUntil
+	}
+	return time.Time{}
+}
+
+// fail records a failed attempt from source. It returns the failures in the
+// current window and, if this failure locked the source out, when the
+// lockout ends.
+func (l *authLockout) fail(source string, now time.Time) (int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.failures++
+	l.prune(now)
+
+	s, ok := l.sources[source]
+	if !ok || now.Sub(s.windowStart) >= l.window {
+		s = &authSource{windowStart: now}
+		l.sources[source] = s
+	}
+	s.failures++
+
+	if l.maxFailures > 0 && s.failures >= l.maxFailures {
+		l.lockouts++
+		s.lockedUntil = now.Add(l.duration)
+		// The next window starts once the lockout ends
+		s.failures, s.windowStart = 0, s.lockedUntil
+		return l.maxFailures, s.lockedUntil
+	}
+	return s.failures, time.Time{}
+}
+
+// prune forgets sources whose window and lockout are over
+func (l *authLockout) prune(now time.Time) {
+	for source, s := range l.sources {
+		if now.Sub(s.windowStart) >= l.window && !now.Before(s.lockedUntil) {
+			delete(l.sources, source)
+		}
+	}
+}
+
+// lockoutSnapshot is a point-in-time copy of lockout state for metrics
+type lockoutSnapshot struct {
+	failures uint64
+	lockouts uint64
+	rejected uint64
+	locked   int
+}
+
+// snapshot copies the current lockout counters
+func (l *authLockout) snapshot(now time.Time) lockoutSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := lockoutSnapshot{failures: l.failures, lockouts: l.lockouts, rejected: l.rejected}
+	for _, source := range l.sources {
+		if now.Before(source.lockedUntil) {
+			s.locked++
+		}
+	}
+	return s
+}
+
+// lockOutFailedAuth counts responses rejecting a caller's credentials and
+// rejects requests from a locked out source with TOO_MANY_REQUESTS and a
+// Retry-After of the seconds left in the lockout.
+func (h *Handler) lockOutFailedAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source := clientIP(r)
+		now := time.Now()
+		if until := h.lockout.lockedUntil(source, now); !until.IsZero() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(until.Sub(now).Seconds()))))
+			h.writeError(w, domain.NewError(domain.ErrorCodeTooManyRequests,
+				fmt.Sprintf("too many failed authentication attempts; locked out until %s", until.UTC().Format(time.RFC3339)),
+				map[string]interface{}{
+					"source":       source,
+					"locked_until": until.UTC().Format(time.RFC3339),
+				}))
+			return
+		}
+
+		if websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status != http.StatusUnauthorized {
+			return
+		}
+
+		svc := h.service.WithActor(domain.ActorAnonymous)
+		failures, until := h.lockout.fail(source, time.Now())
+		svc.RecordAuthFailure(source, fmt.Sprintf("authentication failed for %s %s", r.Method, r.URL.Path))
+		if !until.IsZero() {
+			svc.RecordAuthLockout(source, fmt.Sprintf("locked out after %d failed authentication attempts until %s",
+				failures, until.UTC().Format(time.RFC3339)))
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthLockout(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newAuthLockout(3, time.Minute, 5*time.Minute)
+
+	// Failures outside the window start a new count
+	l.fail("a", start)
+	l.fail("a", start.Add(10*time.Second))
+	failures, until := l.fail("a", start.Add(90*time.Second))
+	assert.Equal(t, 1, failures)
+	assert.True(t, until.IsZero())
+
+	l.fail("a", start.Add(100*time.Second))
+	failures, until = l.fail("a", start.Add(110*time.Second))
+	assert.Equal(t, 3, failures)
+	assert.Equal(t, start.Add(110*time.Second+5*time.Minute), until)
+
+	assert.Equal(t, until, l.lockedUntil("a", start.Add(2*time.Minute)))
+	assert.True(t, l.lockedUntil("b", start.Add(2*time.Minute)).IsZero(), "other sources are unaffected")
+	assert.True(t, l.lockedUntil("a", until).IsZero(), "the lockout ends")
+
+	snap := l.snapshot(start.Add(2 * time.Minute))
+	assert.Equal(t, lockoutSnapshot{failures: 5, lockouts: 1, rejected: 1, locked: 1}, snap)
+
+	t.Run("disabled", func(t *testing.T) {
+		l := newAuthLockout(0, time.Minute, time.Minute)
+		for i := 0; i < 10; i++ {
+			_, until := l.fail("a", start)
+			assert.True(t, until.IsZero())
+		}
+		assert.Equal(t, uint64(10), l.snapshot(start).failures)
+	})
+}
+
+func TestHandler_lockOutFailedAuth(t *testing.T) {
+	svc := newTestService(t)
+	h := NewHandler(svc, chaos.NewChaosService(), Config{Token: "secret", MaxAuthFailures: 3, AuthLockout: time.Minute})
+	router := SetupRouter(h)
+
+	request := func(addr, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/v1/projects", nil)
+		r.RemoteAddr = addr
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusUnauthorized, request("203.0.113.7:4000", "guess").Code)
+	}
+
+	locked := request("203.0.113.7:4001", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, locked.Code, "the right token is refused while locked out")
+	assert.Equal(t, "60", locked.Header().Get("Retry-After"))
+	assert.Contains(t, locked.Body.String(), `"source":"203.0.113.7"`)
+
+	assert.Equal(t, http.StatusOK, request("198.51.100.1:4000", "secret").Code)
+
+	failed, err := svc.ListEvents(domain.EventListOptions{Type: domain.EventAuthFailed})
+	require.NoError(t, err)
+	assert.Len(t, failed, 3)
+	lockouts, err := svc.ListEvents(domain.EventListOptions{Type: domain.EventAuthLockedOut})
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, "203.0.113.7", lockouts[0].ResourceID)
+	assert.Equal(t, domain.ActorAnonymous, lockouts[0].Actor)
+
+	metrics := httptest.NewRecorder()
+	h.Metrics(metrics, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, metrics.Body.String(), "dirt_auth_failures_total 3\n")
+	assert.Contains(t, metrics.Body.String(), "dirt_auth_lockouts_total 1\n")
+	assert.Contains(t, metrics.Body.String(), "dirt_auth_lockout_rejected_total 1\n")
+	assert.Contains(t, metrics.Body.String(), "dirt_auth_locked_out_sources 1\n")
+}
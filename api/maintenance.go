@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// maintenanceWindow holds the simulated maintenance window, if any. A window
+// ends on its own once its duration has passed.
+type maintenanceWindow struct {
+	mu      sync.Mutex
+	current *domain.Maintenance
+}
+
+// active returns the window in effect at now, or nil
+func (m *maintenanceWindow) active(now time.Time) *domain.Maintenance {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil && !now.Before(m.current.EndsAt) {
+		m.current = nil
+	}
+	return m.current
+}
+
+// start replaces any window with a new one
+func (m *maintenanceWindow) start(window *domain.Maintenance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current = window
+}
+
+// end removes the window, reporting whether one was in effect at now
+func (m *maintenanceWindow) end(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ended := m.current != nil && now.Before(m.current.EndsAt)
+	m.current = nil
+	return ended
+}
+
+// maintenanceRejects reports whether a request with method is refused during
+// a window. Read-only windows still serve reads.
+func maintenanceRejects(window *domain.Maintenance, method string) bool {
+	if window.Mode == domain.MaintenanceUnavailable {
+		return true
+	}
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	default:
+		return true
+	}
+}
+
+// enforceMaintenance rejects requests with SERVICE_UNAVAILABLE during a
+// maintenance window, with a Retry-After of the seconds left in the window.
+// The chaos and admin routes are exempt so the window can be ended early.
+func (h *Handler) enforceMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		window := h.maintenance.active(now)
+		if window == nil || controlRoute(r) || !maintenanceRejects(window, r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		retryAfter := int(math.Ceil(window.EndsAt.Sub(now).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+		message := "server is unavailable for maintenance"
+		if window.Mode == domain.MaintenanceReadOnly {
+			message = "server is read-only for maintenance"
+		}
+		h.writeError(w, domain.NewError(domain.ErrorCodeServiceUnavailable,
+			fmt.Sprintf("%s until %s", message, window.EndsAt.UTC().Format(time.RFC3339)),
+			map[string]interface{}{
+				"maintenance": true,
+				"mode":        window.Mode,
+				"ends_at":     window.EndsAt.UTC().Format(time.RFC3339),
+			}))
+	})
+}
+
+// GetMaintenance handles GET /v1/admin/maintenance
+func (h *Handler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	window := h.maintenance.active(time.Now())
+	if window == nil {
+		h.writeError(w, domain.NotFoundError("maintenance window", ""))
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, window)
+}
+
+// StartMaintenance handles POST /v1/admin/maintenance. It replaces any window
+// already in effect.
+func (h *Handler) StartMaintenance(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.StartMaintenanceRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var v domain.FieldViolations
+	switch req.Mode {
+	case domain.MaintenanceReadOnly, domain.MaintenanceUnavailable:
+	default:
+		v.Add("mode", fmt.Sprintf("must be %s or %s", domain.MaintenanceReadOnly, domain.MaintenanceUnavailable))
+	}
+	if req.DurationSeconds <= 0 {
+		v.Add("duration_seconds", "must be positive")
+	}
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	window := &domain.Maintenance{
+		Mode:      req.Mode,
+		StartedAt: now,
+		EndsAt:    now.Add(time.Duration(req.DurationSeconds) * time.Second),
+	}
+	h.maintenance.start(window)
+
+	h.writeJSON(w, http.StatusCreated, window)
+}
+
+// EndMaintenance handles DELETE /v1/admin/maintenance
+func (h *Handler) EndMaintenance(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if !h.maintenance.end(time.Now()) {
+		h.writeError(w, domain.NotFoundError("maintenance window", ""))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
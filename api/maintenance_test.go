@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("GET", "/v1/admin/maintenance", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do("POST", "/v1/admin/maintenance", `{"mode": "down", "duration_seconds": 0}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "mode")
+	assert.Contains(t, w.Body.String(), "duration_seconds")
+
+	// Read-only windows serve reads and reject writes
+	w = do("POST", "/v1/admin/maintenance", `{"mode": "read_only", "duration_seconds": 60}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var window domain.Maintenance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &window))
+	assert.Equal(t, domain.MaintenanceReadOnly, window.Mode)
+	assert.WithinDuration(t, window.StartedAt.Add(time.Minute), window.EndsAt, 0)
+
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/projects", "").Code)
+
+	w = do("POST", "/v1/projects", `{"name": "blocked"}`)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 60, retryAfter, 1)
+
+	var dirtErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dirtErr))
+	assert.Equal(t, domain.ErrorCodeServiceUnavailable, dirtErr.Code)
+	assert.Equal(t, domain.MaintenanceReadOnly, dirtErr.Details["mode"])
+	assert.Equal(t, window.EndsAt.Format(time.RFC3339), dirtErr.Details["ends_at"])
+
+	// Unavailable windows reject everything but the control routes
+	w = do("POST", "/v1/admin/maintenance", `{"mode": "unavailable", "duration_seconds": 30}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	assert.Equal(t, http.StatusServiceUnavailable, do("GET", "/v1/projects", "").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/chaos", "").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/admin/maintenance", "").Code)
+
+	// Ending the window restores service
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/v1/admin/maintenance", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/v1/admin/maintenance", "").Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/v1/projects", `{"name": "allowed"}`).Code)
+}
+
+func TestMaintenanceExpires(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	now := time.Now()
+	h.maintenance.start(&domain.Maintenance{
+		Mode:      domain.MaintenanceUnavailable,
+		StartedAt: now.Add(-time.Minute),
+		EndsAt:    now.Add(-time.Second),
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/projects", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/admin/maintenance", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// bufferedResponse holds a response so it can be rewritten before it is sent
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+func (b *bufferedResponse) WriteHeader(status int) { b.status = status }
+
+// malformResponses replaces 200 responses with malformed ones when chaos calls
+// for it. WebSocket upgrades pass through untouched.
+func (h *Handler) malformResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) || controlRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		kind, err := h.chaosService.MalformedResponse(r)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		if kind == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(buf, r)
+
+		body := buf.body.Bytes()
+		if buf.status == http.StatusOK {
+			body = chaos.Malform(kind, buf.header, body)
+		}
+
+		for key, values := range buf.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(buf.status)
+		w.Write(body)
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMalformedResponses(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+
+	get := func(path, kind string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set(chaos.MalformedResponseHeader, kind)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/v1/projects/"+project.ID, chaos.MalformedInvalidJSON)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, json.Valid(w.Body.Bytes()))
+
+	w = get("/v1/projects/"+project.ID, chaos.MalformedHTML)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = get("/v1/projects", chaos.MalformedGzip)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	// Errors are sent unchanged
+	w = get("/v1/projects/missing", chaos.MalformedHTML)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.True(t, json.Valid(w.Body.Bytes()))
+
+	// Chaos routes are exempt
+	w = get("/v1/chaos", chaos.MalformedInvalidJSON)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, json.Valid(w.Body.Bytes()))
+
+	assert.Equal(t, http.StatusBadRequest, get("/v1/projects", "yaml").Code)
+}
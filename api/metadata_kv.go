@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// consulKV is an entry of consul kv export output. Folders are exported as
+// keys ending in a slash with a null value.
+type consulKV struct {
+	Key   string  `json:"key"`
+	Flags uint64  `json:"flags"`
+	Value *string `json:"value"`
+}
+
+// etcdKV is a key of etcdctl's JSON output. Both key and value are base64.
+// The revisions etcd reports alongside them are ignored on import and,
+// having no equivalent here, left out of exports.
+type etcdKV struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// etcdRange is etcdctl's JSON output for a range read
+type etcdRange struct {
+	KVs   []etcdKV `json:"kvs"`
+	Count int      `json:"count"`
+}
+
+// parseMetadataFormat validates the format query parameter of imports and
+// exports
+func parseMetadataFormat(v *domain.FieldViolations, value string) string {
+	switch value {
+	case domain.MetadataFormatEtcd, domain.MetadataFormatConsul:
+	case "":
+		v.Add("format", "is required")
+	default:
+		v.Add("format", "must be etcd or consul")
+	}
+	return value
+}
+
+// ExportMetadata handles GET /v1/metadata:export, writing the metadata tree,
+// or the part of it under prefix, in etcd or Consul KV format. Entries are
+// written as they are read, so large trees are never held in memory.
+func (h *Handler) ExportMetadata(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	var v domain.FieldViolations
+	format := parseMetadataFormat(&v, query.Get("format"))
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	head, tail := "[", "]\n"
+	if format == domain.MetadataFormatEtcd {
+		head = `{"kvs":[`
+	}
+
+	count := 0
+	err := h.service.StreamMetadata(domain.MetadataListOptions{Prefix: query.Get("prefix")}, func(m *domain.Metadata) error {
+		var entry interface{}
+		if format == domain.MetadataFormatEtcd {
+			entry = etcdKV{Key: encodeKV(m.Path), Value: encodeKV(m.Value)}
+		} else {
+			value := encodeKV(m.Value)
+			entry = consulKV{Key: m.Path, Flags: m.Flags, Value: &value}
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			w.Header().Set("Content-Type", ContentTypeJSON)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(head))
+		} else {
+			w.Write([]byte(","))
+		}
+		w.Write(data)
+		count++
+		return nil
+	})
+	if err != nil {
+		if count == 0 {
+			h.writeError(w, err)
+			return
+		}
+		// As with streamed lists, a truncated body must not look complete
+		log.Printf("Failed to export metadata: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	if count == 0 {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(head))
+	}
+	if format == domain.MetadataFormatEtcd {
+		tail = fmt.Sprintf(`],"count":%d}`+"\n", count)
+	}
+	w.Write([]byte(tail))
+}
+
+// ImportMetadata handles POST /v1/metadata:import, writing every key of an
+// etcd or Consul KV export into the metadata store. Existing paths are
+// overwritten; Consul folder entries are skipped.
+func (h *Handler) ImportMetadata(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var v domain.FieldViolations
+	format := parseMetadataFormat(&v, r.URL.Query().Get("format"))
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	body, err := h.readBody(w, r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var entries []domain.CreateMetadataRequest
+	if format == domain.MetadataFormatEtcd {
+		entries, err = decodeEtcdKVs(body)
+	} else {
+		entries, err = decodeConsulKVs(body)
+	}
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	result, err := h.service.ImportMetadata(entries)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// decodeEtcdKVs decodes the keys of etcdctl JSON output. Other fields, such
+// as the header and revisions, are ignored. Foreign formats are decoded
+// leniently, unlike request bodies.
+func decodeEtcdKVs(body []byte) ([]domain.CreateMetadataRequest, error) {
+	var export etcdRange
+	if err := json.Unmarshal(body, &export); err != nil {
+		return nil, syntaxError(err)
+	}
+
+	var v domain.FieldViolations
+	entries := make([]domain.CreateMetadataRequest, 0, len(export.KVs))
+	for i, kv := range export.KVs {
+		path := decodeKV(&v, fmt.Sprintf("kvs[%d].key", i), kv.Key)
+		value := decodeKV(&v, fmt.Sprintf("kvs[%d].value", i), kv.Value)
+		entries = append(entries, domain.CreateMetadataRequest{Path: path, Value: value})
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// decodeConsulKVs decodes consul kv export output, skipping folders
+func decodeConsulKVs(body []byte) ([]domain.CreateMetadataRequest, error) {
+	var export []consulKV
+	if err := json.Unmarshal(body, &export); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field == "" {
+			return nil, domain.InvalidInputError("invalid JSON", map[string]interface{}{
+				"reason": fmt.Sprintf("expected a JSON array, got %s", typeErr.Value),
+			})
+		}
+		return nil, syntaxError(err)
+	}
+
+	var v domain.FieldViolations
+	entries := make([]domain.CreateMetadataRequest, 0, len(export))
+	for i, kv := range export {
+		if kv.Value == nil && strings.HasSuffix(kv.Key, "/") {
+			continue
+		}
+		var value string
+		if kv.Value != nil {
+			value = decodeKV(&v, fmt.Sprintf("[%d].value", i), *kv.Value)
+		}
+		entries = append(entries, domain.CreateMetadataRequest{Path: kv.Key, Value: value, Flags: kv.Flags})
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// encodeKV base64 encodes a key or value for export
+func encodeKV(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// decodeKV decodes a base64 key or value. Metadata is text, so values that
+// are not UTF-8 cannot be imported.
+func decodeKV(v *domain.FieldViolations, field, encoded string) string {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		v.Add(field, "must be base64")
+		return ""
+	}
+	if !utf8.Valid(data) {
+		v.Add(field, "must be UTF-8 text")
+		return ""
+	}
+	return string(data)
+}
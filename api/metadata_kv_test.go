@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataExportImport(t *testing.T) {
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   domain.MetadataFormatEtcd,
+			expected: `{"kvs":[{"key":"` + b64("app/a") + `","value":"` + b64("1") + `"},{"key":"` + b64("app/b") + `","value":"` + b64("two") + `"}],"count":2}`,
+		},
+		{
+			format:   domain.MetadataFormatConsul,
+			expected: `[{"key":"app/a","flags":0,"value":"` + b64("1") + `"},{"key":"app/b","flags":0,"value":"` + b64("two") + `"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			source := newTestHandler(t)
+			for path, value := range map[string]string{"app/a": "1", "app/b": "two", "other/c": "3"} {
+				_, err := source.service.CreateMetadata(domain.CreateMetadataRequest{Path: path, Value: value})
+				require.NoError(t, err)
+			}
+
+			w := httptest.NewRecorder()
+			SetupRouter(source).ServeHTTP(w, httptest.NewRequest("GET", "/v1/metadata:export?prefix=app/&format="+tt.format, nil))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.JSONEq(t, tt.expected, w.Body.String())
+			export := w.Body.String()
+
+			// Import into a store that already has one of the keys
+			target := newTestHandler(t)
+			_, err := target.service.CreateMetadata(domain.CreateMetadataRequest{Path: "app/a", Value: "old"})
+			require.NoError(t, err)
+
+			w = httptest.NewRecorder()
+			SetupRouter(target).ServeHTTP(w, httptest.NewRequest("POST", "/v1/metadata:import?format="+tt.format, strings.NewReader(export)))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.JSONEq(t, `{"created":1,"updated":1,"unchanged":0}`, w.Body.String())
+
+			entries, err := target.service.ListMetadata(domain.MetadataListOptions{})
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+			assert.Equal(t, "1", entries[0].Value)
+			assert.Equal(t, "two", entries[1].Value)
+		})
+	}
+}
+
+func TestMetadataImport_Invalid(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+		field string
+	}{
+		{name: "no format", query: "", body: `[]`, field: "format"},
+		{name: "unknown format", query: "?format=zookeeper", body: `[]`, field: "format"},
+		{name: "etcd key not base64", query: "?format=etcd", body: `{"kvs":[{"key":"not base64!","value":""}]}`, field: "kvs[0].key"},
+		{name: "consul value not UTF-8", query: "?format=consul", body: `[{"key":"a","flags":0,"value":"/w=="}]`, field: "[0].value"},
+		{name: "duplicate paths", query: "?format=consul", body: `[{"key":"a","value":null},{"key":"a","value":null}]`, field: "entries[1].path"},
+		{name: "consul object", query: "?format=consul", body: `{"kvs":[]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/metadata:import"+tt.query, strings.NewReader(tt.body)))
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+			var dirtErr domain.DirtError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dirtErr))
+			if tt.field != "" {
+				assert.Contains(t, w.Body.String(), `"`+tt.field+`"`)
+			}
+		})
+	}
+
+	// Nothing was written by the failed imports
+	entries, err := h.service.ListMetadata(domain.MetadataListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestMetadataImport_ConsulFolders(t *testing.T) {
+	h := newTestHandler(t)
+
+	body := `[{"key":"app/","flags":0,"value":null},{"key":"app/empty","flags":0,"value":null},{"key":"app/x","flags":42,"value":"eA=="}]`
+	w := httptest.NewRecorder()
+	SetupRouter(h).ServeHTTP(w, httptest.NewRequest("POST", "/v1/metadata:import?format=consul", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"created":2,"updated":0,"unchanged":0}`, w.Body.String())
+
+	// Flags survive the round trip
+	w = httptest.NewRecorder()
+	SetupRouter(h).ServeHTTP(w, httptest.NewRequest("GET", "/v1/metadata:export?format=consul&prefix=app/x", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `[{"key":"app/x","flags":42,"value":"eA=="}]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	SetupRouter(h).ServeHTTP(w, httptest.NewRequest("GET", "/v1/metadata:export?format=etcd&prefix=none/", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"kvs":[],"count":0}`, w.Body.String())
+}
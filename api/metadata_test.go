@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataLimits(t *testing.T) {
+	svc := newTestServiceWithConfig(t, service.Config{MetadataLimits: domain.MetadataLimits{MaxKeys: 2, MaxValueBytes: 16}})
+	router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{}))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("POST", "/v1/metadata", `{"path":"app/a","value":"12345"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = do("POST", "/v1/metadata", `{"path":"app/b","value":"this value is too long"}`)
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	var dirtErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dirtErr))
+	assert.Equal(t, domain.ErrorCodeQuotaExceeded, dirtErr.Code)
+	assert.Equal(t, "max_value_bytes", dirtErr.Details["limit"])
+
+	w = do("GET", "/v1/metadata:usage", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"keys":1,"total_bytes":10,"limits":{"max_keys":2,"max_value_bytes":16}}`, w.Body.String())
+}
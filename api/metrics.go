@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Metrics handles GET /metrics, exposing control plane load in the Prometheus
+// text format
+func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	snap := h.limiter.snapshot()
+
+	var b strings.Builder
+
+	fmt.Fprintln(&b, "# HELP dirt_requests_in_flight API requests currently being served.")
+	fmt.Fprintln(&b, "# TYPE dirt_requests_in_flight gauge")
+	fmt.Fprintf(&b, "dirt_requests_in_flight %d\n", snap.global)
+
+	fmt.Fprintln(&b, "# HELP dirt_route_requests_in_flight API requests currently being served per route.")
+	fmt.Fprintln(&b, "# TYPE dirt_route_requests_in_flight gauge")
+	for _, route := range sortedKeys(snap.byRoute) {
+		fmt.Fprintf(&b, "dirt_route_requests_in_flight{route=%q} %d\n", route, snap.byRoute[route])
+	}
+
+	scopes := []string{limitScopeGlobal, limitScopeToken, limitScopeRoute}
+
+	fmt.Fprintln(&b, "# HELP dirt_requests_in_flight_limit Configured in-flight request limits; 0 means unlimited.")
+	fmt.Fprintln(&b, "# TYPE dirt_requests_in_flight_limit gauge")
+	for _, scope := range scopes {
+		fmt.Fprintf(&b, "dirt_requests_in_flight_limit{scope=%q} %d\n", scope, h.limiter.limit(scope))
+	}
+
+	fmt.Fprintln(&b, "# HELP dirt_requests_rejected_total API requests rejected because an in-flight limit was reached.")
+	fmt.Fprintln(&b, "# TYPE dirt_requests_rejected_total counter")
+	for _, scope := range scopes {
+		fmt.Fprintf(&b, "dirt_requests_rejected_total{scope=%q} %d\n", scope, snap.rejected[scope])
+	}
+
+	lockout := h.lockout.snapshot(time.Now())
+
+	fmt.Fprintln(&b, "# HELP dirt_auth_failures_total API requests rejected for missing or invalid credentials.")
+	fmt.Fprintln(&b, "# TYPE dirt_auth_failures_total counter")
+	fmt.Fprintf(&b, "dirt_auth_failures_total %d\n", lockout.failures)
+
+	fmt.Fprintln(&b, "# HELP dirt_auth_lockouts_total Times a source was locked out after repeated authentication failures.")
+	fmt.Fprintln(&b, "# TYPE dirt_auth_lockouts_total counter")
+	fmt.Fprintf(&b, "dirt_auth_lockouts_total %d\n", lockout.lockouts)
+
+	fmt.Fprintln(&b, "# HELP dirt_auth_lockout_rejected_total API requests rejected because their source was locked out.")
+	fmt.Fprintln(&b, "# TYPE dirt_auth_lockout_rejected_total counter")
+	fmt.Fprintf(&b, "dirt_auth_lockout_rejected_total %d\n", lockout.rejected)
+
+	fmt.Fprintln(&b, "# HELP dirt_auth_locked_out_sources Sources currently locked out.")
+	fmt.Fprintln(&b, "# TYPE dirt_auth_locked_out_sources gauge")
+	fmt.Fprintf(&b, "dirt_auth_locked_out_sources %d\n", lockout.locked)
+
+	// Handlers built without chaos, as in tests, have nothing to report
+	if h.chaosService != nil {
+		deadlines := h.chaosService.DeadlineStats()
+
+		fmt.Fprintln(&b, "# HELP dirt_chaos_write_deadline_extended_total Times injected latency pushed back a response's write deadline.")
+		fmt.Fprintln(&b, "# TYPE dirt_chaos_write_deadline_extended_total counter")
+		fmt.Fprintf(&b, "dirt_chaos_write_deadline_extended_total %d\n", deadlines.Extended)
+
+		fmt.Fprintln(&b, "# HELP dirt_chaos_latency_capped_total Times injected latency was cut short to end before a response's write deadline.")
+		fmt.Fprintln(&b, "# TYPE dirt_chaos_latency_capped_total counter")
+		fmt.Fprintf(&b, "dirt_chaos_latency_capped_total %d\n", deadlines.Capped)
+	}
+
+	if h.cacheStats != nil {
+		h.writeCacheMetrics(&b)
+	}
+
+	h.writeUsageMetrics(&b)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// writeCacheMetrics writes the reads of each cached repository
+func (h *Handler) writeCacheMetrics(b *strings.Builder) {
+	stats := h.cacheStats()
+
+	counters := []struct {
+		name, help string
+		value      func(domain.CacheStats) uint64
+	}{
+		{"dirt_repository_cache_hits_total", "Repository reads served from the cache.",
+			func(s domain.CacheStats) uint64 { return s.Hits }},
+		{"dirt_repository_cache_misses_total", "Repository reads that went to the database.",
+			func(s domain.CacheStats) uint64 { return s.Misses }},
+		{"dirt_repository_cache_invalidations_total", "Writes that dropped cached repository reads.",
+			func(s domain.CacheStats) uint64 { return s.Invalidations }},
+	}
+
+	for _, counter := range counters {
+		fmt.Fprintf(b, "# HELP %s %s\n", counter.name, counter.help)
+		fmt.Fprintf(b, "# TYPE %s counter\n", counter.name)
+		for _, s := range stats {
+			fmt.Fprintf(b, "%s{repository=%q} %d\n", counter.name, s.Repository, counter.value(s))
+		}
+	}
+}
+
+// writeUsageMetrics writes each project's simulated usage for the current
+// month. Usage that cannot be read is left out rather than failing the scrape.
+func (h *Handler) writeUsageMetrics(b *strings.Builder) {
+	usages, err := h.service.ListUsage(time.Now().UTC().Format(domain.UsagePeriodLayout))
+	if err != nil {
+		log.Printf("Failed to list usage for metrics: %v", err)
+		return
+	}
+
+	gauges := []struct {
+		name, help string
+		value      func(*domain.Usage) float64
+	}{
+		{"dirt_project_instance_hours", "Instance-hours a project's running instances accrued this month.",
+			func(u *domain.Usage) float64 { return u.InstanceHours }},
+		{"dirt_project_vcpu_hours", "vCPU-hours a project's running instances accrued this month.",
+			func(u *domain.Usage) float64 { return u.VCPUHours }},
+		{"dirt_project_memory_gb_hours", "Memory GB-hours a project's running instances accrued this month.",
+			func(u *domain.Usage) float64 { return u.MemoryGBHours }},
+		{"dirt_project_volume_gb_hours", "Boot volume GB-hours a project's instances accrued this month.",
+			func(u *domain.Usage) float64 { return u.VolumeGBHours }},
+	}
+
+	for _, gauge := range gauges {
+		fmt.Fprintf(b, "# HELP %s %s\n", gauge.name, gauge.help)
+		fmt.Fprintf(b, "# TYPE %s gauge\n", gauge.name)
+		for _, usage := range usages {
+			fmt.Fprintf(b, "%s{project_id=%q,period=%q} %g\n", gauge.name, usage.ProjectID, usage.Period, gauge.value(usage))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_RepositoryCache(t *testing.T) {
+	h := newTestHandler(t)
+	w := httptest.NewRecorder()
+	h.Metrics(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.NotContains(t, w.Body.String(), "dirt_repository_cache")
+
+	h = NewHandler(newTestService(t), chaos.NewChaosService(), Config{
+		CacheStats: func() []domain.CacheStats {
+			return []domain.CacheStats{{Repository: "instances", Hits: 3, Misses: 1, Invalidations: 2}}
+		},
+	})
+	w = httptest.NewRecorder()
+	h.Metrics(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `dirt_repository_cache_hits_total{repository="instances"} 3`)
+	assert.Contains(t, body, `dirt_repository_cache_misses_total{repository="instances"} 1`)
+	assert.Contains(t, body, `dirt_repository_cache_invalidations_total{repository="instances"} 2`)
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// mirrorBodyLimit is the largest request or response body included in a
+// mirrored request
+const mirrorBodyLimit = 1 << 20
+
+// mirrorQueueSize is how many mirrored requests may wait for a slow sink
+// before new ones are dropped
+const mirrorQueueSize = 1000
+
+// redacted replaces credentials and secret values in mirrored requests
+const redacted = "REDACTED"
+
+// redactedHeaders carry credentials
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-CSRF-Token"}
+
+// redactedFields hold secret values in request and response bodies
+var redactedFields = map[string]bool{
+	"password":          true,
+	"connection_string": true,
+	"payload":           true,
+	"token":             true,
+}
+
+// MirrorConfig selects where API requests are mirrored. Either or both
+// sinks may be set.
+type MirrorConfig struct {
+	// URL receives each request as a JSON POST
+	URL string
+
+	// File has each request appended to it as a line of JSON
+	File string
+}
+
+// Mirror sends sanitized copies of API requests to external sinks. Requests
+// are sent in the background so a slow sink never delays the API.
+type Mirror struct {
+	url    string
+	client *http.Client
+	file   *os.File
+
+	queue     chan *domain.MirroredRequest
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMirror starts mirroring to the configured sinks. It returns nil when
+// no sink is configured.
+func NewMirror(config MirrorConfig) (*Mirror, error) {
+	if config.URL == "" && config.File == "" {
+		return nil, nil
+	}
+
+	m := &Mirror{
+		url:   config.URL,
+		queue: make(chan *domain.MirroredRequest, mirrorQueueSize),
+		done:  make(chan struct{}),
+	}
+	if m.url != "" {
+		m.client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if config.File != "" {
+		file, err := os.OpenFile(config.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open mirror file: %w", err)
+		}
+		m.file = file
+	}
+
+	go m.run()
+	return m, nil
+}
+
+// Close sends the requests still queued and closes the mirror file. It must
+// not be called while requests are being served.
+func (m *Mirror) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.queue)
+		<-m.done
+		if m.file != nil {
+			err = m.file.Close()
+		}
+	})
+	return err
+}
+
+// enqueue queues a request for the sinks, dropping it if the queue is full
+func (m *Mirror) enqueue(req *domain.MirroredRequest) {
+	select {
+	case m.queue <- req:
+	default:
+		log.Printf("Mirror queue full, dropping %s %s", req.Method, req.Path)
+	}
+}
+
+func (m *Mirror) run() {
+	defer close(m.done)
+	for req := range m.queue {
+		data, err := json.Marshal(req)
+		if err != nil {
+			log.Printf("Failed to encode mirrored request: %v", err)
+			continue
+		}
+		if m.file != nil {
+			if _, err := m.file.Write(append(data, '\n')); err != nil {
+				log.Printf("Failed to write mirrored request: %v", err)
+			}
+		}
+		if m.url != "" {
+			m.post(data)
+		}
+	}
+}
+
+func (m *Mirror) post(data []byte) {
+	resp, err := m.client.Post(m.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		log.Printf("Failed to send mirrored request: %v", err)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Mirror sink responded %s", resp.Status)
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	io.ReadCloser
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += n
+	return n, err
+}
+
+// mirrorRecorder keeps the start of a response body along with its status
+type mirrorRecorder struct {
+	statusRecorder
+	body  bytes.Buffer
+	bytes int
+}
+
+func (m *mirrorRecorder) Write(p []byte) (int, error) {
+	if room := mirrorBodyLimit + 1 - m.body.Len(); room > 0 {
+		m.body.Write(p[:min(len(p), room)])
+	}
+	n, err := m.statusRecorder.Write(p)
+	m.bytes += n
+	return n, err
+}
+
+// mirrorRequests sends every API request and its response to the mirror.
+// WebSocket upgrades are not mirrored since they have no response body.
+func (h *Handler) mirrorRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.mirror == nil || websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+
+		// Read the start of the body up front so requests rejected before
+		// their body is read are still mirrored in full
+		head, _ := io.ReadAll(io.LimitReader(r.Body, mirrorBodyLimit+1))
+		rest := &countingReader{ReadCloser: r.Body}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), rest), rest}
+
+		rec := &mirrorRecorder{statusRecorder: statusRecorder{ResponseWriter: w}}
+		next.ServeHTTP(rec, r)
+
+		h.mirror.enqueue(&domain.MirroredRequest{
+			Time:            start,
+			Method:          r.Method,
+			Path:            r.URL.Path,
+			Query:           r.URL.RawQuery,
+			ClientIP:        clientIP(r),
+			RequestHeaders:  sanitizeHeaders(r.Header),
+			RequestBody:     sanitizeBody(head),
+			RequestBytes:    len(head) + rest.n,
+			Status:          rec.status,
+			ResponseHeaders: sanitizeHeaders(w.Header()),
+			ResponseBody:    sanitizeBody(rec.body.Bytes()),
+			ResponseBytes:   rec.bytes,
+			LatencyMS:       float64(time.Since(start).Microseconds()) / 1000,
+		})
+	})
+}
+
+// sanitizeHeaders copies headers with credentials redacted
+func sanitizeHeaders(header http.Header) map[string][]string {
+	if len(header) == 0 {
+		return nil
+	}
+	sanitized := header.Clone()
+	for _, name := range redactedHeaders {
+		if _, ok := sanitized[name]; ok {
+			sanitized[name] = []string{redacted}
+		}
+	}
+	return sanitized
+}
+
+// sanitizeBody returns a JSON body with secret values redacted, or nil for
+// bodies that are empty, too large or not JSON
+func sanitizeBody(body []byte) json.RawMessage {
+	if len(body) == 0 || len(body) > mirrorBodyLimit {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return nil
+	}
+
+	data, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// redactValue replaces the values of secret fields anywhere in a decoded
+// JSON value
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedFields[strings.ToLower(key)] && field != nil && field != "" {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
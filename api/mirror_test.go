@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirror_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mirror.ndjson")
+	mirror, err := NewMirror(MirrorConfig{File: path})
+	require.NoError(t, err)
+
+	h := NewHandler(newTestService(t), chaos.NewChaosService(), Config{Mirror: mirror})
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "mirrored"})
+	require.NoError(t, err)
+
+	send := func(method, path, body string) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer not-for-the-mirror")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	send("POST", "/v1/secrets", `{"project_id": "`+project.ID+`", "name": "db", "payload": "hunter2"}`)
+	send("GET", "/v1/projects?name=mirrored", "")
+	require.NoError(t, mirror.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var mirrored []domain.MirroredRequest
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var req domain.MirroredRequest
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &req))
+		mirrored = append(mirrored, req)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, mirrored, 2)
+
+	create := mirrored[0]
+	assert.Equal(t, "POST", create.Method)
+	assert.Equal(t, "/v1/secrets", create.Path)
+	assert.Equal(t, http.StatusCreated, create.Status)
+	assert.Equal(t, []string{redacted}, create.RequestHeaders["Authorization"])
+	assert.Contains(t, string(create.RequestBody), `"payload":"REDACTED"`)
+	assert.NotContains(t, string(create.RequestBody), "hunter2")
+	assert.Contains(t, string(create.ResponseBody), `"name":"db"`)
+	assert.Positive(t, create.RequestBytes)
+	assert.Positive(t, create.ResponseBytes)
+
+	list := mirrored[1]
+	assert.Equal(t, "name=mirrored", list.Query)
+	assert.Equal(t, http.StatusOK, list.Status)
+	assert.Empty(t, list.RequestBody)
+	assert.Contains(t, string(list.ResponseBody), project.ID)
+}
+
+func TestMirror_URL(t *testing.T) {
+	received := make(chan domain.MirroredRequest, 1)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req domain.MirroredRequest
+		body, _ := io.ReadAll(r.Body)
+		if json.Unmarshal(body, &req) == nil {
+			received <- req
+		}
+	}))
+	defer sink.Close()
+
+	mirror, err := NewMirror(MirrorConfig{URL: sink.URL})
+	require.NoError(t, err)
+
+	h := NewHandler(newTestService(t), chaos.NewChaosService(), Config{Mirror: mirror})
+	router := SetupRouter(h)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/projects/missing", nil))
+	require.NoError(t, mirror.Close())
+
+	req := <-received
+	assert.Equal(t, "/v1/projects/missing", req.Path)
+	assert.Equal(t, http.StatusNotFound, req.Status)
+	assert.Contains(t, string(req.ResponseBody), "NOT_FOUND")
+}
+
+func TestNewMirror_NoSinks(t *testing.T) {
+	mirror, err := NewMirror(MirrorConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, mirror)
+}
+
+func TestSanitizeBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty", "", ""},
+		{"not json", "name: web", ""},
+		{"nested secrets", `{"items":[{"password":"p","connection_string":"c","port":5432}]}`,
+			`{"items":[{"connection_string":"REDACTED","password":"REDACTED","port":5432}]}`},
+		{"empty secret kept", `{"payload":""}`, `{"payload":""}`},
+		{"too large", `"` + strings.Repeat("a", mirrorBodyLimit) + `"`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(sanitizeBody([]byte(tt.body))))
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// parentLookup returns the project owning a parent resource, or an error if
+// the parent does not exist
+type parentLookup func(id string) (projectID string, err error)
+
+// nestedCollection serves a child collection below its parent, such as
+// GET /v1/projects/{id}/instances. A missing parent is a 404 rather than an
+// empty list; otherwise the collection's list handler runs with filter set to
+// the parent ID, overriding any filter the caller passed.
+func (h *Handler) nestedCollection(lookup parentLookup, filter string, list http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		member, err := h.principal(r)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+
+		projectID, err := lookup(id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+
+		if err := h.requireRole(r, member, projectID, domain.RoleViewer); err != nil {
+			h.writeError(w, err)
+			return
+		}
+
+		query := r.URL.Query()
+		query.Set(filter, id)
+		r.URL.RawQuery = query.Encode()
+
+		list(w, r)
+	}
+}
+
+// projectParent looks up a project as the parent of a collection
+func (h *Handler) projectParent(id string) (string, error) {
+	project, err := h.service.GetProject(id)
+	if err != nil {
+		return "", err
+	}
+	return project.ID, nil
+}
+
+// autoscalingGroupParent looks up an autoscaling group as the parent of a collection
+func (h *Handler) autoscalingGroupParent(id string) (string, error) {
+	group, err := h.service.GetAutoscalingGroup(id)
+	if err != nil {
+		return "", err
+	}
+	return group.ProjectID, nil
+}
+
+// topicParent looks up a topic as the parent of a collection
+func (h *Handler) topicParent(id string) (string, error) {
+	topic, err := h.service.GetTopic(id)
+	if err != nil {
+		return "", err
+	}
+	return topic.ProjectID, nil
+}
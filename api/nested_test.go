@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedCollections(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "parent"})
+	require.NoError(t, err)
+	other, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "other"})
+	require.NoError(t, err)
+	for _, p := range []*domain.Project{project, other} {
+		_, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: p.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+		require.NoError(t, err)
+	}
+	topic, err := h.service.CreateTopic(domain.CreateTopicRequest{ProjectID: project.ID, Name: "orders"})
+	require.NoError(t, err)
+	_, err = h.service.CreateSubscription(domain.CreateSubscriptionRequest{TopicID: topic.ID, Name: "worker"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{"project instances", "/v1/projects/" + project.ID + "/instances", http.StatusOK, 1},
+		{"parent overrides query filter", "/v1/projects/" + project.ID + "/instances?project_id=" + other.ID, http.StatusOK, 1},
+		{"missing project", "/v1/projects/missing/instances", http.StatusNotFound, 0},
+		{"project topics", "/v1/projects/" + project.ID + "/topics", http.StatusOK, 1},
+		{"empty collection", "/v1/projects/" + other.ID + "/secrets", http.StatusOK, 0},
+		{"topic subscriptions", "/v1/topics/" + topic.ID + "/subscriptions", http.StatusOK, 1},
+		{"missing topic", "/v1/topics/missing/subscriptions", http.StatusNotFound, 0},
+		{"missing autoscaling group", "/v1/autoscaling-groups/missing/instances", http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			require.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var items []map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+			assert.Len(t, items, tt.expectedCount)
+		})
+	}
+
+	t.Run("requires viewer on the parent", func(t *testing.T) {
+		_, err := h.service.SetProjectIAMPolicy(other.ID, domain.SetIAMPolicyRequest{Bindings: []domain.IAMBinding{
+			{Role: domain.RoleViewer, Members: []string{"token:alice"}},
+		}})
+		require.NoError(t, err)
+
+		r := httptest.NewRequest("GET", "/v1/projects/"+project.ID+"/instances", nil)
+		r.Header.Set("Authorization", "Bearer alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		r = httptest.NewRequest("GET", "/v1/projects/"+other.ID+"/instances", nil)
+		r.Header.Set("Authorization", "Bearer alice")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// NextPageTokenHeader carries the page_token of the next page of a list
+// response that was cut short by its limit
+const NextPageTokenHeader = "X-Dirt-Next-Page-Token"
+
+// parsePageParams parses the limit and page_token query parameters of a
+// listing
+func parsePageParams(v *domain.FieldViolations, query url.Values) (limit int, pageToken string) {
+	limit = int(parseIntParam(v, query.Get("limit"), "limit"))
+	if limit < 0 {
+		v.Add("limit", "must not be negative")
+	}
+	return limit, query.Get("page_token")
+}
+
+// pageFields returns the fields to load for a listing returning fields:
+// paged listings also load the key fields of their page tokens
+func pageFields(fields []string, limit int, sort []domain.SortField) []string {
+	if len(fields) == 0 || limit <= 0 {
+		return fields
+	}
+	return append(append([]string{}, fields...), domain.PageKey(sort)...)
+}
+
+// setNextPageToken sets the token of the page following items, the results
+// loaded for a listing, when there may be one. Tokens are made from the last
+// result loaded, before any are filtered out, so that filtering does not make
+// the next page repeat them.
+func setNextPageToken(w http.ResponseWriter, items interface{}, limit int, sort []domain.SortField) {
+	list := reflect.ValueOf(items)
+	if limit <= 0 || list.Len() < limit {
+		return
+	}
+
+	token, err := domain.NewPageToken(list.Index(list.Len()-1).Interface(), domain.PageKey(sort))
+	if err != nil {
+		log.Printf("Failed to make page token: %v", err)
+		return
+	}
+	w.Header().Set(NextPageTokenHeader, token)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListInstances_PageToken(t *testing.T) {
+	for _, sort := range []string{"", "-name", "expires_at", "-expires_at,cpu"} {
+		t.Run("sort="+sort, func(t *testing.T) {
+			h := newTestHandler(t)
+			router := SetupRouter(h)
+
+			project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "paged"})
+			require.NoError(t, err)
+
+			// Half the instances expire, so paging by expiry crosses from
+			// NULLs to times
+			create := func(name string, expires bool) string {
+				req := domain.CreateInstanceRequest{ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu"}
+				if expires {
+					expiresAt := time.Now().Add(time.Hour).UTC()
+					req.ExpiresAt = &expiresAt
+				}
+				instance, err := h.service.CreateInstance(req)
+				require.NoError(t, err)
+				return instance.ID
+			}
+			ids := make(map[string]string)
+			for i := 0; i < 7; i++ {
+				name := fmt.Sprintf("vm-%d", i)
+				ids[name] = create(name, i%2 == 1)
+			}
+
+			params := url.Values{"project_id": {project.ID}, "limit": {"3"}, "fields": {"name"}, "sort": {sort}}
+			var seen []string
+			for page := 0; ; page++ {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/instances?"+params.Encode(), nil))
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+				var instances []map[string]interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instances))
+				for _, instance := range instances {
+					seen = append(seen, instance["name"].(string))
+				}
+
+				if page == 0 {
+					// Between pages, delete the last instance seen and one
+					// not seen yet, and create another
+					last := seen[len(seen)-1]
+					var unseen string
+					for name := range ids {
+						if !containsString(seen, name) && name != last {
+							unseen = name
+						}
+					}
+					require.NoError(t, h.service.DeleteInstance(ids[last]))
+					require.NoError(t, h.service.DeleteInstance(ids[unseen]))
+					delete(ids, unseen)
+					create("vm-7", false)
+				}
+
+				token := w.Header().Get(NextPageTokenHeader)
+				if token == "" {
+					break
+				}
+				params.Set("page_token", token)
+			}
+
+			// Every instance there throughout is listed exactly once; the
+			// one created in between may or may not be
+			var expected []string
+			for name := range ids {
+				expected = append(expected, name)
+			}
+			if containsString(seen, "vm-7") {
+				expected = append(expected, "vm-7")
+			}
+			assert.ElementsMatch(t, expected, seen)
+		})
+	}
+}
+
+func TestListProjects_PageParams(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	for _, query := range []string{"limit=-1", "limit=two", "page_token=%21%21"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/projects?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
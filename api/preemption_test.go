@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreemptInstance(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "spot"})
+	require.NoError(t, err)
+
+	body := `{"project_id":"` + project.ID + `","name":"vm","cpu":1,"memory_mb":512,"image":"ubuntu","class":"spot"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/instances", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var instance domain.Instance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instance))
+	assert.Equal(t, domain.InstanceClassSpot, instance.Class)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/instances?class=spot", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var instances []domain.Instance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instances))
+	assert.Len(t, instances, 1)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/admin/instances/"+instance.ID+"/preempt", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instance))
+	assert.Equal(t, domain.StatusStopped, instance.Status)
+
+	// Only running instances can be preempted
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/admin/instances/"+instance.ID+"/preempt", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestUpdateInstance_ImmutableClass(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "spot"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PATCH", "/v1/instances/"+instance.ID, strings.NewReader(`{"class":"spot"}`)))
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var apiErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, domain.ErrorCodeImmutableField, apiErr.Code)
+	assert.Equal(t, "class", apiErr.Details["field"])
+}
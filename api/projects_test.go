@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteProject_Cascade(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "doomed"})
+	require.NoError(t, err)
+	_, err = h.service.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	del := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("DELETE", "/v1/projects/"+project.ID+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := del("")
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	var dirtErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dirtErr))
+	assert.Equal(t, domain.ErrorCodeFailedPrecondition, dirtErr.Code)
+	assert.Equal(t, float64(1), dirtErr.Details["instance_count"])
+	assert.Equal(t, float64(0), dirtErr.Details["autoscaling_group_count"])
+
+	w = del("?cascade=maybe")
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = del("?cascade=true")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+	instances, err := h.service.ListInstances(domain.InstanceListOptions{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
+
+func TestDeleteInstance_DeletionProtection(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "prod"})
+	require.NoError(t, err)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request("POST", "/v1/instances", `{"project_id":"`+project.ID+`","name":"db","cpu":1,"memory_mb":512,"image":"postgres","deletion_protection":true}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var instance domain.Instance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instance))
+	assert.True(t, instance.DeletionProtection)
+
+	w = request("DELETE", "/v1/instances/"+instance.ID, "")
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	var dirtErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dirtErr))
+	assert.Equal(t, domain.ErrorCodeFailedPrecondition, dirtErr.Code)
+
+	w = request("PATCH", "/v1/instances/"+instance.ID, `{"deletion_protection":false}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = request("DELETE", "/v1/instances/"+instance.ID, "")
+	assert.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+}
+
+func TestGetByName(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "frontend", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expectedID   string
+	}{
+		{name: "project by name", path: "/v1/projects/web", expectedCode: http.StatusOK, expectedID: project.ID},
+		{name: "project by ID", path: "/v1/projects/" + project.ID, expectedCode: http.StatusOK, expectedID: project.ID},
+		{name: "unknown project", path: "/v1/projects/missing", expectedCode: http.StatusNotFound},
+		{name: "instance by name", path: "/v1/instances/frontend?project=web", expectedCode: http.StatusOK, expectedID: instance.ID},
+		{name: "instance name without project", path: "/v1/instances/frontend", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+
+			if tt.expectedID != "" {
+				var body struct {
+					ID string `json:"id"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, tt.expectedID, body.ID)
+			}
+		})
+	}
+}
+
+func TestUpdateInstance_ResizeRequiresStopped(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "resize"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusRunning, instance.Status)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("PATCH", "/v1/instances/"+instance.ID, strings.NewReader(body)))
+		return w
+	}
+
+	// PATCH is held to the same rule as /resize
+	w := patch(`{"cpu":4}`)
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	var apiErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, domain.ErrorCodeFailedPrecondition, apiErr.Code)
+
+	got, err := h.service.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.CPU)
+
+	// Sending the current size is not a resize
+	assert.Equal(t, http.StatusOK, patch(`{"cpu":1,"memory_mb":512,"name":"vm-2"}`).Code)
+	assert.Equal(t, http.StatusOK, patch(`{"status":"stopped"}`).Code)
+	assert.Equal(t, http.StatusOK, patch(`{"cpu":4}`).Code)
+}
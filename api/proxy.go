@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies parses proxy addresses given as IPs or CIDR ranges
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: must be an IP address or CIDR range", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: must be an IP address or CIDR range", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// clientIP returns the address a request came from. Behind a trusted proxy
+// this is the client address the proxy forwarded.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// trustsProxy reports whether addr is one of the trusted proxies
+func (h *Handler) trustsProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range h.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyForwarded takes the client address, scheme and host of requests
+// relayed by a trusted proxy from its X-Forwarded-For, X-Forwarded-Proto and
+// X-Forwarded-Host headers. Requests from anyone else are left as they
+// are, so clients cannot spoof their address.
+func (h *Handler) applyForwarded(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(h.trustedProxies) == 0 || !h.trustsProxy(clientIP(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		if client := h.forwardedFor(r); client != "" {
+			r.RemoteAddr = net.JoinHostPort(client, "0")
+		}
+		if proto := strings.ToLower(firstForwarded(r, "X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			r.URL.Scheme = proto
+		}
+		if host := firstForwarded(r, "X-Forwarded-Host"); host != "" {
+			r.Host = host
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedFor returns the client address in X-Forwarded-For: the last
+// address not added by a trusted proxy. Addresses further left were set by
+// the client and cannot be trusted.
+func (h *Handler) forwardedFor(r *http.Request) string {
+	var addrs []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(header, ",") {
+			addrs = append(addrs, strings.TrimSpace(addr))
+		}
+	}
+
+	for i := len(addrs) - 1; i >= 0; i-- {
+		if net.ParseIP(addrs[i]) == nil {
+			return ""
+		}
+		if i == 0 || !h.trustsProxy(addrs[i]) {
+			return addrs[i]
+		}
+	}
+	return ""
+}
+
+// firstForwarded returns the first value of a forwarded header, which the
+// proxy nearest the client set
+func firstForwarded(r *http.Request, header string) string {
+	value, _, _ := strings.Cut(r.Header.Get(header), ",")
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	nets, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "::1"})
+	require.NoError(t, err)
+	require.Len(t, nets, 3)
+	assert.Equal(t, "192.0.2.1/32", nets[1].String())
+	assert.Equal(t, "::1/128", nets[2].String())
+
+	_, err = ParseTrustedProxies([]string{"proxy.internal"})
+	assert.ErrorContains(t, err, `invalid trusted proxy "proxy.internal"`)
+}
+
+func TestHandler_applyForwarded(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	h := NewHandler(newTestService(t), nil, Config{TrustedProxies: proxies})
+
+	tests := []struct {
+		name           string
+		peer           string
+		headers        map[string]string
+		expectedIP     string
+		expectedScheme string
+		expectedHost   string
+	}{
+		{
+			name:       "no proxy",
+			peer:       "203.0.113.9:5000",
+			expectedIP: "203.0.113.9", expectedHost: "dirt.internal",
+		},
+		{
+			name:       "untrusted peer",
+			peer:       "203.0.113.9:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "dirt.example.com"},
+			expectedIP: "203.0.113.9", expectedHost: "dirt.internal",
+		},
+		{
+			name:       "trusted proxy",
+			peer:       "10.0.0.2:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "dirt.example.com"},
+			expectedIP: "198.51.100.1", expectedScheme: "https", expectedHost: "dirt.example.com",
+		},
+		{
+			name:       "chain of trusted proxies",
+			peer:       "10.0.0.2:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.7", "X-Forwarded-Proto": "https, http"},
+			expectedIP: "198.51.100.1", expectedScheme: "https", expectedHost: "dirt.internal",
+		},
+		{
+			name:       "spoofed addresses are skipped",
+			peer:       "10.0.0.2:5000",
+			headers:    map[string]string{"X-Forwarded-For": "127.0.0.1, 198.51.100.1"},
+			expectedIP: "198.51.100.1", expectedHost: "dirt.internal",
+		},
+		{
+			name:       "malformed address",
+			peer:       "10.0.0.2:5000",
+			headers:    map[string]string{"X-Forwarded-For": "unknown", "X-Forwarded-Proto": "gopher"},
+			expectedIP: "10.0.0.2", expectedHost: "dirt.internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen *http.Request
+			handler := h.applyForwarded(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r
+			}))
+
+			r := httptest.NewRequest("GET", "http://dirt.internal/v1/projects", nil)
+			r.URL.Scheme = ""
+			r.RemoteAddr = tt.peer
+			for header, value := range tt.headers {
+				r.Header.Set(header, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			require.NotNil(t, seen)
+			assert.Equal(t, tt.expectedIP, clientIP(seen))
+			assert.Equal(t, tt.expectedScheme, seen.URL.Scheme)
+			assert.Equal(t, tt.expectedHost, seen.Host)
+		})
+	}
+}
+
+func TestHandler_ForwardedClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	svc := newTestService(t)
+	router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{
+		Token:           "secret",
+		TrustedProxies:  proxies,
+		MaxAuthFailures: 1,
+		RequestLogSize:  10,
+	}))
+
+	request := func(client string) int {
+		r := httptest.NewRequest("GET", "/v1/projects", nil)
+		r.RemoteAddr = "10.0.0.2:5000"
+		r.Header.Set("X-Forwarded-For", client)
+		r.Header.Set("Authorization", "Bearer guess")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Clients behind the same proxy are locked out separately
+	assert.Equal(t, http.StatusUnauthorized, request("198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, request("198.51.100.1"))
+	assert.Equal(t, http.StatusUnauthorized, request("198.51.100.2"))
+
+	entries, err := svc.ListRequests(domain.RequestLogListOptions{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "198.51.100.2", entries[0].ClientIP)
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Topic handlers
+
+// CreateTopic handles POST /v1/topics
+func (h *Handler) CreateTopic(w http.ResponseWriter, r *http.Request) {
+	member, err := h.principal(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateTopicRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.requireRole(r, member, req.ProjectID, domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, req.ProjectID), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	topic, err := h.service.CreateTopic(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, topic.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, topic)
+}
+
+// GetTopic handles GET /v1/topics/{id}
+func (h *Handler) GetTopic(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeTopic(r, mux.Vars(r)["id"], domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	topic, err := h.service.GetTopic(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, topic, topic.UpdatedAt)
+}
+
+// ListTopics handles GET /v1/topics
+func (h *Handler) ListTopics(w http.ResponseWriter, r *http.Request) {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, query.Get("project_id")), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	topics, err := h.service.ListTopics(domain.TopicListOptions{
+		ProjectID: query.Get("project_id"),
+		Name:      query.Get("name"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if visible != nil {
+		var allowed []*domain.Topic
+		for _, topic := range topics {
+			ok, err := visible(topic.ProjectID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, topic)
+			}
+		}
+		topics = allowed
+	}
+
+	h.writeJSON(w, http.StatusOK, topics)
+}
+
+// DeleteTopic handles DELETE /v1/topics/{id}
+func (h *Handler) DeleteTopic(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeTopic(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteTopic(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Publish handles POST /v1/topics/{id}:publish
+func (h *Handler) Publish(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeTopic(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.PublishRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	resp, err := h.service.Publish(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// Subscription handlers
+
+// CreateSubscription handles POST /v1/subscriptions
+func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	member, err := h.principal(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateSubscriptionRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// Subscriptions belong to their topic's project. A missing topic is
+	// reported by the service as an invalid reference.
+	projectID := ""
+	if topic, err := h.service.GetTopic(req.TopicID); err == nil {
+		projectID = topic.ProjectID
+	} else if !domain.IsNotFound(err) {
+		h.writeError(w, err)
+		return
+	}
+
+	if projectID != "" {
+		if err := h.requireRole(r, member, projectID, domain.RoleEditor); err != nil {
+			h.writeError(w, err)
+			return
+		}
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, projectID), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	subscription, err := h.service.CreateSubscription(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, subscription.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var defaults defaultsApplied
+	if req.AckDeadlineSeconds == 0 {
+		defaults.add("ack_deadline_seconds", subscription.AckDeadlineSeconds)
+	}
+	defaults.setHeader(w)
+
+	h.writeJSON(w, http.StatusCreated, subscription)
+}
+
+// GetSubscription handles GET /v1/subscriptions/{id}
+func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSubscription(r, mux.Vars(r)["id"], domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	subscription, err := h.service.GetSubscription(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, subscription, subscription.UpdatedAt)
+}
+
+// ListSubscriptions handles GET /v1/subscriptions
+func (h *Handler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, query.Get("project_id")), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	subscriptions, err := h.service.ListSubscriptions(domain.SubscriptionListOptions{
+		ProjectID: query.Get("project_id"),
+		TopicID:   query.Get("topic_id"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if visible != nil {
+		var allowed []*domain.Subscription
+		for _, subscription := range subscriptions {
+			ok, err := visible(subscription.ProjectID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, subscription)
+			}
+		}
+		subscriptions = allowed
+	}
+
+	h.writeJSON(w, http.StatusOK, subscriptions)
+}
+
+// UpdateSubscription handles PATCH /v1/subscriptions/{id}
+func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSubscription(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateSubscriptionRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	subscription, err := h.service.UpdateSubscription(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, subscription)
+}
+
+// DeleteSubscription handles DELETE /v1/subscriptions/{id}
+func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSubscription(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteSubscription(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Pull handles POST /v1/subscriptions/{id}:pull
+func (h *Handler) Pull(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSubscription(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.PullRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	resp, err := h.service.Pull(mux.Vars(r)["id"], req, time.Now())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// Acknowledge handles POST /v1/subscriptions/{id}:acknowledge
+func (h *Handler) Acknowledge(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSubscription(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.AcknowledgeRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.Acknowledge(mux.Vars(r)["id"], req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSubRoutes(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "pubsub"})
+	require.NoError(t, err)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("POST", "/v1/topics", `{"project_id": "`+project.ID+`", "name": "orders"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var topic domain.Topic
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &topic))
+
+	w = do("POST", "/v1/subscriptions", `{"topic_id": "`+topic.ID+`", "name": "worker"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "ack_deadline_seconds=10", w.Header().Get(DefaultsAppliedHeader))
+	var subscription domain.Subscription
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &subscription))
+
+	w = do("POST", "/v1/topics/"+topic.ID+":publish", `{"messages": [{"data": "hello", "attributes": {"source": "test"}}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var published domain.PublishResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &published))
+	require.Len(t, published.MessageIDs, 1)
+
+	w = do("POST", "/v1/subscriptions/"+subscription.ID+":pull", `{"max_messages": 5}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var pulled domain.PullResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pulled))
+	require.Len(t, pulled.ReceivedMessages, 1)
+	assert.Equal(t, published.MessageIDs[0], pulled.ReceivedMessages[0].Message.ID)
+	assert.Equal(t, "hello", pulled.ReceivedMessages[0].Message.Data)
+
+	w = do("POST", "/v1/subscriptions/"+subscription.ID+":acknowledge", `{"ack_ids": ["`+pulled.ReceivedMessages[0].AckID+`"]}`)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+	w = do("POST", "/v1/subscriptions/"+subscription.ID+":pull", `{}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"received_messages": []}`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/v1/subscriptions", `{"topic_id": "missing", "name": "orphan"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("PATCH", "/v1/subscriptions/"+subscription.ID, `{"ack_deadline_seconds": 9000}`).Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/v1/topics/missing:publish", `{"messages": [{"data": "x"}]}`).Code)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/v1/topics/"+topic.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/subscriptions/"+subscription.ID, "").Code)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// parseSort parses a sort query parameter such as "name,-created_at".
+// A leading dash sorts that field in descending order.
+func parseSort(value string) []domain.SortField {
+	var sort []domain.SortField
+	for _, part := range splitList(value) {
+		if strings.HasPrefix(part, "-") {
+			sort = append(sort, domain.SortField{Field: strings.TrimPrefix(part, "-"), Desc: true})
+		} else {
+			sort = append(sort, domain.SortField{Field: strings.TrimPrefix(part, "+")})
+		}
+	}
+	return sort
+}
+
+// parseBoolParam parses an optional boolean query parameter, defaulting to false
+func parseBoolParam(v *domain.FieldViolations, value, field string) bool {
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		v.Add(field, "must be true or false")
+	}
+	return b
+}
+
+// parseIntParam parses an optional integer query parameter, defaulting to 0
+func parseIntParam(v *domain.FieldViolations, value, field string) int64 {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		v.Add(field, "must be an integer")
+	}
+	return n
+}
+
+// splitList splits a comma separated query parameter, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// selectFields reduces each element of a list response to the requested JSON
+// fields. The repositories have already validated fields against their allowlist.
+func selectFields(items interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	keep := map[string]bool{"id": true}
+	for _, f := range fields {
+		keep[f] = true
+	}
+
+	result := make([]map[string]json.RawMessage, len(rows))
+	for i, row := range rows {
+		selected := make(map[string]json.RawMessage, len(keep))
+		for k, v := range row {
+			if keep[k] {
+				selected[k] = v
+			}
+		}
+		result[i] = selected
+	}
+
+	return result, nil
+}
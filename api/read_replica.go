@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// ReadPreferenceHeader, set to replica, serves a read from the lagging read
+// replica instead of the primary, for clients testing how they cope with
+// stale reads. Setting it to primary, or leaving it out, reads the primary.
+const ReadPreferenceHeader = "X-Dirt-Read-Preference"
+
+// Read preferences
+const (
+	ReadPreferencePrimary = "primary"
+	ReadPreferenceReplica = "replica"
+)
+
+// ReplicaLagHeader reports, on responses served from the read replica, how
+// far behind the primary the state they show is
+const ReplicaLagHeader = "X-Dirt-Replica-Lag"
+
+// readFromReplica serves GET requests preferring the replica from a copy of
+// the handler whose service reads the replica. Like debugged requests, they
+// go through a router of their own, so it must come right after
+// debugRequests. Servers without a replica, and replicas with nothing to
+// serve yet, read the primary.
+func (h *Handler) readFromReplica(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		preference := r.Header.Get(ReadPreferenceHeader)
+		if h.replicated || preference == "" || preference == ReadPreferencePrimary || !apiRoute(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if preference != ReadPreferenceReplica {
+			h.writeError(w, domain.InvalidInputError(ReadPreferenceHeader+" must be "+ReadPreferencePrimary+" or "+ReadPreferenceReplica, nil))
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		svc, at, ok := h.service.Replica()
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		replica := *h
+		replica.service = svc
+		replica.replicated = true
+		w.Header().Set(ReplicaLagHeader, time.Since(at).Round(time.Millisecond).String())
+		SetupRouter(&replica).ServeHTTP(w, r)
+	})
+}
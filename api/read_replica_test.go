@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFromReplica(t *testing.T) {
+	db, err := sqlite.NewDB("file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_fk=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// The replica lags by longer than the test runs, so it keeps serving
+	// its first snapshot
+	backupDir := t.TempDir()
+	replica := sqlite.NewReplica(db, time.Hour, func(snapshot *sqlite.DB) service.Repositories {
+		return service.NewSQLiteRepositories(snapshot, backupDir)
+	})
+	t.Cleanup(func() { replica.Close() })
+	repos := service.NewSQLiteRepositories(db, backupDir)
+	repos.Replica = replica
+	h := NewHandler(service.NewService(repos, service.Config{}), chaos.NewChaosService(), Config{})
+	router := SetupRouter(h)
+
+	do := func(method, path, preference, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if preference != "" {
+			r.Header.Set(ReadPreferenceHeader, preference)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	names := func(w *httptest.ResponseRecorder) []string {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var projects []domain.Project
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projects))
+		var names []string
+		for _, p := range projects {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	// Until the replica first syncs, reads go to the primary
+	w := do("POST", "/v1/projects", ReadPreferenceReplica, `{"name":"first"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = do("GET", "/v1/projects", ReadPreferenceReplica, "")
+	assert.Equal(t, []string{"first"}, names(w))
+	assert.Empty(t, w.Header().Get(ReplicaLagHeader))
+
+	require.NoError(t, replica.Sync())
+
+	// Writes go to the primary whatever the preference, and only reads
+	// preferring the primary see them
+	w = do("POST", "/v1/projects", ReadPreferenceReplica, `{"name":"second"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var second domain.Project
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+	require.NoError(t, replica.Sync())
+
+	w = do("GET", "/v1/projects", ReadPreferenceReplica, "")
+	assert.Equal(t, []string{"first"}, names(w))
+	assert.NotEmpty(t, w.Header().Get(ReplicaLagHeader))
+	assert.Equal(t, []string{"first", "second"}, names(do("GET", "/v1/projects", "", "")))
+	assert.Equal(t, []string{"first", "second"}, names(do("GET", "/v1/projects", ReadPreferencePrimary, "")))
+
+	w = do("GET", "/v1/projects/"+second.ID, ReadPreferenceReplica, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = do("GET", "/v1/projects/"+second.ID, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = do("GET", "/v1/projects", "nearest", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayCreate(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowDuplicate bool
+		expectedStatus int
+		expectedCode   string
+		expected       int
+	}{
+		{name: "duplicate names allowed", allowDuplicate: true, expectedStatus: http.StatusCreated, expected: 2},
+		{name: "unique names", expectedStatus: http.StatusConflict, expectedCode: domain.ErrorCodeAlreadyExists, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sqlite.NewDB("file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_fk=1")
+			require.NoError(t, err)
+			t.Cleanup(func() { db.Close() })
+			require.NoError(t, db.SetUniqueInstanceNames(!tt.allowDuplicate))
+
+			svc := service.NewService(service.NewSQLiteRepositories(db, t.TempDir()), service.Config{AllowDuplicateInstanceNames: tt.allowDuplicate})
+			router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{}))
+
+			project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "web"})
+			require.NoError(t, err)
+
+			body := `{"project_id":"` + project.ID + `","name":"vm-1","cpu":1,"memory_mb":512,"image":"ubuntu"}`
+			r := httptest.NewRequest("POST", "/v1/instances", strings.NewReader(body))
+			r.Header.Set(chaos.ReplayCreateHeader, "true")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			require.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+
+			instances, err := svc.ListInstances(domain.InstanceListOptions{ProjectID: project.ID, Name: "vm-1"})
+			require.NoError(t, err)
+			require.Len(t, instances, tt.expected)
+
+			if tt.expectedCode != "" {
+				// The client sees the replay's conflict, though the first
+				// create was kept
+				var apiErr domain.DirtError
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+				assert.Equal(t, tt.expectedCode, apiErr.Code)
+				return
+			}
+
+			var created domain.Instance
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+			var ids []string
+			for _, instance := range instances {
+				ids = append(ids, instance.ID)
+			}
+			assert.Contains(t, ids, created.ID)
+		})
+	}
+}
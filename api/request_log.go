@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// DefaultRequestLogSize is the number of requests the request log keeps
+const DefaultRequestLogSize = 1000
+
+// requestLogRoute is not logged itself, so polling the log leaves it intact
+const requestLogRoute = "/v1/admin/requests"
+
+// statusRecorder remembers the status of a response. Connections taken over
+// by a handler have no status.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher for streamed lists
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for dropped connections
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter { return s.ResponseWriter }
+
+// logRequests records API requests in the request log along with the chaos
+// applied to them. WebSocket upgrades are not logged since they stay open
+// for the life of the connection.
+func (h *Handler) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.requestLogSize <= 0 || websocket.IsWebSocketUpgrade(r) || r.URL.Path == requestLogRoute {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		ctx, applied := chaos.WithApplied(r.Context())
+		r = r.WithContext(ctx)
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		entry := &domain.RequestLogEntry{
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Query:     r.URL.RawQuery,
+			Status:    rec.status,
+			LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			Actor:     h.actor(r),
+			ClientIP:  clientIP(r),
+			Chaos:     applied.Kinds(),
+			CreatedAt: start,
+		}
+		if err := h.service.RecordRequest(entry, h.requestLogSize); err != nil {
+			log.Printf("Failed to log request: %v", err)
+		}
+	})
+}
+
+// ListRequests handles GET /v1/admin/requests
+func (h *Handler) ListRequests(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := domain.RequestLogListOptions{
+		Method: query.Get("method"),
+		Path:   query.Get("path"),
+		Actor:  query.Get("actor"),
+	}
+
+	var v domain.FieldViolations
+	opts.Status = int(parseIntParam(&v, query.Get("status"), "status"))
+	opts.Chaos = parseBoolParam(&v, query.Get("chaos"), "chaos")
+	opts.Since = parseTimeParam(&v, query.Get("since"), "since")
+	opts.Until = parseTimeParam(&v, query.Get("until"), "until")
+	opts.Limit = int(parseIntParam(&v, query.Get("limit"), "limit"))
+	if err := v.Err(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	entries, err := h.service.ListRequests(opts)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeList(w, r, entries, nil)
+}
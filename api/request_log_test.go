@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLog(t *testing.T) {
+	h := NewHandler(newTestService(t), chaos.NewChaosService(), Config{RequestLogSize: 10})
+	router := SetupRouter(h)
+
+	send := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	send("POST", "/v1/projects", `{"name":"logged"}`, nil)
+	send("GET", "/v1/projects?name=logged", "", map[string]string{chaos.ForceStatusHeader: "503"})
+	send("GET", "/v1/projects/missing", "", nil)
+
+	list := func(t *testing.T, query string) []domain.RequestLogEntry {
+		w := send("GET", "/v1/admin/requests"+query, "", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var entries []domain.RequestLogEntry
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+		return entries
+	}
+
+	entries := list(t, "")
+	require.Len(t, entries, 3, "listing the log is not logged itself")
+
+	assert.Equal(t, "GET", entries[0].Method)
+	assert.Equal(t, "/v1/projects/missing", entries[0].Path)
+	assert.Equal(t, http.StatusNotFound, entries[0].Status)
+	assert.Equal(t, domain.ActorAnonymous, entries[0].Actor)
+	assert.Empty(t, entries[0].Chaos)
+
+	assert.Equal(t, "name=logged", entries[1].Query)
+	assert.Equal(t, http.StatusServiceUnavailable, entries[1].Status)
+	assert.Equal(t, []string{chaos.AppliedError}, entries[1].Chaos)
+
+	assert.Equal(t, "POST", entries[2].Method)
+	assert.Equal(t, http.StatusCreated, entries[2].Status)
+
+	chaosEntries := list(t, "?chaos=true")
+	require.Len(t, chaosEntries, 1)
+	assert.Equal(t, entries[1].ID, chaosEntries[0].ID)
+
+	assert.Len(t, list(t, "?method=POST&path=/v1/projects"), 1)
+	assert.Len(t, list(t, "?status=404"), 1)
+
+	w := send("GET", "/v1/admin/requests?status=abc", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRequestLog_Disabled(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/projects", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/admin/requests", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var entries []domain.RequestLogEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Empty(t, entries)
+}
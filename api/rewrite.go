@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// jsonRewriter holds JSON responses, including those of JSON based media
+// types so they can be rewritten before they are
+// sent. Other responses, such as streamed lists, pass straight through.
+type jsonRewriter struct {
+	w       http.ResponseWriter
+	header  http.Header
+	status  int
+	rewrite func(w http.ResponseWriter, header http.Header, status int, body []byte)
+
+	// buffered is set for JSON responses once the status is written
+	buffered bool
+	body     bytes.Buffer
+}
+
+// newJSONRewriter creates a writer for w whose JSON responses are sent
+// through rewrite once the handler finishes
+func newJSONRewriter(w http.ResponseWriter, rewrite func(w http.ResponseWriter, header http.Header, status int, body []byte)) *jsonRewriter {
+	return &jsonRewriter{w: w, header: make(http.Header), rewrite: rewrite}
+}
+
+func (rw *jsonRewriter) Header() http.Header { return rw.header }
+
+func (rw *jsonRewriter) WriteHeader(status int) {
+	if rw.status != 0 {
+		return
+	}
+	rw.status = status
+
+	mediaType, _, _ := mime.ParseMediaType(rw.header.Get("Content-Type"))
+	if mediaType == ContentTypeJSON || strings.HasSuffix(mediaType, "+json") {
+		rw.buffered = true
+		return
+	}
+
+	for key, values := range rw.header {
+		rw.w.Header()[key] = values
+	}
+	rw.w.WriteHeader(status)
+}
+
+func (rw *jsonRewriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.buffered {
+		return rw.body.Write(p)
+	}
+	return rw.w.Write(p)
+}
+
+// Flush implements http.Flusher for streamed lists
+func (rw *jsonRewriter) Flush() {
+	if rw.buffered {
+		return
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for dropped connections
+func (rw *jsonRewriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the connection's deadlines
+func (rw *jsonRewriter) Unwrap() http.ResponseWriter { return rw.w }
+
+// finish sends a buffered response through rewrite
+func (rw *jsonRewriter) finish() {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.buffered {
+		rw.rewrite(rw.w, rw.header, rw.status, rw.body.Bytes())
+	}
+}
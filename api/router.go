@@ -12,29 +12,44 @@ func SetupRouter(handler *Handler) *mux.Router {
 	router := mux.NewRouter()
 
 	// Web console routes
-	webHandler := web.NewHandler(handler.service)
+	webHandler := web.NewHandler(handler.service, web.Config{
+		Token:           handler.token,
+		InsecureCookies: handler.webInsecureCookies,
+	})
+	// Static assets are registered ahead of the console so they skip the login
+	router.PathPrefix("/web/static/").Handler(webHandler.Static()).Methods("GET")
 	webRouter := router.PathPrefix("/web").Subrouter()
-	
+	webRouter.Use(webHandler.RequireSession)
+
+	// Session routes
+	webRouter.HandleFunc("/login", webHandler.LoginForm).Methods("GET").Name(web.LoginRoute)
+	webRouter.HandleFunc("/login", webHandler.Login).Methods("POST").Name(web.LoginRoute)
+	webRouter.HandleFunc("/logout", webHandler.Logout).Methods("POST")
+
 	// Dashboard
 	webRouter.HandleFunc("", webHandler.Dashboard).Methods("GET")
 	webRouter.HandleFunc("/", webHandler.Dashboard).Methods("GET")
-	
+
 	// Project routes
 	webRouter.HandleFunc("/projects", webHandler.ListProjects).Methods("GET")
 	webRouter.HandleFunc("/projects", webHandler.CreateProject).Methods("POST")
 	webRouter.HandleFunc("/projects/new", webHandler.NewProjectForm).Methods("GET")
 	webRouter.HandleFunc("/projects/{id}/edit", webHandler.EditProjectForm).Methods("GET")
+	webRouter.HandleFunc("/projects/{id}", webHandler.ProjectDetail).Methods("GET")
 	webRouter.HandleFunc("/projects/{id}", webHandler.UpdateProject).Methods("PUT")
 	webRouter.HandleFunc("/projects/{id}", webHandler.DeleteProject).Methods("DELETE")
-	
+
 	// Instance routes
 	webRouter.HandleFunc("/instances", webHandler.ListInstances).Methods("GET")
 	webRouter.HandleFunc("/instances", webHandler.CreateInstance).Methods("POST")
 	webRouter.HandleFunc("/instances/new", webHandler.NewInstanceForm).Methods("GET")
 	webRouter.HandleFunc("/instances/{id}/edit", webHandler.EditInstanceForm).Methods("GET")
+	webRouter.HandleFunc("/instances/{id}", webHandler.InstanceDetail).Methods("GET")
+	webRouter.HandleFunc("/instances/{id}/start", webHandler.StartInstance).Methods("POST")
+	webRouter.HandleFunc("/instances/{id}/stop", webHandler.StopInstance).Methods("POST")
 	webRouter.HandleFunc("/instances/{id}", webHandler.UpdateInstance).Methods("PUT")
 	webRouter.HandleFunc("/instances/{id}", webHandler.DeleteInstance).Methods("DELETE")
-	
+
 	// Metadata routes
 	webRouter.HandleFunc("/metadata", webHandler.ListMetadata).Methods("GET")
 	webRouter.HandleFunc("/metadata", webHandler.CreateMetadata).Methods("POST")
@@ -43,54 +58,305 @@ func SetupRouter(handler *Handler) *mux.Router {
 	webRouter.HandleFunc("/metadata/update", webHandler.UpdateMetadata).Methods("PUT")
 	webRouter.HandleFunc("/metadata/delete", webHandler.DeleteMetadata).Methods("DELETE")
 
+	// Event routes
+	webRouter.HandleFunc("/events", webHandler.ListEvents).Methods("GET")
+
+	// Metrics
+	router.HandleFunc("/metrics", handler.Metrics).Methods("GET")
+
+	// GraphQL over projects, instances and metadata
+	router.HandleFunc("/graphql", handler.GraphQL).Methods("GET", "POST")
+
 	// API prefix
 	api := router.PathPrefix("/v1").Subrouter()
 
+	// Word errors in the languages clients accept
+	api.Use(handler.localizeErrors)
+
+	// Verify signed requests while their body is untouched
+	api.Use(handler.verifySignatures)
+
+	// Mirror requests to external sinks, exactly as clients saw them
+	api.Use(handler.mirrorRequests)
+
+	// Log requests, including those rejected by the middleware below
+	api.Use(handler.logRequests)
+
+	// Lock out sources that repeatedly fail to authenticate
+	api.Use(handler.lockOutFailedAuth)
+
+	// Reject requests during a simulated maintenance window
+	api.Use(handler.enforceMaintenance)
+
+	// Bound concurrent API requests
+	api.Use(handler.limitConcurrency)
+
+	// Connection and response chaos
+	api.Use(handler.brownout)
+	api.Use(handler.spendFailureBudget)
+	api.Use(handler.dropConnections)
+	api.Use(handler.malformResponses)
+
+	// Refuse requests for resources in other regions, and register those
+	// created here with them
+	api.Use(handler.federate)
+
+	// Error reference, linked from the help_url of errors
+	api.HandleFunc("/errors", handler.ListErrorReasons).Methods("GET")
+	api.HandleFunc("/errors/{reason}", handler.GetErrorReason).Methods("GET")
+
 	// Project routes
 	api.HandleFunc("/projects", handler.CreateProject).Methods("POST")
 	api.HandleFunc("/projects", handler.ListProjects).Methods("GET")
 	api.HandleFunc("/projects/{id}", handler.GetProject).Methods("GET")
 	api.HandleFunc("/projects/{id}", handler.UpdateProject).Methods("PATCH")
 	api.HandleFunc("/projects/{id}", handler.DeleteProject).Methods("DELETE")
+	api.HandleFunc("/projects/{id}:move", handler.MoveProject).Methods("POST")
+	api.HandleFunc("/projects/{id}/ancestry", handler.GetProjectAncestry).Methods("GET")
+	api.HandleFunc("/projects/{id}/iam", handler.GetProjectIAMPolicy).Methods("GET")
+	api.HandleFunc("/projects/{id}/iam", handler.SetProjectIAMPolicy).Methods("PUT")
+	api.HandleFunc("/projects/{id}/usage", handler.GetProjectUsage).Methods("GET")
+
+	// Organization routes
+	api.HandleFunc("/organizations", handler.CreateOrganization).Methods("POST")
+	api.HandleFunc("/organizations", handler.ListOrganizations).Methods("GET")
+	api.HandleFunc("/organizations/{id}", handler.GetOrganization).Methods("GET")
+	api.HandleFunc("/organizations/{id}", handler.UpdateOrganization).Methods("PATCH")
+	api.HandleFunc("/organizations/{id}", handler.DeleteOrganization).Methods("DELETE")
+	api.HandleFunc("/organizations/{id}/children", handler.ListOrganizationChildren).Methods("GET")
+
+	// Folder routes
+	api.HandleFunc("/folders", handler.CreateFolder).Methods("POST")
+	api.HandleFunc("/folders", handler.ListFolders).Methods("GET")
+	api.HandleFunc("/folders/{id}", handler.GetFolder).Methods("GET")
+	api.HandleFunc("/folders/{id}", handler.UpdateFolder).Methods("PATCH")
+	api.HandleFunc("/folders/{id}", handler.DeleteFolder).Methods("DELETE")
+	api.HandleFunc("/folders/{id}:move", handler.MoveFolder).Methods("POST")
+	api.HandleFunc("/folders/{id}/children", handler.ListFolderChildren).Methods("GET")
 
 	// Instance routes
 	api.HandleFunc("/instances", handler.CreateInstance).Methods("POST")
 	api.HandleFunc("/instances", handler.ListInstances).Methods("GET")
+	api.HandleFunc("/instances:fromTemplate", handler.CreateInstanceFromTemplate).Methods("POST")
 	api.HandleFunc("/instances/{id}", handler.GetInstance).Methods("GET")
 	api.HandleFunc("/instances/{id}", handler.UpdateInstance).Methods("PATCH")
 	api.HandleFunc("/instances/{id}", handler.DeleteInstance).Methods("DELETE")
+	api.HandleFunc("/instances/{id}/resize", handler.ResizeInstance).Methods("POST")
+	api.HandleFunc("/instances/{id}/clone", handler.CloneInstance).Methods("POST")
+	api.HandleFunc("/instances/{id}:diff", handler.DiffInstance).Methods("POST")
+	api.HandleFunc("/instances/{id}/console", handler.InstanceConsole).Methods("GET")
+	api.HandleFunc("/instances/{id}/events", handler.ListInstanceEvents).Methods("GET")
+
+	// Availability zone routes
+	api.HandleFunc("/zones", handler.ListZones).Methods("GET")
+	api.HandleFunc("/zones/{zone}", handler.GetZone).Methods("GET")
+
+	// Instance template routes
+	api.HandleFunc("/instance-templates", handler.CreateInstanceTemplate).Methods("POST")
+	api.HandleFunc("/instance-templates", handler.ListInstanceTemplates).Methods("GET")
+	api.HandleFunc("/instance-templates/{id}", handler.GetInstanceTemplate).Methods("GET")
+	api.HandleFunc("/instance-templates/{id}", handler.UpdateInstanceTemplate).Methods("PATCH")
+	api.HandleFunc("/instance-templates/{id}", handler.DeleteInstanceTemplate).Methods("DELETE")
+
+	// Autoscaling group routes
+	api.HandleFunc("/autoscaling-groups", handler.CreateAutoscalingGroup).Methods("POST")
+	api.HandleFunc("/autoscaling-groups", handler.ListAutoscalingGroups).Methods("GET")
+	api.HandleFunc("/autoscaling-groups/{id}", handler.GetAutoscalingGroup).Methods("GET")
+	api.HandleFunc("/autoscaling-groups/{id}", handler.UpdateAutoscalingGroup).Methods("PATCH")
+	api.HandleFunc("/autoscaling-groups/{id}", handler.DeleteAutoscalingGroup).Methods("DELETE")
 
 	// Metadata routes
 	api.HandleFunc("/metadata", handler.CreateMetadata).Methods("POST")
 	api.HandleFunc("/metadata", handler.ListMetadata).Methods("GET").Queries("prefix", "")
 	api.HandleFunc("/metadata", handler.ListMetadata).Methods("GET")
+	api.HandleFunc("/metadata:usage", handler.GetMetadataUsage).Methods("GET")
+	api.HandleFunc("/metadata:export", handler.ExportMetadata).Methods("GET")
+	api.HandleFunc("/metadata:import", handler.ImportMetadata).Methods("POST")
 	api.HandleFunc("/metadata/{id}", handler.GetMetadata).Methods("GET")
 	api.HandleFunc("/metadata/{id}", handler.UpdateMetadata).Methods("PATCH")
 	api.HandleFunc("/metadata/{id}", handler.DeleteMetadata).Methods("DELETE")
 
-	// Add CORS middleware for development
-	router.Use(corsMiddleware)
+	// Consul KV facade over metadata
+	api.HandleFunc("/kv/{key:.*}", handler.GetKV).Methods("GET")
+	api.HandleFunc("/kv/{key:.*}", handler.PutKV).Methods("PUT")
+	api.HandleFunc("/kv/{key:.*}", handler.DeleteKV).Methods("DELETE")
 
-	// Add logging middleware
-	router.Use(loggingMiddleware)
+	// Terraform HTTP state backend over metadata
+	api.HandleFunc("/tfstate/{name}", handler.GetTFState).Methods("GET")
+	api.HandleFunc("/tfstate/{name}", handler.PutTFState).Methods("POST", "PUT")
+	api.HandleFunc("/tfstate/{name}", handler.DeleteTFState).Methods("DELETE")
+	api.HandleFunc("/tfstate/{name}", handler.LockTFState).Methods("LOCK")
+	api.HandleFunc("/tfstate/{name}", handler.UnlockTFState).Methods("UNLOCK")
 
-	return router
-}
+	// Environment routes
+	api.HandleFunc("/environments", handler.CreateEnvironment).Methods("POST")
+	api.HandleFunc("/environments", handler.ListEnvironments).Methods("GET")
+	api.HandleFunc("/environments/{id}", handler.GetEnvironment).Methods("GET")
+	api.HandleFunc("/environments/{id}", handler.UpdateEnvironment).Methods("PATCH")
+	api.HandleFunc("/environments/{id}", handler.DeleteEnvironment).Methods("DELETE")
+	api.HandleFunc("/environments/{id}/summary", handler.GetEnvironmentSummary).Methods("GET")
+	api.HandleFunc("/environments/{id}/resources", handler.AddEnvironmentResources).Methods("POST")
+	api.HandleFunc("/environments/{id}/resources/{type}/{resource_id}", handler.RemoveEnvironmentResource).Methods("DELETE")
 
-// corsMiddleware adds CORS headers
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token, X-Dirt-No-Chaos, X-Dirt-Latency")
+	// Alias routes, mapping external IDs to resources
+	api.HandleFunc("/aliases", handler.CreateAlias).Methods("POST")
+	api.HandleFunc("/aliases", handler.ListAliases).Methods("GET")
+	api.HandleFunc("/aliases/{external_id:.+}", handler.DeleteAlias).Methods("DELETE")
+	api.HandleFunc("/resolve", handler.ResolveAlias).Methods("GET")
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	// Budget routes
+	api.HandleFunc("/budgets", handler.CreateBudget).Methods("POST")
+	api.HandleFunc("/budgets", handler.ListBudgets).Methods("GET")
+	api.HandleFunc("/budgets/{id}", handler.GetBudget).Methods("GET")
+	api.HandleFunc("/budgets/{id}", handler.UpdateBudget).Methods("PATCH")
+	api.HandleFunc("/budgets/{id}", handler.DeleteBudget).Methods("DELETE")
 
-		next.ServeHTTP(w, r)
-	})
+	// Database routes
+	api.HandleFunc("/databases", handler.CreateDatabase).Methods("POST")
+	api.HandleFunc("/databases", handler.ListDatabases).Methods("GET")
+	api.HandleFunc("/databases/{id}", handler.GetDatabase).Methods("GET")
+	api.HandleFunc("/databases/{id}", handler.UpdateDatabase).Methods("PATCH")
+	api.HandleFunc("/databases/{id}", handler.DeleteDatabase).Methods("DELETE")
+
+	// Pub/sub routes
+	api.HandleFunc("/topics", handler.CreateTopic).Methods("POST")
+	api.HandleFunc("/topics", handler.ListTopics).Methods("GET")
+	api.HandleFunc("/topics/{id}:publish", handler.Publish).Methods("POST")
+	api.HandleFunc("/topics/{id}", handler.GetTopic).Methods("GET")
+	api.HandleFunc("/topics/{id}", handler.DeleteTopic).Methods("DELETE")
+	api.HandleFunc("/subscriptions", handler.CreateSubscription).Methods("POST")
+	api.HandleFunc("/subscriptions", handler.ListSubscriptions).Methods("GET")
+	api.HandleFunc("/subscriptions/{id}:pull", handler.Pull).Methods("POST")
+	api.HandleFunc("/subscriptions/{id}:acknowledge", handler.Acknowledge).Methods("POST")
+	api.HandleFunc("/subscriptions/{id}", handler.GetSubscription).Methods("GET")
+	api.HandleFunc("/subscriptions/{id}", handler.UpdateSubscription).Methods("PATCH")
+	api.HandleFunc("/subscriptions/{id}", handler.DeleteSubscription).Methods("DELETE")
+
+	// Secret routes
+	api.HandleFunc("/secrets", handler.CreateSecret).Methods("POST")
+	api.HandleFunc("/secrets", handler.ListSecrets).Methods("GET")
+	api.HandleFunc("/secrets/{id}", handler.GetSecret).Methods("GET")
+	api.HandleFunc("/secrets/{id}", handler.UpdateSecret).Methods("PATCH")
+	api.HandleFunc("/secrets/{id}", handler.DeleteSecret).Methods("DELETE")
+	api.HandleFunc("/secrets/{id}/versions", handler.AddSecretVersion).Methods("POST")
+	api.HandleFunc("/secrets/{id}/versions", handler.ListSecretVersions).Methods("GET")
+	api.HandleFunc("/secrets/{id}/versions/{version}:access", handler.AccessSecretVersion).Methods("GET")
+	api.HandleFunc("/secrets/{id}/versions/{version}:destroy", handler.DestroySecretVersion).Methods("POST")
+	api.HandleFunc("/secrets/{id}/versions/{version}", handler.GetSecretVersion).Methods("GET")
+
+	// Event routes
+	api.HandleFunc("/events", handler.ListEvents).Methods("GET")
+	api.HandleFunc("/projects/{id}/events/ws", handler.ProjectEventStream).Methods("GET")
+
+	// Search routes
+	api.HandleFunc("/search", handler.Search).Methods("GET")
+	api.HandleFunc("/lookup/{type}", handler.Lookup).Methods("GET")
+
+	// Nested collection routes: the children of an existing parent
+	api.HandleFunc("/projects/{id}/instances", handler.nestedCollection(handler.projectParent, "project_id", handler.ListInstances)).Methods("GET")
+	api.HandleFunc("/projects/{id}/autoscaling-groups", handler.nestedCollection(handler.projectParent, "project_id", handler.ListAutoscalingGroups)).Methods("GET")
+	api.HandleFunc("/projects/{id}/budgets", handler.nestedCollection(handler.projectParent, "project_id", handler.ListBudgets)).Methods("GET")
+	api.HandleFunc("/projects/{id}/databases", handler.nestedCollection(handler.projectParent, "project_id", handler.ListDatabases)).Methods("GET")
+	api.HandleFunc("/projects/{id}/topics", handler.nestedCollection(handler.projectParent, "project_id", handler.ListTopics)).Methods("GET")
+	api.HandleFunc("/projects/{id}/subscriptions", handler.nestedCollection(handler.projectParent, "project_id", handler.ListSubscriptions)).Methods("GET")
+	api.HandleFunc("/projects/{id}/secrets", handler.nestedCollection(handler.projectParent, "project_id", handler.ListSecrets)).Methods("GET")
+	api.HandleFunc("/autoscaling-groups/{id}/instances", handler.nestedCollection(handler.autoscalingGroupParent, "autoscaling_group_id", handler.ListInstances)).Methods("GET")
+	api.HandleFunc("/topics/{id}/subscriptions", handler.nestedCollection(handler.topicParent, "topic_id", handler.ListSubscriptions)).Methods("GET")
+
+	// Chaos routes
+	api.HandleFunc("/chaos", handler.GetChaos).Methods("GET")
+	api.HandleFunc("/chaos", handler.PutChaos).Methods("PUT")
+	api.HandleFunc("/chaos/profiles", handler.ListChaosProfiles).Methods("GET")
+	api.HandleFunc("/chaos/profiles/{name}", handler.GetChaosProfile).Methods("GET")
+	api.HandleFunc("/chaos/profiles/{name}", handler.PutChaosProfile).Methods("PUT")
+	api.HandleFunc("/chaos/profiles/{name}", handler.DeleteChaosProfile).Methods("DELETE")
+	api.HandleFunc("/chaos/targets", handler.ListChaosTargets).Methods("GET")
+	api.HandleFunc("/chaos/targets/{resource_id}", handler.GetChaosTarget).Methods("GET")
+	api.HandleFunc("/chaos/targets/{resource_id}", handler.PutChaosTarget).Methods("PUT")
+	api.HandleFunc("/chaos/targets/{resource_id}", handler.DeleteChaosTarget).Methods("DELETE")
+	api.HandleFunc("/chaos/presets", handler.ListChaosPresets).Methods("GET")
+	api.HandleFunc("/chaos/presets/{name}:activate", handler.ActivateChaosPreset).Methods("POST")
+	api.HandleFunc("/chaos/presets/{name}:deactivate", handler.DeactivateChaosPreset).Methods("POST")
+	api.HandleFunc("/chaos/presets/{name}", handler.GetChaosPreset).Methods("GET")
+	api.HandleFunc("/chaos/failure-budget", handler.GetChaosFailureBudget).Methods("GET")
+	api.HandleFunc("/chaos/failure-budget", handler.PutChaosFailureBudget).Methods("PUT")
+	api.HandleFunc("/chaos/failure-budget", handler.DeleteChaosFailureBudget).Methods("DELETE")
+
+	// Admin routes
+	api.HandleFunc("/admin/backup", handler.CreateBackup).Methods("POST")
+	api.HandleFunc("/admin/backups", handler.ListBackups).Methods("GET")
+	api.HandleFunc("/admin/restore", handler.RestoreBackup).Methods("POST")
+	api.HandleFunc("/admin/reset", handler.ResetData).Methods("POST")
+	api.HandleFunc("/admin/maintenance", handler.GetMaintenance).Methods("GET")
+	api.HandleFunc("/admin/maintenance", handler.StartMaintenance).Methods("POST")
+	api.HandleFunc("/admin/maintenance", handler.EndMaintenance).Methods("DELETE")
+	api.HandleFunc("/admin/requests", handler.ListRequests).Methods("GET")
+	api.HandleFunc("/admin/assert", handler.Assert).Methods("POST")
+	api.HandleFunc("/admin/integrity", handler.CheckIntegrity).Methods("GET")
+	api.HandleFunc("/admin/integrity:repair", handler.RepairIntegrity).Methods("POST")
+	api.HandleFunc("/admin/quarantine", handler.ListQuarantined).Methods("GET")
+	api.HandleFunc("/admin/seed", handler.LoadSeed).Methods("POST")
+	api.HandleFunc("/admin/seed:fromTraffic", handler.GenerateSeed).Methods("POST")
+	api.HandleFunc("/admin/cors", handler.GetCORS).Methods("GET")
+	api.HandleFunc("/admin/cors", handler.PutCORS).Methods("PUT")
+	api.HandleFunc("/admin/zones/{zone}/outage", handler.StartZoneOutage).Methods("POST")
+	api.HandleFunc("/admin/zones/{zone}/outage", handler.EndZoneOutage).Methods("DELETE")
+	api.HandleFunc("/admin/zones/{zone}/capacity", handler.SetZoneCapacity).Methods("PUT")
+	api.HandleFunc("/admin/zones/{zone}/capacity", handler.ClearZoneCapacity).Methods("DELETE")
+	api.HandleFunc("/admin/instances/{id}/preempt", handler.PreemptInstance).Methods("POST")
+	api.HandleFunc("/admin/instances/{id}/probe-failures", handler.SetProbeFailures).Methods("PUT")
+	api.HandleFunc("/admin/instances/{id}/probe-failures", handler.ClearProbeFailures).Methods("DELETE")
+
+	// Federation routes, shared by the regions of a federation
+	api.HandleFunc("/federation/peers", handler.ListFederationPeers).Methods("GET")
+	api.HandleFunc("/federation/peers/{region}", handler.PutFederationPeer).Methods("PUT")
+	api.HandleFunc("/federation/peers/{region}", handler.DeleteFederationPeer).Methods("DELETE")
+	api.HandleFunc("/federation/resources", handler.ListFederatedResources).Methods("GET")
+	api.HandleFunc("/federation/resources/{id}", handler.PutFederatedResource).Methods("PUT")
+	api.HandleFunc("/federation/resources/{id}", handler.DeleteFederatedResource).Methods("DELETE")
+
+	// Activity timelines
+	api.HandleFunc("/{collection:"+activityCollections()+"}/{id}/activity", handler.ListActivity).Methods("GET")
+
+	// Plan-time validation
+	api.HandleFunc("/validate/{resourceType}", handler.ValidateResource).Methods("POST")
+
+	// The /v2 API, translated to and from /v1
+	router.PathPrefix("/v2/").Handler(handler.serveV2(router))
+
+	// Echo tracing headers, and serve debugged requests through a router
+	// of their own so every middleware below runs inside it
+	router.Use(handler.debugRequests)
+
+	// Serve reads preferring the replica through a router of their own too,
+	// over a service reading the replica
+	router.Use(handler.readFromReplica)
+
+	// Fit injected latency to the write timeout while the response writer
+	// is still the server's own
+	router.Use(handler.fitChaosToWriteTimeout)
+
+	// Take client details from trusted reverse proxies before anything
+	// uses them
+	router.Use(handler.applyForwarded)
+
+	// Answer preflights for every route. Without a route matching OPTIONS
+	// the middleware below would never run for them.
+	router.Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	// Add CORS headers allowed by the configured policy
+	router.Use(handler.applyCORS)
+
+	// Let API clients send and receive YAML instead of JSON
+	router.Use(handler.negotiateYAML)
+
+	// Format /v1 responses as JSON:API documents when asked to
+	router.Use(handler.formatJSONAPI)
+
+	// Add logging middleware
+	router.Use(loggingMiddleware)
+
+	return router
 }
 
 // loggingMiddleware adds basic request logging
@@ -100,4 +366,4 @@ func loggingMiddleware(next http.Handler) http.Handler {
 		// For now, we'll let the main server handle logging
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Search handles GET /v1/search. IAM members only see resources in projects
+// they hold a role on; organizations, folders and templates are left out.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	results, err := h.service.Search(domain.SearchOptions{
+		Query: query.Get("q"),
+		Types: splitList(query.Get("types")),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if visible != nil {
+		allowed := []domain.SearchResult{}
+		for _, result := range results {
+			projectID := result.ProjectID
+			if result.Type == "project" {
+				projectID = result.ID
+			}
+			if projectID == "" {
+				continue
+			}
+			ok, err := visible(projectID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, result)
+			}
+		}
+		results = allowed
+	}
+
+	h.writeJSON(w, http.StatusOK, results)
+}
+
+// Lookup handles GET /v1/lookup/{type}, which returns the one resource of a
+// type matching ?name= and ?selector=, optionally within ?project=. Several
+// matches fail with AMBIGUOUS_MATCH instead of returning one of them.
+func (h *Handler) Lookup(w http.ResponseWriter, r *http.Request) {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(r.Context(), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	result, err := h.service.Lookup(domain.LookupOptions{
+		Type:     mux.Vars(r)["type"],
+		Project:  query.Get("project"),
+		Name:     query.Get("name"),
+		Selector: query.Get("selector"),
+	}, visible)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
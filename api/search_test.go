@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	visible, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web-visible"})
+	require.NoError(t, err)
+	hidden, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web-hidden"})
+	require.NoError(t, err)
+	for _, p := range []*domain.Project{visible, hidden} {
+		_, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: p.ID, Name: "web-1", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+		require.NoError(t, err)
+	}
+	_, err = h.service.CreateOrganization(domain.CreateOrganizationRequest{Name: "web-org"})
+	require.NoError(t, err)
+	_, err = h.service.SetProjectIAMPolicy(visible.ID, domain.SetIAMPolicyRequest{Bindings: []domain.IAMBinding{
+		{Role: domain.RoleViewer, Members: []string{"token:alice"}},
+	}})
+	require.NoError(t, err)
+
+	search := func(t *testing.T, path, token string) (int, []map[string]interface{}) {
+		r := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var results []map[string]interface{}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+		}
+		return w.Code, results
+	}
+
+	t.Run("heterogeneous results", func(t *testing.T) {
+		status, results := search(t, "/v1/search?q=web-*&types=instance,project", "")
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, results, 4)
+		assert.Equal(t, "project", results[0]["type"])
+		assert.Equal(t, "instance", results[3]["type"])
+		resource := results[3]["resource"].(map[string]interface{})
+		assert.Equal(t, "ubuntu", resource["image"])
+	})
+
+	t.Run("members see their projects only", func(t *testing.T) {
+		status, results := search(t, "/v1/search?q=web-*", "alice")
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, results, 2)
+		for _, result := range results {
+			assert.NotEqual(t, hidden.ID, result["id"])
+			assert.NotEqual(t, hidden.ID, result["project_id"])
+			assert.NotEqual(t, "organization", result["type"])
+		}
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		status, _ := search(t, "/v1/search?q=web&types=volume", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
+
+func TestLookup(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	for _, name := range []string{"web", "api"} {
+		_, err := h.service.CreateProject(domain.CreateProjectRequest{Name: name, Labels: map[string]string{"env": "prod"}})
+		require.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lookup/project?name=web", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result domain.SearchResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "web", result.Name)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/lookup/project?selector=env%3Dprod", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var dirtErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dirtErr))
+	assert.Equal(t, domain.ErrorCodeAmbiguousMatch, dirtErr.Code)
+	assert.Equal(t, float64(2), dirtErr.Details["count"])
+}
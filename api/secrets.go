@@ -0,0 +1,274 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Secret handlers
+
+// CreateSecret handles POST /v1/secrets
+func (h *Handler) CreateSecret(w http.ResponseWriter, r *http.Request) {
+	member, err := h.principal(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateSecretRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.requireRole(r, member, req.ProjectID, domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, req.ProjectID), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	secret, err := h.service.CreateSecret(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, secret.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, secret)
+}
+
+// GetSecret handles GET /v1/secrets/{id}
+func (h *Handler) GetSecret(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSecret(r, mux.Vars(r)["id"], domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	secret, err := h.service.GetSecret(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, secret, secret.UpdatedAt)
+}
+
+// ListSecrets handles GET /v1/secrets
+func (h *Handler) ListSecrets(w http.ResponseWriter, r *http.Request) {
+	visible, err := h.projectVisibility(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectChaos(r, query.Get("project_id")), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	secrets, err := h.service.ListSecrets(domain.SecretListOptions{
+		ProjectID: query.Get("project_id"),
+		Name:      query.Get("name"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if visible != nil {
+		var allowed []*domain.Secret
+		for _, secret := range secrets {
+			ok, err := visible(secret.ProjectID)
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			if ok {
+				allowed = append(allowed, secret)
+			}
+		}
+		secrets = allowed
+	}
+
+	h.writeJSON(w, http.StatusOK, secrets)
+}
+
+// UpdateSecret handles PATCH /v1/secrets/{id}
+func (h *Handler) UpdateSecret(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSecret(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateSecretRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	secret, err := h.service.UpdateSecret(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, secret)
+}
+
+// DeleteSecret handles DELETE /v1/secrets/{id}
+func (h *Handler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSecret(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteSecret(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddSecretVersion handles POST /v1/secrets/{id}/versions
+func (h *Handler) AddSecretVersion(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSecret(r, mux.Vars(r)["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.AddSecretVersionRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	version, err := h.service.AddSecretVersion(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, version)
+}
+
+// ListSecretVersions handles GET /v1/secrets/{id}/versions
+func (h *Handler) ListSecretVersions(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeSecret(r, mux.Vars(r)["id"], domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	versions, err := h.service.ListSecretVersions(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, versions)
+}
+
+// GetSecretVersion handles GET /v1/secrets/{id}/versions/{version}
+func (h *Handler) GetSecretVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.authorizeSecret(r, vars["id"], domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	version, err := h.service.GetSecretVersion(vars["id"], vars["version"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, version)
+}
+
+// AccessSecretVersion handles GET /v1/secrets/{id}/versions/{version}:access
+func (h *Handler) AccessSecretVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.authorizeSecretAccess(r, vars["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	payload, err := h.service.AccessSecretVersion(vars["id"], vars["version"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// Payloads must not linger in shared caches
+	w.Header().Set("Cache-Control", "no-store")
+	h.writeJSON(w, http.StatusOK, payload)
+}
+
+// DestroySecretVersion handles POST /v1/secrets/{id}/versions/{version}:destroy
+func (h *Handler) DestroySecretVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.authorizeSecret(r, vars["id"], domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(resourceChaos(r), r, r.Method); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	version, err := h.service.DestroySecretVersion(vars["id"], vars["version"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, version)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretRoutes(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "secrets"})
+	require.NoError(t, err)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("POST", "/v1/secrets", `{"project_id": "`+project.ID+`", "name": "db-password", "payload": "hunter2", "rotation_period_seconds": 3600}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "hunter2")
+
+	var secret domain.Secret
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &secret))
+	assert.Equal(t, 1, secret.LatestVersion)
+	require.NotNil(t, secret.NextRotationAt)
+
+	w = do("POST", "/v1/secrets/"+secret.ID+"/versions", `{"payload": "correct-horse"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = do("GET", "/v1/secrets/"+secret.ID+"/versions/latest:access", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var payload domain.SecretPayload
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &payload))
+	assert.Equal(t, domain.SecretPayload{SecretID: secret.ID, Version: 2, Payload: "correct-horse"}, payload)
+
+	w = do("GET", "/v1/secrets/"+secret.ID+"/versions/1", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "hunter2")
+
+	w = do("POST", "/v1/secrets/"+secret.ID+"/versions/1:destroy", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusConflict, do("GET", "/v1/secrets/"+secret.ID+"/versions/1:access", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/v1/secrets/"+secret.ID+"/versions/first:access", "").Code)
+
+	w = do("GET", "/v1/secrets/"+secret.ID+"/versions", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var versions []*domain.SecretVersion
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &versions))
+	require.Len(t, versions, 2)
+	assert.Equal(t, domain.SecretVersionDestroyed, versions[1].State)
+
+	assert.Equal(t, http.StatusBadRequest, do("PATCH", "/v1/secrets/"+secret.ID, `{"rotation_period_seconds": 5}`).Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/v1/secrets/"+secret.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/v1/secrets/"+secret.ID+"/versions/latest:access", "").Code)
+}
+
+func TestSecretAccessRequiresScope(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "scoped"})
+	require.NoError(t, err)
+	payload := "s3cret"
+	secret, err := h.service.CreateSecret(domain.CreateSecretRequest{ProjectID: project.ID, Name: "api-key", Payload: &payload})
+	require.NoError(t, err)
+
+	_, err = h.service.SetProjectIAMPolicy(project.ID, domain.SetIAMPolicyRequest{Bindings: []domain.IAMBinding{
+		{Role: domain.RoleEditor, Members: []string{"token:bob"}},
+		{Role: domain.RoleSecretAccessor, Members: []string{"token:app"}},
+		{Role: domain.RoleAdmin, Members: []string{"token:root"}},
+	}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"editor views secret", "GET", "/v1/secrets/" + secret.ID, "bob", http.StatusOK},
+		{"editor cannot access payload", "GET", "/v1/secrets/" + secret.ID + "/versions/latest:access", "bob", http.StatusForbidden},
+		{"accessor accesses payload", "GET", "/v1/secrets/" + secret.ID + "/versions/latest:access", "app", http.StatusOK},
+		{"accessor cannot view secret", "GET", "/v1/secrets/" + secret.ID, "app", http.StatusForbidden},
+		{"admin accesses payload", "GET", "/v1/secrets/" + secret.ID + "/versions/1:access", "root", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/signer"
+)
+
+// DefaultSigningClockSkew is how far the time a request was signed may be
+// from the server clock by default
+const DefaultSigningClockSkew = 5 * time.Minute
+
+// signedRequestKey is the context key of a signedRequest
+type signedRequestKey struct{}
+
+// signedRequest is the outcome of verifying a signed request
+type signedRequest struct {
+	accessKey string
+	err       error
+}
+
+// verifySignatures verifies signed requests before anything reads their
+// body. The outcome is kept on the request so that a bad signature is
+// rejected when the handler authenticates the caller, like a bad token.
+func (h *Handler) verifySignatures(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(h.signingKeys) == 0 || !signer.IsSigned(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		accessKey, err := h.verifySignature(r)
+		ctx := context.WithValue(r.Context(), signedRequestKey{}, &signedRequest{accessKey: accessKey, err: err})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// signedCaller returns the verified signature of a request, or nil if the
+// request is not signed
+func signedCaller(r *http.Request) *signedRequest {
+	signed, _ := r.Context().Value(signedRequestKey{}).(*signedRequest)
+	return signed
+}
+
+// verifySignature checks the signature of a request and returns the access
+// key that signed it. The body is read to check its hash and then restored.
+func (h *Handler) verifySignature(r *http.Request) (string, error) {
+	auth, err := signer.ParseAuthorization(r.Header.Get("Authorization"))
+	if err != nil {
+		return "", domain.UnauthorizedError("invalid signature: " + err.Error())
+	}
+	for _, name := range signer.RequiredHeaders {
+		if !containsHeader(auth.SignedHeaders, name) {
+			return "", domain.UnauthorizedError(fmt.Sprintf("invalid signature: SignedHeaders must include %s", name))
+		}
+	}
+
+	secret, ok := h.signingKeys[auth.AccessKey]
+	if !ok {
+		return "", domain.UnauthorizedError(fmt.Sprintf("unknown access key %q", auth.AccessKey))
+	}
+
+	timestamp := r.Header.Get(signer.DateHeader)
+	signedAt, err := time.Parse(signer.DateFormat, timestamp)
+	if err != nil {
+		return "", domain.UnauthorizedError(fmt.Sprintf("invalid signature: %s must be formatted as %s", signer.DateHeader, signer.DateFormat))
+	}
+	if timestamp[:8] != auth.Date {
+		return "", domain.UnauthorizedError(fmt.Sprintf("invalid signature: credential date %s does not match %s", auth.Date, signer.DateHeader))
+	}
+	now := time.Now()
+	if skew := now.Sub(signedAt); skew > h.signingClockSkew || skew < -h.signingClockSkew {
+		return "", domain.NewError(domain.ErrorCodeUnauthorized, "request time is outside the allowed clock skew", map[string]interface{}{
+			"request_time": signedAt.Format(time.RFC3339),
+			"server_time":  now.UTC().Format(time.RFC3339),
+			"max_skew":     h.signingClockSkew.String(),
+		})
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(r.Body, h.maxBodyBytes+1))
+		if err != nil {
+			return "", domain.InvalidInputError("failed to read request body", nil)
+		}
+		if int64(len(body)) > h.maxBodyBytes {
+			return "", domain.InvalidInputError("request body too large", map[string]interface{}{
+				"max_bytes": h.maxBodyBytes,
+			})
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	payloadHash := signer.HashPayload(body)
+	if r.Header.Get(signer.ContentHashHeader) != payloadHash {
+		return "", domain.UnauthorizedError(fmt.Sprintf("invalid signature: %s does not match the request body", signer.ContentHashHeader))
+	}
+
+	canonicalRequest := signer.CanonicalRequest(r, auth.SignedHeaders, payloadHash)
+	stringToSign := signer.StringToSign(timestamp, auth.Scope(), canonicalRequest)
+	if !hmac.Equal([]byte(signer.Signature(secret, auth.Date, stringToSign)), []byte(auth.Signature)) {
+		// Echo what was signed, as SigV4 does, so clients can find where
+		// their canonical request differs
+		return "", domain.NewError(domain.ErrorCodeUnauthorized, "signature does not match", map[string]interface{}{
+			"canonical_request": canonicalRequest,
+			"string_to_sign":    stringToSign,
+		})
+	}
+	return auth.AccessKey, nil
+}
+
+// containsHeader reports whether a signed header list names header
+func containsHeader(headers []string, header string) bool {
+	for _, h := range headers {
+		if h == header {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/signer"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_SignedRequests(t *testing.T) {
+	svc := newTestService(t)
+	router := SetupRouter(NewHandler(svc, chaos.NewChaosService(), Config{
+		SigningKeys: map[string]string{"AKADMIN": "admin-secret", "AKDEV": "dev-secret"},
+	}))
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	_, err = svc.SetProjectIAMPolicy(project.ID, domain.SetIAMPolicyRequest{Bindings: []domain.IAMBinding{
+		{Role: domain.RoleViewer, Members: []string{"token:AKDEV"}},
+	}})
+	require.NoError(t, err)
+
+	body := `{"name":"signed"}`
+	signed := func(accessKey, secret string, at time.Time, tamper func(r *http.Request)) *http.Request {
+		r := httptest.NewRequest("POST", "/v1/projects?validate_only=false", strings.NewReader(body))
+		signer.Sign(r, []byte(body), accessKey, secret, at)
+		if tamper != nil {
+			tamper(r)
+		}
+		return r
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		request  *http.Request
+		expected int
+		errorMsg string
+	}{
+		{"valid signature", signed("AKADMIN", "admin-secret", now, nil), http.StatusCreated, ""},
+		// Authenticated, so the duplicate name is what fails
+		{"within clock skew", signed("AKADMIN", "admin-secret", now.Add(-4*time.Minute), nil), http.StatusConflict, ""},
+		{"outside clock skew", signed("AKADMIN", "admin-secret", now.Add(-10*time.Minute), nil), http.StatusUnauthorized, "clock skew"},
+		{"unknown access key", signed("AKNOPE", "admin-secret", now, nil), http.StatusUnauthorized, "unknown access key"},
+		{"wrong secret", signed("AKADMIN", "guess", now, nil), http.StatusUnauthorized, "signature does not match"},
+		{"tampered body", signed("AKADMIN", "admin-secret", now, func(r *http.Request) {
+			r.Body = http.NoBody
+		}), http.StatusUnauthorized, "does not match the request body"},
+		{"tampered query", signed("AKADMIN", "admin-secret", now, func(r *http.Request) {
+			r.URL.RawQuery = "validate_only=true"
+		}), http.StatusUnauthorized, "signature does not match"},
+		{"date header unsigned", signed("AKADMIN", "admin-secret", now, func(r *http.Request) {
+			r.Header.Set("Authorization", strings.Replace(r.Header.Get("Authorization"), ";x-dirt-date", "", 1))
+		}), http.StatusUnauthorized, "SignedHeaders must include x-dirt-date"},
+		{"IAM-bound access key", signed("AKDEV", "dev-secret", now, nil), http.StatusForbidden, ""},
+		{"unsigned", httptest.NewRequest("GET", "/v1/projects", nil), http.StatusUnauthorized, "missing authorization header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, tt.request)
+			require.Equal(t, tt.expected, w.Code, w.Body.String())
+			if tt.errorMsg != "" {
+				assert.Contains(t, w.Body.String(), tt.errorMsg)
+			}
+		})
+	}
+
+	t.Run("mismatch details", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, signed("AKADMIN", "guess", now, nil))
+
+		var dirtErr domain.DirtError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dirtErr))
+		assert.Contains(t, dirtErr.Details["canonical_request"], "POST\n/v1/projects\nvalidate_only=false\n")
+		assert.Contains(t, dirtErr.Details["string_to_sign"], signer.Scheme)
+	})
+}
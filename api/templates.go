@@ -0,0 +1,260 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Instance template handlers
+
+// CreateInstanceTemplate handles POST /v1/instance-templates
+func (h *Handler) CreateInstanceTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateInstanceTemplateRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	tmpl, err := h.service.CreateInstanceTemplate(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, tmpl)
+}
+
+// GetInstanceTemplate handles GET /v1/instance-templates/{id}
+func (h *Handler) GetInstanceTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(resourceChaos(r), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	tmpl, err := h.service.GetInstanceTemplate(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeResource(w, r, tmpl, tmpl.UpdatedAt)
+}
+
+// ListInstanceTemplates handles GET /v1/instance-templates
+func (h *Handler) ListInstanceTemplates(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(r.Context(), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	templates, err := h.service.ListInstanceTemplates(domain.InstanceTemplateListOptions{
+		Name: r.URL.Query().Get("name"),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, templates)
+}
+
+// UpdateInstanceTemplate handles PATCH /v1/instance-templates/{id}
+func (h *Handler) UpdateInstanceTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(resourceChaos(r), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.UpdateInstanceTemplateRequest
+	if err := h.decodePatch(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	tmpl, err := h.service.UpdateInstanceTemplate(mux.Vars(r)["id"], req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, tmpl)
+}
+
+// DeleteInstanceTemplate handles DELETE /v1/instance-templates/{id}
+func (h *Handler) DeleteInstanceTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticate(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(resourceChaos(r), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteInstanceTemplate(mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateInstanceFromTemplate handles POST /v1/instances:fromTemplate
+func (h *Handler) CreateInstanceFromTemplate(w http.ResponseWriter, r *http.Request) {
+	member, err := h.principal(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CreateInstanceFromTemplateRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.requireRole(r, member, req.ProjectID, domain.RoleEditor); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.projectChaos(r, req.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	svc := h.service.WithActor(h.actor(r))
+	instance, err := svc.CreateInstanceFromTemplate(req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.replayCreate(h.projectChaos(r, instance.ProjectID), r, func() error {
+		_, err := svc.WithReason(domain.ReasonChaos).CreateInstanceFromTemplate(req)
+		return err
+	}); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, instance.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var defaults defaultsApplied
+	if req.Status == "" {
+		defaults.add("status", instance.Status)
+	}
+	if req.Zone == "" {
+		defaults.add("zone", instance.Zone)
+	}
+	if req.Class == "" {
+		defaults.add("class", instance.Class)
+	}
+	defaults.setHeader(w)
+
+	h.writeJSON(w, http.StatusCreated, instance)
+}
+
+// CloneInstance handles POST /v1/instances/{id}/clone
+func (h *Handler) CloneInstance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.authorizeInstance(r, id, domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyInstancesChaos(h.instanceChaos(r, id), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var req domain.CloneInstanceRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	// The clone lands in the source project unless another one is named
+	var err error
+	if req.ProjectID != "" {
+		err = h.authorizeProject(r, req.ProjectID, domain.RoleEditor)
+	} else {
+		err = h.authorizeInstance(r, id, domain.RoleEditor)
+	}
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	svc := h.service.WithActor(h.actor(r))
+	instance, err := svc.CloneInstance(id, req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.replayCreate(h.projectChaos(r, instance.ProjectID), r, func() error {
+		_, err := svc.WithReason(domain.ReasonChaos).CloneInstance(id, req)
+		return err
+	}); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyCreateChaos(h.projectChaos(r, instance.ProjectID), r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var defaults defaultsApplied
+	if req.ProjectID == "" {
+		defaults.add("project_id", instance.ProjectID)
+	}
+	if req.Status == "" {
+		defaults.add("status", instance.Status)
+	}
+	if req.Zone == "" {
+		defaults.add("zone", instance.Zone)
+	}
+	defaults.setHeader(w)
+
+	h.writeJSON(w, http.StatusCreated, instance)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/signer"
+	"github.com/hypertf/dirtcloud-server/service"
+)
+
+// Tenancy modes
+const (
+	// TenancyToken gives every bearer token or signing access key its own
+	// database
+	TenancyToken = "token"
+	// TenancyHeader gives every X-Dirt-Tenant header value its own database
+	TenancyHeader = "header"
+	// TenancyClaim gives every tenant named by the tenant claim of a JWT its
+	// own database
+	TenancyClaim = "claim"
+)
+
+// TenantHeader names the tenant of a request in header tenancy mode
+const TenantHeader = "X-Dirt-Tenant"
+
+// tenantNamePattern restricts tenant names to values that are safe file names
+var tenantNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,62}$`)
+
+// TenantOpener returns the service for a tenant, creating its storage on first
+// use. Tenant keys are safe to use as file names.
+type TenantOpener func(tenant string) (*service.Service, error)
+
+// TenantRouter serves each tenant from its own service and database. Requests
+// that name no tenant are served by the default handler. Chaos and in-flight
+// limits are shared by all tenants.
+//
+// A tenant is only opened for a caller the default handler authenticates, so
+// that unauthenticated clients cannot create databases, and at most
+// maxTenants are opened.
+type TenantRouter struct {
+	mode       string
+	base       *Handler
+	open       TenantOpener
+	fallback   http.Handler
+	maxTenants int
+
+	mu      sync.Mutex
+	routers map[string]http.Handler
+}
+
+// NewTenantRouter creates a tenant router for the given mode. The base handler
+// serves requests that name no tenant and is the template for tenant handlers.
+// maxTenants caps the tenants opened; 0 leaves them unlimited.
+func NewTenantRouter(mode string, base *Handler, open TenantOpener, maxTenants int) (*TenantRouter, error) {
+	switch mode {
+	case TenancyToken, TenancyHeader:
+	case TenancyClaim:
+		if base.jwt == nil {
+			return nil, fmt.Errorf("tenancy mode %q requires JWT validation", mode)
+		}
+	default:
+		return nil, fmt.Errorf("unknown tenancy mode %q (expected %q, %q or %q)", mode, TenancyToken, TenancyHeader, TenancyClaim)
+	}
+
+	return &TenantRouter{
+		mode:       mode,
+		base:       base,
+		open:       open,
+		fallback:   SetupRouter(base),
+		maxTenants: maxTenants,
+		routers:    make(map[string]http.Handler),
+	}, nil
+}
+
+// ServeHTTP dispatches a request to the router of its tenant
+func (t *TenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant, err := t.tenantKey(r)
+	if err != nil {
+		t.base.writeError(w, err)
+		return
+	}
+	if tenant == "" {
+		t.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	router, err := t.router(tenant, r)
+	if err != nil {
+		t.base.writeError(w, err)
+		return
+	}
+	router.ServeHTTP(w, r)
+}
+
+// tenantKey derives the storage key of a request's tenant, or "" for the
+// default tenant. Tokens are hashed so they never appear in file names.
+func (t *TenantRouter) tenantKey(r *http.Request) (string, error) {
+	if t.mode == TenancyToken {
+		token := bearerToken(r)
+		if auth, err := signer.ParseAuthorization(r.Header.Get("Authorization")); err == nil {
+			// The tenant's own router verifies the signature
+			token = auth.AccessKey
+		}
+		if token == "" {
+			return "", nil
+		}
+		sum := sha256.Sum256([]byte(token))
+		return "token-" + hex.EncodeToString(sum[:8]), nil
+	}
+
+	name, field := r.Header.Get(TenantHeader), TenantHeader
+	if t.mode == TenancyClaim {
+		caller, err := t.base.jwtCaller(r)
+		if err != nil || caller == nil {
+			return "", err
+		}
+		name, field = caller.tenant, t.base.jwt.config.TenantClaim
+	}
+
+	if name == "" {
+		return "", nil
+	}
+	if !tenantNamePattern.MatchString(name) {
+		return "", domain.ValidationError([]domain.FieldViolation{{
+			Field:   field,
+			Message: fmt.Sprintf("must be 1-63 letters, digits, '-' or '_' starting with a letter or digit (got %q)", name),
+		}})
+	}
+	return name, nil
+}
+
+// authenticate checks the credentials of a request that would open a tenant
+// against the default handler. Tokens bound in a tenant's IAM policies can
+// only be checked once the tenant is open, so they cannot open one.
+func (t *TenantRouter) authenticate(r *http.Request) error {
+	if t.mode == TenancyClaim {
+		return nil // tenantKey verified the JWT naming the tenant
+	}
+	if len(t.base.signingKeys) > 0 && signer.IsSigned(r) {
+		_, err := t.base.verifySignature(r)
+		return err
+	}
+	if caller, err := t.base.jwtCaller(r); err != nil || caller != nil {
+		return err
+	}
+	return t.base.checkToken(r)
+}
+
+// router returns the router of a tenant, opening the tenant on first use for
+// an authenticated request
+func (t *TenantRouter) router(tenant string, r *http.Request) (http.Handler, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if router, ok := t.routers[tenant]; ok {
+		return router, nil
+	}
+
+	if err := t.authenticate(r); err != nil {
+		return nil, err
+	}
+	if t.maxTenants > 0 && len(t.routers) >= t.maxTenants {
+		return nil, domain.QuotaExceededError("tenant", "max_tenants", t.maxTenants, len(t.routers)+1)
+	}
+
+	svc, err := t.open(tenant)
+	if err != nil {
+		return nil, domain.InternalError(fmt.Sprintf("failed to open tenant: %v", err))
+	}
+
+	h := *t.base
+	h.service = svc
+	router := SetupRouter(&h)
+	t.routers[tenant] = router
+
+	return router, nil
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listProjectNames lists project names as seen by a request with the given header
+func listProjectNames(t *testing.T, router http.Handler, header, value string) []string {
+	t.Helper()
+
+	r := httptest.NewRequest("GET", "/v1/projects", nil)
+	if value != "" {
+		r.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var projects []domain.Project
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projects))
+	var names []string
+	for _, p := range projects {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestTenantRouter(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		header string
+		first  string
+		second string
+	}{
+		{name: "header tenancy", mode: TenancyHeader, header: TenantHeader, first: "ci-1", second: "ci-2"},
+		{name: "token tenancy", mode: TenancyToken, header: "Authorization", first: "Bearer one", second: "Bearer two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := newTestHandler(t)
+			var opened []string
+			tenants, err := NewTenantRouter(tt.mode, base, func(tenant string) (*service.Service, error) {
+				opened = append(opened, tenant)
+				return newTestService(t), nil
+			}, 0)
+			require.NoError(t, err)
+
+			for _, value := range []string{"", tt.first, tt.second} {
+				r := httptest.NewRequest("POST", "/v1/projects", strings.NewReader(`{"name":"shared-name"}`))
+				if value != "" {
+					r.Header.Set(tt.header, value)
+				}
+				w := httptest.NewRecorder()
+				tenants.ServeHTTP(w, r)
+				require.Equal(t, http.StatusCreated, w.Code, "tenant %q: %s", value, w.Body.String())
+			}
+
+			// Each tenant sees only its own project
+			assert.Equal(t, []string{"shared-name"}, listProjectNames(t, tenants, tt.header, tt.first))
+			assert.Equal(t, []string{"shared-name"}, listProjectNames(t, tenants, tt.header, tt.second))
+			assert.Equal(t, []string{"shared-name"}, listProjectNames(t, tenants, tt.header, ""))
+
+			// Tenants are opened once, under file-name-safe keys
+			require.Len(t, opened, 2)
+			for _, key := range opened {
+				assert.Regexp(t, tenantNamePattern, key)
+			}
+			if tt.mode == TenancyToken {
+				assert.NotContains(t, opened[0], "one", "tokens must not appear in tenant keys")
+			}
+		})
+	}
+}
+
+func TestTenantRouter_InvalidTenant(t *testing.T) {
+	tenants, err := NewTenantRouter(TenancyHeader, newTestHandler(t), func(string) (*service.Service, error) {
+		t.Fatal("invalid tenants must not be opened")
+		return nil, nil
+	}, 0)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/v1/projects", nil)
+	r.Header.Set(TenantHeader, "../etc")
+	w := httptest.NewRecorder()
+	tenants.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), TenantHeader)
+
+	_, err = NewTenantRouter("database", newTestHandler(t), nil, 0)
+	assert.Error(t, err)
+}
+
+func TestTenantRouter_OpensOnlyForAuthenticatedCallers(t *testing.T) {
+	base := NewHandler(newTestService(t), chaos.NewChaosService(), Config{Token: "secret"})
+	var opened []string
+	tenants, err := NewTenantRouter(TenancyHeader, base, func(tenant string) (*service.Service, error) {
+		opened = append(opened, tenant)
+		return newTestService(t), nil
+	}, 1)
+	require.NoError(t, err)
+
+	request := func(tenant, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/v1/projects", nil)
+		r.Header.Set(TenantHeader, tenant)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		tenants.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request("ci-1", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request("ci-1", "guess").Code)
+	assert.Empty(t, opened)
+
+	assert.Equal(t, http.StatusOK, request("ci-1", "secret").Code)
+
+	// The cap stops further tenants from being opened
+	w := request("ci-2", "secret")
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), domain.ErrorCodeQuotaExceeded)
+	assert.Equal(t, []string{"ci-1"}, opened)
+}
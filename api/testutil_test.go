@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHandler creates a handler backed by a file SQLite database so that
+// concurrent requests share the same data
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	return NewHandler(newTestService(t), chaos.NewChaosService(), Config{})
+}
+
+// newTestService creates a service backed by its own file SQLite database
+func newTestService(t *testing.T) *service.Service {
+	t.Helper()
+
+	return newTestServiceWithConfig(t, service.Config{})
+}
+
+// newTestServiceWithConfig is newTestService with service settings
+func newTestServiceWithConfig(t *testing.T, config service.Config) *service.Service {
+	t.Helper()
+
+	db, err := sqlite.NewDB("file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_fk=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return service.NewService(service.NewSQLiteRepositories(db, filepath.Join(t.TempDir(), "backups")), config)
+}
+
+// doRequest sends a request through the handler's router
+func doRequest(t *testing.T, h *Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	SetupRouter(h).ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
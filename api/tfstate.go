@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// The Terraform HTTP backend protocol, served under /v1/tfstate/{name} with
+// the default methods: GET, POST and DELETE on the state, LOCK and UNLOCK on
+// its lock. A backend block pointing address, lock_address and
+// unlock_address at the same URL stores its state here.
+
+// tfStateAuth accepts the password of HTTP basic auth, the only credentials
+// Terraform's HTTP backend sends, as the request's bearer token
+func tfStateAuth(r *http.Request) {
+	if _, password, ok := r.BasicAuth(); ok {
+		r.Header.Set("Authorization", "Bearer "+password)
+	}
+}
+
+// tfStatePreamble authenticates a state request and applies metadata chaos,
+// returning the state name
+func (h *Handler) tfStatePreamble(r *http.Request) (string, error) {
+	tfStateAuth(r)
+	if err := h.authenticate(r); err != nil {
+		return "", err
+	}
+
+	if err := h.chaosService.ApplyMetadataChaos(r.Context(), r); err != nil {
+		return "", err
+	}
+	return mux.Vars(r)["name"], nil
+}
+
+// writeTFStateError writes err, answering a locked state as the protocol
+// expects: 423 Locked with the holder's lock info as the body, which
+// Terraform shows to the user
+func (h *Handler) writeTFStateError(w http.ResponseWriter, err error) {
+	if de, ok := domain.AsDirtError(err); ok && de.Code == domain.ErrorCodeFailedPrecondition {
+		if lock, ok := de.Details["lock"].(json.RawMessage); ok {
+			w.Header().Set("Content-Type", ContentTypeJSON)
+			w.WriteHeader(http.StatusLocked)
+			w.Write(lock)
+			return
+		}
+	}
+	h.writeError(w, err)
+}
+
+// readTFStateBody reads a state or lock info body, which is JSON text
+func (h *Handler) readTFStateBody(w http.ResponseWriter, r *http.Request) (string, error) {
+	body, err := h.readBody(w, r)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(body) {
+		return "", domain.InvalidInputError("body must be UTF-8 text", nil)
+	}
+	return string(body), nil
+}
+
+// GetTFState handles GET /v1/tfstate/{name}, returning the stored state.
+// Terraform reads the 404 for a state never written as an empty one.
+func (h *Handler) GetTFState(w http.ResponseWriter, r *http.Request) {
+	name, err := h.tfStatePreamble(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	state, err := h.service.GetTFState(name)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(state))
+}
+
+// PutTFState handles POST /v1/tfstate/{name}, storing the body as the state.
+// Terraform passes the ID of the lock it holds as ?ID.
+func (h *Handler) PutTFState(w http.ResponseWriter, r *http.Request) {
+	name, err := h.tfStatePreamble(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	state, err := h.readTFStateBody(w, r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.PutTFState(name, state, r.URL.Query().Get("ID")); err != nil {
+		h.writeTFStateError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteTFState handles DELETE /v1/tfstate/{name}
+func (h *Handler) DeleteTFState(w http.ResponseWriter, r *http.Request) {
+	name, err := h.tfStatePreamble(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteTFState(name, r.URL.Query().Get("ID")); err != nil {
+		h.writeTFStateError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// LockTFState handles LOCK /v1/tfstate/{name}, the body being Terraform's
+// lock info
+func (h *Handler) LockTFState(w http.ResponseWriter, r *http.Request) {
+	name, err := h.tfStatePreamble(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	info, err := h.readTFStateBody(w, r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.LockTFState(name, info); err != nil {
+		h.writeTFStateError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// UnlockTFState handles UNLOCK /v1/tfstate/{name}. The body is the lock info
+// the lock was taken with, or empty for terraform force-unlock.
+func (h *Handler) UnlockTFState(w http.ResponseWriter, r *http.Request) {
+	name, err := h.tfStatePreamble(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	info, err := h.readTFStateBody(w, r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.service.UnlockTFState(name, info); err != nil {
+		h.writeTFStateError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTFState_Lifecycle(t *testing.T) {
+	h := newTestHandler(t)
+	const state = `{"version":4,"serial":1,"resources":[]}`
+	const lock = `{"ID":"lock-1","Operation":"OperationTypeApply","Who":"ci@runner"}`
+
+	w := doRequest(t, h, "GET", "/v1/tfstate/prod", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(t, h, "LOCK", "/v1/tfstate/prod", lock)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Taking the same lock again is fine; another one is refused with the
+	// holder's lock info
+	w = doRequest(t, h, "LOCK", "/v1/tfstate/prod", lock)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(t, h, "LOCK", "/v1/tfstate/prod", `{"ID":"lock-2"}`)
+	assert.Equal(t, http.StatusLocked, w.Code)
+	assert.JSONEq(t, lock, w.Body.String())
+
+	w = doRequest(t, h, "POST", "/v1/tfstate/prod", state)
+	assert.Equal(t, http.StatusLocked, w.Code, "a write without the lock is refused")
+	w = doRequest(t, h, "POST", "/v1/tfstate/prod?ID=lock-1", state)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = doRequest(t, h, "GET", "/v1/tfstate/prod", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, state, w.Body.String())
+
+	w = doRequest(t, h, "UNLOCK", "/v1/tfstate/prod", `{"ID":"lock-2"}`)
+	assert.Equal(t, http.StatusLocked, w.Code)
+	w = doRequest(t, h, "UNLOCK", "/v1/tfstate/prod", lock)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = doRequest(t, h, "DELETE", "/v1/tfstate/prod", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(t, h, "GET", "/v1/tfstate/prod", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTFState_ForceUnlock(t *testing.T) {
+	h := newTestHandler(t)
+
+	w := doRequest(t, h, "LOCK", "/v1/tfstate/prod", `{"ID":"stale"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = doRequest(t, h, "UNLOCK", "/v1/tfstate/prod", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(t, h, "POST", "/v1/tfstate/prod", `{}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestTFState_InvalidLock(t *testing.T) {
+	h := newTestHandler(t)
+
+	for _, body := range []string{"", "not json", `{"Who":"me"}`} {
+		w := doRequest(t, h, "LOCK", "/v1/tfstate/prod", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "body %q", body)
+	}
+}
+
+func TestTFState_BasicAuth(t *testing.T) {
+	h := newTestHandler(t)
+	h.token = "secret"
+
+	w := doRequest(t, h, "GET", "/v1/tfstate/prod", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/v1/tfstate/prod", strings.NewReader(`{}`))
+	r.SetBasicAuth("terraform", "secret")
+	SetupRouter(h).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
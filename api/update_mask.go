@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// UpdateMaskHeader sets the update mask of a PATCH request when the
+// update_mask query parameter is absent
+const UpdateMaskHeader = "X-Fields"
+
+// decodePatch decodes a PATCH body like decodeJSON and applies the request's
+// update mask, a comma separated list of JSON field names. Fields not named
+// in the mask are reset to their zero value, which update requests treat as
+// "leave unchanged", so only masked fields are modified whatever else the
+// body sets. Without a mask every field in the body applies.
+func (h *Handler) decodePatch(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := h.decodeJSON(w, r, v); err != nil {
+		return err
+	}
+
+	mask := r.URL.Query().Get("update_mask")
+	if mask == "" {
+		mask = r.Header.Get(UpdateMaskHeader)
+	}
+	if fields := splitList(mask); len(fields) > 0 {
+		return applyUpdateMask(v, fields)
+	}
+	return nil
+}
+
+// applyUpdateMask zeroes the fields of the struct pointed to by v that are not
+// named in mask. Names the struct does not have are rejected, each with an
+// update_mask violation listing the fields that can be updated.
+func applyUpdateMask(v interface{}, mask []string) error {
+	value := reflect.ValueOf(v).Elem()
+	t := value.Type()
+
+	keep := make(map[string]bool, len(mask))
+	for _, field := range mask {
+		keep[field] = true
+	}
+
+	var allowed []string
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := jsonName(t.Field(i))
+		if !ok {
+			continue
+		}
+		allowed = append(allowed, name)
+		if keep[name] {
+			delete(keep, name)
+		} else {
+			value.Field(i).Set(reflect.Zero(t.Field(i).Type))
+		}
+	}
+
+	if len(keep) > 0 {
+		unknown := make([]string, 0, len(keep))
+		for field := range keep {
+			unknown = append(unknown, field)
+		}
+		sort.Strings(unknown)
+		sort.Strings(allowed)
+
+		var v domain.FieldViolations
+		for _, field := range unknown {
+			v.Add("update_mask", fmt.Sprintf("names %q, which cannot be updated; allowed fields are %s", field, strings.Join(allowed, ", ")))
+		}
+		return v.Err()
+	}
+
+	return nil
+}
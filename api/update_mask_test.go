@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyUpdateMask(t *testing.T) {
+	name, image := "web", "debian"
+	cpu := 4
+
+	tests := []struct {
+		name        string
+		mask        []string
+		expected    domain.UpdateInstanceRequest
+		expectError bool
+	}{
+		{
+			name:     "keeps masked fields",
+			mask:     []string{"cpu", "image"},
+			expected: domain.UpdateInstanceRequest{CPU: &cpu, Image: &image},
+		},
+		{
+			name:     "masked field missing from the body stays unset",
+			mask:     []string{"status"},
+			expected: domain.UpdateInstanceRequest{},
+		},
+		{
+			name:        "unknown field",
+			mask:        []string{"cpu", "rack"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := domain.UpdateInstanceRequest{Name: &name, CPU: &cpu, Image: &image, Labels: map[string]string{"a": "b"}}
+			err := applyUpdateMask(&req, tt.mask)
+			if tt.expectError {
+				require.Error(t, err)
+				assert.True(t, domain.IsInvalidInput(err))
+				violations := err.(*domain.DirtError).Details["fields"].([]domain.FieldViolation)
+				require.Len(t, violations, 1)
+				assert.Equal(t, "update_mask", violations[0].Field)
+				assert.Contains(t, violations[0].Message, `"rack"`)
+				assert.Contains(t, violations[0].Message, "cpu, deletion_protection")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, req)
+		})
+	}
+}
+
+func TestUpdateMask(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "web", Labels: map[string]string{"env": "dev"}})
+	require.NoError(t, err)
+
+	patch := func(query string, header string, body string) *domain.Project {
+		r := httptest.NewRequest("PATCH", "/v1/projects/"+project.ID+query, strings.NewReader(body))
+		if header != "" {
+			r.Header.Set(UpdateMaskHeader, header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var updated domain.Project
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		return &updated
+	}
+
+	updated := patch("?update_mask=labels", "", `{"name":"ignored","labels":{"env":"prod"}}`)
+	assert.Equal(t, "web", updated.Name)
+	assert.Equal(t, map[string]string{"env": "prod"}, updated.Labels)
+
+	updated = patch("", "name", `{"name":"renamed","labels":{"env":"ignored"}}`)
+	assert.Equal(t, "renamed", updated.Name)
+	assert.Equal(t, map[string]string{"env": "prod"}, updated.Labels)
+
+	// Without a mask every field in the body applies
+	updated = patch("", "", `{"name":"web","labels":{"env":"dev"}}`)
+	assert.Equal(t, "web", updated.Name)
+	assert.Equal(t, map[string]string{"env": "dev"}, updated.Labels)
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// GetProjectUsage handles GET /v1/projects/{id}/usage. The period query
+// parameter selects a month such as 2024-03, defaulting to the current one.
+func (h *Handler) GetProjectUsage(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.authorizeProject(r, id, domain.RoleViewer); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if err := h.chaosService.ApplyProjectsChaos(h.projectResourceChaos(r, id), r, "GET"); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	report, err := h.service.GetProjectUsage(id, r.URL.Query().Get("period"), time.Now())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, report)
+}
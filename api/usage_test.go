@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProjectUsage(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "billing"})
+	require.NoError(t, err)
+	_, err = h.service.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 2, MemoryMB: 1024, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+	require.NoError(t, h.service.MeterUsage(time.Hour, time.Now()))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/projects/"+project.ID+"/usage", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report domain.UsageReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, project.ID, report.ProjectID)
+	assert.Equal(t, time.Now().UTC().Format(domain.UsagePeriodLayout), report.Period)
+	assert.InDelta(t, 1, report.InstanceHours, 1e-9)
+	assert.InDelta(t, 2, report.VCPUHours, 1e-9)
+	assert.Positive(t, report.Cost)
+	assert.Greater(t, report.EstimatedMonthly, report.Cost)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/projects/"+project.ID+"/usage?period=soon", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	metrics := httptest.NewRecorder()
+	h.Metrics(metrics, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, metrics.Body.String(),
+		`dirt_project_vcpu_hours{project_id="`+project.ID+`",period="`+report.Period+`"} 2`)
+}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// The /v2 API serves the same resources as /v1 in deliberately different
+// shapes, so clients can test supporting several API versions and migrating
+// between them against one server:
+//
+//   - fields and query parameters are camelCase instead of snake_case
+//   - responses are enveloped as {"data": ...}
+//   - errors are {"error": {"code": 404, "status": "NOT_FOUND", ...}}
+//
+// It is served by translating each request to /v1 and the response back, so
+// everything /v1 does, chaos included, applies to /v2 as well.
+
+// v2OpaqueFields hold user data, such as label maps and recorded request
+// bodies, whose keys are kept as they are in both directions
+var v2OpaqueFields = map[string]bool{
+	"labels":            true,
+	"effective_labels":  true,
+	"attributes":        true,
+	"types":             true,
+	"instance_statuses": true,
+	"where":             true,
+	"equals":            true,
+	"request_headers":   true,
+	"request_body":      true,
+	"response_headers":  true,
+	"response_body":     true,
+	"data":              true,
+}
+
+// v2FieldNameValues hold the names of fields, which are translated like keys
+var v2FieldNameValues = map[string]bool{
+	"field": true,
+}
+
+// v2QueryFieldNames are the query parameters whose values list field names
+var v2QueryFieldNames = map[string]bool{
+	"fields": true,
+	"sort":   true,
+}
+
+// facadeRoutes keep the formats of the tools they emulate. They are only
+// served under /v1, and their bodies are never translated.
+var facadeRoutes = []string{"/v1/kv/", "/v1/tfstate/"}
+
+// facadeRoute reports whether a /v1 path is served by a facade
+func facadeRoute(path string) bool {
+	for _, prefix := range facadeRoutes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// v2RequestKey marks the /v1 requests the /v2 API is served through
+type v2RequestKey struct{}
+
+// isV2Request reports whether a /v1 request is serving a /v2 one
+func isV2Request(r *http.Request) bool {
+	return r.Context().Value(v2RequestKey{}) != nil
+}
+
+// withV2Request marks a /v1 request as serving a /v2 one
+func withV2Request(ctx context.Context) context.Context {
+	return context.WithValue(ctx, v2RequestKey{}, true)
+}
+
+// snakeToCamel converts a snake_case name to camelCase
+func snakeToCamel(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// camelToSnake converts a camelCase name to snake_case
+func camelToSnake(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// convertKeys renames the object keys in a decoded JSON value with rename,
+// leaving the contents of opaque fields alone. snake names the key in
+// snake_case, which is how opaque and field name fields are recognized.
+func convertKeys(value interface{}, rename func(string) string, snake func(string) string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			name := snake(key)
+			switch {
+			case v2OpaqueFields[name]:
+			case v2FieldNameValues[name]:
+				field = convertNames(field, rename)
+			default:
+				field = convertKeys(field, rename, snake)
+			}
+			converted[rename(key)] = field
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = convertKeys(item, rename, snake)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// convertNames renames a field name, or a list of them
+func convertNames(value interface{}, rename func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return rename(v)
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = convertNames(item, rename)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// decodeAny decodes JSON keeping numbers exact
+func decodeAny(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return value, nil
+}
+
+// v2Error is the error format of the /v2 API
+type v2Error struct {
+	Code    int                    `json:"code"`
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// writeV2Error writes an error raised before a request reaches /v1 in the
+// /v2 format
+func (h *Handler) writeV2Error(w http.ResponseWriter, err error) {
+	buf := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+	h.writeError(buf, err)
+	writeV2Response(w, buf.header, buf.status, buf.body.Bytes())
+}
+
+// serveV2 serves the /v2 API by translating its requests into /v1 requests,
+// which router serves, and their responses back
+func (h *Handler) serveV2(router http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := "/v1" + strings.TrimPrefix(r.URL.Path, "/v2")
+		if facadeRoute(path) {
+			h.writeV2Error(w, domain.NotFoundError("route", r.URL.Path))
+			return
+		}
+
+		r = r.Clone(withV2Request(r.Context()))
+		r.URL.Path = path
+		r.URL.RawPath = ""
+		r.URL.RawQuery = v2Query(r.URL.RawQuery)
+		r.RequestURI = r.URL.RequestURI()
+
+		if websocket.IsWebSocketUpgrade(r) {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			body, err := h.readBody(w, r)
+			if err != nil {
+				h.writeV2Error(w, err)
+				return
+			}
+			// Bodies that are not JSON are left for /v1 to reject
+			if value, err := decodeAny(body); err == nil {
+				body, _ = json.Marshal(convertKeys(value, camelToSnake, camelToSnake))
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+		}
+
+		rw := newJSONRewriter(w, writeV2Response)
+		router.ServeHTTP(rw, r)
+		rw.finish()
+	})
+}
+
+// v2Query translates the query parameters of a /v2 request to /v1 ones
+func v2Query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	query, err := url.ParseQuery(raw)
+	if err != nil {
+		return raw
+	}
+
+	converted := make(url.Values, len(query))
+	for key, values := range query {
+		name := camelToSnake(key)
+		if v2QueryFieldNames[name] {
+			for i, value := range values {
+				fields := strings.Split(value, ",")
+				for j, field := range fields {
+					fields[j] = camelToSnake(field)
+				}
+				values[i] = strings.Join(fields, ",")
+			}
+		}
+		converted[name] = append(converted[name], values...)
+	}
+	return converted.Encode()
+}
+
+// writeV2Response translates a /v1 JSON response to /v2 and writes it. Error
+// responses get the /v2 error format and the rest are enveloped. Bodies that
+// cannot be translated, such as malformed ones chaos forced, are sent as is.
+func writeV2Response(w http.ResponseWriter, header http.Header, status int, body []byte) {
+	for key, values := range header {
+		w.Header()[key] = values
+	}
+	w.Header().Del("Content-Length")
+
+	if value, err := decodeAny(body); err == nil {
+		value = convertKeys(value, snakeToCamel, func(name string) string { return name })
+		fields, _ := value.(map[string]interface{})
+		if code, ok := fields["error"].(string); ok && status >= 400 {
+			message, _ := fields["message"].(string)
+			details, _ := fields["details"].(map[string]interface{})
+			body, _ = json.Marshal(map[string]interface{}{"error": v2Error{
+				Code:    status,
+				Status:  code,
+				Message: message,
+				Details: details,
+			}})
+			body = append(body, '\n')
+		} else if status < 400 {
+			body, _ = json.Marshal(map[string]interface{}{"data": value})
+			body = append(body, '\n')
+		}
+	}
+
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func v2Do(t *testing.T, router http.Handler, method, target, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	var doc map[string]interface{}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), ContentTypeJSON) {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc), w.Body.String())
+	}
+	return w, doc
+}
+
+func TestV2_CamelCaseEnvelope(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	w, doc := v2Do(t, router, "POST", "/v2/projects", `{"name":"web","labels":{"cost_center":"a1"}}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	project := doc["data"].(map[string]interface{})
+	assert.Contains(t, project, "createdAt")
+	assert.NotContains(t, project, "created_at")
+	assert.Equal(t, map[string]interface{}{"cost_center": "a1"}, project["labels"], "label keys are user data")
+	projectID := project["id"].(string)
+
+	w, doc = v2Do(t, router, "POST", "/v2/instances", `{"projectId":"`+projectID+`","name":"vm","cpu":1,"memoryMb":512,"image":"ubuntu"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	instance := doc["data"].(map[string]interface{})
+	assert.Equal(t, projectID, instance["projectId"])
+	assert.EqualValues(t, 512, instance["memoryMb"])
+
+	w, doc = v2Do(t, router, "GET", "/v2/instances?projectId="+projectID+"&fields=id,memoryMb", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": instance["id"], "memoryMb": float64(512)}}, doc["data"])
+
+	// /v1 is unchanged alongside
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/projects/"+projectID, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"created_at"`)
+}
+
+func TestV2_Errors(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	w, doc := v2Do(t, router, "GET", "/v2/projects/missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, map[string]interface{}{
+		"code":    float64(http.StatusNotFound),
+		"status":  "NOT_FOUND",
+		"message": "project not found",
+		"details": map[string]interface{}{"resource": "project", "identifier": "missing"},
+	}, doc["error"])
+
+	w, doc = v2Do(t, router, "POST", "/v2/projects", `{"name":"web","createdAt":"2024-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	details := doc["error"].(map[string]interface{})["details"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"field": "createdAt", "message": "is output-only and cannot be set"},
+	}, details["fields"], "field names are reported as /v2 spells them")
+
+	w, doc = v2Do(t, router, "GET", "/v2/kv/app/config", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "the Consul facade is only served under /v1")
+	assert.Equal(t, "NOT_FOUND", doc["error"].(map[string]interface{})["status"])
+}
+
+func TestCaseConversion(t *testing.T) {
+	for snake, camel := range map[string]string{
+		"id":                   "id",
+		"project_id":           "projectId",
+		"memory_mb":            "memoryMb",
+		"autoscaling_group_id": "autoscalingGroupId",
+		"ipv4_address":         "ipv4Address",
+	} {
+		assert.Equal(t, camel, snakeToCamel(snake))
+		assert.Equal(t, snake, camelToSnake(camel))
+	}
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+)
+
+// createValidator validates a create request body for one resource type
+type createValidator func(h *Handler, r *http.Request, body []byte) (*domain.ValidationResult, error)
+
+// createValidators are the resource types POST /v1/validate/{resourceType}
+// accepts, named like their collections
+var createValidators = map[string]createValidator{
+	"organizations": validateCreate(fullAccess[domain.CreateOrganizationRequest],
+		func(h *Handler, tx *service.Service, req domain.CreateOrganizationRequest) (interface{}, error) {
+			return tx.CreateOrganization(req)
+		}),
+	"folders": validateCreate(fullAccess[domain.CreateFolderRequest],
+		func(h *Handler, tx *service.Service, req domain.CreateFolderRequest) (interface{}, error) {
+			return tx.CreateFolder(req)
+		}),
+	"projects": validateCreate(fullAccess[domain.CreateProjectRequest],
+		func(h *Handler, tx *service.Service, req domain.CreateProjectRequest) (interface{}, error) {
+			if err := h.checkChaosProfile(req.ChaosProfile); err != nil {
+				return nil, err
+			}
+			return tx.CreateProject(req)
+		}),
+	"instances": validateCreate(projectRole(domain.RoleEditor, func(req *domain.CreateInstanceRequest) string { return req.ProjectID }),
+		func(h *Handler, tx *service.Service, req domain.CreateInstanceRequest) (interface{}, error) {
+			return tx.CreateInstance(req)
+		}),
+	"instance-templates": validateCreate(fullAccess[domain.CreateInstanceTemplateRequest],
+		func(h *Handler, tx *service.Service, req domain.CreateInstanceTemplateRequest) (interface{}, error) {
+			return tx.CreateInstanceTemplate(req)
+		}),
+	"autoscaling-groups": validateCreate(projectRole(domain.RoleEditor, func(req *domain.CreateAutoscalingGroupRequest) string { return req.ProjectID }),
+		func(h *Handler, tx *service.Service, req domain.CreateAutoscalingGroupRequest) (interface{}, error) {
+			return tx.CreateAutoscalingGroup(req)
+		}),
+	"metadata": validateCreate(fullAccess[domain.CreateMetadataRequest],
+		func(h *Handler, tx *service.Service, req domain.CreateMetadataRequest) (interface{}, error) {
+			return tx.CreateMetadata(req)
+		}),
+	"budgets": validateCreate(projectRole(domain.RoleAdmin, func(req *domain.CreateBudgetRequest) string { return req.ProjectID }),
+		func(h *Handler, tx *service.Service, req domain.CreateBudgetRequest) (interface{}, error) {
+			return tx.CreateBudget(req)
+		}),
+	"secrets": validateCreate(projectRole(domain.RoleEditor, func(req *domain.CreateSecretRequest) string { return req.ProjectID }),
+		func(h *Handler, tx *service.Service, req domain.CreateSecretRequest) (interface{}, error) {
+			return tx.CreateSecret(req)
+		}),
+	"databases": validateCreate(projectRole(domain.RoleEditor, func(req *domain.CreateDatabaseRequest) string { return req.ProjectID }),
+		func(h *Handler, tx *service.Service, req domain.CreateDatabaseRequest) (interface{}, error) {
+			return tx.CreateDatabase(req)
+		}),
+	"topics": validateCreate(projectRole(domain.RoleEditor, func(req *domain.CreateTopicRequest) string { return req.ProjectID }),
+		func(h *Handler, tx *service.Service, req domain.CreateTopicRequest) (interface{}, error) {
+			return tx.CreateTopic(req)
+		}),
+	"subscriptions": validateCreate(authorizeSubscriptionCreate,
+		func(h *Handler, tx *service.Service, req domain.CreateSubscriptionRequest) (interface{}, error) {
+			return tx.CreateSubscription(req)
+		}),
+}
+
+// validateCreate builds a validator that decodes a body into a T, checks the
+// caller may create it, and dry runs the create
+func validateCreate[T any](
+	authorize func(h *Handler, r *http.Request, req *T) error,
+	create func(h *Handler, tx *service.Service, req T) (interface{}, error),
+) createValidator {
+	return func(h *Handler, r *http.Request, body []byte) (*domain.ValidationResult, error) {
+		var req T
+		var violations []domain.FieldViolation
+		decodeErr := decodeBody(body, &req)
+		if decodeErr != nil {
+			// Decode what can be decoded, so the fields that are valid JSON
+			// are still checked
+			var ok bool
+			violations, ok = decodeViolations(decodeErr)
+			if !ok || decodeLenient(body, violations, &req) != nil {
+				return invalidResult(decodeErr, nil), nil
+			}
+		}
+
+		if err := authorize(h, r, &req); err != nil {
+			return nil, err
+		}
+
+		resource, err := service.DryRun(h.service.WithActor(h.actor(r)), func(tx *service.Service) (interface{}, error) {
+			return create(h, tx, req)
+		})
+		if err != nil {
+			dirtErr, ok := domain.AsDirtError(err)
+			if !ok || errors.Is(err, domain.ErrInternal) {
+				return nil, err
+			}
+			if fields, ok := dirtErr.Details["fields"].([]domain.FieldViolation); ok {
+				violations = mergeViolations(violations, fields)
+			}
+			if decodeErr == nil {
+				decodeErr = err
+			}
+		}
+		if decodeErr != nil {
+			return invalidResult(decodeErr, violations), nil
+		}
+
+		return validResult(body, resource)
+	}
+}
+
+// fullAccess lets only callers with full access validate a T, as for
+// resources outside projects
+func fullAccess[T any](h *Handler, r *http.Request, req *T) error {
+	return h.authenticate(r)
+}
+
+// projectRole lets callers with role on the project of a T validate it
+func projectRole[T any](role string, project func(req *T) string) func(h *Handler, r *http.Request, req *T) error {
+	return func(h *Handler, r *http.Request, req *T) error {
+		member, err := h.principal(r)
+		if err != nil {
+			return err
+		}
+		return h.requireRole(r, member, project(req), role)
+	}
+}
+
+// authorizeSubscriptionCreate checks the caller may create a subscription in
+// its topic's project. A missing topic is left for the service to report.
+func authorizeSubscriptionCreate(h *Handler, r *http.Request, req *domain.CreateSubscriptionRequest) error {
+	member, err := h.principal(r)
+	if err != nil {
+		return err
+	}
+	topic, err := h.service.GetTopic(req.TopicID)
+	if err != nil {
+		if domain.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return h.requireRole(r, member, topic.ProjectID, domain.RoleEditor)
+}
+
+// decodeViolations lists the fields a strict decode rejected. It reports
+// false for errors that are not about individual fields, such as malformed
+// JSON.
+func decodeViolations(err error) ([]domain.FieldViolation, bool) {
+	dirtErr, ok := domain.AsDirtError(err)
+	if !ok || dirtErr.Details == nil {
+		return nil, false
+	}
+
+	violations, _ := dirtErr.Details["fields"].([]domain.FieldViolation)
+	return violations, len(violations) > 0
+}
+
+// decodeLenient decodes body into v without the fields that were rejected
+func decodeLenient(body []byte, rejected []domain.FieldViolation, v interface{}) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return err
+	}
+	for _, violation := range rejected {
+		delete(raw, violation.Field)
+	}
+
+	cleaned, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(cleaned, v)
+}
+
+// mergeViolations adds the violations from a later check, skipping fields
+// already reported by an earlier one
+func mergeViolations(earlier, later []domain.FieldViolation) []domain.FieldViolation {
+	reported := make(map[string]bool, len(earlier))
+	for _, violation := range earlier {
+		reported[violation.Field] = true
+	}
+
+	merged := earlier
+	for _, violation := range later {
+		if !reported[violation.Field] {
+			merged = append(merged, violation)
+		}
+	}
+	return merged
+}
+
+// invalidResult reports a request that would fail with err
+func invalidResult(err error, violations []domain.FieldViolation) *domain.ValidationResult {
+	result := &domain.ValidationResult{Violations: violations}
+	if dirtErr, ok := domain.AsDirtError(err); ok {
+		result.Error = dirtErr
+	}
+	return result
+}
+
+// serverAssignedFields are the resource fields every create assigns, which a
+// validation result leaves out
+var serverAssignedFields = []string{"id", "created_at", "updated_at"}
+
+// validResult reports a request that would create resource, listing as
+// defaults the resource fields the request body left out
+func validResult(body []byte, resource interface{}) (*domain.ValidationResult, error) {
+	encoded, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	for _, field := range serverAssignedFields {
+		delete(fields, field)
+	}
+
+	var requested map[string]json.RawMessage
+	if err := json.Unmarshal(body, &requested); err != nil {
+		return nil, err
+	}
+
+	defaults := make(map[string]interface{})
+	for field, value := range fields {
+		if _, ok := requested[field]; !ok && !outputOnlyFields[field] {
+			defaults[field] = value
+		}
+	}
+
+	return &domain.ValidationResult{Valid: true, Resource: fields, Defaults: defaults}, nil
+}
+
+// validateResourceTypes returns the accepted resource types for error messages
+func validateResourceTypes() string {
+	types := make([]string, 0, len(createValidators))
+	for resourceType := range createValidators {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return strings.Join(types, ", ")
+}
+
+// ValidateResource handles POST /v1/validate/{resourceType}. It checks a
+// create request body as the create would, reporting every problem found
+// and the values the server would fill in, without creating anything.
+// Invalid requests are reported in the result with 200 OK; only failures
+// of the validation itself are errors.
+func (h *Handler) ValidateResource(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.principal(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	resourceType := mux.Vars(r)["resourceType"]
+	validate, ok := createValidators[resourceType]
+	if !ok {
+		h.writeError(w, domain.InvalidInputError("unknown resource type '"+resourceType+"'", map[string]interface{}{
+			"resource_type":       resourceType,
+			"supported_resources": validateResourceTypes(),
+		}))
+		return
+	}
+
+	body, err := h.readBody(w, r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	result, err := validate(h, r, body)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
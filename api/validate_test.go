@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ValidateResource(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "plan"})
+	require.NoError(t, err)
+	_, err = h.service.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "taken", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		resourceType       string
+		body               string
+		expectedValid      bool
+		expectedCode       string
+		expectedViolations []string
+		expectedDefaults   map[string]interface{}
+	}{
+		{
+			name:             "valid instance",
+			resourceType:     "instances",
+			body:             `{"project_id":"` + project.ID + `","name":"web","cpu":2,"memory_mb":1024,"image":"ubuntu"}`,
+			expectedValid:    true,
+			expectedDefaults: map[string]interface{}{"status": "running", "deletion_protection": false},
+		},
+		{
+			name:               "every problem is reported",
+			resourceType:       "instances",
+			body:               `{"project_id":"` + project.ID + `","name":"","cpu":"two","memory_mb":0,"image":"ubuntu","colour":"red"}`,
+			expectedCode:       domain.ErrorCodeInvalidInput,
+			expectedViolations: []string{"colour", "cpu", "name", "memory_mb"},
+		},
+		{
+			name:         "name conflict",
+			resourceType: "instances",
+			body:         `{"project_id":"` + project.ID + `","name":"taken","cpu":1,"memory_mb":512,"image":"ubuntu"}`,
+			expectedCode: domain.ErrorCodeAlreadyExists,
+		},
+		{
+			name:         "missing project",
+			resourceType: "instances",
+			body:         `{"project_id":"missing","name":"web","cpu":1,"memory_mb":512,"image":"ubuntu"}`,
+			expectedCode: domain.ErrorCodeForeignKeyViolation,
+		},
+		{
+			name:         "malformed JSON",
+			resourceType: "projects",
+			body:         `{"name":`,
+			expectedCode: domain.ErrorCodeInvalidInput,
+		},
+		{
+			name:             "database defaults",
+			resourceType:     "databases",
+			body:             `{"project_id":"` + project.ID + `","name":"orders","engine":"postgres"}`,
+			expectedValid:    true,
+			expectedDefaults: map[string]interface{}{"version": "16", "size": "small"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/v1/validate/"+tt.resourceType, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var result domain.ValidationResult
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Equal(t, tt.expectedValid, result.Valid)
+			if tt.expectedValid {
+				assert.Nil(t, result.Error)
+				assert.NotContains(t, result.Resource, "id")
+				for field, value := range tt.expectedDefaults {
+					assert.Equal(t, value, result.Defaults[field], field)
+				}
+				return
+			}
+
+			require.NotNil(t, result.Error)
+			assert.Equal(t, tt.expectedCode, result.Error.Code)
+			var fields []string
+			for _, violation := range result.Violations {
+				fields = append(fields, violation.Field)
+			}
+			assert.Equal(t, tt.expectedViolations, fields)
+		})
+	}
+
+	instances, err := h.service.ListInstances(domain.InstanceListOptions{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Len(t, instances, 1, "validation creates nothing")
+	databases, err := h.service.ListDatabases(domain.DatabaseListOptions{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Empty(t, databases)
+
+	r := httptest.NewRequest("POST", "/v1/validate/widgets", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// fitChaosToWriteTimeout tells chaos when the server's write timeout will
+// cut off each response, so that injected latency can push the deadline
+// back instead of losing the response. The read deadline moves with it, as
+// the server cancels a request whose connection stops being readable.
+func (h *Handler) fitChaosToWriteTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.writeTimeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		extend := func(deadline time.Time) error {
+			if err := rc.SetWriteDeadline(deadline); err != nil {
+				return err
+			}
+			rc.SetReadDeadline(deadline)
+			return nil
+		}
+		ctx := chaos.WithWriteDeadline(r.Context(), time.Now().Add(h.writeTimeout), extend)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
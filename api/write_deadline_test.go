@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_fitChaosToWriteTimeout(t *testing.T) {
+	writeTimeout := 200 * time.Millisecond
+	chaosService := chaos.NewChaosService()
+	server := httptest.NewUnstartedServer(SetupRouter(NewHandler(newTestService(t), chaosService, Config{
+		WriteTimeout: writeTimeout,
+	})))
+	server.Config.WriteTimeout = writeTimeout
+	server.Start()
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL+"/v1/projects", nil)
+	require.NoError(t, err)
+	req.Header.Set(chaos.LatencyHeader, "400")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err, "the response outlasting the write timeout is still delivered")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, uint64(1), chaosService.DeadlineStats().Extended)
+}
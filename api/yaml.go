@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// ContentTypeYAML is the media type of YAML request and response bodies
+const ContentTypeYAML = "application/yaml"
+
+// yamlMediaTypes are the media types accepted for YAML
+var yamlMediaTypes = map[string]bool{
+	ContentTypeYAML:      true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// apiRoute reports whether a path is served by a version of the API
+func apiRoute(path string) bool {
+	return strings.HasPrefix(path, "/v1/") || strings.HasPrefix(path, "/v2/")
+}
+
+// acceptsYAML reports whether a request prefers YAML responses to JSON. Media
+// types are tried in order, as listFormat does.
+func acceptsYAML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if yamlMediaTypes[mediaType] {
+			return true
+		}
+		switch mediaType {
+		case ContentTypeJSON, ContentTypeCSV, ContentTypeNDJSON, "application/*", "*/*":
+			return false
+		}
+	}
+	return false
+}
+
+// sendsYAML reports whether a request body is YAML
+func sendsYAML(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && yamlMediaTypes[mediaType]
+}
+
+// negotiateYAML lets API clients send and receive YAML. YAML request bodies
+// are converted to JSON before the handlers see them, and JSON responses are
+// converted to YAML for requests that accept it. Handlers only ever see
+// JSON, so the /v2 translation, which serves its requests through /v1 again,
+// is not converted twice. Facades keep the formats they emulate.
+func (h *Handler) negotiateYAML(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !apiRoute(r.URL.Path) || facadeRoute(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		toYAML, fromYAML := acceptsYAML(r), sendsYAML(r)
+		if !toYAML && !fromYAML {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		if toYAML {
+			w.Header().Add("Vary", "Accept")
+			r.Header.Set("Accept", ContentTypeJSON)
+			rw := newJSONRewriter(w, writeYAMLResponse)
+			defer rw.finish()
+			w = rw
+		}
+
+		if fromYAML {
+			body, err := h.readBody(w, r)
+			if err == nil {
+				body, err = yamlToJSON(body)
+			}
+			if err != nil {
+				h.writeError(w, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Set("Content-Type", ContentTypeJSON)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// yamlToJSON converts a YAML document to JSON. An empty body stays empty so
+// handlers can report it as missing.
+func yamlToJSON(body []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return body, nil
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal(body, &value); err != nil {
+		return nil, domain.InvalidInputError("invalid YAML", map[string]interface{}{
+			"reason": err.Error(),
+		})
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, domain.InvalidInputError("YAML body cannot be represented as JSON", map[string]interface{}{
+			"reason": err.Error(),
+		})
+	}
+	return data, nil
+}
+
+// writeYAMLResponse converts a JSON response to YAML and writes it. JSON is
+// YAML already, so it is parsed as YAML and written back in block style,
+// which keeps numbers exactly as they were. Bodies that cannot be parsed,
+// such as malformed ones chaos forced, are sent as is.
+func writeYAMLResponse(w http.ResponseWriter, header http.Header, status int, body []byte) {
+	for key, values := range header {
+		w.Header()[key] = values
+	}
+	w.Header().Del("Content-Length")
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err == nil && len(bytes.TrimSpace(body)) > 0 {
+		blockStyle(&doc)
+		if data, err := yaml.Marshal(&doc); err == nil {
+			w.Header().Set("Content-Type", ContentTypeYAML)
+			body = data
+		}
+	}
+
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// blockStyle clears the flow style and quoting JSON parses with, leaving the
+// encoder to quote only the strings that need it
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
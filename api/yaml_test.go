@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func yamlDo(t *testing.T, router http.Handler, method, target, contentType, accept, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestYAML_RequestAndResponse(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	body := "name: web\nlabels:\n  team: \"123\"\n"
+	w := yamlDo(t, router, "POST", "/v1/projects", "application/yaml", "application/yaml", body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, ContentTypeYAML, w.Header().Get("Content-Type"))
+
+	var project map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &project), w.Body.String())
+	assert.Equal(t, "web", project["name"])
+	assert.Equal(t, map[string]interface{}{"team": "123"}, project["labels"], "strings that look like numbers stay strings")
+
+	// A YAML request can still get JSON back
+	w = yamlDo(t, router, "POST", "/v1/projects", "text/yaml", "", "name: api\n")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, ContentTypeJSON, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"name":"api"`)
+
+	// Lists, and the /v2 envelope, come back as YAML too
+	w = yamlDo(t, router, "GET", "/v2/projects?sort=name", "", "application/yaml, application/json", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list struct {
+		Data []struct {
+			Name string `yaml:"name"`
+		} `yaml:"data"`
+	}
+	require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &list), w.Body.String())
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, "api", list.Data[0].Name)
+}
+
+func TestYAML_Errors(t *testing.T) {
+	router := SetupRouter(newTestHandler(t))
+
+	w := yamlDo(t, router, "POST", "/v1/projects", "application/yaml", "application/yaml", "name: [web\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var doc map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &doc), w.Body.String())
+	assert.Equal(t, "INVALID_INPUT", doc["error"])
+	assert.Equal(t, "invalid YAML", doc["message"])
+
+	w = yamlDo(t, router, "GET", "/v1/projects/missing", "", "application/yaml", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "error: NOT_FOUND")
+}
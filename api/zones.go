@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// ListZones handles GET /v1/zones
+func (h *Handler) ListZones(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.principal(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	zones, err := h.service.ListZones()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, zones)
+}
+
+// GetZone handles GET /v1/zones/{zone}
+func (h *Handler) GetZone(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.principal(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	zone, err := h.service.GetZone(mux.Vars(r)["zone"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, zone)
+}
+
+// StartZoneOutage handles POST /v1/admin/zones/{zone}/outage, taking the
+// zone down along with every instance in it
+func (h *Handler) StartZoneOutage(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	zone, err := h.service.WithActor(h.actor(r)).StartZoneOutage(mux.Vars(r)["zone"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, zone)
+}
+
+// EndZoneOutage handles DELETE /v1/admin/zones/{zone}/outage, bringing the
+// zone and its instances back up
+func (h *Handler) EndZoneOutage(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	zone, err := h.service.WithActor(h.actor(r)).EndZoneOutage(mux.Vars(r)["zone"])
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, zone)
+}
+
+// SetZoneCapacity handles PUT /v1/admin/zones/{zone}/capacity, replacing the
+// CPU and memory the zone has for instances
+func (h *Handler) SetZoneCapacity(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var capacity domain.ZoneCapacity
+	if err := h.decodeJSON(w, r, &capacity); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	zone, err := h.service.SetZoneCapacity(mux.Vars(r)["zone"], capacity)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, zone)
+}
+
+// ClearZoneCapacity handles DELETE /v1/admin/zones/{zone}/capacity, leaving
+// the zone with unlimited capacity
+func (h *Handler) ClearZoneCapacity(w http.ResponseWriter, r *http.Request) {
+	if err := h.authenticateAdmin(r); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	zone, err := h.service.SetZoneCapacity(mux.Vars(r)["zone"], domain.ZoneCapacity{})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, zone)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneOutage(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "dr"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm", CPU: 1, MemoryMB: 512, Image: "ubuntu", Zone: "dirt-1b"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/admin/zones/dirt-1b/outage", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/zones", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var zones []domain.Zone
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &zones))
+	require.Len(t, zones, len(domain.DefaultZones))
+	assert.Equal(t, domain.ZoneStatusUp, zones[0].Status)
+	assert.Equal(t, domain.ZoneStatusDown, zones[1].Status)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/instances?zone=dirt-1b", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var instances []domain.Instance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &instances))
+	require.Len(t, instances, 1)
+	assert.Equal(t, instance.ID, instances[0].ID)
+	assert.Equal(t, domain.StatusUnavailable, instances[0].Status)
+
+	body := `{"project_id":"` + project.ID + `","name":"vm-2","cpu":1,"memory_mb":512,"image":"ubuntu","zone":"dirt-1b"}`
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/instances", strings.NewReader(body)))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code, w.Body.String())
+	var apiErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, domain.ReasonZoneUnavailable, apiErr.Reason)
+	assert.Equal(t, "dirt-1b", apiErr.Details["zone"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/v1/admin/zones/dirt-1b/outage", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/instances", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/admin/zones/mars-1a/outage", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestZoneCapacity(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "packed"})
+	require.NoError(t, err)
+
+	for _, zone := range domain.DefaultZones {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/admin/zones/"+zone+"/capacity", strings.NewReader(`{"cpu": 1, "memory_mb": 0}`)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	body := `{"project_id":"` + project.ID + `","name":"vm","cpu":2,"memory_mb":512,"image":"ubuntu","zone":"dirt-1a"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/instances", strings.NewReader(body)))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code, w.Body.String())
+	var apiErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, domain.ErrorCodeResourceExhausted, apiErr.Code)
+	assert.Equal(t, domain.ReasonZoneStockout, apiErr.Reason)
+	assert.Equal(t, []interface{}{}, apiErr.Details["suggested_zones"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/v1/admin/zones/dirt-1a/capacity", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/instances", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
+
+func TestUpdateInstance_ImmutableZone(t *testing.T) {
+	h := newTestHandler(t)
+	router := SetupRouter(h)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "zoned"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PATCH", "/v1/instances/"+instance.ID, strings.NewReader(`{"zone":"dirt-1c"}`)))
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var apiErr domain.DirtError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, domain.ErrorCodeImmutableField, apiErr.Code)
+	assert.Equal(t, "zone", apiErr.Details["field"])
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Project commands
+
+func (c *cli) listProjects(ctx context.Context, args []string) error {
+	flags := c.newFlags("projects list")
+	name := flags.String("name", "", "only list the project with this name")
+	if _, err := c.parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	projects, err := c.client.Projects.List(ctx, domain.ProjectListOptions{Name: *name})
+	if err != nil {
+		return err
+	}
+
+	return printList(c, projects, projectColumns, projectRow)
+}
+
+func (c *cli) getProject(ctx context.Context, args []string) error {
+	pos, err := c.parseArgs(c.newFlags("projects get"), args, "ID|NAME")
+	if err != nil {
+		return err
+	}
+
+	project, err := c.client.Projects.Get(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+
+	return printOne(c, project, projectColumns, projectRow)
+}
+
+func (c *cli) createProject(ctx context.Context, args []string) error {
+	pos, err := c.parseArgs(c.newFlags("projects create"), args, "NAME")
+	if err != nil {
+		return err
+	}
+
+	project, err := c.client.Projects.Create(ctx, domain.CreateProjectRequest{Name: pos[0]})
+	if err != nil {
+		return err
+	}
+
+	return printOne(c, project, projectColumns, projectRow)
+}
+
+func (c *cli) deleteProject(ctx context.Context, args []string) error {
+	flags := c.newFlags("projects delete")
+	cascade := flags.Bool("cascade", false, "also delete the project's instances and autoscaling groups")
+	pos, err := c.parseArgs(flags, args, "ID")
+	if err != nil {
+		return err
+	}
+
+	return c.client.Projects.Delete(ctx, pos[0], domain.DeleteProjectOptions{Cascade: *cascade})
+}
+
+// Instance commands
+
+func (c *cli) listInstances(ctx context.Context, args []string) error {
+	flags := c.newFlags("instances list")
+	projectID := flags.String("project", "", "only list instances in this project")
+	name := flags.String("name", "", "only list instances with this name")
+	status := flags.String("status", "", "only list instances with this status")
+	if _, err := c.parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	instances, err := c.client.Instances.List(ctx, domain.InstanceListOptions{
+		ProjectID: *projectID,
+		Name:      *name,
+		Status:    *status,
+	})
+	if err != nil {
+		return err
+	}
+
+	return printList(c, instances, instanceColumns, instanceRow)
+}
+
+func (c *cli) getInstance(ctx context.Context, args []string) error {
+	flags := c.newFlags("instances get")
+	project := flags.String("project", "", "project ID or name, to look the instance up by name")
+	pos, err := c.parseArgs(flags, args, "ID|NAME")
+	if err != nil {
+		return err
+	}
+
+	var instance *domain.Instance
+	if *project != "" {
+		instance, err = c.client.Instances.GetByName(ctx, *project, pos[0])
+	} else {
+		instance, err = c.client.Instances.Get(ctx, pos[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	return printOne(c, instance, instanceColumns, instanceRow)
+}
+
+func (c *cli) createInstance(ctx context.Context, args []string) error {
+	flags := c.newFlags("instances create")
+	req := domain.CreateInstanceRequest{}
+	flags.StringVar(&req.ProjectID, "project", "", "project ID (required)")
+	flags.StringVar(&req.Name, "name", "", "instance name (required)")
+	flags.IntVar(&req.CPU, "cpu", 1, "number of CPUs")
+	flags.IntVar(&req.MemoryMB, "memory-mb", 1024, "memory in MB")
+	flags.StringVar(&req.Image, "image", "ubuntu-22.04", "boot image")
+	flags.StringVar(&req.Status, "status", "", "initial status (default running)")
+	if _, err := c.parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	instance, err := c.client.Instances.Create(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return printOne(c, instance, instanceColumns, instanceRow)
+}
+
+func (c *cli) deleteInstance(ctx context.Context, args []string) error {
+	pos, err := c.parseArgs(c.newFlags("instances delete"), args, "ID")
+	if err != nil {
+		return err
+	}
+
+	return c.client.Instances.Delete(ctx, pos[0])
+}
+
+// Metadata commands
+
+func (c *cli) listMetadata(ctx context.Context, args []string) error {
+	flags := c.newFlags("metadata list")
+	prefix := flags.String("prefix", "", "only list paths with this prefix")
+	if _, err := c.parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	entries, err := c.client.Metadata.List(ctx, domain.MetadataListOptions{Prefix: *prefix})
+	if err != nil {
+		return err
+	}
+
+	return printList(c, entries, metadataColumns, metadataRow)
+}
+
+func (c *cli) getMetadata(ctx context.Context, args []string) error {
+	pos, err := c.parseArgs(c.newFlags("metadata get"), args, "PATH")
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.findMetadata(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+
+	// A bare value is the most useful table output for scripts
+	if c.output == "table" {
+		_, err := fmt.Fprintln(c.stdout, entry.Value)
+		return err
+	}
+
+	return printOne(c, entry, metadataColumns, metadataRow)
+}
+
+// putMetadata creates the entry at a path or replaces its value
+func (c *cli) putMetadata(ctx context.Context, args []string) error {
+	pos, err := c.parseArgs(c.newFlags("metadata put"), args, "PATH", "VALUE")
+	if err != nil {
+		return err
+	}
+	path, value := pos[0], pos[1]
+
+	entry, err := c.findMetadata(ctx, path)
+	switch {
+	case err == nil:
+		entry, err = c.client.Metadata.Update(ctx, entry.ID, domain.UpdateMetadataRequest{Value: &value})
+	case domain.IsNotFound(err):
+		entry, err = c.client.Metadata.Create(ctx, domain.CreateMetadataRequest{Path: path, Value: value})
+	}
+	if err != nil {
+		return err
+	}
+
+	return printOne(c, entry, metadataColumns, metadataRow)
+}
+
+func (c *cli) deleteMetadata(ctx context.Context, args []string) error {
+	pos, err := c.parseArgs(c.newFlags("metadata delete"), args, "PATH")
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.findMetadata(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+
+	return c.client.Metadata.Delete(ctx, entry.ID)
+}
+
+// Chaos commands
+
+func (c *cli) chaosStatus(ctx context.Context, args []string) error {
+	if _, err := c.parseArgs(c.newFlags("chaos status"), args); err != nil {
+		return err
+	}
+
+	status, err := c.client.Chaos.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return printOne(c, status, chaosColumns, chaosRow)
+}
+
+func (c *cli) enableChaos(ctx context.Context, args []string) error {
+	return c.setChaos(ctx, "chaos enable", args, true)
+}
+
+func (c *cli) disableChaos(ctx context.Context, args []string) error {
+	return c.setChaos(ctx, "chaos disable", args, false)
+}
+
+func (c *cli) setChaos(ctx context.Context, name string, args []string, enabled bool) error {
+	if _, err := c.parseArgs(c.newFlags(name), args); err != nil {
+		return err
+	}
+
+	status, err := c.client.Chaos.SetEnabled(ctx, enabled)
+	if err != nil {
+		return err
+	}
+
+	return printOne(c, status, chaosColumns, chaosRow)
+}
+
+// Admin commands
+
+func (c *cli) resetData(ctx context.Context, args []string) error {
+	flags := c.newFlags("admin reset")
+	yes := flags.Bool("yes", false, "confirm deleting every resource on the server")
+	if _, err := c.parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	if !*yes {
+		return fmt.Errorf("admin reset deletes every resource on the server; pass --yes to confirm")
+	}
+
+	return c.client.Admin.Reset(ctx)
+}
@@ -0,0 +1,238 @@
+// Command dirtctl is a command-line client for the DirtCloud HTTP API
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/client"
+)
+
+const usage = `Usage: dirtctl [flags] <command> [args]
+
+Commands:
+  projects list [--name NAME]
+  projects get ID|NAME
+  projects create NAME
+  projects delete [--cascade] ID
+  instances list [--project ID] [--name NAME] [--status STATUS]
+  instances get [--project ID|NAME] ID|NAME
+  instances create --project ID --name NAME [--cpu N] [--memory-mb N] [--image IMAGE] [--status STATUS]
+  instances delete ID
+  metadata list [--prefix PREFIX]
+  metadata get PATH
+  metadata put PATH VALUE
+  metadata delete PATH
+  chaos status
+  chaos enable
+  chaos disable
+  admin reset --yes
+
+Flags:
+`
+
+const envHelp = `
+Environment:
+  DIRT_SERVER      API base URL, overridden by --server (default http://localhost:8080)
+  DIRT_TOKEN       bearer token
+  DIRT_TOKEN_FILE  file holding the bearer token, overridden by --token-file
+  DIRT_ADMIN_TOKEN bearer token for chaos and admin commands, if the server
+                   requires a separate one
+  DIRT_ACCESS_KEY  access key to sign requests with instead of sending a token
+  DIRT_SECRET_KEY  secret of the access key
+`
+
+// errUsage reports a malformed command line. The usage text has already been
+// printed when it is returned.
+var errUsage = errors.New("usage")
+
+// cli holds the state shared by every command
+type cli struct {
+	client *client.Client
+	output string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run executes a dirtctl command line and returns the process exit code
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	flags := flag.NewFlagSet("dirtctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+		fmt.Fprint(stderr, envHelp)
+	}
+
+	server := flags.String("server", getenv("DIRT_SERVER"), "API base URL")
+	tokenFile := flags.String("token-file", getenv("DIRT_TOKEN_FILE"), "file holding the bearer token")
+	output := flags.String("output", "table", "output format: json or table")
+	timeout := flags.Duration("timeout", 30*time.Second, "request timeout")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *output != "json" && *output != "table" {
+		fmt.Fprintf(stderr, "dirtctl: unknown output format %q\n", *output)
+		return 2
+	}
+
+	token, err := loadToken(getenv("DIRT_TOKEN"), *tokenFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "dirtctl: %v\n", err)
+		return 1
+	}
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 2
+	}
+
+	c := &cli{
+		client: client.NewClient(client.Config{
+			BaseURL:    *server,
+			Token:      token,
+			AdminToken: getenv("DIRT_ADMIN_TOKEN"),
+			AccessKey:  getenv("DIRT_ACCESS_KEY"),
+			SecretKey:  getenv("DIRT_SECRET_KEY"),
+		}),
+		output: *output,
+		stdout: stdout,
+		stderr: stderr,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := c.dispatch(ctx, flags.Arg(0), flags.Arg(1), flags.Args()[2:]); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "dirtctl: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// loadToken resolves the bearer token. A token in the environment wins over a
+// token file.
+func loadToken(envToken, tokenFile string) (string, error) {
+	if envToken != "" {
+		return envToken, nil
+	}
+	if tokenFile == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// dispatch runs the command for a resource and verb
+func (c *cli) dispatch(ctx context.Context, resource, verb string, args []string) error {
+	commands := map[string]map[string]func(context.Context, []string) error{
+		"projects": {
+			"list":   c.listProjects,
+			"get":    c.getProject,
+			"create": c.createProject,
+			"delete": c.deleteProject,
+		},
+		"instances": {
+			"list":   c.listInstances,
+			"get":    c.getInstance,
+			"create": c.createInstance,
+			"delete": c.deleteInstance,
+		},
+		"metadata": {
+			"list":   c.listMetadata,
+			"get":    c.getMetadata,
+			"put":    c.putMetadata,
+			"delete": c.deleteMetadata,
+		},
+		"chaos": {
+			"status":  c.chaosStatus,
+			"enable":  c.enableChaos,
+			"disable": c.disableChaos,
+		},
+		"admin": {
+			"reset": c.resetData,
+		},
+	}
+
+	cmd, ok := commands[resource][verb]
+	if !ok {
+		fmt.Fprintf(c.stderr, "dirtctl: unknown command %q\n", resource+" "+verb)
+		return c.usageError()
+	}
+
+	return cmd(ctx, args)
+}
+
+// usageError prints the command summary and returns errUsage
+func (c *cli) usageError() error {
+	fmt.Fprint(c.stderr, strings.SplitN(usage, "\nFlags:", 2)[0])
+	return errUsage
+}
+
+// newFlags creates the flag set of a command
+func (c *cli) newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+// parseArgs parses a command's flags and checks the number of positional
+// arguments left over
+func (c *cli) parseArgs(flags *flag.FlagSet, args []string, positional ...string) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, errUsage
+	}
+
+	if flags.NArg() != len(positional) {
+		fmt.Fprintf(c.stderr, "Usage: dirtctl %s", flags.Name())
+		for _, name := range positional {
+			fmt.Fprintf(c.stderr, " %s", name)
+		}
+		fmt.Fprintln(c.stderr)
+		flags.PrintDefaults()
+		return nil, errUsage
+	}
+
+	return flags.Args(), nil
+}
+
+// findMetadata looks up a metadata entry by its exact path
+func (c *cli) findMetadata(ctx context.Context, path string) (*domain.Metadata, error) {
+	entries, err := c.client.Metadata.List(ctx, domain.MetadataListOptions{Prefix: path})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.Path == path {
+			return entry, nil
+		}
+	}
+
+	return nil, domain.NotFoundError("metadata", path)
+}
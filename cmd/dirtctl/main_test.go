@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/testing/tfharness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCLI runs dirtctl against a server and returns its exit code and output
+func runCLI(t *testing.T, env map[string]string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr, func(key string) string { return env[key] })
+	return code, stdout.String(), stderr.String()
+}
+
+func TestLoadToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0o600))
+
+	tests := []struct {
+		name      string
+		envToken  string
+		tokenFile string
+		expected  string
+		expectErr bool
+	}{
+		{name: "none", expected: ""},
+		{name: "environment", envToken: "from-env", expected: "from-env"},
+		{name: "file", tokenFile: tokenFile, expected: "from-file"},
+		{name: "environment wins", envToken: "from-env", tokenFile: tokenFile, expected: "from-env"},
+		{name: "missing file", tokenFile: filepath.Join(t.TempDir(), "missing"), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := loadToken(tt.envToken, tt.tokenFile)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, token)
+		})
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no command", args: nil},
+		{name: "unknown command", args: []string{"projects", "explode"}},
+		{name: "missing argument", args: []string{"projects", "get"}},
+		{name: "extra argument", args: []string{"chaos", "enable", "now"}},
+		{name: "unknown output", args: []string{"--output", "xml", "projects", "list"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, nil, tt.args...)
+			assert.Equal(t, 2, code)
+			assert.Empty(t, stdout)
+			assert.NotEmpty(t, stderr)
+		})
+	}
+}
+
+func TestRun_AgainstServer(t *testing.T) {
+	h := tfharness.NewWithOptions(t, tfharness.Options{Token: "secret"})
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte(h.Token+"\n"), 0o600))
+	env := map[string]string{"DIRT_SERVER": h.URL, "DIRT_TOKEN_FILE": tokenFile}
+
+	// Requests without the token are rejected
+	code, _, stderr := runCLI(t, map[string]string{"DIRT_SERVER": h.URL}, "projects", "list")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "dirtctl:")
+
+	code, stdout, stderr := runCLI(t, env, "--output", "json", "projects", "create", "web")
+	require.Equal(t, 0, code, stderr)
+	var project domain.Project
+	require.NoError(t, json.Unmarshal([]byte(stdout), &project))
+	assert.Equal(t, "web", project.Name)
+
+	code, stdout, stderr = runCLI(t, env, "projects", "list")
+	require.Equal(t, 0, code, stderr)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "ID"))
+	assert.Contains(t, lines[1], project.ID)
+
+	code, stdout, stderr = runCLI(t, env, "instances", "create", "--project", project.ID, "--name", "vm-1", "--cpu", "2")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "vm-1")
+
+	code, stdout, stderr = runCLI(t, env, "--output", "json", "instances", "list", "--project", project.ID)
+	require.Equal(t, 0, code, stderr)
+	var instances []domain.Instance
+	require.NoError(t, json.Unmarshal([]byte(stdout), &instances))
+	require.Len(t, instances, 1)
+	assert.Equal(t, 2, instances[0].CPU)
+
+	// put creates the entry, then replaces its value
+	code, _, stderr = runCLI(t, env, "metadata", "put", "app/config", "v1")
+	require.Equal(t, 0, code, stderr)
+	code, _, stderr = runCLI(t, env, "metadata", "put", "app/config", "v2")
+	require.Equal(t, 0, code, stderr)
+	code, stdout, stderr = runCLI(t, env, "metadata", "get", "app/config")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "v2\n", stdout)
+
+	code, _, stderr = runCLI(t, env, "metadata", "get", "app")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "not found")
+
+	code, stdout, stderr = runCLI(t, env, "--output", "json", "chaos", "enable")
+	require.Equal(t, 0, code, stderr)
+	assert.JSONEq(t, `{"enabled": true}`, stdout)
+	code, stdout, stderr = runCLI(t, env, "--output", "json", "chaos", "disable")
+	require.Equal(t, 0, code, stderr)
+	assert.JSONEq(t, `{"enabled": false}`, stdout)
+
+	// reset needs confirmation
+	code, _, stderr = runCLI(t, env, "admin", "reset")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "--yes")
+
+	code, _, stderr = runCLI(t, env, "admin", "reset", "--yes")
+	require.Equal(t, 0, code, stderr)
+
+	code, stdout, stderr = runCLI(t, env, "--output", "json", "projects", "list")
+	require.Equal(t, 0, code, stderr)
+	assert.JSONEq(t, `[]`, stdout)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// Table columns and row formatters for each resource
+
+var projectColumns = []string{"ID", "NAME", "ORGANIZATION", "FOLDER", "CREATED"}
+
+func projectRow(p *domain.Project) []string {
+	return []string{p.ID, p.Name, p.OrganizationID, p.FolderID, formatTime(p.CreatedAt)}
+}
+
+var instanceColumns = []string{"ID", "PROJECT", "NAME", "CPU", "MEMORY_MB", "IMAGE", "STATUS"}
+
+func instanceRow(i *domain.Instance) []string {
+	return []string{i.ID, i.ProjectID, i.Name, strconv.Itoa(i.CPU), strconv.Itoa(i.MemoryMB), i.Image, i.Status}
+}
+
+var metadataColumns = []string{"ID", "PATH", "VALUE", "UPDATED"}
+
+func metadataRow(m *domain.Metadata) []string {
+	return []string{m.ID, m.Path, m.Value, formatTime(m.UpdatedAt)}
+}
+
+var chaosColumns = []string{"ENABLED"}
+
+func chaosRow(s *chaos.Status) []string {
+	return []string{strconv.FormatBool(s.Enabled)}
+}
+
+// printOne writes a single resource in the selected output format
+func printOne[T any](c *cli, item T, columns []string, row func(T) []string) error {
+	if c.output == "json" {
+		return writeJSON(c, item)
+	}
+
+	return writeTable(c, []T{item}, columns, row)
+}
+
+// printList writes a list of resources in the selected output format
+func printList[T any](c *cli, items []T, columns []string, row func(T) []string) error {
+	if c.output == "json" {
+		// Print an empty list as [] rather than null
+		if items == nil {
+			items = []T{}
+		}
+		return writeJSON(c, items)
+	}
+
+	return writeTable(c, items, columns, row)
+}
+
+func writeJSON(c *cli, v interface{}) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeTable[T any](c *cli, items []T, columns []string, row func(T) []string) error {
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	for _, item := range items {
+		fmt.Fprintln(tw, strings.Join(row(item), "\t"))
+	}
+	return tw.Flush()
+}
+
+// formatTime formats a timestamp for tables, leaving unset times blank
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/client"
+)
+
+// operation is one kind of request the generator sends
+type operation int
+
+const (
+	projectCreate operation = iota
+	projectUpdate
+	projectDelete
+	instanceCreate
+	instanceUpdate
+	instanceDelete
+	numOperations
+)
+
+var operationNames = [numOperations]string{
+	projectCreate:  "project.create",
+	projectUpdate:  "project.update",
+	projectDelete:  "project.delete",
+	instanceCreate: "instance.create",
+	instanceUpdate: "instance.update",
+	instanceDelete: "instance.delete",
+}
+
+func (op operation) String() string {
+	return operationNames[op]
+}
+
+// errSkipped is returned by an operation that found no resource to act on,
+// such as an update before anything has been created
+var errSkipped = errors.New("nothing to act on")
+
+// generator sends operations at fixed rates and records their latencies
+type generator struct {
+	client  *client.Client
+	timeout time.Duration
+
+	// slots limits the requests in flight
+	slots chan struct{}
+
+	runID string
+	seq   atomic.Int64
+
+	pool  *pool
+	stats *recorder
+}
+
+func newGenerator(c *client.Client, concurrency int, timeout time.Duration) *generator {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return &generator{
+		client:  c,
+		timeout: timeout,
+		slots:   make(chan struct{}, concurrency),
+		runID:   strconv.FormatInt(r.Int63n(1<<32), 36),
+		pool:    newPool(r),
+		stats:   newRecorder(),
+	}
+}
+
+// run starts each operation at its rate and stops after duration, waiting
+// for the requests in flight. The schedule is open-loop: a slow server does
+// not slow the schedule down, and an operation due while every slot is busy
+// is dropped rather than queued.
+func (g *generator) run(ctx context.Context, rates [numOperations]float64, duration time.Duration) *report {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	start := time.Now()
+	var schedules, requests sync.WaitGroup
+	for op, rate := range rates {
+		if rate == 0 {
+			continue
+		}
+
+		interval := time.Duration(float64(time.Second) / rate)
+		if interval <= 0 {
+			interval = 1
+		}
+
+		schedules.Add(1)
+		go func(op operation) {
+			defer schedules.Done()
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				select {
+				case g.slots <- struct{}{}:
+				default:
+					g.stats.drop(op)
+					continue
+				}
+
+				requests.Add(1)
+				go func() {
+					defer requests.Done()
+					defer func() { <-g.slots }()
+					g.do(op)
+				}()
+			}
+		}(operation(op))
+	}
+
+	schedules.Wait()
+	requests.Wait()
+	return g.stats.report(time.Since(start))
+}
+
+// do runs one operation and records its outcome
+func (g *generator) do(op operation) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	switch op {
+	case projectCreate:
+		err = g.createProject(ctx)
+	case projectUpdate:
+		err = g.updateProject(ctx)
+	case projectDelete:
+		err = g.deleteProject(ctx)
+	case instanceCreate:
+		err = g.createInstance(ctx)
+	case instanceUpdate:
+		err = g.updateInstance(ctx)
+	case instanceDelete:
+		err = g.deleteInstance(ctx)
+	}
+
+	if errors.Is(err, errSkipped) {
+		g.stats.skip(op)
+		return
+	}
+	g.stats.record(op, time.Since(start), err)
+}
+
+// name returns a resource name unique to this run
+func (g *generator) name() string {
+	return fmt.Sprintf("dirtload-%s-%d", g.runID, g.seq.Add(1))
+}
+
+// labels marks the resources of this run
+func (g *generator) labels() map[string]string {
+	return map[string]string{"dirtload": g.runID, "seq": strconv.FormatInt(g.seq.Add(1), 10)}
+}
+
+func (g *generator) createProject(ctx context.Context) error {
+	project, err := g.client.Projects.Create(ctx, domain.CreateProjectRequest{Name: g.name(), Labels: g.labels()})
+	if err != nil {
+		return err
+	}
+	g.pool.addProject(project.ID)
+	return nil
+}
+
+func (g *generator) updateProject(ctx context.Context) error {
+	id, ok := g.pool.holdProject()
+	if !ok {
+		return errSkipped
+	}
+	defer g.pool.releaseProject(id)
+
+	_, err := g.client.Projects.Update(ctx, id, domain.UpdateProjectRequest{Labels: g.labels()})
+	return err
+}
+
+func (g *generator) deleteProject(ctx context.Context) error {
+	id, ok := g.pool.takeProject()
+	if !ok {
+		return errSkipped
+	}
+
+	err := g.client.Projects.Delete(ctx, id, domain.DeleteProjectOptions{Cascade: true})
+	g.pool.deleteProject(id, err == nil || domain.IsNotFound(err))
+	return err
+}
+
+func (g *generator) createInstance(ctx context.Context) error {
+	projectID, ok := g.pool.holdProject()
+	if !ok {
+		return errSkipped
+	}
+	defer g.pool.releaseProject(projectID)
+
+	instance, err := g.client.Instances.Create(ctx, domain.CreateInstanceRequest{
+		ProjectID: projectID,
+		Name:      g.name(),
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu",
+		Labels:    g.labels(),
+	})
+	if err != nil {
+		return err
+	}
+	g.pool.addInstance(instanceRef{id: instance.ID, projectID: projectID})
+	return nil
+}
+
+func (g *generator) updateInstance(ctx context.Context) error {
+	ref, ok := g.pool.takeInstance()
+	if !ok {
+		return errSkipped
+	}
+	defer g.pool.releaseInstance(ref, true)
+
+	_, err := g.client.Instances.Update(ctx, ref.id, domain.UpdateInstanceRequest{Labels: g.labels()})
+	return err
+}
+
+func (g *generator) deleteInstance(ctx context.Context) error {
+	ref, ok := g.pool.takeInstance()
+	if !ok {
+		return errSkipped
+	}
+
+	err := g.client.Instances.Delete(ctx, ref.id)
+	g.pool.releaseInstance(ref, err != nil && !domain.IsNotFound(err))
+	return err
+}
+
+// cleanup deletes the projects the run created, and with them their
+// instances
+func (g *generator) cleanup(ctx context.Context) error {
+	var errs []error
+	for _, id := range g.pool.drain() {
+		ctx, cancel := context.WithTimeout(ctx, g.timeout)
+		err := g.client.Projects.Delete(ctx, id, domain.DeleteProjectOptions{Cascade: true})
+		cancel()
+		if err != nil && !domain.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("project %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// instanceRef identifies an instance and the project it belongs to
+type instanceRef struct {
+	id        string
+	projectID string
+}
+
+// pool tracks the live resources a run created, so that no two operations
+// race on one resource. Updates and instance creates hold a project, which
+// keeps it from being deleted under them; deletes take a project only once
+// nothing holds it, and set its instances aside until the delete finishes.
+// An instance is taken by one update or delete at a time, and holds its
+// project meanwhile.
+type pool struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	projects  []string
+	holds     map[string]int
+	instances []instanceRef
+
+	// deleting holds the instances of the projects being deleted
+	deleting map[string][]instanceRef
+}
+
+func newPool(r *rand.Rand) *pool {
+	return &pool{rand: r, holds: make(map[string]int), deleting: make(map[string][]instanceRef)}
+}
+
+func (p *pool) addProject(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.projects = append(p.projects, id)
+}
+
+// holdProject picks a live project and holds it until releaseProject
+func (p *pool) holdProject() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.projects) == 0 {
+		return "", false
+	}
+	id := p.projects[p.rand.Intn(len(p.projects))]
+	p.holds[id]++
+	return id, true
+}
+
+func (p *pool) releaseProject(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.release(id)
+}
+
+func (p *pool) release(id string) {
+	if p.holds[id]--; p.holds[id] <= 0 {
+		delete(p.holds, id)
+	}
+}
+
+// takeProject removes a project nothing holds from the pool, for deletion
+func (p *pool) takeProject() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.projects)
+	if n == 0 {
+		return "", false
+	}
+	start := p.rand.Intn(n)
+	for i := 0; i < n; i++ {
+		j := (start + i) % n
+		id := p.projects[j]
+		if p.holds[id] == 0 {
+			p.projects[j] = p.projects[n-1]
+			p.projects = p.projects[:n-1]
+			p.deleting[id] = p.removeInstances(id)
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// deleteProject finishes a takeProject. A deleted project's instances leave
+// the pool with it; a project that failed to delete goes back.
+func (p *pool) deleteProject(id string, deleted bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	instances := p.deleting[id]
+	delete(p.deleting, id)
+	if !deleted {
+		p.projects = append(p.projects, id)
+		p.instances = append(p.instances, instances...)
+	}
+}
+
+// removeInstances removes the instances of a project from the pool,
+// returning them
+func (p *pool) removeInstances(projectID string) []instanceRef {
+	var removed []instanceRef
+	kept := p.instances[:0]
+	for _, ref := range p.instances {
+		if ref.projectID == projectID {
+			removed = append(removed, ref)
+		} else {
+			kept = append(kept, ref)
+		}
+	}
+	p.instances = kept
+	return removed
+}
+
+// addInstance adds an instance created in a held project
+func (p *pool) addInstance(ref instanceRef) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.instances = append(p.instances, ref)
+}
+
+// takeInstance removes an instance from the pool until releaseInstance
+func (p *pool) takeInstance() (instanceRef, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.instances)
+	if n == 0 {
+		return instanceRef{}, false
+	}
+	i := p.rand.Intn(n)
+	ref := p.instances[i]
+	p.instances[i] = p.instances[n-1]
+	p.instances = p.instances[:n-1]
+	p.holds[ref.projectID]++
+	return ref, true
+}
+
+// releaseInstance finishes a takeInstance, returning the instance to the
+// pool if it still exists
+func (p *pool) releaseInstance(ref instanceRef, exists bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.release(ref.projectID)
+	if exists {
+		p.instances = append(p.instances, ref)
+	}
+}
+
+// drain empties the pool, returning its projects
+func (p *pool) drain() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	projects := p.projects
+	p.projects, p.instances = nil, nil
+	return projects
+}
@@ -0,0 +1,144 @@
+// Command dirtload generates load against a DirtCloud server. It creates,
+// updates and deletes projects and instances at fixed rates for a while and
+// then reports the latency of each operation.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/pkg/client"
+)
+
+const usage = `Usage: dirtload [flags]
+
+Runs each operation at its rate, in operations per second, for --duration
+and prints the latency percentiles of each. A rate of 0 disables the
+operation. Updates and deletes act on resources the run created.
+
+Flags:
+`
+
+const envHelp = `
+Environment:
+  DIRT_SERVER      API base URL, overridden by --server (default http://localhost:8080)
+  DIRT_TOKEN       bearer token
+  DIRT_TOKEN_FILE  file holding the bearer token, overridden by --token-file
+  DIRT_ACCESS_KEY  access key to sign requests with instead of sending a token
+  DIRT_SECRET_KEY  secret of the access key
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run executes a dirtload command line and returns the process exit code
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	flags := flag.NewFlagSet("dirtload", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+		fmt.Fprint(stderr, envHelp)
+	}
+
+	server := flags.String("server", getenv("DIRT_SERVER"), "API base URL")
+	tokenFile := flags.String("token-file", getenv("DIRT_TOKEN_FILE"), "file holding the bearer token")
+	output := flags.String("output", "table", "report format: json or table")
+	duration := flags.Duration("duration", 30*time.Second, "how long to generate load")
+	concurrency := flags.Int("concurrency", 16, "maximum requests in flight")
+	timeout := flags.Duration("timeout", 30*time.Second, "request timeout")
+	keep := flags.Bool("keep", false, "keep the resources the run created")
+
+	var rates [numOperations]float64
+	defaults := [numOperations]float64{
+		projectCreate:  1,
+		projectUpdate:  1,
+		projectDelete:  0.5,
+		instanceCreate: 10,
+		instanceUpdate: 5,
+		instanceDelete: 5,
+	}
+	for op := operation(0); op < numOperations; op++ {
+		flags.Float64Var(&rates[op], strings.ReplaceAll(op.String(), ".", "-")+"-rate", defaults[op], op.String()+" operations per second")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *output != "json" && *output != "table" {
+		fmt.Fprintf(stderr, "dirtload: unknown output format %q\n", *output)
+		return 2
+	}
+	if *duration <= 0 || *concurrency < 1 {
+		fmt.Fprintln(stderr, "dirtload: --duration and --concurrency must be positive")
+		return 2
+	}
+	for op, rate := range rates {
+		if rate < 0 {
+			fmt.Fprintf(stderr, "dirtload: the %s rate cannot be negative\n", operation(op))
+			return 2
+		}
+	}
+
+	token, err := loadToken(getenv("DIRT_TOKEN"), *tokenFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "dirtload: %v\n", err)
+		return 1
+	}
+
+	g := newGenerator(client.NewClient(client.Config{
+		BaseURL:   *server,
+		Token:     token,
+		AccessKey: getenv("DIRT_ACCESS_KEY"),
+		SecretKey: getenv("DIRT_SECRET_KEY"),
+	}), *concurrency, *timeout)
+
+	report := g.run(context.Background(), rates, *duration)
+
+	if !*keep {
+		if err := g.cleanup(context.Background()); err != nil {
+			fmt.Fprintf(stderr, "dirtload: cleanup: %v\n", err)
+		}
+	}
+
+	if *output == "json" {
+		err = report.writeJSON(stdout)
+	} else {
+		err = report.writeTable(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "dirtload: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// loadToken resolves the bearer token. A token in the environment wins over a
+// token file.
+func loadToken(envToken, tokenFile string) (string, error) {
+	if envToken != "" {
+		return envToken, nil
+	}
+	if tokenFile == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
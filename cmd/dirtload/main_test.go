@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/client"
+	"github.com/hypertf/dirtcloud-server/testing/tfharness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		name     string
+		sorted   []time.Duration
+		p        float64
+		expected time.Duration
+	}{
+		{name: "empty", sorted: nil, p: 50, expected: 0},
+		{name: "single", sorted: latencies[:1], p: 99, expected: time.Millisecond},
+		{name: "median", sorted: latencies, p: 50, expected: 50 * time.Millisecond},
+		{name: "p99", sorted: latencies, p: 99, expected: 99 * time.Millisecond},
+		{name: "max", sorted: latencies, p: 100, expected: 100 * time.Millisecond},
+		{name: "rounds up", sorted: latencies[:3], p: 50, expected: 2 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, percentile(tt.sorted, tt.p))
+		})
+	}
+}
+
+func TestPool_DeletesWaitForHolds(t *testing.T) {
+	p := newPool(rand.New(rand.NewSource(1)))
+	p.addProject("p1")
+
+	held, ok := p.holdProject()
+	require.True(t, ok)
+	_, ok = p.takeProject()
+	assert.False(t, ok, "a held project cannot be deleted")
+
+	p.releaseProject(held)
+	p.addInstance(instanceRef{id: "i1", projectID: "p1"})
+	ref, ok := p.takeInstance()
+	require.True(t, ok)
+	_, ok = p.takeProject()
+	assert.False(t, ok, "a project cannot be deleted while one of its instances is in use")
+
+	p.releaseInstance(ref, true)
+	id, ok := p.takeProject()
+	require.True(t, ok)
+	_, ok = p.takeInstance()
+	assert.False(t, ok, "the instances of a project being deleted cannot be used")
+	p.deleteProject(id, false)
+	_, ok = p.takeInstance()
+	assert.True(t, ok, "the instances of a project that failed to delete go back")
+	p.releaseInstance(ref, true)
+
+	id, ok = p.takeProject()
+	require.True(t, ok)
+	p.deleteProject(id, true)
+
+	_, ok = p.takeInstance()
+	assert.False(t, ok, "the instances of a deleted project leave the pool")
+}
+
+func TestRun(t *testing.T) {
+	h := tfharness.NewWithOptions(t, tfharness.Options{Token: "secret"})
+
+	var stdout, stderr bytes.Buffer
+	env := map[string]string{"DIRT_SERVER": h.URL, "DIRT_TOKEN": h.Token}
+	code := run([]string{
+		"--duration", "500ms",
+		"--output", "json",
+		"--project-create-rate", "20",
+		"--project-update-rate", "20",
+		"--project-delete-rate", "5",
+		"--instance-create-rate", "50",
+		"--instance-update-rate", "20",
+		"--instance-delete-rate", "20",
+	}, &stdout, &stderr, func(key string) string { return env[key] })
+	require.Equal(t, 0, code, stderr.String())
+
+	var rep report
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &rep))
+	ops := map[string]opReport{}
+	for _, op := range rep.Operations {
+		ops[op.Operation] = op
+		assert.Zero(t, op.Errors, "%s: %s", op.Operation, op.FirstError)
+		if op.Requests > 0 {
+			assert.LessOrEqual(t, op.P50Ms, op.P99Ms)
+			assert.LessOrEqual(t, op.P99Ms, op.MaxMs)
+		}
+	}
+	assert.Positive(t, ops["project.create"].Requests)
+	assert.Positive(t, ops["instance.create"].Requests)
+
+	// The run deletes what it created
+	c := client.NewClient(client.Config{BaseURL: h.URL, Token: h.Token})
+	projects, err := c.Projects.List(context.Background(), domain.ProjectListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "unknown flag", args: []string{"--bogus"}},
+		{name: "unknown output", args: []string{"--output", "yaml"}},
+		{name: "negative rate", args: []string{"--instance-create-rate", "-1"}},
+		{name: "no duration", args: []string{"--duration", "0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr, func(string) string { return "" })
+			assert.Equal(t, 2, code)
+			assert.Empty(t, stdout.String())
+		})
+	}
+}
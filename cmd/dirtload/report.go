@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// recorder collects the outcome of every operation of a run
+type recorder struct {
+	mu  sync.Mutex
+	ops [numOperations]opRecord
+}
+
+type opRecord struct {
+	latencies  []time.Duration
+	errors     int
+	skipped    int
+	dropped    int
+	firstError string
+}
+
+func newRecorder() *recorder {
+	return &recorder{}
+}
+
+// record adds a request that reached the server, or failed trying
+func (r *recorder) record(op operation, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := &r.ops[op]
+	rec.latencies = append(rec.latencies, latency)
+	if err != nil {
+		if rec.errors == 0 {
+			rec.firstError = err.Error()
+		}
+		rec.errors++
+	}
+}
+
+func (r *recorder) skip(op operation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops[op].skipped++
+}
+
+func (r *recorder) drop(op operation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops[op].dropped++
+}
+
+// report summarizes the operations recorded over elapsed
+func (r *recorder) report(elapsed time.Duration) *report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep := &report{Duration: elapsed.Round(time.Millisecond).String()}
+	for op := operation(0); op < numOperations; op++ {
+		rec := r.ops[op]
+		if len(rec.latencies) == 0 && rec.skipped == 0 && rec.dropped == 0 {
+			continue
+		}
+
+		latencies := append([]time.Duration(nil), rec.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		rep.Operations = append(rep.Operations, opReport{
+			Operation:  op.String(),
+			Requests:   len(latencies),
+			Errors:     rec.errors,
+			Skipped:    rec.skipped,
+			Dropped:    rec.dropped,
+			Throughput: float64(len(latencies)) / elapsed.Seconds(),
+			P50Ms:      milliseconds(percentile(latencies, 50)),
+			P90Ms:      milliseconds(percentile(latencies, 90)),
+			P99Ms:      milliseconds(percentile(latencies, 99)),
+			MaxMs:      milliseconds(percentile(latencies, 100)),
+			FirstError: rec.firstError,
+		})
+	}
+	return rep
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// report is the summary a run prints
+type report struct {
+	Duration   string     `json:"duration"`
+	Operations []opReport `json:"operations"`
+}
+
+// opReport summarizes one operation. Skipped operations found no resource
+// to act on; dropped ones came due while every request slot was busy.
+type opReport struct {
+	Operation  string  `json:"operation"`
+	Requests   int     `json:"requests"`
+	Errors     int     `json:"errors"`
+	Skipped    int     `json:"skipped"`
+	Dropped    int     `json:"dropped"`
+	Throughput float64 `json:"throughput"`
+	P50Ms      float64 `json:"p50_ms"`
+	P90Ms      float64 `json:"p90_ms"`
+	P99Ms      float64 `json:"p99_ms"`
+	MaxMs      float64 `json:"max_ms"`
+	FirstError string  `json:"first_error,omitempty"`
+}
+
+func (r *report) writeJSON(w io.Writer) error {
+	if r.Operations == nil {
+		r.Operations = []opReport{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *report) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tREQUESTS\tERRORS\tSKIPPED\tDROPPED\tREQ/S\tP50\tP90\tP99\tMAX")
+	for _, op := range r.Operations {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.1f\t%.1fms\t%.1fms\t%.1fms\t%.1fms\n",
+			op.Operation, op.Requests, op.Errors, op.Skipped, op.Dropped, op.Throughput, op.P50Ms, op.P90Ms, op.P99Ms, op.MaxMs)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nRan for %s\n", r.Duration)
+	for _, op := range r.Operations {
+		if op.FirstError != "" {
+			fmt.Fprintf(w, "%s: first error: %s\n", op.Operation, op.FirstError)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/api"
+)
+
+// unixPrefix marks a listen address as the path of a Unix domain socket
+const unixPrefix = "unix:"
+
+// listen listens on a TCP "host:port" address, probing idle connections
+// every keepAlive, or on a "unix:/path" socket. A socket file left behind by
+// a server that is no longer running is removed first.
+func listen(addr string, keepAlive time.Duration) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		lc := net.ListenConfig{KeepAlive: keepAlive}
+		return lc.Listen(context.Background(), "tcp", addr)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("invalid listen address %q: missing socket path", addr)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale socket %s: %w", path, err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// servers serves a router on the API, admin and metrics listeners
+type servers struct {
+	servers []*http.Server
+	errors  chan error
+}
+
+// startServers listens on every configured address and serves each role
+// from its own http.Server. The API listeners serve the admin and metrics
+// routes too unless those have addresses of their own.
+func startServers(router http.Handler, config Config) (*servers, error) {
+	addrs := map[string][]string{
+		api.ListenerAPI:     config.HTTPAddrs,
+		api.ListenerAdmin:   config.AdminAddrs,
+		api.ListenerMetrics: config.MetricsAddrs,
+	}
+	var separate []string
+	for _, role := range []string{api.ListenerAdmin, api.ListenerMetrics} {
+		if len(addrs[role]) > 0 {
+			separate = append(separate, role)
+		}
+	}
+
+	s := &servers{errors: make(chan error, len(config.HTTPAddrs)+len(config.AdminAddrs)+len(config.MetricsAddrs))}
+	for _, role := range []string{api.ListenerAPI, api.ListenerAdmin, api.ListenerMetrics} {
+		if len(addrs[role]) == 0 {
+			continue
+		}
+		server := &http.Server{
+			Handler:           api.ListenerHandler(router, role, separate...),
+			ReadTimeout:       config.ReadTimeout,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+			MaxHeaderBytes:    config.MaxHeaderBytes,
+		}
+		server.SetKeepAlivesEnabled(config.KeepAlives)
+		s.servers = append(s.servers, server)
+
+		for _, addr := range addrs[role] {
+			l, err := listen(addr, config.TCPKeepAlive)
+			if err != nil {
+				s.close()
+				return nil, fmt.Errorf("%s listener: %w", role, err)
+			}
+			log.Printf("DirtCloud %s listener on %s", role, listenerAddr(l))
+			go func() {
+				if err := server.Serve(l); err != http.ErrServerClosed {
+					s.errors <- err
+				}
+			}()
+		}
+	}
+	if len(s.servers) == 0 {
+		return nil, fmt.Errorf("no listen addresses configured")
+	}
+	return s, nil
+}
+
+// listenerAddr formats the address a listener is bound to, which for port 0
+// is the port the kernel picked
+func listenerAddr(l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return unixPrefix + l.Addr().String()
+	}
+	return l.Addr().String()
+}
+
+// shutdown gracefully stops every server, closing them outright if ctx
+// expires first
+func (s *servers) shutdown(ctx context.Context) {
+	for _, server := range s.servers {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Graceful shutdown failed: %v", err)
+			if err := server.Close(); err != nil {
+				log.Printf("Force close failed: %v", err)
+			}
+		}
+	}
+}
+
+// close stops every server immediately
+func (s *servers) close() {
+	for _, server := range s.servers {
+		server.Close()
+	}
+}
@@ -2,69 +2,237 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/hypertf/dirtcloud-server/api"
+	"github.com/hypertf/dirtcloud-server/domain"
 	"github.com/hypertf/dirtcloud-server/service"
 	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/hypertf/dirtcloud-server/storage/cache"
 	"github.com/hypertf/dirtcloud-server/storage/sqlite"
 )
 
 func main() {
+	migrateOnly := flag.Bool("migrate-only", false, "migrate the database schema to the latest version and exit")
+	migrateTo := flag.Int("migrate-to", -1, "migrate the database schema up or down to the given version and exit")
+	flag.Parse()
+
 	// Load configuration from environment variables
 	config := loadConfig()
 
+	if *migrateOnly || *migrateTo >= 0 {
+		if err := runMigrations(config.SQLiteDSN, *migrateTo); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	if config.AdminToken != "" && config.AdminToken == config.Token {
+		log.Fatalf("DIRT_ADMIN_TOKEN must differ from DIRT_TOKEN")
+	}
+
 	// Initialize database
-	db, err := sqlite.NewDB(config.SQLiteDSN)
+	db, err := openDB(config.SQLiteDSN, config)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
-	// Initialize repositories
-	projectRepo := sqlite.NewProjectRepository(db)
-	instanceRepo := sqlite.NewInstanceRepository(db)
-	metadataRepo := sqlite.NewMetadataRepository(db)
+	// Cache repository reads in memory, shared by the tenants' services
+	var repoCache *cache.Cache
+	var cacheStats func() []domain.CacheStats
+	if config.RepositoryCache {
+		repoCache = cache.New()
+		cacheStats = repoCache.Stats
+	}
 
 	// Initialize service layer
-	svc := service.NewService(projectRepo, instanceRepo, metadataRepo)
+	svc, err := newService(db, config, config.BackupDir, repoCache)
+	if err != nil {
+		log.Fatalf("Failed to initialize service: %v", err)
+	}
+
+	// Terminate expired instances, converge autoscaling groups, meter usage,
+	// provision databases, rotate secrets and take scheduled backups in the
+	// background
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	defer stopReaper()
+	runBackground(reaperCtx, svc, config)
 
 	// Initialize chaos service
 	chaosService := chaos.NewChaosService()
 
+	// Mirror API requests to external sinks
+	mirror, err := api.NewMirror(api.MirrorConfig{URL: config.MirrorURL, File: config.MirrorFile})
+	if err != nil {
+		log.Fatalf("Failed to initialize request mirror: %v", err)
+	}
+	if mirror != nil {
+		defer mirror.Close()
+	}
+
+	// Join a federation of regions
+	federationPeers, err := parseFederationPeers(config.FederationPeers)
+	if err != nil {
+		log.Fatalf("Invalid DIRT_FEDERATION_PEERS: %v", err)
+	}
+	federation, err := api.NewFederation(api.FederationConfig{
+		Region:    config.Region,
+		Peers:     federationPeers,
+		PeerToken: config.FederationToken,
+	})
+	if err != nil {
+		log.Fatalf("Failed to join federation: %v", err)
+	}
+	if federation != nil {
+		defer federation.Close()
+	}
+
+	// Validate JWT bearer tokens when a key source is configured
+	var jwtVerifier *api.JWTVerifier
+	if config.JWTJWKSURL != "" || config.JWTKeyFile != "" {
+		jwtConfig := api.JWTConfig{
+			JWKSURL:       config.JWTJWKSURL,
+			Issuer:        config.JWTIssuer,
+			Audience:      config.JWTAudience,
+			AdminScope:    config.JWTAdminScope,
+			ProjectsClaim: config.JWTProjectsClaim,
+			TenantClaim:   config.JWTTenantClaim,
+		}
+		if config.JWTKeyFile != "" {
+			if jwtConfig.Key, err = os.ReadFile(config.JWTKeyFile); err != nil {
+				log.Fatalf("Failed to read JWT key: %v", err)
+			}
+		}
+		if jwtVerifier, err = api.NewJWTVerifier(jwtConfig); err != nil {
+			log.Fatalf("Failed to initialize JWT validation: %v", err)
+		}
+	}
+
+	trustedProxies, err := api.ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid DIRT_TRUSTED_PROXIES: %v", err)
+	}
+
+	if err := api.ValidateCORSPolicy(config.CORS); err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
+
+	signingKeys, err := parseSigningKeys(config.SigningKeys)
+	if err != nil {
+		log.Fatalf("Invalid DIRT_SIGNING_KEYS: %v", err)
+	}
+
+	// Word error messages from the deployment's catalogs
+	var translator domain.Translator
+	if config.MessageCatalogFile != "" {
+		data, err := os.ReadFile(config.MessageCatalogFile)
+		if err != nil {
+			log.Fatalf("Failed to read message catalog: %v", err)
+		}
+		catalogs, err := domain.ParseCatalogs(data)
+		if err != nil {
+			log.Fatalf("Invalid message catalog: %v", err)
+		}
+		translator = catalogs
+	}
+
 	// Initialize API handlers
-	handler := api.NewHandler(svc, chaosService, config.Token)
+	handler := api.NewHandler(svc, chaosService, api.Config{
+		Token:        config.Token,
+		AdminToken:   config.AdminToken,
+		JWT:          jwtVerifier,
+		MaxBodyBytes: config.MaxBodyBytes,
+
+		SigningKeys:      signingKeys,
+		SigningClockSkew: config.SigningClockSkew,
+
+		MaxInFlight:         config.MaxInFlight,
+		MaxInFlightPerToken: config.MaxInFlightPerToken,
+		MaxInFlightPerRoute: config.MaxInFlightPerRoute,
+
+		MaxAuthFailures:   config.MaxAuthFailures,
+		AuthFailureWindow: config.AuthFailureWindow,
+		AuthLockout:       config.AuthLockout,
+
+		TrustedProxies: trustedProxies,
+		CORS:           &config.CORS,
+
+		RequestLogSize: config.RequestLogSize,
+		Mirror:         mirror,
+		Federation:     federation,
+
+		WebInsecureCookies: config.WebInsecureCookies,
+
+		JSONAPI:      config.JSONAPI,
+		Translator:   translator,
+		ErrorHelpURL: config.ErrorHelpURL,
+
+		WriteTimeout: config.WriteTimeout,
+
+		CacheStats: cacheStats,
+	})
 
 	// Setup router
-	router := api.SetupRouter(handler)
+	var router http.Handler
+	if config.Tenancy == "" {
+		router = api.SetupRouter(handler)
+	} else {
+		// Each tenant gets its own database, created on first use
+		var tenantDBs []*sqlite.DB
+		defer func() {
+			for _, tenantDB := range tenantDBs {
+				tenantDB.Close()
+			}
+		}()
+
+		tenants, err := api.NewTenantRouter(config.Tenancy, handler, func(tenant string) (*service.Service, error) {
+			if err := os.MkdirAll(config.TenantDir, 0o755); err != nil {
+				return nil, err
+			}
+			tenantDB, err := openDB(tenantDSN(config.TenantDir, tenant), config)
+			if err != nil {
+				return nil, err
+			}
+			tenantDBs = append(tenantDBs, tenantDB)
 
-	// Create HTTP server
-	server := &http.Server{
-		Addr:         config.HTTPAddr,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+			tenantSvc, err := newService(tenantDB, config, filepath.Join(config.BackupDir, tenant), repoCache)
+			if err != nil {
+				return nil, err
+			}
+			runBackground(reaperCtx, tenantSvc, config)
+			log.Printf("Opened tenant %s", tenant)
+			return tenantSvc, nil
+		}, config.MaxTenants)
+		if err != nil {
+			log.Fatalf("Failed to initialize tenancy: %v", err)
+		}
+		router = tenants
 	}
 
-	// Start server in a goroutine
-	serverErrors := make(chan error, 1)
-	go func() {
-		log.Printf("DirtCloud server starting on %s", config.HTTPAddr)
-		serverErrors <- server.ListenAndServe()
-	}()
+	// Start the API, admin and metrics listeners
+	servers, err := startServers(router, config)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
 
 	// Wait for shutdown signal
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
 	select {
-	case err := <-serverErrors:
+	case err := <-servers.errors:
 		log.Fatalf("Server error: %v", err)
 	case sig := <-shutdown:
 		log.Printf("Received signal %v, starting graceful shutdown", sig)
@@ -73,31 +241,591 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		if err := server.Shutdown(ctx); err != nil {
-			log.Printf("Graceful shutdown failed: %v", err)
-			if err := server.Close(); err != nil {
-				log.Printf("Force close failed: %v", err)
-			}
-		}
+		servers.shutdown(ctx)
 	}
 
 	log.Println("Server stopped")
 }
 
+// runMigrations migrates the schema to version, or to the latest version when
+// version is negative
+func runMigrations(dsn string, version int) error {
+	db, err := sqlite.Open(dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if version < 0 {
+		err = db.Migrate()
+	} else {
+		err = db.MigrateTo(version)
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	log.Printf("Database schema is at version %d", current)
+	return nil
+}
+
+// openDB opens and migrates a database, applying the schema settings of config
+func openDB(dsn string, config Config) (*sqlite.DB, error) {
+	db, err := sqlite.NewDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.SetUniqueInstanceNames(!config.AllowDuplicateInstanceNames); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to configure instance name uniqueness: %w", err)
+	}
+
+	return db, nil
+}
+
+// newService creates a service backed by the repositories of db, keeping
+// database snapshots in backupDir and caching reads in repoCache unless it
+// is nil. Each service numbers deterministic IDs on its own.
+func newService(db *sqlite.DB, config Config, backupDir string, repoCache *cache.Cache) (*service.Service, error) {
+	ids, err := newIDGenerator(config)
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := parsePriceSheet(config.PriceSheet)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneCapacity, err := parseZoneCapacity(config.ZoneCapacity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DIRT_ZONE_CAPACITY: %w", err)
+	}
+
+	repos := service.NewSQLiteRepositories(db, backupDir)
+	if repoCache != nil {
+		repos = repoCache.Wrap(repos)
+	}
+	if config.ReplicaLag > 0 {
+		repos.Replica = sqlite.NewReplica(db, config.ReplicaLag, func(snapshot *sqlite.DB) service.Repositories {
+			return service.NewSQLiteRepositories(snapshot, backupDir)
+		})
+	}
+
+	svc := service.NewService(repos, service.Config{
+		AllowOnlineResize:           config.AllowOnlineResize,
+		AllowImageUpdate:            config.AllowImageUpdate,
+		AllowDuplicateInstanceNames: config.AllowDuplicateInstanceNames,
+		IDs:                         ids,
+		Prices:                      prices,
+		DatabaseProvisioningDelay:   config.DatabaseProvisioningDelay,
+		DeletionDelay:               config.DeletionDelay,
+		Zones:                       config.Zones,
+		ZoneCapacity:                zoneCapacity,
+		SpotPreemptionRate:          config.SpotPreemptionRate,
+		SpotPreemptionNotice:        config.SpotPreemptionNotice,
+		ProbeFailureRate:            config.ProbeFailureRate,
+		UnhealthyThreshold:          config.UnhealthyThreshold,
+		Rand:                        chaos.NewLockedRand(config.Seed),
+		MetadataLimits: domain.MetadataLimits{
+			MaxKeys:       config.MetadataMaxKeys,
+			MaxValueBytes: config.MetadataMaxValueBytes,
+			MaxTotalBytes: config.MetadataMaxTotalBytes,
+		},
+	})
+
+	if err := validateStoredData(svc, config.DataValidation); err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+// validateStoredData checks the stored data against the current validation
+// rules, logging each problem and what was done about it
+func validateStoredData(svc *service.Service, mode string) error {
+	problems, err := svc.ValidateStoredData(mode)
+	if err != nil {
+		return fmt.Errorf("failed to validate stored data: %w", err)
+	}
+
+	for _, p := range problems {
+		action := "left as is"
+		if p.Action != "" {
+			action = p.Action
+		}
+		log.Printf("Stored %s %s: %s %s (%s)", p.ResourceType, p.ResourceID, p.Field, p.Message, action)
+	}
+	if len(problems) > 0 && mode == domain.DataValidationWarn {
+		log.Printf("Found %d problems in stored data; set DIRT_DATA_VALIDATION=fix or quarantine to repair them", len(problems))
+	}
+	return nil
+}
+
+// parsePriceSheet parses a JSON price sheet. Prices it leaves out keep their
+// defaults; an empty sheet uses the defaults throughout.
+func parsePriceSheet(value string) (*domain.PriceSheet, error) {
+	prices := service.DefaultPriceSheet
+	if value == "" {
+		return &prices, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&prices); err != nil {
+		return nil, fmt.Errorf("invalid price sheet: %w", err)
+	}
+	return &prices, nil
+}
+
+// newIDGenerator creates the resource ID generator selected by config
+func newIDGenerator(config Config) (*service.IDGenerator, error) {
+	if config.DeterministicIDs {
+		return service.NewDeterministicIDGenerator(config.IDFormat, config.IDSeed)
+	}
+	return service.NewIDGenerator(config.IDFormat)
+}
+
+// runBackground starts the background loops of a service until ctx is done
+func runBackground(ctx context.Context, svc *service.Service, config Config) {
+	if config.ReaperInterval > 0 {
+		go svc.RunReaper(ctx, config.ReaperInterval)
+	}
+	if config.AutoscalerInterval > 0 {
+		go svc.RunAutoscaler(ctx, config.AutoscalerInterval)
+	}
+	if config.UsageInterval > 0 {
+		go svc.RunUsageMeter(ctx, config.UsageInterval)
+	}
+	if config.DatabaseProvisionerInterval > 0 {
+		go svc.RunDatabaseProvisioner(ctx, config.DatabaseProvisionerInterval)
+	}
+	if config.SecretRotationInterval > 0 {
+		go svc.RunSecretRotation(ctx, config.SecretRotationInterval)
+	}
+	if config.BackupInterval > 0 {
+		go svc.RunBackups(ctx, config.BackupInterval, config.BackupKeep)
+	}
+	if config.PreemptorInterval > 0 && config.SpotPreemptionRate > 0 {
+		go svc.RunPreemptor(ctx, config.PreemptorInterval)
+	}
+	if config.HealthCheckInterval > 0 {
+		go svc.RunHealthChecker(ctx, config.HealthCheckInterval)
+	}
+	if config.ReplicaLag > 0 && config.ReplicaSyncInterval > 0 {
+		go svc.RunReplication(ctx, config.ReplicaSyncInterval)
+	}
+}
+
+// tenantDSN returns the SQLite DSN of a tenant database in dir
+func tenantDSN(dir, tenant string) string {
+	return "file:" + filepath.Join(dir, tenant+".db") + "?_busy_timeout=5000&_fk=1"
+}
+
 // Config holds server configuration
 type Config struct {
-	HTTPAddr  string
-	Token     string
-	SQLiteDSN string
+	HTTPAddrs    []string
+	Token        string
+	SQLiteDSN    string
+	MaxBodyBytes int64
+
+	// RepositoryCache caches project, instance and metadata reads in memory
+	RepositoryCache bool
+
+	// AdminToken is required by the admin and chaos routes instead of
+	// Token when set, so clients holding only Token cannot reset or
+	// reconfigure the server
+	AdminToken string
+
+	// Each address is "host:port" or "unix:/path/to.sock". The admin and
+	// chaos routes and /metrics are served on the HTTP addresses too unless
+	// AdminAddrs or MetricsAddrs are set.
+	AdminAddrs   []string
+	MetricsAddrs []string
+
+	// HTTP server timeouts; 0 means none. ReadHeaderTimeout of 0 uses
+	// ReadTimeout, and MaxHeaderBytes of 0 the net/http default of 1 MB.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// KeepAlives lets clients reuse connections for several requests.
+	// TCPKeepAlive is the period of TCP keep-alive probes on accepted
+	// connections: 0 uses the net package default and a negative value
+	// disables them.
+	KeepAlives   bool
+	TCPKeepAlive time.Duration
+
+	// JWT bearer tokens are validated against the keys at JWTJWKSURL or the
+	// PEM public key or HMAC secret in JWTKeyFile. A subject is the IAM
+	// member "user:<sub>"; the projects claim grants it project roles and
+	// the admin scope full access.
+	JWTJWKSURL       string
+	JWTKeyFile       string
+	JWTIssuer        string
+	JWTAudience      string
+	JWTAdminScope    string
+	JWTProjectsClaim string
+	JWTTenantClaim   string
+
+	// SigningKeys lists the access keys accepted for HMAC request signing as
+	// comma-separated "access_key:secret" pairs. Signatures more than
+	// SigningClockSkew from the server clock are rejected.
+	SigningKeys      string
+	SigningClockSkew time.Duration
+
+	// ReaperInterval is how often expired instances are terminated and
+	// deleted ones removed; 0 disables the reaper
+	ReaperInterval time.Duration
+
+	// DeletionDelay is how long deleted instances stay in the deleting
+	// status, still readable, before they are removed
+	DeletionDelay time.Duration
+
+	// Zones are the availability zones instances are placed in; empty uses
+	// the default zones
+	Zones []string
+
+	// ZoneCapacity lists the CPU and memory each zone has for instances, as
+	// comma-separated "zone=cpu:memory_mb" entries; zones left out, and
+	// fields of 0, are unlimited
+	ZoneCapacity string
+
+	// The preemptor runs every PreemptorInterval, warning each running spot
+	// instance of its preemption with a probability of SpotPreemptionRate
+	// and preempting it SpotPreemptionNotice later; a rate of 0 disables it
+	PreemptorInterval    time.Duration
+	SpotPreemptionRate   float64
+	SpotPreemptionNotice time.Duration
+
+	// The health checker probes running instances every
+	// HealthCheckInterval, each probe failing with a probability of
+	// ProbeFailureRate; instances failing UnhealthyThreshold probes in a row
+	// are unhealthy, and replaced if they have auto-repair. An interval of
+	// 0 disables it.
+	HealthCheckInterval time.Duration
+	ProbeFailureRate    float64
+	UnhealthyThreshold  int
+
+	// Seed seeds the simulated spot preemptions and health probe failures,
+	// so that runs with the same seed can be reproduced
+	Seed int64
+
+	// AutoscalerInterval is how often autoscaling groups step toward their
+	// desired size; 0 disables the autoscaler
+	AutoscalerInterval time.Duration
+
+	// UsageInterval is how often instance usage is metered; 0 disables
+	// usage metering
+	UsageInterval time.Duration
+
+	// DatabaseProvisioningDelay is how long new and resized databases take
+	// to become available
+	DatabaseProvisioningDelay time.Duration
+
+	// DatabaseProvisionerInterval is how often databases whose provisioning
+	// delay passed are made available; 0 leaves them provisioning until
+	// they are read
+	DatabaseProvisionerInterval time.Duration
+
+	// SecretRotationInterval is how often secrets due for rotation are
+	// rotated; 0 disables rotation
+	SecretRotationInterval time.Duration
+
+	// PriceSheet is a JSON price sheet for usage cost estimates, overriding
+	// the default prices it names
+	PriceSheet string
+
+	// AllowOnlineResize lets running instances be resized without stopping them
+	AllowOnlineResize bool
+
+	// AllowImageUpdate lets instance images be changed in place instead of
+	// requiring a new instance
+	AllowImageUpdate bool
+
+	// AllowDuplicateInstanceNames lets several instances in a project share a name
+	AllowDuplicateInstanceNames bool
+
+	// MetadataMaxKeys, MetadataMaxValueBytes and MetadataMaxTotalBytes cap
+	// the metadata store; 0 leaves a limit off
+	MetadataMaxKeys       int
+	MetadataMaxValueBytes int
+	MetadataMaxTotalBytes int
+
+	// DataValidation is what happens at startup to stored resources that
+	// break the current validation rules, such as names written before the
+	// name rules tightened: "warn" logs them, "fix" rewrites them,
+	// "quarantine" moves them aside where it can, and "off" skips the check
+	DataValidation string
+
+	// IDFormat is the resource ID scheme: "hex", "uuid" or "prefixed". With
+	// DeterministicIDs, IDs are numbered sequentially from IDSeed instead of
+	// being random, and restart when the data is reset.
+	IDFormat         string
+	DeterministicIDs bool
+	IDSeed           uint64
+
+	// BackupInterval is how often a database snapshot is written to
+	// BackupDir; 0 disables scheduled backups. Only the newest BackupKeep
+	// snapshots are kept, or all of them when BackupKeep is 0.
+	BackupInterval time.Duration
+	BackupDir      string
+	BackupKeep     int
+
+	// ReplicaLag, when positive, simulates a read replica lagging the
+	// database by that long, which requests with X-Dirt-Read-Preference:
+	// replica read from. The replica copies the database every
+	// ReplicaSyncInterval, so its reads are up to that much staler still.
+	ReplicaLag          time.Duration
+	ReplicaSyncInterval time.Duration
+
+	// In-flight API request limits; 0 means unlimited
+	MaxInFlight         int
+	MaxInFlightPerToken int
+	MaxInFlightPerRoute int
+
+	// MaxAuthFailures failed authentication attempts from one address
+	// within AuthFailureWindow lock it out for AuthLockout; 0 disables
+	// lockout
+	MaxAuthFailures   int
+	AuthFailureWindow time.Duration
+	AuthLockout       time.Duration
+
+	// TrustedProxies are the IPs and CIDR ranges of reverse proxies whose
+	// X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers give
+	// the client address, scheme and host of a request
+	TrustedProxies []string
+
+	// CORS is the initial CORS policy; lists left unset take their
+	// defaults. The policy can be replaced at runtime through the admin API.
+	CORS domain.CORSPolicy
+
+	// RequestLogSize is how many recent API requests are kept for
+	// GET /v1/admin/requests; 0 disables request logging
+	RequestLogSize int
+
+	// MirrorURL and MirrorFile mirror every API request, sanitized, to an
+	// HTTP endpoint as JSON and to a file as NDJSON
+	MirrorURL  string
+	MirrorFile string
+
+	// Region is the region this server serves in a federation of servers;
+	// empty runs a standalone server. FederationPeers lists the other
+	// regions as comma-separated "region=url" pairs, and FederationToken is
+	// the admin token the server authenticates to them with.
+	Region          string
+	FederationPeers string
+	FederationToken string
+
+	// WebInsecureCookies drops the Secure attribute from web console session
+	// cookies, for consoles served over plain HTTP
+	WebInsecureCookies bool
+
+	// JSONAPI formats every /v1 response as a JSON:API document; without
+	// it only requests accepting application/vnd.api+json get them
+	JSONAPI bool
+
+	// MessageCatalogFile is a JSON file of message catalogs keyed by
+	// language, which customize or translate error messages
+	MessageCatalogFile string
+
+	// ErrorHelpURL is where the help_url of errors points, with {reason}
+	// replaced by their reason; empty uses the help under /v1/errors
+	ErrorHelpURL string
+
+	// Tenancy selects how requests are assigned isolated databases: "token",
+	// "header", "claim" (the JWT tenant claim), or "" to serve everyone from
+	// one database. Tenant databases are created in TenantDir.
+	Tenancy   string
+	TenantDir string
+
+	// MaxTenants caps the tenant databases opened; 0 leaves them unlimited
+	MaxTenants int
 }
 
 // loadConfig loads configuration from environment variables
 func loadConfig() Config {
 	return Config{
-		HTTPAddr:  getEnv("DIRT_HTTP_ADDR", ":8080"),
-		Token:     getEnv("DIRT_TOKEN", ""),
-		SQLiteDSN: getEnv("DIRT_SQLITE_DSN", ""),
+		HTTPAddrs:  getListEnvDefault("DIRT_HTTP_ADDR", []string{":8080"}),
+		Token:      getEnv("DIRT_TOKEN", ""),
+		AdminToken: getEnv("DIRT_ADMIN_TOKEN", ""),
+
+		AdminAddrs:   getListEnv("DIRT_ADMIN_ADDR"),
+		MetricsAddrs: getListEnv("DIRT_METRICS_ADDR"),
+
+		ReadTimeout:       getDurationEnv("DIRT_READ_TIMEOUT", 15*time.Second),
+		ReadHeaderTimeout: getDurationEnv("DIRT_READ_HEADER_TIMEOUT", 0),
+		WriteTimeout:      getDurationEnv("DIRT_WRITE_TIMEOUT", 15*time.Second),
+		IdleTimeout:       getDurationEnv("DIRT_IDLE_TIMEOUT", 60*time.Second),
+		MaxHeaderBytes:    int(getInt64Env("DIRT_MAX_HEADER_BYTES", 0)),
+
+		KeepAlives:   getBoolEnv("DIRT_KEEP_ALIVES", true),
+		TCPKeepAlive: getDurationEnv("DIRT_TCP_KEEP_ALIVE", 0),
+
+		JWTJWKSURL:       getEnv("DIRT_JWT_JWKS_URL", ""),
+		JWTKeyFile:       getEnv("DIRT_JWT_KEY_FILE", ""),
+		JWTIssuer:        getEnv("DIRT_JWT_ISSUER", ""),
+		JWTAudience:      getEnv("DIRT_JWT_AUDIENCE", ""),
+		JWTAdminScope:    getEnv("DIRT_JWT_ADMIN_SCOPE", api.DefaultJWTAdminScope),
+		JWTProjectsClaim: getEnv("DIRT_JWT_PROJECTS_CLAIM", "projects"),
+		JWTTenantClaim:   getEnv("DIRT_JWT_TENANT_CLAIM", "tenant"),
+
+		SigningKeys:      getEnv("DIRT_SIGNING_KEYS", ""),
+		SigningClockSkew: getDurationEnv("DIRT_SIGNING_CLOCK_SKEW", api.DefaultSigningClockSkew),
+
+		SQLiteDSN:    getEnv("DIRT_SQLITE_DSN", ""),
+		MaxBodyBytes: getInt64Env("DIRT_MAX_BODY_BYTES", api.DefaultMaxBodyBytes),
+
+		RepositoryCache: getBoolEnv("DIRT_REPOSITORY_CACHE", false),
+
+		ReaperInterval:     getDurationEnv("DIRT_REAPER_INTERVAL", 5*time.Second),
+		DeletionDelay:      getDurationEnv("DIRT_DELETION_DELAY", 0),
+		Zones:              getListEnv("DIRT_ZONES"),
+		ZoneCapacity:       getEnv("DIRT_ZONE_CAPACITY", ""),
+		AutoscalerInterval: getDurationEnv("DIRT_AUTOSCALER_INTERVAL", 2*time.Second),
+		AllowOnlineResize:  getBoolEnv("DIRT_ALLOW_ONLINE_RESIZE", false),
+		AllowImageUpdate:   getBoolEnv("DIRT_ALLOW_IMAGE_UPDATE", false),
+
+		PreemptorInterval:    getDurationEnv("DIRT_PREEMPTOR_INTERVAL", 10*time.Second),
+		SpotPreemptionRate:   getFloatEnv("DIRT_SPOT_PREEMPTION_RATE", 0),
+		SpotPreemptionNotice: getDurationEnv("DIRT_SPOT_PREEMPTION_NOTICE", 30*time.Second),
+
+		HealthCheckInterval: getDurationEnv("DIRT_HEALTH_CHECK_INTERVAL", 5*time.Second),
+		ProbeFailureRate:    getFloatEnv("DIRT_PROBE_FAILURE_RATE", 0),
+		UnhealthyThreshold:  int(getInt64Env("DIRT_UNHEALTHY_THRESHOLD", 3)),
+		Seed:                getInt64Env("DIRT_SEED", time.Now().UnixNano()),
+
+		UsageInterval: getDurationEnv("DIRT_USAGE_INTERVAL", 10*time.Second),
+		PriceSheet:    getEnv("DIRT_PRICE_SHEET", ""),
+
+		DatabaseProvisioningDelay:   getDurationEnv("DIRT_DATABASE_PROVISIONING_DELAY", 5*time.Second),
+		DatabaseProvisionerInterval: getDurationEnv("DIRT_DATABASE_PROVISIONER_INTERVAL", time.Second),
+
+		SecretRotationInterval: getDurationEnv("DIRT_SECRET_ROTATION_INTERVAL", 10*time.Second),
+
+		AllowDuplicateInstanceNames: getBoolEnv("DIRT_ALLOW_DUPLICATE_INSTANCE_NAMES", false),
+
+		MetadataMaxKeys:       int(getInt64Env("DIRT_METADATA_MAX_KEYS", 0)),
+		MetadataMaxValueBytes: int(getInt64Env("DIRT_METADATA_MAX_VALUE_BYTES", 0)),
+		MetadataMaxTotalBytes: int(getInt64Env("DIRT_METADATA_MAX_TOTAL_BYTES", 0)),
+
+		DataValidation: getEnv("DIRT_DATA_VALIDATION", domain.DataValidationWarn),
+
+		IDFormat:         getEnv("DIRT_ID_FORMAT", service.IDFormatHex),
+		DeterministicIDs: getBoolEnv("DIRT_DETERMINISTIC_IDS", false),
+		IDSeed:           uint64(getInt64Env("DIRT_ID_SEED", 0)),
+
+		BackupInterval: getDurationEnv("DIRT_BACKUP_INTERVAL", 0),
+		BackupDir:      getEnv("DIRT_BACKUP_DIR", "backups"),
+		BackupKeep:     int(getInt64Env("DIRT_BACKUP_KEEP", 10)),
+
+		ReplicaLag:          getDurationEnv("DIRT_REPLICA_LAG", 0),
+		ReplicaSyncInterval: getDurationEnv("DIRT_REPLICA_SYNC_INTERVAL", 250*time.Millisecond),
+
+		MaxInFlight:         int(getInt64Env("DIRT_MAX_INFLIGHT", 0)),
+		MaxInFlightPerToken: int(getInt64Env("DIRT_MAX_INFLIGHT_PER_TOKEN", 0)),
+		MaxInFlightPerRoute: int(getInt64Env("DIRT_MAX_INFLIGHT_PER_ROUTE", 0)),
+
+		MaxAuthFailures:   int(getInt64Env("DIRT_AUTH_MAX_FAILURES", 0)),
+		AuthFailureWindow: getDurationEnv("DIRT_AUTH_FAILURE_WINDOW", api.DefaultAuthFailureWindow),
+		AuthLockout:       getDurationEnv("DIRT_AUTH_LOCKOUT", api.DefaultAuthLockout),
+
+		TrustedProxies: getListEnv("DIRT_TRUSTED_PROXIES"),
+
+		CORS: domain.CORSPolicy{
+			AllowedOrigins:   getListEnv("DIRT_CORS_ALLOWED_ORIGINS"),
+			AllowedMethods:   getListEnv("DIRT_CORS_ALLOWED_METHODS"),
+			AllowedHeaders:   getListEnv("DIRT_CORS_ALLOWED_HEADERS"),
+			ExposedHeaders:   getListEnv("DIRT_CORS_EXPOSED_HEADERS"),
+			AllowCredentials: getBoolEnv("DIRT_CORS_ALLOW_CREDENTIALS", false),
+			MaxAgeSeconds:    int(getDurationEnv("DIRT_CORS_MAX_AGE", 0).Seconds()),
+		},
+
+		RequestLogSize: int(getInt64Env("DIRT_REQUEST_LOG_SIZE", api.DefaultRequestLogSize)),
+
+		MirrorURL:  getEnv("DIRT_MIRROR_URL", ""),
+		MirrorFile: getEnv("DIRT_MIRROR_FILE", ""),
+
+		Region:          getEnv("DIRT_REGION", ""),
+		FederationPeers: getEnv("DIRT_FEDERATION_PEERS", ""),
+		FederationToken: getEnv("DIRT_FEDERATION_TOKEN", ""),
+
+		WebInsecureCookies: getBoolEnv("DIRT_WEB_INSECURE_COOKIES", false),
+
+		JSONAPI: getBoolEnv("DIRT_JSONAPI", false),
+
+		MessageCatalogFile: getEnv("DIRT_MESSAGE_CATALOG", ""),
+		ErrorHelpURL:       getEnv("DIRT_ERROR_HELP_URL", ""),
+
+		Tenancy:   getEnv("DIRT_TENANCY", ""),
+		TenantDir: getEnv("DIRT_TENANT_DIR", "tenants"),
+
+		MaxTenants: int(getInt64Env("DIRT_MAX_TENANTS", 100)),
+	}
+}
+
+// parseSigningKeys parses comma-separated "access_key:secret" pairs
+func parseSigningKeys(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	keys := make(map[string]string)
+	for i, pair := range strings.Split(value, ",") {
+		accessKey, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || accessKey == "" || secret == "" {
+			// The pair is left out of the error since it may hold a secret
+			return nil, fmt.Errorf("entry %d is not of the form access_key:secret", i+1)
+		}
+		keys[accessKey] = secret
 	}
+	return keys, nil
+}
+
+// parseFederationPeers parses comma-separated "region=url" pairs
+func parseFederationPeers(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	peers := make(map[string]string)
+	for i, pair := range strings.Split(value, ",") {
+		region, url, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || region == "" || url == "" {
+			return nil, fmt.Errorf("entry %d is not of the form region=url", i+1)
+		}
+		peers[region] = url
+	}
+	return peers, nil
+}
+
+// parseZoneCapacity parses comma-separated "zone=cpu:memory_mb" entries
+func parseZoneCapacity(value string) (map[string]domain.ZoneCapacity, error) {
+	if value == "" {
+		return nil, nil
+	}
+	pools := make(map[string]domain.ZoneCapacity)
+	for i, entry := range strings.Split(value, ",") {
+		zone, pool, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		cpu, memoryMB, ok2 := strings.Cut(pool, ":")
+		if !ok || !ok2 || zone == "" {
+			return nil, fmt.Errorf("entry %d is not of the form zone=cpu:memory_mb", i+1)
+		}
+		var capacity domain.ZoneCapacity
+		var err error
+		if capacity.CPU, err = strconv.Atoi(cpu); err != nil || capacity.CPU < 0 {
+			return nil, fmt.Errorf("entry %d has an invalid cpu %q", i+1, cpu)
+		}
+		if capacity.MemoryMB, err = strconv.Atoi(memoryMB); err != nil || capacity.MemoryMB < 0 {
+			return nil, fmt.Errorf("entry %d has an invalid memory_mb %q", i+1, memoryMB)
+		}
+		pools[zone] = capacity
+	}
+	return pools, nil
 }
 
 // getEnv gets an environment variable with a default value
@@ -106,4 +834,69 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getInt64Env gets an integer environment variable with a default value
+func getInt64Env(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// getDurationEnv gets a duration environment variable (e.g. "10s") with a default value
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getFloatEnv gets a floating point environment variable with a default value
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// getListEnv gets a comma-separated list environment variable, or nil if it
+// is unset
+func getListEnv(key string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getListEnvDefault gets a comma-separated list environment variable, or
+// defaultValue when it is unset
+func getListEnvDefault(key string, defaultValue []string) []string {
+	if list := getListEnv(key); list != nil {
+		return list
+	}
+	return defaultValue
+}
+
+// getBoolEnv gets a boolean environment variable with a default value
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
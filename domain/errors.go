@@ -1,60 +1,142 @@
 package domain
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 )
 
 // Error codes
 const (
-	ErrorCodeNotFound           = "NOT_FOUND"
-	ErrorCodeAlreadyExists      = "ALREADY_EXISTS"
-	ErrorCodeInvalidInput       = "INVALID_INPUT"
+	ErrorCodeNotFound            = "NOT_FOUND"
+	ErrorCodeAlreadyExists       = "ALREADY_EXISTS"
+	ErrorCodeInvalidInput        = "INVALID_INPUT"
 	ErrorCodeForeignKeyViolation = "FOREIGN_KEY_VIOLATION"
-	ErrorCodeInternalError      = "INTERNAL_ERROR"
-	ErrorCodeUnauthorized       = "UNAUTHORIZED"
-	ErrorCodeTooManyRequests    = "TOO_MANY_REQUESTS"
-	ErrorCodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	ErrorCodeInternalError       = "INTERNAL_ERROR"
+	ErrorCodeUnauthorized        = "UNAUTHORIZED"
+	ErrorCodeTooManyRequests     = "TOO_MANY_REQUESTS"
+	ErrorCodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
+	ErrorCodeQuotaExceeded       = "QUOTA_EXCEEDED"
+	ErrorCodeFailedPrecondition  = "FAILED_PRECONDITION"
+	ErrorCodePermissionDenied    = "PERMISSION_DENIED"
+	ErrorCodeDeadlineExceeded    = "DEADLINE_EXCEEDED"
+	ErrorCodeImmutableField      = "IMMUTABLE_FIELD"
+	ErrorCodeAmbiguousMatch      = "AMBIGUOUS_MATCH"
+	ErrorCodeWrongRegion         = "WRONG_REGION"
+	ErrorCodeResourceExhausted   = "RESOURCE_EXHAUSTED"
 )
 
-// DirtError represents a domain error with structured information
+// Sentinel errors, one per code, for use with errors.Is. Any DirtError in an
+// error's chain matches the sentinel of its code, whatever its message and
+// details.
+var (
+	ErrNotFound            = &DirtError{Code: ErrorCodeNotFound}
+	ErrAlreadyExists       = &DirtError{Code: ErrorCodeAlreadyExists}
+	ErrInvalidInput        = &DirtError{Code: ErrorCodeInvalidInput}
+	ErrForeignKeyViolation = &DirtError{Code: ErrorCodeForeignKeyViolation}
+	ErrInternal            = &DirtError{Code: ErrorCodeInternalError}
+	ErrUnauthorized        = &DirtError{Code: ErrorCodeUnauthorized}
+	ErrTooManyRequests     = &DirtError{Code: ErrorCodeTooManyRequests}
+	ErrServiceUnavailable  = &DirtError{Code: ErrorCodeServiceUnavailable}
+	ErrQuotaExceeded       = &DirtError{Code: ErrorCodeQuotaExceeded}
+	ErrFailedPrecondition  = &DirtError{Code: ErrorCodeFailedPrecondition}
+	ErrPermissionDenied    = &DirtError{Code: ErrorCodePermissionDenied}
+	ErrDeadlineExceeded    = &DirtError{Code: ErrorCodeDeadlineExceeded}
+	ErrImmutableField      = &DirtError{Code: ErrorCodeImmutableField}
+	ErrAmbiguousMatch      = &DirtError{Code: ErrorCodeAmbiguousMatch}
+	ErrWrongRegion         = &DirtError{Code: ErrorCodeWrongRegion}
+	ErrResourceExhausted   = &DirtError{Code: ErrorCodeResourceExhausted}
+)
+
+// DirtError represents a domain error with structured information. Reason,
+// Retryable and HelpURL let clients classify it without parsing Message.
 type DirtError struct {
-	Code    string                 `json:"error"`
-	Message string                 `json:"message"`
-	Details map[string]interface{} `json:"details,omitempty"`
+	Code      string                 `json:"error"`
+	Message   string                 `json:"message"`
+	Reason    string                 `json:"reason,omitempty"`
+	Retryable bool                   `json:"retryable"`
+	HelpURL   string                 `json:"help_url,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+
+	// messageID and params word Message from the message catalog, for
+	// errors created from it
+	messageID string
+	params    map[string]interface{}
+
+	// cause is the underlying error, such as a SQL error. It is reported by
+	// Error and Unwrap but never sent to clients.
+	cause error
 }
 
 func (e *DirtError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.cause)
+	}
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
-// NewError creates a new domain error
+// Is reports whether target is a DirtError of the same code and, if target
+// has a reason, the same reason. It lets errors.Is match the sentinels.
+func (e *DirtError) Is(target error) bool {
+	t, ok := target.(*DirtError)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code && (t.Reason == "" || t.Reason == e.Reason)
+}
+
+// Unwrap returns the underlying cause of the error, if any
+func (e *DirtError) Unwrap() error {
+	return e.cause
+}
+
+// WithCause records the underlying error that led to the error and returns
+// the error
+func (e *DirtError) WithCause(cause error) *DirtError {
+	e.cause = cause
+	return e
+}
+
+// AsDirtError returns the first DirtError in err's chain
+func AsDirtError(err error) (*DirtError, bool) {
+	var dirtErr *DirtError
+	if errors.As(err, &dirtErr) {
+		return dirtErr, true
+	}
+	return nil, false
+}
+
+// NewError creates a new domain error with the default reason of its code
 func NewError(code, message string, details ...map[string]interface{}) *DirtError {
 	var d map[string]interface{}
 	if len(details) > 0 {
 		d = details[0]
 	}
-	return &DirtError{
+	err := &DirtError{
 		Code:    code,
 		Message: message,
 		Details: d,
 	}
+	return err.WithReason(defaultReason(code).Reason)
 }
 
 // NotFoundError creates a not found error
 func NotFoundError(resource string, identifier string) *DirtError {
-	return NewError(ErrorCodeNotFound, fmt.Sprintf("%s not found", resource), map[string]interface{}{
+	details := map[string]interface{}{
 		"resource":   resource,
 		"identifier": identifier,
-	})
+	}
+	return newMessageError(ErrorCodeNotFound, MessageNotFound, details, details)
 }
 
 // AlreadyExistsError creates an already exists error
 func AlreadyExistsError(resource string, field string, value string) *DirtError {
-	return NewError(ErrorCodeAlreadyExists, fmt.Sprintf("%s with %s '%s' already exists", resource, field, value), map[string]interface{}{
+	details := map[string]interface{}{
 		"resource": resource,
 		"field":    field,
 		"value":    value,
-	})
+	}
+	return newMessageError(ErrorCodeAlreadyExists, MessageAlreadyExists, details, details)
 }
 
 // InvalidInputError creates an invalid input error
@@ -62,13 +144,61 @@ func InvalidInputError(message string, details map[string]interface{}) *DirtErro
 	return NewError(ErrorCodeInvalidInput, message, details)
 }
 
+// FieldViolation describes a single invalid request field
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldViolations accumulates field violations so that all problems with a
+// request can be reported in a single error
+type FieldViolations []FieldViolation
+
+// Add records a violation for the given field
+func (v *FieldViolations) Add(field, message string) {
+	*v = append(*v, FieldViolation{Field: field, Message: message})
+}
+
+// Err returns an invalid input error listing all violations, or nil if there are none
+func (v FieldViolations) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return ValidationError(v)
+}
+
+// ValidationError creates an invalid input error carrying field-level violations
+func ValidationError(violations []FieldViolation) *DirtError {
+	details := map[string]interface{}{
+		"fields": violations,
+	}
+	if len(violations) == 1 {
+		params := map[string]interface{}{"field": violations[0].Field, "problem": violations[0].Message}
+		return newMessageError(ErrorCodeInvalidInput, MessageFieldViolation, params, details)
+	}
+	return newMessageError(ErrorCodeInvalidInput, MessageValidationFailed, nil, details)
+}
+
+// InstanceNameConflictError creates an error for an instance name that is
+// already taken in a project. existingID may be empty when the conflicting
+// instance is not known.
+func InstanceNameConflictError(projectID, name, existingID string) *DirtError {
+	err := AlreadyExistsError("instance", "name", name)
+	err.Details["project_id"] = projectID
+	if existingID != "" {
+		err.Details["existing_id"] = existingID
+	}
+	return err
+}
+
 // ForeignKeyViolationError creates a foreign key violation error
 func ForeignKeyViolationError(resource string, field string, value string) *DirtError {
-	return NewError(ErrorCodeForeignKeyViolation, fmt.Sprintf("Referenced %s with %s '%s' does not exist", resource, field, value), map[string]interface{}{
+	details := map[string]interface{}{
 		"resource": resource,
 		"field":    field,
 		"value":    value,
-	})
+	}
+	return newMessageError(ErrorCodeForeignKeyViolation, MessageForeignKeyViolation, details, details)
 }
 
 // InternalError creates an internal error
@@ -91,34 +221,169 @@ func ServiceUnavailableError(message string) *DirtError {
 	return NewError(ErrorCodeServiceUnavailable, message)
 }
 
-// IsNotFound checks if error is a not found error
-func IsNotFound(err error) bool {
-	if dirtErr, ok := err.(*DirtError); ok {
-		return dirtErr.Code == ErrorCodeNotFound
+// DeadlineExceededError creates a deadline exceeded error
+func DeadlineExceededError(message string) *DirtError {
+	return NewError(ErrorCodeDeadlineExceeded, message)
+}
+
+// QuotaExceededError creates a quota exceeded error
+func QuotaExceededError(resource string, limit string, max int, requested int) *DirtError {
+	details := map[string]interface{}{
+		"resource":  resource,
+		"limit":     limit,
+		"max":       max,
+		"requested": requested,
 	}
-	return false
+	return newMessageError(ErrorCodeQuotaExceeded, MessageQuotaExceeded, details, details)
 }
 
-// IsAlreadyExists checks if error is an already exists error
-func IsAlreadyExists(err error) bool {
-	if dirtErr, ok := err.(*DirtError); ok {
-		return dirtErr.Code == ErrorCodeAlreadyExists
+// FailedPreconditionError creates an error for an operation rejected because
+// the resource is not in a state that allows it
+func FailedPreconditionError(message string, details map[string]interface{}) *DirtError {
+	return NewError(ErrorCodeFailedPrecondition, message, details)
+}
+
+// TFStateLockedError creates an error for a write to a Terraform state that
+// another client holds the lock on. The details carry the holder's lock info.
+func TFStateLockedError(name string, lock *TFStateLock) *DirtError {
+	params := map[string]interface{}{"name": name, "lock_id": lock.ID}
+	return newMessageError(ErrorCodeFailedPrecondition, MessageTFStateLocked, params, map[string]interface{}{
+		"name": name,
+		"lock": json.RawMessage(lock.Info),
+	}).WithReason(ReasonStateLocked)
+}
+
+// DeletionProtectedError creates an error for deleting a resource that has
+// deletion protection enabled
+func DeletionProtectedError(resource string, id string) *DirtError {
+	details := map[string]interface{}{
+		"resource": resource,
+		"id":       id,
 	}
-	return false
+	return newMessageError(ErrorCodeFailedPrecondition, MessageDeletionProtected, details, details).WithReason(ReasonDeletionProtected)
 }
 
-// IsForeignKeyViolation checks if error is a foreign key violation error
-func IsForeignKeyViolation(err error) bool {
-	if dirtErr, ok := err.(*DirtError); ok {
-		return dirtErr.Code == ErrorCodeForeignKeyViolation
+// ImmutableFieldError creates an error for an update to a field that can only
+// be set on creation. The resource has to be deleted and recreated instead.
+func ImmutableFieldError(resource string, id string, field string) *DirtError {
+	details := map[string]interface{}{
+		"resource": resource,
+		"id":       id,
+		"field":    field,
 	}
-	return false
+	return newMessageError(ErrorCodeImmutableField, MessageImmutableField, details, details)
 }
 
-// IsInvalidInput checks if error is an invalid input error
-func IsInvalidInput(err error) bool {
-	if dirtErr, ok := err.(*DirtError); ok {
-		return dirtErr.Code == ErrorCodeInvalidInput
+// AmbiguousMatchError creates an error for a lookup that should find exactly
+// one resource but matched several. The details list their IDs in order.
+func AmbiguousMatchError(resource string, ids []string) *DirtError {
+	details := map[string]interface{}{
+		"resource": resource,
+		"count":    len(ids),
+		"ids":      ids,
+	}
+	return newMessageError(ErrorCodeAmbiguousMatch, MessageAmbiguousMatch, details, details)
+}
+
+// WrongRegionError creates an error for a request about a resource that
+// lives in another region, naming the region and where its API is served
+func WrongRegionError(resource, id, region, url string) *DirtError {
+	details := map[string]interface{}{
+		"resource": resource,
+		"id":       id,
+		"region":   region,
+		"url":      url,
+	}
+	return newMessageError(ErrorCodeWrongRegion, MessageWrongRegion, details, details)
+}
+
+// ZoneUnavailableError creates an error for an operation on a resource in
+// an availability zone that is down
+func ZoneUnavailableError(resource, zone string) *DirtError {
+	details := map[string]interface{}{
+		"resource": resource,
+		"zone":     zone,
 	}
-	return false
-}
\ No newline at end of file
+	return newMessageError(ErrorCodeServiceUnavailable, MessageZoneUnavailable, details, details).WithReason(ReasonZoneUnavailable)
+}
+
+// ZoneStockoutError creates an error for a request for more of a resource,
+// "cpu" or "memory_mb", than a zone has left. The details suggest the zones
+// that could take the request, which may be none.
+func ZoneStockoutError(zone, resource string, requested, available int, suggestedZones []string) *DirtError {
+	params := map[string]interface{}{
+		"zone":      zone,
+		"resource":  resource,
+		"requested": requested,
+		"available": available,
+	}
+	details := map[string]interface{}{
+		"zone":            zone,
+		"resource":        resource,
+		"requested":       requested,
+		"available":       available,
+		"suggested_zones": suggestedZones,
+	}
+	return newMessageError(ErrorCodeResourceExhausted, MessageZoneStockout, params, details)
+}
+
+// PermissionDeniedError creates an error for a caller that lacks the role an operation requires
+func PermissionDeniedError(message string, details map[string]interface{}) *DirtError {
+	return NewError(ErrorCodePermissionDenied, message, details)
+}
+
+// IsNotFound checks if err or an error it wraps is a not found error
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsAlreadyExists checks if err or an error it wraps is an already exists error
+func IsAlreadyExists(err error) bool {
+	return errors.Is(err, ErrAlreadyExists)
+}
+
+// IsForeignKeyViolation checks if err or an error it wraps is a foreign key violation error
+func IsForeignKeyViolation(err error) bool {
+	return errors.Is(err, ErrForeignKeyViolation)
+}
+
+// IsImmutableField checks if err or an error it wraps is an immutable field error
+func IsImmutableField(err error) bool {
+	return errors.Is(err, ErrImmutableField)
+}
+
+// IsInvalidInput checks if err or an error it wraps is an invalid input error
+func IsInvalidInput(err error) bool {
+	return errors.Is(err, ErrInvalidInput)
+}
+
+// IsFailedPrecondition checks if err or an error it wraps is a failed precondition error
+func IsFailedPrecondition(err error) bool {
+	return errors.Is(err, ErrFailedPrecondition)
+}
+
+// IsQuotaExceeded checks if err or an error it wraps is a quota exceeded error
+func IsQuotaExceeded(err error) bool {
+	return errors.Is(err, ErrQuotaExceeded)
+}
+
+// IsPermissionDenied checks if err or an error it wraps is a permission denied error
+func IsPermissionDenied(err error) bool {
+	return errors.Is(err, ErrPermissionDenied)
+}
+
+// IsAmbiguousMatch checks if err or an error it wraps is an ambiguous match error
+func IsAmbiguousMatch(err error) bool {
+	return errors.Is(err, ErrAmbiguousMatch)
+}
+
+// IsWrongRegion checks if err or an error it wraps is a wrong region error
+func IsWrongRegion(err error) bool {
+	return errors.Is(err, ErrWrongRegion)
+}
+
+// IsResourceExhausted checks if err or an error it wraps is a resource
+// exhausted error
+func IsResourceExhausted(err error) bool {
+	return errors.Is(err, ErrResourceExhausted)
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,6 +31,11 @@ func TestDirtError_Error(t *testing.T) {
 			},
 			expected: "INVALID_INPUT: validation failed",
 		},
+		{
+			name:     "error with cause",
+			err:      InternalError("failed to create project").WithCause(errors.New("disk I/O error")),
+			expected: "INTERNAL_ERROR: failed to create project: disk I/O error",
+		},
 	}
 
 	for _, tt := range tests {
@@ -53,6 +60,8 @@ func TestNewError(t *testing.T) {
 			expected: &DirtError{
 				Code:    ErrorCodeNotFound,
 				Message: "not found",
+				Reason:  ReasonResourceNotFound,
+				HelpURL: "/v1/errors/RESOURCE_NOT_FOUND",
 				Details: nil,
 			},
 		},
@@ -64,6 +73,8 @@ func TestNewError(t *testing.T) {
 			expected: &DirtError{
 				Code:    ErrorCodeInvalidInput,
 				Message: "invalid",
+				Reason:  ReasonInvalidField,
+				HelpURL: "/v1/errors/INVALID_FIELD",
 				Details: map[string]interface{}{"field": "name"},
 			},
 		},
@@ -79,7 +90,7 @@ func TestNewError(t *testing.T) {
 
 func TestNotFoundError(t *testing.T) {
 	err := NotFoundError("project", "123")
-	
+
 	assert.Equal(t, ErrorCodeNotFound, err.Code)
 	assert.Equal(t, "project not found", err.Message)
 	assert.Equal(t, map[string]interface{}{
@@ -90,7 +101,7 @@ func TestNotFoundError(t *testing.T) {
 
 func TestAlreadyExistsError(t *testing.T) {
 	err := AlreadyExistsError("project", "name", "test")
-	
+
 	assert.Equal(t, ErrorCodeAlreadyExists, err.Code)
 	assert.Equal(t, "project with name 'test' already exists", err.Message)
 	assert.Equal(t, map[string]interface{}{
@@ -103,15 +114,38 @@ func TestAlreadyExistsError(t *testing.T) {
 func TestInvalidInputError(t *testing.T) {
 	details := map[string]interface{}{"field": "cpu", "min": 1}
 	err := InvalidInputError("CPU must be positive", details)
-	
+
 	assert.Equal(t, ErrorCodeInvalidInput, err.Code)
 	assert.Equal(t, "CPU must be positive", err.Message)
 	assert.Equal(t, details, err.Details)
 }
 
+func TestFieldViolations_Err(t *testing.T) {
+	var empty FieldViolations
+	assert.NoError(t, empty.Err())
+
+	var single FieldViolations
+	single.Add("name", "cannot be empty")
+	err := single.Err().(*DirtError)
+	assert.Equal(t, ErrorCodeInvalidInput, err.Code)
+	assert.Equal(t, "name cannot be empty", err.Message)
+
+	var multiple FieldViolations
+	multiple.Add("name", "cannot be empty")
+	multiple.Add("cpu", "must be positive")
+	err = multiple.Err().(*DirtError)
+	assert.Equal(t, "validation failed", err.Message)
+	assert.Equal(t, map[string]interface{}{
+		"fields": []FieldViolation{
+			{Field: "name", Message: "cannot be empty"},
+			{Field: "cpu", Message: "must be positive"},
+		},
+	}, err.Details)
+}
+
 func TestForeignKeyViolationError(t *testing.T) {
 	err := ForeignKeyViolationError("project", "id", "123")
-	
+
 	assert.Equal(t, ErrorCodeForeignKeyViolation, err.Code)
 	assert.Equal(t, "Referenced project with id '123' does not exist", err.Message)
 	assert.Equal(t, map[string]interface{}{
@@ -123,7 +157,7 @@ func TestForeignKeyViolationError(t *testing.T) {
 
 func TestInternalError(t *testing.T) {
 	err := InternalError("something went wrong")
-	
+
 	assert.Equal(t, ErrorCodeInternalError, err.Code)
 	assert.Equal(t, "something went wrong", err.Message)
 	assert.Nil(t, err.Details)
@@ -131,7 +165,7 @@ func TestInternalError(t *testing.T) {
 
 func TestUnauthorizedError(t *testing.T) {
 	err := UnauthorizedError("invalid token")
-	
+
 	assert.Equal(t, ErrorCodeUnauthorized, err.Code)
 	assert.Equal(t, "invalid token", err.Message)
 	assert.Nil(t, err.Details)
@@ -139,7 +173,7 @@ func TestUnauthorizedError(t *testing.T) {
 
 func TestTooManyRequestsError(t *testing.T) {
 	err := TooManyRequestsError("rate limited")
-	
+
 	assert.Equal(t, ErrorCodeTooManyRequests, err.Code)
 	assert.Equal(t, "rate limited", err.Message)
 	assert.Nil(t, err.Details)
@@ -147,7 +181,7 @@ func TestTooManyRequestsError(t *testing.T) {
 
 func TestServiceUnavailableError(t *testing.T) {
 	err := ServiceUnavailableError("service down")
-	
+
 	assert.Equal(t, ErrorCodeServiceUnavailable, err.Code)
 	assert.Equal(t, "service down", err.Message)
 	assert.Nil(t, err.Details)
@@ -276,4 +310,34 @@ func TestIsInvalidInput(t *testing.T) {
 			assert.Equal(t, tt.expected, IsInvalidInput(tt.err))
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestDirtError_Is(t *testing.T) {
+	cause := errors.New("UNIQUE constraint failed: projects.name")
+	err := AlreadyExistsError("project", "name", "web").WithCause(cause)
+	wrapped := fmt.Errorf("creating project: %w", err)
+
+	assert.True(t, errors.Is(wrapped, ErrAlreadyExists))
+	assert.False(t, errors.Is(wrapped, ErrNotFound))
+	assert.True(t, errors.Is(wrapped, cause), "the cause is part of the chain")
+	assert.True(t, IsAlreadyExists(wrapped))
+
+	// Sentinels with a reason only match errors with that reason
+	inUse := FailedPreconditionError("project has instances", nil).WithReason(ReasonResourceInUse)
+	assert.True(t, errors.Is(inUse, ErrFailedPrecondition))
+	assert.True(t, errors.Is(inUse, &DirtError{Code: ErrorCodeFailedPrecondition, Reason: ReasonResourceInUse}))
+	assert.False(t, errors.Is(inUse, &DirtError{Code: ErrorCodeFailedPrecondition, Reason: ReasonStateLocked}))
+}
+
+func TestAsDirtError(t *testing.T) {
+	err := NotFoundError("project", "123")
+
+	found, ok := AsDirtError(fmt.Errorf("lookup: %w", err))
+	assert.True(t, ok)
+	assert.Same(t, err, found)
+
+	_, ok = AsDirtError(assert.AnError)
+	assert.False(t, ok)
+	_, ok = AsDirtError(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Message IDs of the message catalog. Most are the code of the errors they
+// word; codes whose errors are worded differently by cause have one ID per
+// wording.
+const (
+	MessageNotFound            = ErrorCodeNotFound
+	MessageAlreadyExists       = ErrorCodeAlreadyExists
+	MessageValidationFailed    = ErrorCodeInvalidInput
+	MessageFieldViolation      = ErrorCodeInvalidInput + ".field"
+	MessageForeignKeyViolation = ErrorCodeForeignKeyViolation
+	MessageQuotaExceeded       = ErrorCodeQuotaExceeded
+	MessageTFStateLocked       = ErrorCodeFailedPrecondition + ".tfstate_locked"
+	MessageDeletionProtected   = ErrorCodeFailedPrecondition + ".deletion_protected"
+	MessageImmutableField      = ErrorCodeImmutableField
+	MessageAmbiguousMatch      = ErrorCodeAmbiguousMatch
+	MessageWrongRegion         = ErrorCodeWrongRegion
+	MessageZoneUnavailable     = ErrorCodeServiceUnavailable + ".zone_unavailable"
+	MessageZoneStockout        = ErrorCodeResourceExhausted
+)
+
+// MessageCatalog maps message IDs to message templates. Templates name their
+// parameters in braces, such as "{resource} not found".
+type MessageCatalog map[string]string
+
+// DefaultMessages is the catalog errors are worded with when created
+var DefaultMessages = MessageCatalog{
+	MessageNotFound:            "{resource} not found",
+	MessageAlreadyExists:       "{resource} with {field} '{value}' already exists",
+	MessageValidationFailed:    "validation failed",
+	MessageFieldViolation:      "{field} {problem}",
+	MessageForeignKeyViolation: "Referenced {resource} with {field} '{value}' does not exist",
+	MessageQuotaExceeded:       "{resource} quota exceeded: {limit} limit is {max}",
+	MessageTFStateLocked:       "terraform state {name} is locked by {lock_id}",
+	MessageDeletionProtected:   "{resource} {id} has deletion protection enabled; unset deletion_protection to delete it",
+	MessageImmutableField:      "{field} cannot be changed on an existing {resource}; delete and recreate the {resource} instead",
+	MessageAmbiguousMatch:      "{count} {resource} resources match; narrow the lookup to exactly one",
+	MessageWrongRegion:         "{resource} {id} is in region {region}; send the request to {url}",
+	MessageZoneUnavailable:     "zone {zone} is down; {resource} resources cannot be created or changed in it until it recovers",
+	MessageZoneStockout:        "zone {zone} is out of capacity: {requested} {resource} requested but {available} available; try one of suggested_zones",
+}
+
+// Render fills in the template of a message with params. It returns false
+// if the catalog has no such message.
+func (c MessageCatalog) Render(id string, params map[string]interface{}) (string, bool) {
+	template, ok := c[id]
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template[start+1:], '}')
+		if start < 0 || end < 0 {
+			b.WriteString(template)
+			return b.String(), true
+		}
+		end += start + 1
+
+		b.WriteString(template[:start])
+		if value, ok := params[template[start+1:end]]; ok {
+			fmt.Fprint(&b, value)
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+}
+
+// Translator words catalog messages for the languages a client accepts, in
+// order of preference. Deployments can supply their own to customize or
+// translate error messages.
+type Translator interface {
+	Translate(languages []string, id string, params map[string]interface{}) (string, bool)
+}
+
+// DefaultLanguage keys the catalog of Catalogs used for clients accepting
+// none of the others
+const DefaultLanguage = "*"
+
+// Catalogs is a Translator over message catalogs keyed by lower-case
+// language tag, such as "fr" or "pt-br". Catalogs need only hold the messages
+// they change; the others keep their default wording.
+type Catalogs map[string]MessageCatalog
+
+// Translate renders a message from the catalog of the first accepted
+// language that has it, trying each language tag before its base language,
+// then from the DefaultLanguage catalog
+func (c Catalogs) Translate(languages []string, id string, params map[string]interface{}) (string, bool) {
+	for _, lang := range languages {
+		lang = strings.ToLower(lang)
+		if message, ok := c[lang].Render(id, params); ok {
+			return message, true
+		}
+		if base, _, ok := strings.Cut(lang, "-"); ok {
+			if message, ok := c[base].Render(id, params); ok {
+				return message, true
+			}
+		}
+	}
+	return c[DefaultLanguage].Render(id, params)
+}
+
+// ParseCatalogs parses message catalogs from a JSON object keyed by language
+// tag, each a catalog object keyed by message ID. Unknown message IDs are
+// rejected so that typos do not go unnoticed.
+func ParseCatalogs(data []byte) (Catalogs, error) {
+	var catalogs Catalogs
+	if err := json.Unmarshal(data, &catalogs); err != nil {
+		return nil, err
+	}
+
+	normalized := make(Catalogs, len(catalogs))
+	for lang, catalog := range catalogs {
+		for id := range catalog {
+			if _, ok := DefaultMessages[id]; !ok {
+				return nil, fmt.Errorf("catalog %q: unknown message %q", lang, id)
+			}
+		}
+		normalized[strings.ToLower(lang)] = catalog
+	}
+	return normalized, nil
+}
+
+// newMessageError creates an error worded by the default catalog. The
+// message and its params are kept so that it can be localized.
+func newMessageError(code, id string, params map[string]interface{}, details map[string]interface{}) *DirtError {
+	message, _ := DefaultMessages.Render(id, params)
+	err := NewError(code, message, details)
+	err.messageID = id
+	err.params = params
+	return err
+}
+
+// Localize returns the error with its message worded by t for the accepted
+// languages. Errors not created from the catalog, or that t has no wording
+// for, are returned as they are.
+func (e *DirtError) Localize(t Translator, languages []string) *DirtError {
+	if t == nil || e.messageID == "" {
+		return e
+	}
+
+	message, ok := t.Translate(languages, e.messageID, e.params)
+	if !ok || message == e.Message {
+		return e
+	}
+
+	localized := *e
+	localized.Message = message
+	return &localized
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCatalog_Render(t *testing.T) {
+	catalog := MessageCatalog{"m": "{count} {resource} match {missing}", "plain": "no params"}
+
+	message, ok := catalog.Render("m", map[string]interface{}{"count": 2, "resource": "instance"})
+	assert.True(t, ok)
+	assert.Equal(t, "2 instance match {missing}", message)
+
+	message, ok = catalog.Render("plain", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "no params", message)
+
+	_, ok = catalog.Render("other", nil)
+	assert.False(t, ok)
+}
+
+func TestDefaultMessages(t *testing.T) {
+	// Errors keep their wording now that it comes from the catalog
+	assert.Equal(t, "project not found", NotFoundError("project", "p-1").Message)
+	assert.Equal(t, "instance with name 'web' already exists", AlreadyExistsError("instance", "name", "web").Message)
+	assert.Equal(t, "name cannot be empty", ValidationError([]FieldViolation{{Field: "name", Message: "cannot be empty"}}).Message)
+	assert.Equal(t, "validation failed", ValidationError([]FieldViolation{{Field: "a"}, {Field: "b"}}).Message)
+	assert.Equal(t, "cpu quota exceeded: per_project limit is 8", QuotaExceededError("cpu", "per_project", 8, 9).Message)
+	assert.Equal(t, "terraform state prod is locked by lock-1", TFStateLockedError("prod", &TFStateLock{ID: "lock-1", Info: "{}"}).Message)
+}
+
+func TestDirtError_Localize(t *testing.T) {
+	catalogs, err := ParseCatalogs([]byte(`{
+		"fr": {"NOT_FOUND": "{resource} introuvable"},
+		"pt-BR": {"NOT_FOUND": "{resource} não encontrado"},
+		"*": {"ALREADY_EXISTS": "{resource} '{value}' is taken"}
+	}`))
+	require.NoError(t, err)
+
+	notFound := NotFoundError("project", "p-1")
+	assert.Equal(t, "project introuvable", notFound.Localize(catalogs, []string{"de", "fr-ca"}).Message)
+	assert.Equal(t, "project não encontrado", notFound.Localize(catalogs, []string{"pt-br"}).Message)
+	assert.Equal(t, "project not found", notFound.Localize(catalogs, []string{"de"}).Message)
+	assert.Equal(t, "project not found", notFound.Message, "the original is left as is")
+
+	localized := AlreadyExistsError("project", "name", "web").Localize(catalogs, nil)
+	assert.Equal(t, "project 'web' is taken", localized.Message)
+	assert.Equal(t, ErrorCodeAlreadyExists, localized.Code)
+	assert.Equal(t, "web", localized.Details["value"])
+
+	// Errors with free-form messages are not in the catalog
+	assert.Equal(t, "boom", InternalError("boom").Localize(catalogs, []string{"fr"}).Message)
+
+	_, err = ParseCatalogs([]byte(`{"fr": {"NOT_FOUNDD": "x"}}`))
+	assert.Error(t, err)
+}
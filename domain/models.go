@@ -1,15 +1,82 @@
 package domain
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
 // Project represents a project in the DirtCloud system
 type Project struct {
-	ID        string    `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID             string            `json:"id" db:"id"`
+	Name           string            `json:"name" db:"name"`
+	OrganizationID string            `json:"organization_id,omitempty" db:"organization_id"`
+	FolderID       string            `json:"folder_id,omitempty" db:"folder_id"`
+	Labels         map[string]string `json:"labels,omitempty" db:"labels"`
+	ChaosProfile   string            `json:"chaos_profile,omitempty" db:"chaos_profile"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+
+	// DeletionProtection makes deleting the project fail until it is unset
+	DeletionProtection bool `json:"deletion_protection" db:"deletion_protection"`
+}
+
+// Organization is the root of the resource hierarchy
+type Organization struct {
+	ID        string            `json:"id" db:"id"`
+	Name      string            `json:"name" db:"name"`
+	Labels    map[string]string `json:"labels,omitempty" db:"labels"`
+	Quota     *Quota            `json:"quota,omitempty" db:"quota"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// Folder groups projects and other folders within an organization
+type Folder struct {
+	ID             string            `json:"id" db:"id"`
+	OrganizationID string            `json:"organization_id" db:"organization_id"`
+	ParentID       string            `json:"parent_id,omitempty" db:"parent_id"`
+	Name           string            `json:"name" db:"name"`
+	Labels         map[string]string `json:"labels,omitempty" db:"labels"`
+	Quota          *Quota            `json:"quota,omitempty" db:"quota"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// Quota limits the resources each project beneath a hierarchy node may use.
+// Zero values mean unlimited.
+type Quota struct {
+	MaxInstances int `json:"max_instances,omitempty"`
+	MaxCPU       int `json:"max_cpu,omitempty"`
+	MaxMemoryMB  int `json:"max_memory_mb,omitempty"`
+}
+
+// HierarchyNode identifies an ancestor of a project
+type HierarchyNode struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Hierarchy node types
+const (
+	NodeTypeOrganization = "organization"
+	NodeTypeFolder       = "folder"
+)
+
+// ProjectAncestry describes a project's position in the hierarchy along with
+// the labels and quota it inherits from its ancestors
+type ProjectAncestry struct {
+	Project         *Project          `json:"project"`
+	Ancestors       []HierarchyNode   `json:"ancestors"`
+	EffectiveLabels map[string]string `json:"effective_labels"`
+	EffectiveQuota  Quota             `json:"effective_quota"`
+}
+
+// HierarchyChildren lists the direct children of an organization or folder
+type HierarchyChildren struct {
+	Folders  []*Folder  `json:"folders"`
+	Projects []*Project `json:"projects"`
 }
 
 // Instance represents a compute instance within a project
@@ -23,14 +90,636 @@ type Instance struct {
 	Status    string    `json:"status" db:"status"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	Labels map[string]string `json:"labels,omitempty" db:"labels"`
+
+	// Zone is the availability zone the instance runs in. Instances created
+	// before zones existed have none.
+	Zone string `json:"zone,omitempty" db:"zone"`
+
+	// Class is the instance's class. Spot instances can be preempted at any
+	// time, with a warning event shortly before.
+	Class string `json:"class" db:"class"`
+
+	// Health is the result of the instance's simulated health probes. With
+	// AutoRepair set, an unhealthy instance is replaced by a new one with
+	// the same spec and a new ID.
+	Health     string `json:"health" db:"health"`
+	AutoRepair bool   `json:"auto_repair" db:"auto_repair"`
+
+	// AutoscalingGroupID is set on instances managed by an autoscaling group
+	AutoscalingGroupID string `json:"autoscaling_group_id,omitempty" db:"autoscaling_group_id"`
+
+	// ExpiresAt is when the reaper terminates the instance, if ever
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+
+	// DeletionProtection makes DELETE requests for the instance fail until
+	// it is unset. Like termination protection, it does not stop the reaper
+	// or an autoscaling group from terminating the instance.
+	DeletionProtection bool `json:"deletion_protection" db:"deletion_protection"`
+
+	// DeleteAt is when a deleting instance is removed
+	DeleteAt *time.Time `json:"delete_at,omitempty" db:"delete_at"`
 }
 
 // InstanceStatus constants
 const (
 	StatusRunning = "running"
 	StatusStopped = "stopped"
+
+	// StatusDeleting is the status of an instance between its deletion
+	// being requested and the instance being removed, when deletion is
+	// delayed
+	StatusDeleting = "deleting"
+
+	// StatusUnavailable is the status of instances in a zone that is down.
+	// They return to their previous status when the zone recovers.
+	StatusUnavailable = "unavailable"
+
+	// StatusTerminated is never stored: status change events report it for
+	// deleted instances
+	StatusTerminated = "terminated"
+)
+
+// Instance health statuses
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// Instance classes
+const (
+	InstanceClassStandard = "standard"
+	InstanceClassSpot     = "spot"
+)
+
+// InstanceTemplate captures an instance shape that instances can be created from
+type InstanceTemplate struct {
+	ID        string            `json:"id" db:"id"`
+	Name      string            `json:"name" db:"name"`
+	CPU       int               `json:"cpu" db:"cpu"`
+	MemoryMB  int               `json:"memory_mb" db:"memory_mb"`
+	Image     string            `json:"image" db:"image"`
+	Labels    map[string]string `json:"labels,omitempty" db:"labels"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// AutoscalingGroup keeps a number of instances created from a template
+// running in a project. CurrentSize is the number of members at read time;
+// the autoscaler converges it toward DesiredSize in the background.
+type AutoscalingGroup struct {
+	ID          string    `json:"id" db:"id"`
+	ProjectID   string    `json:"project_id" db:"project_id"`
+	Name        string    `json:"name" db:"name"`
+	TemplateID  string    `json:"template_id" db:"template_id"`
+	MinSize     int       `json:"min_size" db:"min_size"`
+	MaxSize     int       `json:"max_size" db:"max_size"`
+	DesiredSize int       `json:"desired_size" db:"desired_size"`
+	CurrentSize int       `json:"current_size" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Environment groups resources of any kind, across projects, so that a test
+// scenario's resources can be summarized and torn down together. A resource
+// belongs to at most one environment. Resources deleted on their own stay
+// listed until removed from the environment.
+type Environment struct {
+	ID        string            `json:"id" db:"id"`
+	Name      string            `json:"name" db:"name"`
+	Labels    map[string]string `json:"labels,omitempty" db:"labels"`
+	Resources []ResourceRef     `json:"resources" db:"-"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// ResourceRef identifies a resource of any type, such as an instance
+type ResourceRef struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Alias maps an external ID, such as the ID a resource has on another cloud,
+// to a DirtCloud resource of any type. Aliases outlive the resources they
+// refer to; resolving one whose resource is gone reports it not found.
+type Alias struct {
+	ExternalID   string    `json:"external_id" db:"external_id"`
+	ResourceType string    `json:"resource_type" db:"resource_type"`
+	ResourceID   string    `json:"resource_id" db:"resource_id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// EnvironmentSummary counts an environment's resources by type. Missing
+// lists the resources that were deleted outside the environment.
+type EnvironmentSummary struct {
+	EnvironmentID string         `json:"environment_id"`
+	Total         int            `json:"total"`
+	Types         map[string]int `json:"types"`
+
+	// InstanceStatuses counts the environment's instances by status
+	InstanceStatuses map[string]int `json:"instance_statuses,omitempty"`
+
+	Missing []ResourceRef `json:"missing,omitempty"`
+}
+
+// Event records something that happened to a resource
+type Event struct {
+	ID           string    `json:"id" db:"id"`
+	Type         string    `json:"type" db:"type"`
+	ResourceType string    `json:"resource_type" db:"resource_type"`
+	ResourceID   string    `json:"resource_id" db:"resource_id"`
+	ProjectID    string    `json:"project_id,omitempty" db:"project_id"`
+	Message      string    `json:"message" db:"message"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+
+	// Actor is who caused the event: an IAM member, "admin" for the
+	// configured token, "anonymous", or a "system:" background worker
+	Actor string `json:"actor,omitempty" db:"actor"`
+
+	// Status change events record the transition and a machine-readable
+	// reason for it, one of the Reason constants
+	PreviousStatus string `json:"previous_status,omitempty" db:"previous_status"`
+	Status         string `json:"status,omitempty" db:"status"`
+	Reason         string `json:"reason,omitempty" db:"reason"`
+
+	// Sequence increases with each recorded event. Event streams can be
+	// resumed after the last sequence a client saw.
+	Sequence int64 `json:"sequence" db:"rowid"`
+}
+
+// Verb returns the action part of the event type, e.g. "resized" for
+// "instance.resized"
+func (e *Event) Verb() string {
+	if i := strings.LastIndex(e.Type, "."); i >= 0 {
+		return e.Type[i+1:]
+	}
+	return e.Type
+}
+
+// Event actors other than IAM members
+const (
+	ActorAdmin       = "admin"
+	ActorAnonymous   = "anonymous"
+	ActorReaper      = "system:reaper"
+	ActorAutoscaler  = "system:autoscaler"
+	ActorBilling     = "system:billing"
+	ActorRotator     = "system:rotator"
+	ActorProvisioner = "system:provisioner"
+	ActorPreemptor   = "system:preemptor"
+	ActorHealthCheck = "system:health_check"
+)
+
+// Event types
+const (
+	EventInstanceExpired       = "instance.expired"
+	EventInstanceResized       = "instance.resized"
+	EventInstanceStatusChanged = "instance.status_changed"
+
+	// Spot instances are warned of their preemption before it happens
+	EventInstancePreemptionWarning = "instance.preemption_warning"
+	EventInstancePreempted         = "instance.preempted"
+
+	// Repaired events name the unhealthy instance that was replaced
+	EventInstanceHealthChanged = "instance.health_changed"
+	EventInstanceRepaired      = "instance.repaired"
+
+	EventAutoscalingScaleOut    = "autoscaling_group.scale_out"
+	EventAutoscalingScaleIn     = "autoscaling_group.scale_in"
+	EventAutoscalingScaleFailed = "autoscaling_group.scale_failed"
+
+	EventBudgetThresholdCrossed = "budget.threshold_crossed"
+	EventBudgetExceeded         = "budget.exceeded"
+
+	EventSecretRotated = "secret.rotated"
+
+	EventDatabaseAvailable = "database.available"
+
+	// Zone events name the zone as their resource, with resource type
+	// "zone"
+	EventZoneOutageStarted = "zone.outage_started"
+	EventZoneOutageEnded   = "zone.outage_ended"
+
+	// Authentication events name the source address of the attempts as
+	// their resource, with resource type "source"
+	EventAuthFailed    = "auth.failed"
+	EventAuthLockedOut = "auth.locked_out"
+)
+
+// Reasons for status changes
+const (
+	ReasonUserRequest = "user_request"
+	ReasonChaos       = "chaos"
+	ReasonTTLExpired  = "ttl_expired"
+	ReasonAutoscaler  = "autoscaler"
+	ReasonPreempted   = "preempted"
+	ReasonAutoRepair  = "auto_repair"
+
+	ReasonZoneOutage   = "zone_outage"
+	ReasonZoneRecovery = "zone_recovery"
+)
+
+// IAM roles, from least to most privileged
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// RoleSecretAccessor grants access to the payloads of a project's secrets and
+// nothing else. It is outside the ranking of the other roles: admins may also
+// access payloads, but a secret accessor cannot view the project.
+const RoleSecretAccessor = "secret_accessor"
+
+// IAM member prefixes. A "token:" member is matched by the bearer token after
+// the prefix; a "serviceAccount:" member is matched by a bearer token equal to
+// the whole member string; a "user:" member is matched by a JWT with that
+// subject.
+const (
+	MemberPrefixToken          = "token:"
+	MemberPrefixServiceAccount = "serviceAccount:"
+	MemberPrefixUser           = "user:"
+)
+
+// IAMBinding grants a role to a set of members
+type IAMBinding struct {
+	Role    string   `json:"role"`
+	Members []string `json:"members"`
+}
+
+// IAMPolicy is the set of role bindings attached to a project
+type IAMPolicy struct {
+	ProjectID string       `json:"project_id"`
+	Bindings  []IAMBinding `json:"bindings"`
+	Etag      string       `json:"etag"`
+}
+
+// RequestLogEntry records an API request served by the server
+type RequestLogEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	Method    string    `json:"method" db:"method"`
+	Path      string    `json:"path" db:"path"`
+	Query     string    `json:"query,omitempty" db:"query"`
+	Status    int       `json:"status" db:"status"`
+	LatencyMS float64   `json:"latency_ms" db:"latency_ms"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// Actor is who sent the request, named as on events. Bearer tokens
+	// themselves are never logged.
+	Actor string `json:"actor" db:"actor"`
+
+	// ClientIP is the address the request came from, as forwarded by a
+	// trusted proxy when there is one
+	ClientIP string `json:"client_ip,omitempty" db:"client_ip"`
+
+	// Chaos lists the kinds of chaos applied to the request
+	Chaos []string `json:"chaos,omitempty" db:"chaos"`
+}
+
+// Kinds of activity timeline entries
+const (
+	ActivityEvent   = "event"
+	ActivityRequest = "request"
+)
+
+// ActivityEntry is one entry of a resource's activity timeline: an event
+// recorded for the resource or a logged API request addressed to it
+type ActivityEntry struct {
+	Kind    string    `json:"kind"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor,omitempty"`
+	Summary string    `json:"summary"`
+
+	// Exactly one of Event and Request is set, according to Kind
+	Event   *Event           `json:"event,omitempty"`
+	Request *RequestLogEntry `json:"request,omitempty"`
+}
+
+// MirroredRequest is an API request and its response as sent to a request
+// mirror. Credentials and secret values are redacted. Bodies are included
+// when they are JSON of at most 1 MiB; otherwise only their size is.
+type MirroredRequest struct {
+	Time           time.Time           `json:"time"`
+	Method         string              `json:"method"`
+	Path           string              `json:"path"`
+	Query          string              `json:"query,omitempty"`
+	ClientIP       string              `json:"client_ip,omitempty"`
+	RequestHeaders map[string][]string `json:"request_headers,omitempty"`
+	RequestBody    json.RawMessage     `json:"request_body,omitempty"`
+	RequestBytes   int                 `json:"request_bytes"`
+
+	// Status is 0 when chaos dropped the connection without a response
+	Status          int                 `json:"status"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	ResponseBody    json.RawMessage     `json:"response_body,omitempty"`
+	ResponseBytes   int                 `json:"response_bytes"`
+	LatencyMS       float64             `json:"latency_ms"`
+}
+
+// Backup describes a snapshot of the database
+type Backup struct {
+	Name      string    `json:"name"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Integrity checks, which name the check that found an IntegrityIssue
+const (
+	// IntegrityForeignKey finds rows whose foreign key refers to a missing row
+	IntegrityForeignKey = "foreign_key"
+	// IntegrityDanglingReference finds references the schema does not
+	// enforce, such as an instance's autoscaling group or an environment's
+	// resources, to resources that no longer exist
+	IntegrityDanglingReference = "dangling_reference"
+	// IntegrityMetadataPath finds metadata paths that are not normalized
+	IntegrityMetadataPath = "metadata_path"
+)
+
+// IntegrityReport lists the problems found in the stored data, usually left
+// behind by rows written to the database by hand
+type IntegrityReport struct {
+	Issues   []IntegrityIssue `json:"issues"`
+	Repaired int              `json:"repaired"`
+}
+
+// IntegrityIssue is one problem found by an integrity check. Repair says how
+// the issue is repaired, and is empty for issues that need a person to
+// decide.
+type IntegrityIssue struct {
+	Check        string `json:"check"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	Message      string `json:"message"`
+	Repair       string `json:"repair,omitempty"`
+	Repaired     bool   `json:"repaired"`
+}
+
+// Stored data validation modes, for data written before the validation rules
+// it breaks were introduced
+const (
+	DataValidationOff        = "off"
+	DataValidationWarn       = "warn"
+	DataValidationQuarantine = "quarantine"
+	DataValidationFix        = "fix"
+)
+
+// DataProblem is a stored resource field breaking a validation rule. Action
+// is what was done about it: "fixed", "quarantined", or nothing.
+type DataProblem struct {
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	Field        string `json:"field"`
+	Message      string `json:"message"`
+	Action       string `json:"action,omitempty"`
+}
+
+// QuarantinedResource is a resource taken out of service because it broke a
+// validation rule. Data is the resource as it was stored.
+type QuarantinedResource struct {
+	ResourceType  string          `json:"resource_type"`
+	ResourceID    string          `json:"resource_id"`
+	Data          json.RawMessage `json:"data"`
+	Problems      []string        `json:"problems"`
+	QuarantinedAt time.Time       `json:"quarantined_at"`
+}
+
+// Usage is the simulated usage a project's instances accrued in a calendar
+// month. Running instances accrue instance, vCPU and memory hours; every
+// instance accrues volume hours for its boot volume, running or not.
+type Usage struct {
+	ProjectID     string    `json:"project_id" db:"project_id"`
+	Period        string    `json:"period" db:"period"` // e.g. "2024-03"
+	InstanceHours float64   `json:"instance_hours" db:"instance_hours"`
+	VCPUHours     float64   `json:"vcpu_hours" db:"vcpu_hours"`
+	MemoryGBHours float64   `json:"memory_gb_hours" db:"memory_gb_hours"`
+	VolumeGBHours float64   `json:"volume_gb_hours" db:"volume_gb_hours"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UsagePeriodLayout formats the calendar month of a usage period
+const UsagePeriodLayout = "2006-01"
+
+// PriceSheet prices each unit of simulated usage
+type PriceSheet struct {
+	Currency     string  `json:"currency"`
+	InstanceHour float64 `json:"instance_hour"`
+	VCPUHour     float64 `json:"vcpu_hour"`
+	MemoryGBHour float64 `json:"memory_gb_hour"`
+	VolumeGBHour float64 `json:"volume_gb_hour"`
+}
+
+// UsageReport is a project's usage in a period with its cost under a price
+// sheet. For the current month, EstimatedMonthly adds the cost of running the
+// project's current instances until the month ends; for past months it is
+// the final cost.
+type UsageReport struct {
+	Usage
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Prices      PriceSheet `json:"prices"`
+
+	Cost             float64 `json:"cost"`
+	HourlyRate       float64 `json:"hourly_rate"`
+	EstimatedMonthly float64 `json:"estimated_monthly"`
+}
+
+// Budget tracks a project's monthly spend against an amount. Each threshold,
+// a fraction of Amount, records an event and notifies WebhookURL the first
+// time a month's spend reaches it; once spend reaches Amount the budget is
+// exceeded until the next month.
+type Budget struct {
+	ID         string    `json:"id" db:"id"`
+	ProjectID  string    `json:"project_id" db:"project_id"`
+	Name       string    `json:"name" db:"name"`
+	Amount     float64   `json:"amount" db:"amount"`
+	Thresholds []float64 `json:"thresholds" db:"thresholds"`
+	WebhookURL string    `json:"webhook_url,omitempty" db:"webhook_url"`
+
+	// Status, Spend and CrossedThresholds describe Period, the month the
+	// budget was last evaluated in
+	Status            string    `json:"status" db:"status"`
+	Spend             float64   `json:"spend" db:"spend"`
+	Period            string    `json:"period,omitempty" db:"period"`
+	CrossedThresholds []float64 `json:"crossed_thresholds,omitempty" db:"crossed_thresholds"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Budget statuses
+const (
+	BudgetOK       = "ok"
+	BudgetExceeded = "exceeded"
+)
+
+// BudgetState is the evaluated state of a budget
+type BudgetState struct {
+	Status            string
+	Spend             float64
+	Period            string
+	CrossedThresholds []float64
+}
+
+// BudgetAlert is the payload sent to a budget's webhook when its spend
+// crosses a threshold
+type BudgetAlert struct {
+	Type      string    `json:"type"`
+	Budget    *Budget   `json:"budget"`
+	Threshold float64   `json:"threshold"`
+	Spend     float64   `json:"spend"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Secret is a named, versioned secret of a project. Payloads are only
+// returned by accessing a version. A secret with a rotation period gets a new,
+// generated version every period, the first at NextRotationAt.
+type Secret struct {
+	ID                    string     `json:"id" db:"id"`
+	ProjectID             string     `json:"project_id" db:"project_id"`
+	Name                  string     `json:"name" db:"name"`
+	LatestVersion         int        `json:"latest_version" db:"latest_version"`
+	RotationPeriodSeconds int64      `json:"rotation_period_seconds,omitempty" db:"rotation_period_seconds"`
+	NextRotationAt        *time.Time `json:"next_rotation_at,omitempty" db:"next_rotation_at"`
+	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Database is a simulated managed database of a project. It is provisioned
+// asynchronously: a new or resized database becomes available at ReadyAt.
+// Its connection details and credentials are generated when it is created.
+type Database struct {
+	ID        string `json:"id" db:"id"`
+	ProjectID string `json:"project_id" db:"project_id"`
+	Name      string `json:"name" db:"name"`
+	Engine    string `json:"engine" db:"engine"`
+	Version   string `json:"version" db:"version"`
+	Size      string `json:"size" db:"size"`
+	Status    string `json:"status" db:"status"`
+
+	Host             string `json:"host" db:"host"`
+	Port             int    `json:"port" db:"port"`
+	DatabaseName     string `json:"database_name" db:"database_name"`
+	Username         string `json:"username" db:"username"`
+	Password         string `json:"password" db:"password"`
+	ConnectionString string `json:"connection_string" db:"connection_string"`
+
+	// ReadyAt is when a provisioning or updating database becomes available
+	ReadyAt *time.Time `json:"ready_at,omitempty" db:"ready_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Database engines
+const (
+	DatabaseEnginePostgres = "postgres"
+	DatabaseEngineMySQL    = "mysql"
 )
 
+// Database statuses
+const (
+	DatabaseProvisioning = "provisioning"
+	DatabaseUpdating     = "updating"
+	DatabaseAvailable    = "available"
+)
+
+// Topic is a pub/sub topic of a project. Messages published to a topic are
+// delivered to every subscription attached to it at the time.
+type Topic struct {
+	ID        string    `json:"id" db:"id"`
+	ProjectID string    `json:"project_id" db:"project_id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Subscription receives the messages published to a topic. A pulled message
+// is redelivered unless it is acknowledged within AckDeadlineSeconds.
+type Subscription struct {
+	ID                 string    `json:"id" db:"id"`
+	ProjectID          string    `json:"project_id" db:"project_id"`
+	TopicID            string    `json:"topic_id" db:"topic_id"`
+	Name               string    `json:"name" db:"name"`
+	AckDeadlineSeconds int       `json:"ack_deadline_seconds" db:"ack_deadline_seconds"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Message is a message published to a topic
+type Message struct {
+	ID          string            `json:"id" db:"id"`
+	Data        string            `json:"data,omitempty" db:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty" db:"attributes"`
+	PublishedAt time.Time         `json:"published_at" db:"published_at"`
+}
+
+// ReceivedMessage is a message pulled from a subscription. AckID
+// acknowledges this delivery of the message; it is invalidated by
+// redelivery.
+type ReceivedMessage struct {
+	AckID           string  `json:"ack_id"`
+	Message         Message `json:"message"`
+	DeliveryAttempt int     `json:"delivery_attempt"`
+}
+
+// Secret version states
+const (
+	SecretVersionEnabled   = "enabled"
+	SecretVersionDestroyed = "destroyed"
+)
+
+// SecretVersion describes one value of a secret. Versions are numbered from 1.
+type SecretVersion struct {
+	SecretID    string     `json:"secret_id" db:"secret_id"`
+	Version     int        `json:"version" db:"version"`
+	State       string     `json:"state" db:"state"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	DestroyedAt *time.Time `json:"destroyed_at,omitempty" db:"destroyed_at"`
+}
+
+// SecretPayload is the value of a secret version
+type SecretPayload struct {
+	SecretID string `json:"secret_id"`
+	Version  int    `json:"version"`
+	Payload  string `json:"payload"`
+}
+
+// Maintenance modes
+const (
+	// MaintenanceReadOnly rejects writes while reads are still served
+	MaintenanceReadOnly = "read_only"
+	// MaintenanceUnavailable rejects every request
+	MaintenanceUnavailable = "unavailable"
+)
+
+// Maintenance describes a simulated maintenance window
+type Maintenance struct {
+	Mode      string    `json:"mode"`
+	StartedAt time.Time `json:"started_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// CORSPolicy controls which browser origins may call the API. Fields left
+// out take their defaults; an empty AllowedOrigins allows no cross-origin
+// requests.
+type CORSPolicy struct {
+	// AllowedOrigins are origins such as "https://app.example.com",
+	// patterns such as "https://*.example.com", or "*" for any origin
+	AllowedOrigins []string `json:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods"`
+	AllowedHeaders []string `json:"allowed_headers"`
+	ExposedHeaders []string `json:"exposed_headers"`
+
+	// AllowCredentials lets browsers send cookies and Authorization
+	// headers. It requires origins to be listed rather than "*".
+	AllowCredentials bool `json:"allow_credentials"`
+
+	// MaxAgeSeconds is how long browsers may cache a preflight response;
+	// 0 leaves it to the browser
+	MaxAgeSeconds int `json:"max_age_seconds"`
+}
+
 // Metadata represents key-value metadata storage
 type Metadata struct {
 	ID        string    `json:"id" db:"id"`
@@ -38,16 +727,150 @@ type Metadata struct {
 	Value     string    `json:"value" db:"value"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Flags is an opaque number stored with the entry for clients' own use,
+	// like the flags of a Consul key
+	Flags uint64 `json:"flags,omitempty" db:"flags"`
+}
+
+// CreateIndex identifies the write that created the entry
+func (m *Metadata) CreateIndex() uint64 {
+	return uint64(m.CreatedAt.UnixMicro())
+}
+
+// ModifyIndex identifies the entry's latest write; compare-and-set writes
+// check it. Timestamps in microseconds stay exact as JSON numbers, and writes
+// are serialized far further apart than that.
+func (m *Metadata) ModifyIndex() uint64 {
+	return uint64(m.UpdatedAt.UnixMicro())
+}
+
+// TFStateLock is a lock on a Terraform state, taken through the HTTP backend.
+// Info is the lock info document Terraform sent, kept verbatim to hand back to
+// clients that find the state locked; ID is the lock ID from it.
+type TFStateLock struct {
+	ID   string
+	Info string
+}
+
+// MetadataWriteOptions makes a metadata write conditional
+type MetadataWriteOptions struct {
+	// CAS, when set, is the ModifyIndex the entry must have for the write
+	// to happen, or 0 for an entry that must not exist yet
+	CAS *uint64
 }
 
+// MetadataLimits caps the size of the metadata store. Total bytes count the
+// path and value of every entry. A zero limit is unlimited.
+type MetadataLimits struct {
+	MaxKeys       int `json:"max_keys,omitempty"`
+	MaxValueBytes int `json:"max_value_bytes,omitempty"`
+	MaxTotalBytes int `json:"max_total_bytes,omitempty"`
+}
+
+// MetadataUsage reports how much of the metadata store is in use
+type MetadataUsage struct {
+	Keys       int            `json:"keys"`
+	TotalBytes int            `json:"total_bytes"`
+	Limits     MetadataLimits `json:"limits"`
+}
+
+// MetadataImportResult counts what an import did to each of its entries
+type MetadataImportResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Metadata import and export formats
+const (
+	// MetadataFormatEtcd is the JSON etcdctl prints for a range read:
+	// {"kvs": [{"key": ..., "value": ...}], "count": N} with base64 keys
+	// and values
+	MetadataFormatEtcd = "etcd"
+
+	// MetadataFormatConsul is the output of consul kv export:
+	// [{"key": ..., "flags": 0, "value": ...}] with base64 values
+	MetadataFormatConsul = "consul"
+)
+
 // CreateProjectRequest represents the request to create a project
 type CreateProjectRequest struct {
-	Name string `json:"name"`
+	Name           string            `json:"name"`
+	OrganizationID string            `json:"organization_id,omitempty"`
+	FolderID       string            `json:"folder_id,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	ChaosProfile   string            `json:"chaos_profile,omitempty"`
+
+	DeletionProtection bool `json:"deletion_protection,omitempty"`
 }
 
-// UpdateProjectRequest represents the request to update a project
+// UpdateProjectRequest represents the request to update a project. A nil
+// ChaosProfile leaves the profile unchanged; an empty string clears it.
 type UpdateProjectRequest struct {
-	Name string `json:"name"`
+	Name         *string           `json:"name,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	ChaosProfile *string           `json:"chaos_profile,omitempty"`
+
+	DeletionProtection *bool `json:"deletion_protection,omitempty"`
+}
+
+// DeleteProjectOptions controls what happens to a project's child resources.
+// Without Cascade a project that still has instances or autoscaling groups
+// cannot be deleted.
+type DeleteProjectOptions struct {
+	Cascade bool
+}
+
+// MoveRequest represents the request to move a project or folder. Exactly one
+// of FolderID (move under a folder) or OrganizationID (move to the
+// organization root) must be set.
+type MoveRequest struct {
+	OrganizationID string `json:"organization_id,omitempty"`
+	FolderID       string `json:"folder_id,omitempty"`
+}
+
+// CreateOrganizationRequest represents the request to create an organization
+type CreateOrganizationRequest struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Quota  *Quota            `json:"quota,omitempty"`
+}
+
+// UpdateOrganizationRequest represents the request to update an organization
+type UpdateOrganizationRequest struct {
+	Name   *string           `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Quota  *Quota            `json:"quota,omitempty"`
+}
+
+// CreateFolderRequest represents the request to create a folder. ParentID
+// nests the folder under another folder of the same organization.
+type CreateFolderRequest struct {
+	OrganizationID string            `json:"organization_id"`
+	ParentID       string            `json:"parent_id,omitempty"`
+	Name           string            `json:"name"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Quota          *Quota            `json:"quota,omitempty"`
+}
+
+// UpdateFolderRequest represents the request to update a folder
+type UpdateFolderRequest struct {
+	Name   *string           `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Quota  *Quota            `json:"quota,omitempty"`
+}
+
+// OrganizationListOptions represents query options for listing organizations
+type OrganizationListOptions struct {
+	Name string
+}
+
+// FolderListOptions represents query options for listing folders
+type FolderListOptions struct {
+	OrganizationID string
+	ParentID       string
+	Name           string
 }
 
 // CreateInstanceRequest represents the request to create an instance
@@ -58,42 +881,660 @@ type CreateInstanceRequest struct {
 	MemoryMB  int    `json:"memory_mb"`
 	Image     string `json:"image"`
 	Status    string `json:"status,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// At most one of TTLSeconds (relative to creation) or ExpiresAt may be set
+	TTLSeconds int        `json:"ttl_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+
+	DeletionProtection bool `json:"deletion_protection,omitempty"`
+
+	// Zone places the instance in an availability zone; empty places it in
+	// the first zone that is up
+	Zone string `json:"zone,omitempty"`
+
+	// Class is standard, the default, or spot
+	Class string `json:"class,omitempty"`
+
+	AutoRepair bool `json:"auto_repair,omitempty"`
 }
 
-// UpdateInstanceRequest represents the request to update an instance
+// UpdateInstanceRequest represents the request to update an instance.
+// TTLSeconds resets the expiry relative to now, with 0 clearing it; ExpiresAt
+// sets an absolute expiry. At most one of the two may be set.
 type UpdateInstanceRequest struct {
 	Name     *string `json:"name,omitempty"`
 	CPU      *int    `json:"cpu,omitempty"`
 	MemoryMB *int    `json:"memory_mb,omitempty"`
 	Image    *string `json:"image,omitempty"`
 	Status   *string `json:"status,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+
+	TTLSeconds *int       `json:"ttl_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+
+	DeletionProtection *bool `json:"deletion_protection,omitempty"`
+	AutoRepair         *bool `json:"auto_repair,omitempty"`
+
+	// Zone and Class can only be set on creation; changing them fails with
+	// IMMUTABLE_FIELD
+	Zone  *string `json:"zone,omitempty"`
+	Class *string `json:"class,omitempty"`
 }
 
-// ProjectListOptions represents query options for listing projects
-type ProjectListOptions struct {
+// CreateInstanceTemplateRequest represents the request to create an instance template
+type CreateInstanceTemplateRequest struct {
+	Name     string            `json:"name"`
+	CPU      int               `json:"cpu"`
+	MemoryMB int               `json:"memory_mb"`
+	Image    string            `json:"image"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// UpdateInstanceTemplateRequest represents the request to update an instance template
+type UpdateInstanceTemplateRequest struct {
+	Name     *string           `json:"name,omitempty"`
+	CPU      *int              `json:"cpu,omitempty"`
+	MemoryMB *int              `json:"memory_mb,omitempty"`
+	Image    *string           `json:"image,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// InstanceTemplateListOptions represents query options for listing instance templates
+type InstanceTemplateListOptions struct {
 	Name string
 }
 
-// InstanceListOptions represents query options for listing instances
-type InstanceListOptions struct {
+// CreateInstanceFromTemplateRequest represents the request to create an
+// instance from a template. Labels are merged over the template's labels.
+type CreateInstanceFromTemplateRequest struct {
+	TemplateID string            `json:"template_id"`
+	ProjectID  string            `json:"project_id"`
+	Name       string            `json:"name"`
+	Status     string            `json:"status,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	TTLSeconds int               `json:"ttl_seconds,omitempty"`
+	Zone       string            `json:"zone,omitempty"`
+	Class      string            `json:"class,omitempty"`
+}
+
+// CloneInstanceRequest represents the request to clone an instance. The clone
+// lands in the source's project unless ProjectID is set.
+type CloneInstanceRequest struct {
+	Name      string `json:"name"`
+	ProjectID string `json:"project_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Zone      string `json:"zone,omitempty"`
+}
+
+// CreateAutoscalingGroupRequest represents the request to create an autoscaling
+// group. DesiredSize defaults to MinSize.
+type CreateAutoscalingGroupRequest struct {
+	ProjectID   string `json:"project_id"`
+	Name        string `json:"name"`
+	TemplateID  string `json:"template_id"`
+	MinSize     int    `json:"min_size"`
+	MaxSize     int    `json:"max_size"`
+	DesiredSize *int   `json:"desired_size,omitempty"`
+}
+
+// UpdateAutoscalingGroupRequest represents the request to update an autoscaling group
+type UpdateAutoscalingGroupRequest struct {
+	TemplateID  *string `json:"template_id,omitempty"`
+	MinSize     *int    `json:"min_size,omitempty"`
+	MaxSize     *int    `json:"max_size,omitempty"`
+	DesiredSize *int    `json:"desired_size,omitempty"`
+}
+
+// AutoscalingGroupListOptions represents query options for listing autoscaling groups
+type AutoscalingGroupListOptions struct {
 	ProjectID string
 	Name      string
+}
+
+// CreateEnvironmentRequest represents the request to create an environment,
+// optionally grouping existing resources from the start
+type CreateEnvironmentRequest struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Resources []ResourceRef     `json:"resources,omitempty"`
+}
+
+// UpdateEnvironmentRequest represents the request to update an environment
+type UpdateEnvironmentRequest struct {
+	Name   *string           `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AddEnvironmentResourcesRequest represents the request to add resources to
+// an environment. Resources already in it are left as they are.
+type AddEnvironmentResourcesRequest struct {
+	Resources []ResourceRef `json:"resources"`
+}
+
+// EnvironmentListOptions represents query options for listing environments
+type EnvironmentListOptions struct {
+	Name string
+}
+
+// DeleteEnvironmentOptions represents options for deleting an environment.
+// Without Cascade an environment that still groups resources cannot be
+// deleted; with it, its resources are deleted with it, all or none.
+type DeleteEnvironmentOptions struct {
+	Cascade bool
+}
+
+// CreateAliasRequest represents the request to attach an external ID to a
+// resource
+type CreateAliasRequest struct {
+	ExternalID   string `json:"external_id"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+}
+
+// AliasListOptions represents query options for listing aliases
+type AliasListOptions struct {
+	ResourceType string
+	ResourceID   string
+}
+
+// CreateBudgetRequest represents the request to create a budget. Thresholds
+// default to 0.5, 0.9 and 1.
+type CreateBudgetRequest struct {
+	ProjectID  string    `json:"project_id"`
+	Name       string    `json:"name"`
+	Amount     float64   `json:"amount"`
+	Thresholds []float64 `json:"thresholds,omitempty"`
+	WebhookURL string    `json:"webhook_url,omitempty"`
+}
+
+// UpdateBudgetRequest represents the request to update a budget
+type UpdateBudgetRequest struct {
+	Name       *string   `json:"name,omitempty"`
+	Amount     *float64  `json:"amount,omitempty"`
+	Thresholds []float64 `json:"thresholds,omitempty"`
+	WebhookURL *string   `json:"webhook_url,omitempty"`
+}
+
+// BudgetListOptions represents query options for listing budgets
+type BudgetListOptions struct {
+	ProjectID string
 	Status    string
 }
 
+// CreateDatabaseRequest represents the request to create a database. Version
+// defaults to the newest version of the engine and Size to "small".
+type CreateDatabaseRequest struct {
+	ProjectID string `json:"project_id"`
+	Name      string `json:"name"`
+	Engine    string `json:"engine"`
+	Version   string `json:"version,omitempty"`
+	Size      string `json:"size,omitempty"`
+}
+
+// UpdateDatabaseRequest represents the request to update a database. A new
+// size is applied asynchronously.
+type UpdateDatabaseRequest struct {
+	Name *string `json:"name,omitempty"`
+	Size *string `json:"size,omitempty"`
+}
+
+// DatabaseListOptions represents query options for listing databases
+type DatabaseListOptions struct {
+	ProjectID string
+	Engine    string
+	Status    string
+}
+
+// CreateTopicRequest represents the request to create a topic
+type CreateTopicRequest struct {
+	ProjectID string `json:"project_id"`
+	Name      string `json:"name"`
+}
+
+// TopicListOptions represents query options for listing topics
+type TopicListOptions struct {
+	ProjectID string
+	Name      string
+}
+
+// PublishRequest represents the request to publish messages to a topic. Only
+// the data and attributes of each message are used.
+type PublishRequest struct {
+	Messages []Message `json:"messages"`
+}
+
+// PublishResponse lists the IDs of published messages, in request order
+type PublishResponse struct {
+	MessageIDs []string `json:"message_ids"`
+}
+
+// CreateSubscriptionRequest represents the request to create a subscription.
+// AckDeadlineSeconds defaults to 10.
+type CreateSubscriptionRequest struct {
+	TopicID            string `json:"topic_id"`
+	Name               string `json:"name"`
+	AckDeadlineSeconds int    `json:"ack_deadline_seconds,omitempty"`
+}
+
+// UpdateSubscriptionRequest represents the request to update a subscription
+type UpdateSubscriptionRequest struct {
+	AckDeadlineSeconds *int `json:"ack_deadline_seconds,omitempty"`
+}
+
+// SubscriptionListOptions represents query options for listing subscriptions
+type SubscriptionListOptions struct {
+	ProjectID string
+	TopicID   string
+}
+
+// PullRequest represents the request to pull messages from a subscription.
+// MaxMessages defaults to 10.
+type PullRequest struct {
+	MaxMessages int `json:"max_messages,omitempty"`
+}
+
+// PullResponse lists the messages pulled from a subscription, oldest first
+type PullResponse struct {
+	ReceivedMessages []ReceivedMessage `json:"received_messages"`
+}
+
+// AcknowledgeRequest represents the request to acknowledge pulled messages
+type AcknowledgeRequest struct {
+	AckIDs []string `json:"ack_ids"`
+}
+
+// CreateSecretRequest represents the request to create a secret. A payload,
+// if given, becomes version 1.
+type CreateSecretRequest struct {
+	ProjectID             string  `json:"project_id"`
+	Name                  string  `json:"name"`
+	Payload               *string `json:"payload,omitempty"`
+	RotationPeriodSeconds int64   `json:"rotation_period_seconds,omitempty"`
+}
+
+// UpdateSecretRequest represents the request to update a secret. A rotation
+// period of 0 stops rotation.
+type UpdateSecretRequest struct {
+	Name                  *string `json:"name,omitempty"`
+	RotationPeriodSeconds *int64  `json:"rotation_period_seconds,omitempty"`
+}
+
+// AddSecretVersionRequest represents the request to add a secret version
+type AddSecretVersionRequest struct {
+	Payload string `json:"payload"`
+}
+
+// SecretListOptions represents query options for listing secrets
+type SecretListOptions struct {
+	ProjectID string
+	Name      string
+}
+
+// SearchOptions represents a search across resource types. Query is a name
+// pattern where * and ? are wildcards, or key=pattern to match a label.
+// Types limits the resource types searched; empty means all of them.
+type SearchOptions struct {
+	Query string
+	Types []string
+}
+
+// SearchResult is a resource matched by a search. Type discriminates the
+// kind of resource, which Resource holds in full.
+type SearchResult struct {
+	Type      string            `json:"type"`
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	ProjectID string            `json:"project_id,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Resource  interface{}       `json:"resource"`
+}
+
+// LookupOptions represents a lookup of exactly one resource of Type, as
+// terraform import and data sources need. Name matches exactly; Selector is a
+// comma-separated list of key=value labels the resource must all carry.
+// Project, an ID or name, narrows the lookup for types that live in projects.
+type LookupOptions struct {
+	Type     string
+	Project  string
+	Name     string
+	Selector string
+}
+
+// AssertRequest represents a set of expectations about the stored resources,
+// evaluated together
+type AssertRequest struct {
+	Assertions []Assertion `json:"assertions"`
+}
+
+// Assertion is an expectation about the resources of one type. Where selects
+// resources by field, with dotted names for nested fields such as
+// "labels.env". Count, MinCount and MaxCount check how many were selected;
+// Equals checks fields of every selected resource. With neither, at least one
+// resource must be selected.
+type Assertion struct {
+	// Name labels the assertion in the report
+	Name string `json:"name,omitempty"`
+
+	Type     string                 `json:"type"`
+	Where    map[string]interface{} `json:"where,omitempty"`
+	Count    *int                   `json:"count,omitempty"`
+	MinCount *int                   `json:"min_count,omitempty"`
+	MaxCount *int                   `json:"max_count,omitempty"`
+	Equals   map[string]interface{} `json:"equals,omitempty"`
+}
+
+// AssertResponse reports the outcome of each assertion of a request
+type AssertResponse struct {
+	Passed  bool              `json:"passed"`
+	Failed  int               `json:"failed"`
+	Results []AssertionResult `json:"results"`
+}
+
+// AssertionResult is the outcome of one assertion. Failures explain why it
+// did not pass.
+type AssertionResult struct {
+	Index    int      `json:"index"`
+	Name     string   `json:"name,omitempty"`
+	Passed   bool     `json:"passed"`
+	Matched  int      `json:"matched"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Seed is a set of resources to load into a server, ordered so that every
+// resource comes after the resources it refers to
+type Seed struct {
+	Resources []SeedResource `json:"resources"`
+}
+
+// SeedResource is one resource of a seed, in the form the API returns it
+type SeedResource struct {
+	Type     string          `json:"type"`
+	Resource json.RawMessage `json:"resource"`
+}
+
+// RequestLogListOptions represents query options for listing logged requests.
+// Path matches paths with that prefix.
+type RequestLogListOptions struct {
+	Method string
+	Path   string
+	Status int
+	Actor  string
+
+	// Chaos, when set, lists only requests chaos was applied to
+	Chaos bool
+
+	// Since and Until bound the request time when set; Since is inclusive
+	// and Until exclusive
+	Since time.Time
+	Until time.Time
+
+	// Limit caps the number of entries when positive
+	Limit int
+}
+
+// ActivityListOptions selects the activity timeline of one resource
+type ActivityListOptions struct {
+	ResourceType string
+	ResourceID   string
+
+	// RequestPath is the resource's API path, e.g. "/v1/instances/{id}".
+	// Requests to it and to its sub-resources and custom methods are
+	// included.
+	RequestPath string
+
+	// Since and Until bound the entry time when set; Since is inclusive and
+	// Until exclusive
+	Since time.Time
+	Until time.Time
+
+	// Limit keeps only the newest entries when positive
+	Limit int
+}
+
+// ResizeInstanceRequest represents the request to change an instance's shape.
+// At least one of CPU or MemoryMB must be set.
+type ResizeInstanceRequest struct {
+	CPU      *int `json:"cpu,omitempty"`
+	MemoryMB *int `json:"memory_mb,omitempty"`
+}
+
+// InstanceDiffRequest is the desired spec of an instance to compare with its
+// current state. Fields left out, and null labels, are not compared.
+type InstanceDiffRequest struct {
+	ProjectID *string           `json:"project_id,omitempty"`
+	Name      *string           `json:"name,omitempty"`
+	CPU       *int              `json:"cpu,omitempty"`
+	MemoryMB  *int              `json:"memory_mb,omitempty"`
+	Image     *string           `json:"image,omitempty"`
+	Status    *string           `json:"status,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	DeletionProtection *bool      `json:"deletion_protection,omitempty"`
+	AutoRepair         *bool      `json:"auto_repair,omitempty"`
+	Zone               *string    `json:"zone,omitempty"`
+	Class              *string    `json:"class,omitempty"`
+}
+
+// Field diff actions
+const (
+	DiffChanged = "changed"
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+)
+
+// FieldDiff is a difference in one field between the current and desired
+// state of a resource. Label keys are reported as fields such as
+// "labels.env".
+type FieldDiff struct {
+	Field   string      `json:"field"`
+	Action  string      `json:"action"`
+	Current interface{} `json:"current,omitempty"`
+	Desired interface{} `json:"desired,omitempty"`
+
+	// RequiresReplace is set when the field cannot be updated in place, so
+	// the resource has to be recreated to apply the change
+	RequiresReplace bool `json:"requires_replace,omitempty"`
+}
+
+// ResourceDiff lists the field differences between the current and desired
+// state of a resource, sorted by field
+type ResourceDiff struct {
+	ResourceType    string      `json:"resource_type"`
+	ResourceID      string      `json:"resource_id"`
+	Changes         []FieldDiff `json:"changes"`
+	RequiresReplace bool        `json:"requires_replace"`
+}
+
+// SortField represents a single sort key for list queries
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ProjectListOptions represents query options for listing projects
+type ProjectListOptions struct {
+	Name           string
+	OrganizationID string
+	FolderID       string
+
+	// Search matches projects whose name contains it, ignoring case
+	Search string
+
+	// Sort orders results by the given fields; Fields limits the returned fields
+	Sort   []SortField
+	Fields []string
+
+	// Limit caps the number of results when positive; Offset skips that
+	// many results first
+	Limit  int
+	Offset int
+
+	// PageToken, from a previous page, lists only the results after that
+	// page's last one. Unlike Offset, it stays on track when results are
+	// created or deleted between pages.
+	PageToken string
+}
+
+// InstanceListOptions represents query options for listing instances
+type InstanceListOptions struct {
+	ProjectID          string
+	Name               string
+	Status             string
+	AutoscalingGroupID string
+	Zone               string
+	Class              string
+
+	// Search matches instances whose name contains it, ignoring case
+	Search string
+
+	// Sort orders results by the given fields; Fields limits the returned fields
+	Sort   []SortField
+	Fields []string
+
+	// Limit caps the number of results when positive; Offset skips that
+	// many results first
+	Limit  int
+	Offset int
+
+	// PageToken, from a previous page, lists only the results after that
+	// page's last one. Unlike Offset, it stays on track when results are
+	// created or deleted between pages.
+	PageToken string
+}
+
+// EventListOptions represents query options for listing events
+type EventListOptions struct {
+	Type         string
+	ResourceType string
+	ResourceID   string
+	ProjectID    string
+	Actor        string
+	Reason       string
+
+	// Since and Until bound the event time when set; Since is inclusive and
+	// Until exclusive
+	Since time.Time
+	Until time.Time
+
+	// AfterSequence, when set, lists only events with a greater sequence
+	AfterSequence int64
+}
+
+// SetIAMPolicyRequest represents the request to replace a project's IAM policy.
+// When Etag is set it must match the current policy.
+type SetIAMPolicyRequest struct {
+	Bindings []IAMBinding `json:"bindings"`
+	Etag     string       `json:"etag,omitempty"`
+}
+
+// RestoreBackupRequest represents the request to restore a database snapshot
+type RestoreBackupRequest struct {
+	Name string `json:"name"`
+}
+
+// StartMaintenanceRequest represents the request to start a maintenance window
+type StartMaintenanceRequest struct {
+	Mode            string `json:"mode"`
+	DurationSeconds int    `json:"duration_seconds"`
+}
+
 // CreateMetadataRequest represents the request to create metadata
 type CreateMetadataRequest struct {
 	Path  string `json:"path"`
 	Value string `json:"value"`
+	Flags uint64 `json:"flags,omitempty"`
 }
 
 // UpdateMetadataRequest represents the request to update metadata
 type UpdateMetadataRequest struct {
 	Path  *string `json:"path,omitempty"`
 	Value *string `json:"value,omitempty"`
+	Flags *uint64 `json:"flags,omitempty"`
 }
 
 // MetadataListOptions represents query options for listing metadata
 type MetadataListOptions struct {
 	Prefix string
-}
\ No newline at end of file
+}
+
+// ValidationResult reports whether a create request would succeed, without
+// creating anything. Violations lists every field-level problem found; Error
+// is the error the create would have failed with. For a valid request,
+// Resource is the resource as it would be created, without the fields the
+// server assigns on creation, and Defaults holds the values the server
+// filled in for fields the request left out.
+type ValidationResult struct {
+	Valid      bool                   `json:"valid"`
+	Violations []FieldViolation       `json:"violations,omitempty"`
+	Error      *DirtError             `json:"error,omitempty"`
+	Resource   map[string]interface{} `json:"resource,omitempty"`
+	Defaults   map[string]interface{} `json:"defaults,omitempty"`
+}
+
+// CacheStats counts the reads of a cached repository. Invalidations counts
+// the writes that dropped cached reads.
+type CacheStats struct {
+	Repository    string
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+}
+
+// FederationPeer is a region of a federation and the server serving it.
+// Local is set on the region of the server listing the peers.
+type FederationPeer struct {
+	Region string `json:"region"`
+	URL    string `json:"url"`
+	Local  bool   `json:"local,omitempty"`
+}
+
+// FederatedResource records which region of a federation a resource was
+// created in. Servers register the resources they create with every peer,
+// so that any region can tell where a resource lives.
+type FederatedResource struct {
+	ID           string `json:"id"`
+	ResourceType string `json:"resource_type"`
+	Region       string `json:"region"`
+}
+
+// DefaultZones are the availability zones instances are placed in when no
+// zones are configured
+var DefaultZones = []string{"dirt-1a", "dirt-1b", "dirt-1c"}
+
+// Zone statuses
+const (
+	ZoneStatusUp   = "up"
+	ZoneStatusDown = "down"
+)
+
+// Zone is an availability zone. While a zone is down its instances are
+// unavailable and no instances can be created in it.
+type Zone struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+
+	// OutageStartedAt is when the zone went down, while it is down
+	OutageStartedAt *time.Time `json:"outage_started_at,omitempty"`
+
+	// Capacity is the zone's capacity pool, or nil when it is unlimited.
+	// Used counts what the instances in the zone take from it.
+	Capacity *ZoneCapacity `json:"capacity,omitempty"`
+	Used     ZoneCapacity  `json:"used"`
+}
+
+// ZoneCapacity is an amount of CPU and memory in a zone. As a capacity
+// pool, a field left at 0 is unlimited.
+type ZoneCapacity struct {
+	CPU      int `json:"cpu"`
+	MemoryMB int `json:"memory_mb"`
+}
+
+// ProbeFailures overrides the probability that the simulated health probes
+// of an instance fail, from 0 (never) to 1 (always)
+type ProbeFailures struct {
+	FailureRate float64 `json:"failure_rate"`
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Page tokens continue a listing after the last result of a previous page.
+// A token holds the values that result had for the fields the listing is
+// ordered by, so the next page starts right after it in that order: results
+// created or deleted between pages, including the result itself, never make
+// the next page skip or repeat others the way an offset would.
+
+// DefaultSortField orders listings that are not given a sort order
+const DefaultSortField = "name"
+
+// PageKey returns the fields a listing sorted by sort is ordered by, which
+// are the key fields of its page tokens. It always includes id, which breaks
+// ties.
+func PageKey(sort []SortField) []string {
+	if len(sort) == 0 {
+		sort = []SortField{{Field: DefaultSortField}}
+	}
+
+	var fields []string
+	hasID := false
+	for _, s := range sort {
+		fields = append(fields, s.Field)
+		hasID = hasID || s.Field == "id"
+	}
+	if !hasID {
+		fields = append(fields, "id")
+	}
+	return fields
+}
+
+// NewPageToken returns the token of the page following item, holding its
+// values for the given key fields, which are named as in its JSON
+func NewPageToken(item interface{}, fields []string) (string, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return "", err
+	}
+
+	key := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			key[field] = value
+		} else {
+			// Fields left out when empty
+			key[field] = json.RawMessage("null")
+		}
+	}
+	data, err = json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodePageToken decodes a page token into item, a pointer to the kind of
+// resource it was made from, and returns the key fields it holds. Malformed
+// tokens are reported as an invalid page_token.
+func DecodePageToken(token string, item interface{}) ([]string, error) {
+	invalid := func() error {
+		return ValidationError([]FieldViolation{{Field: "page_token", Message: "is not a valid page token"}})
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid()
+	}
+	var key map[string]json.RawMessage
+	if err := json.Unmarshal(data, &key); err != nil || len(key) == 0 {
+		return nil, invalid()
+	}
+	if err := json.Unmarshal(data, item); err != nil {
+		return nil, invalid()
+	}
+
+	fields := make([]string, 0, len(key))
+	for field := range key {
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
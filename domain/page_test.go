@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageKey(t *testing.T) {
+	assert.Equal(t, []string{"name", "id"}, PageKey(nil))
+	assert.Equal(t, []string{"created_at", "name", "id"}, PageKey([]SortField{{Field: "created_at", Desc: true}, {Field: "name"}}))
+	assert.Equal(t, []string{"id", "name"}, PageKey([]SortField{{Field: "id"}, {Field: "name"}}))
+}
+
+func TestPageToken_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 30, 0, 123, time.UTC)
+	token, err := NewPageToken(&Instance{ID: "i-1", Name: "web", CreatedAt: created, Image: "ubuntu"}, []string{"created_at", "expires_at", "id"})
+	require.NoError(t, err)
+
+	var last Instance
+	fields, err := DecodePageToken(token, &last)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"created_at", "expires_at", "id"}, fields)
+	assert.Equal(t, Instance{ID: "i-1", CreatedAt: created}, last, "only the key fields are kept")
+
+	for _, token := range []string{"", "!!", "bm90IGpzb24", "e30"} {
+		_, err := DecodePageToken(token, &last)
+		assert.True(t, IsInvalidInput(err), token)
+	}
+}
@@ -0,0 +1,110 @@
+package domain
+
+// Error reasons say why an error happened, more finely than its code, so
+// that clients can tell causes apart without parsing messages. Every error
+// has one; errors created with only a code get the code's default reason.
+const (
+	ReasonResourceNotFound       = "RESOURCE_NOT_FOUND"
+	ReasonAlreadyExists          = "ALREADY_EXISTS"
+	ReasonInvalidField           = "INVALID_FIELD"
+	ReasonMissingReference       = "MISSING_REFERENCE"
+	ReasonInternal               = "INTERNAL"
+	ReasonUnauthenticated        = "UNAUTHENTICATED"
+	ReasonRateLimited            = "RATE_LIMITED"
+	ReasonUnavailable            = "UNAVAILABLE"
+	ReasonQuotaExceeded          = "QUOTA_EXCEEDED"
+	ReasonInvalidState           = "INVALID_STATE"
+	ReasonResourceInUse          = "RESOURCE_IN_USE"
+	ReasonResourceNotReady       = "RESOURCE_NOT_READY"
+	ReasonStateLocked            = "STATE_LOCKED"
+	ReasonDeletionProtected      = "DELETION_PROTECTED"
+	ReasonConcurrentModification = "CONCURRENT_MODIFICATION"
+	ReasonPermissionDenied       = "PERMISSION_DENIED"
+	ReasonTimeout                = "TIMEOUT"
+	ReasonImmutableField         = "IMMUTABLE_FIELD"
+	ReasonAmbiguousMatch         = "AMBIGUOUS_MATCH"
+	ReasonWrongRegion            = "WRONG_REGION"
+	ReasonZoneUnavailable        = "ZONE_UNAVAILABLE"
+	ReasonZoneStockout           = "ZONE_STOCKOUT"
+)
+
+// HelpURLPrefix is where the help of each reason is served, followed by the
+// reason itself
+const HelpURLPrefix = "/v1/errors/"
+
+// ReasonInfo describes an error reason. Retryable reasons are worth retrying
+// as is after a backoff; others need the request or the resources it touches
+// to change first.
+type ReasonInfo struct {
+	Reason      string `json:"reason"`
+	Code        string `json:"code"`
+	Retryable   bool   `json:"retryable"`
+	Description string `json:"description"`
+}
+
+// reasons lists every error reason, ordered by code. The first reason of
+// each code is its default.
+var reasons = []ReasonInfo{
+	{ReasonAlreadyExists, ErrorCodeAlreadyExists, false, "A resource with the same unique field already exists."},
+	{ReasonAmbiguousMatch, ErrorCodeAmbiguousMatch, false, "A lookup that must find exactly one resource matched several; narrow it down."},
+	{ReasonTimeout, ErrorCodeDeadlineExceeded, true, "The request did not complete in time; it may or may not have taken effect."},
+	{ReasonInvalidState, ErrorCodeFailedPrecondition, false, "The resource is not in a state that allows the operation."},
+	{ReasonResourceInUse, ErrorCodeFailedPrecondition, false, "The resource is still used by or holds other resources."},
+	{ReasonResourceNotReady, ErrorCodeFailedPrecondition, true, "The resource is still changing state; the operation will be allowed once it settles."},
+	{ReasonStateLocked, ErrorCodeFailedPrecondition, true, "Another client holds the lock on the resource."},
+	{ReasonDeletionProtected, ErrorCodeFailedPrecondition, false, "The resource has deletion protection enabled."},
+	{ReasonConcurrentModification, ErrorCodeFailedPrecondition, false, "The resource changed since it was read; read it again and reapply the change."},
+	{ReasonMissingReference, ErrorCodeForeignKeyViolation, false, "The request references a resource that does not exist."},
+	{ReasonImmutableField, ErrorCodeImmutableField, false, "The field can only be set when the resource is created."},
+	{ReasonInternal, ErrorCodeInternalError, true, "The server failed unexpectedly."},
+	{ReasonInvalidField, ErrorCodeInvalidInput, false, "The request has missing or invalid fields."},
+	{ReasonResourceNotFound, ErrorCodeNotFound, false, "The resource does not exist."},
+	{ReasonPermissionDenied, ErrorCodePermissionDenied, false, "The caller lacks the role the operation requires."},
+	{ReasonQuotaExceeded, ErrorCodeQuotaExceeded, false, "The operation would exceed a quota."},
+	{ReasonZoneStockout, ErrorCodeResourceExhausted, true, "The zone has too little capacity left; retry later or in one of the suggested zones."},
+	{ReasonUnavailable, ErrorCodeServiceUnavailable, true, "The server is temporarily unable to handle the request."},
+	{ReasonZoneUnavailable, ErrorCodeServiceUnavailable, true, "The availability zone is down; retry once it recovers or use another zone."},
+	{ReasonRateLimited, ErrorCodeTooManyRequests, true, "Too many requests were sent; slow down, honoring Retry-After when given."},
+	{ReasonUnauthenticated, ErrorCodeUnauthorized, false, "The request lacks valid credentials."},
+	{ReasonWrongRegion, ErrorCodeWrongRegion, false, "The resource lives in another region of the federation; send the request to that region's server."},
+}
+
+// Reasons returns every error reason, ordered by code
+func Reasons() []ReasonInfo {
+	return append([]ReasonInfo(nil), reasons...)
+}
+
+// LookupReason returns the description of an error reason
+func LookupReason(reason string) (ReasonInfo, bool) {
+	for _, info := range reasons {
+		if info.Reason == reason {
+			return info, true
+		}
+	}
+	return ReasonInfo{}, false
+}
+
+// defaultReason returns the reason of errors with code that were not given
+// one, or ReasonInternal for unknown codes
+func defaultReason(code string) ReasonInfo {
+	for _, info := range reasons {
+		if info.Code == code {
+			return info
+		}
+	}
+	info, _ := LookupReason(ReasonInternal)
+	return info
+}
+
+// WithReason sets the reason of the error, along with whether it is
+// retryable and its help URL, and returns the error
+func (e *DirtError) WithReason(reason string) *DirtError {
+	info, ok := LookupReason(reason)
+	if !ok {
+		info = defaultReason(e.Code)
+	}
+	e.Reason = info.Reason
+	e.Retryable = info.Retryable
+	e.HelpURL = HelpURLPrefix + info.Reason
+	return e
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReasons(t *testing.T) {
+	// Every code has a default reason
+	codes := []string{
+		ErrorCodeNotFound, ErrorCodeAlreadyExists, ErrorCodeInvalidInput, ErrorCodeForeignKeyViolation,
+		ErrorCodeInternalError, ErrorCodeUnauthorized, ErrorCodeTooManyRequests, ErrorCodeServiceUnavailable,
+		ErrorCodeQuotaExceeded, ErrorCodeFailedPrecondition, ErrorCodePermissionDenied, ErrorCodeDeadlineExceeded,
+		ErrorCodeImmutableField, ErrorCodeAmbiguousMatch,
+	}
+	for _, code := range codes {
+		assert.Equal(t, code, defaultReason(code).Code, code)
+	}
+
+	err := ServiceUnavailableError("down")
+	assert.Equal(t, ReasonUnavailable, err.Reason)
+	assert.True(t, err.Retryable)
+	assert.Equal(t, "/v1/errors/UNAVAILABLE", err.HelpURL)
+
+	err = DeletionProtectedError("instance", "i-1")
+	assert.Equal(t, ErrorCodeFailedPrecondition, err.Code)
+	assert.Equal(t, ReasonDeletionProtected, err.Reason)
+	assert.False(t, err.Retryable)
+
+	err = TFStateLockedError("prod", &TFStateLock{ID: "l-1", Info: "{}"})
+	assert.Equal(t, ReasonStateLocked, err.Reason)
+	assert.True(t, err.Retryable)
+
+	// Unknown reasons fall back to the code's default
+	err = FailedPreconditionError("nope", nil).WithReason("NOPE")
+	assert.Equal(t, ReasonInvalidState, err.Reason)
+	assert.Equal(t, ReasonInternal, NewError("SOMETHING_ELSE", "x").Reason)
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// TracedStatement is a SQL statement run on behalf of a traced request.
+// Arguments are left out, as they can hold secret values.
+type TracedStatement struct {
+	SQL        string  `json:"sql"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// StatementTrace collects the SQL statements run on behalf of a request. It
+// is safe for concurrent use.
+type StatementTrace struct {
+	mu         sync.Mutex
+	statements []TracedStatement
+	total      time.Duration
+}
+
+// Add records a statement that took d to run and failed with err, if not nil
+func (t *StatementTrace) Add(sql string, d time.Duration, err error) {
+	s := TracedStatement{SQL: sql, DurationMS: float64(d.Microseconds()) / 1000}
+	if err != nil {
+		s.Error = err.Error()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.statements = append(t.statements, s)
+	t.total += d
+}
+
+// Statements returns the statements recorded so far, in the order they ran
+func (t *StatementTrace) Statements() []TracedStatement {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TracedStatement{}, t.statements...)
+}
+
+// Duration returns how long the recorded statements took in total
+func (t *StatementTrace) Duration() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// AdminService provides access to the admin API
+type AdminService struct {
+	client *Client
+}
+
+// CreateBackup writes a snapshot of the database
+func (s *AdminService) CreateBackup(ctx context.Context) (*domain.Backup, error) {
+	var backup domain.Backup
+	err := s.client.do(ctx, "POST", "/admin/backup", nil, &backup)
+	return &backup, err
+}
+
+// ListBackups lists the database snapshots, oldest first
+func (s *AdminService) ListBackups(ctx context.Context) ([]*domain.Backup, error) {
+	var backups []*domain.Backup
+	err := s.client.do(ctx, "GET", "/admin/backups", nil, &backups)
+	return backups, err
+}
+
+// RestoreBackup replaces the database contents with a snapshot
+func (s *AdminService) RestoreBackup(ctx context.Context, req domain.RestoreBackupRequest) error {
+	return s.client.do(ctx, "POST", "/admin/restore", req, nil)
+}
+
+// GetMaintenance returns the maintenance window in effect
+func (s *AdminService) GetMaintenance(ctx context.Context) (*domain.Maintenance, error) {
+	var window domain.Maintenance
+	err := s.client.do(ctx, "GET", "/admin/maintenance", nil, &window)
+	return &window, err
+}
+
+// StartMaintenance starts a maintenance window, replacing any in effect
+func (s *AdminService) StartMaintenance(ctx context.Context, req domain.StartMaintenanceRequest) (*domain.Maintenance, error) {
+	var window domain.Maintenance
+	err := s.client.do(ctx, "POST", "/admin/maintenance", req, &window)
+	return &window, err
+}
+
+// EndMaintenance ends the maintenance window in effect
+func (s *AdminService) EndMaintenance(ctx context.Context) error {
+	return s.client.do(ctx, "DELETE", "/admin/maintenance", nil, nil)
+}
+
+// ListRequests lists the API requests the server logged, newest first
+func (s *AdminService) ListRequests(ctx context.Context, opts domain.RequestLogListOptions) ([]*domain.RequestLogEntry, error) {
+	params := url.Values{}
+	if opts.Method != "" {
+		params.Set("method", opts.Method)
+	}
+	if opts.Path != "" {
+		params.Set("path", opts.Path)
+	}
+	if opts.Status != 0 {
+		params.Set("status", strconv.Itoa(opts.Status))
+	}
+	if opts.Actor != "" {
+		params.Set("actor", opts.Actor)
+	}
+	if opts.Chaos {
+		params.Set("chaos", "true")
+	}
+	if !opts.Since.IsZero() {
+		params.Set("since", opts.Since.Format(time.RFC3339Nano))
+	}
+	if !opts.Until.IsZero() {
+		params.Set("until", opts.Until.Format(time.RFC3339Nano))
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	var entries []*domain.RequestLogEntry
+	err := s.client.do(ctx, "GET", withQuery("/admin/requests", params), nil, &entries)
+	return entries, err
+}
+
+// Reset deletes every resource on the server
+func (s *AdminService) Reset(ctx context.Context) error {
+	return s.client.do(ctx, "POST", "/admin/reset", nil, nil)
+}
+
+// Assert evaluates expectations about the resources on the server. Failed
+// assertions are reported in the response, not as an error.
+func (s *AdminService) Assert(ctx context.Context, req domain.AssertRequest) (*domain.AssertResponse, error) {
+	var resp domain.AssertResponse
+	err := s.client.do(ctx, "POST", "/admin/assert", req, &resp)
+	return &resp, err
+}
+
+// CheckIntegrity reports problems in the stored data, such as rows referring
+// to missing resources, without changing anything
+func (s *AdminService) CheckIntegrity(ctx context.Context) (*domain.IntegrityReport, error) {
+	var report domain.IntegrityReport
+	err := s.client.do(ctx, "GET", "/admin/integrity", nil, &report)
+	return &report, err
+}
+
+// RepairIntegrity repairs the problems in the stored data that can be
+// repaired, and reports all of them
+func (s *AdminService) RepairIntegrity(ctx context.Context) (*domain.IntegrityReport, error) {
+	var report domain.IntegrityReport
+	err := s.client.do(ctx, "POST", "/admin/integrity:repair", nil, &report)
+	return &report, err
+}
+
+// ListQuarantined lists the resources the server took out of service at
+// startup for breaking validation rules
+func (s *AdminService) ListQuarantined(ctx context.Context) ([]*domain.QuarantinedResource, error) {
+	var quarantined []*domain.QuarantinedResource
+	err := s.client.do(ctx, "GET", "/admin/quarantine", nil, &quarantined)
+	return quarantined, err
+}
+
+// GenerateSeed builds a seed of the resources in their final state from
+// traffic recorded by the request mirror
+func (s *AdminService) GenerateSeed(ctx context.Context, traffic []*domain.MirroredRequest) (*domain.Seed, error) {
+	var body strings.Builder
+	for _, req := range traffic {
+		line, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal recorded request: %w", err)
+		}
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+
+	var seed domain.Seed
+	err := s.client.do(ctx, "POST", "/admin/seed:fromTraffic", body.String(), &seed)
+	return &seed, err
+}
+
+// LoadSeed creates the resources of a seed with their recorded IDs
+func (s *AdminService) LoadSeed(ctx context.Context, seed domain.Seed) error {
+	return s.client.do(ctx, "POST", "/admin/seed", seed, nil)
+}
+
+// GetCORS returns the CORS policy in effect
+func (s *AdminService) GetCORS(ctx context.Context) (*domain.CORSPolicy, error) {
+	var policy domain.CORSPolicy
+	err := s.client.do(ctx, "GET", "/admin/cors", nil, &policy)
+	return &policy, err
+}
+
+// SetCORS replaces the CORS policy in effect. Fields left nil take their
+// defaults.
+func (s *AdminService) SetCORS(ctx context.Context, policy domain.CORSPolicy) (*domain.CORSPolicy, error) {
+	var updated domain.CORSPolicy
+	err := s.client.do(ctx, "PUT", "/admin/cors", policy, &updated)
+	return &updated, err
+}
+
+// StartZoneOutage takes an availability zone down, making every instance in
+// it unavailable
+func (s *AdminService) StartZoneOutage(ctx context.Context, zone string) (*domain.Zone, error) {
+	var z domain.Zone
+	err := s.client.do(ctx, "POST", resourcePath("/admin/zones", zone)+"/outage", nil, &z)
+	return &z, err
+}
+
+// EndZoneOutage brings an availability zone and its instances back up
+func (s *AdminService) EndZoneOutage(ctx context.Context, zone string) (*domain.Zone, error) {
+	var z domain.Zone
+	err := s.client.do(ctx, "DELETE", resourcePath("/admin/zones", zone)+"/outage", nil, &z)
+	return &z, err
+}
+
+// SetZoneCapacity replaces the CPU and memory an availability zone has for
+// instances; fields of 0 are unlimited
+func (s *AdminService) SetZoneCapacity(ctx context.Context, zone string, capacity domain.ZoneCapacity) (*domain.Zone, error) {
+	var z domain.Zone
+	err := s.client.do(ctx, "PUT", resourcePath("/admin/zones", zone)+"/capacity", capacity, &z)
+	return &z, err
+}
+
+// ClearZoneCapacity leaves an availability zone with unlimited capacity
+func (s *AdminService) ClearZoneCapacity(ctx context.Context, zone string) (*domain.Zone, error) {
+	var z domain.Zone
+	err := s.client.do(ctx, "DELETE", resourcePath("/admin/zones", zone)+"/capacity", nil, &z)
+	return &z, err
+}
+
+// PreemptInstance preempts a running spot instance at once, stopping it
+func (s *AdminService) PreemptInstance(ctx context.Context, id string) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "POST", resourcePath("/admin/instances", id)+"/preempt", nil, &instance)
+	return &instance, err
+}
+
+// SetProbeFailures overrides how often the simulated health probes of an
+// instance fail
+func (s *AdminService) SetProbeFailures(ctx context.Context, id string, failures domain.ProbeFailures) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "PUT", resourcePath("/admin/instances", id)+"/probe-failures", failures, &instance)
+	return &instance, err
+}
+
+// ClearProbeFailures returns the health probes of an instance to the
+// server's failure rate
+func (s *AdminService) ClearProbeFailures(ctx context.Context, id string) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "DELETE", resourcePath("/admin/instances", id)+"/probe-failures", nil, &instance)
+	return &instance, err
+}
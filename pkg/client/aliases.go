@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// AliasesService provides access to the alias API
+type AliasesService struct {
+	client *Client
+}
+
+// Create attaches an external ID to a resource
+func (s *AliasesService) Create(ctx context.Context, req domain.CreateAliasRequest) (*domain.Alias, error) {
+	var alias domain.Alias
+	err := s.client.do(ctx, "POST", "/aliases", req, &alias)
+	return &alias, err
+}
+
+// List lists aliases with optional filtering
+func (s *AliasesService) List(ctx context.Context, opts domain.AliasListOptions) ([]*domain.Alias, error) {
+	params := url.Values{}
+	if opts.ResourceType != "" {
+		params.Set("resource_type", opts.ResourceType)
+	}
+	if opts.ResourceID != "" {
+		params.Set("resource_id", opts.ResourceID)
+	}
+
+	var aliases []*domain.Alias
+	err := s.client.do(ctx, "GET", withQuery("/aliases", params), nil, &aliases)
+	return aliases, err
+}
+
+// Resolve returns the resource an external ID refers to
+func (s *AliasesService) Resolve(ctx context.Context, externalID string) (*domain.Alias, error) {
+	var alias domain.Alias
+	err := s.client.do(ctx, "GET", withQuery("/resolve", url.Values{"external_id": {externalID}}), nil, &alias)
+	return &alias, err
+}
+
+// Delete detaches an external ID from its resource
+func (s *AliasesService) Delete(ctx context.Context, externalID string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/aliases", externalID), nil, nil)
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// BudgetsService provides access to the budget API
+type BudgetsService struct {
+	client *Client
+}
+
+// Create creates a new budget
+func (s *BudgetsService) Create(ctx context.Context, req domain.CreateBudgetRequest) (*domain.Budget, error) {
+	var budget domain.Budget
+	err := s.client.do(ctx, "POST", "/budgets", req, &budget)
+	return &budget, err
+}
+
+// Get retrieves a budget by ID
+func (s *BudgetsService) Get(ctx context.Context, id string) (*domain.Budget, error) {
+	var budget domain.Budget
+	err := s.client.do(ctx, "GET", resourcePath("/budgets", id), nil, &budget)
+	return &budget, err
+}
+
+// List lists budgets with optional filtering
+func (s *BudgetsService) List(ctx context.Context, opts domain.BudgetListOptions) ([]*domain.Budget, error) {
+	params := url.Values{}
+	if opts.ProjectID != "" {
+		params.Set("project_id", opts.ProjectID)
+	}
+	if opts.Status != "" {
+		params.Set("status", opts.Status)
+	}
+
+	var budgets []*domain.Budget
+	err := s.client.do(ctx, "GET", withQuery("/budgets", params), nil, &budgets)
+	return budgets, err
+}
+
+// Update updates an existing budget
+func (s *BudgetsService) Update(ctx context.Context, id string, req domain.UpdateBudgetRequest) (*domain.Budget, error) {
+	var budget domain.Budget
+	err := s.client.do(ctx, "PATCH", resourcePath("/budgets", id), req, &budget)
+	return &budget, err
+}
+
+// Delete deletes a budget
+func (s *BudgetsService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/budgets", id), nil, nil)
+}
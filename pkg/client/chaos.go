@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+)
+
+// ChaosService provides access to the chaos API
+type ChaosService struct {
+	client *Client
+}
+
+// Get reports whether server-wide chaos is enabled
+func (s *ChaosService) Get(ctx context.Context) (*chaos.Status, error) {
+	var status chaos.Status
+	err := s.client.do(ctx, "GET", "/chaos", nil, &status)
+	return &status, err
+}
+
+// SetEnabled turns server-wide chaos on or off
+func (s *ChaosService) SetEnabled(ctx context.Context, enabled bool) (*chaos.Status, error) {
+	var status chaos.Status
+	err := s.client.do(ctx, "PUT", "/chaos", chaos.Status{Enabled: enabled}, &status)
+	return &status, err
+}
+
+// ListProfiles lists the chaos profiles ordered by name
+func (s *ChaosService) ListProfiles(ctx context.Context) ([]*chaos.Profile, error) {
+	var profiles []*chaos.Profile
+	err := s.client.do(ctx, "GET", "/chaos/profiles", nil, &profiles)
+	return profiles, err
+}
+
+// GetProfile retrieves a chaos profile by name
+func (s *ChaosService) GetProfile(ctx context.Context, name string) (*chaos.Profile, error) {
+	var profile chaos.Profile
+	err := s.client.do(ctx, "GET", resourcePath("/chaos/profiles", name), nil, &profile)
+	return &profile, err
+}
+
+// PutProfile creates or replaces the chaos profile named by profile.Name
+func (s *ChaosService) PutProfile(ctx context.Context, profile chaos.Profile) (*chaos.Profile, error) {
+	var result chaos.Profile
+	err := s.client.do(ctx, "PUT", resourcePath("/chaos/profiles", profile.Name), profile, &result)
+	return &result, err
+}
+
+// DeleteProfile deletes a chaos profile
+func (s *ChaosService) DeleteProfile(ctx context.Context, name string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/chaos/profiles", name), nil, nil)
+}
+
+// ListTargets lists the chaos targets ordered by resource ID
+func (s *ChaosService) ListTargets(ctx context.Context) ([]*chaos.Target, error) {
+	var targets []*chaos.Target
+	err := s.client.do(ctx, "GET", "/chaos/targets", nil, &targets)
+	return targets, err
+}
+
+// GetTarget retrieves the chaos target for a resource
+func (s *ChaosService) GetTarget(ctx context.Context, resourceID string) (*chaos.Target, error) {
+	var target chaos.Target
+	err := s.client.do(ctx, "GET", resourcePath("/chaos/targets", resourceID), nil, &target)
+	return &target, err
+}
+
+// PutTarget creates or replaces the chaos target for target.ResourceID
+func (s *ChaosService) PutTarget(ctx context.Context, target chaos.Target) (*chaos.Target, error) {
+	var result chaos.Target
+	err := s.client.do(ctx, "PUT", resourcePath("/chaos/targets", target.ResourceID), target, &result)
+	return &result, err
+}
+
+// DeleteTarget deletes the chaos target for a resource
+func (s *ChaosService) DeleteTarget(ctx context.Context, resourceID string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/chaos/targets", resourceID), nil, nil)
+}
+
+// GetFailureBudget retrieves the failure budget in effect and its counts
+func (s *ChaosService) GetFailureBudget(ctx context.Context) (*chaos.FailureBudgetStatus, error) {
+	var status chaos.FailureBudgetStatus
+	err := s.client.do(ctx, "GET", "/chaos/failure-budget", nil, &status)
+	return &status, err
+}
+
+// SetFailureBudget replaces the failure budget, resetting its counts
+func (s *ChaosService) SetFailureBudget(ctx context.Context, budget chaos.FailureBudget) (*chaos.FailureBudgetStatus, error) {
+	var status chaos.FailureBudgetStatus
+	err := s.client.do(ctx, "PUT", "/chaos/failure-budget", budget, &status)
+	return &status, err
+}
+
+// DeleteFailureBudget turns the failure budget off
+func (s *ChaosService) DeleteFailureBudget(ctx context.Context) error {
+	return s.client.do(ctx, "DELETE", "/chaos/failure-budget", nil, nil)
+}
+
+// ListPresets lists the chaos presets shipped with the server
+func (s *ChaosService) ListPresets(ctx context.Context) ([]*chaos.Preset, error) {
+	var presets []*chaos.Preset
+	err := s.client.do(ctx, "GET", "/chaos/presets", nil, &presets)
+	return presets, err
+}
+
+// ActivatePreset switches a chaos preset on, on top of any other chaos
+func (s *ChaosService) ActivatePreset(ctx context.Context, name string) (*chaos.Preset, error) {
+	var preset chaos.Preset
+	err := s.client.do(ctx, "POST", resourcePath("/chaos/presets", name)+":activate", nil, &preset)
+	return &preset, err
+}
+
+// DeactivatePreset switches a chaos preset off
+func (s *ChaosService) DeactivatePreset(ctx context.Context, name string) (*chaos.Preset, error) {
+	var preset chaos.Preset
+	err := s.client.do(ctx, "POST", resourcePath("/chaos/presets", name)+":deactivate", nil, &preset)
+	return &preset, err
+}
@@ -1,3 +1,11 @@
+// Package client is a Go SDK for the DirtCloud API.
+//
+// Each API is exposed as a service on Client, for example
+// client.Projects.Create(ctx, req). Requests that fail with 429 or a 5xx
+// status, or that never reach the server, are retried with exponential
+// backoff, honoring Retry-After. API errors are returned as
+// *domain.DirtError, so callers can use errors.As or the domain.Is*
+// helpers on them.
 package client
 
 import (
@@ -6,6 +14,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -13,26 +22,62 @@ import (
 	"time"
 
 	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/signer"
 )
 
 // Client provides a Go SDK for the DirtCloud API
 type Client struct {
 	baseURL    string
 	token      string
+	adminToken string
 	httpClient *http.Client
-	
+
+	// Request signing credentials
+	accessKey string
+	secretKey string
+
 	// Retry configuration
 	retryMax              int
 	retryInitialBackoffMs int
+
+	// Services for each API
+	Projects          *ProjectsService
+	Organizations     *OrganizationsService
+	Folders           *FoldersService
+	Instances         *InstancesService
+	InstanceTemplates *InstanceTemplatesService
+	AutoscalingGroups *AutoscalingGroupsService
+	Budgets           *BudgetsService
+	Secrets           *SecretsService
+	Databases         *DatabasesService
+	Environments      *EnvironmentsService
+	Aliases           *AliasesService
+	Topics            *TopicsService
+	Subscriptions     *SubscriptionsService
+	Metadata          *MetadataService
+	Events            *EventsService
+	Chaos             *ChaosService
+	Admin             *AdminService
 }
 
 // Config holds client configuration
 type Config struct {
+	// BaseURL is the server's URL, or "unix:/path/to.sock" to connect over
+	// a Unix domain socket
 	BaseURL               string
 	Token                 string
 	HTTPClient            *http.Client
 	RetryMax              int
 	RetryInitialBackoffMs int
+
+	// AdminToken, when set, is sent instead of Token on admin and chaos
+	// requests
+	AdminToken string
+
+	// AccessKey and SecretKey, when set, sign requests instead of sending
+	// Token. Admin and chaos requests still use AdminToken if it is set.
+	AccessKey string
+	SecretKey string
 }
 
 // NewClient creates a new DirtCloud API client
@@ -40,53 +85,108 @@ func NewClient(config Config) *Client {
 	if config.BaseURL == "" {
 		config.BaseURL = "http://localhost:8080"
 	}
-	
+
+	var transport http.RoundTripper
+	if socket, ok := strings.CutPrefix(config.BaseURL, "unix:"); ok {
+		transport = unixTransport(socket)
+		config.BaseURL = "http://unix"
+	}
+
 	if config.HTTPClient == nil {
 		config.HTTPClient = &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: transport,
+			Timeout:   30 * time.Second,
 		}
 	}
-	
+
 	if config.RetryMax == 0 {
 		config.RetryMax = 3
 	}
-	
+
 	if config.RetryInitialBackoffMs == 0 {
 		config.RetryInitialBackoffMs = 1000
 	}
-	
-	return &Client{
+
+	c := &Client{
 		baseURL:               strings.TrimRight(config.BaseURL, "/"),
 		token:                 config.Token,
+		adminToken:            config.AdminToken,
 		httpClient:            config.HTTPClient,
+		accessKey:             config.AccessKey,
+		secretKey:             config.SecretKey,
 		retryMax:              config.RetryMax,
 		retryInitialBackoffMs: config.RetryInitialBackoffMs,
 	}
+
+	c.Projects = &ProjectsService{client: c}
+	c.Organizations = &OrganizationsService{client: c}
+	c.Folders = &FoldersService{client: c}
+	c.Instances = &InstancesService{client: c}
+	c.InstanceTemplates = &InstanceTemplatesService{client: c}
+	c.AutoscalingGroups = &AutoscalingGroupsService{client: c}
+	c.Budgets = &BudgetsService{client: c}
+	c.Secrets = &SecretsService{client: c}
+	c.Databases = &DatabasesService{client: c}
+	c.Environments = &EnvironmentsService{client: c}
+	c.Aliases = &AliasesService{client: c}
+	c.Topics = &TopicsService{client: c}
+	c.Subscriptions = &SubscriptionsService{client: c}
+	c.Metadata = &MetadataService{client: c}
+	c.Events = &EventsService{client: c}
+	c.Chaos = &ChaosService{client: c}
+	c.Admin = &AdminService{client: c}
+
+	return c
+}
+
+// unixTransport returns a transport that sends every request over the Unix
+// socket at path
+func unixTransport(path string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+	return transport
+}
+
+// HTTPError is returned for failed responses whose body is not a DirtCloud
+// error, such as those from a proxy in front of the server
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
 }
 
 // do performs an HTTP request with retry logic
 func (c *Client) do(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	var reqBody io.Reader
-	
+	var payload []byte
+	var contentType string
+
 	if body != nil {
 		if s, ok := body.(string); ok {
 			// Handle plain text body (for metadata)
-			reqBody = strings.NewReader(s)
+			payload = []byte(s)
+			contentType = "text/plain"
 		} else {
 			// Handle JSON body
 			jsonData, err := json.Marshal(body)
 			if err != nil {
 				return fmt.Errorf("failed to marshal request body: %w", err)
 			}
-			reqBody = bytes.NewBuffer(jsonData)
+			payload = jsonData
+			contentType = "application/json"
 		}
 	}
-	
+
 	url := c.baseURL + "/v1" + path
-	
+
 	var lastErr error
 	backoff := time.Duration(c.retryInitialBackoffMs) * time.Millisecond
-	
+
 	for attempt := 0; attempt <= c.retryMax; attempt++ {
 		if attempt > 0 {
 			select {
@@ -96,218 +196,163 @@ func (c *Client) do(ctx context.Context, method, path string, body interface{},
 			}
 			backoff *= 2 // Exponential backoff
 		}
-		
+
+		// Every attempt needs a fresh reader over the request body
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+
 		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 		if err != nil {
 			return fmt.Errorf("failed to create request: %w", err)
 		}
-		
-		// Set headers
-		if body != nil {
-			if _, ok := body.(string); ok {
-				req.Header.Set("Content-Type", "text/plain")
-			} else {
-				req.Header.Set("Content-Type", "application/json")
-			}
+
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
 		}
-		
-		if c.token != "" {
-			req.Header.Set("Authorization", "Bearer "+c.token)
+
+		if c.accessKey != "" && !c.usesAdminToken(path) {
+			signer.Sign(req, payload, c.accessKey, c.secretKey, time.Now())
+		} else if token := c.tokenFor(path); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
 		}
-		
+
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			lastErr = fmt.Errorf("request failed: %w", err)
 			continue
 		}
-		
-		defer resp.Body.Close()
-		
-		// Check if we should retry
-		if shouldRetry(resp.StatusCode) {
-			respBody, _ := io.ReadAll(resp.Body)
-			
-			// Handle Retry-After header for 429 responses
-			if resp.StatusCode == http.StatusTooManyRequests {
-				if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-					if seconds, err := strconv.Atoi(retryAfter); err == nil {
-						backoff = time.Duration(seconds) * time.Second
-					}
-				}
-			}
-			
-			lastErr = fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read response body: %w", err)
 			continue
 		}
-		
-		// Handle successful responses
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			if result != nil {
-				if s, ok := result.(*string); ok {
-					// Handle plain text response (for metadata)
-					body, err := io.ReadAll(resp.Body)
-					if err != nil {
-						return fmt.Errorf("failed to read response body: %w", err)
-					}
-					*s = string(body)
-				} else {
-					// Handle JSON response
-					if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-						return fmt.Errorf("failed to decode response: %w", err)
-					}
-				}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			err := decodeError(resp.StatusCode, respBody)
+			if !shouldRetry(resp.StatusCode, err) {
+				return err
 			}
-			return nil
-		}
-		
-		// Handle client/server errors (don't retry)
-		respBody, _ := io.ReadAll(resp.Body)
-		
-		// Try to decode as DirtError
-		var dirtErr domain.DirtError
-		if err := json.Unmarshal(respBody, &dirtErr); err == nil {
-			return &dirtErr
+			if wait, ok := retryAfter(resp.Header); ok {
+				backoff = wait
+			}
+			lastErr = err
+			continue
 		}
-		
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+
+		return decodeResult(respBody, result)
 	}
-	
-	return lastErr
-}
 
-// shouldRetry determines if a request should be retried based on status code
-func shouldRetry(statusCode int) bool {
-	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+	return lastErr
 }
 
-// Project operations
-
-// CreateProject creates a new project
-func (c *Client) CreateProject(ctx context.Context, req domain.CreateProjectRequest) (*domain.Project, error) {
-	var project domain.Project
-	err := c.do(ctx, "POST", "/projects", req, &project)
-	return &project, err
-}
+// decodeResult decodes a successful response body into result
+func decodeResult(body []byte, result interface{}) error {
+	if result == nil {
+		return nil
+	}
 
-// GetProject retrieves a project by ID
-func (c *Client) GetProject(ctx context.Context, id string) (*domain.Project, error) {
-	var project domain.Project
-	err := c.do(ctx, "GET", "/projects/"+url.PathEscape(id), nil, &project)
-	return &project, err
-}
+	// Handle plain text response (for metadata)
+	if s, ok := result.(*string); ok {
+		*s = string(body)
+		return nil
+	}
 
-// ListProjects lists projects with optional filtering
-func (c *Client) ListProjects(ctx context.Context, opts domain.ProjectListOptions) ([]*domain.Project, error) {
-	path := "/projects"
-	if opts.Name != "" {
-		path += "?name=" + url.QueryEscape(opts.Name)
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
-	
-	var projects []*domain.Project
-	err := c.do(ctx, "GET", path, nil, &projects)
-	return projects, err
+	return nil
 }
 
-// UpdateProject updates an existing project
-func (c *Client) UpdateProject(ctx context.Context, id string, req domain.UpdateProjectRequest) (*domain.Project, error) {
-	var project domain.Project
-	err := c.do(ctx, "PATCH", "/projects/"+url.PathEscape(id), req, &project)
-	return &project, err
-}
+// decodeError converts a failed response into a *domain.DirtError, falling
+// back to an *HTTPError when the body is not a DirtCloud error
+func decodeError(statusCode int, body []byte) error {
+	var dirtErr domain.DirtError
+	if err := json.Unmarshal(body, &dirtErr); err == nil && dirtErr.Code != "" {
+		return &dirtErr
+	}
 
-// DeleteProject deletes a project
-func (c *Client) DeleteProject(ctx context.Context, id string) error {
-	return c.do(ctx, "DELETE", "/projects/"+url.PathEscape(id), nil, nil)
+	return &HTTPError{StatusCode: statusCode, Body: string(body)}
 }
 
-// Instance operations
-
-// CreateInstance creates a new instance
-func (c *Client) CreateInstance(ctx context.Context, req domain.CreateInstanceRequest) (*domain.Instance, error) {
-	var instance domain.Instance
-	err := c.do(ctx, "POST", "/instances", req, &instance)
-	return &instance, err
+// tokenFor returns the bearer token to send on a request to path
+func (c *Client) tokenFor(path string) string {
+	if c.usesAdminToken(path) {
+		return c.adminToken
+	}
+	return c.token
 }
 
-// GetInstance retrieves an instance by ID
-func (c *Client) GetInstance(ctx context.Context, id string) (*domain.Instance, error) {
-	var instance domain.Instance
-	err := c.do(ctx, "GET", "/instances/"+url.PathEscape(id), nil, &instance)
-	return &instance, err
+// usesAdminToken reports whether a request to path is sent the admin token
+func (c *Client) usesAdminToken(path string) bool {
+	return c.adminToken != "" && (strings.HasPrefix(path, "/admin") || strings.HasPrefix(path, "/chaos"))
 }
 
-// ListInstances lists instances with optional filtering
-func (c *Client) ListInstances(ctx context.Context, opts domain.InstanceListOptions) ([]*domain.Instance, error) {
-	path := "/instances"
-	params := url.Values{}
-	
-	if opts.ProjectID != "" {
-		params.Set("project_id", opts.ProjectID)
-	}
-	if opts.Name != "" {
-		params.Set("name", opts.Name)
-	}
-	if opts.Status != "" {
-		params.Set("status", opts.Status)
+// shouldRetry determines if a failed request should be retried. Errors that
+// carry a reason say whether they are retryable; otherwise the status code
+// decides.
+func shouldRetry(statusCode int, err error) bool {
+	if dirtErr, ok := domain.AsDirtError(err); ok && dirtErr.Reason != "" {
+		return dirtErr.Retryable
 	}
-	
-	if len(params) > 0 {
-		path += "?" + params.Encode()
-	}
-	
-	var instances []*domain.Instance
-	err := c.do(ctx, "GET", path, nil, &instances)
-	return instances, err
-}
-
-// UpdateInstance updates an existing instance
-func (c *Client) UpdateInstance(ctx context.Context, id string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
-	var instance domain.Instance
-	err := c.do(ctx, "PATCH", "/instances/"+url.PathEscape(id), req, &instance)
-	return &instance, err
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
 }
 
-// DeleteInstance deletes an instance
-func (c *Client) DeleteInstance(ctx context.Context, id string) error {
-	return c.do(ctx, "DELETE", "/instances/"+url.PathEscape(id), nil, nil)
-}
+// retryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
 
-// Metadata operations
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
 
-// CreateMetadata creates new metadata
-func (c *Client) CreateMetadata(ctx context.Context, req domain.CreateMetadataRequest) (*domain.Metadata, error) {
-	var metadata domain.Metadata
-	err := c.do(ctx, "POST", "/metadata", req, &metadata)
-	return &metadata, err
-}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
 
-// GetMetadata retrieves metadata by ID
-func (c *Client) GetMetadata(ctx context.Context, id string) (*domain.Metadata, error) {
-	var metadata domain.Metadata
-	err := c.do(ctx, "GET", "/metadata/"+id, nil, &metadata)
-	return &metadata, err
+	return 0, false
 }
 
-// UpdateMetadata updates existing metadata
-func (c *Client) UpdateMetadata(ctx context.Context, id string, req domain.UpdateMetadataRequest) (*domain.Metadata, error) {
-	var metadata domain.Metadata
-	err := c.do(ctx, "PATCH", "/metadata/"+id, req, &metadata)
-	return &metadata, err
+// setListParams encodes sort and field selection options as query parameters
+func setListParams(params url.Values, sort []domain.SortField, fields []string) {
+	if len(sort) > 0 {
+		keys := make([]string, len(sort))
+		for i, s := range sort {
+			keys[i] = s.Field
+			if s.Desc {
+				keys[i] = "-" + s.Field
+			}
+		}
+		params.Set("sort", strings.Join(keys, ","))
+	}
+	if len(fields) > 0 {
+		params.Set("fields", strings.Join(fields, ","))
+	}
 }
 
-// ListMetadata lists metadata with optional prefix filtering
-func (c *Client) ListMetadata(ctx context.Context, opts domain.MetadataListOptions) ([]*domain.Metadata, error) {
-	path := "/metadata"
-	if opts.Prefix != "" {
-		path += "?prefix=" + url.QueryEscape(opts.Prefix)
+// withQuery appends encoded query parameters to a path
+func withQuery(path string, params url.Values) string {
+	if len(params) == 0 {
+		return path
 	}
-	
-	var metadata []*domain.Metadata
-	err := c.do(ctx, "GET", path, nil, &metadata)
-	return metadata, err
+	return path + "?" + params.Encode()
 }
 
-// DeleteMetadata deletes metadata by ID
-func (c *Client) DeleteMetadata(ctx context.Context, id string) error {
-	return c.do(ctx, "DELETE", "/metadata/"+id, nil, nil)
-}
\ No newline at end of file
+// resourcePath joins a collection path and an escaped resource ID
+func resourcePath(collection, id string) string {
+	return collection + "/" + url.PathEscape(id)
+}
@@ -0,0 +1,336 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/signer"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/hypertf/dirtcloud-server/testing/tfharness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFastClient creates a client that retries without meaningful backoff
+func newFastClient(baseURL, token string) *Client {
+	return NewClient(Config{BaseURL: baseURL, Token: token, RetryMax: 2, RetryInitialBackoffMs: 1})
+}
+
+func TestClient_Services(t *testing.T) {
+	h := tfharness.NewWithOptions(t, tfharness.Options{Token: "secret"})
+	c := newFastClient(h.URL, h.Token)
+	ctx := context.Background()
+
+	org, err := c.Organizations.Create(ctx, domain.CreateOrganizationRequest{Name: "acme"})
+	require.NoError(t, err)
+	folder, err := c.Folders.Create(ctx, domain.CreateFolderRequest{Name: "eng", OrganizationID: org.ID})
+	require.NoError(t, err)
+
+	project, err := c.Projects.Create(ctx, domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	project, err = c.Projects.Move(ctx, project.ID, domain.MoveRequest{FolderID: folder.ID})
+	require.NoError(t, err)
+	assert.Equal(t, folder.ID, project.FolderID)
+
+	ancestry, err := c.Projects.Ancestry(ctx, project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, project.ID, ancestry.Project.ID)
+	assert.NotEmpty(t, ancestry.Ancestors)
+
+	children, err := c.Folders.Children(ctx, folder.ID)
+	require.NoError(t, err)
+	require.Len(t, children.Projects, 1)
+	assert.Equal(t, project.ID, children.Projects[0].ID)
+
+	instance, err := c.Instances.Create(ctx, domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusStopped,
+	})
+	require.NoError(t, err)
+	cpu := 2
+	instance, err = c.Instances.Resize(ctx, instance.ID, domain.ResizeInstanceRequest{CPU: &cpu})
+	require.NoError(t, err)
+	assert.Equal(t, 2, instance.CPU)
+
+	cpu = 4
+	diff, err := c.Instances.Diff(ctx, instance.ID, domain.InstanceDiffRequest{CPU: &cpu, Name: &instance.Name})
+	require.NoError(t, err)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "cpu", diff.Changes[0].Field)
+	assert.False(t, diff.RequiresReplace)
+
+	instances, err := c.Instances.List(ctx, domain.InstanceListOptions{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+
+	byName, err := c.Instances.GetByName(ctx, project.Name, instance.Name)
+	require.NoError(t, err)
+	assert.Equal(t, instance.ID, byName.ID)
+
+	events, err := c.Events.List(ctx, domain.EventListOptions{ResourceID: instance.ID, Since: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.NotEmpty(t, events)
+
+	entry, err := c.Metadata.Create(ctx, domain.CreateMetadataRequest{Path: "app/config", Value: "v1"})
+	require.NoError(t, err)
+	entries, err := c.Metadata.List(ctx, domain.MetadataListOptions{Prefix: "app/"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, entry.ID, entries[0].ID)
+	usage, err := c.Metadata.Usage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.Keys)
+
+	_, err = c.Aliases.Create(ctx, domain.CreateAliasRequest{ExternalID: "ssm:/app/config", ResourceType: "metadata", ResourceID: entry.ID})
+	require.NoError(t, err)
+	alias, err := c.Aliases.Resolve(ctx, "ssm:/app/config")
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, alias.ResourceID)
+	require.NoError(t, c.Aliases.Delete(ctx, "ssm:/app/config"))
+	aliases, err := c.Aliases.List(ctx, domain.AliasListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, aliases)
+
+	env, err := c.Environments.Create(ctx, domain.CreateEnvironmentRequest{Name: "scenario"})
+	require.NoError(t, err)
+	env, err = c.Environments.AddResources(ctx, env.ID, domain.ResourceRef{Type: "metadata", ID: entry.ID})
+	require.NoError(t, err)
+	assert.Len(t, env.Resources, 1)
+	summary, err := c.Environments.Summary(ctx, env.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Types["metadata"])
+	require.NoError(t, c.Environments.Delete(ctx, env.ID, domain.DeleteEnvironmentOptions{Cascade: true}))
+	_, err = c.Metadata.Get(ctx, entry.ID)
+	assert.True(t, domain.IsNotFound(err), "got %v", err)
+
+	profile, err := c.Chaos.PutProfile(ctx, chaos.Profile{Name: "flaky", ErrorRate: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, "flaky", profile.Name)
+	status, err := c.Chaos.SetEnabled(ctx, false)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	require.NoError(t, c.Admin.Reset(ctx))
+	projects, err := c.Projects.List(ctx, domain.ProjectListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestClient_ErrorUnwrapping(t *testing.T) {
+	h := tfharness.New(t)
+	c := newFastClient(h.URL, "")
+
+	_, err := c.Projects.Get(context.Background(), "missing")
+	require.Error(t, err)
+
+	var dirtErr *domain.DirtError
+	require.True(t, errors.As(err, &dirtErr))
+	assert.Equal(t, domain.ErrorCodeNotFound, dirtErr.Code)
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestClient_NonDirtErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"unrelated": true}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	_, err := newFastClient(server.URL, "").Projects.Get(context.Background(), "p")
+
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+}
+
+func TestClient_RetriesReplayBody(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"name": "web"}`, string(body), "every attempt must send the full body")
+
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "p-1", "name": "web"}`))
+	}))
+	defer server.Close()
+
+	project, err := newFastClient(server.URL, "").Projects.Create(context.Background(), domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, "p-1", project.ID)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestClient_AdminToken(t *testing.T) {
+	tokens := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens[r.URL.Path] = r.Header.Get("Authorization")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{BaseURL: server.URL, Token: "api", AdminToken: "admin"})
+	ctx := context.Background()
+	_, err := c.Projects.Get(ctx, "p-1")
+	require.NoError(t, err)
+	_, err = c.Chaos.Get(ctx)
+	require.NoError(t, err)
+	require.NoError(t, c.Admin.Reset(ctx))
+
+	assert.Equal(t, map[string]string{
+		"/v1/projects/p-1": "Bearer api",
+		"/v1/chaos":        "Bearer admin",
+		"/v1/admin/reset":  "Bearer admin",
+	}, tokens)
+}
+
+func TestClient_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "dirt.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/projects/p-1", r.URL.Path)
+		w.Write([]byte(`{"id":"p-1"}`))
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	project, err := NewClient(Config{BaseURL: "unix:" + socket}).Projects.Get(context.Background(), "p-1")
+	require.NoError(t, err)
+	assert.Equal(t, "p-1", project.ID)
+}
+
+func TestClient_SignedRequests(t *testing.T) {
+	auth := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, _, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		auth[r.URL.Path] = scheme
+		if signer.IsSigned(r) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, signer.HashPayload(body), r.Header.Get(signer.ContentHashHeader))
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{BaseURL: server.URL, Token: "api", AdminToken: "admin", AccessKey: "AKID", SecretKey: "secret"})
+	ctx := context.Background()
+	_, err := c.Projects.Create(ctx, domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	require.NoError(t, c.Admin.Reset(ctx))
+
+	assert.Equal(t, map[string]string{
+		"/v1/projects":    signer.Scheme,
+		"/v1/admin/reset": "Bearer",
+	}, auth)
+}
+
+func TestClient_RetriesExhausted(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": "TOO_MANY_REQUESTS", "message": "slow down"}`))
+	}))
+	defer server.Close()
+
+	_, err := newFastClient(server.URL, "").Projects.List(context.Background(), domain.ProjectListOptions{})
+
+	var dirtErr *domain.DirtError
+	require.True(t, errors.As(err, &dirtErr), "the last response's error is returned")
+	assert.Equal(t, "TOO_MANY_REQUESTS", dirtErr.Code)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestClient_RetriesRetryableErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		attempts int32
+	}{
+		{"retryable reason", http.StatusConflict, `{"error": "FAILED_PRECONDITION", "reason": "STATE_LOCKED", "retryable": true}`, 3},
+		{"non-retryable reason", http.StatusInternalServerError, `{"error": "INTERNAL_ERROR", "reason": "INTERNAL", "retryable": false}`, 1},
+		{"no reason", http.StatusServiceUnavailable, `{"error": "SERVICE_UNAVAILABLE"}`, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := newFastClient(server.URL, "").Projects.List(context.Background(), domain.ProjectListOptions{})
+			require.Error(t, err)
+			assert.Equal(t, tt.attempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func TestClient_ContextCanceledDuringBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := newFastClient(server.URL, "").Projects.List(ctx, domain.ProjectListOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "missing", value: "", ok: false},
+		{name: "seconds", value: "3", expected: 3 * time.Second, ok: true},
+		{name: "past date", value: "Mon, 02 Jan 2006 15:04:05 GMT", expected: 0, ok: true},
+		{name: "negative", value: "-1", ok: false},
+		{name: "garbage", value: "soon", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+
+			wait, ok := retryAfter(header)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, wait)
+		})
+	}
+
+	header := http.Header{}
+	header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	wait, ok := retryAfter(header)
+	assert.True(t, ok)
+	assert.Greater(t, wait, 59*time.Minute)
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// DatabasesService provides access to the database API
+type DatabasesService struct {
+	client *Client
+}
+
+// Create creates a new database. It is returned provisioning, with its
+// connection details; Get reports when it is available.
+func (s *DatabasesService) Create(ctx context.Context, req domain.CreateDatabaseRequest) (*domain.Database, error) {
+	var database domain.Database
+	err := s.client.do(ctx, "POST", "/databases", req, &database)
+	return &database, err
+}
+
+// Get retrieves a database by ID
+func (s *DatabasesService) Get(ctx context.Context, id string) (*domain.Database, error) {
+	var database domain.Database
+	err := s.client.do(ctx, "GET", resourcePath("/databases", id), nil, &database)
+	return &database, err
+}
+
+// List lists databases with optional filtering
+func (s *DatabasesService) List(ctx context.Context, opts domain.DatabaseListOptions) ([]*domain.Database, error) {
+	params := url.Values{}
+	if opts.ProjectID != "" {
+		params.Set("project_id", opts.ProjectID)
+	}
+	if opts.Engine != "" {
+		params.Set("engine", opts.Engine)
+	}
+	if opts.Status != "" {
+		params.Set("status", opts.Status)
+	}
+
+	var databases []*domain.Database
+	err := s.client.do(ctx, "GET", withQuery("/databases", params), nil, &databases)
+	return databases, err
+}
+
+// Update updates an existing database
+func (s *DatabasesService) Update(ctx context.Context, id string, req domain.UpdateDatabaseRequest) (*domain.Database, error) {
+	var database domain.Database
+	err := s.client.do(ctx, "PATCH", resourcePath("/databases", id), req, &database)
+	return &database, err
+}
+
+// Delete deletes a database
+func (s *DatabasesService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/databases", id), nil, nil)
+}
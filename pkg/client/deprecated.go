@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Flat methods kept for callers written before the per-API services
+
+// CreateProject creates a new project.
+//
+// Deprecated: use Projects.Create.
+func (c *Client) CreateProject(ctx context.Context, req domain.CreateProjectRequest) (*domain.Project, error) {
+	return c.Projects.Create(ctx, req)
+}
+
+// GetProject retrieves a project by ID.
+//
+// Deprecated: use Projects.Get.
+func (c *Client) GetProject(ctx context.Context, id string) (*domain.Project, error) {
+	return c.Projects.Get(ctx, id)
+}
+
+// ListProjects lists projects with optional filtering.
+//
+// Deprecated: use Projects.List.
+func (c *Client) ListProjects(ctx context.Context, opts domain.ProjectListOptions) ([]*domain.Project, error) {
+	return c.Projects.List(ctx, opts)
+}
+
+// UpdateProject updates an existing project.
+//
+// Deprecated: use Projects.Update.
+func (c *Client) UpdateProject(ctx context.Context, id string, req domain.UpdateProjectRequest) (*domain.Project, error) {
+	return c.Projects.Update(ctx, id, req)
+}
+
+// DeleteProject deletes a project.
+//
+// Deprecated: use Projects.Delete.
+func (c *Client) DeleteProject(ctx context.Context, id string) error {
+	return c.Projects.Delete(ctx, id, domain.DeleteProjectOptions{})
+}
+
+// CreateInstance creates a new instance.
+//
+// Deprecated: use Instances.Create.
+func (c *Client) CreateInstance(ctx context.Context, req domain.CreateInstanceRequest) (*domain.Instance, error) {
+	return c.Instances.Create(ctx, req)
+}
+
+// GetInstance retrieves an instance by ID.
+//
+// Deprecated: use Instances.Get.
+func (c *Client) GetInstance(ctx context.Context, id string) (*domain.Instance, error) {
+	return c.Instances.Get(ctx, id)
+}
+
+// ListInstances lists instances with optional filtering.
+//
+// Deprecated: use Instances.List.
+func (c *Client) ListInstances(ctx context.Context, opts domain.InstanceListOptions) ([]*domain.Instance, error) {
+	return c.Instances.List(ctx, opts)
+}
+
+// UpdateInstance updates an existing instance.
+//
+// Deprecated: use Instances.Update.
+func (c *Client) UpdateInstance(ctx context.Context, id string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	return c.Instances.Update(ctx, id, req)
+}
+
+// DeleteInstance deletes an instance.
+//
+// Deprecated: use Instances.Delete.
+func (c *Client) DeleteInstance(ctx context.Context, id string) error {
+	return c.Instances.Delete(ctx, id)
+}
+
+// CreateMetadata creates new metadata.
+//
+// Deprecated: use Metadata.Create.
+func (c *Client) CreateMetadata(ctx context.Context, req domain.CreateMetadataRequest) (*domain.Metadata, error) {
+	return c.Metadata.Create(ctx, req)
+}
+
+// GetMetadata retrieves metadata by ID.
+//
+// Deprecated: use Metadata.Get.
+func (c *Client) GetMetadata(ctx context.Context, id string) (*domain.Metadata, error) {
+	return c.Metadata.Get(ctx, id)
+}
+
+// UpdateMetadata updates existing metadata.
+//
+// Deprecated: use Metadata.Update.
+func (c *Client) UpdateMetadata(ctx context.Context, id string, req domain.UpdateMetadataRequest) (*domain.Metadata, error) {
+	return c.Metadata.Update(ctx, id, req)
+}
+
+// ListMetadata lists metadata with optional prefix filtering.
+//
+// Deprecated: use Metadata.List.
+func (c *Client) ListMetadata(ctx context.Context, opts domain.MetadataListOptions) ([]*domain.Metadata, error) {
+	return c.Metadata.List(ctx, opts)
+}
+
+// DeleteMetadata deletes metadata by ID.
+//
+// Deprecated: use Metadata.Delete.
+func (c *Client) DeleteMetadata(ctx context.Context, id string) error {
+	return c.Metadata.Delete(ctx, id)
+}
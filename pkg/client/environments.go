@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// EnvironmentsService provides access to the environment API
+type EnvironmentsService struct {
+	client *Client
+}
+
+// Create creates a new environment
+func (s *EnvironmentsService) Create(ctx context.Context, req domain.CreateEnvironmentRequest) (*domain.Environment, error) {
+	var env domain.Environment
+	err := s.client.do(ctx, "POST", "/environments", req, &env)
+	return &env, err
+}
+
+// Get retrieves an environment by ID
+func (s *EnvironmentsService) Get(ctx context.Context, id string) (*domain.Environment, error) {
+	var env domain.Environment
+	err := s.client.do(ctx, "GET", resourcePath("/environments", id), nil, &env)
+	return &env, err
+}
+
+// List lists environments with optional filtering
+func (s *EnvironmentsService) List(ctx context.Context, opts domain.EnvironmentListOptions) ([]*domain.Environment, error) {
+	params := url.Values{}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+
+	var envs []*domain.Environment
+	err := s.client.do(ctx, "GET", withQuery("/environments", params), nil, &envs)
+	return envs, err
+}
+
+// Update updates an existing environment
+func (s *EnvironmentsService) Update(ctx context.Context, id string, req domain.UpdateEnvironmentRequest) (*domain.Environment, error) {
+	var env domain.Environment
+	err := s.client.do(ctx, "PATCH", resourcePath("/environments", id), req, &env)
+	return &env, err
+}
+
+// AddResources adds existing resources to an environment
+func (s *EnvironmentsService) AddResources(ctx context.Context, id string, resources ...domain.ResourceRef) (*domain.Environment, error) {
+	var env domain.Environment
+	req := domain.AddEnvironmentResourcesRequest{Resources: resources}
+	err := s.client.do(ctx, "POST", resourcePath("/environments", id)+"/resources", req, &env)
+	return &env, err
+}
+
+// RemoveResource takes a resource out of an environment without deleting it
+func (s *EnvironmentsService) RemoveResource(ctx context.Context, id string, ref domain.ResourceRef) error {
+	path := resourcePath(resourcePath(resourcePath("/environments", id)+"/resources", ref.Type), ref.ID)
+	return s.client.do(ctx, "DELETE", path, nil, nil)
+}
+
+// Summary counts an environment's resources
+func (s *EnvironmentsService) Summary(ctx context.Context, id string) (*domain.EnvironmentSummary, error) {
+	var summary domain.EnvironmentSummary
+	err := s.client.do(ctx, "GET", resourcePath("/environments", id)+"/summary", nil, &summary)
+	return &summary, err
+}
+
+// Delete deletes an environment, and with opts.Cascade its resources
+func (s *EnvironmentsService) Delete(ctx context.Context, id string, opts domain.DeleteEnvironmentOptions) error {
+	params := url.Values{}
+	if opts.Cascade {
+		params.Set("cascade", "true")
+	}
+
+	return s.client.do(ctx, "DELETE", withQuery(resourcePath("/environments", id), params), nil, nil)
+}
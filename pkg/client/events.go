@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// EventsService provides access to the event API
+type EventsService struct {
+	client *Client
+}
+
+// List lists events, newest first, with optional filtering
+func (s *EventsService) List(ctx context.Context, opts domain.EventListOptions) ([]*domain.Event, error) {
+	var events []*domain.Event
+	err := s.client.do(ctx, "GET", withQuery("/events", eventParams(opts)), nil, &events)
+	return events, err
+}
+
+// Activity lists the activity timeline of the resource id in collection,
+// e.g. "instances": its events and the logged API requests addressed to it,
+// oldest first. Limit keeps only the newest entries when positive.
+func (s *EventsService) Activity(ctx context.Context, collection, id string, limit int) ([]*domain.ActivityEntry, error) {
+	params := url.Values{}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var entries []*domain.ActivityEntry
+	err := s.client.do(ctx, "GET", withQuery(resourcePath("/"+collection, id)+"/activity", params), nil, &entries)
+	return entries, err
+}
+
+// eventParams encodes event list options as query parameters
+func eventParams(opts domain.EventListOptions) url.Values {
+	params := url.Values{}
+	if opts.Type != "" {
+		params.Set("type", opts.Type)
+	}
+	if opts.ResourceType != "" {
+		params.Set("resource_type", opts.ResourceType)
+	}
+	if opts.ResourceID != "" {
+		params.Set("resource_id", opts.ResourceID)
+	}
+	if opts.ProjectID != "" {
+		params.Set("project_id", opts.ProjectID)
+	}
+	if opts.Actor != "" {
+		params.Set("actor", opts.Actor)
+	}
+	if opts.Reason != "" {
+		params.Set("reason", opts.Reason)
+	}
+	if !opts.Since.IsZero() {
+		params.Set("since", opts.Since.Format(time.RFC3339Nano))
+	}
+	if !opts.Until.IsZero() {
+		params.Set("until", opts.Until.Format(time.RFC3339Nano))
+	}
+	return params
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// OrganizationsService provides access to the organization API
+type OrganizationsService struct {
+	client *Client
+}
+
+// Create creates a new organization
+func (s *OrganizationsService) Create(ctx context.Context, req domain.CreateOrganizationRequest) (*domain.Organization, error) {
+	var org domain.Organization
+	err := s.client.do(ctx, "POST", "/organizations", req, &org)
+	return &org, err
+}
+
+// Get retrieves an organization by ID
+func (s *OrganizationsService) Get(ctx context.Context, id string) (*domain.Organization, error) {
+	var org domain.Organization
+	err := s.client.do(ctx, "GET", resourcePath("/organizations", id), nil, &org)
+	return &org, err
+}
+
+// List lists organizations with optional filtering
+func (s *OrganizationsService) List(ctx context.Context, opts domain.OrganizationListOptions) ([]*domain.Organization, error) {
+	params := url.Values{}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+
+	var orgs []*domain.Organization
+	err := s.client.do(ctx, "GET", withQuery("/organizations", params), nil, &orgs)
+	return orgs, err
+}
+
+// Update updates an existing organization
+func (s *OrganizationsService) Update(ctx context.Context, id string, req domain.UpdateOrganizationRequest) (*domain.Organization, error) {
+	var org domain.Organization
+	err := s.client.do(ctx, "PATCH", resourcePath("/organizations", id), req, &org)
+	return &org, err
+}
+
+// Delete deletes an organization
+func (s *OrganizationsService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/organizations", id), nil, nil)
+}
+
+// Children lists the folders and projects directly under an organization
+func (s *OrganizationsService) Children(ctx context.Context, id string) (*domain.HierarchyChildren, error) {
+	var children domain.HierarchyChildren
+	err := s.client.do(ctx, "GET", resourcePath("/organizations", id)+"/children", nil, &children)
+	return &children, err
+}
+
+// FoldersService provides access to the folder API
+type FoldersService struct {
+	client *Client
+}
+
+// Create creates a new folder
+func (s *FoldersService) Create(ctx context.Context, req domain.CreateFolderRequest) (*domain.Folder, error) {
+	var folder domain.Folder
+	err := s.client.do(ctx, "POST", "/folders", req, &folder)
+	return &folder, err
+}
+
+// Get retrieves a folder by ID
+func (s *FoldersService) Get(ctx context.Context, id string) (*domain.Folder, error) {
+	var folder domain.Folder
+	err := s.client.do(ctx, "GET", resourcePath("/folders", id), nil, &folder)
+	return &folder, err
+}
+
+// List lists folders with optional filtering
+func (s *FoldersService) List(ctx context.Context, opts domain.FolderListOptions) ([]*domain.Folder, error) {
+	params := url.Values{}
+	if opts.OrganizationID != "" {
+		params.Set("organization_id", opts.OrganizationID)
+	}
+	if opts.ParentID != "" {
+		params.Set("parent_id", opts.ParentID)
+	}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+
+	var folders []*domain.Folder
+	err := s.client.do(ctx, "GET", withQuery("/folders", params), nil, &folders)
+	return folders, err
+}
+
+// Update updates an existing folder
+func (s *FoldersService) Update(ctx context.Context, id string, req domain.UpdateFolderRequest) (*domain.Folder, error) {
+	var folder domain.Folder
+	err := s.client.do(ctx, "PATCH", resourcePath("/folders", id), req, &folder)
+	return &folder, err
+}
+
+// Delete deletes a folder
+func (s *FoldersService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/folders", id), nil, nil)
+}
+
+// Move moves a folder under a new organization or folder
+func (s *FoldersService) Move(ctx context.Context, id string, req domain.MoveRequest) (*domain.Folder, error) {
+	var folder domain.Folder
+	err := s.client.do(ctx, "POST", resourcePath("/folders", id)+":move", req, &folder)
+	return &folder, err
+}
+
+// Children lists the folders and projects directly under a folder
+func (s *FoldersService) Children(ctx context.Context, id string) (*domain.HierarchyChildren, error) {
+	var children domain.HierarchyChildren
+	err := s.client.do(ctx, "GET", resourcePath("/folders", id)+"/children", nil, &children)
+	return &children, err
+}
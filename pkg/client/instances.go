@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// InstancesService provides access to the instance API
+type InstancesService struct {
+	client *Client
+}
+
+// Create creates a new instance
+func (s *InstancesService) Create(ctx context.Context, req domain.CreateInstanceRequest) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "POST", "/instances", req, &instance)
+	return &instance, err
+}
+
+// CreateFromTemplate creates a new instance from an instance template
+func (s *InstancesService) CreateFromTemplate(ctx context.Context, req domain.CreateInstanceFromTemplateRequest) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "POST", "/instances:fromTemplate", req, &instance)
+	return &instance, err
+}
+
+// Get retrieves an instance by ID
+func (s *InstancesService) Get(ctx context.Context, id string) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "GET", resourcePath("/instances", id), nil, &instance)
+	return &instance, err
+}
+
+// GetByName retrieves an instance by name within a project, which may itself
+// be given by ID or name
+func (s *InstancesService) GetByName(ctx context.Context, project, name string) (*domain.Instance, error) {
+	params := url.Values{}
+	params.Set("project", project)
+
+	var instance domain.Instance
+	err := s.client.do(ctx, "GET", withQuery(resourcePath("/instances", name), params), nil, &instance)
+	return &instance, err
+}
+
+// List lists instances with optional filtering
+func (s *InstancesService) List(ctx context.Context, opts domain.InstanceListOptions) ([]*domain.Instance, error) {
+	params := url.Values{}
+	if opts.ProjectID != "" {
+		params.Set("project_id", opts.ProjectID)
+	}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+	if opts.Status != "" {
+		params.Set("status", opts.Status)
+	}
+	if opts.AutoscalingGroupID != "" {
+		params.Set("autoscaling_group_id", opts.AutoscalingGroupID)
+	}
+	if opts.Zone != "" {
+		params.Set("zone", opts.Zone)
+	}
+	if opts.Class != "" {
+		params.Set("class", opts.Class)
+	}
+	setListParams(params, opts.Sort, opts.Fields)
+
+	var instances []*domain.Instance
+	err := s.client.do(ctx, "GET", withQuery("/instances", params), nil, &instances)
+	return instances, err
+}
+
+// Update updates an existing instance
+func (s *InstancesService) Update(ctx context.Context, id string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "PATCH", resourcePath("/instances", id), req, &instance)
+	return &instance, err
+}
+
+// Delete deletes an instance
+func (s *InstancesService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/instances", id), nil, nil)
+}
+
+// Resize changes the CPU and memory of an instance
+func (s *InstancesService) Resize(ctx context.Context, id string, req domain.ResizeInstanceRequest) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "POST", resourcePath("/instances", id)+"/resize", req, &instance)
+	return &instance, err
+}
+
+// ListEvents lists the events of an instance, including those of its status
+// changes and why they happened. The resource and project filters of opts
+// are ignored.
+func (s *InstancesService) ListEvents(ctx context.Context, id string, opts domain.EventListOptions) ([]*domain.Event, error) {
+	opts.ResourceType, opts.ResourceID, opts.ProjectID = "", "", ""
+	var events []*domain.Event
+	err := s.client.do(ctx, "GET", withQuery(resourcePath("/instances", id)+"/events", eventParams(opts)), nil, &events)
+	return events, err
+}
+
+// Clone creates a copy of an instance
+func (s *InstancesService) Clone(ctx context.Context, id string, req domain.CloneInstanceRequest) (*domain.Instance, error) {
+	var instance domain.Instance
+	err := s.client.do(ctx, "POST", resourcePath("/instances", id)+"/clone", req, &instance)
+	return &instance, err
+}
+
+// InstanceTemplatesService provides access to the instance template API
+type InstanceTemplatesService struct {
+	client *Client
+}
+
+// Create creates a new instance template
+func (s *InstanceTemplatesService) Create(ctx context.Context, req domain.CreateInstanceTemplateRequest) (*domain.InstanceTemplate, error) {
+	var tmpl domain.InstanceTemplate
+	err := s.client.do(ctx, "POST", "/instance-templates", req, &tmpl)
+	return &tmpl, err
+}
+
+// Get retrieves an instance template by ID
+func (s *InstanceTemplatesService) Get(ctx context.Context, id string) (*domain.InstanceTemplate, error) {
+	var tmpl domain.InstanceTemplate
+	err := s.client.do(ctx, "GET", resourcePath("/instance-templates", id), nil, &tmpl)
+	return &tmpl, err
+}
+
+// List lists instance templates with optional filtering
+func (s *InstanceTemplatesService) List(ctx context.Context, opts domain.InstanceTemplateListOptions) ([]*domain.InstanceTemplate, error) {
+	params := url.Values{}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+
+	var templates []*domain.InstanceTemplate
+	err := s.client.do(ctx, "GET", withQuery("/instance-templates", params), nil, &templates)
+	return templates, err
+}
+
+// Update updates an existing instance template
+func (s *InstanceTemplatesService) Update(ctx context.Context, id string, req domain.UpdateInstanceTemplateRequest) (*domain.InstanceTemplate, error) {
+	var tmpl domain.InstanceTemplate
+	err := s.client.do(ctx, "PATCH", resourcePath("/instance-templates", id), req, &tmpl)
+	return &tmpl, err
+}
+
+// Delete deletes an instance template
+func (s *InstanceTemplatesService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/instance-templates", id), nil, nil)
+}
+
+// AutoscalingGroupsService provides access to the autoscaling group API
+type AutoscalingGroupsService struct {
+	client *Client
+}
+
+// Create creates a new autoscaling group
+func (s *AutoscalingGroupsService) Create(ctx context.Context, req domain.CreateAutoscalingGroupRequest) (*domain.AutoscalingGroup, error) {
+	var group domain.AutoscalingGroup
+	err := s.client.do(ctx, "POST", "/autoscaling-groups", req, &group)
+	return &group, err
+}
+
+// Get retrieves an autoscaling group by ID
+func (s *AutoscalingGroupsService) Get(ctx context.Context, id string) (*domain.AutoscalingGroup, error) {
+	var group domain.AutoscalingGroup
+	err := s.client.do(ctx, "GET", resourcePath("/autoscaling-groups", id), nil, &group)
+	return &group, err
+}
+
+// List lists autoscaling groups with optional filtering
+func (s *AutoscalingGroupsService) List(ctx context.Context, opts domain.AutoscalingGroupListOptions) ([]*domain.AutoscalingGroup, error) {
+	params := url.Values{}
+	if opts.ProjectID != "" {
+		params.Set("project_id", opts.ProjectID)
+	}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+
+	var groups []*domain.AutoscalingGroup
+	err := s.client.do(ctx, "GET", withQuery("/autoscaling-groups", params), nil, &groups)
+	return groups, err
+}
+
+// Update updates an existing autoscaling group
+func (s *AutoscalingGroupsService) Update(ctx context.Context, id string, req domain.UpdateAutoscalingGroupRequest) (*domain.AutoscalingGroup, error) {
+	var group domain.AutoscalingGroup
+	err := s.client.do(ctx, "PATCH", resourcePath("/autoscaling-groups", id), req, &group)
+	return &group, err
+}
+
+// Delete deletes an autoscaling group
+func (s *AutoscalingGroupsService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/autoscaling-groups", id), nil, nil)
+}
+
+// Diff compares a desired instance spec with the instance's current state
+func (s *InstancesService) Diff(ctx context.Context, id string, req domain.InstanceDiffRequest) (*domain.ResourceDiff, error) {
+	var diff domain.ResourceDiff
+	err := s.client.do(ctx, "POST", resourcePath("/instances", id)+":diff", req, &diff)
+	return &diff, err
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// MetadataService provides access to the metadata API
+type MetadataService struct {
+	client *Client
+}
+
+// Create creates new metadata
+func (s *MetadataService) Create(ctx context.Context, req domain.CreateMetadataRequest) (*domain.Metadata, error) {
+	var metadata domain.Metadata
+	err := s.client.do(ctx, "POST", "/metadata", req, &metadata)
+	return &metadata, err
+}
+
+// Get retrieves metadata by ID
+func (s *MetadataService) Get(ctx context.Context, id string) (*domain.Metadata, error) {
+	var metadata domain.Metadata
+	err := s.client.do(ctx, "GET", resourcePath("/metadata", id), nil, &metadata)
+	return &metadata, err
+}
+
+// List lists metadata with optional prefix filtering
+func (s *MetadataService) List(ctx context.Context, opts domain.MetadataListOptions) ([]*domain.Metadata, error) {
+	params := url.Values{}
+	if opts.Prefix != "" {
+		params.Set("prefix", opts.Prefix)
+	}
+
+	var metadata []*domain.Metadata
+	err := s.client.do(ctx, "GET", withQuery("/metadata", params), nil, &metadata)
+	return metadata, err
+}
+
+// Usage reports the size of the metadata store and its limits
+func (s *MetadataService) Usage(ctx context.Context) (*domain.MetadataUsage, error) {
+	var usage domain.MetadataUsage
+	err := s.client.do(ctx, "GET", "/metadata:usage", nil, &usage)
+	return &usage, err
+}
+
+// Export exports the metadata under prefix, or all of it, in the etcd or
+// Consul KV format named by domain.MetadataFormatEtcd or
+// domain.MetadataFormatConsul
+func (s *MetadataService) Export(ctx context.Context, format, prefix string) (json.RawMessage, error) {
+	params := url.Values{}
+	params.Set("format", format)
+	if prefix != "" {
+		params.Set("prefix", prefix)
+	}
+
+	var export json.RawMessage
+	err := s.client.do(ctx, "GET", withQuery("/metadata:export", params), nil, &export)
+	return export, err
+}
+
+// Import writes every key of an etcd or Consul KV export into the metadata
+// store, overwriting existing paths
+func (s *MetadataService) Import(ctx context.Context, format string, export json.RawMessage) (*domain.MetadataImportResult, error) {
+	params := url.Values{}
+	params.Set("format", format)
+
+	var result domain.MetadataImportResult
+	err := s.client.do(ctx, "POST", withQuery("/metadata:import", params), export, &result)
+	return &result, err
+}
+
+// Update updates existing metadata
+func (s *MetadataService) Update(ctx context.Context, id string, req domain.UpdateMetadataRequest) (*domain.Metadata, error) {
+	var metadata domain.Metadata
+	err := s.client.do(ctx, "PATCH", resourcePath("/metadata", id), req, &metadata)
+	return &metadata, err
+}
+
+// Delete deletes metadata by ID
+func (s *MetadataService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/metadata", id), nil, nil)
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// ProjectsService provides access to the project API
+type ProjectsService struct {
+	client *Client
+}
+
+// Create creates a new project
+func (s *ProjectsService) Create(ctx context.Context, req domain.CreateProjectRequest) (*domain.Project, error) {
+	var project domain.Project
+	err := s.client.do(ctx, "POST", "/projects", req, &project)
+	return &project, err
+}
+
+// Get retrieves a project by ID or name
+func (s *ProjectsService) Get(ctx context.Context, id string) (*domain.Project, error) {
+	var project domain.Project
+	err := s.client.do(ctx, "GET", resourcePath("/projects", id), nil, &project)
+	return &project, err
+}
+
+// List lists projects with optional filtering
+func (s *ProjectsService) List(ctx context.Context, opts domain.ProjectListOptions) ([]*domain.Project, error) {
+	params := url.Values{}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+	setListParams(params, opts.Sort, opts.Fields)
+
+	var projects []*domain.Project
+	err := s.client.do(ctx, "GET", withQuery("/projects", params), nil, &projects)
+	return projects, err
+}
+
+// Update updates an existing project
+func (s *ProjectsService) Update(ctx context.Context, id string, req domain.UpdateProjectRequest) (*domain.Project, error) {
+	var project domain.Project
+	err := s.client.do(ctx, "PATCH", resourcePath("/projects", id), req, &project)
+	return &project, err
+}
+
+// Delete deletes a project. With opts.Cascade its instances and autoscaling
+// groups are deleted too.
+func (s *ProjectsService) Delete(ctx context.Context, id string, opts domain.DeleteProjectOptions) error {
+	params := url.Values{}
+	if opts.Cascade {
+		params.Set("cascade", "true")
+	}
+
+	return s.client.do(ctx, "DELETE", withQuery(resourcePath("/projects", id), params), nil, nil)
+}
+
+// Move moves a project under a new organization or folder
+func (s *ProjectsService) Move(ctx context.Context, id string, req domain.MoveRequest) (*domain.Project, error) {
+	var project domain.Project
+	err := s.client.do(ctx, "POST", resourcePath("/projects", id)+":move", req, &project)
+	return &project, err
+}
+
+// Ancestry retrieves a project's position in the resource hierarchy
+func (s *ProjectsService) Ancestry(ctx context.Context, id string) (*domain.ProjectAncestry, error) {
+	var ancestry domain.ProjectAncestry
+	err := s.client.do(ctx, "GET", resourcePath("/projects", id)+"/ancestry", nil, &ancestry)
+	return &ancestry, err
+}
+
+// GetIAMPolicy retrieves a project's IAM policy
+func (s *ProjectsService) GetIAMPolicy(ctx context.Context, id string) (*domain.IAMPolicy, error) {
+	var policy domain.IAMPolicy
+	err := s.client.do(ctx, "GET", resourcePath("/projects", id)+"/iam", nil, &policy)
+	return &policy, err
+}
+
+// GetUsage retrieves a project's simulated usage and cost for period, a month
+// such as "2024-03", or for the current month when period is empty
+func (s *ProjectsService) GetUsage(ctx context.Context, id, period string) (*domain.UsageReport, error) {
+	params := url.Values{}
+	if period != "" {
+		params.Set("period", period)
+	}
+
+	var report domain.UsageReport
+	err := s.client.do(ctx, "GET", withQuery(resourcePath("/projects", id)+"/usage", params), nil, &report)
+	return &report, err
+}
+
+// SetIAMPolicy replaces a project's IAM policy
+func (s *ProjectsService) SetIAMPolicy(ctx context.Context, id string, req domain.SetIAMPolicyRequest) (*domain.IAMPolicy, error) {
+	var policy domain.IAMPolicy
+	err := s.client.do(ctx, "PUT", resourcePath("/projects", id)+"/iam", req, &policy)
+	return &policy, err
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// TopicsService provides access to the pub/sub topic API
+type TopicsService struct {
+	client *Client
+}
+
+// Create creates a new topic
+func (s *TopicsService) Create(ctx context.Context, req domain.CreateTopicRequest) (*domain.Topic, error) {
+	var topic domain.Topic
+	err := s.client.do(ctx, "POST", "/topics", req, &topic)
+	return &topic, err
+}
+
+// Get retrieves a topic by ID
+func (s *TopicsService) Get(ctx context.Context, id string) (*domain.Topic, error) {
+	var topic domain.Topic
+	err := s.client.do(ctx, "GET", resourcePath("/topics", id), nil, &topic)
+	return &topic, err
+}
+
+// List lists topics with optional filtering
+func (s *TopicsService) List(ctx context.Context, opts domain.TopicListOptions) ([]*domain.Topic, error) {
+	params := url.Values{}
+	if opts.ProjectID != "" {
+		params.Set("project_id", opts.ProjectID)
+	}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+
+	var topics []*domain.Topic
+	err := s.client.do(ctx, "GET", withQuery("/topics", params), nil, &topics)
+	return topics, err
+}
+
+// Delete deletes a topic along with its subscriptions
+func (s *TopicsService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/topics", id), nil, nil)
+}
+
+// Publish publishes messages to a topic
+func (s *TopicsService) Publish(ctx context.Context, id string, req domain.PublishRequest) (*domain.PublishResponse, error) {
+	var resp domain.PublishResponse
+	err := s.client.do(ctx, "POST", resourcePath("/topics", id)+":publish", req, &resp)
+	return &resp, err
+}
+
+// SubscriptionsService provides access to the pub/sub subscription API
+type SubscriptionsService struct {
+	client *Client
+}
+
+// Create creates a new subscription to a topic
+func (s *SubscriptionsService) Create(ctx context.Context, req domain.CreateSubscriptionRequest) (*domain.Subscription, error) {
+	var subscription domain.Subscription
+	err := s.client.do(ctx, "POST", "/subscriptions", req, &subscription)
+	return &subscription, err
+}
+
+// Get retrieves a subscription by ID
+func (s *SubscriptionsService) Get(ctx context.Context, id string) (*domain.Subscription, error) {
+	var subscription domain.Subscription
+	err := s.client.do(ctx, "GET", resourcePath("/subscriptions", id), nil, &subscription)
+	return &subscription, err
+}
+
+// List lists subscriptions with optional filtering
+func (s *SubscriptionsService) List(ctx context.Context, opts domain.SubscriptionListOptions) ([]*domain.Subscription, error) {
+	params := url.Values{}
+	if opts.ProjectID != "" {
+		params.Set("project_id", opts.ProjectID)
+	}
+	if opts.TopicID != "" {
+		params.Set("topic_id", opts.TopicID)
+	}
+
+	var subscriptions []*domain.Subscription
+	err := s.client.do(ctx, "GET", withQuery("/subscriptions", params), nil, &subscriptions)
+	return subscriptions, err
+}
+
+// Update updates an existing subscription
+func (s *SubscriptionsService) Update(ctx context.Context, id string, req domain.UpdateSubscriptionRequest) (*domain.Subscription, error) {
+	var subscription domain.Subscription
+	err := s.client.do(ctx, "PATCH", resourcePath("/subscriptions", id), req, &subscription)
+	return &subscription, err
+}
+
+// Delete deletes a subscription
+func (s *SubscriptionsService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/subscriptions", id), nil, nil)
+}
+
+// Pull leases available messages of a subscription
+func (s *SubscriptionsService) Pull(ctx context.Context, id string, req domain.PullRequest) (*domain.PullResponse, error) {
+	var resp domain.PullResponse
+	err := s.client.do(ctx, "POST", resourcePath("/subscriptions", id)+":pull", req, &resp)
+	return &resp, err
+}
+
+// Acknowledge removes pulled messages from a subscription
+func (s *SubscriptionsService) Acknowledge(ctx context.Context, id string, ackIDs []string) error {
+	return s.client.do(ctx, "POST", resourcePath("/subscriptions", id)+":acknowledge", domain.AcknowledgeRequest{AckIDs: ackIDs}, nil)
+}
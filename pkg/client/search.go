@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Search finds resources of several types by name or label. The Resource of
+// each result is decoded as a generic JSON object.
+func (c *Client) Search(ctx context.Context, opts domain.SearchOptions) ([]domain.SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", opts.Query)
+	if len(opts.Types) > 0 {
+		params.Set("types", strings.Join(opts.Types, ","))
+	}
+
+	var results []domain.SearchResult
+	err := c.do(ctx, "GET", withQuery("/search", params), nil, &results)
+	return results, err
+}
+
+// Lookup returns the one resource matching opts. Several matches fail with
+// an AMBIGUOUS_MATCH error. The Resource of the result is decoded as a
+// generic JSON object.
+func (c *Client) Lookup(ctx context.Context, opts domain.LookupOptions) (*domain.SearchResult, error) {
+	params := url.Values{}
+	if opts.Project != "" {
+		params.Set("project", opts.Project)
+	}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+	if opts.Selector != "" {
+		params.Set("selector", opts.Selector)
+	}
+
+	var result domain.SearchResult
+	err := c.do(ctx, "GET", withQuery(resourcePath("/lookup", opts.Type), params), nil, &result)
+	return &result, err
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// LatestSecretVersion names the newest version of a secret
+const LatestSecretVersion = "latest"
+
+// SecretsService provides access to the secret API
+type SecretsService struct {
+	client *Client
+}
+
+// versionPath returns the path of a secret version, a number or "latest"
+func versionPath(id, version string) string {
+	return resourcePath("/secrets", id) + "/versions/" + url.PathEscape(version)
+}
+
+// Create creates a new secret
+func (s *SecretsService) Create(ctx context.Context, req domain.CreateSecretRequest) (*domain.Secret, error) {
+	var secret domain.Secret
+	err := s.client.do(ctx, "POST", "/secrets", req, &secret)
+	return &secret, err
+}
+
+// Get retrieves a secret by ID
+func (s *SecretsService) Get(ctx context.Context, id string) (*domain.Secret, error) {
+	var secret domain.Secret
+	err := s.client.do(ctx, "GET", resourcePath("/secrets", id), nil, &secret)
+	return &secret, err
+}
+
+// List lists secrets with optional filtering
+func (s *SecretsService) List(ctx context.Context, opts domain.SecretListOptions) ([]*domain.Secret, error) {
+	params := url.Values{}
+	if opts.ProjectID != "" {
+		params.Set("project_id", opts.ProjectID)
+	}
+	if opts.Name != "" {
+		params.Set("name", opts.Name)
+	}
+
+	var secrets []*domain.Secret
+	err := s.client.do(ctx, "GET", withQuery("/secrets", params), nil, &secrets)
+	return secrets, err
+}
+
+// Update updates an existing secret
+func (s *SecretsService) Update(ctx context.Context, id string, req domain.UpdateSecretRequest) (*domain.Secret, error) {
+	var secret domain.Secret
+	err := s.client.do(ctx, "PATCH", resourcePath("/secrets", id), req, &secret)
+	return &secret, err
+}
+
+// Delete deletes a secret and all of its versions
+func (s *SecretsService) Delete(ctx context.Context, id string) error {
+	return s.client.do(ctx, "DELETE", resourcePath("/secrets", id), nil, nil)
+}
+
+// AddVersion adds a new version to a secret
+func (s *SecretsService) AddVersion(ctx context.Context, id string, req domain.AddSecretVersionRequest) (*domain.SecretVersion, error) {
+	var version domain.SecretVersion
+	err := s.client.do(ctx, "POST", resourcePath("/secrets", id)+"/versions", req, &version)
+	return &version, err
+}
+
+// ListVersions lists the versions of a secret, newest first
+func (s *SecretsService) ListVersions(ctx context.Context, id string) ([]*domain.SecretVersion, error) {
+	var versions []*domain.SecretVersion
+	err := s.client.do(ctx, "GET", resourcePath("/secrets", id)+"/versions", nil, &versions)
+	return versions, err
+}
+
+// GetVersion retrieves a secret version, a number or LatestSecretVersion
+func (s *SecretsService) GetVersion(ctx context.Context, id, version string) (*domain.SecretVersion, error) {
+	var v domain.SecretVersion
+	err := s.client.do(ctx, "GET", versionPath(id, version), nil, &v)
+	return &v, err
+}
+
+// Access returns the payload of a secret version, a number or
+// LatestSecretVersion. The client's token must be granted the secret
+// accessor role on the secret's project.
+func (s *SecretsService) Access(ctx context.Context, id, version string) (*domain.SecretPayload, error) {
+	var payload domain.SecretPayload
+	err := s.client.do(ctx, "GET", versionPath(id, version)+":access", nil, &payload)
+	return &payload, err
+}
+
+// DestroyVersion erases the payload of a secret version
+func (s *SecretsService) DestroyVersion(ctx context.Context, id, version string) (*domain.SecretVersion, error) {
+	var v domain.SecretVersion
+	err := s.client.do(ctx, "POST", versionPath(id, version)+":destroy", nil, &v)
+	return &v, err
+}
@@ -0,0 +1,16 @@
+package client
+
+import (
+	"context"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Validate checks a create request for a resource type, such as "instances",
+// without creating anything. An invalid request is reported in the result,
+// not as an error.
+func (c *Client) Validate(ctx context.Context, resourceType string, req interface{}) (*domain.ValidationResult, error) {
+	var result domain.ValidationResult
+	err := c.do(ctx, "POST", "/validate/"+resourceType, req, &result)
+	return &result, err
+}
@@ -0,0 +1,69 @@
+package signer
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"name":"web"}`)
+	req := httptest.NewRequest("POST", "http://dirt.example:8080/v1/projects?b=2&a=x%20y&a=1", strings.NewReader(string(body)))
+	Sign(req, body, "AKID", "secret", time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, "20261016T120000Z", req.Header.Get(DateHeader))
+	assert.Equal(t, HashPayload(body), req.Header.Get(ContentHashHeader))
+
+	auth, err := ParseAuthorization(req.Header.Get("Authorization"))
+	require.NoError(t, err)
+	assert.Equal(t, "AKID", auth.AccessKey)
+	assert.Equal(t, "20261016", auth.Date)
+	assert.Equal(t, RequiredHeaders, auth.SignedHeaders)
+
+	canonical := CanonicalRequest(req, auth.SignedHeaders, HashPayload(body))
+	assert.Equal(t, strings.Join([]string{
+		"POST",
+		"/v1/projects",
+		"a=1&a=x%20y&b=2",
+		"host:dirt.example:8080",
+		"x-dirt-content-sha256:" + HashPayload(body),
+		"x-dirt-date:20261016T120000Z",
+		"",
+		"host;x-dirt-content-sha256;x-dirt-date",
+		HashPayload(body),
+	}, "\n"), canonical)
+
+	// The signature is pinned so that changes to the scheme, which would
+	// break SDKs implementing it, show up here
+	assert.Equal(t, "959dd0d199a35758f03b117590623374ac125695ebdda655d9c7c25a9ff37ade", auth.Signature)
+}
+
+func TestParseAuthorization(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		errorMsg string
+	}{
+		{"bearer", "Bearer token", "authorization scheme must be DIRT-HMAC-SHA256"},
+		{"malformed parameter", "DIRT-HMAC-SHA256 Credential", "malformed authorization parameter"},
+		{"bad scope", "DIRT-HMAC-SHA256 Credential=AKID/20261016/aws4_request, SignedHeaders=host, Signature=ab", "credential must be"},
+		{"missing signature", "DIRT-HMAC-SHA256 Credential=AKID/20261016/dirt_request, SignedHeaders=host", "missing Signature"},
+		{"valid", "DIRT-HMAC-SHA256 Credential=AKID/20261016/dirt_request, SignedHeaders=host, Signature=ab", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAuthorization(tt.header)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
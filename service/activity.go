@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// ListActivity returns the activity timeline of a resource, oldest first:
+// the events recorded for it merged with the logged API requests addressed to
+// it. Without a request log only events are listed.
+func (s *Service) ListActivity(opts domain.ActivityListOptions) ([]*domain.ActivityEntry, error) {
+	events, err := s.eventRepo.List(domain.EventListOptions{
+		ResourceType: opts.ResourceType,
+		ResourceID:   opts.ResourceID,
+		Since:        opts.Since,
+		Until:        opts.Until,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*domain.ActivityEntry
+	for _, event := range events {
+		entries = append(entries, &domain.ActivityEntry{
+			Kind:    domain.ActivityEvent,
+			Time:    event.CreatedAt,
+			Actor:   event.Actor,
+			Summary: event.Message,
+			Event:   event,
+		})
+	}
+
+	if s.requestLogRepo != nil && opts.RequestPath != "" {
+		requests, err := s.requestLogRepo.List(domain.RequestLogListOptions{
+			Path:  opts.RequestPath,
+			Since: opts.Since,
+			Until: opts.Until,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, request := range requests {
+			if !addressesResource(request.Path, opts.RequestPath) {
+				continue
+			}
+			entries = append(entries, &domain.ActivityEntry{
+				Kind:    domain.ActivityRequest,
+				Time:    request.CreatedAt,
+				Actor:   request.Actor,
+				Summary: fmt.Sprintf("%s %s returned %d", request.Method, request.Path, request.Status),
+				Request: request,
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	if opts.Limit > 0 && len(entries) > opts.Limit {
+		entries = entries[len(entries)-opts.Limit:]
+	}
+	return entries, nil
+}
+
+// addressesResource reports whether a request path is the resource at
+// resourcePath, one of its sub-resources or one of its custom methods. The
+// request log matches path prefixes, which would also take in resources
+// whose IDs merely start with the same characters.
+func addressesResource(path, resourcePath string) bool {
+	if !strings.HasPrefix(path, resourcePath) {
+		return false
+	}
+	rest := path[len(resourcePath):]
+	return rest == "" || rest[0] == '/' || rest[0] == ':'
+}
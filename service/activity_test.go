@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressesResource(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/v1/instances/abc", true},
+		{"/v1/instances/abc/resize", true},
+		{"/v1/instances/abc:clone", true},
+		{"/v1/instances/abcdef", false},
+		{"/v1/instances", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, addressesResource(tt.path, "/v1/instances/abc"), tt.path)
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Alias operations. Aliases can refer to resources of every type an
+// environment can group.
+
+// CreateAlias attaches an external ID to an existing resource. An external
+// ID can only name one resource, but a resource can have several.
+func (s *Service) CreateAlias(req domain.CreateAliasRequest) (*domain.Alias, error) {
+	var v domain.FieldViolations
+	if req.ExternalID == "" {
+		v.Add("external_id", "cannot be empty")
+	} else if len(req.ExternalID) > 1024 {
+		v.Add("external_id", fmt.Sprintf("must be at most 1024 characters (got %d)", len(req.ExternalID)))
+	}
+	validateResourceType(&v, "resource_type", req.ResourceType)
+	if req.ResourceID == "" {
+		v.Add("resource_id", "cannot be empty")
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.Alias, error) {
+		t, _ := findEnvironmentType(req.ResourceType)
+		if err := t.exists(tx, req.ResourceID); err != nil {
+			if domain.IsNotFound(err) {
+				return nil, domain.ForeignKeyViolationError(req.ResourceType, "resource_id", req.ResourceID)
+			}
+			return nil, err
+		}
+
+		alias := &domain.Alias{ExternalID: req.ExternalID, ResourceType: req.ResourceType, ResourceID: req.ResourceID}
+		if err := tx.aliasRepo.Create(alias); err != nil {
+			return nil, err
+		}
+		return alias, nil
+	})
+}
+
+// ListAliases lists aliases with optional filtering
+func (s *Service) ListAliases(opts domain.AliasListOptions) ([]*domain.Alias, error) {
+	return s.aliasRepo.List(opts)
+}
+
+// DeleteAlias detaches an external ID from its resource
+func (s *Service) DeleteAlias(externalID string) error {
+	return s.aliasRepo.Delete(externalID)
+}
+
+// ResolveAlias returns the alias of an external ID, checking that its
+// resource still exists. An alias whose resource was deleted resolves to
+// that resource not being found.
+func (s *Service) ResolveAlias(externalID string) (*domain.Alias, error) {
+	if externalID == "" {
+		return nil, domain.ValidationError([]domain.FieldViolation{
+			{Field: "external_id", Message: "is required"},
+		})
+	}
+
+	return inTx(s, func(tx *Service) (*domain.Alias, error) {
+		alias, err := tx.aliasRepo.Get(externalID)
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := findEnvironmentType(alias.ResourceType); ok {
+			if err := t.exists(tx, alias.ResourceID); err != nil {
+				return nil, err
+			}
+		}
+		return alias, nil
+	})
+}
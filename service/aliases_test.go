@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Aliases(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "imported"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	_, err = svc.CreateAlias(domain.CreateAliasRequest{ExternalID: "i-0abc", ResourceType: "instance", ResourceID: instance.ID})
+	require.NoError(t, err)
+	_, err = svc.CreateAlias(domain.CreateAliasRequest{ExternalID: "arn:aws:ec2:us-east-1:123:instance/i-0abc", ResourceType: "instance", ResourceID: instance.ID})
+	require.NoError(t, err, "a resource can have several aliases")
+
+	_, err = svc.CreateAlias(domain.CreateAliasRequest{ExternalID: "i-0abc", ResourceType: "project", ResourceID: project.ID})
+	assert.True(t, domain.IsAlreadyExists(err), "got %v", err)
+	_, err = svc.CreateAlias(domain.CreateAliasRequest{ExternalID: "i-0def", ResourceType: "instance", ResourceID: "missing"})
+	assert.True(t, domain.IsForeignKeyViolation(err), "got %v", err)
+	_, err = svc.CreateAlias(domain.CreateAliasRequest{ExternalID: "", ResourceType: "vm", ResourceID: instance.ID})
+	assert.True(t, domain.IsInvalidInput(err), "got %v", err)
+
+	alias, err := svc.ResolveAlias("i-0abc")
+	require.NoError(t, err)
+	assert.Equal(t, "instance", alias.ResourceType)
+	assert.Equal(t, instance.ID, alias.ResourceID)
+
+	aliases, err := svc.ListAliases(domain.AliasListOptions{ResourceType: "instance", ResourceID: instance.ID})
+	require.NoError(t, err)
+	assert.Len(t, aliases, 2)
+
+	// Aliases outlive their resources, but no longer resolve
+	require.NoError(t, svc.DeleteInstance(instance.ID))
+	_, err = svc.ResolveAlias("i-0abc")
+	require.True(t, domain.IsNotFound(err), "got %v", err)
+	assert.Equal(t, "instance", err.(*domain.DirtError).Details["resource"])
+
+	require.NoError(t, svc.DeleteAlias("i-0abc"))
+	_, err = svc.ResolveAlias("i-0abc")
+	require.True(t, domain.IsNotFound(err), "got %v", err)
+	assert.Equal(t, "alias", err.(*domain.DirtError).Details["resource"])
+}
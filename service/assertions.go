@@ -0,0 +1,211 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// resourceFields is a resource in its JSON form, as assertions see it
+type resourceFields map[string]interface{}
+
+// field returns the value of a field, following dots into nested objects
+func (r resourceFields) field(name string) (interface{}, bool) {
+	var value interface{} = map[string]interface{}(r)
+	for _, part := range strings.Split(name, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// toJSONValue converts v to the value it decodes to from JSON, so values
+// given in a request compare equal to the fields of stored resources
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// jsonText renders a value for a failure message
+func jsonText(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// Assert evaluates expectations about the stored resources and reports which
+// hold. All assertions see the same snapshot of the data.
+func (s *Service) Assert(req domain.AssertRequest) (*domain.AssertResponse, error) {
+	var v domain.FieldViolations
+	if len(req.Assertions) == 0 {
+		v.Add("assertions", "cannot be empty")
+	}
+	for i, a := range req.Assertions {
+		field := fmt.Sprintf("assertions[%d]", i)
+		if a.Type != "metadata" && !containsString(SearchTypes, a.Type) {
+			v.Add(field+".type", fmt.Sprintf("unknown resource type %q (must be metadata or one of %s)", a.Type, strings.Join(SearchTypes, ", ")))
+		}
+		for name, count := range map[string]*int{"count": a.Count, "min_count": a.MinCount, "max_count": a.MaxCount} {
+			if count != nil && *count < 0 {
+				v.Add(field+"."+name, "cannot be negative")
+			}
+		}
+		if a.MinCount != nil && a.MaxCount != nil && *a.MinCount > *a.MaxCount {
+			v.Add(field+".min_count", "cannot be greater than max_count")
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.AssertResponse, error) {
+		listed := make(map[string][]resourceFields)
+		resp := &domain.AssertResponse{Passed: true, Results: make([]domain.AssertionResult, len(req.Assertions))}
+
+		for i, a := range req.Assertions {
+			resources, ok := listed[a.Type]
+			if !ok {
+				var err error
+				if resources, err = tx.assertionResources(a.Type); err != nil {
+					return nil, err
+				}
+				listed[a.Type] = resources
+			}
+
+			result, err := evaluateAssertion(a, resources)
+			if err != nil {
+				return nil, domain.ValidationError([]domain.FieldViolation{
+					{Field: fmt.Sprintf("assertions[%d]", i), Message: err.Error()},
+				})
+			}
+			result.Index = i
+			result.Name = a.Name
+
+			if !result.Passed {
+				resp.Passed = false
+				resp.Failed++
+			}
+			resp.Results[i] = result
+		}
+
+		return resp, nil
+	})
+}
+
+// assertionResources lists every resource of a type in its JSON form
+func (s *Service) assertionResources(resourceType string) ([]resourceFields, error) {
+	var items []interface{}
+	if resourceType == "metadata" {
+		metadata, err := s.ListMetadata(domain.MetadataListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range metadata {
+			items = append(items, m)
+		}
+	} else {
+		results, err := s.searchType(resourceType, func(string, map[string]string) bool { return true })
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			items = append(items, r.Resource)
+		}
+	}
+
+	resources := make([]resourceFields, 0, len(items))
+	for _, item := range items {
+		value, err := toJSONValue(item)
+		if err != nil {
+			return nil, domain.InternalError("failed to encode resource")
+		}
+		resources = append(resources, value.(map[string]interface{}))
+	}
+	return resources, nil
+}
+
+// evaluateAssertion checks an assertion against the resources of its type
+func evaluateAssertion(a domain.Assertion, resources []resourceFields) (domain.AssertionResult, error) {
+	where, err := toJSONValue(a.Where)
+	if err != nil {
+		return domain.AssertionResult{}, fmt.Errorf("where is not valid JSON")
+	}
+	equals, err := toJSONValue(a.Equals)
+	if err != nil {
+		return domain.AssertionResult{}, fmt.Errorf("equals is not valid JSON")
+	}
+	whereFields, _ := where.(map[string]interface{})
+	equalsFields, _ := equals.(map[string]interface{})
+
+	var matched []resourceFields
+	for _, r := range resources {
+		if matchesFields(r, whereFields) {
+			matched = append(matched, r)
+		}
+	}
+
+	result := domain.AssertionResult{Matched: len(matched)}
+	fail := func(format string, args ...interface{}) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case a.Count != nil && len(matched) != *a.Count:
+		fail("expected exactly %d matching %s resources, found %d", *a.Count, a.Type, len(matched))
+	case a.Count == nil && a.MinCount == nil && a.MaxCount == nil && len(matched) == 0:
+		fail("no matching %s resource", a.Type)
+	}
+	if a.MinCount != nil && len(matched) < *a.MinCount {
+		fail("expected at least %d matching %s resources, found %d", *a.MinCount, a.Type, len(matched))
+	}
+	if a.MaxCount != nil && len(matched) > *a.MaxCount {
+		fail("expected at most %d matching %s resources, found %d", *a.MaxCount, a.Type, len(matched))
+	}
+
+	names := make([]string, 0, len(equalsFields))
+	for name := range equalsFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, r := range matched {
+		for _, name := range names {
+			actual, ok := r.field(name)
+			switch {
+			case !ok:
+				fail("%s %v has no field %s", a.Type, r["id"], name)
+			case !reflect.DeepEqual(actual, equalsFields[name]):
+				fail("%s %v has %s %s, expected %s", a.Type, r["id"], name, jsonText(actual), jsonText(equalsFields[name]))
+			}
+		}
+	}
+
+	result.Passed = len(result.Failures) == 0
+	return result, nil
+}
+
+// matchesFields reports whether a resource has every given field value
+func matchesFields(r resourceFields, fields map[string]interface{}) bool {
+	for name, expected := range fields {
+		actual, ok := r.field(name)
+		if !ok || !reflect.DeepEqual(actual, expected) {
+			return false
+		}
+	}
+	return true
+}
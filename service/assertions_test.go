@@ -0,0 +1,121 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestService_Assert(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "checked"})
+	require.NoError(t, err)
+	for _, name := range []string{"web-1", "web-2"} {
+		_, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: name, CPU: 2, MemoryMB: 1024, Image: "ubuntu", Labels: map[string]string{"tier": "web"}})
+		require.NoError(t, err)
+	}
+	_, err = svc.CreateMetadata(domain.CreateMetadataRequest{Path: "a/b", Value: "hello"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		assertion domain.Assertion
+		matched   int
+		failures  []string
+	}{
+		{
+			name:      "exact count",
+			assertion: domain.Assertion{Type: "instance", Where: map[string]interface{}{"project_id": project.ID, "status": "running"}, Count: intPtr(2)},
+			matched:   2,
+		},
+		{
+			name:      "wrong count",
+			assertion: domain.Assertion{Type: "instance", Where: map[string]interface{}{"project_id": project.ID}, Count: intPtr(3)},
+			matched:   2,
+			failures:  []string{"expected exactly 3 matching instance resources, found 2"},
+		},
+		{
+			name:      "count range",
+			assertion: domain.Assertion{Type: "instance", MinCount: intPtr(3), MaxCount: intPtr(5)},
+			matched:   2,
+			failures:  []string{"expected at least 3 matching instance resources, found 2"},
+		},
+		{
+			name:      "nested field and numbers",
+			assertion: domain.Assertion{Type: "instance", Where: map[string]interface{}{"labels.tier": "web"}, Equals: map[string]interface{}{"cpu": 2, "memory_mb": 1024}},
+			matched:   2,
+		},
+		{
+			name:      "metadata value",
+			assertion: domain.Assertion{Type: "metadata", Where: map[string]interface{}{"path": "a/b"}, Equals: map[string]interface{}{"value": "hello"}},
+			matched:   1,
+		},
+		{
+			name:      "metadata value differs",
+			assertion: domain.Assertion{Type: "metadata", Where: map[string]interface{}{"path": "a/b"}, Equals: map[string]interface{}{"value": "bye"}},
+			matched:   1,
+			failures:  []string{`has value "hello", expected "bye"`},
+		},
+		{
+			name:      "missing field",
+			assertion: domain.Assertion{Type: "project", Where: map[string]interface{}{"name": "checked"}, Equals: map[string]interface{}{"labels.env": "prod"}},
+			matched:   1,
+			failures:  []string{"has no field labels.env"},
+		},
+		{
+			name:      "nothing matches",
+			assertion: domain.Assertion{Type: "project", Where: map[string]interface{}{"name": "absent"}},
+			failures:  []string{"no matching project resource"},
+		},
+		{
+			name:      "absence",
+			assertion: domain.Assertion{Type: "database", Count: intPtr(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.Assert(domain.AssertRequest{Assertions: []domain.Assertion{tt.assertion}})
+			require.NoError(t, err)
+			require.Len(t, resp.Results, 1)
+
+			result := resp.Results[0]
+			assert.Equal(t, tt.matched, result.Matched)
+			assert.Equal(t, len(tt.failures) == 0, result.Passed)
+			assert.Equal(t, result.Passed, resp.Passed)
+			require.Len(t, result.Failures, len(tt.failures), result.Failures)
+			for i, failure := range tt.failures {
+				assert.Contains(t, result.Failures[i], failure)
+			}
+		})
+	}
+
+	t.Run("report", func(t *testing.T) {
+		resp, err := svc.Assert(domain.AssertRequest{Assertions: []domain.Assertion{
+			{Name: "has project", Type: "project"},
+			{Name: "no secrets", Type: "secret", Count: intPtr(1)},
+		}})
+		require.NoError(t, err)
+		assert.False(t, resp.Passed)
+		assert.Equal(t, 1, resp.Failed)
+		assert.Equal(t, "no secrets", resp.Results[1].Name)
+		assert.Equal(t, 1, resp.Results[1].Index)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, req := range []domain.AssertRequest{
+			{},
+			{Assertions: []domain.Assertion{{Type: "volume"}}},
+			{Assertions: []domain.Assertion{{Type: "instance", Count: intPtr(-1)}}},
+			{Assertions: []domain.Assertion{{Type: "instance", MinCount: intPtr(2), MaxCount: intPtr(1)}}},
+		} {
+			_, err := svc.Assert(req)
+			assert.True(t, domain.IsInvalidInput(err), "%+v", req)
+		}
+	})
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// maxAutoscalingGroupSize bounds the number of members a group may have
+const maxAutoscalingGroupSize = 100
+
+// validateGroupSizes validates autoscaling group size bounds
+func validateGroupSizes(v *domain.FieldViolations, minSize, maxSize, desiredSize int) {
+	if minSize < 0 {
+		v.Add("min_size", "cannot be negative")
+	}
+	if maxSize > maxAutoscalingGroupSize {
+		v.Add("max_size", fmt.Sprintf("must be at most %d (got %d)", maxAutoscalingGroupSize, maxSize))
+	}
+	if maxSize < minSize {
+		v.Add("max_size", fmt.Sprintf("must be at least min_size (%d)", minSize))
+	}
+	if desiredSize < minSize || desiredSize > maxSize {
+		v.Add("desired_size", fmt.Sprintf("must be between min_size (%d) and max_size (%d)", minSize, maxSize))
+	}
+}
+
+// Autoscaling group operations
+
+// CreateAutoscalingGroup creates a new autoscaling group. Members are created
+// by the autoscaler, not by this call.
+func (s *Service) CreateAutoscalingGroup(req domain.CreateAutoscalingGroupRequest) (*domain.AutoscalingGroup, error) {
+	return inTx(s, func(tx *Service) (*domain.AutoscalingGroup, error) {
+		desired := req.MinSize
+		if req.DesiredSize != nil {
+			desired = *req.DesiredSize
+		}
+
+		var v domain.FieldViolations
+		if req.ProjectID == "" {
+			v.Add("project_id", "cannot be empty")
+		}
+		validateName(&v, "name", req.Name)
+		if req.TemplateID == "" {
+			v.Add("template_id", "cannot be empty")
+		}
+		validateGroupSizes(&v, req.MinSize, req.MaxSize, desired)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.projectRepo.GetByID(req.ProjectID); err != nil {
+			if domain.IsNotFound(err) {
+				return nil, domain.ForeignKeyViolationError("project", "id", req.ProjectID)
+			}
+			return nil, err
+		}
+		if _, err := tx.templateRepo.GetByID(req.TemplateID); err != nil {
+			if domain.IsNotFound(err) {
+				return nil, domain.ForeignKeyViolationError("instance template", "id", req.TemplateID)
+			}
+			return nil, err
+		}
+
+		id, err := tx.newID(kindAutoscalingGroup)
+		if err != nil {
+			return nil, domain.InternalError("failed to generate ID")
+		}
+
+		group := &domain.AutoscalingGroup{
+			ID:          id,
+			ProjectID:   req.ProjectID,
+			Name:        req.Name,
+			TemplateID:  req.TemplateID,
+			MinSize:     req.MinSize,
+			MaxSize:     req.MaxSize,
+			DesiredSize: desired,
+		}
+
+		if err := tx.groupRepo.Create(group); err != nil {
+			return nil, err
+		}
+
+		return group, nil
+	})
+}
+
+// GetAutoscalingGroup retrieves an autoscaling group by ID
+func (s *Service) GetAutoscalingGroup(id string) (*domain.AutoscalingGroup, error) {
+	group, err := s.groupRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.fillCurrentSize(group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// ListAutoscalingGroups lists autoscaling groups with optional filtering
+func (s *Service) ListAutoscalingGroups(opts domain.AutoscalingGroupListOptions) ([]*domain.AutoscalingGroup, error) {
+	groups, err := s.groupRepo.List(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		if err := s.fillCurrentSize(group); err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
+
+// UpdateAutoscalingGroup updates the template or sizes of an autoscaling group.
+// Existing members keep the shape they were created with.
+func (s *Service) UpdateAutoscalingGroup(id string, req domain.UpdateAutoscalingGroupRequest) (*domain.AutoscalingGroup, error) {
+	return inTx(s, func(tx *Service) (*domain.AutoscalingGroup, error) {
+		current, err := tx.groupRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		minSize, maxSize, desired := current.MinSize, current.MaxSize, current.DesiredSize
+		if req.MinSize != nil {
+			minSize = *req.MinSize
+		}
+		if req.MaxSize != nil {
+			maxSize = *req.MaxSize
+		}
+		if req.DesiredSize != nil {
+			desired = *req.DesiredSize
+		}
+
+		var v domain.FieldViolations
+		if req.TemplateID != nil && *req.TemplateID == "" {
+			v.Add("template_id", "cannot be empty")
+		}
+		validateGroupSizes(&v, minSize, maxSize, desired)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+
+		group, err := tx.groupRepo.Update(id, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tx.fillCurrentSize(group); err != nil {
+			return nil, err
+		}
+
+		return group, nil
+	})
+}
+
+// DeleteAutoscalingGroup deletes an autoscaling group along with its members
+func (s *Service) DeleteAutoscalingGroup(id string) error {
+	return s.runInTx(func(tx *Service) error {
+		members, err := tx.instanceRepo.List(domain.InstanceListOptions{AutoscalingGroupID: id})
+		if err != nil {
+			return err
+		}
+		if err := tx.groupRepo.Delete(id); err != nil {
+			return err
+		}
+		for _, member := range members {
+			tx.recordStatusChange(member, member.Status, domain.StatusTerminated)
+		}
+		return nil
+	})
+}
+
+// fillCurrentSize sets the number of members a group currently has
+func (s *Service) fillCurrentSize(group *domain.AutoscalingGroup) error {
+	members, err := s.instanceRepo.List(domain.InstanceListOptions{AutoscalingGroupID: group.ID})
+	if err != nil {
+		return err
+	}
+	group.CurrentSize = len(members)
+	return nil
+}
+
+// ReconcileAutoscalingGroups moves every group one member closer to its
+// desired size, recording an event for each change, and returns how many
+// groups changed. Stepping one member at a time lets clients observe groups
+// converging.
+func (s *Service) ReconcileAutoscalingGroups() (int, error) {
+	s = s.WithActor(domain.ActorAutoscaler).WithReason(domain.ReasonAutoscaler)
+
+	groups, err := s.groupRepo.List(domain.AutoscalingGroupListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, group := range groups {
+		members, err := s.instanceRepo.List(domain.InstanceListOptions{AutoscalingGroupID: group.ID})
+		if err != nil {
+			return changed, err
+		}
+
+		switch {
+		case len(members) < group.DesiredSize:
+			if s.scaleOut(group) {
+				changed++
+			}
+		case len(members) > group.DesiredSize:
+			if s.scaleIn(group, members) {
+				changed++
+			}
+		}
+	}
+
+	return changed, nil
+}
+
+// scaleOut adds one member to a group
+func (s *Service) scaleOut(group *domain.AutoscalingGroup) bool {
+	// The tail of an ID varies between consecutive deterministic IDs too
+	suffix, err := s.newID(kindInstance)
+	if err != nil {
+		return false
+	}
+	suffix = suffix[len(suffix)-6:]
+
+	tmpl, err := s.templateRepo.GetByID(group.TemplateID)
+	if err == nil {
+		var instance *domain.Instance
+		instance, err = s.createInstance(domain.CreateInstanceRequest{
+			ProjectID: group.ProjectID,
+			Name:      group.Name + "-" + suffix,
+			CPU:       tmpl.CPU,
+			MemoryMB:  tmpl.MemoryMB,
+			Image:     tmpl.Image,
+			Labels:    mergeLabels(tmpl.Labels, nil),
+		}, group.ID)
+		if err == nil {
+			s.recordEvent(domain.EventAutoscalingScaleOut, "autoscaling_group", group.ID, group.ProjectID,
+				fmt.Sprintf("autoscaling group %s created instance %s (%s)", group.Name, instance.Name, instance.ID))
+			return true
+		}
+	}
+
+	s.recordEvent(domain.EventAutoscalingScaleFailed, "autoscaling_group", group.ID, group.ProjectID,
+		fmt.Sprintf("autoscaling group %s failed to create an instance: %v", group.Name, err))
+	return false
+}
+
+// scaleIn removes the newest member of a group
+func (s *Service) scaleIn(group *domain.AutoscalingGroup, members []*domain.Instance) bool {
+	newest := members[0]
+	for _, m := range members[1:] {
+		if m.CreatedAt.After(newest.CreatedAt) {
+			newest = m
+		}
+	}
+
+	if err := s.instanceRepo.Delete(newest.ID); err != nil {
+		s.recordEvent(domain.EventAutoscalingScaleFailed, "autoscaling_group", group.ID, group.ProjectID,
+			fmt.Sprintf("autoscaling group %s failed to delete instance %s: %v", group.Name, newest.Name, err))
+		return false
+	}
+	s.recordStatusChange(newest, newest.Status, domain.StatusTerminated)
+
+	s.recordEvent(domain.EventAutoscalingScaleIn, "autoscaling_group", group.ID, group.ProjectID,
+		fmt.Sprintf("autoscaling group %s deleted instance %s (%s)", group.Name, newest.Name, newest.ID))
+	return true
+}
+
+// RunAutoscaler reconciles autoscaling groups every interval until ctx is done
+func (s *Service) RunAutoscaler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ReconcileAutoscalingGroups(); err != nil {
+				log.Printf("Autoscaler failed: %v", err)
+			}
+		}
+	}
+}
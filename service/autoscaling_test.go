@@ -0,0 +1,143 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGroupSizes(t *testing.T) {
+	tests := []struct {
+		name          string
+		min, max      int
+		desired       int
+		expectedField string
+	}{
+		{name: "valid", min: 1, max: 3, desired: 2},
+		{name: "empty group", min: 0, max: 1, desired: 0},
+		{name: "negative min", min: -1, max: 3, desired: 0, expectedField: "min_size"},
+		{name: "max below min", min: 3, max: 2, desired: 2, expectedField: "max_size"},
+		{name: "max too large", min: 0, max: maxAutoscalingGroupSize + 1, desired: 0, expectedField: "max_size"},
+		{name: "desired above max", min: 0, max: 2, desired: 3, expectedField: "desired_size"},
+		{name: "desired below min", min: 2, max: 4, desired: 1, expectedField: "desired_size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v domain.FieldViolations
+			validateGroupSizes(&v, tt.min, tt.max, tt.desired)
+
+			if tt.expectedField == "" {
+				assert.Empty(t, v)
+				return
+			}
+			require.NotEmpty(t, v)
+			assert.Equal(t, tt.expectedField, v[0].Field)
+		})
+	}
+}
+
+func TestService_ReconcileAutoscalingGroups(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "scaling"})
+	require.NoError(t, err)
+	tmpl, err := svc.CreateInstanceTemplate(domain.CreateInstanceTemplateRequest{
+		Name: "worker", CPU: 1, MemoryMB: 512, Image: "alpine",
+	})
+	require.NoError(t, err)
+
+	desired := 2
+	group, err := svc.CreateAutoscalingGroup(domain.CreateAutoscalingGroupRequest{
+		ProjectID: project.ID, Name: "workers", TemplateID: tmpl.ID, MinSize: 0, MaxSize: 3, DesiredSize: &desired,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, group.CurrentSize)
+
+	// Each pass moves the group one member closer to the desired size
+	for want := 1; want <= 2; want++ {
+		changed, err := svc.ReconcileAutoscalingGroups()
+		require.NoError(t, err)
+		assert.Equal(t, 1, changed)
+
+		group, err = svc.GetAutoscalingGroup(group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, want, group.CurrentSize)
+	}
+
+	changed, err := svc.ReconcileAutoscalingGroups()
+	require.NoError(t, err)
+	assert.Equal(t, 0, changed)
+
+	members, err := svc.ListInstances(domain.InstanceListOptions{AutoscalingGroupID: group.ID})
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	for _, m := range members {
+		assert.Equal(t, project.ID, m.ProjectID)
+		assert.Equal(t, "alpine", m.Image)
+		assert.Equal(t, 512, m.MemoryMB)
+	}
+
+	desired = 1
+	_, err = svc.UpdateAutoscalingGroup(group.ID, domain.UpdateAutoscalingGroupRequest{DesiredSize: &desired})
+	require.NoError(t, err)
+	_, err = svc.ReconcileAutoscalingGroups()
+	require.NoError(t, err)
+
+	group, err = svc.GetAutoscalingGroup(group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, group.CurrentSize)
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceType: "autoscaling_group", ResourceID: group.ID})
+	require.NoError(t, err)
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []string{
+		domain.EventAutoscalingScaleOut,
+		domain.EventAutoscalingScaleOut,
+		domain.EventAutoscalingScaleIn,
+	}, types)
+
+	// Deleting the group removes its members
+	require.NoError(t, svc.DeleteAutoscalingGroup(group.ID))
+	members, err = svc.ListInstances(domain.InstanceListOptions{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func TestService_CreateAutoscalingGroup(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "scaling"})
+	require.NoError(t, err)
+	tmpl, err := svc.CreateInstanceTemplate(domain.CreateInstanceTemplateRequest{
+		Name: "worker", CPU: 1, MemoryMB: 512, Image: "alpine",
+	})
+	require.NoError(t, err)
+
+	group, err := svc.CreateAutoscalingGroup(domain.CreateAutoscalingGroupRequest{
+		ProjectID: project.ID, Name: "workers", TemplateID: tmpl.ID, MinSize: 2, MaxSize: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, group.DesiredSize, "desired size defaults to min size")
+
+	_, err = svc.CreateAutoscalingGroup(domain.CreateAutoscalingGroupRequest{
+		ProjectID: project.ID, Name: "workers", TemplateID: tmpl.ID, MinSize: 0, MaxSize: 1,
+	})
+	assert.True(t, domain.IsAlreadyExists(err))
+
+	_, err = svc.CreateAutoscalingGroup(domain.CreateAutoscalingGroupRequest{
+		ProjectID: project.ID, Name: "orphans", TemplateID: "missing", MinSize: 0, MaxSize: 1,
+	})
+	assert.True(t, domain.IsForeignKeyViolation(err))
+
+	// Referenced templates and projects cannot be deleted
+	err = svc.DeleteInstanceTemplate(tmpl.ID)
+	assert.Error(t, err)
+	err = svc.DeleteProject(project.ID, domain.DeleteProjectOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// CreateBackup writes a snapshot of the database
+func (s *Service) CreateBackup() (*domain.Backup, error) {
+	return s.backupRepo.Create()
+}
+
+// ListBackups lists the database snapshots, oldest first
+func (s *Service) ListBackups() ([]*domain.Backup, error) {
+	return s.backupRepo.List()
+}
+
+// RestoreBackup replaces the database contents with a snapshot
+func (s *Service) RestoreBackup(req domain.RestoreBackupRequest) error {
+	var v domain.FieldViolations
+	if req.Name == "" {
+		v.Add("name", "is required")
+	} else if filepath.Base(req.Name) != req.Name || req.Name == "." || req.Name == ".." {
+		v.Add("name", "must be a backup file name")
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	return s.backupRepo.Restore(req.Name)
+}
+
+// ResetData deletes every resource, leaving an empty database at the latest
+// schema version. Snapshots are kept. Deterministic IDs restart at their seed.
+func (s *Service) ResetData() error {
+	if err := s.backupRepo.Reset(); err != nil {
+		return err
+	}
+	s.ids().Reset()
+	return nil
+}
+
+// PruneBackups deletes all but the newest keep snapshots and returns how many
+// were deleted. A keep of 0 or less keeps every snapshot.
+func (s *Service) PruneBackups(keep int) (int, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+
+	backups, err := s.backupRepo.List()
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for len(backups)-pruned > keep {
+		if err := s.backupRepo.Delete(backups[pruned].Name); err != nil && !domain.IsNotFound(err) {
+			return pruned, err
+		}
+		pruned++
+	}
+
+	return pruned, nil
+}
+
+// RunBackups snapshots the database every interval, keeping the newest keep
+// snapshots, until ctx is done
+func (s *Service) RunBackups(ctx context.Context, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			backup, err := s.CreateBackup()
+			if err != nil {
+				log.Printf("Scheduled backup failed: %v", err)
+				continue
+			}
+			log.Printf("Wrote backup %s (%d bytes)", backup.Name, backup.SizeBytes)
+
+			if _, err := s.PruneBackups(keep); err != nil {
+				log.Printf("Pruning backups failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreBackup_ValidatesName(t *testing.T) {
+	svc := newTestService(t)
+
+	tests := []struct {
+		name        string
+		backupName  string
+		expectField string
+	}{
+		{name: "missing", backupName: "", expectField: "name"},
+		{name: "path traversal", backupName: "../dirt.db", expectField: "name"},
+		{name: "absolute path", backupName: "/etc/passwd", expectField: "name"},
+		{name: "parent directory", backupName: "..", expectField: "name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.RestoreBackup(domain.RestoreBackupRequest{Name: tt.backupName})
+			require.Error(t, err)
+			assert.True(t, domain.IsInvalidInput(err))
+		})
+	}
+
+	err := svc.RestoreBackup(domain.RestoreBackupRequest{Name: "dirt-missing.db"})
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestPruneBackups(t *testing.T) {
+	svc := newTestService(t)
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		backup, err := svc.CreateBackup()
+		require.NoError(t, err)
+		names = append(names, backup.Name)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	pruned, err := svc.PruneBackups(0)
+	require.NoError(t, err)
+	assert.Zero(t, pruned, "keep 0 retains every backup")
+
+	pruned, err = svc.PruneBackups(2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pruned)
+
+	backups, err := svc.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, names[2], backups[0].Name)
+	assert.Equal(t, names[3], backups[1].Name)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// maxBudgetThreshold bounds budget thresholds, as a fraction of the amount
+const maxBudgetThreshold = 10
+
+// defaultBudgetThresholds alert at half the amount, close to it and at it
+var defaultBudgetThresholds = []float64{0.5, 0.9, 1}
+
+// webhookClient delivers budget alerts
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// validateBudget validates budget settings
+func validateBudget(v *domain.FieldViolations, amount float64, thresholds []float64, webhookURL string) {
+	if amount <= 0 {
+		v.Add("amount", "must be positive")
+	}
+
+	seen := make(map[float64]bool)
+	for i, threshold := range thresholds {
+		field := fmt.Sprintf("thresholds[%d]", i)
+		if threshold <= 0 || threshold > maxBudgetThreshold {
+			v.Add(field, fmt.Sprintf("must be a fraction of the amount above 0 and at most %d (got %g)", maxBudgetThreshold, threshold))
+		}
+		if seen[threshold] {
+			v.Add(field, fmt.Sprintf("duplicates threshold %g", threshold))
+		}
+		seen[threshold] = true
+	}
+
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Add("webhook_url", "must be an absolute http or https URL")
+		}
+	}
+}
+
+// sortedThresholds returns a sorted copy of thresholds
+func sortedThresholds(thresholds []float64) []float64 {
+	sorted := append([]float64(nil), thresholds...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// Budget operations
+
+// CreateBudget creates a new budget for a project
+func (s *Service) CreateBudget(req domain.CreateBudgetRequest) (*domain.Budget, error) {
+	thresholds := req.Thresholds
+	if thresholds == nil {
+		thresholds = defaultBudgetThresholds
+	}
+
+	var v domain.FieldViolations
+	if req.ProjectID == "" {
+		v.Add("project_id", "cannot be empty")
+	}
+	validateName(&v, "name", req.Name)
+	validateBudget(&v, req.Amount, thresholds, req.WebhookURL)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	id, err := s.newID(kindBudget)
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+
+	budget := &domain.Budget{
+		ID:         id,
+		ProjectID:  req.ProjectID,
+		Name:       req.Name,
+		Amount:     req.Amount,
+		Thresholds: sortedThresholds(thresholds),
+		WebhookURL: req.WebhookURL,
+		Status:     domain.BudgetOK,
+	}
+
+	if err := s.budgetRepo.Create(budget); err != nil {
+		return nil, err
+	}
+
+	return budget, nil
+}
+
+// GetBudget retrieves a budget by ID
+func (s *Service) GetBudget(id string) (*domain.Budget, error) {
+	return s.budgetRepo.GetByID(id)
+}
+
+// ListBudgets lists budgets with optional filtering
+func (s *Service) ListBudgets(opts domain.BudgetListOptions) ([]*domain.Budget, error) {
+	return s.budgetRepo.List(opts)
+}
+
+// UpdateBudget updates the settings of a budget. Its status follows on the
+// next evaluation.
+func (s *Service) UpdateBudget(id string, req domain.UpdateBudgetRequest) (*domain.Budget, error) {
+	return inTx(s, func(tx *Service) (*domain.Budget, error) {
+		current, err := tx.budgetRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		amount, thresholds, webhookURL := current.Amount, current.Thresholds, current.WebhookURL
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		if req.Thresholds != nil {
+			thresholds = req.Thresholds
+			req.Thresholds = sortedThresholds(req.Thresholds)
+		}
+		if req.WebhookURL != nil {
+			webhookURL = *req.WebhookURL
+		}
+
+		var v domain.FieldViolations
+		if req.Name != nil {
+			validateName(&v, "name", *req.Name)
+		}
+		validateBudget(&v, amount, thresholds, webhookURL)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+
+		return tx.budgetRepo.Update(id, req)
+	})
+}
+
+// DeleteBudget deletes a budget
+func (s *Service) DeleteBudget(id string) error {
+	return s.budgetRepo.Delete(id)
+}
+
+// EvaluateBudgets compares every budget with its project's spend in the month
+// of now. A threshold crossed for the first time that month records an event
+// and notifies the budget's webhook; spend reaching the amount marks the
+// budget exceeded. A new month starts every budget afresh.
+func (s *Service) EvaluateBudgets(now time.Time) error {
+	s = s.WithActor(domain.ActorBilling)
+	period := now.UTC().Format(domain.UsagePeriodLayout)
+	prices := s.prices()
+
+	var alerts []domain.BudgetAlert
+	err := s.runInTx(func(tx *Service) error {
+		budgets, err := tx.budgetRepo.List(domain.BudgetListOptions{})
+		if err != nil {
+			return err
+		}
+
+		spends := make(map[string]float64)
+		for _, budget := range budgets {
+			spend, ok := spends[budget.ProjectID]
+			if !ok {
+				if spend, err = tx.projectSpend(budget.ProjectID, period, prices); err != nil {
+					return err
+				}
+				spends[budget.ProjectID] = spend
+			}
+
+			crossed := budget.CrossedThresholds
+			wasExceeded := budget.Status == domain.BudgetExceeded
+			if budget.Period != period {
+				crossed, wasExceeded = nil, false
+			}
+
+			state := domain.BudgetState{Status: domain.BudgetOK, Spend: spend, Period: period, CrossedThresholds: crossed}
+			for _, threshold := range budget.Thresholds {
+				if spend < threshold*budget.Amount || containsThreshold(crossed, threshold) {
+					continue
+				}
+				state.CrossedThresholds = append(state.CrossedThresholds, threshold)
+				tx.recordEvent(domain.EventBudgetThresholdCrossed, "budget", budget.ID, budget.ProjectID,
+					fmt.Sprintf("budget %s reached %g%% of %.2f %s: spend is %.2f", budget.Name, threshold*100, budget.Amount, prices.Currency, spend))
+				alerts = append(alerts, domain.BudgetAlert{
+					Type:      domain.EventBudgetThresholdCrossed,
+					Budget:    budget,
+					Threshold: threshold,
+					Spend:     spend,
+					Currency:  prices.Currency,
+					CreatedAt: now,
+				})
+			}
+
+			if spend >= budget.Amount {
+				state.Status = domain.BudgetExceeded
+				if !wasExceeded {
+					tx.recordEvent(domain.EventBudgetExceeded, "budget", budget.ID, budget.ProjectID,
+						fmt.Sprintf("budget %s exceeded: spend %.2f %s is over %.2f", budget.Name, spend, prices.Currency, budget.Amount))
+				}
+			}
+
+			if err := tx.budgetRepo.SetState(budget.ID, state); err != nil {
+				return err
+			}
+			budget.Status, budget.Spend, budget.Period, budget.CrossedThresholds = state.Status, state.Spend, state.Period, state.CrossedThresholds
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Webhooks are only sent once the new state is committed
+	for _, alert := range alerts {
+		if alert.Budget.WebhookURL != "" {
+			go deliverBudgetAlert(alert)
+		}
+	}
+
+	return nil
+}
+
+// projectSpend returns the cost of a project's usage in period
+func (s *Service) projectSpend(projectID, period string, prices domain.PriceSheet) (float64, error) {
+	usage, err := s.usageRepo.Get(projectID, period)
+	if domain.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return usageCost(*usage, prices), nil
+}
+
+// containsThreshold reports whether thresholds contains threshold
+func containsThreshold(thresholds []float64, threshold float64) bool {
+	for _, t := range thresholds {
+		if t == threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverBudgetAlert posts an alert to its budget's webhook. Failures are
+// logged and not retried.
+func deliverBudgetAlert(alert domain.BudgetAlert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("Failed to encode budget alert for %s: %v", alert.Budget.ID, err)
+		return
+	}
+
+	resp, err := webhookClient.Post(alert.Budget.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to deliver budget alert for %s: %v", alert.Budget.ID, err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log.Printf("Budget alert webhook for %s returned %s", alert.Budget.ID, resp.Status)
+	}
+}
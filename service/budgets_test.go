@@ -0,0 +1,146 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CreateBudgetValidation(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "budgets"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		req           domain.CreateBudgetRequest
+		expectedField string
+	}{
+		{name: "missing project", req: domain.CreateBudgetRequest{Name: "b", Amount: 10}, expectedField: "project_id"},
+		{name: "zero amount", req: domain.CreateBudgetRequest{ProjectID: project.ID, Name: "b"}, expectedField: "amount"},
+		{name: "negative threshold", req: domain.CreateBudgetRequest{ProjectID: project.ID, Name: "b", Amount: 10, Thresholds: []float64{-0.5}}, expectedField: "thresholds[0]"},
+		{name: "duplicate threshold", req: domain.CreateBudgetRequest{ProjectID: project.ID, Name: "b", Amount: 10, Thresholds: []float64{0.5, 0.5}}, expectedField: "thresholds[1]"},
+		{name: "relative webhook", req: domain.CreateBudgetRequest{ProjectID: project.ID, Name: "b", Amount: 10, WebhookURL: "/hook"}, expectedField: "webhook_url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateBudget(tt.req)
+			require.True(t, domain.IsInvalidInput(err), "expected invalid input, got %v", err)
+			assert.Contains(t, err.Error(), tt.expectedField)
+		})
+	}
+
+	budget, err := svc.CreateBudget(domain.CreateBudgetRequest{ProjectID: project.ID, Name: "default", Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 0.9, 1}, budget.Thresholds)
+	assert.Equal(t, domain.BudgetOK, budget.Status)
+
+	_, err = svc.CreateBudget(domain.CreateBudgetRequest{ProjectID: "missing", Name: "b", Amount: 10})
+	assert.True(t, domain.IsForeignKeyViolation(err))
+}
+
+func TestService_EvaluateBudgets(t *testing.T) {
+	alerts := make(chan domain.BudgetAlert, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert domain.BudgetAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err == nil {
+			alerts <- alert
+		}
+	}))
+	defer hook.Close()
+
+	svc := newTestService(t)
+	svc.config.Prices = &domain.PriceSheet{Currency: "USD", InstanceHour: 1}
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "spender"})
+	require.NoError(t, err)
+	_, err = svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	budget, err := svc.CreateBudget(domain.CreateBudgetRequest{
+		ProjectID: project.ID, Name: "monthly", Amount: 10, Thresholds: []float64{1, 0.5}, WebhookURL: hook.URL,
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	spend := func(hours int) {
+		t.Helper()
+		require.NoError(t, svc.MeterUsage(time.Duration(hours)*time.Hour, now))
+		require.NoError(t, svc.EvaluateBudgets(now))
+	}
+	eventTypes := func() []string {
+		events, err := svc.ListEvents(domain.EventListOptions{ResourceID: budget.ID})
+		require.NoError(t, err)
+		var types []string
+		for _, event := range events {
+			types = append(types, event.Type)
+		}
+		return types
+	}
+	receive := func() domain.BudgetAlert {
+		t.Helper()
+		select {
+		case alert := <-alerts:
+			return alert
+		case <-time.After(5 * time.Second):
+			t.Fatal("no budget alert delivered")
+			return domain.BudgetAlert{}
+		}
+	}
+
+	// Below every threshold
+	spend(4)
+	budget, err = svc.GetBudget(budget.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BudgetOK, budget.Status)
+	assert.InDelta(t, 4, budget.Spend, 1e-9)
+	assert.Equal(t, "2026-03", budget.Period)
+	assert.Empty(t, eventTypes())
+
+	// Crossing half the amount alerts once
+	spend(2)
+	alert := receive()
+	assert.Equal(t, domain.EventBudgetThresholdCrossed, alert.Type)
+	assert.Equal(t, 0.5, alert.Threshold)
+	assert.InDelta(t, 6, alert.Spend, 1e-9)
+	assert.Equal(t, []string{domain.EventBudgetThresholdCrossed}, eventTypes())
+
+	spend(1)
+	assert.Len(t, eventTypes(), 1)
+
+	// Reaching the amount exceeds the budget
+	spend(3)
+	alert = receive()
+	assert.Equal(t, 1.0, alert.Threshold)
+	assert.Equal(t, domain.BudgetExceeded, alert.Budget.Status)
+
+	budget, err = svc.GetBudget(budget.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BudgetExceeded, budget.Status)
+	assert.Equal(t, []float64{0.5, 1}, budget.CrossedThresholds)
+	assert.ElementsMatch(t, []string{domain.EventBudgetThresholdCrossed, domain.EventBudgetThresholdCrossed, domain.EventBudgetExceeded}, eventTypes())
+
+	exceeded, err := svc.ListBudgets(domain.BudgetListOptions{Status: domain.BudgetExceeded})
+	require.NoError(t, err)
+	assert.Len(t, exceeded, 1)
+
+	// A new month starts afresh
+	now = now.AddDate(0, 1, 0)
+	require.NoError(t, svc.EvaluateBudgets(now))
+	budget, err = svc.GetBudget(budget.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BudgetOK, budget.Status)
+	assert.Zero(t, budget.Spend)
+	assert.Empty(t, budget.CrossedThresholds)
+	assert.Equal(t, "2026-04", budget.Period)
+}
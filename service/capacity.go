@@ -0,0 +1,132 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// capacityPools holds the CPU and memory each zone has for instances. It is
+// shared by every copy of the service.
+type capacityPools struct {
+	mu    sync.Mutex
+	pools map[string]domain.ZoneCapacity
+}
+
+func newCapacityPools(pools map[string]domain.ZoneCapacity) *capacityPools {
+	c := &capacityPools{pools: make(map[string]domain.ZoneCapacity)}
+	for zone, capacity := range pools {
+		c.set(zone, capacity)
+	}
+	return c
+}
+
+// pool returns the capacity pool of zone, or false when it is unlimited
+func (c *capacityPools) pool(zone string) (domain.ZoneCapacity, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	capacity, ok := c.pools[zone]
+	return capacity, ok
+}
+
+// set replaces the capacity pool of zone; an empty pool is unlimited
+func (c *capacityPools) set(zone string, capacity domain.ZoneCapacity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if capacity == (domain.ZoneCapacity{}) {
+		delete(c.pools, zone)
+		return
+	}
+	c.pools[zone] = capacity
+}
+
+// zoneUsage sums the CPU and memory the instances in zone take, other than
+// the instance replacingID. Instances take capacity whatever their status,
+// until they are removed.
+func (s *Service) zoneUsage(zone, replacingID string) (domain.ZoneCapacity, error) {
+	var used domain.ZoneCapacity
+	err := s.instanceRepo.Stream(domain.InstanceListOptions{Zone: zone, Fields: []string{"id", "cpu", "memory_mb"}}, func(instance *domain.Instance) error {
+		if instance.ID != replacingID {
+			used.CPU += instance.CPU
+			used.MemoryMB += instance.MemoryMB
+		}
+		return nil
+	})
+	return used, err
+}
+
+// zoneShortfall returns the resource, "cpu" or "memory_mb", zone has too
+// little of left for an instance of the given size replacing replacingID,
+// and how much of it is left. It returns "" when the instance fits.
+func (s *Service) zoneShortfall(zone string, cpu, memoryMB int, replacingID string) (string, int, error) {
+	capacity, ok := s.capacity.pool(zone)
+	if !ok {
+		return "", 0, nil
+	}
+	used, err := s.zoneUsage(zone, replacingID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if capacity.CPU > 0 && used.CPU+cpu > capacity.CPU {
+		return "cpu", max(capacity.CPU-used.CPU, 0), nil
+	}
+	if capacity.MemoryMB > 0 && used.MemoryMB+memoryMB > capacity.MemoryMB {
+		return "memory_mb", max(capacity.MemoryMB-used.MemoryMB, 0), nil
+	}
+	return "", 0, nil
+}
+
+// checkZoneCapacity returns RESOURCE_EXHAUSTED if zone has too little
+// capacity left for an instance of the given size replacing replacingID,
+// suggesting the other zones that are up and have room for it
+func (s *Service) checkZoneCapacity(zone string, cpu, memoryMB int, replacingID string) error {
+	resource, available, err := s.zoneShortfall(zone, cpu, memoryMB, replacingID)
+	if err != nil || resource == "" {
+		return err
+	}
+
+	requested := cpu
+	if resource == "memory_mb" {
+		requested = memoryMB
+	}
+
+	suggested := []string{}
+	for _, other := range s.zones() {
+		if other == zone || s.outages.isDown(other) {
+			continue
+		}
+		short, _, err := s.zoneShortfall(other, cpu, memoryMB, "")
+		if err != nil {
+			return err
+		}
+		if short == "" {
+			suggested = append(suggested, other)
+		}
+	}
+
+	return domain.ZoneStockoutError(zone, resource, requested, available, suggested)
+}
+
+// SetZoneCapacity replaces the capacity pool of a zone, with fields left at
+// 0 unlimited. Instances already in the zone stay even when they exceed the
+// new pool; only new and resized instances must fit in it.
+func (s *Service) SetZoneCapacity(name string, capacity domain.ZoneCapacity) (*domain.Zone, error) {
+	if !s.hasZone(name) {
+		return nil, domain.NotFoundError("zone", name)
+	}
+
+	var v domain.FieldViolations
+	if capacity.CPU < 0 {
+		v.Add("cpu", "must not be negative")
+	}
+	if capacity.MemoryMB < 0 {
+		v.Add("memory_mb", "must not be negative")
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	s.capacity.set(name, capacity)
+	return s.zone(name)
+}
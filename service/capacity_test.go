@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneCapacity(t *testing.T) {
+	svc := newTestService(t)
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "packed"})
+	require.NoError(t, err)
+
+	zone := domain.DefaultZones[0]
+	_, err = svc.SetZoneCapacity(zone, domain.ZoneCapacity{CPU: 4, MemoryMB: 4096})
+	require.NoError(t, err)
+	_, err = svc.SetZoneCapacity(domain.DefaultZones[1], domain.ZoneCapacity{CPU: 1})
+	require.NoError(t, err)
+
+	create := func(name, zone string, cpu int) (*domain.Instance, error) {
+		return svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: cpu, MemoryMB: 1024, Image: "ubuntu", Status: domain.StatusStopped, Zone: zone,
+		})
+	}
+	first, err := create("first", zone, 3)
+	require.NoError(t, err)
+
+	// The zone has 1 CPU left, which only the third zone can beat
+	_, err = create("second", zone, 2)
+	require.Error(t, err)
+	assert.True(t, domain.IsResourceExhausted(err))
+	dirtErr, ok := domain.AsDirtError(err)
+	require.True(t, ok)
+	assert.Equal(t, domain.ReasonZoneStockout, dirtErr.Reason)
+	assert.True(t, dirtErr.Retryable)
+	assert.Equal(t, "cpu", dirtErr.Details["resource"])
+	assert.Equal(t, 1, dirtErr.Details["available"])
+	assert.Equal(t, []string{domain.DefaultZones[2]}, dirtErr.Details["suggested_zones"])
+
+	// Without a requested zone, the instance goes where there is room
+	placed, err := create("placed", "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DefaultZones[2], placed.Zone)
+
+	// Resizing counts the instance's new size in place of its old one
+	cpu := 4
+	resized, err := svc.ResizeInstance(first.ID, domain.ResizeInstanceRequest{CPU: &cpu})
+	require.NoError(t, err)
+	assert.Equal(t, 4, resized.CPU)
+	cpu = 5
+	_, err = svc.ResizeInstance(first.ID, domain.ResizeInstanceRequest{CPU: &cpu})
+	assert.True(t, domain.IsResourceExhausted(err))
+
+	got, err := svc.GetZone(zone)
+	require.NoError(t, err)
+	assert.Equal(t, &domain.ZoneCapacity{CPU: 4, MemoryMB: 4096}, got.Capacity)
+	assert.Equal(t, domain.ZoneCapacity{CPU: 4, MemoryMB: 1024}, got.Used)
+
+	// Clearing the pool leaves the zone unlimited
+	got, err = svc.SetZoneCapacity(zone, domain.ZoneCapacity{})
+	require.NoError(t, err)
+	assert.Nil(t, got.Capacity)
+	_, err = create("second", zone, 2)
+	assert.NoError(t, err)
+
+	_, err = svc.SetZoneCapacity(zone, domain.ZoneCapacity{CPU: -1})
+	assert.True(t, domain.IsInvalidInput(err))
+}
@@ -0,0 +1,117 @@
+package chaos
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Kinds of chaos applied to a request, as reported by Applied
+const (
+	AppliedLatency           = "latency"
+	AppliedLatencyCapped     = "latency_capped"
+	AppliedError             = "error"
+	AppliedBrownout          = "brownout"
+	AppliedFlakyCreate       = "flaky_create"
+	AppliedReplayCreate      = "replay_create"
+	AppliedConnectionFault   = "connection_fault"
+	AppliedMalformedResponse = "malformed_response"
+)
+
+// Decision is one piece of chaos applied to a request. Detail says what was
+// injected, such as the latency or the error.
+type Decision struct {
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Applied collects the kinds of chaos applied while serving a request
+type Applied struct {
+	mu        sync.Mutex
+	kinds     []string
+	decisions []Decision
+	delay     time.Duration
+}
+
+type appliedKey struct{}
+
+// WithApplied returns a context that collects the chaos applied to a request
+// into the returned Applied. A context already collecting it is returned
+// as is, with its Applied, so every collector sees the whole request.
+func WithApplied(ctx context.Context) (context.Context, *Applied) {
+	if a, ok := ctx.Value(appliedKey{}).(*Applied); ok {
+		return ctx, a
+	}
+	a := &Applied{}
+	return context.WithValue(ctx, appliedKey{}, a), a
+}
+
+// Kinds returns the kinds of chaos applied so far, in the order first applied
+func (a *Applied) Kinds() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.kinds...)
+}
+
+// Decisions returns every piece of chaos applied so far, in order
+func (a *Applied) Decisions() []Decision {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Decision(nil), a.decisions...)
+}
+
+// Delay returns how long the request was held up by injected latency
+func (a *Applied) Delay() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.delay
+}
+
+// noteApplied records that chaos of kind was applied to the request of ctx,
+// if it collects applied chaos
+func noteApplied(ctx context.Context, kind string) {
+	noteDecision(ctx, kind, "")
+}
+
+// noteDecision records that chaos of kind was applied to the request of ctx,
+// with a detail of what was injected, if it collects applied chaos
+func noteDecision(ctx context.Context, kind, detail string) {
+	a, ok := ctx.Value(appliedKey{}).(*Applied)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.decisions = append(a.decisions, Decision{Kind: kind, Detail: detail})
+	for _, k := range a.kinds {
+		if k == kind {
+			return
+		}
+	}
+	a.kinds = append(a.kinds, kind)
+}
+
+// noteError records an injected error, passing it through
+func noteError(ctx context.Context, err error) error {
+	if err != nil {
+		noteDecision(ctx, AppliedError, err.Error())
+	}
+	return err
+}
+
+// pause injects latency of d, or less if ctx is done first, recording the
+// time waited
+func pause(ctx context.Context, d time.Duration) {
+	start := time.Now()
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+
+	if a, ok := ctx.Value(appliedKey{}).(*Applied); ok {
+		a.mu.Lock()
+		a.delay += time.Since(start)
+		a.mu.Unlock()
+	}
+}
@@ -0,0 +1,69 @@
+package chaos
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplied(t *testing.T) {
+	c := NewChaosService()
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected []string
+	}{
+		{"no chaos", nil, nil},
+		{"forced latency", map[string]string{LatencyHeader: "1"}, []string{AppliedLatency}},
+		{"forced latency and status", map[string]string{LatencyHeader: "1", ForceStatusHeader: "503"}, []string{AppliedLatency, AppliedError}},
+		{"bypassed", map[string]string{NoChaosHeader: "true", ForceStatusHeader: "503"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/projects", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			ctx, applied := WithApplied(r.Context())
+			r = r.WithContext(ctx)
+
+			c.ApplyProjectsChaos(ctx, r, r.Method)
+			c.ApplyProjectsChaos(ctx, r, r.Method)
+
+			assert.Equal(t, tt.expected, applied.Kinds(), "each kind is listed once")
+		})
+	}
+
+	t.Run("create and connection chaos", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/v1/projects", nil)
+		r.Header.Set(FlakyCreateHeader, FlakyCreateTimeout)
+		r.Header.Set(ReplayCreateHeader, "true")
+		r.Header.Set(ConnectionFaultHeader, ConnectionClose)
+		ctx, applied := WithApplied(r.Context())
+		r = r.WithContext(ctx)
+
+		fault, err := c.ConnectionFault(r)
+		require.NoError(t, err)
+		assert.Equal(t, ConnectionClose, fault)
+		assert.True(t, c.ReplayCreate(ctx, r))
+		assert.Error(t, c.ApplyCreateChaos(ctx, r))
+
+		assert.Equal(t, []string{AppliedConnectionFault, AppliedReplayCreate, AppliedFlakyCreate}, applied.Kinds())
+		assert.Equal(t, []Decision{
+			{Kind: AppliedConnectionFault, Detail: ConnectionClose},
+			{Kind: AppliedReplayCreate},
+			{Kind: AppliedFlakyCreate, Detail: FlakyCreateTimeout},
+		}, applied.Decisions())
+	})
+
+	t.Run("without a collector", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/v1/projects", nil)
+		r.Header.Set(ForceStatusHeader, "503")
+		assert.Error(t, c.ApplyProjectsChaos(context.Background(), r, r.Method))
+	})
+}
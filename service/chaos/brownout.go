@@ -0,0 +1,96 @@
+package chaos
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Brownout curve shapes: how added latency grows with each request in flight
+// beyond the threshold
+const (
+	BrownoutLinear      = "linear"
+	BrownoutQuadratic   = "quadratic"
+	BrownoutExponential = "exponential"
+)
+
+// BrownoutCurve simulates an overloaded control plane by slowing requests in
+// proportion to how many are in flight, rather than by a constant delay. With
+// n requests in flight and excess = n - Threshold, a request is delayed by
+// StepMs*excess (linear), StepMs*excess² (quadratic) or StepMs*(2^excess - 1)
+// (exponential) milliseconds, at most MaxMs.
+type BrownoutCurve struct {
+	Shape     string
+	Threshold int
+	StepMs    int
+	MaxMs     int
+}
+
+// Latency returns the delay for a request arriving with concurrency requests
+// in flight, itself included
+func (b *BrownoutCurve) Latency(concurrency int) time.Duration {
+	excess := float64(concurrency - b.Threshold)
+	if excess <= 0 {
+		return 0
+	}
+
+	var ms float64
+	switch b.Shape {
+	case BrownoutQuadratic:
+		ms = float64(b.StepMs) * excess * excess
+	case BrownoutExponential:
+		ms = float64(b.StepMs) * (math.Pow(2, excess) - 1)
+	default:
+		ms = float64(b.StepMs) * excess
+	}
+
+	ms = math.Min(ms, float64(b.MaxMs))
+	return time.Duration(ms) * time.Millisecond
+}
+
+// loadBrownoutFromEnv loads the brownout curve. Brownout is off unless
+// DIRT_CHAOS_BROWNOUT_CURVE names a shape.
+func loadBrownoutFromEnv() *BrownoutCurve {
+	shape := getEnv("DIRT_CHAOS_BROWNOUT_CURVE", "")
+	switch shape {
+	case "":
+		return nil
+	case BrownoutLinear, BrownoutQuadratic, BrownoutExponential:
+	default:
+		log.Printf("Ignoring invalid DIRT_CHAOS_BROWNOUT_CURVE %q: must be %s, %s or %s",
+			shape, BrownoutLinear, BrownoutQuadratic, BrownoutExponential)
+		return nil
+	}
+
+	return &BrownoutCurve{
+		Shape:     shape,
+		Threshold: int(getIntEnv("DIRT_CHAOS_BROWNOUT_THRESHOLD", 1)),
+		StepMs:    int(getIntEnv("DIRT_CHAOS_BROWNOUT_STEP_MS", 50)),
+		MaxMs:     int(getIntEnv("DIRT_CHAOS_BROWNOUT_MAX_MS", 10000)),
+	}
+}
+
+// Brownout counts a request as in flight until the returned function is
+// called and, when server-wide chaos is enabled with a brownout curve, first
+// delays it by the curve's latency at the current concurrency. Requests that
+// bypass chaos or force their own latency are counted but not delayed.
+func (c *ChaosService) Brownout(ctx context.Context, r *http.Request) func() {
+	concurrency := int(c.inflight.Add(1))
+	release := func() { c.inflight.Add(-1) }
+
+	curve := c.config.Brownout
+	if curve == nil || !c.Status().Enabled {
+		return release
+	}
+	if r.Header.Get(NoChaosHeader) == "true" || r.Header.Get(LatencyHeader) != "" {
+		return release
+	}
+
+	if latency := curve.Latency(concurrency); latency > 0 {
+		noteDecision(ctx, AppliedBrownout, latency.String())
+		pause(ctx, c.fitLatency(ctx, latency))
+	}
+	return release
+}
@@ -0,0 +1,73 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrownoutCurve_Latency(t *testing.T) {
+	tests := []struct {
+		shape       string
+		concurrency int
+		expected    time.Duration
+	}{
+		{BrownoutLinear, 2, 0},
+		{BrownoutLinear, 3, 10 * time.Millisecond},
+		{BrownoutLinear, 5, 30 * time.Millisecond},
+		{BrownoutQuadratic, 5, 90 * time.Millisecond},
+		{BrownoutExponential, 3, 10 * time.Millisecond},
+		{BrownoutExponential, 5, 70 * time.Millisecond},
+		{BrownoutExponential, 100, time.Second},
+	}
+
+	for _, tt := range tests {
+		curve := &BrownoutCurve{Shape: tt.shape, Threshold: 2, StepMs: 10, MaxMs: 1000}
+		assert.Equal(t, tt.expected, curve.Latency(tt.concurrency), "%s at %d", tt.shape, tt.concurrency)
+	}
+}
+
+func TestChaosService_Brownout(t *testing.T) {
+	step := 50 * time.Millisecond
+	service := &ChaosService{
+		config: &Config{
+			Enabled:  true,
+			Brownout: &BrownoutCurve{Shape: BrownoutLinear, StepMs: int(step / time.Millisecond), MaxMs: 1000},
+		},
+		rng: rand.New(rand.NewSource(42)),
+	}
+	req, _ := http.NewRequest("GET", "/v1/projects", nil)
+
+	timed := func(r *http.Request) (func(), time.Duration) {
+		start := time.Now()
+		release := service.Brownout(context.Background(), r)
+		return release, time.Since(start)
+	}
+
+	first, elapsed := timed(req)
+	assert.GreaterOrEqual(t, elapsed, step)
+
+	// A second request in flight alongside the first waits twice as long
+	second, elapsed := timed(req)
+	assert.GreaterOrEqual(t, elapsed, 2*step)
+	second()
+
+	bypass := req.Clone(context.Background())
+	bypass.Header.Set(NoChaosHeader, "true")
+	release, elapsed := timed(bypass)
+	assert.Less(t, elapsed, step)
+	assert.EqualValues(t, 2, service.inflight.Load(), "bypassed requests are still counted")
+	release()
+
+	first()
+	assert.EqualValues(t, 0, service.inflight.Load())
+
+	service.SetEnabled(false)
+	release, elapsed = timed(req)
+	assert.Less(t, elapsed, step)
+	release()
+}
@@ -0,0 +1,157 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// FailureBudget fails a share of all requests to the server while bounding
+// how many failures in a row any one client sees, so that suites retrying
+// failed requests always get to finish.
+//
+// The budget is a token bucket: every request adds Rate tokens, up to
+// MaxConsecutive, and a request fails by spending a whole one. A client that
+// has already failed MaxConsecutive times in a row is let through instead,
+// leaving the token for the next request, so the server as a whole still
+// fails at Rate as long as there are other clients to fail.
+type FailureBudget struct {
+	Rate           float64 `json:"rate"`
+	MaxConsecutive int     `json:"max_consecutive"`
+}
+
+// validate checks the budget settings
+func (b *FailureBudget) validate() error {
+	var v domain.FieldViolations
+	if b.Rate < 0 || b.Rate > 1 {
+		v.Add("rate", fmt.Sprintf("must be between 0 and 1 (got %g)", b.Rate))
+	}
+	if b.MaxConsecutive < 1 {
+		v.Add("max_consecutive", fmt.Sprintf("must be at least 1 (got %d)", b.MaxConsecutive))
+	}
+	return v.Err()
+}
+
+// FailureBudgetStatus is the failure budget in effect, if any, with counts of
+// the requests it has seen since it was set. Spared counts the requests let
+// through only because their client had reached MaxConsecutive.
+type FailureBudgetStatus struct {
+	Budget   *FailureBudget `json:"budget"`
+	Requests uint64         `json:"requests"`
+	Failed   uint64         `json:"failed"`
+	Spared   uint64         `json:"spared"`
+}
+
+// failureBudget is the bucket and per-client streaks of a FailureBudget
+type failureBudget struct {
+	mu     sync.Mutex
+	budget *FailureBudget
+	tokens float64
+
+	// streaks counts the consecutive failures of each client; clients
+	// whose last request succeeded are not kept
+	streaks map[string]int
+
+	requests, failed, spared uint64
+}
+
+// loadFailureBudgetFromEnv loads the failure budget. It is off unless
+// DIRT_CHAOS_FAILURE_BUDGET_RATE is set.
+func loadFailureBudgetFromEnv() *FailureBudget {
+	rate := getFloatEnv("DIRT_CHAOS_FAILURE_BUDGET_RATE", 0.0)
+	if rate <= 0 {
+		return nil
+	}
+
+	budget := &FailureBudget{
+		Rate:           rate,
+		MaxConsecutive: int(getIntEnv("DIRT_CHAOS_FAILURE_BUDGET_MAX_CONSECUTIVE", 2)),
+	}
+	if err := budget.validate(); err != nil {
+		log.Printf("Ignoring invalid chaos failure budget: %v", err)
+		return nil
+	}
+	return budget
+}
+
+// FailureBudget returns the failure budget in effect and its counts
+func (c *ChaosService) FailureBudget() FailureBudgetStatus {
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+
+	status := FailureBudgetStatus{Requests: c.budget.requests, Failed: c.budget.failed, Spared: c.budget.spared}
+	if c.budget.budget != nil {
+		budget := *c.budget.budget
+		status.Budget = &budget
+	}
+	return status
+}
+
+// SetFailureBudget replaces the failure budget at runtime, emptying its
+// bucket and resetting its counts; nil turns it off
+func (c *ChaosService) SetFailureBudget(budget *FailureBudget) error {
+	if budget != nil {
+		if err := budget.validate(); err != nil {
+			return err
+		}
+	}
+
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+
+	c.budget.budget = budget
+	c.budget.tokens = 0
+	c.budget.streaks = make(map[string]int)
+	c.budget.requests, c.budget.failed, c.budget.spared = 0, 0, 0
+	return nil
+}
+
+// SpendFailureBudget returns the error a request from client fails with
+// under the failure budget, or nil to handle it normally. The budget only
+// applies while server-wide chaos is enabled, and not to requests bypassing
+// chaos.
+func (c *ChaosService) SpendFailureBudget(ctx context.Context, r *http.Request, client string) error {
+	if !c.Status().Enabled || r.Header.Get(NoChaosHeader) == "true" {
+		return nil
+	}
+
+	b := &c.budget
+	b.mu.Lock()
+	budget := b.budget
+	if budget == nil {
+		b.mu.Unlock()
+		return nil
+	}
+
+	if b.streaks == nil {
+		b.streaks = make(map[string]int)
+	}
+	b.requests++
+	b.tokens += budget.Rate
+	if capacity := float64(budget.MaxConsecutive); b.tokens > capacity {
+		b.tokens = capacity
+	}
+
+	if b.tokens < 1 {
+		delete(b.streaks, client)
+		b.mu.Unlock()
+		return nil
+	}
+	if b.streaks[client] >= budget.MaxConsecutive {
+		delete(b.streaks, client)
+		b.spared++
+		b.mu.Unlock()
+		return nil
+	}
+
+	b.tokens--
+	b.streaks[client]++
+	b.failed++
+	b.mu.Unlock()
+
+	return noteError(ctx, c.injectError(1, c.config.ErrorTypes, c.config.ErrorWeights))
+}
@@ -0,0 +1,61 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosService_SpendFailureBudget(t *testing.T) {
+	service := &ChaosService{
+		config: &Config{Enabled: true, ErrorTypes: []int{503}},
+		rng:    rand.New(rand.NewSource(42)),
+	}
+	req, _ := http.NewRequest("GET", "/v1/projects", nil)
+	ctx := context.Background()
+
+	// Off until a budget is set
+	assert.NoError(t, service.SpendFailureBudget(ctx, req, "a"))
+
+	require.Error(t, service.SetFailureBudget(&FailureBudget{Rate: 1.5}))
+	require.NoError(t, service.SetFailureBudget(&FailureBudget{Rate: 0.5, MaxConsecutive: 2}))
+
+	// Two clients taking turns fail at the budgeted rate
+	failures := 0
+	for i := 0; i < 100; i++ {
+		client := []string{"a", "b"}[i%2]
+		if service.SpendFailureBudget(ctx, req, client) != nil {
+			failures++
+		}
+	}
+	assert.Equal(t, 50, failures)
+
+	// A lone client never fails more than MaxConsecutive times in a row
+	require.NoError(t, service.SetFailureBudget(&FailureBudget{Rate: 1, MaxConsecutive: 2}))
+	streak, longest := 0, 0
+	for i := 0; i < 30; i++ {
+		if err := service.SpendFailureBudget(ctx, req, "a"); err != nil {
+			streak++
+		} else {
+			streak = 0
+		}
+		if streak > longest {
+			longest = streak
+		}
+	}
+	assert.Equal(t, 2, longest)
+
+	status := service.FailureBudget()
+	assert.Equal(t, uint64(30), status.Requests)
+	assert.Equal(t, uint64(20), status.Failed)
+	assert.Equal(t, uint64(10), status.Spared)
+
+	// Requests bypassing chaos are not counted
+	req.Header.Set(NoChaosHeader, "true")
+	assert.NoError(t, service.SpendFailureBudget(ctx, req, "a"))
+	assert.Equal(t, uint64(30), service.FailureBudget().Requests)
+}
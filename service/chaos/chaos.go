@@ -7,6 +7,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hypertf/dirtcloud-server/domain"
@@ -31,6 +33,27 @@ type Config struct {
 	InstancesErrorRate   float64
 	MetadataErrorRate    float64
 	
+	// Create chaos rates: the chance that a create request fails after its
+	// resource was persisted, and that it is applied twice
+	FlakyCreateRate  float64
+	ReplayCreateRate float64
+	
+	// MalformedResponseRate is the chance that a successful response is
+	// replaced with a malformed one of MalformedResponseKinds
+	MalformedResponseRate  float64
+	MalformedResponseKinds []string
+	
+	// ConnectionFaultRate is the chance that a request's connection is
+	// dropped without a response
+	ConnectionFaultRate float64
+	
+	// Brownout slows requests as concurrency grows; nil disables it
+	Brownout *BrownoutCurve
+	
+	// WriteTimeoutMode is how latency that would outlast a response's
+	// write deadline is handled: WriteTimeoutExtend or WriteTimeoutCap
+	WriteTimeoutMode string
+	
 	// Error configuration
 	ErrorTypes   []int
 	ErrorWeights []int
@@ -38,31 +61,70 @@ type Config struct {
 
 // LatencyRange defines min-max latency in milliseconds
 type LatencyRange struct {
-	Min int
-	Max int
+	Min int `json:"min"`
+	Max int `json:"max"`
 }
 
 // ChaosService provides chaos engineering capabilities
 type ChaosService struct {
 	config *Config
 	rng    *rand.Rand
+
+	mu       sync.RWMutex
+	profiles map[string]*Profile
+	targets  map[string]*Target
+	presets  map[string]bool
+
+	// inflight counts the requests in flight for brownout
+	inflight atomic.Int64
+
+	// deadlines counts latency fitted to write deadlines
+	deadlines deadlineCounters
+
+	// budget fails a share of all requests, capping failures per client
+	budget failureBudget
 }
 
 // NewChaosService creates a new chaos service from environment variables
 func NewChaosService() *ChaosService {
 	config := loadConfigFromEnv()
-	
-	var rng *rand.Rand
-	if config.Enabled {
-		rng = rand.New(rand.NewSource(config.Seed))
-	}
-	
+
+	// Always seed a generator: project profiles apply even when global chaos is
+	// off. Concurrent requests share it, so its source is locked.
+	rng := NewLockedRand(config.Seed)
+
 	return &ChaosService{
-		config: config,
-		rng:    rng,
+		config:   config,
+		rng:      rng,
+		profiles: loadProfilesFromEnv(),
+		targets:  loadTargetsFromEnv(),
+		presets:  loadPresetsFromEnv(),
+		budget:   failureBudget{budget: loadFailureBudgetFromEnv()},
 	}
 }
 
+// Status reports the server-wide chaos switch
+type Status struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Status returns whether server-wide chaos is enabled. Project profiles and
+// client overrides apply either way.
+func (c *ChaosService) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return Status{Enabled: c.config.Enabled}
+}
+
+// SetEnabled turns server-wide chaos on or off at runtime
+func (c *ChaosService) SetEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config.Enabled = enabled
+}
+
 // loadConfigFromEnv loads chaos configuration from environment variables
 func loadConfigFromEnv() *Config {
 	config := &Config{
@@ -92,6 +154,13 @@ func loadConfigFromEnv() *Config {
 	config.ProjectsGetErrorRate = getFloatEnv("DIRT_ERRRATE_PROJECTS_GET", config.ProjectsErrorRate)
 	config.InstancesErrorRate = getFloatEnv("DIRT_ERRRATE_INSTANCES", 0.0)
 	config.MetadataErrorRate = getFloatEnv("DIRT_ERRRATE_METADATA", 0.0)
+	config.FlakyCreateRate = getFloatEnv("DIRT_CHAOS_FLAKY_CREATE_RATE", 0.0)
+	config.ReplayCreateRate = getFloatEnv("DIRT_CHAOS_REPLAY_CREATE_RATE", 0.0)
+	config.MalformedResponseRate = getFloatEnv("DIRT_CHAOS_MALFORMED_RATE", 0.0)
+	config.MalformedResponseKinds = parseMalformedKinds(getEnv("DIRT_CHAOS_MALFORMED_KINDS", ""))
+	config.ConnectionFaultRate = getFloatEnv("DIRT_CHAOS_CONNECTION_FAULT_RATE", 0.0)
+	config.Brownout = loadBrownoutFromEnv()
+	config.WriteTimeoutMode = loadWriteTimeoutModeFromEnv()
 	
 	// Load error types and weights
 	if types := getEnv("DIRT_ERROR_TYPES", ""); types != "" {
@@ -106,108 +175,113 @@ func loadConfigFromEnv() *Config {
 
 // ApplyProjectsChaos applies chaos to projects operations
 func (c *ChaosService) ApplyProjectsChaos(ctx context.Context, r *http.Request, method string) error {
-	if !c.config.Enabled {
-		return nil
-	}
-	
-	// Check for bypass header
-	if r.Header.Get("X-Dirt-No-Chaos") == "true" {
-		return nil
-	}
-	
-	// Apply latency
-	c.applyLatency(ctx, r, c.config.ProjectsLatencyRange)
-	
-	// Apply error injection
 	errorRate := c.config.ProjectsErrorRate
 	if method == "GET" && c.config.ProjectsGetErrorRate > 0 {
 		errorRate = c.config.ProjectsGetErrorRate
 	}
-	
-	return c.maybeInjectError(errorRate)
+
+	return c.apply(ctx, r, c.config.ProjectsLatencyRange, errorRate)
 }
 
 // ApplyInstancesChaos applies chaos to instances operations
 func (c *ChaosService) ApplyInstancesChaos(ctx context.Context, r *http.Request) error {
-	if !c.config.Enabled {
-		return nil
-	}
-	
-	// Check for bypass header
-	if r.Header.Get("X-Dirt-No-Chaos") == "true" {
-		return nil
-	}
-	
-	// Apply latency
-	c.applyLatency(ctx, r, c.config.InstancesLatencyRange)
-	
-	// Apply error injection
-	return c.maybeInjectError(c.config.InstancesErrorRate)
+	return c.apply(ctx, r, c.config.InstancesLatencyRange, c.config.InstancesErrorRate)
 }
 
 // ApplyMetadataChaos applies chaos to metadata operations
 func (c *ChaosService) ApplyMetadataChaos(ctx context.Context, r *http.Request) error {
-	if !c.config.Enabled {
+	return c.apply(ctx, r, c.config.MetadataLatencyRange, c.config.MetadataErrorRate)
+}
+
+// apply applies chaos to a request. Client-forced controls take precedence over
+// active presets, which are layered on a target for the addressed resource,
+// then the request's chaos profile, then the global config.
+func (c *ChaosService) apply(ctx context.Context, r *http.Request, resourceRange *LatencyRange, errorRate float64) error {
+	// Check for bypass header
+	if r.Header.Get(NoChaosHeader) == "true" {
 		return nil
 	}
-	
-	// Check for bypass header
-	if r.Header.Get("X-Dirt-No-Chaos") == "true" {
+
+	o, err := ParseOverrides(r)
+	if err != nil {
+		return err
+	}
+
+	if o.Latency != nil {
+		c.sleep(ctx, o.Latency)
+	}
+	if o.Status != 0 {
+		return noteError(ctx, o.forcedError())
+	}
+
+	if err := c.applyPresets(ctx, r, o.Latency != nil); err != nil {
+		return noteError(ctx, err)
+	}
+
+	if t := c.requestTarget(ctx, r); t != nil {
+		return noteError(ctx, c.applyTarget(ctx, t, o.Latency != nil))
+	}
+
+	if p := c.requestProfile(ctx, r); p != nil {
+		return noteError(ctx, c.applyProfile(ctx, p, o.Latency != nil))
+	}
+
+	if !c.Status().Enabled {
 		return nil
 	}
-	
-	// Apply latency
-	c.applyLatency(ctx, r, c.config.MetadataLatencyRange)
-	
+
+	// Apply latency unless the client forced its own
+	if o.Latency == nil {
+		c.applyLatency(ctx, resourceRange)
+	}
+
 	// Apply error injection
-	return c.maybeInjectError(c.config.MetadataErrorRate)
+	return noteError(ctx, c.maybeInjectError(errorRate))
 }
 
 // applyLatency applies latency injection
-func (c *ChaosService) applyLatency(ctx context.Context, r *http.Request, resourceRange *LatencyRange) {
-	// Check for forced latency header
-	if forcedLatency := r.Header.Get("X-Dirt-Latency"); forcedLatency != "" {
-		if ms, err := strconv.Atoi(forcedLatency); err == nil && ms > 0 {
-			select {
-			case <-time.After(time.Duration(ms) * time.Millisecond):
-			case <-ctx.Done():
-			}
-			return
-		}
-	}
-	
+func (c *ChaosService) applyLatency(ctx context.Context, resourceRange *LatencyRange) {
 	// Determine latency range to use (resource-specific overrides global)
 	latencyRange := c.config.GlobalLatencyRange
 	if resourceRange != nil {
 		latencyRange = resourceRange
 	}
-	
+
+	c.sleep(ctx, latencyRange)
+}
+
+// sleep waits for a random latency within a range, or until ctx is done
+func (c *ChaosService) sleep(ctx context.Context, latencyRange *LatencyRange) {
 	if latencyRange == nil {
 		return
 	}
-	
+
 	// Calculate random latency within range
 	latency := latencyRange.Min
 	if latencyRange.Max > latencyRange.Min {
 		latency += c.rng.Intn(latencyRange.Max - latencyRange.Min + 1)
 	}
-	
+
 	if latency > 0 {
-		select {
-		case <-time.After(time.Duration(latency) * time.Millisecond):
-		case <-ctx.Done():
-		}
+		d := time.Duration(latency) * time.Millisecond
+		noteDecision(ctx, AppliedLatency, d.String())
+		pause(ctx, c.fitLatency(ctx, d))
 	}
 }
 
 // maybeInjectError randomly injects an error based on error rate
 func (c *ChaosService) maybeInjectError(errorRate float64) error {
+	return c.injectError(errorRate, c.config.ErrorTypes, c.config.ErrorWeights)
+}
+
+// injectError randomly injects one of the given error types based on error rate
+func (c *ChaosService) injectError(errorRate float64, errorTypes []int, errorWeights []int) error {
 	if errorRate <= 0.0 || c.rng.Float64() > errorRate {
 		return nil
 	}
-	
+
 	// Select error type based on weights
-	errorCode := c.selectWeightedErrorType()
+	errorCode := c.selectWeighted(errorTypes, errorWeights)
 	
 	switch errorCode {
 	case 429:
@@ -223,38 +297,43 @@ func (c *ChaosService) maybeInjectError(errorRate float64) error {
 
 // selectWeightedErrorType selects an error type based on configured weights
 func (c *ChaosService) selectWeightedErrorType() int {
-	if len(c.config.ErrorTypes) == 0 {
+	return c.selectWeighted(c.config.ErrorTypes, c.config.ErrorWeights)
+}
+
+// selectWeighted selects one of errorTypes based on errorWeights
+func (c *ChaosService) selectWeighted(errorTypes []int, errorWeights []int) int {
+	if len(errorTypes) == 0 {
 		return 500
 	}
-	
-	if len(c.config.ErrorWeights) != len(c.config.ErrorTypes) {
+
+	if len(errorWeights) != len(errorTypes) {
 		// If weights don't match types, use uniform distribution
-		return c.config.ErrorTypes[c.rng.Intn(len(c.config.ErrorTypes))]
+		return errorTypes[c.rng.Intn(len(errorTypes))]
 	}
-	
+
 	// Calculate total weight
 	totalWeight := 0
-	for _, weight := range c.config.ErrorWeights {
+	for _, weight := range errorWeights {
 		totalWeight += weight
 	}
-	
+
 	if totalWeight == 0 {
-		return c.config.ErrorTypes[0]
+		return errorTypes[0]
 	}
-	
+
 	// Select based on weights
 	target := c.rng.Intn(totalWeight)
 	currentWeight := 0
-	
-	for i, weight := range c.config.ErrorWeights {
+
+	for i, weight := range errorWeights {
 		currentWeight += weight
 		if target < currentWeight {
-			return c.config.ErrorTypes[i]
+			return errorTypes[i]
 		}
 	}
-	
+
 	// Fallback
-	return c.config.ErrorTypes[0]
+	return errorTypes[0]
 }
 
 // Utility functions for parsing environment variables
@@ -0,0 +1,63 @@
+package chaos
+
+import (
+	"net"
+	"net/http"
+)
+
+// Connection faults: ways to drop a request's connection without any HTTP
+// response, so clients exercise transport-level retries
+const (
+	// ConnectionReset aborts the connection with a TCP RST
+	ConnectionReset = "reset"
+	// ConnectionClose closes the connection cleanly before responding
+	ConnectionClose = "close"
+)
+
+// ConnectionFault returns the fault to inflict on a request's connection
+// instead of handling it, or "" to handle it normally. The per-request header
+// forces a fault; otherwise, with server-wide chaos enabled,
+// DIRT_CHAOS_CONNECTION_FAULT_RATE sets the chance of one, to which active
+// presets add.
+func (c *ChaosService) ConnectionFault(r *http.Request) (string, error) {
+	if r.Header.Get(NoChaosHeader) == "true" {
+		return "", nil
+	}
+
+	o, err := ParseOverrides(r)
+	if err != nil {
+		return "", err
+	}
+	if o.ConnectionFault != "" {
+		noteDecision(r.Context(), AppliedConnectionFault, o.ConnectionFault)
+		return o.ConnectionFault, nil
+	}
+
+	rate := 0.0
+	if c.Status().Enabled {
+		rate = c.config.ConnectionFaultRate
+	}
+	rate = c.presetRate(r, rate, func(p *Preset) float64 { return p.ConnectionFaultRate })
+	if !c.roll(rate) {
+		return "", nil
+	}
+
+	fault := ConnectionClose
+	if c.rng.Intn(2) == 0 {
+		fault = ConnectionReset
+	}
+	noteDecision(r.Context(), AppliedConnectionFault, fault)
+	return fault, nil
+}
+
+// DropConnection inflicts fault on a hijacked connection
+func DropConnection(conn net.Conn, fault string) error {
+	if tcp, ok := conn.(*net.TCPConn); ok && fault == ConnectionReset {
+		// Discarding unsent data on close makes the kernel send a RST
+		// instead of a FIN
+		if err := tcp.SetLinger(0); err != nil {
+			return err
+		}
+	}
+	return conn.Close()
+}
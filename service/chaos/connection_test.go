@@ -0,0 +1,54 @@
+package chaos
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosService_ConnectionFault(t *testing.T) {
+	tests := []struct {
+		name        string
+		enabled     bool
+		rate        float64
+		headers     map[string]string
+		expected    []string
+		expectError bool
+	}{
+		{name: "disabled", rate: 1},
+		{name: "header forces a reset", headers: map[string]string{ConnectionFaultHeader: "reset"}, expected: []string{ConnectionReset}},
+		{name: "header forces a close", headers: map[string]string{ConnectionFaultHeader: "Close"}, expected: []string{ConnectionClose}},
+		{name: "unknown fault", headers: map[string]string{ConnectionFaultHeader: "hang"}, expectError: true},
+		{name: "global rate", enabled: true, rate: 1, expected: []string{ConnectionReset, ConnectionClose}},
+		{name: "bypass header", headers: map[string]string{NoChaosHeader: "true", ConnectionFaultHeader: "reset"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &ChaosService{
+				config: &Config{Enabled: tt.enabled, ConnectionFaultRate: tt.rate},
+				rng:    rand.New(rand.NewSource(42)),
+			}
+			req, _ := http.NewRequest("GET", "/v1/projects", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			fault, err := service.ConnectionFault(req)
+			if tt.expectError {
+				assert.True(t, domain.IsInvalidInput(err))
+				return
+			}
+			require.NoError(t, err)
+			if len(tt.expected) == 0 {
+				assert.Empty(t, fault)
+				return
+			}
+			assert.Contains(t, tt.expected, fault)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Chaos applied to create requests once their resource has been persisted
+
+// Flaky create outcomes: how a create request that persisted its resource
+// reports failure
+const (
+	FlakyCreateError   = "error"
+	FlakyCreateTimeout = "timeout"
+)
+
+// ApplyCreateChaos runs after a create request has persisted its resource and
+// may make the request fail anyway, leaving the client unsure whether the
+// resource exists until it reads it back. The per-request header forces an
+// outcome; otherwise the request's chaos profile or, with server-wide chaos
+// enabled, the global config sets the chance of one, to which active presets
+// add.
+func (c *ChaosService) ApplyCreateChaos(ctx context.Context, r *http.Request) error {
+	if r.Header.Get(NoChaosHeader) == "true" {
+		return nil
+	}
+
+	// Invalid headers were already rejected before the create ran
+	if o, err := ParseOverrides(r); err == nil && o.FlakyCreate != "" {
+		noteDecision(ctx, AppliedFlakyCreate, o.FlakyCreate)
+		return flakyCreateError(o.FlakyCreate)
+	}
+
+	rate := c.createRate(ctx, r, c.config.FlakyCreateRate,
+		func(p *Profile) float64 { return p.FlakyCreateRate },
+		func(p *Preset) float64 { return p.FlakyCreateRate })
+	if !c.roll(rate) {
+		return nil
+	}
+
+	outcome := FlakyCreateTimeout
+	if c.rng.Intn(2) == 0 {
+		outcome = FlakyCreateError
+	}
+	noteDecision(ctx, AppliedFlakyCreate, outcome)
+	return flakyCreateError(outcome)
+}
+
+// flakyCreateError returns the error reported for a flaky create outcome. It
+// matches the errors of genuine failures so clients cannot tell them apart.
+func flakyCreateError(outcome string) error {
+	if outcome == FlakyCreateTimeout {
+		return domain.DeadlineExceededError("chaos: request timed out")
+	}
+	return domain.InternalError("chaos: internal server error")
+}
+
+// ReplayCreate reports whether a create request that persisted its resource
+// should be applied a second time, as a write replayed by a retrying proxy
+// would be. Where instance names may repeat, the replay leaves a duplicate
+// with the same name and a new ID while the client only hears about the
+// first; where they are unique, the client hears the replay's conflict
+// instead. The per-request header forces
+// a replay; otherwise rates are chosen as for ApplyCreateChaos.
+func (c *ChaosService) ReplayCreate(ctx context.Context, r *http.Request) bool {
+	if r.Header.Get(NoChaosHeader) == "true" {
+		return false
+	}
+
+	if o, err := ParseOverrides(r); err == nil && o.ReplayCreate {
+		noteApplied(ctx, AppliedReplayCreate)
+		return true
+	}
+
+	rate := c.createRate(ctx, r, c.config.ReplayCreateRate,
+		func(p *Profile) float64 { return p.ReplayCreateRate },
+		func(p *Preset) float64 { return p.ReplayCreateRate })
+	if !c.roll(rate) {
+		return false
+	}
+	noteApplied(ctx, AppliedReplayCreate)
+	return true
+}
+
+// createRate returns the chance of a create chaos behavior: the request's
+// chaos profile sets it, or with server-wide chaos enabled the global rate,
+// and active presets add to it
+func (c *ChaosService) createRate(ctx context.Context, r *http.Request, global float64, fromProfile func(*Profile) float64, fromPreset func(*Preset) float64) float64 {
+	rate := 0.0
+	if p := c.requestProfile(ctx, r); p != nil {
+		rate = fromProfile(p)
+	} else if c.Status().Enabled {
+		rate = global
+	}
+	return c.presetRate(r, rate, fromPreset)
+}
+
+// roll reports whether an event with the given chance happens
+func (c *ChaosService) roll(rate float64) bool {
+	return rate > 0 && c.rng.Float64() <= rate
+}
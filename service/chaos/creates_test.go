@@ -0,0 +1,108 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosService_ApplyCreateChaos(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		rate     float64
+		profile  *Profile
+		headers  map[string]string
+		expected []string
+	}{
+		{name: "disabled", rate: 1},
+		{name: "header forces an error", headers: map[string]string{FlakyCreateHeader: "error"}, expected: []string{domain.ErrorCodeInternalError}},
+		{name: "header forces a timeout", headers: map[string]string{FlakyCreateHeader: "timeout"}, expected: []string{domain.ErrorCodeDeadlineExceeded}},
+		{
+			name:     "global rate when enabled",
+			enabled:  true,
+			rate:     1,
+			expected: []string{domain.ErrorCodeInternalError, domain.ErrorCodeDeadlineExceeded},
+		},
+		{
+			name:     "profile rate without global chaos",
+			profile:  &Profile{Name: "flaky", FlakyCreateRate: 1},
+			expected: []string{domain.ErrorCodeInternalError, domain.ErrorCodeDeadlineExceeded},
+		},
+		{name: "profile replaces the global rate", enabled: true, rate: 1, profile: &Profile{Name: "steady"}},
+		{
+			name:    "bypass header",
+			headers: map[string]string{NoChaosHeader: "true", FlakyCreateHeader: "error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &ChaosService{
+				config: &Config{Enabled: tt.enabled, FlakyCreateRate: tt.rate},
+				rng:    rand.New(rand.NewSource(42)),
+			}
+			ctx := context.Background()
+			if tt.profile != nil {
+				require.NoError(t, service.SetProfile(tt.profile))
+				ctx = WithProfile(ctx, tt.profile.Name)
+			}
+
+			req, _ := http.NewRequest("POST", "/v1/instances", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			err := service.ApplyCreateChaos(ctx, req)
+			if len(tt.expected) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, tt.expected, err.(*domain.DirtError).Code)
+		})
+	}
+}
+
+func TestChaosService_ReplayCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		rate     float64
+		profile  *Profile
+		headers  map[string]string
+		expected bool
+	}{
+		{name: "disabled", rate: 1},
+		{name: "header forces a replay", headers: map[string]string{ReplayCreateHeader: "true"}, expected: true},
+		{name: "global rate when enabled", enabled: true, rate: 1, expected: true},
+		{name: "profile rate without global chaos", profile: &Profile{Name: "replays", ReplayCreateRate: 1}, expected: true},
+		{name: "bypass header", headers: map[string]string{NoChaosHeader: "true", ReplayCreateHeader: "true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &ChaosService{
+				config: &Config{Enabled: tt.enabled, ReplayCreateRate: tt.rate},
+				rng:    rand.New(rand.NewSource(42)),
+			}
+			ctx := context.Background()
+			if tt.profile != nil {
+				require.NoError(t, service.SetProfile(tt.profile))
+				ctx = WithProfile(ctx, tt.profile.Name)
+			}
+
+			req, _ := http.NewRequest("POST", "/v1/instances", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			assert.Equal(t, tt.expected, service.ReplayCreate(ctx, req))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package chaos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week, each held as a bit set of matching values
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domAny and dowAny record a * day field. As in cron, when both day
+	// fields are restricted a time matches if either does.
+	domAny, dowAny bool
+}
+
+// cronFields are the names and bounds of the fields of a cron expression.
+// Day of week runs from 0 (Sunday) to 7, which is Sunday again.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseCron parses a cron expression such as "*/15 9-17 * * 1-5". Fields
+// take *, values, ranges, /steps and comma-separated lists of those.
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("must have 5 fields (minute, hour, day of month, month, day of week), got %d", len(fields))
+	}
+
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", cronFields[i].name, err)
+		}
+		sets[i] = set
+	}
+
+	// Fold Sunday as 7 into Sunday as 0
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+
+	return &cronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses one field of a cron expression into a bit set of the
+// values in [min, max] it matches
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		span, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			span, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case span == "*":
+		case strings.Contains(span, "-"):
+			bounds := strings.SplitN(span, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", span)
+			}
+		default:
+			n, err := strconv.Atoi(span)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", span)
+			}
+			// A single value with a step, such as 5/15, runs to the maximum
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// matches reports whether the schedule fires in the minute of t
+func (s *cronSchedule) matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 || s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// openAt reports whether a window of length d, opened each time the schedule
+// fires, is open at t. Schedules are evaluated in UTC.
+func (s *cronSchedule) openAt(t time.Time, d time.Duration) bool {
+	t = t.UTC()
+	for fired := t.Truncate(time.Minute); t.Sub(fired) < d; fired = fired.Add(-time.Minute) {
+		if s.matches(fired) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package chaos
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// How injected latency that would outlast a request's write deadline is
+// handled
+const (
+	// WriteTimeoutExtend pushes the write deadline back to fit the latency
+	WriteTimeoutExtend = "extend"
+	// WriteTimeoutCap shortens the latency to end before the deadline
+	WriteTimeoutCap = "cap"
+)
+
+// WriteTimeoutHeadroom is the time left after injected latency for the
+// handler to write its response before the write deadline
+const WriteTimeoutHeadroom = time.Second
+
+// writeDeadline is when the server stops writing a request's response
+type writeDeadline struct {
+	at     time.Time
+	extend func(time.Time) error
+}
+
+type deadlineKey struct{}
+
+// WithWriteDeadline returns a context whose injected latency is fitted to a
+// response write deadline at deadline. Extend moves the deadline, returning
+// an error if it cannot.
+func WithWriteDeadline(ctx context.Context, deadline time.Time, extend func(time.Time) error) context.Context {
+	return context.WithValue(ctx, deadlineKey{}, &writeDeadline{at: deadline, extend: extend})
+}
+
+// DeadlineStats counts latency that would have outlasted a write deadline
+type DeadlineStats struct {
+	Extended uint64
+	Capped   uint64
+}
+
+// deadlineCounters are the running totals behind DeadlineStats
+type deadlineCounters struct {
+	extended atomic.Uint64
+	capped   atomic.Uint64
+}
+
+// DeadlineStats returns how often injected latency extended a write deadline
+// or was capped to fit one
+func (c *ChaosService) DeadlineStats() DeadlineStats {
+	return DeadlineStats{Extended: c.deadlines.extended.Load(), Capped: c.deadlines.capped.Load()}
+}
+
+// fitLatency returns how long to inject latency for the request of ctx.
+// Latency that leaves less than WriteTimeoutHeadroom before the write
+// deadline extends the deadline or, in WriteTimeoutCap mode or when the
+// deadline cannot be moved, is cut short.
+func (c *ChaosService) fitLatency(ctx context.Context, latency time.Duration) time.Duration {
+	d, ok := ctx.Value(deadlineKey{}).(*writeDeadline)
+	if !ok || latency <= 0 {
+		return latency
+	}
+
+	now := time.Now()
+	end := now.Add(latency + WriteTimeoutHeadroom)
+	if !end.After(d.at) {
+		return latency
+	}
+
+	if c.config.WriteTimeoutMode != WriteTimeoutCap && d.extend != nil && d.extend(end) == nil {
+		d.at = end
+		c.deadlines.extended.Add(1)
+		return latency
+	}
+
+	c.deadlines.capped.Add(1)
+	capped := d.at.Sub(now) - WriteTimeoutHeadroom
+	if capped < 0 {
+		capped = 0
+	}
+	noteDecision(ctx, AppliedLatencyCapped, capped.String())
+	return capped
+}
+
+// loadWriteTimeoutModeFromEnv loads how latency is fitted to write deadlines
+func loadWriteTimeoutModeFromEnv() string {
+	mode := getEnv("DIRT_CHAOS_WRITE_TIMEOUT_MODE", WriteTimeoutExtend)
+	if mode != WriteTimeoutExtend && mode != WriteTimeoutCap {
+		log.Printf("Ignoring invalid DIRT_CHAOS_WRITE_TIMEOUT_MODE %q: must be %s or %s",
+			mode, WriteTimeoutExtend, WriteTimeoutCap)
+		return WriteTimeoutExtend
+	}
+	return mode
+}
@@ -0,0 +1,62 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChaosService_fitLatency(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		latency   time.Duration
+		extendErr error
+		expected  time.Duration
+		extended  bool
+		capped    bool
+	}{
+		{name: "within deadline", mode: WriteTimeoutExtend, latency: 5 * time.Second, expected: 5 * time.Second},
+		{name: "extended", mode: WriteTimeoutExtend, latency: 20 * time.Second, expected: 20 * time.Second, extended: true},
+		{name: "capped", mode: WriteTimeoutCap, latency: 20 * time.Second, expected: 9 * time.Second, capped: true},
+		{
+			name: "deadline cannot move", mode: WriteTimeoutExtend, latency: 20 * time.Second,
+			extendErr: errors.New("not supported"), expected: 9 * time.Second, capped: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ChaosService{config: &Config{WriteTimeoutMode: tt.mode}}
+			var movedTo time.Time
+			ctx, applied := WithApplied(context.Background())
+			ctx = WithWriteDeadline(ctx, time.Now().Add(10*time.Second), func(deadline time.Time) error {
+				if tt.extendErr == nil {
+					movedTo = deadline
+				}
+				return tt.extendErr
+			})
+
+			latency := c.fitLatency(ctx, tt.latency)
+
+			assert.InDelta(t, tt.expected, latency, float64(100*time.Millisecond))
+			assert.Equal(t, tt.extended, !movedTo.IsZero())
+			if tt.extended {
+				assert.WithinDuration(t, time.Now().Add(tt.latency+WriteTimeoutHeadroom), movedTo, 100*time.Millisecond)
+			}
+			stats := c.DeadlineStats()
+			assert.Equal(t, tt.extended, stats.Extended == 1)
+			assert.Equal(t, tt.capped, stats.Capped == 1)
+			assert.Equal(t, tt.capped, len(applied.Kinds()) == 1, "capping is noted as applied chaos")
+		})
+	}
+}
+
+func TestChaosService_fitLatency_NoDeadline(t *testing.T) {
+	c := &ChaosService{config: &Config{WriteTimeoutMode: WriteTimeoutCap}}
+	assert.Equal(t, time.Minute, c.fitLatency(context.Background(), time.Minute))
+	assert.Zero(t, c.DeadlineStats().Capped)
+}
@@ -0,0 +1,119 @@
+package chaos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Malformed response kinds. Each keeps the 200 status so that clients must
+// notice the problem from the body.
+const (
+	// MalformedInvalidJSON truncates the JSON body part way through
+	MalformedInvalidJSON = "invalid_json"
+	// MalformedHTML replaces the body with an HTML error page, as a proxy
+	// in front of the API might return
+	MalformedHTML = "html"
+	// MalformedGzip declares a gzip encoded body that cannot be decompressed
+	MalformedGzip = "gzip"
+)
+
+// malformedKinds lists every malformed response kind
+var malformedKinds = []string{MalformedInvalidJSON, MalformedHTML, MalformedGzip}
+
+// validMalformedKind reports whether kind is a malformed response kind
+func validMalformedKind(kind string) bool {
+	for _, k := range malformedKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMalformedKinds parses DIRT_CHAOS_MALFORMED_KINDS, a comma separated
+// list of kinds, dropping unknown ones. Without a valid kind all are used.
+func parseMalformedKinds(value string) []string {
+	var kinds []string
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if !validMalformedKind(kind) {
+			log.Printf("Ignoring unknown malformed response kind %q", kind)
+			continue
+		}
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		return malformedKinds
+	}
+	return kinds
+}
+
+// MalformedResponse returns the kind of malformed response to send instead
+// of a request's successful response, or "" to send it unchanged. The
+// per-request header forces a kind; otherwise, with server-wide chaos
+// enabled, DIRT_CHAOS_MALFORMED_RATE sets the chance of one.
+func (c *ChaosService) MalformedResponse(r *http.Request) (string, error) {
+	if r.Header.Get(NoChaosHeader) == "true" {
+		return "", nil
+	}
+
+	o, err := ParseOverrides(r)
+	if err != nil {
+		return "", err
+	}
+	if o.MalformedResponse != "" {
+		noteDecision(r.Context(), AppliedMalformedResponse, o.MalformedResponse)
+		return o.MalformedResponse, nil
+	}
+
+	if !c.Status().Enabled || !c.roll(c.config.MalformedResponseRate) {
+		return "", nil
+	}
+
+	kinds := c.config.MalformedResponseKinds
+	if len(kinds) == 0 {
+		kinds = malformedKinds
+	}
+	kind := kinds[c.rng.Intn(len(kinds))]
+	noteDecision(r.Context(), AppliedMalformedResponse, kind)
+	return kind, nil
+}
+
+// Malform rewrites a response body as a malformed response of kind, setting
+// the headers that go with it
+func Malform(kind string, header http.Header, body []byte) []byte {
+	header.Del("Content-Length")
+
+	switch kind {
+	case MalformedHTML:
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		return []byte("<html>\n<head><title>502 Bad Gateway</title></head>\n" +
+			"<body>\n<center><h1>502 Bad Gateway</h1></center>\n<hr><center>nginx</center>\n</body>\n</html>\n")
+	case MalformedGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		zw.Close()
+
+		// Cut the stream short and flip bits in what remains of the
+		// compressed data, past the 10 byte gzip header
+		corrupt := buf.Bytes()[:buf.Len()/2]
+		for i := 10; i < len(corrupt); i += 3 {
+			corrupt[i] ^= 0xff
+		}
+		header.Set("Content-Encoding", "gzip")
+		return corrupt
+	default:
+		body = bytes.TrimSpace(body)
+		if len(body) < 2 {
+			return []byte(`{"`)
+		}
+		return body[:len(body)/2]
+	}
+}
@@ -0,0 +1,95 @@
+package chaos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMalform(t *testing.T) {
+	body := []byte(`{"id":"p-1","name":"web","labels":{"env":"prod"}}` + "\n")
+
+	t.Run("invalid json", func(t *testing.T) {
+		header := http.Header{"Content-Type": []string{"application/json"}}
+		out := Malform(MalformedInvalidJSON, header, body)
+		assert.False(t, json.Valid(out))
+		assert.Equal(t, "application/json", header.Get("Content-Type"))
+	})
+
+	t.Run("html", func(t *testing.T) {
+		header := http.Header{"Content-Type": []string{"application/json"}}
+		out := Malform(MalformedHTML, header, body)
+		assert.Contains(t, string(out), "<html>")
+		assert.Equal(t, "text/html; charset=utf-8", header.Get("Content-Type"))
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		header := http.Header{"Content-Length": []string{"50"}}
+		out := Malform(MalformedGzip, header, body)
+		assert.Equal(t, "gzip", header.Get("Content-Encoding"))
+		assert.Empty(t, header.Get("Content-Length"))
+
+		decoded, err := func() ([]byte, error) {
+			zr, err := gzip.NewReader(bytes.NewReader(out))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(zr)
+		}()
+		assert.Error(t, err)
+		assert.NotEqual(t, body, decoded)
+	})
+}
+
+func TestChaosService_MalformedResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		enabled     bool
+		rate        float64
+		kinds       []string
+		header      string
+		expected    string
+		expectError bool
+	}{
+		{name: "disabled", rate: 1},
+		{name: "header", header: "HTML", expected: MalformedHTML},
+		{name: "unknown header kind", header: "xml", expectError: true},
+		{name: "global rate", enabled: true, rate: 1, kinds: []string{MalformedGzip}, expected: MalformedGzip},
+		{name: "zero rate", enabled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &ChaosService{
+				config: &Config{Enabled: tt.enabled, MalformedResponseRate: tt.rate, MalformedResponseKinds: tt.kinds},
+				rng:    rand.New(rand.NewSource(42)),
+			}
+			req, _ := http.NewRequest("GET", "/v1/projects", nil)
+			if tt.header != "" {
+				req.Header.Set(MalformedResponseHeader, tt.header)
+			}
+
+			kind, err := service.MalformedResponse(req)
+			if tt.expectError {
+				assert.True(t, domain.IsInvalidInput(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, kind)
+		})
+	}
+}
+
+func TestParseMalformedKinds(t *testing.T) {
+	assert.Equal(t, malformedKinds, parseMalformedKinds(""))
+	assert.Equal(t, []string{MalformedHTML, MalformedGzip}, parseMalformedKinds("html, xml, gzip"))
+	assert.Equal(t, malformedKinds, parseMalformedKinds("xml"))
+}
@@ -0,0 +1,175 @@
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Per-request chaos control headers
+const (
+	// NoChaosHeader set to "true" disables all chaos for the request
+	NoChaosHeader = "X-Dirt-No-Chaos"
+	// LatencyHeader forces a latency in milliseconds, e.g. "250" or "100-500"
+	LatencyHeader = "X-Dirt-Latency"
+	// ForceStatusHeader forces an error response with the given 4xx or 5xx status
+	ForceStatusHeader = "X-Dirt-Force-Status"
+	// ForceBodyHeader replaces the body of a forced error response
+	ForceBodyHeader = "X-Dirt-Force-Body"
+	// FlakyCreateHeader makes a create request persist its resource and then
+	// fail anyway, with "error" for a 500 or "timeout" for a 504
+	FlakyCreateHeader = "X-Dirt-Flaky-Create"
+	// ReplayCreateHeader set to "true" makes a create request be applied
+	// twice, leaving a duplicate resource
+	ReplayCreateHeader = "X-Dirt-Replay-Create"
+	// MalformedResponseHeader replaces a successful response with a
+	// malformed one of the given kind
+	MalformedResponseHeader = "X-Dirt-Malformed-Response"
+	// ConnectionFaultHeader drops the request's connection without a
+	// response, with "reset" for a TCP RST or "close" for a clean close
+	ConnectionFaultHeader = "X-Dirt-Connection-Fault"
+)
+
+// maxForcedLatencyMs bounds the latency a client may force on a request
+const maxForcedLatencyMs = 60000
+
+// Overrides are chaos controls requested by a client for a single request.
+// They apply whether or not chaos is enabled on the server.
+type Overrides struct {
+	Latency      *LatencyRange
+	Status       int
+	Body         string
+	FlakyCreate  string
+	ReplayCreate bool
+
+	MalformedResponse string
+	ConnectionFault   string
+}
+
+// ParseOverrides reads and validates the per-request chaos control headers
+func ParseOverrides(r *http.Request) (*Overrides, error) {
+	o := &Overrides{}
+	var v domain.FieldViolations
+
+	if value := strings.TrimSpace(r.Header.Get(LatencyHeader)); value != "" {
+		o.Latency = parseForcedLatency(value)
+		if o.Latency == nil {
+			v.Add(LatencyHeader, fmt.Sprintf("must be milliseconds or a min-max range up to %d (got %q)", maxForcedLatencyMs, value))
+		}
+	}
+
+	if value := strings.TrimSpace(r.Header.Get(ForceStatusHeader)); value != "" {
+		status, err := strconv.Atoi(value)
+		if err != nil || status < 400 || status > 599 {
+			v.Add(ForceStatusHeader, fmt.Sprintf("must be an HTTP status between 400 and 599 (got %q)", value))
+		}
+		o.Status = status
+	}
+
+	if value := strings.TrimSpace(r.Header.Get(FlakyCreateHeader)); value != "" {
+		o.FlakyCreate = strings.ToLower(value)
+		if o.FlakyCreate != FlakyCreateError && o.FlakyCreate != FlakyCreateTimeout {
+			v.Add(FlakyCreateHeader, fmt.Sprintf("must be %s or %s (got %q)", FlakyCreateError, FlakyCreateTimeout, value))
+		}
+	}
+
+	if value := strings.TrimSpace(r.Header.Get(ReplayCreateHeader)); value != "" {
+		replay, err := strconv.ParseBool(value)
+		if err != nil {
+			v.Add(ReplayCreateHeader, fmt.Sprintf("must be true or false (got %q)", value))
+		}
+		o.ReplayCreate = replay
+	}
+
+	if value := strings.TrimSpace(r.Header.Get(MalformedResponseHeader)); value != "" {
+		o.MalformedResponse = strings.ToLower(value)
+		if !validMalformedKind(o.MalformedResponse) {
+			v.Add(MalformedResponseHeader, fmt.Sprintf("must be one of %s (got %q)", strings.Join(malformedKinds, ", "), value))
+		}
+	}
+
+	if value := strings.TrimSpace(r.Header.Get(ConnectionFaultHeader)); value != "" {
+		o.ConnectionFault = strings.ToLower(value)
+		if o.ConnectionFault != ConnectionReset && o.ConnectionFault != ConnectionClose {
+			v.Add(ConnectionFaultHeader, fmt.Sprintf("must be %s or %s (got %q)", ConnectionReset, ConnectionClose, value))
+		}
+	}
+
+	o.Body = r.Header.Get(ForceBodyHeader)
+	if o.Body != "" && o.Status == 0 {
+		o.Status = http.StatusInternalServerError
+	}
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// parseForcedLatency parses a single latency or a latency range in milliseconds
+func parseForcedLatency(value string) *LatencyRange {
+	var lr *LatencyRange
+	if strings.Contains(value, "-") {
+		lr = parseLatencyRange(value)
+	} else if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+		lr = &LatencyRange{Min: ms, Max: ms}
+	}
+	if lr == nil || lr.Max > maxForcedLatencyMs {
+		return nil
+	}
+	return lr
+}
+
+// ForcedError is returned when a client forces an error response. Body, when
+// set, is written verbatim instead of the JSON encoding of Err.
+type ForcedError struct {
+	Status int
+	Body   string
+	Err    *domain.DirtError
+}
+
+func (e *ForcedError) Error() string {
+	return e.Err.Error()
+}
+
+// ContentType returns the media type of the forced body
+func (e *ForcedError) ContentType() string {
+	if json.Valid([]byte(e.Body)) {
+		return "application/json"
+	}
+	return "text/plain"
+}
+
+// forcedErrorCodes maps forced statuses to the error code the API would use
+var forcedErrorCodes = map[int]string{
+	http.StatusBadRequest:          domain.ErrorCodeInvalidInput,
+	http.StatusUnauthorized:        domain.ErrorCodeUnauthorized,
+	http.StatusForbidden:           domain.ErrorCodePermissionDenied,
+	http.StatusNotFound:            domain.ErrorCodeNotFound,
+	http.StatusConflict:            domain.ErrorCodeAlreadyExists,
+	http.StatusTooManyRequests:     domain.ErrorCodeTooManyRequests,
+	http.StatusServiceUnavailable:  domain.ErrorCodeServiceUnavailable,
+	http.StatusInternalServerError: domain.ErrorCodeInternalError,
+	http.StatusGatewayTimeout:      domain.ErrorCodeDeadlineExceeded,
+}
+
+// forcedError builds the error for a forced status
+func (o *Overrides) forcedError() *ForcedError {
+	code, ok := forcedErrorCodes[o.Status]
+	if !ok {
+		code = domain.ErrorCodeInternalError
+		if o.Status < 500 {
+			code = domain.ErrorCodeInvalidInput
+		}
+	}
+
+	return &ForcedError{
+		Status: o.Status,
+		Body:   o.Body,
+		Err:    domain.NewError(code, fmt.Sprintf("chaos: forced %d %s", o.Status, http.StatusText(o.Status))),
+	}
+}
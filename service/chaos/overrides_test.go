@@ -0,0 +1,181 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOverrides(t *testing.T) {
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		expected       *Overrides
+		expectedFields []string
+	}{
+		{
+			name:     "no headers",
+			expected: &Overrides{},
+		},
+		{
+			name:     "fixed latency",
+			headers:  map[string]string{LatencyHeader: "250"},
+			expected: &Overrides{Latency: &LatencyRange{Min: 250, Max: 250}},
+		},
+		{
+			name:     "latency range",
+			headers:  map[string]string{LatencyHeader: "100-500"},
+			expected: &Overrides{Latency: &LatencyRange{Min: 100, Max: 500}},
+		},
+		{
+			name:     "forced status",
+			headers:  map[string]string{ForceStatusHeader: "503"},
+			expected: &Overrides{Status: 503},
+		},
+		{
+			name:     "forced body defaults to 500",
+			headers:  map[string]string{ForceBodyHeader: "<html>bad gateway</html>"},
+			expected: &Overrides{Status: 500, Body: "<html>bad gateway</html>"},
+		},
+		{
+			name: "all invalid headers are reported",
+			headers: map[string]string{
+				LatencyHeader:     "soon",
+				ForceStatusHeader: "200",
+			},
+			expectedFields: []string{LatencyHeader, ForceStatusHeader},
+		},
+		{
+			name:     "flaky create",
+			headers:  map[string]string{FlakyCreateHeader: "Timeout"},
+			expected: &Overrides{FlakyCreate: FlakyCreateTimeout},
+		},
+		{
+			name:     "replay create",
+			headers:  map[string]string{ReplayCreateHeader: "true"},
+			expected: &Overrides{ReplayCreate: true},
+		},
+		{
+			name:           "invalid replay create",
+			headers:        map[string]string{ReplayCreateHeader: "twice"},
+			expectedFields: []string{ReplayCreateHeader},
+		},
+		{
+			name:     "connection fault",
+			headers:  map[string]string{ConnectionFaultHeader: "reset"},
+			expected: &Overrides{ConnectionFault: ConnectionReset},
+		},
+		{
+			name:           "unknown flaky create outcome",
+			headers:        map[string]string{FlakyCreateHeader: "sometimes"},
+			expectedFields: []string{FlakyCreateHeader},
+		},
+		{
+			name:           "latency too long",
+			headers:        map[string]string{LatencyHeader: "0-600000"},
+			expectedFields: []string{LatencyHeader},
+		},
+		{
+			name:           "reversed range",
+			headers:        map[string]string{LatencyHeader: "500-100"},
+			expectedFields: []string{LatencyHeader},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/test", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			o, err := ParseOverrides(req)
+
+			if tt.expectedFields == nil {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, o)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, domain.IsInvalidInput(err))
+			var fields []string
+			for _, v := range err.(*domain.DirtError).Details["fields"].([]domain.FieldViolation) {
+				fields = append(fields, v.Field)
+			}
+			assert.Equal(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestChaosService_ApplyChaos_ForcedStatus(t *testing.T) {
+	service := &ChaosService{
+		config: &Config{Enabled: false},
+		rng:    rand.New(rand.NewSource(42)),
+	}
+	require.NoError(t, service.SetProfile(&Profile{Name: "healthy"}))
+
+	tests := []struct {
+		name         string
+		status       string
+		body         string
+		expectedCode string
+	}{
+		{name: "mapped status", status: "503", expectedCode: domain.ErrorCodeServiceUnavailable},
+		{name: "unmapped 5xx", status: "502", expectedCode: domain.ErrorCodeInternalError},
+		{name: "unmapped 4xx", status: "418", expectedCode: domain.ErrorCodeInvalidInput},
+		{name: "forced body", status: "404", body: `{"oops":true}`, expectedCode: domain.ErrorCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set(ForceStatusHeader, tt.status)
+			req.Header.Set(ForceBodyHeader, tt.body)
+			// Forced controls win over the request's profile
+			req.Header.Set(ProfileHeader, "healthy")
+
+			err := service.ApplyInstancesChaos(context.Background(), req)
+
+			forced, ok := err.(*ForcedError)
+			require.True(t, ok, "expected a forced error, got %v", err)
+			assert.Equal(t, tt.expectedCode, forced.Err.Code)
+			assert.Equal(t, tt.body, forced.Body)
+		})
+	}
+
+	t.Run("bypass header wins", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set(ForceStatusHeader, "503")
+		req.Header.Set(NoChaosHeader, "true")
+
+		assert.NoError(t, service.ApplyMetadataChaos(context.Background(), req))
+	})
+}
+
+func TestChaosService_ApplyChaos_ForcedLatencyRange(t *testing.T) {
+	service := &ChaosService{
+		config: &Config{Enabled: false},
+		rng:    rand.New(rand.NewSource(42)),
+	}
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set(LatencyHeader, "10-20")
+
+	start := time.Now()
+	err := service.ApplyProjectsChaos(context.Background(), req, "GET")
+	duration := time.Since(start)
+
+	assert.NoError(t, err)
+	assert.True(t, duration >= 10*time.Millisecond, "Expected at least 10ms delay, got %v", duration)
+}
+
+func TestForcedError_ContentType(t *testing.T) {
+	assert.Equal(t, "application/json", (&ForcedError{Body: `{"a":1}`}).ContentType())
+	assert.Equal(t, "text/plain", (&ForcedError{Body: "upstream connect error"}).ContentType())
+}
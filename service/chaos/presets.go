@@ -0,0 +1,188 @@
+package chaos
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Preset is a named set of server-wide failure characteristics shipped with
+// the server, so common failure modes can be switched on with one call.
+// Active presets apply whether or not server-wide chaos is enabled, and are
+// layered on top of every other policy: their latency and errors come before
+// those of targets, profiles and the global config, and their connection
+// fault and create rates add to the others.
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// Methods restricts the preset to these HTTP methods; empty means all
+	Methods []string `json:"methods,omitempty"`
+
+	LatencyRange        *LatencyRange `json:"latency_ms,omitempty"`
+	ErrorRate           float64       `json:"error_rate,omitempty"`
+	ErrorTypes          []int         `json:"error_types,omitempty"`
+	ConnectionFaultRate float64       `json:"connection_fault_rate,omitempty"`
+	FlakyCreateRate     float64       `json:"flaky_create_rate,omitempty"`
+	ReplayCreateRate    float64       `json:"replay_create_rate,omitempty"`
+
+	// Active reports whether the preset is switched on
+	Active bool `json:"active"`
+}
+
+// writeMethods are the methods that change resources
+var writeMethods = []string{"POST", "PUT", "PATCH", "DELETE"}
+
+// builtinPresets are the presets shipped with the server, ordered by name
+var builtinPresets = []Preset{
+	{
+		Name:                "flaky-network",
+		Description:         "Jittery latency, occasional dropped connections and gateway errors",
+		LatencyRange:        &LatencyRange{Min: 50, Max: 300},
+		ErrorRate:           0.05,
+		ErrorTypes:          []int{503},
+		ConnectionFaultRate: 0.05,
+	},
+	{
+		Name:        "rate-limited",
+		Description: "Three in ten requests are throttled with 429 Too Many Requests",
+		ErrorRate:   0.3,
+		ErrorTypes:  []int{429},
+	},
+	{
+		Name:         "slow-control-plane",
+		Description:  "Writes take between half a second and two seconds to complete",
+		Methods:      writeMethods,
+		LatencyRange: &LatencyRange{Min: 500, Max: 2000},
+	},
+	{
+		Name:             "split-brain",
+		Description:      "Creates are reported failed after they were persisted, or are applied twice",
+		Methods:          []string{"POST"},
+		FlakyCreateRate:  0.2,
+		ReplayCreateRate: 0.1,
+	},
+}
+
+// findPreset returns the built-in preset with the given name
+func findPreset(name string) (*Preset, bool) {
+	for i := range builtinPresets {
+		if builtinPresets[i].Name == name {
+			return &builtinPresets[i], true
+		}
+	}
+	return nil, false
+}
+
+// loadPresetsFromEnv loads the presets active at startup from
+// DIRT_CHAOS_PRESETS, a comma-separated list of preset names
+func loadPresetsFromEnv() map[string]bool {
+	active := make(map[string]bool)
+	for _, name := range strings.Split(getEnv("DIRT_CHAOS_PRESETS", ""), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := findPreset(name); !ok {
+			log.Printf("Ignoring unknown chaos preset %q", name)
+			continue
+		}
+		active[name] = true
+	}
+	return active
+}
+
+// ListPresets returns the built-in presets ordered by name
+func (c *ChaosService) ListPresets() []*Preset {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	presets := make([]*Preset, 0, len(builtinPresets))
+	for _, p := range builtinPresets {
+		preset := p
+		preset.Active = c.presets[p.Name]
+		presets = append(presets, &preset)
+	}
+	return presets
+}
+
+// GetPreset returns a built-in preset by name
+func (c *ChaosService) GetPreset(name string) (*Preset, error) {
+	p, ok := findPreset(name)
+	if !ok {
+		return nil, domain.NotFoundError("chaos preset", name)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	preset := *p
+	preset.Active = c.presets[name]
+	return &preset, nil
+}
+
+// SetPresetActive switches a built-in preset on or off
+func (c *ChaosService) SetPresetActive(name string, active bool) (*Preset, error) {
+	if _, ok := findPreset(name); !ok {
+		return nil, domain.NotFoundError("chaos preset", name)
+	}
+
+	c.mu.Lock()
+	if c.presets == nil {
+		c.presets = make(map[string]bool)
+	}
+	if active {
+		c.presets[name] = true
+	} else {
+		delete(c.presets, name)
+	}
+	c.mu.Unlock()
+
+	return c.GetPreset(name)
+}
+
+// requestPresets returns the active presets that apply to a request
+func (c *ChaosService) requestPresets(r *http.Request) []*Preset {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var presets []*Preset
+	for i := range builtinPresets {
+		p := &builtinPresets[i]
+		if c.presets[p.Name] && matchesMethod(p.Methods, r.Method) {
+			presets = append(presets, p)
+		}
+	}
+	return presets
+}
+
+// applyPresets applies the latency and errors of the active presets, in name
+// order, skipping their latency when the client forced one
+func (c *ChaosService) applyPresets(ctx context.Context, r *http.Request, latencyForced bool) error {
+	for _, p := range c.requestPresets(r) {
+		if !latencyForced {
+			c.sleep(ctx, p.LatencyRange)
+		}
+
+		types, weights := p.ErrorTypes, []int(nil)
+		if len(types) == 0 {
+			types, weights = c.config.ErrorTypes, c.config.ErrorWeights
+		}
+		if err := c.injectError(p.ErrorRate, types, weights); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// presetRate layers the rate of the active presets for a request onto rate,
+// each as an independent chance
+func (c *ChaosService) presetRate(r *http.Request, rate float64, fromPreset func(*Preset) float64) float64 {
+	for _, p := range c.requestPresets(r) {
+		rate = 1 - (1-rate)*(1-fromPreset(p))
+	}
+	return rate
+}
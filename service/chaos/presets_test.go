@@ -0,0 +1,63 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosService_Presets(t *testing.T) {
+	service := &ChaosService{config: &Config{}, rng: rand.New(rand.NewSource(42))}
+
+	presets := service.ListPresets()
+	var names []string
+	for _, p := range presets {
+		names = append(names, p.Name)
+		assert.False(t, p.Active)
+	}
+	assert.Equal(t, []string{"flaky-network", "rate-limited", "slow-control-plane", "split-brain"}, names)
+
+	_, err := service.SetPresetActive("missing", true)
+	assert.True(t, domain.IsNotFound(err))
+
+	preset, err := service.SetPresetActive("rate-limited", true)
+	require.NoError(t, err)
+	assert.True(t, preset.Active)
+
+	// Presets apply without server-wide chaos
+	req, _ := http.NewRequest("GET", "/v1/projects", nil)
+	throttled := 0
+	for i := 0; i < 100; i++ {
+		if err := service.ApplyProjectsChaos(context.Background(), req, "GET"); err != nil {
+			assert.Equal(t, domain.ErrorCodeTooManyRequests, err.(*domain.DirtError).Code)
+			throttled++
+		}
+	}
+	assert.InDelta(t, 30, throttled, 15)
+
+	_, err = service.SetPresetActive("rate-limited", false)
+	require.NoError(t, err)
+	assert.NoError(t, service.ApplyProjectsChaos(context.Background(), req, "GET"))
+}
+
+func TestChaosService_PresetsLayerWithProfiles(t *testing.T) {
+	service := &ChaosService{config: &Config{}, rng: rand.New(rand.NewSource(42))}
+	require.NoError(t, service.SetProfile(&Profile{Name: "dupes", ReplayCreateRate: 0}))
+	_, err := service.SetPresetActive("split-brain", true)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("POST", "/v1/instances", nil)
+	ctx := WithProfile(context.Background(), "dupes")
+	assert.InDelta(t, 0.1, service.createRate(ctx, req, 0,
+		func(p *Profile) float64 { return p.ReplayCreateRate },
+		func(p *Preset) float64 { return p.ReplayCreateRate }), 1e-9)
+
+	// Split-brain only affects creates
+	get, _ := http.NewRequest("GET", "/v1/instances", nil)
+	assert.Empty(t, service.requestPresets(get))
+}
@@ -0,0 +1,210 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// ProfileHeader overrides the chaos profile for a single request. The value
+// "none" disables any profile inherited from the project.
+const ProfileHeader = "X-Dirt-Chaos-Profile"
+
+// Profile is a named set of failure characteristics that can be attached to a
+// project. Requests touching the project's resources use the profile instead
+// of the server-wide chaos configuration. Its Window can limit when it does.
+type Profile struct {
+	Name         string        `json:"name"`
+	LatencyRange *LatencyRange `json:"latency_ms,omitempty"`
+	ErrorRate    float64       `json:"error_rate"`
+	ErrorTypes   []int         `json:"error_types,omitempty"`
+	ErrorWeights []int         `json:"error_weights,omitempty"`
+
+	// FlakyCreateRate is the chance that a create request fails after its
+	// resource was persisted
+	FlakyCreateRate float64 `json:"flaky_create_rate,omitempty"`
+
+	// ReplayCreateRate is the chance that a create request is applied twice
+	ReplayCreateRate float64 `json:"replay_create_rate,omitempty"`
+
+	Window
+}
+
+// validate checks profile settings
+func (p *Profile) validate() error {
+	var v domain.FieldViolations
+	if p.Name == "" {
+		v.Add("name", "cannot be empty")
+	}
+	validateFailures(&v, p.LatencyRange, p.ErrorRate, p.ErrorTypes, p.ErrorWeights)
+	if p.FlakyCreateRate < 0 || p.FlakyCreateRate > 1 {
+		v.Add("flaky_create_rate", "must be between 0 and 1")
+	}
+	if p.ReplayCreateRate < 0 || p.ReplayCreateRate > 1 {
+		v.Add("replay_create_rate", "must be between 0 and 1")
+	}
+	p.Window.validate(&v)
+	return v.Err()
+}
+
+// validateFailures checks the failure characteristics shared by profiles and
+// targets
+func validateFailures(v *domain.FieldViolations, latencyRange *LatencyRange, errorRate float64, errorTypes []int, errorWeights []int) {
+	if errorRate < 0 || errorRate > 1 {
+		v.Add("error_rate", "must be between 0 and 1")
+	}
+	if latencyRange != nil && (latencyRange.Min < 0 || latencyRange.Max < latencyRange.Min) {
+		v.Add("latency_ms", "min must be non-negative and max must not be less than min")
+	}
+	for _, code := range errorTypes {
+		if code != 429 && code != 500 && code != 503 {
+			v.Add("error_types", "supported error types are 429, 500 and 503")
+			break
+		}
+	}
+	if len(errorWeights) > 0 && len(errorWeights) != len(errorTypes) {
+		v.Add("error_weights", "must have one weight per error type")
+	}
+}
+
+type profileKey struct{}
+
+// WithProfile returns a context carrying the chaos profile inherited from the
+// project a request touches
+func WithProfile(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, profileKey{}, name)
+}
+
+// profileFromContext returns the inherited profile name, if any
+func profileFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(profileKey{}).(string)
+	return name
+}
+
+// loadProfilesFromEnv loads profiles from DIRT_CHAOS_PROFILES, a JSON object
+// keyed by profile name
+func loadProfilesFromEnv() map[string]*Profile {
+	profiles := make(map[string]*Profile)
+
+	value := getEnv("DIRT_CHAOS_PROFILES", "")
+	if value == "" {
+		return profiles
+	}
+
+	var raw map[string]*Profile
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		log.Printf("Ignoring invalid DIRT_CHAOS_PROFILES: %v", err)
+		return profiles
+	}
+
+	for name, p := range raw {
+		p.Name = name
+		if err := p.validate(); err != nil {
+			log.Printf("Ignoring invalid chaos profile %q: %v", name, err)
+			continue
+		}
+		profiles[name] = p
+	}
+
+	return profiles
+}
+
+// ListProfiles returns all registered profiles ordered by name
+func (c *ChaosService) ListProfiles() []*Profile {
+	c.pruneExpired(time.Now())
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	profiles := make([]*Profile, 0, len(c.profiles))
+	for _, p := range c.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// GetProfile returns a profile by name
+func (c *ChaosService) GetProfile(name string) (*Profile, error) {
+	c.pruneExpired(time.Now())
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p, ok := c.profiles[name]
+	if !ok {
+		return nil, domain.NotFoundError("chaos profile", name)
+	}
+	return p, nil
+}
+
+// SetProfile creates or replaces a profile
+func (c *ChaosService) SetProfile(p *Profile) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.profiles == nil {
+		c.profiles = make(map[string]*Profile)
+	}
+	c.profiles[p.Name] = p
+	return nil
+}
+
+// DeleteProfile removes a profile. Projects still referencing it fall back to
+// the server-wide configuration.
+func (c *ChaosService) DeleteProfile(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.profiles[name]; !ok {
+		return domain.NotFoundError("chaos profile", name)
+	}
+	delete(c.profiles, name)
+	return nil
+}
+
+// requestProfile resolves the profile for a request: the per-request header
+// wins over the profile inherited from the project. A profile outside its
+// window does not apply.
+func (c *ChaosService) requestProfile(ctx context.Context, r *http.Request) *Profile {
+	name := profileFromContext(ctx)
+	if override := strings.TrimSpace(r.Header.Get(ProfileHeader)); override != "" {
+		name = override
+	}
+	if name == "" || name == "none" {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p := c.profiles[name]
+	if p == nil || !p.inForce(time.Now()) {
+		return nil
+	}
+	return p
+}
+
+// applyProfile applies the failure characteristics of a profile, skipping its
+// latency when the client forced one
+func (c *ChaosService) applyProfile(ctx context.Context, p *Profile, latencyForced bool) error {
+	if !latencyForced {
+		c.sleep(ctx, p.LatencyRange)
+	}
+
+	types, weights := p.ErrorTypes, p.ErrorWeights
+	if len(types) == 0 {
+		types, weights = c.config.ErrorTypes, c.config.ErrorWeights
+	}
+	return c.injectError(p.ErrorRate, types, weights)
+}
@@ -0,0 +1,141 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosService_ApplyChaos_Profiles(t *testing.T) {
+	service := &ChaosService{
+		config: &Config{Enabled: false},
+		rng:    rand.New(rand.NewSource(42)),
+	}
+	require.NoError(t, service.SetProfile(&Profile{Name: "broken", ErrorRate: 1.0, ErrorTypes: []int{503}}))
+	require.NoError(t, service.SetProfile(&Profile{Name: "healthy", ErrorRate: 0.0}))
+
+	tests := []struct {
+		name        string
+		inherited   string
+		header      string
+		expectError bool
+	}{
+		{
+			name:        "inherited profile applies when global chaos is disabled",
+			inherited:   "broken",
+			expectError: true,
+		},
+		{
+			name:      "header disables inherited profile",
+			inherited: "broken",
+			header:    "none",
+		},
+		{
+			name:      "header overrides inherited profile",
+			inherited: "broken",
+			header:    "healthy",
+		},
+		{
+			name:        "header selects profile without inheritance",
+			header:      "broken",
+			expectError: true,
+		},
+		{
+			name:      "unknown profile falls back to global config",
+			inherited: "missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/test", nil)
+			if tt.header != "" {
+				req.Header.Set(ProfileHeader, tt.header)
+			}
+			ctx := context.Background()
+			if tt.inherited != "" {
+				ctx = WithProfile(ctx, tt.inherited)
+			}
+
+			err := service.ApplyInstancesChaos(ctx, req)
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Equal(t, domain.ErrorCodeServiceUnavailable, err.(*domain.DirtError).Code)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestChaosService_SetProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		profile     Profile
+		expectError bool
+	}{
+		{
+			name:    "valid profile",
+			profile: Profile{Name: "slow", LatencyRange: &LatencyRange{Min: 100, Max: 200}, ErrorRate: 0.1},
+		},
+		{
+			name:        "error rate out of range",
+			profile:     Profile{Name: "bad", ErrorRate: 1.5},
+			expectError: true,
+		},
+		{
+			name:        "inverted latency range",
+			profile:     Profile{Name: "bad", LatencyRange: &LatencyRange{Min: 200, Max: 100}},
+			expectError: true,
+		},
+		{
+			name:        "unsupported error type",
+			profile:     Profile{Name: "bad", ErrorTypes: []int{418}},
+			expectError: true,
+		},
+		{
+			name:        "flaky create rate out of range",
+			profile:     Profile{Name: "bad", FlakyCreateRate: -0.5},
+			expectError: true,
+		},
+		{
+			name:        "missing name",
+			profile:     Profile{ErrorRate: 0.1},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &ChaosService{config: &Config{}}
+
+			err := service.SetProfile(&tt.profile)
+			if tt.expectError {
+				assert.True(t, domain.IsInvalidInput(err))
+				assert.Empty(t, service.ListProfiles())
+			} else {
+				require.NoError(t, err)
+				got, err := service.GetProfile(tt.profile.Name)
+				require.NoError(t, err)
+				assert.Equal(t, &tt.profile, got)
+			}
+		})
+	}
+}
+
+func TestLoadProfilesFromEnv(t *testing.T) {
+	os.Setenv("DIRT_CHAOS_PROFILES", `{"flaky":{"error_rate":0.5},"slow":{"latency_ms":{"min":100,"max":500}},"bad":{"error_rate":2}}`)
+	defer os.Unsetenv("DIRT_CHAOS_PROFILES")
+
+	profiles := loadProfilesFromEnv()
+
+	assert.Len(t, profiles, 2)
+	assert.Equal(t, &Profile{Name: "flaky", ErrorRate: 0.5}, profiles["flaky"])
+	assert.Equal(t, &LatencyRange{Min: 100, Max: 500}, profiles["slow"].LatencyRange)
+}
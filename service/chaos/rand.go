@@ -0,0 +1,37 @@
+package chaos
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// lockedSource is a random source safe for concurrent use, unlike the
+// sources of rand.NewSource
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+// NewLockedRand returns a generator seeded with seed that is safe for
+// concurrent use
+func NewLockedRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
@@ -0,0 +1,200 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Target injects failures into the requests addressing a single resource,
+// such as making every GET of one instance fail, so a test can break exactly
+// one resource among many. Targets apply whether or not server-wide chaos is
+// enabled, and take precedence over chaos profiles. A target's Window can
+// limit when it applies.
+type Target struct {
+	ResourceID string `json:"resource_id"`
+
+	// Methods restricts the target to these HTTP methods; empty means all
+	Methods []string `json:"methods,omitempty"`
+
+	LatencyRange *LatencyRange `json:"latency_ms,omitempty"`
+	ErrorRate    float64       `json:"error_rate"`
+	ErrorTypes   []int         `json:"error_types,omitempty"`
+	ErrorWeights []int         `json:"error_weights,omitempty"`
+
+	Window
+}
+
+// validate checks target settings and normalizes its methods to upper case
+func (t *Target) validate() error {
+	var v domain.FieldViolations
+	if t.ResourceID == "" {
+		v.Add("resource_id", "cannot be empty")
+	}
+	for i, method := range t.Methods {
+		t.Methods[i] = strings.ToUpper(method)
+		switch t.Methods[i] {
+		case "GET", "POST", "PUT", "PATCH", "DELETE":
+		default:
+			v.Add("methods", "supported methods are GET, POST, PUT, PATCH and DELETE")
+		}
+	}
+	validateFailures(&v, t.LatencyRange, t.ErrorRate, t.ErrorTypes, t.ErrorWeights)
+	t.Window.validate(&v)
+	return v.Err()
+}
+
+// matches reports whether the target applies to requests with method
+func (t *Target) matches(method string) bool {
+	return matchesMethod(t.Methods, method)
+}
+
+// matchesMethod reports whether method is one of methods, or methods is empty
+func matchesMethod(methods []string, method string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+type resourceKey struct{}
+
+// WithResource returns a context carrying the ID of the resource a request
+// addresses, so that a target for that resource applies to it
+func WithResource(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, resourceKey{}, id)
+}
+
+// resourceFromContext returns the addressed resource ID, if any
+func resourceFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(resourceKey{}).(string)
+	return id
+}
+
+// loadTargetsFromEnv loads targets from DIRT_CHAOS_TARGETS, a JSON object
+// keyed by resource ID
+func loadTargetsFromEnv() map[string]*Target {
+	targets := make(map[string]*Target)
+
+	value := getEnv("DIRT_CHAOS_TARGETS", "")
+	if value == "" {
+		return targets
+	}
+
+	var raw map[string]*Target
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		log.Printf("Ignoring invalid DIRT_CHAOS_TARGETS: %v", err)
+		return targets
+	}
+
+	for id, t := range raw {
+		t.ResourceID = id
+		if err := t.validate(); err != nil {
+			log.Printf("Ignoring invalid chaos target %q: %v", id, err)
+			continue
+		}
+		targets[id] = t
+	}
+
+	return targets
+}
+
+// ListTargets returns all targets ordered by resource ID
+func (c *ChaosService) ListTargets() []*Target {
+	c.pruneExpired(time.Now())
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	targets := make([]*Target, 0, len(c.targets))
+	for _, t := range c.targets {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ResourceID < targets[j].ResourceID })
+	return targets
+}
+
+// GetTarget returns the target for a resource
+func (c *ChaosService) GetTarget(resourceID string) (*Target, error) {
+	c.pruneExpired(time.Now())
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	t, ok := c.targets[resourceID]
+	if !ok {
+		return nil, domain.NotFoundError("chaos target", resourceID)
+	}
+	return t, nil
+}
+
+// SetTarget creates or replaces the target for a resource
+func (c *ChaosService) SetTarget(t *Target) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.targets == nil {
+		c.targets = make(map[string]*Target)
+	}
+	c.targets[t.ResourceID] = t
+	return nil
+}
+
+// DeleteTarget removes the target for a resource
+func (c *ChaosService) DeleteTarget(resourceID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.targets[resourceID]; !ok {
+		return domain.NotFoundError("chaos target", resourceID)
+	}
+	delete(c.targets, resourceID)
+	return nil
+}
+
+// requestTarget returns the target for the resource a request addresses, if
+// one applies to the request's method and is within its window
+func (c *ChaosService) requestTarget(ctx context.Context, r *http.Request) *Target {
+	id := resourceFromContext(ctx)
+	if id == "" {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	t := c.targets[id]
+	if t == nil || !t.matches(r.Method) || !t.inForce(time.Now()) {
+		return nil
+	}
+	return t
+}
+
+// applyTarget applies the failure characteristics of a target, skipping its
+// latency when the client forced one
+func (c *ChaosService) applyTarget(ctx context.Context, t *Target, latencyForced bool) error {
+	if !latencyForced {
+		c.sleep(ctx, t.LatencyRange)
+	}
+
+	types, weights := t.ErrorTypes, t.ErrorWeights
+	if len(types) == 0 {
+		types, weights = c.config.ErrorTypes, c.config.ErrorWeights
+	}
+	return c.injectError(t.ErrorRate, types, weights)
+}
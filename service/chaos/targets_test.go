@@ -0,0 +1,119 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosService_ApplyChaos_Targets(t *testing.T) {
+	service := &ChaosService{
+		config: &Config{Enabled: false},
+		rng:    rand.New(rand.NewSource(42)),
+	}
+	require.NoError(t, service.SetTarget(&Target{ResourceID: "i-broken", Methods: []string{"get"}, ErrorRate: 1.0, ErrorTypes: []int{500}}))
+	require.NoError(t, service.SetTarget(&Target{ResourceID: "i-healthy", ErrorRate: 0.0}))
+	require.NoError(t, service.SetProfile(&Profile{Name: "broken", ErrorRate: 1.0, ErrorTypes: []int{503}}))
+
+	tests := []struct {
+		name     string
+		resource string
+		method   string
+		profile  string
+		header   http.Header
+		expected string
+	}{
+		{name: "targeted resource and method", resource: "i-broken", method: "GET", expected: domain.ErrorCodeInternalError},
+		{name: "other method", resource: "i-broken", method: "DELETE"},
+		{name: "other resource", resource: "i-other", method: "GET"},
+		{name: "no resource", method: "GET"},
+		{
+			name:     "target wins over the project profile",
+			resource: "i-healthy", method: "GET", profile: "broken",
+		},
+		{
+			name:     "profile applies when the target skips the method",
+			resource: "i-broken", method: "POST", profile: "broken",
+			expected: domain.ErrorCodeServiceUnavailable,
+		},
+		{
+			name:     "bypass header",
+			resource: "i-broken", method: "GET",
+			header: http.Header{NoChaosHeader: []string{"true"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "/test", nil)
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+			ctx := context.Background()
+			if tt.resource != "" {
+				ctx = WithResource(ctx, tt.resource)
+			}
+			if tt.profile != "" {
+				ctx = WithProfile(ctx, tt.profile)
+			}
+
+			err := service.ApplyInstancesChaos(ctx, req)
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.expected, err.(*domain.DirtError).Code)
+		})
+	}
+}
+
+func TestChaosService_SetTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      Target
+		expectError bool
+	}{
+		{name: "valid target", target: Target{ResourceID: "i-1", Methods: []string{"GET", "PATCH"}, ErrorRate: 1}},
+		{name: "missing resource", target: Target{ErrorRate: 1}, expectError: true},
+		{name: "unknown method", target: Target{ResourceID: "i-1", Methods: []string{"TRACE"}}, expectError: true},
+		{name: "error rate out of range", target: Target{ResourceID: "i-1", ErrorRate: 2}, expectError: true},
+		{name: "unsupported error type", target: Target{ResourceID: "i-1", ErrorTypes: []int{404}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &ChaosService{config: &Config{}}
+
+			err := service.SetTarget(&tt.target)
+			if tt.expectError {
+				assert.True(t, domain.IsInvalidInput(err))
+				assert.Empty(t, service.ListTargets())
+				return
+			}
+			require.NoError(t, err)
+			got, err := service.GetTarget(tt.target.ResourceID)
+			require.NoError(t, err)
+			assert.Equal(t, &tt.target, got)
+
+			require.NoError(t, service.DeleteTarget(tt.target.ResourceID))
+			assert.True(t, domain.IsNotFound(service.DeleteTarget(tt.target.ResourceID)))
+		})
+	}
+}
+
+func TestLoadTargetsFromEnv(t *testing.T) {
+	os.Setenv("DIRT_CHAOS_TARGETS", `{"i-1":{"methods":["get"],"error_rate":1},"i-2":{"error_rate":2}}`)
+	defer os.Unsetenv("DIRT_CHAOS_TARGETS")
+
+	targets := loadTargetsFromEnv()
+	require.Len(t, targets, 1)
+	assert.Equal(t, "i-1", targets["i-1"].ResourceID)
+	assert.Equal(t, []string{"GET"}, targets["i-1"].Methods)
+}
@@ -0,0 +1,96 @@
+package chaos
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// MaxScheduleDuration bounds how long each window of a chaos schedule stays
+// open
+const MaxScheduleDuration = 24 * time.Hour
+
+// Window limits when a chaos profile or target is in force, so failure
+// windows can be programmed ahead of a test phase. The policy applies from
+// StartsAt until EndsAt, either of which may be left open, and is removed
+// once EndsAt has passed. Within those bounds, a Schedule further restricts
+// it to windows of Duration opening each time the cron expression fires, in
+// UTC.
+type Window struct {
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	Schedule string     `json:"schedule,omitempty"`
+	Duration string     `json:"duration,omitempty"`
+
+	// schedule and length are Schedule and Duration, parsed by validate
+	schedule *cronSchedule
+	length   time.Duration
+}
+
+// validate checks the window settings, parsing its schedule
+func (w *Window) validate(v *domain.FieldViolations) {
+	if w.StartsAt != nil && w.EndsAt != nil && !w.EndsAt.After(*w.StartsAt) {
+		v.Add("ends_at", "must be after starts_at")
+	}
+
+	w.schedule, w.length = nil, 0
+	if w.Schedule == "" {
+		if w.Duration != "" {
+			v.Add("duration", "requires a schedule")
+		}
+		return
+	}
+
+	schedule, err := parseCron(w.Schedule)
+	if err != nil {
+		v.Add("schedule", err.Error())
+	}
+	length, err := time.ParseDuration(w.Duration)
+	switch {
+	case w.Duration == "":
+		v.Add("duration", "is required with a schedule")
+	case err != nil:
+		v.Add("duration", "must be a duration such as 30s or 10m")
+	case length < time.Minute || length > MaxScheduleDuration:
+		v.Add("duration", fmt.Sprintf("must be between 1m and %s", MaxScheduleDuration))
+	}
+	w.schedule, w.length = schedule, length
+}
+
+// inForce reports whether a policy with this window applies at now
+func (w *Window) inForce(now time.Time) bool {
+	if w.StartsAt != nil && now.Before(*w.StartsAt) {
+		return false
+	}
+	if w.expired(now) {
+		return false
+	}
+	return w.schedule == nil || w.schedule.openAt(now, w.length)
+}
+
+// expired reports whether the window has closed for good
+func (w *Window) expired(now time.Time) bool {
+	return w.EndsAt != nil && !now.Before(*w.EndsAt)
+}
+
+// pruneExpired removes the profiles and targets whose windows have closed
+// for good
+func (c *ChaosService) pruneExpired(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, p := range c.profiles {
+		if p.expired(now) {
+			log.Printf("chaos: profile %q ended at %s", name, p.EndsAt.Format(time.RFC3339))
+			delete(c.profiles, name)
+		}
+	}
+	for id, t := range c.targets {
+		if t.expired(now) {
+			log.Printf("chaos: target %q ended at %s", id, t.EndsAt.Format(time.RFC3339))
+			delete(c.targets, id)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	// Monday 2024-01-01 09:30 UTC
+	monday := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr    string
+		at      time.Time
+		matches bool
+	}{
+		{"* * * * *", monday, true},
+		{"30 9 * * *", monday, true},
+		{"*/15 9-17 * * 1-5", monday, true},
+		{"*/20 * * * *", monday, false},
+		{"0,30 * * * 0,6", monday, false},
+		{"30 9 * * 7", monday.AddDate(0, 0, 6), true},
+		{"30 9 15 * 1", monday, true},
+		{"30 9 15 * *", monday, false},
+		{"30 9 1 2-12 *", monday, false},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.matches, s.matches(tt.at), tt.expr)
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := parseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestWindow_InForce(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	w := Window{StartsAt: &start, EndsAt: &end, Schedule: "0,30 * * * *", Duration: "10m"}
+	var v domain.FieldViolations
+	w.validate(&v)
+	require.NoError(t, v.Err())
+
+	assert.False(t, w.inForce(start.Add(-time.Minute)), "before starts_at")
+	assert.True(t, w.inForce(start))
+	assert.True(t, w.inForce(start.Add(9*time.Minute)))
+	assert.False(t, w.inForce(start.Add(10*time.Minute)), "between scheduled windows")
+	assert.True(t, w.inForce(start.Add(35*time.Minute)))
+	assert.False(t, w.inForce(end), "at ends_at")
+	assert.True(t, w.expired(end))
+
+	for _, bad := range []Window{
+		{StartsAt: &end, EndsAt: &start},
+		{Schedule: "* * * * *"},
+		{Schedule: "* * * * *", Duration: "30s"},
+		{Duration: "10m"},
+		{Schedule: "nope", Duration: "10m"},
+	} {
+		var v domain.FieldViolations
+		bad.validate(&v)
+		assert.Error(t, v.Err(), "%+v", bad)
+	}
+}
+
+func TestChaosService_ExpiredPoliciesAreRemoved(t *testing.T) {
+	service := &ChaosService{config: &Config{}}
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(t, service.SetTarget(&Target{ResourceID: "ended", ErrorRate: 1, Window: Window{EndsAt: &past}}))
+	require.NoError(t, service.SetTarget(&Target{ResourceID: "pending", ErrorRate: 1, Window: Window{StartsAt: &future}}))
+	require.NoError(t, service.SetProfile(&Profile{Name: "ended", ErrorRate: 1, Window: Window{EndsAt: &past}}))
+
+	req, _ := http.NewRequest("GET", "/v1/instances/pending", nil)
+	assert.Nil(t, service.requestTarget(WithResource(context.Background(), "pending"), req), "not started yet")
+
+	targets := service.ListTargets()
+	require.Len(t, targets, 1)
+	assert.Equal(t, "pending", targets[0].ResourceID)
+	assert.Empty(t, service.ListProfiles())
+}
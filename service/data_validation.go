@@ -0,0 +1,321 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// storedResource is a stored resource as checked against the validation
+// rules
+type storedResource struct {
+	id     string
+	name   string
+	labels map[string]string
+
+	// status is checked for instances only
+	status string
+
+	// value is the resource as kept when it is quarantined
+	value interface{}
+}
+
+// storedFix holds the changes that bring a stored resource in line with the
+// validation rules; nil fields are left alone
+type storedFix struct {
+	name   *string
+	labels map[string]string
+	status *string
+}
+
+// storedType is how stored data validation reads and repairs one type of
+// resource. Types whose resources own others, like projects, have no
+// quarantine: taking them out of service would take their contents too.
+type storedType struct {
+	name       string
+	list       func(s *Service) ([]storedResource, error)
+	fix        func(s *Service, id string, fix storedFix) error
+	quarantine func(s *Service, id string) error
+}
+
+var storedTypes = []storedType{
+	{
+		name: "organization",
+		list: func(s *Service) ([]storedResource, error) {
+			orgs, err := s.organizationRepo.List(domain.OrganizationListOptions{})
+			resources := make([]storedResource, len(orgs))
+			for i, org := range orgs {
+				resources[i] = storedResource{id: org.ID, name: org.Name, labels: org.Labels, value: org}
+			}
+			return resources, err
+		},
+		fix: func(s *Service, id string, fix storedFix) error {
+			_, err := s.organizationRepo.Update(id, domain.UpdateOrganizationRequest{Name: fix.name, Labels: fix.labels})
+			return err
+		},
+	},
+	{
+		name: "folder",
+		list: func(s *Service) ([]storedResource, error) {
+			folders, err := s.folderRepo.List(domain.FolderListOptions{})
+			resources := make([]storedResource, len(folders))
+			for i, folder := range folders {
+				resources[i] = storedResource{id: folder.ID, name: folder.Name, labels: folder.Labels, value: folder}
+			}
+			return resources, err
+		},
+		fix: func(s *Service, id string, fix storedFix) error {
+			_, err := s.folderRepo.Update(id, domain.UpdateFolderRequest{Name: fix.name, Labels: fix.labels})
+			return err
+		},
+	},
+	{
+		name: "project",
+		list: func(s *Service) ([]storedResource, error) {
+			projects, err := s.projectRepo.List(domain.ProjectListOptions{})
+			resources := make([]storedResource, len(projects))
+			for i, project := range projects {
+				resources[i] = storedResource{id: project.ID, name: project.Name, labels: project.Labels, value: project}
+			}
+			return resources, err
+		},
+		fix: func(s *Service, id string, fix storedFix) error {
+			_, err := s.projectRepo.Update(id, domain.UpdateProjectRequest{Name: fix.name, Labels: fix.labels})
+			return err
+		},
+	},
+	{
+		name: "instance",
+		list: func(s *Service) ([]storedResource, error) {
+			var resources []storedResource
+			err := s.instanceRepo.Stream(domain.InstanceListOptions{}, func(instance *domain.Instance) error {
+				resources = append(resources, storedResource{id: instance.ID, name: instance.Name, labels: instance.Labels, status: instance.Status, value: instance})
+				return nil
+			})
+			return resources, err
+		},
+		fix: func(s *Service, id string, fix storedFix) error {
+			_, err := s.instanceRepo.Update(id, domain.UpdateInstanceRequest{Name: fix.name, Labels: fix.labels, Status: fix.status})
+			return err
+		},
+		quarantine: func(s *Service, id string) error { return s.instanceRepo.Delete(id) },
+	},
+	{
+		name: "environment",
+		list: func(s *Service) ([]storedResource, error) {
+			envs, err := s.environmentRepo.List(domain.EnvironmentListOptions{})
+			resources := make([]storedResource, len(envs))
+			for i, env := range envs {
+				resources[i] = storedResource{id: env.ID, name: env.Name, labels: env.Labels, value: env}
+			}
+			return resources, err
+		},
+		fix: func(s *Service, id string, fix storedFix) error {
+			_, err := s.environmentRepo.Update(id, domain.UpdateEnvironmentRequest{Name: fix.name, Labels: fix.labels})
+			return err
+		},
+		quarantine: func(s *Service, id string) error { return s.environmentRepo.Delete(id) },
+	},
+}
+
+// storedStatuses are the instance statuses that can be stored
+var storedStatuses = map[string]bool{
+	domain.StatusRunning:     true,
+	domain.StatusStopped:     true,
+	domain.StatusDeleting:    true,
+	domain.StatusUnavailable: true,
+}
+
+// invalidNameChars matches the runs of characters names cannot contain
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// invalidLabelKeyChars matches the characters label keys cannot contain,
+// once lowercased
+var invalidLabelKeyChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// fixName returns a valid name close to name: invalid characters are
+// replaced with dashes, and an empty name becomes the resource type and ID
+func fixName(name, resourceType, id string) string {
+	fixed := strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-")
+	if fixed == "" {
+		fixed = invalidNameChars.ReplaceAllString(resourceType+"-"+id, "-")
+	}
+	if len(fixed) > 255 {
+		fixed = fixed[:255]
+	}
+	return fixed
+}
+
+// fixLabels returns valid labels close to labels: keys are lowercased, with
+// invalid characters replaced by underscores, and values are truncated.
+// Labels whose key cannot be fixed this way, or clashes with another, are
+// dropped.
+func fixLabels(labels map[string]string) map[string]string {
+	fixed := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			key = invalidLabelKeyChars.ReplaceAllString(strings.ToLower(key), "_")
+			if len(key) > 63 {
+				key = key[:63]
+			}
+			if _, clash := labels[key]; clash || !labelKeyPattern.MatchString(key) {
+				continue
+			}
+		}
+		if len(value) > 63 {
+			value = value[:63]
+		}
+		fixed[key] = value
+	}
+	return fixed
+}
+
+// ValidateStoredData checks stored organizations, folders, projects,
+// instances and environments against the current validation rules, which
+// may be stricter than those they were written under. In warn mode problems
+// are only reported. In fix mode resources are brought in line: names and
+// label keys are rewritten and unknown instance statuses become stopped. In
+// quarantine mode instances and environments are moved out of service to
+// the quarantine, while resources that own others are only reported.
+func (s *Service) ValidateStoredData(mode string) ([]domain.DataProblem, error) {
+	switch mode {
+	case domain.DataValidationOff:
+		return nil, nil
+	case domain.DataValidationWarn, domain.DataValidationQuarantine, domain.DataValidationFix:
+	default:
+		return nil, domain.InvalidInputError(fmt.Sprintf("data validation mode must be one of %s, %s, %s, %s (got %q)",
+			domain.DataValidationOff, domain.DataValidationWarn, domain.DataValidationQuarantine, domain.DataValidationFix, mode), nil)
+	}
+
+	var problems []domain.DataProblem
+	for _, t := range storedTypes {
+		resources, err := t.list(s)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resource := range resources {
+			found, err := s.validateStoredResource(t, resource, mode)
+			if err != nil {
+				return nil, err
+			}
+			problems = append(problems, found...)
+		}
+	}
+
+	return problems, nil
+}
+
+// validateStoredResource checks one stored resource, acting on its problems
+// as mode says
+func (s *Service) validateStoredResource(t storedType, resource storedResource, mode string) ([]domain.DataProblem, error) {
+	var v domain.FieldViolations
+	var fix storedFix
+
+	validateName(&v, "name", resource.name)
+	if len(v) > 0 {
+		name := fixName(resource.name, t.name, resource.id)
+		fix.name = &name
+	}
+
+	n := len(v)
+	validateLabels(&v, resource.labels)
+	if len(v) > n {
+		fix.labels = fixLabels(resource.labels)
+	}
+
+	if t.name == "instance" && !storedStatuses[resource.status] {
+		v.Add("status", fmt.Sprintf("must be one of %s, %s, %s, %s (got %q)", domain.StatusRunning, domain.StatusStopped, domain.StatusDeleting, domain.StatusUnavailable, resource.status))
+		status := domain.StatusStopped
+		fix.status = &status
+	}
+
+	if len(v) == 0 {
+		return nil, nil
+	}
+
+	action := ""
+	switch {
+	case mode == domain.DataValidationFix:
+		if err := s.fixStoredResource(t, resource, fix); err != nil {
+			return nil, err
+		}
+		action = "fixed"
+	case mode == domain.DataValidationQuarantine && t.quarantine != nil:
+		if err := s.quarantineStoredResource(t, resource, v); err != nil {
+			return nil, err
+		}
+		action = "quarantined"
+	}
+
+	problems := make([]domain.DataProblem, len(v))
+	for i, violation := range v {
+		problems[i] = domain.DataProblem{
+			ResourceType: t.name,
+			ResourceID:   resource.id,
+			Field:        violation.Field,
+			Message:      violation.Message,
+			Action:       action,
+		}
+	}
+	return problems, nil
+}
+
+// fixStoredResource applies fix to a resource. A fixed name already taken
+// gets the resource's ID appended.
+func (s *Service) fixStoredResource(t storedType, resource storedResource, fix storedFix) error {
+	err := t.fix(s, resource.id, fix)
+	if fix.name != nil && domain.IsAlreadyExists(err) {
+		name := fixName(*fix.name+"-"+resource.id, t.name, resource.id)
+		fix.name = &name
+		err = t.fix(s, resource.id, fix)
+	}
+	return err
+}
+
+// quarantineStoredResource keeps a copy of a resource in the quarantine and
+// removes it, and its environment membership, in one transaction
+func (s *Service) quarantineStoredResource(t storedType, resource storedResource, v domain.FieldViolations) error {
+	data, err := json.Marshal(resource.value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s %s: %w", t.name, resource.id, err)
+	}
+
+	reasons := make([]string, len(v))
+	for i, violation := range v {
+		reasons[i] = violation.Field + ": " + violation.Message
+	}
+
+	return s.runInTx(func(tx *Service) error {
+		if err := tx.integrityRepo.Quarantine(&domain.QuarantinedResource{
+			ResourceType: t.name,
+			ResourceID:   resource.id,
+			Data:         data,
+			Problems:     reasons,
+		}); err != nil {
+			return err
+		}
+
+		ref := domain.ResourceRef{Type: t.name, ID: resource.id}
+		owner, err := tx.environmentRepo.EnvironmentOf(ref)
+		if err != nil {
+			return err
+		}
+		if owner != "" {
+			if err := tx.environmentRepo.RemoveResource(owner, ref); err != nil {
+				return err
+			}
+		}
+
+		return t.quarantine(tx, resource.id)
+	})
+}
+
+// ListQuarantined lists the resources quarantined by stored data
+// validation, oldest first
+func (s *Service) ListQuarantined() ([]*domain.QuarantinedResource, error) {
+	return s.integrityRepo.ListQuarantined()
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLegacyData stores, bypassing validation, a valid project "taken", a
+// project "taken!" whose fixed name clashes with it, and an instance with a
+// bad name, label and status in an environment
+func newLegacyData(t *testing.T, svc *Service) (*domain.Project, *domain.Instance, *domain.Environment) {
+	t.Helper()
+
+	require.NoError(t, svc.projectRepo.Create(&domain.Project{ID: "proj-1", Name: "taken"}))
+	project := &domain.Project{ID: "proj-2", Name: "taken!"}
+	require.NoError(t, svc.projectRepo.Create(project))
+
+	instance := &domain.Instance{
+		ID:        "inst-1",
+		ProjectID: "proj-1",
+		Name:      "web server",
+		CPU:       1,
+		MemoryMB:  512,
+		Image:     "ubuntu",
+		Status:    "booting",
+		Labels:    map[string]string{"Team": "infra"},
+	}
+	require.NoError(t, svc.instanceRepo.Create(instance))
+
+	env, err := svc.CreateEnvironment(domain.CreateEnvironmentRequest{
+		Name:      "legacy",
+		Resources: []domain.ResourceRef{{Type: "instance", ID: instance.ID}},
+	})
+	require.NoError(t, err)
+	return project, instance, env
+}
+
+// problemFields lists "type id field" for each problem, with its action
+func problemFields(problems []domain.DataProblem) map[string]string {
+	fields := map[string]string{}
+	for _, p := range problems {
+		fields[p.ResourceType+" "+p.ResourceID+" "+p.Field] = p.Action
+	}
+	return fields
+}
+
+func TestService_ValidateStoredData(t *testing.T) {
+	t.Run("warn", func(t *testing.T) {
+		svc := newTestService(t)
+		_, instance, _ := newLegacyData(t, svc)
+
+		problems, err := svc.ValidateStoredData(domain.DataValidationWarn)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"project proj-2 name":         "",
+			"instance inst-1 name":        "",
+			"instance inst-1 labels.Team": "",
+			"instance inst-1 status":      "",
+		}, problemFields(problems))
+
+		stored, err := svc.GetInstance(instance.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "web server", stored.Name, "warn changes nothing")
+	})
+
+	t.Run("fix", func(t *testing.T) {
+		svc := newTestService(t)
+		project, instance, _ := newLegacyData(t, svc)
+
+		problems, err := svc.ValidateStoredData(domain.DataValidationFix)
+		require.NoError(t, err)
+		assert.Len(t, problems, 4)
+		for _, p := range problems {
+			assert.Equal(t, "fixed", p.Action)
+		}
+
+		fixedProject, err := svc.GetProject(project.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "taken-proj-2", fixedProject.Name, "a fixed name already taken gets the ID")
+
+		fixed, err := svc.GetInstance(instance.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "web-server", fixed.Name)
+		assert.Equal(t, map[string]string{"team": "infra"}, fixed.Labels)
+		assert.Equal(t, domain.StatusStopped, fixed.Status)
+
+		problems, err = svc.ValidateStoredData(domain.DataValidationWarn)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("quarantine", func(t *testing.T) {
+		svc := newTestService(t)
+		_, instance, env := newLegacyData(t, svc)
+
+		problems, err := svc.ValidateStoredData(domain.DataValidationQuarantine)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"project proj-2 name":         "",
+			"instance inst-1 name":        "quarantined",
+			"instance inst-1 labels.Team": "quarantined",
+			"instance inst-1 status":      "quarantined",
+		}, problemFields(problems), "projects own other resources, so they are not quarantined")
+
+		_, err = svc.GetInstance(instance.ID)
+		assert.True(t, domain.IsNotFound(err))
+		env, err = svc.GetEnvironment(env.ID)
+		require.NoError(t, err)
+		assert.Empty(t, env.Resources)
+
+		quarantined, err := svc.ListQuarantined()
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		assert.Equal(t, "instance", quarantined[0].ResourceType)
+		assert.Equal(t, instance.ID, quarantined[0].ResourceID)
+		assert.Contains(t, string(quarantined[0].Data), `"name":"web server"`)
+		assert.Len(t, quarantined[0].Problems, 3)
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		_, err := newTestService(t).ValidateStoredData("strict")
+		assert.True(t, domain.IsInvalidInput(err))
+	})
+}
+
+func TestFixLabels(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"env":         "prod",
+		"cost_center": "a",
+	}, fixLabels(map[string]string{
+		"env":         "prod",
+		"Env":         "clash",
+		"Cost Center": "a",
+		"1st":         "dropped",
+	}))
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// databaseEngine describes a simulated database engine
+type databaseEngine struct {
+	versions []string // oldest first; the last is the default
+	port     int
+	scheme   string
+	options  string
+}
+
+// databaseEngines are the engines databases can be created with
+var databaseEngines = map[string]databaseEngine{
+	domain.DatabaseEnginePostgres: {versions: []string{"13", "14", "15", "16"}, port: 5432, scheme: "postgresql", options: "sslmode=require"},
+	domain.DatabaseEngineMySQL:    {versions: []string{"5.7", "8.0"}, port: 3306, scheme: "mysql", options: "tls=true"},
+}
+
+// databaseSizes are the sizes databases can be created with
+var databaseSizes = []string{"small", "medium", "large"}
+
+// defaultDatabaseSize is the size of databases created without one
+const defaultDatabaseSize = "small"
+
+// databaseUsername is the administrator every database is created with
+const databaseUsername = "dirtadmin"
+
+// databaseHostSuffix is the domain of simulated database hosts
+const databaseHostSuffix = ".db.dirtcloud.internal"
+
+// validateDatabaseSize validates the size of a database
+func validateDatabaseSize(v *domain.FieldViolations, size string) {
+	for _, s := range databaseSizes {
+		if s == size {
+			return
+		}
+	}
+	v.Add("size", fmt.Sprintf("must be one of %s (got %q)", strings.Join(databaseSizes, ", "), size))
+}
+
+// engineNames returns the known engine names, sorted
+func engineNames() []string {
+	names := make([]string, 0, len(databaseEngines))
+	for name := range databaseEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// generatePassword returns a random database password
+func generatePassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// connectionString returns the URL clients connect to a database with
+func connectionString(engine databaseEngine, database *domain.Database) string {
+	u := url.URL{
+		Scheme:   engine.scheme,
+		User:     url.UserPassword(database.Username, database.Password),
+		Host:     fmt.Sprintf("%s:%d", database.Host, database.Port),
+		Path:     "/" + database.DatabaseName,
+		RawQuery: engine.options,
+	}
+	return u.String()
+}
+
+// readyAt returns when a database provisioned from now becomes available
+func (s *Service) readyAt(now time.Time) *time.Time {
+	ready := now.Add(s.config.DatabaseProvisioningDelay)
+	return &ready
+}
+
+// Database operations
+
+// CreateDatabase creates a new database. It is returned provisioning, with
+// its connection details already generated, and becomes available after the
+// configured provisioning delay.
+func (s *Service) CreateDatabase(req domain.CreateDatabaseRequest) (*domain.Database, error) {
+	var v domain.FieldViolations
+	if req.ProjectID == "" {
+		v.Add("project_id", "cannot be empty")
+	}
+	validateName(&v, "name", req.Name)
+	engine, ok := databaseEngines[req.Engine]
+	if !ok {
+		v.Add("engine", fmt.Sprintf("must be one of %s (got %q)", strings.Join(engineNames(), ", "), req.Engine))
+	} else if req.Version != "" && !containsString(engine.versions, req.Version) {
+		v.Add("version", fmt.Sprintf("must be one of %s for %s (got %q)", strings.Join(engine.versions, ", "), req.Engine, req.Version))
+	}
+	if req.Size != "" {
+		validateDatabaseSize(&v, req.Size)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	version := req.Version
+	if version == "" {
+		version = engine.versions[len(engine.versions)-1]
+	}
+	size := req.Size
+	if size == "" {
+		size = defaultDatabaseSize
+	}
+
+	id, err := s.newID(kindDatabase)
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+	password, err := generatePassword()
+	if err != nil {
+		return nil, domain.InternalError("failed to generate password")
+	}
+
+	database := &domain.Database{
+		ID:           id,
+		ProjectID:    req.ProjectID,
+		Name:         req.Name,
+		Engine:       req.Engine,
+		Version:      version,
+		Size:         size,
+		Status:       domain.DatabaseProvisioning,
+		Host:         id + databaseHostSuffix,
+		Port:         engine.port,
+		DatabaseName: strings.ReplaceAll(req.Name, "-", "_"),
+		Username:     databaseUsername,
+		Password:     password,
+		ReadyAt:      s.readyAt(time.Now()),
+	}
+	database.ConnectionString = connectionString(engine, database)
+
+	if err := s.databaseRepo.Create(database); err != nil {
+		return nil, err
+	}
+
+	return database, nil
+}
+
+// GetDatabase retrieves a database by ID, completing its provisioning first
+// if it is due
+func (s *Service) GetDatabase(id string) (*domain.Database, error) {
+	database, err := s.databaseRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if database.ReadyAt != nil && !time.Now().Before(*database.ReadyAt) {
+		if err := s.WithActor(domain.ActorProvisioner).completeProvisioning(database); err != nil {
+			return nil, err
+		}
+		return s.databaseRepo.GetByID(id)
+	}
+
+	return database, nil
+}
+
+// ListDatabases lists databases with optional filtering
+func (s *Service) ListDatabases(opts domain.DatabaseListOptions) ([]*domain.Database, error) {
+	return s.databaseRepo.List(opts)
+}
+
+// UpdateDatabase updates a database. A new size puts the database into the
+// updating status until the provisioning delay passes; databases that are
+// not available cannot be updated.
+func (s *Service) UpdateDatabase(id string, req domain.UpdateDatabaseRequest) (*domain.Database, error) {
+	var v domain.FieldViolations
+	if req.Name != nil {
+		validateName(&v, "name", *req.Name)
+	}
+	if req.Size != nil {
+		validateDatabaseSize(&v, *req.Size)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.Database, error) {
+		current, err := tx.databaseRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if current.Status != domain.DatabaseAvailable {
+			return nil, domain.FailedPreconditionError(fmt.Sprintf("database %s is %s", current.Name, current.Status), map[string]interface{}{
+				"database_id": id,
+				"status":      current.Status,
+			}).WithReason(domain.ReasonResourceNotReady)
+		}
+
+		database, err := tx.databaseRepo.Update(id, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.Size != nil && *req.Size != current.Size {
+			database.Status, database.ReadyAt = domain.DatabaseUpdating, tx.readyAt(time.Now())
+			if err := tx.databaseRepo.SetStatus(id, database.Status, database.ReadyAt); err != nil {
+				return nil, err
+			}
+		}
+
+		return database, nil
+	})
+}
+
+// DeleteDatabase deletes a database
+func (s *Service) DeleteDatabase(id string) error {
+	return s.databaseRepo.Delete(id)
+}
+
+// completeProvisioning makes a provisioning or updating database available
+// and records an event
+func (s *Service) completeProvisioning(database *domain.Database) error {
+	return s.runInTx(func(tx *Service) error {
+		if err := tx.databaseRepo.SetStatus(database.ID, domain.DatabaseAvailable, nil); err != nil {
+			return err
+		}
+		tx.recordEvent(domain.EventDatabaseAvailable, "database", database.ID, database.ProjectID,
+			fmt.Sprintf("database %s is available: %s %s, %s", database.Name, database.Engine, database.Version, database.Size))
+		return nil
+	})
+}
+
+// ProvisionDatabases makes every database whose provisioning completes at or
+// before now available and returns how many were
+func (s *Service) ProvisionDatabases(now time.Time) (int, error) {
+	s = s.WithActor(domain.ActorProvisioner)
+
+	ready, err := s.databaseRepo.ListReady(now)
+	if err != nil {
+		return 0, err
+	}
+
+	provisioned := 0
+	for _, database := range ready {
+		if err := s.completeProvisioning(database); err != nil {
+			if domain.IsNotFound(err) {
+				continue // Deleted concurrently
+			}
+			return provisioned, err
+		}
+		provisioned++
+	}
+
+	return provisioned, nil
+}
+
+// RunDatabaseProvisioner completes database provisioning every interval until
+// ctx is done
+func (s *Service) RunDatabaseProvisioner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.ProvisionDatabases(now); err != nil {
+				log.Printf("Database provisioner failed: %v", err)
+			}
+		}
+	}
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
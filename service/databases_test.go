@@ -0,0 +1,107 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CreateDatabase(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "databases"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		req           domain.CreateDatabaseRequest
+		expectedField string
+	}{
+		{name: "postgres defaults", req: domain.CreateDatabaseRequest{ProjectID: project.ID, Name: "orders", Engine: "postgres"}},
+		{name: "mysql version", req: domain.CreateDatabaseRequest{ProjectID: project.ID, Name: "legacy", Engine: "mysql", Version: "5.7", Size: "large"}},
+		{name: "unknown engine", req: domain.CreateDatabaseRequest{ProjectID: project.ID, Name: "x", Engine: "oracle"}, expectedField: "engine"},
+		{name: "unknown version", req: domain.CreateDatabaseRequest{ProjectID: project.ID, Name: "x", Engine: "postgres", Version: "9.6"}, expectedField: "version"},
+		{name: "unknown size", req: domain.CreateDatabaseRequest{ProjectID: project.ID, Name: "x", Engine: "mysql", Size: "huge"}, expectedField: "size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, err := svc.CreateDatabase(tt.req)
+			if tt.expectedField != "" {
+				require.True(t, domain.IsInvalidInput(err), "expected invalid input, got %v", err)
+				assert.Contains(t, err.Error(), tt.expectedField)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, domain.DatabaseProvisioning, database.Status)
+			assert.NotEmpty(t, database.Password)
+
+			u, err := url.Parse(database.ConnectionString)
+			require.NoError(t, err)
+			password, _ := u.User.Password()
+			assert.Equal(t, database.Password, password)
+			assert.Equal(t, database.Username, u.User.Username())
+			assert.Equal(t, database.Host, u.Hostname())
+			assert.Equal(t, "/"+database.DatabaseName, u.Path)
+		})
+	}
+
+	orders, err := svc.ListDatabases(domain.DatabaseListOptions{Engine: "postgres"})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "16", orders[0].Version)
+	assert.Equal(t, "small", orders[0].Size)
+	assert.Equal(t, 5432, orders[0].Port)
+}
+
+func TestService_ProvisionDatabases(t *testing.T) {
+	svc := newTestService(t)
+	svc.config.DatabaseProvisioningDelay = time.Minute
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "provisioning"})
+	require.NoError(t, err)
+
+	database, err := svc.CreateDatabase(domain.CreateDatabaseRequest{ProjectID: project.ID, Name: "app", Engine: "postgres"})
+	require.NoError(t, err)
+	require.NotNil(t, database.ReadyAt)
+
+	// Databases cannot be changed until they are available
+	size := "medium"
+	_, err = svc.UpdateDatabase(database.ID, domain.UpdateDatabaseRequest{Size: &size})
+	assert.True(t, domain.IsFailedPrecondition(err))
+
+	n, err := svc.ProvisionDatabases(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = svc.ProvisionDatabases(database.ReadyAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	available, err := svc.GetDatabase(database.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DatabaseAvailable, available.Status)
+	assert.Nil(t, available.ReadyAt)
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceID: database.ID})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.EventDatabaseAvailable, events[0].Type)
+	assert.Equal(t, domain.ActorProvisioner, events[0].Actor)
+
+	// Resizing provisions again; a read after the delay completes it
+	svc.config.DatabaseProvisioningDelay = 0
+	resized, err := svc.UpdateDatabase(database.ID, domain.UpdateDatabaseRequest{Size: &size})
+	require.NoError(t, err)
+	assert.Equal(t, domain.DatabaseUpdating, resized.Status)
+	assert.Equal(t, "medium", resized.Size)
+
+	available, err = svc.GetDatabase(database.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DatabaseAvailable, available.Status)
+	assert.Equal(t, database.ConnectionString, available.ConnectionString)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// instanceReplaceFields returns the instance fields an update cannot change
+func (s *Service) instanceReplaceFields() map[string]bool {
+	fields := map[string]bool{"project_id": true, "zone": true, "class": true}
+	if !s.config.AllowImageUpdate {
+		fields["image"] = true
+	}
+	return fields
+}
+
+// DiffInstance compares the desired spec of an instance with its current
+// state, field by field. The desired values are validated as an update would
+// validate them, so a diff never proposes a change the API would reject.
+func (s *Service) DiffInstance(id string, req domain.InstanceDiffRequest) (*domain.ResourceDiff, error) {
+	current, err := s.GetInstance(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var v domain.FieldViolations
+	if req.ProjectID != nil && *req.ProjectID == "" {
+		v.Add("project_id", "cannot be empty")
+	}
+	if req.Name != nil {
+		validateName(&v, "name", *req.Name)
+	}
+	if req.CPU != nil || req.MemoryMB != nil || req.Image != nil {
+		cpu, memory, image := current.CPU, current.MemoryMB, current.Image
+		if req.CPU != nil {
+			cpu = *req.CPU
+		}
+		if req.MemoryMB != nil {
+			memory = *req.MemoryMB
+		}
+		if req.Image != nil {
+			image = *req.Image
+		}
+		validateInstanceSpecs(&v, cpu, memory, image)
+	}
+	if req.Status != nil {
+		validateInstanceStatus(&v, *req.Status)
+	}
+	validateLabels(&v, req.Labels)
+	if req.Zone != nil {
+		if *req.Zone == "" {
+			v.Add("zone", "cannot be empty")
+		}
+		s.validateZone(&v, *req.Zone)
+	}
+	if req.Class != nil {
+		validateInstanceClass(&v, *req.Class)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	replaceFields := s.instanceReplaceFields()
+	diff := &domain.ResourceDiff{ResourceType: "instance", ResourceID: id, Changes: []domain.FieldDiff{}}
+	change := func(field, action string, currentValue, desiredValue interface{}) {
+		replace := replaceFields[field]
+		diff.Changes = append(diff.Changes, domain.FieldDiff{
+			Field:           field,
+			Action:          action,
+			Current:         currentValue,
+			Desired:         desiredValue,
+			RequiresReplace: replace,
+		})
+		diff.RequiresReplace = diff.RequiresReplace || replace
+	}
+
+	if req.ProjectID != nil && *req.ProjectID != current.ProjectID {
+		change("project_id", domain.DiffChanged, current.ProjectID, *req.ProjectID)
+	}
+	if req.Name != nil && *req.Name != current.Name {
+		change("name", domain.DiffChanged, current.Name, *req.Name)
+	}
+	if req.CPU != nil && *req.CPU != current.CPU {
+		change("cpu", domain.DiffChanged, current.CPU, *req.CPU)
+	}
+	if req.MemoryMB != nil && *req.MemoryMB != current.MemoryMB {
+		change("memory_mb", domain.DiffChanged, current.MemoryMB, *req.MemoryMB)
+	}
+	if req.Image != nil && *req.Image != current.Image {
+		change("image", domain.DiffChanged, current.Image, *req.Image)
+	}
+	if req.Status != nil && *req.Status != current.Status {
+		change("status", domain.DiffChanged, current.Status, *req.Status)
+	}
+	if req.DeletionProtection != nil && *req.DeletionProtection != current.DeletionProtection {
+		change("deletion_protection", domain.DiffChanged, current.DeletionProtection, *req.DeletionProtection)
+	}
+	if req.Zone != nil && *req.Zone != current.Zone {
+		change("zone", domain.DiffChanged, current.Zone, *req.Zone)
+	}
+	if req.Class != nil && *req.Class != current.Class {
+		change("class", domain.DiffChanged, current.Class, *req.Class)
+	}
+	if req.AutoRepair != nil && *req.AutoRepair != current.AutoRepair {
+		change("auto_repair", domain.DiffChanged, current.AutoRepair, *req.AutoRepair)
+	}
+	if req.ExpiresAt != nil {
+		desired := req.ExpiresAt.UTC().Format(time.RFC3339)
+		switch {
+		case current.ExpiresAt == nil:
+			change("expires_at", domain.DiffAdded, nil, desired)
+		case !current.ExpiresAt.Equal(*req.ExpiresAt):
+			change("expires_at", domain.DiffChanged, current.ExpiresAt.UTC().Format(time.RFC3339), desired)
+		}
+	}
+
+	if req.Labels != nil {
+		for key, value := range req.Labels {
+			currentValue, ok := current.Labels[key]
+			switch {
+			case !ok:
+				change("labels."+key, domain.DiffAdded, nil, value)
+			case currentValue != value:
+				change("labels."+key, domain.DiffChanged, currentValue, value)
+			}
+		}
+		for key, value := range current.Labels {
+			if _, ok := req.Labels[key]; !ok {
+				change("labels."+key, domain.DiffRemoved, value, nil)
+			}
+		}
+	}
+
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		return diff.Changes[i].Field < diff.Changes[j].Field
+	})
+
+	return diff, nil
+}
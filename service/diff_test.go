@@ -0,0 +1,121 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_DiffInstance(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "diff"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+		Labels: map[string]string{"env": "dev", "team": "core"},
+	})
+	require.NoError(t, err)
+
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name            string
+		req             domain.InstanceDiffRequest
+		expected        []domain.FieldDiff
+		requiresReplace bool
+		expectedField   string
+	}{
+		{
+			name:     "matching spec",
+			req:      domain.InstanceDiffRequest{Name: str("web"), CPU: intPtr(1), Image: str("ubuntu")},
+			expected: []domain.FieldDiff{},
+		},
+		{
+			name: "in-place changes",
+			req:  domain.InstanceDiffRequest{CPU: intPtr(2), Status: str(domain.StatusStopped), DeletionProtection: boolPtr(true)},
+			expected: []domain.FieldDiff{
+				{Field: "cpu", Action: domain.DiffChanged, Current: 1, Desired: 2},
+				{Field: "deletion_protection", Action: domain.DiffChanged, Current: false, Desired: true},
+				{Field: "status", Action: domain.DiffChanged, Current: domain.StatusRunning, Desired: domain.StatusStopped},
+			},
+		},
+		{
+			name: "labels",
+			req:  domain.InstanceDiffRequest{Labels: map[string]string{"env": "prod", "tier": "web"}},
+			expected: []domain.FieldDiff{
+				{Field: "labels.env", Action: domain.DiffChanged, Current: "dev", Desired: "prod"},
+				{Field: "labels.team", Action: domain.DiffRemoved, Current: "core"},
+				{Field: "labels.tier", Action: domain.DiffAdded, Desired: "web"},
+			},
+		},
+		{
+			name: "moving project requires replacement",
+			req:  domain.InstanceDiffRequest{ProjectID: str("other")},
+			expected: []domain.FieldDiff{
+				{Field: "project_id", Action: domain.DiffChanged, Current: project.ID, Desired: "other", RequiresReplace: true},
+			},
+			requiresReplace: true,
+		},
+		{
+			name: "changing image requires replacement",
+			req:  domain.InstanceDiffRequest{Image: str("debian"), CPU: intPtr(2)},
+			expected: []domain.FieldDiff{
+				{Field: "cpu", Action: domain.DiffChanged, Current: 1, Desired: 2},
+				{Field: "image", Action: domain.DiffChanged, Current: "ubuntu", Desired: "debian", RequiresReplace: true},
+			},
+			requiresReplace: true,
+		},
+		{
+			name: "moving zone requires replacement",
+			req:  domain.InstanceDiffRequest{Zone: str(domain.DefaultZones[1])},
+			expected: []domain.FieldDiff{
+				{Field: "zone", Action: domain.DiffChanged, Current: domain.DefaultZones[0], Desired: domain.DefaultZones[1], RequiresReplace: true},
+			},
+			requiresReplace: true,
+		},
+		{
+			name: "changing class requires replacement",
+			req:  domain.InstanceDiffRequest{Class: str(domain.InstanceClassSpot)},
+			expected: []domain.FieldDiff{
+				{Field: "class", Action: domain.DiffChanged, Current: domain.InstanceClassStandard, Desired: domain.InstanceClassSpot, RequiresReplace: true},
+			},
+			requiresReplace: true,
+		},
+		{
+			name:          "unknown class",
+			req:           domain.InstanceDiffRequest{Class: str("reserved")},
+			expectedField: "class",
+		},
+		{
+			name:          "unknown zone",
+			req:           domain.InstanceDiffRequest{Zone: str("mars-1a")},
+			expectedField: "zone",
+		},
+		{
+			name:          "invalid desired value",
+			req:           domain.InstanceDiffRequest{MemoryMB: intPtr(0)},
+			expectedField: "memory_mb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := svc.DiffInstance(instance.ID, tt.req)
+			if tt.expectedField != "" {
+				require.True(t, domain.IsInvalidInput(err), "got %v", err)
+				assert.Equal(t, tt.expectedField, err.(*domain.DirtError).Details["fields"].([]domain.FieldViolation)[0].Field)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, instance.ID, diff.ResourceID)
+			assert.Equal(t, tt.expected, diff.Changes)
+			assert.Equal(t, tt.requiresReplace, diff.RequiresReplace)
+		})
+	}
+
+	_, err = svc.DiffInstance("missing", domain.InstanceDiffRequest{})
+	assert.True(t, domain.IsNotFound(err))
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// errDryRun rolls back the transaction of a dry run that succeeded
+var errDryRun = errors.New("dry run")
+
+// DryRun runs fn against a copy of s inside a transaction that is always
+// rolled back, and returns what fn returned. Nothing fn writes is kept: IDs
+// come from a random generator so a deterministic sequence does not advance,
+// and event stream subscribers are not notified of the events it records.
+func DryRun[T any](s *Service, fn func(tx *Service) (T, error)) (T, error) {
+	var result T
+	if s.uow == nil {
+		return result, domain.InternalError("dry runs require transactional storage")
+	}
+
+	dry := *s
+	dry.config.IDs = &IDGenerator{format: s.ids().format}
+	dry.hub = newEventHub()
+
+	var fnErr error
+	err := dry.uow.Do(func(repos Repositories) error {
+		tx := dry
+		tx.setRepositories(repos)
+
+		result, fnErr = fn(&tx)
+		if fnErr != nil {
+			return fnErr
+		}
+		return errDryRun
+	})
+	if fnErr != nil {
+		return result, fnErr
+	}
+	if !errors.Is(err, errDryRun) {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	ids, err := NewDeterministicIDGenerator(IDFormatPrefixed, 0)
+	require.NoError(t, err)
+	svc := newTestService(t)
+	svc.config.IDs = ids
+
+	project, err := DryRun(svc, func(tx *Service) (*domain.Project, error) {
+		return tx.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "web", project.Name)
+
+	projects, err := svc.ListProjects(domain.ProjectListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, projects, "a dry run is rolled back")
+
+	project, err = svc.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, "proj-0000000000000001", project.ID, "a dry run leaves the ID sequence alone")
+
+	_, err = DryRun(svc, func(tx *Service) (*domain.Project, error) {
+		return tx.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	})
+	assert.True(t, domain.IsAlreadyExists(err), "got %v", err)
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// environmentType is how environments check for and delete one type of
+// resource
+type environmentType struct {
+	name   string
+	exists func(s *Service, id string) error
+	delete func(s *Service, id string) error
+}
+
+// environmentTypes are the resource types an environment can group, in the
+// order a cascading delete removes them: dependents before what they depend
+// on, so that groups go before their template and instances before their
+// project.
+var environmentTypes = []environmentType{
+	{
+		name:   "autoscaling_group",
+		exists: func(s *Service, id string) error { _, err := s.groupRepo.GetByID(id); return err },
+		delete: (*Service).DeleteAutoscalingGroup,
+	},
+	{
+		name:   "instance",
+		exists: func(s *Service, id string) error { _, err := s.instanceRepo.GetByID(id); return err },
+		delete: (*Service).DeleteInstance,
+	},
+	{
+		name:   "instance_template",
+		exists: func(s *Service, id string) error { _, err := s.templateRepo.GetByID(id); return err },
+		delete: (*Service).DeleteInstanceTemplate,
+	},
+	{
+		name:   "subscription",
+		exists: func(s *Service, id string) error { _, err := s.subscriptionRepo.GetByID(id); return err },
+		delete: (*Service).DeleteSubscription,
+	},
+	{
+		name:   "topic",
+		exists: func(s *Service, id string) error { _, err := s.topicRepo.GetByID(id); return err },
+		delete: (*Service).DeleteTopic,
+	},
+	{
+		name:   "database",
+		exists: func(s *Service, id string) error { _, err := s.databaseRepo.GetByID(id); return err },
+		delete: (*Service).DeleteDatabase,
+	},
+	{
+		name:   "secret",
+		exists: func(s *Service, id string) error { _, err := s.secretRepo.GetByID(id); return err },
+		delete: (*Service).DeleteSecret,
+	},
+	{
+		name:   "budget",
+		exists: func(s *Service, id string) error { _, err := s.budgetRepo.GetByID(id); return err },
+		delete: (*Service).DeleteBudget,
+	},
+	{
+		name:   "metadata",
+		exists: func(s *Service, id string) error { _, err := s.metadataRepo.GetByID(id); return err },
+		delete: (*Service).DeleteMetadata,
+	},
+	{
+		name:   "project",
+		exists: func(s *Service, id string) error { _, err := s.projectRepo.GetByID(id); return err },
+		delete: func(s *Service, id string) error {
+			return s.DeleteProject(id, domain.DeleteProjectOptions{Cascade: true})
+		},
+	},
+}
+
+// findEnvironmentType returns the environment type named name
+func findEnvironmentType(name string) (environmentType, bool) {
+	for _, t := range environmentTypes {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return environmentType{}, false
+}
+
+// validateResourceType validates that a resource type is one an environment
+// can group, recording violations against field
+func validateResourceType(v *domain.FieldViolations, field, resourceType string) {
+	if _, ok := findEnvironmentType(resourceType); ok {
+		return
+	}
+	names := make([]string, 0, len(environmentTypes))
+	for _, t := range environmentTypes {
+		names = append(names, t.name)
+	}
+	v.Add(field, fmt.Sprintf("must be one of %s (got %q)", strings.Join(names, ", "), resourceType))
+}
+
+// validateResourceRefs validates the resources of a request
+func validateResourceRefs(v *domain.FieldViolations, refs []domain.ResourceRef) {
+	for i, ref := range refs {
+		validateResourceType(v, fmt.Sprintf("resources[%d].type", i), ref.Type)
+		if ref.ID == "" {
+			v.Add(fmt.Sprintf("resources[%d].id", i), "cannot be empty")
+		}
+	}
+}
+
+// Environment operations
+
+// CreateEnvironment creates a new environment grouping the given resources
+func (s *Service) CreateEnvironment(req domain.CreateEnvironmentRequest) (*domain.Environment, error) {
+	var v domain.FieldViolations
+	validateName(&v, "name", req.Name)
+	validateLabels(&v, req.Labels)
+	validateResourceRefs(&v, req.Resources)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	id, err := s.newID(kindEnvironment)
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+
+	return inTx(s, func(tx *Service) (*domain.Environment, error) {
+		env := &domain.Environment{ID: id, Name: req.Name, Labels: req.Labels}
+		if err := tx.environmentRepo.Create(env); err != nil {
+			return nil, err
+		}
+		if len(req.Resources) == 0 {
+			return env, nil
+		}
+		if err := tx.addEnvironmentResources(id, req.Resources); err != nil {
+			return nil, err
+		}
+		return tx.environmentRepo.GetByID(id)
+	})
+}
+
+// GetEnvironment retrieves an environment by ID
+func (s *Service) GetEnvironment(id string) (*domain.Environment, error) {
+	return s.environmentRepo.GetByID(id)
+}
+
+// ListEnvironments lists environments with optional filtering
+func (s *Service) ListEnvironments(opts domain.EnvironmentListOptions) ([]*domain.Environment, error) {
+	return s.environmentRepo.List(opts)
+}
+
+// UpdateEnvironment updates an environment's name and labels
+func (s *Service) UpdateEnvironment(id string, req domain.UpdateEnvironmentRequest) (*domain.Environment, error) {
+	var v domain.FieldViolations
+	if req.Name != nil {
+		validateName(&v, "name", *req.Name)
+	}
+	validateLabels(&v, req.Labels)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.environmentRepo.Update(id, req)
+}
+
+// AddEnvironmentResources adds existing resources to an environment. A
+// resource already in another environment cannot be added.
+func (s *Service) AddEnvironmentResources(id string, req domain.AddEnvironmentResourcesRequest) (*domain.Environment, error) {
+	var v domain.FieldViolations
+	if len(req.Resources) == 0 {
+		v.Add("resources", "cannot be empty")
+	}
+	validateResourceRefs(&v, req.Resources)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.Environment, error) {
+		if _, err := tx.environmentRepo.GetByID(id); err != nil {
+			return nil, err
+		}
+		if err := tx.addEnvironmentResources(id, req.Resources); err != nil {
+			return nil, err
+		}
+		return tx.environmentRepo.GetByID(id)
+	})
+}
+
+// addEnvironmentResources adds validated resources to an environment,
+// skipping those already in it
+func (s *Service) addEnvironmentResources(id string, refs []domain.ResourceRef) error {
+	for _, ref := range refs {
+		t, _ := findEnvironmentType(ref.Type)
+		if err := t.exists(s, ref.ID); err != nil {
+			if domain.IsNotFound(err) {
+				return domain.ForeignKeyViolationError(ref.Type, "id", ref.ID)
+			}
+			return err
+		}
+
+		owner, err := s.environmentRepo.EnvironmentOf(ref)
+		if err != nil {
+			return err
+		}
+		if owner == id {
+			continue
+		}
+		if owner != "" {
+			return domain.FailedPreconditionError(fmt.Sprintf("%s %s is already in environment %s", ref.Type, ref.ID, owner), map[string]interface{}{
+				"resource_type":  ref.Type,
+				"resource_id":    ref.ID,
+				"environment_id": owner,
+			}).WithReason(domain.ReasonResourceInUse)
+		}
+
+		if err := s.environmentRepo.AddResource(id, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveEnvironmentResource takes a resource out of an environment without
+// deleting it
+func (s *Service) RemoveEnvironmentResource(id string, ref domain.ResourceRef) error {
+	return s.runInTx(func(tx *Service) error {
+		if _, err := tx.environmentRepo.GetByID(id); err != nil {
+			return err
+		}
+		return tx.environmentRepo.RemoveResource(id, ref)
+	})
+}
+
+// GetEnvironmentSummary counts the resources of an environment, reporting
+// the ones that no longer exist
+func (s *Service) GetEnvironmentSummary(id string) (*domain.EnvironmentSummary, error) {
+	return inTx(s, func(tx *Service) (*domain.EnvironmentSummary, error) {
+		env, err := tx.environmentRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		summary := &domain.EnvironmentSummary{EnvironmentID: id, Types: map[string]int{}}
+		for _, ref := range env.Resources {
+			var err error
+			if ref.Type == "instance" {
+				var instance *domain.Instance
+				if instance, err = tx.instanceRepo.GetByID(ref.ID); err == nil {
+					if summary.InstanceStatuses == nil {
+						summary.InstanceStatuses = map[string]int{}
+					}
+					summary.InstanceStatuses[instance.Status]++
+				}
+			} else if t, ok := findEnvironmentType(ref.Type); ok {
+				err = t.exists(tx, ref.ID)
+			}
+			if domain.IsNotFound(err) {
+				summary.Missing = append(summary.Missing, ref)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			summary.Types[ref.Type]++
+			summary.Total++
+		}
+		return summary, nil
+	})
+}
+
+// DeleteEnvironment deletes an environment. Without opts.Cascade it must no
+// longer group any resources. With it, the environment's resources are
+// deleted too, in one transaction: if any cannot be, such as an instance with
+// deletion protection, nothing is deleted. Resources already gone are
+// skipped.
+func (s *Service) DeleteEnvironment(id string, opts domain.DeleteEnvironmentOptions) error {
+	return s.runInTx(func(tx *Service) error {
+		env, err := tx.environmentRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+
+		if !opts.Cascade && len(env.Resources) > 0 {
+			return domain.FailedPreconditionError(fmt.Sprintf("environment %s still has %d resources; delete with cascade to delete them too", env.Name, len(env.Resources)), map[string]interface{}{
+				"environment_id": id,
+				"resource_count": len(env.Resources),
+			}).WithReason(domain.ReasonResourceInUse)
+		}
+
+		for _, t := range environmentTypes {
+			for _, ref := range env.Resources {
+				if ref.Type != t.name {
+					continue
+				}
+				if err := t.delete(tx, ref.ID); err != nil && !domain.IsNotFound(err) {
+					return err
+				}
+			}
+		}
+
+		return tx.environmentRepo.Delete(id)
+	})
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEnvironmentScenario creates an environment holding a project, one of
+// its instances, an instance in an unrelated project and a metadata entry
+func newEnvironmentScenario(t *testing.T, svc *Service) (*domain.Environment, *domain.Project, *domain.Instance) {
+	t.Helper()
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "scenario"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	other, err := svc.CreateProject(domain.CreateProjectRequest{Name: "shared"})
+	require.NoError(t, err)
+	worker, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: other.ID, Name: "worker", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	m, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "scenario/seed", Value: "1"})
+	require.NoError(t, err)
+
+	env, err := svc.CreateEnvironment(domain.CreateEnvironmentRequest{
+		Name: "scenario",
+		Resources: []domain.ResourceRef{
+			{Type: "project", ID: project.ID},
+			{Type: "instance", ID: instance.ID},
+			{Type: "instance", ID: worker.ID},
+			{Type: "metadata", ID: m.ID},
+		},
+	})
+	require.NoError(t, err)
+	return env, other, worker
+}
+
+func TestService_DeleteEnvironment_Cascade(t *testing.T) {
+	svc := newTestService(t)
+	env, other, _ := newEnvironmentScenario(t, svc)
+
+	err := svc.DeleteEnvironment(env.ID, domain.DeleteEnvironmentOptions{})
+	require.True(t, domain.IsFailedPrecondition(err), "got %v", err)
+
+	require.NoError(t, svc.DeleteEnvironment(env.ID, domain.DeleteEnvironmentOptions{Cascade: true}))
+
+	_, err = svc.GetEnvironment(env.ID)
+	assert.True(t, domain.IsNotFound(err))
+	projects, err := svc.ListProjects(domain.ProjectListOptions{})
+	require.NoError(t, err)
+	require.Len(t, projects, 1, "only the project outside the environment is left")
+	assert.Equal(t, other.ID, projects[0].ID)
+	instances, err := svc.ListInstances(domain.InstanceListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+	metadata, err := svc.ListMetadata(domain.MetadataListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, metadata)
+}
+
+func TestService_DeleteEnvironment_AllOrNothing(t *testing.T) {
+	svc := newTestService(t)
+	env, _, worker := newEnvironmentScenario(t, svc)
+
+	protect := true
+	_, err := svc.UpdateInstance(worker.ID, domain.UpdateInstanceRequest{DeletionProtection: &protect})
+	require.NoError(t, err)
+
+	err = svc.DeleteEnvironment(env.ID, domain.DeleteEnvironmentOptions{Cascade: true})
+	require.True(t, domain.IsFailedPrecondition(err), "got %v", err)
+
+	summary, err := svc.GetEnvironmentSummary(env.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, summary.Total, "nothing was deleted")
+	assert.Empty(t, summary.Missing)
+}
+
+func TestService_EnvironmentResources(t *testing.T) {
+	svc := newTestService(t)
+	env, other, worker := newEnvironmentScenario(t, svc)
+
+	second, err := svc.CreateEnvironment(domain.CreateEnvironmentRequest{Name: "second"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		ref   domain.ResourceRef
+		check func(error) bool
+	}{
+		{name: "in another environment", ref: domain.ResourceRef{Type: "instance", ID: worker.ID}, check: domain.IsFailedPrecondition},
+		{name: "missing", ref: domain.ResourceRef{Type: "instance", ID: "nope"}, check: domain.IsForeignKeyViolation},
+		{name: "unknown type", ref: domain.ResourceRef{Type: "volume", ID: "v"}, check: domain.IsInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.AddEnvironmentResources(second.ID, domain.AddEnvironmentResourcesRequest{Resources: []domain.ResourceRef{tt.ref}})
+			assert.True(t, tt.check(err), "got %v", err)
+		})
+	}
+
+	// Adding a resource twice is harmless
+	updated, err := svc.AddEnvironmentResources(second.ID, domain.AddEnvironmentResourcesRequest{Resources: []domain.ResourceRef{
+		{Type: "project", ID: other.ID},
+		{Type: "project", ID: other.ID},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ResourceRef{{Type: "project", ID: other.ID}}, updated.Resources)
+
+	// A resource deleted on its own shows up as missing
+	require.NoError(t, svc.DeleteInstance(worker.ID))
+	summary, err := svc.GetEnvironmentSummary(env.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, map[string]int{"project": 1, "instance": 1, "metadata": 1}, summary.Types)
+	assert.Equal(t, map[string]int{domain.StatusRunning: 1}, summary.InstanceStatuses)
+	assert.Equal(t, []domain.ResourceRef{{Type: "instance", ID: worker.ID}}, summary.Missing)
+
+	require.NoError(t, svc.RemoveEnvironmentResource(env.ID, domain.ResourceRef{Type: "instance", ID: worker.ID}))
+	err = svc.RemoveEnvironmentResource(env.ID, domain.ResourceRef{Type: "instance", ID: worker.ID})
+	assert.True(t, domain.IsNotFound(err))
+}
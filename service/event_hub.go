@@ -0,0 +1,51 @@
+package service
+
+import "sync"
+
+// eventHub fans out notices of newly recorded events to subscribers, keyed by
+// project. A notice carries no event: subscribers read the new events from the
+// repository, so they only ever see events that were committed.
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[string]map[chan struct{}]struct{})}
+}
+
+// subscribe registers for notices about projectID's events. The returned
+// function unsubscribes.
+func (h *eventHub) subscribe(projectID string) (<-chan struct{}, func()) {
+	// A buffer of one lets notices arriving while the subscriber is busy
+	// coalesce instead of blocking the publisher
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[projectID] == nil {
+		h.subscribers[projectID] = make(map[chan struct{}]struct{})
+	}
+	h.subscribers[projectID][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[projectID], ch)
+		if len(h.subscribers[projectID]) == 0 {
+			delete(h.subscribers, projectID)
+		}
+	}
+}
+
+// notify tells projectID's subscribers that it may have new events
+func (h *eventHub) notify(projectID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[projectID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// recordEvent stores an event. Failures are logged rather than returned so
+// that the operation being recorded is not undone by a bookkeeping error.
+func (s *Service) recordEvent(eventType, resourceType, resourceID, projectID, message string) {
+	s.storeEvent(&domain.Event{
+		Type:         eventType,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ProjectID:    projectID,
+		Message:      message,
+	})
+}
+
+// recordStatusChange records an instance moving from one status to another,
+// from "" when it was created, for the reason set with WithReason
+func (s *Service) recordStatusChange(instance *domain.Instance, from, to string) {
+	if from == to {
+		return
+	}
+
+	reason := s.reason
+	if reason == "" {
+		reason = domain.ReasonUserRequest
+	}
+
+	message := fmt.Sprintf("instance %s changed from %s to %s (%s)", instance.Name, from, to, reason)
+	if from == "" {
+		message = fmt.Sprintf("instance %s created %s (%s)", instance.Name, to, reason)
+	}
+
+	s.storeEvent(&domain.Event{
+		Type:           domain.EventInstanceStatusChanged,
+		ResourceType:   "instance",
+		ResourceID:     instance.ID,
+		ProjectID:      instance.ProjectID,
+		Message:        message,
+		PreviousStatus: from,
+		Status:         to,
+		Reason:         reason,
+	})
+}
+
+// storeEvent assigns an event its ID and actor and stores it
+func (s *Service) storeEvent(event *domain.Event) {
+	id, err := s.newID(kindEvent)
+	if err != nil {
+		log.Printf("Failed to generate event ID: %v", err)
+		return
+	}
+	event.ID = id
+	event.Actor = s.actor
+
+	if err := s.eventRepo.Create(event); err != nil {
+		log.Printf("Failed to record %s event for %s %s: %v", event.Type, event.ResourceType, event.ResourceID, err)
+		return
+	}
+
+	if event.ProjectID != "" {
+		s.hub.notify(event.ProjectID)
+	}
+}
+
+// RecordAuthFailure records a failed authentication attempt from a source
+// address
+func (s *Service) RecordAuthFailure(source, message string) {
+	s.recordEvent(domain.EventAuthFailed, "source", source, "", message)
+}
+
+// RecordAuthLockout records that a source address was locked out after
+// repeated authentication failures
+func (s *Service) RecordAuthLockout(source, message string) {
+	s.recordEvent(domain.EventAuthLockedOut, "source", source, "", message)
+}
+
+// WithActor returns a service that records actor as the cause of its events
+func (s *Service) WithActor(actor string) *Service {
+	c := *s
+	c.actor = actor
+	return &c
+}
+
+// WithReason returns a service that records reason as the cause of the
+// status changes it makes. Without one, changes are attributed to a user
+// request.
+func (s *Service) WithReason(reason string) *Service {
+	c := *s
+	c.reason = reason
+	return &c
+}
+
+// ListEvents lists events with optional filtering
+func (s *Service) ListEvents(opts domain.EventListOptions) ([]*domain.Event, error) {
+	return s.eventRepo.List(opts)
+}
+
+// SubscribeProjectEvents returns a channel that receives a value when events
+// may have been recorded for a project, and a function that ends the
+// subscription. Notices for events recorded inside a transaction can arrive
+// before the transaction commits, so subscribers should also poll.
+func (s *Service) SubscribeProjectEvents(projectID string) (<-chan struct{}, func()) {
+	return s.hub.subscribe(projectID)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_InstanceStatusChanges(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "transitions"})
+	require.NoError(t, err)
+	tmpl, err := svc.CreateInstanceTemplate(domain.CreateInstanceTemplateRequest{
+		Name: "worker", CPU: 1, MemoryMB: 512, Image: "alpine",
+	})
+	require.NoError(t, err)
+
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+	stopped := domain.StatusStopped
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Status: &stopped})
+	require.NoError(t, err)
+	name := "web-1"
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Name: &name})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteInstance(instance.ID))
+
+	replayed, err := svc.WithReason(domain.ReasonChaos).CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "replayed", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	desired := 1
+	group, err := svc.CreateAutoscalingGroup(domain.CreateAutoscalingGroupRequest{
+		ProjectID: project.ID, Name: "workers", TemplateID: tmpl.ID, MinSize: 0, MaxSize: 1, DesiredSize: &desired,
+	})
+	require.NoError(t, err)
+	_, err = svc.ReconcileAutoscalingGroups()
+	require.NoError(t, err)
+	members, err := svc.ListInstances(domain.InstanceListOptions{AutoscalingGroupID: group.ID})
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	require.NoError(t, svc.DeleteAutoscalingGroup(group.ID))
+
+	type transition struct{ from, to, reason string }
+	transitions := func(id string) []transition {
+		events, err := svc.ListEvents(domain.EventListOptions{ResourceID: id, Type: domain.EventInstanceStatusChanged})
+		require.NoError(t, err)
+		var out []transition
+		for _, e := range events {
+			assert.Equal(t, project.ID, e.ProjectID)
+			out = append(out, transition{e.PreviousStatus, e.Status, e.Reason})
+		}
+		return out
+	}
+
+	assert.Equal(t, []transition{
+		{"", domain.StatusRunning, domain.ReasonUserRequest},
+		{domain.StatusRunning, domain.StatusStopped, domain.ReasonUserRequest},
+		{domain.StatusStopped, domain.StatusTerminated, domain.ReasonUserRequest},
+	}, transitions(instance.ID), "renames are not transitions")
+	assert.Equal(t, []transition{
+		{"", domain.StatusRunning, domain.ReasonChaos},
+	}, transitions(replayed.ID))
+	assert.Equal(t, []transition{
+		{"", domain.StatusRunning, domain.ReasonAutoscaler},
+		{domain.StatusRunning, domain.StatusTerminated, domain.ReasonUserRequest},
+	}, transitions(members[0].ID), "members are terminated with their group")
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// defaultUnhealthyThreshold is how many probes in a row an instance fails
+// before it is unhealthy when the service does not set it
+const defaultUnhealthyThreshold = 3
+
+// healthProbes holds the state of the simulated health probes. It is shared
+// by every copy of the service.
+type healthProbes struct {
+	mu sync.Mutex
+
+	// failures counts, by instance, the probes failed in a row
+	failures map[string]int
+
+	// rates overrides, by instance, the probability that probes fail
+	rates map[string]float64
+}
+
+func newHealthProbes() *healthProbes {
+	return &healthProbes{failures: make(map[string]int), rates: make(map[string]float64)}
+}
+
+// probe runs a probe of instance id, failing with the instance's failure
+// rate or else defaultRate as drawn by roll, and returns how many probes in
+// a row it failed
+func (h *healthProbes) probe(id string, defaultRate float64, roll func() float64) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rate, ok := h.rates[id]
+	if !ok {
+		rate = defaultRate
+	}
+	if rate > 0 && roll() < rate {
+		h.failures[id]++
+	} else {
+		delete(h.failures, id)
+	}
+	return h.failures[id]
+}
+
+// forget drops the probe state of instance id
+func (h *healthProbes) forget(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.failures, id)
+	delete(h.rates, id)
+}
+
+// unhealthyThreshold returns how many probes in a row an instance fails
+// before it is unhealthy
+func (s *Service) unhealthyThreshold() int {
+	if s.config.UnhealthyThreshold > 0 {
+		return s.config.UnhealthyThreshold
+	}
+	return defaultUnhealthyThreshold
+}
+
+// CheckInstanceHealth probes every running instance once. Instances that
+// fail enough probes in a row become unhealthy and those that pass one
+// become healthy again. Unhealthy instances with auto-repair are replaced.
+// It returns how many instances were repaired.
+func (s *Service) CheckInstanceHealth() (int, error) {
+	s = s.WithActor(domain.ActorHealthCheck)
+
+	running, err := s.instanceRepo.List(domain.InstanceListOptions{Status: domain.StatusRunning})
+	if err != nil {
+		return 0, err
+	}
+
+	repaired := 0
+	for _, instance := range running {
+		health := domain.HealthHealthy
+		if s.health.probe(instance.ID, s.config.ProbeFailureRate, s.randFloat64) >= s.unhealthyThreshold() {
+			health = domain.HealthUnhealthy
+		}
+
+		if health != instance.Health {
+			updated, err := s.setHealth(instance, health)
+			if err != nil {
+				if domain.IsNotFound(err) {
+					continue // Deleted concurrently
+				}
+				return repaired, err
+			}
+			instance = updated
+		}
+
+		// Expired instances are left to the reaper rather than replaced
+		expired := instance.ExpiresAt != nil && !instance.ExpiresAt.After(time.Now())
+		if instance.Health == domain.HealthUnhealthy && instance.AutoRepair && !expired {
+			if _, err := s.repairInstance(instance); err != nil {
+				if domain.IsNotFound(err) {
+					continue
+				}
+				return repaired, err
+			}
+			repaired++
+		}
+	}
+
+	return repaired, nil
+}
+
+// setHealth changes the health of an instance, recording an event
+func (s *Service) setHealth(instance *domain.Instance, health string) (*domain.Instance, error) {
+	return inTx(s, func(tx *Service) (*domain.Instance, error) {
+		updated, err := tx.instanceRepo.SetHealth(instance.ID, health)
+		if err != nil {
+			return nil, err
+		}
+		tx.recordEvent(domain.EventInstanceHealthChanged, "instance", instance.ID, instance.ProjectID,
+			fmt.Sprintf("instance %s changed from %s to %s", instance.Name, instance.Health, health))
+		return updated, nil
+	})
+}
+
+// repairInstance replaces an unhealthy instance with a new one with the same
+// spec, name, zone and autoscaling group but a new ID. The unhealthy
+// instance is removed first, regardless of deletion protection, so that the
+// replacement takes its name and capacity.
+func (s *Service) repairInstance(instance *domain.Instance) (*domain.Instance, error) {
+	replacement, err := inTx(s.WithReason(domain.ReasonAutoRepair), func(tx *Service) (*domain.Instance, error) {
+		if err := tx.instanceRepo.Delete(instance.ID); err != nil {
+			return nil, err
+		}
+		tx.recordStatusChange(instance, instance.Status, domain.StatusTerminated)
+
+		replacement, err := tx.createInstance(domain.CreateInstanceRequest{
+			ProjectID:          instance.ProjectID,
+			Name:               instance.Name,
+			CPU:                instance.CPU,
+			MemoryMB:           instance.MemoryMB,
+			Image:              instance.Image,
+			Status:             domain.StatusRunning,
+			Labels:             instance.Labels,
+			ExpiresAt:          instance.ExpiresAt,
+			DeletionProtection: instance.DeletionProtection,
+			Zone:               instance.Zone,
+			Class:              instance.Class,
+			AutoRepair:         instance.AutoRepair,
+		}, instance.AutoscalingGroupID)
+		if err != nil {
+			return nil, err
+		}
+
+		tx.recordEvent(domain.EventInstanceRepaired, "instance", instance.ID, instance.ProjectID,
+			fmt.Sprintf("unhealthy instance %s was replaced by %s", instance.Name, replacement.ID))
+		return replacement, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.health.forget(instance.ID)
+	return replacement, nil
+}
+
+// SetProbeFailures overrides how often the health probes of an instance fail
+func (s *Service) SetProbeFailures(id string, failures domain.ProbeFailures) (*domain.Instance, error) {
+	if failures.FailureRate < 0 || failures.FailureRate > 1 {
+		return nil, domain.ValidationError([]domain.FieldViolation{{
+			Field:   "failure_rate",
+			Message: fmt.Sprintf("must be between 0 and 1 (got %g)", failures.FailureRate),
+		}})
+	}
+
+	instance, err := s.instanceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.health.mu.Lock()
+	s.health.rates[id] = failures.FailureRate
+	s.health.mu.Unlock()
+	return instance, nil
+}
+
+// ClearProbeFailures returns the health probes of an instance to failing at
+// the service's probe failure rate
+func (s *Service) ClearProbeFailures(id string) (*domain.Instance, error) {
+	instance, err := s.instanceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.health.mu.Lock()
+	delete(s.health.rates, id)
+	s.health.mu.Unlock()
+	return instance, nil
+}
+
+// RunHealthChecker probes instances and repairs unhealthy ones every
+// interval until ctx is done
+func (s *Service) RunHealthChecker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.CheckInstanceHealth(); err != nil {
+				log.Printf("Health checker failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Health checker repaired %d instance(s)", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckInstanceHealth(t *testing.T) {
+	svc := newTestService(t)
+	svc.config.UnhealthyThreshold = 2
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "health"})
+	require.NoError(t, err)
+	create := func(name string, autoRepair bool) *domain.Instance {
+		instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu",
+			Labels: map[string]string{"app": "web"}, Zone: domain.DefaultZones[1], AutoRepair: autoRepair,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, domain.HealthHealthy, instance.Health)
+		return instance
+	}
+	sick := create("sick", false)
+	repairable := create("repairable", true)
+
+	for _, instance := range []*domain.Instance{sick, repairable} {
+		_, err := svc.SetProbeFailures(instance.ID, domain.ProbeFailures{FailureRate: 1})
+		require.NoError(t, err)
+	}
+
+	// One failed probe is below the threshold
+	n, err := svc.CheckInstanceHealth()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	got, err := svc.GetInstance(sick.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.HealthHealthy, got.Health)
+
+	n, err = svc.CheckInstanceHealth()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	got, err = svc.GetInstance(sick.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.HealthUnhealthy, got.Health)
+
+	// The repairable instance is replaced by a healthy one with a new ID
+	_, err = svc.GetInstance(repairable.ID)
+	assert.True(t, domain.IsNotFound(err))
+	replacements, err := svc.ListInstances(domain.InstanceListOptions{ProjectID: project.ID, Name: "repairable"})
+	require.NoError(t, err)
+	require.Len(t, replacements, 1)
+	replacement := replacements[0]
+	assert.NotEqual(t, repairable.ID, replacement.ID)
+	assert.Equal(t, domain.HealthHealthy, replacement.Health)
+	assert.Equal(t, repairable.Zone, replacement.Zone)
+	assert.Equal(t, repairable.Labels, replacement.Labels)
+	assert.True(t, replacement.AutoRepair)
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceID: repairable.ID, Type: domain.EventInstanceRepaired})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Contains(t, events[0].Message, replacement.ID)
+	assert.Equal(t, domain.ActorHealthCheck, events[0].Actor)
+
+	// A passing probe makes the instance healthy again
+	_, err = svc.ClearProbeFailures(sick.ID)
+	require.NoError(t, err)
+	_, err = svc.CheckInstanceHealth()
+	require.NoError(t, err)
+	got, err = svc.GetInstance(sick.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.HealthHealthy, got.Health)
+
+	events, err = svc.ListEvents(domain.EventListOptions{ResourceID: sick.ID, Type: domain.EventInstanceHealthChanged})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	_, err = svc.SetProbeFailures(sick.ID, domain.ProbeFailures{FailureRate: 2})
+	assert.True(t, domain.IsInvalidInput(err))
+}
+
+func TestCheckInstanceHealth_Seeded(t *testing.T) {
+	// unhealthy returns the instances a pass finds unhealthy with the source
+	// seeded with seed
+	unhealthy := func(seed int64) []string {
+		ids, err := NewDeterministicIDGenerator(IDFormatPrefixed, 0)
+		require.NoError(t, err)
+		svc := newTestService(t)
+		svc.config.IDs = ids
+		svc.config.UnhealthyThreshold = 1
+		svc.config.ProbeFailureRate = 0.5
+		svc.config.Rand = rand.New(rand.NewSource(seed))
+
+		project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "health"})
+		require.NoError(t, err)
+		for i := 0; i < 20; i++ {
+			_, err := svc.CreateInstance(domain.CreateInstanceRequest{
+				ProjectID: project.ID, Name: fmt.Sprintf("vm-%d", i), CPU: 1, MemoryMB: 512, Image: "ubuntu",
+			})
+			require.NoError(t, err)
+		}
+
+		_, err = svc.CheckInstanceHealth()
+		require.NoError(t, err)
+		events, err := svc.ListEvents(domain.EventListOptions{Type: domain.EventInstanceHealthChanged})
+		require.NoError(t, err)
+		var unhealthy []string
+		for _, event := range events {
+			unhealthy = append(unhealthy, event.ResourceID)
+		}
+		sort.Strings(unhealthy)
+		return unhealthy
+	}
+
+	first := unhealthy(1)
+	assert.NotEmpty(t, first)
+	assert.Less(t, len(first), 20)
+	assert.Equal(t, first, unhealthy(1), "the same seed fails the same probes")
+}
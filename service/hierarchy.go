@@ -0,0 +1,470 @@
+package service
+
+import (
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// maxFolderDepth bounds folder nesting, mirroring the limits of real clouds
+const maxFolderDepth = 10
+
+// validateQuota validates quota limits
+func validateQuota(v *domain.FieldViolations, quota *domain.Quota) {
+	if quota == nil {
+		return
+	}
+	if quota.MaxInstances < 0 {
+		v.Add("quota.max_instances", "cannot be negative")
+	}
+	if quota.MaxCPU < 0 {
+		v.Add("quota.max_cpu", "cannot be negative")
+	}
+	if quota.MaxMemoryMB < 0 {
+		v.Add("quota.max_memory_mb", "cannot be negative")
+	}
+}
+
+// Organization operations
+
+// CreateOrganization creates a new organization
+func (s *Service) CreateOrganization(req domain.CreateOrganizationRequest) (*domain.Organization, error) {
+	var v domain.FieldViolations
+	validateName(&v, "name", req.Name)
+	validateLabels(&v, req.Labels)
+	validateQuota(&v, req.Quota)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	id, err := s.newID(kindOrganization)
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+
+	org := &domain.Organization{
+		ID:     id,
+		Name:   req.Name,
+		Labels: req.Labels,
+		Quota:  req.Quota,
+	}
+
+	if err := s.organizationRepo.Create(org); err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// GetOrganization retrieves an organization by ID
+func (s *Service) GetOrganization(id string) (*domain.Organization, error) {
+	return s.organizationRepo.GetByID(id)
+}
+
+// ListOrganizations lists organizations with optional filtering
+func (s *Service) ListOrganizations(opts domain.OrganizationListOptions) ([]*domain.Organization, error) {
+	return s.organizationRepo.List(opts)
+}
+
+// UpdateOrganization updates an existing organization
+func (s *Service) UpdateOrganization(id string, req domain.UpdateOrganizationRequest) (*domain.Organization, error) {
+	var v domain.FieldViolations
+	if req.Name != nil {
+		validateName(&v, "name", *req.Name)
+	}
+	validateLabels(&v, req.Labels)
+	validateQuota(&v, req.Quota)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.organizationRepo.Update(id, req)
+}
+
+// DeleteOrganization deletes an organization with no remaining children
+func (s *Service) DeleteOrganization(id string) error {
+	return s.runInTx(func(tx *Service) error {
+		return tx.organizationRepo.Delete(id)
+	})
+}
+
+// ListOrganizationChildren lists the folders and projects at the root of an organization
+func (s *Service) ListOrganizationChildren(id string) (*domain.HierarchyChildren, error) {
+	if _, err := s.organizationRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	folders, err := s.folderRepo.List(domain.FolderListOptions{OrganizationID: id})
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.projectRepo.List(domain.ProjectListOptions{OrganizationID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	children := &domain.HierarchyChildren{
+		Folders:  []*domain.Folder{},
+		Projects: []*domain.Project{},
+	}
+	for _, f := range folders {
+		if f.ParentID == "" {
+			children.Folders = append(children.Folders, f)
+		}
+	}
+	for _, p := range projects {
+		if p.FolderID == "" {
+			children.Projects = append(children.Projects, p)
+		}
+	}
+
+	return children, nil
+}
+
+// Folder operations
+
+// CreateFolder creates a new folder under an organization or another folder
+func (s *Service) CreateFolder(req domain.CreateFolderRequest) (*domain.Folder, error) {
+	return inTx(s, func(tx *Service) (*domain.Folder, error) {
+		var v domain.FieldViolations
+		validateName(&v, "name", req.Name)
+		validateLabels(&v, req.Labels)
+		validateQuota(&v, req.Quota)
+		if req.OrganizationID == "" && req.ParentID == "" {
+			v.Add("organization_id", "one of organization_id or parent_id is required")
+		}
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+
+		orgID, parentID, err := tx.resolveParent(req.OrganizationID, req.ParentID, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if parentID != "" {
+			depth, err := tx.folderDepth(parentID)
+			if err != nil {
+				return nil, err
+			}
+			if depth+1 > maxFolderDepth {
+				return nil, domain.InvalidInputError("folder nesting too deep", map[string]interface{}{
+					"max_depth": maxFolderDepth,
+				})
+			}
+		}
+
+		id, err := tx.newID(kindFolder)
+		if err != nil {
+			return nil, domain.InternalError("failed to generate ID")
+		}
+
+		folder := &domain.Folder{
+			ID:             id,
+			OrganizationID: orgID,
+			ParentID:       parentID,
+			Name:           req.Name,
+			Labels:         req.Labels,
+			Quota:          req.Quota,
+		}
+
+		if err := tx.folderRepo.Create(folder); err != nil {
+			return nil, err
+		}
+
+		return folder, nil
+	})
+}
+
+// GetFolder retrieves a folder by ID
+func (s *Service) GetFolder(id string) (*domain.Folder, error) {
+	return s.folderRepo.GetByID(id)
+}
+
+// ListFolders lists folders with optional filtering
+func (s *Service) ListFolders(opts domain.FolderListOptions) ([]*domain.Folder, error) {
+	return s.folderRepo.List(opts)
+}
+
+// UpdateFolder updates an existing folder
+func (s *Service) UpdateFolder(id string, req domain.UpdateFolderRequest) (*domain.Folder, error) {
+	var v domain.FieldViolations
+	if req.Name != nil {
+		validateName(&v, "name", *req.Name)
+	}
+	validateLabels(&v, req.Labels)
+	validateQuota(&v, req.Quota)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.folderRepo.Update(id, req)
+}
+
+// DeleteFolder deletes a folder with no remaining children
+func (s *Service) DeleteFolder(id string) error {
+	return s.runInTx(func(tx *Service) error {
+		return tx.folderRepo.Delete(id)
+	})
+}
+
+// MoveFolder moves a folder under another folder or to its organization root.
+// Folders cannot change organization or be moved beneath themselves.
+func (s *Service) MoveFolder(id string, req domain.MoveRequest) (*domain.Folder, error) {
+	return inTx(s, func(tx *Service) (*domain.Folder, error) {
+		folder, err := tx.folderRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		orgID, parentID, err := tx.resolveParent(req.OrganizationID, req.FolderID, true)
+		if err != nil {
+			return nil, err
+		}
+		if orgID != folder.OrganizationID {
+			return nil, domain.InvalidInputError("folders cannot be moved between organizations", map[string]interface{}{
+				"organization_id":        folder.OrganizationID,
+				"target_organization_id": orgID,
+			})
+		}
+
+		// Walk up from the new parent to make sure we don't create a cycle
+		for current := parentID; current != ""; {
+			if current == id {
+				return nil, domain.InvalidInputError("cannot move a folder beneath itself", map[string]interface{}{
+					"folder_id": id,
+					"parent_id": parentID,
+				})
+			}
+			parent, err := tx.folderRepo.GetByID(current)
+			if err != nil {
+				return nil, err
+			}
+			current = parent.ParentID
+		}
+
+		return tx.folderRepo.Move(id, parentID)
+	})
+}
+
+// ListFolderChildren lists the folders and projects directly under a folder
+func (s *Service) ListFolderChildren(id string) (*domain.HierarchyChildren, error) {
+	if _, err := s.folderRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	folders, err := s.folderRepo.List(domain.FolderListOptions{ParentID: id})
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.projectRepo.List(domain.ProjectListOptions{FolderID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	children := &domain.HierarchyChildren{
+		Folders:  append([]*domain.Folder{}, folders...),
+		Projects: append([]*domain.Project{}, projects...),
+	}
+	return children, nil
+}
+
+// Project hierarchy operations
+
+// MoveProject moves a project under a folder or to an organization root
+func (s *Service) MoveProject(id string, req domain.MoveRequest) (*domain.Project, error) {
+	return inTx(s, func(tx *Service) (*domain.Project, error) {
+		if _, err := tx.projectRepo.GetByID(id); err != nil {
+			return nil, err
+		}
+
+		orgID, folderID, err := tx.resolveParent(req.OrganizationID, req.FolderID, true)
+		if err != nil {
+			return nil, err
+		}
+
+		return tx.projectRepo.Move(id, orgID, folderID)
+	})
+}
+
+// GetProjectAncestry returns a project's ancestors with the labels and quota
+// it inherits. Labels set closer to the project override those set higher up;
+// for quotas the most restrictive limit along the chain applies.
+func (s *Service) GetProjectAncestry(id string) (*domain.ProjectAncestry, error) {
+	project, err := s.projectRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.projectAncestry(project)
+}
+
+// projectAncestry resolves the ancestry of an already loaded project
+func (s *Service) projectAncestry(project *domain.Project) (*domain.ProjectAncestry, error) {
+	ancestry := &domain.ProjectAncestry{
+		Project:         project,
+		Ancestors:       []domain.HierarchyNode{},
+		EffectiveLabels: map[string]string{},
+	}
+
+	// Collect label and quota layers from the project upwards
+	var labelLayers []map[string]string
+	var quotas []*domain.Quota
+
+	for current := project.FolderID; current != ""; {
+		folder, err := s.folderRepo.GetByID(current)
+		if err != nil {
+			return nil, err
+		}
+		ancestry.Ancestors = append([]domain.HierarchyNode{{
+			Type: domain.NodeTypeFolder,
+			ID:   folder.ID,
+			Name: folder.Name,
+		}}, ancestry.Ancestors...)
+		labelLayers = append(labelLayers, folder.Labels)
+		quotas = append(quotas, folder.Quota)
+		current = folder.ParentID
+	}
+
+	if project.OrganizationID != "" {
+		org, err := s.organizationRepo.GetByID(project.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		ancestry.Ancestors = append([]domain.HierarchyNode{{
+			Type: domain.NodeTypeOrganization,
+			ID:   org.ID,
+			Name: org.Name,
+		}}, ancestry.Ancestors...)
+		labelLayers = append(labelLayers, org.Labels)
+		quotas = append(quotas, org.Quota)
+	}
+
+	// Apply labels from the root down so nearer nodes win
+	for i := len(labelLayers) - 1; i >= 0; i-- {
+		for k, v := range labelLayers[i] {
+			ancestry.EffectiveLabels[k] = v
+		}
+	}
+	for k, v := range project.Labels {
+		ancestry.EffectiveLabels[k] = v
+	}
+
+	for _, q := range quotas {
+		if q == nil {
+			continue
+		}
+		ancestry.EffectiveQuota.MaxInstances = minLimit(ancestry.EffectiveQuota.MaxInstances, q.MaxInstances)
+		ancestry.EffectiveQuota.MaxCPU = minLimit(ancestry.EffectiveQuota.MaxCPU, q.MaxCPU)
+		ancestry.EffectiveQuota.MaxMemoryMB = minLimit(ancestry.EffectiveQuota.MaxMemoryMB, q.MaxMemoryMB)
+	}
+
+	return ancestry, nil
+}
+
+// minLimit returns the more restrictive of two limits where zero means unlimited
+func minLimit(a, b int) int {
+	if a == 0 {
+		return b
+	}
+	if b == 0 || a < b {
+		return a
+	}
+	return b
+}
+
+// checkInstanceQuota verifies that adding an instance with the given size
+// keeps the project within its inherited quota. When replacingID is set that
+// instance is excluded from the totals, as it is being resized.
+func (s *Service) checkInstanceQuota(project *domain.Project, cpu int, memoryMB int, replacingID string) error {
+	if project.OrganizationID == "" {
+		return nil
+	}
+
+	ancestry, err := s.projectAncestry(project)
+	if err != nil {
+		return err
+	}
+	quota := ancestry.EffectiveQuota
+	if quota == (domain.Quota{}) {
+		return nil
+	}
+
+	instances, err := s.instanceRepo.List(domain.InstanceListOptions{ProjectID: project.ID})
+	if err != nil {
+		return err
+	}
+
+	count, totalCPU, totalMemory := 1, cpu, memoryMB
+	for _, i := range instances {
+		if i.ID == replacingID {
+			continue
+		}
+		count++
+		totalCPU += i.CPU
+		totalMemory += i.MemoryMB
+	}
+
+	if quota.MaxInstances > 0 && count > quota.MaxInstances {
+		return domain.QuotaExceededError("project", "max_instances", quota.MaxInstances, count)
+	}
+	if quota.MaxCPU > 0 && totalCPU > quota.MaxCPU {
+		return domain.QuotaExceededError("project", "max_cpu", quota.MaxCPU, totalCPU)
+	}
+	if quota.MaxMemoryMB > 0 && totalMemory > quota.MaxMemoryMB {
+		return domain.QuotaExceededError("project", "max_memory_mb", quota.MaxMemoryMB, totalMemory)
+	}
+
+	return nil
+}
+
+// resolveParent validates a parent reference given as an organization and/or
+// folder ID and returns the owning organization and folder. When both are
+// given the folder must belong to the organization.
+func (s *Service) resolveParent(orgID string, folderID string, required bool) (string, string, error) {
+	if folderID != "" {
+		folder, err := s.folderRepo.GetByID(folderID)
+		if err != nil {
+			if domain.IsNotFound(err) {
+				return "", "", domain.ForeignKeyViolationError("folder", "id", folderID)
+			}
+			return "", "", err
+		}
+		if orgID != "" && orgID != folder.OrganizationID {
+			return "", "", domain.InvalidInputError("folder does not belong to organization", map[string]interface{}{
+				"folder_id":       folderID,
+				"organization_id": orgID,
+			})
+		}
+		return folder.OrganizationID, folder.ID, nil
+	}
+
+	if orgID != "" {
+		if _, err := s.organizationRepo.GetByID(orgID); err != nil {
+			if domain.IsNotFound(err) {
+				return "", "", domain.ForeignKeyViolationError("organization", "id", orgID)
+			}
+			return "", "", err
+		}
+		return orgID, "", nil
+	}
+
+	if required {
+		return "", "", domain.InvalidInputError("one of organization_id or folder_id is required", nil)
+	}
+	return "", "", nil
+}
+
+// folderDepth returns the nesting depth of a folder, where a folder at the
+// organization root has depth 1
+func (s *Service) folderDepth(id string) (int, error) {
+	depth := 0
+	for current := id; current != ""; depth++ {
+		if depth > maxFolderDepth {
+			break
+		}
+		folder, err := s.folderRepo.GetByID(current)
+		if err != nil {
+			return 0, err
+		}
+		current = folder.ParentID
+	}
+	return depth, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectAncestry_InheritsLabelsAndQuota(t *testing.T) {
+	svc := newTestService(t)
+
+	org, err := svc.CreateOrganization(domain.CreateOrganizationRequest{
+		Name:   "acme",
+		Labels: map[string]string{"env": "prod", "team": "core"},
+		Quota:  &domain.Quota{MaxInstances: 5, MaxCPU: 8},
+	})
+	require.NoError(t, err)
+
+	folder, err := svc.CreateFolder(domain.CreateFolderRequest{
+		OrganizationID: org.ID,
+		Name:           "platform",
+		Labels:         map[string]string{"team": "platform"},
+		Quota:          &domain.Quota{MaxInstances: 2},
+	})
+	require.NoError(t, err)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{
+		Name:     "web",
+		FolderID: folder.ID,
+		Labels:   map[string]string{"app": "web"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, org.ID, project.OrganizationID)
+
+	ancestry, err := svc.GetProjectAncestry(project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.HierarchyNode{
+		{Type: domain.NodeTypeOrganization, ID: org.ID, Name: "acme"},
+		{Type: domain.NodeTypeFolder, ID: folder.ID, Name: "platform"},
+	}, ancestry.Ancestors)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "platform", "app": "web"}, ancestry.EffectiveLabels)
+	assert.Equal(t, domain.Quota{MaxInstances: 2, MaxCPU: 8}, ancestry.EffectiveQuota)
+
+	// The folder allows two instances per project
+	for _, name := range []string{"a", "b"} {
+		_, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 256, Image: "ubuntu",
+		})
+		require.NoError(t, err)
+	}
+	_, err = svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "c", CPU: 1, MemoryMB: 256, Image: "ubuntu",
+	})
+	require.Error(t, err)
+	assert.Equal(t, domain.ErrorCodeQuotaExceeded, err.(*domain.DirtError).Code)
+}
+
+func TestMoveFolder_RejectsCycles(t *testing.T) {
+	svc := newTestService(t)
+
+	org, err := svc.CreateOrganization(domain.CreateOrganizationRequest{Name: "acme"})
+	require.NoError(t, err)
+	parent, err := svc.CreateFolder(domain.CreateFolderRequest{OrganizationID: org.ID, Name: "parent"})
+	require.NoError(t, err)
+	child, err := svc.CreateFolder(domain.CreateFolderRequest{ParentID: parent.ID, Name: "child"})
+	require.NoError(t, err)
+
+	_, err = svc.MoveFolder(parent.ID, domain.MoveRequest{FolderID: child.ID})
+	require.Error(t, err)
+	assert.True(t, domain.IsInvalidInput(err))
+
+	moved, err := svc.MoveFolder(child.ID, domain.MoveRequest{OrganizationID: org.ID})
+	require.NoError(t, err)
+	assert.Empty(t, moved.ParentID)
+
+	children, err := svc.ListOrganizationChildren(org.ID)
+	require.NoError(t, err)
+	assert.Len(t, children.Folders, 2)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// roleRank orders IAM roles so that a higher role implies every lower one
+var roleRank = map[string]int{
+	domain.RoleViewer: 1,
+	domain.RoleEditor: 2,
+	domain.RoleAdmin:  3,
+}
+
+// RoleGrants reports whether holding role held grants role
+func RoleGrants(held, role string) bool {
+	if held == role {
+		return true
+	}
+	return roleRank[held] > 0 && roleRank[held] >= roleRank[role]
+}
+
+// validateBindings validates the role bindings of an IAM policy
+func validateBindings(v *domain.FieldViolations, bindings []domain.IAMBinding) {
+	for i, binding := range bindings {
+		field := fmt.Sprintf("bindings[%d]", i)
+		if _, ok := roleRank[binding.Role]; !ok && binding.Role != domain.RoleSecretAccessor {
+			v.Add(field+".role", fmt.Sprintf("must be one of %s, %s, %s, %s (got %q)",
+				domain.RoleViewer, domain.RoleEditor, domain.RoleAdmin, domain.RoleSecretAccessor, binding.Role))
+		}
+		if len(binding.Members) == 0 {
+			v.Add(field+".members", "cannot be empty")
+		}
+		for j, member := range binding.Members {
+			if !validMember(member) {
+				v.Add(fmt.Sprintf("%s.members[%d]", field, j),
+					fmt.Sprintf("must be %q, %q or %q followed by an identifier (got %q)", domain.MemberPrefixToken, domain.MemberPrefixServiceAccount, domain.MemberPrefixUser, member))
+			}
+		}
+	}
+}
+
+// validMember reports whether member has a known prefix and a non-empty identifier
+func validMember(member string) bool {
+	for _, prefix := range []string{domain.MemberPrefixToken, domain.MemberPrefixServiceAccount, domain.MemberPrefixUser} {
+		if strings.HasPrefix(member, prefix) {
+			return len(member) > len(prefix)
+		}
+	}
+	return false
+}
+
+// policyEtag derives an etag from the stored bindings of a policy
+func policyEtag(bindings []domain.IAMBinding) string {
+	data, _ := json.Marshal(bindings)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// IAM operations
+
+// GetProjectIAMPolicy retrieves the IAM policy of a project
+func (s *Service) GetProjectIAMPolicy(projectID string) (*domain.IAMPolicy, error) {
+	if _, err := s.projectRepo.GetByID(projectID); err != nil {
+		return nil, err
+	}
+
+	bindings, err := s.iamRepo.GetBindings(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if bindings == nil {
+		bindings = []domain.IAMBinding{}
+	}
+
+	return &domain.IAMPolicy{
+		ProjectID: projectID,
+		Bindings:  bindings,
+		Etag:      policyEtag(bindings),
+	}, nil
+}
+
+// SetProjectIAMPolicy replaces the IAM policy of a project. A request carrying
+// an etag is rejected if the policy changed since that etag was read.
+func (s *Service) SetProjectIAMPolicy(projectID string, req domain.SetIAMPolicyRequest) (*domain.IAMPolicy, error) {
+	return inTx(s, func(tx *Service) (*domain.IAMPolicy, error) {
+		var v domain.FieldViolations
+		validateBindings(&v, req.Bindings)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+
+		current, err := tx.GetProjectIAMPolicy(projectID)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.Etag != "" && req.Etag != current.Etag {
+			return nil, domain.FailedPreconditionError("IAM policy was modified concurrently", map[string]interface{}{
+				"project_id": projectID,
+				"etag":       current.Etag,
+			}).WithReason(domain.ReasonConcurrentModification)
+		}
+
+		if err := tx.iamRepo.SetBindings(projectID, req.Bindings); err != nil {
+			return nil, err
+		}
+
+		return tx.GetProjectIAMPolicy(projectID)
+	})
+}
+
+// IsIAMMember reports whether member is bound to a role on any project
+func (s *Service) IsIAMMember(member string) (bool, error) {
+	return s.iamRepo.HasMember(member)
+}
+
+// ProjectRole returns the highest role member holds on a project, or "" if none
+func (s *Service) ProjectRole(projectID, member string) (string, error) {
+	roles, err := s.iamRepo.ListRoles(projectID, member)
+	if err != nil {
+		return "", err
+	}
+
+	best := ""
+	for _, role := range roles {
+		if roleRank[role] > roleRank[best] {
+			best = role
+		}
+	}
+	return best, nil
+}
+
+// CheckProjectPermission returns a permission denied error unless member holds
+// at least the given role on a project
+func (s *Service) CheckProjectPermission(projectID, member, role string) error {
+	held, err := s.ProjectRole(projectID, member)
+	if err != nil {
+		return err
+	}
+
+	if roleRank[held] < roleRank[role] {
+		return domain.PermissionDeniedError(fmt.Sprintf("%s requires role %s on project", member, role), map[string]interface{}{
+			"project_id":    projectID,
+			"member":        member,
+			"required_role": role,
+		})
+	}
+
+	return nil
+}
+
+// CheckSecretAccess returns a permission denied error unless member may access
+// the payloads of a project's secrets, as a secret accessor or an admin
+func (s *Service) CheckSecretAccess(projectID, member string) error {
+	roles, err := s.iamRepo.ListRoles(projectID, member)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		if role == domain.RoleSecretAccessor || role == domain.RoleAdmin {
+			return nil
+		}
+	}
+
+	return domain.PermissionDeniedError(fmt.Sprintf("%s requires role %s on project to access secret payloads", member, domain.RoleSecretAccessor), map[string]interface{}{
+		"project_id":    projectID,
+		"member":        member,
+		"required_role": domain.RoleSecretAccessor,
+	})
+}
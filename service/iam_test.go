@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_SetProjectIAMPolicy(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "iam"})
+	require.NoError(t, err)
+
+	empty, err := svc.GetProjectIAMPolicy(project.ID)
+	require.NoError(t, err)
+	assert.Empty(t, empty.Bindings)
+
+	policy, err := svc.SetProjectIAMPolicy(project.ID, domain.SetIAMPolicyRequest{
+		Etag: empty.Etag,
+		Bindings: []domain.IAMBinding{
+			{Role: domain.RoleViewer, Members: []string{"token:bob", "token:alice"}},
+			{Role: domain.RoleAdmin, Members: []string{"serviceAccount:ci@dirt"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.IAMBinding{
+		{Role: domain.RoleAdmin, Members: []string{"serviceAccount:ci@dirt"}},
+		{Role: domain.RoleViewer, Members: []string{"token:alice", "token:bob"}},
+	}, policy.Bindings)
+	assert.NotEqual(t, empty.Etag, policy.Etag)
+
+	// A stale etag is rejected
+	_, err = svc.SetProjectIAMPolicy(project.ID, domain.SetIAMPolicyRequest{Etag: empty.Etag})
+	assert.True(t, domain.IsFailedPrecondition(err))
+
+	_, err = svc.SetProjectIAMPolicy("missing", domain.SetIAMPolicyRequest{})
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestValidateBindings(t *testing.T) {
+	tests := []struct {
+		name          string
+		binding       domain.IAMBinding
+		expectedField string
+	}{
+		{name: "valid", binding: domain.IAMBinding{Role: domain.RoleEditor, Members: []string{"token:a", "serviceAccount:b", "user:c"}}},
+		{name: "unknown role", binding: domain.IAMBinding{Role: "owner", Members: []string{"token:a"}}, expectedField: "bindings[0].role"},
+		{name: "no members", binding: domain.IAMBinding{Role: domain.RoleViewer}, expectedField: "bindings[0].members"},
+		{name: "unknown member type", binding: domain.IAMBinding{Role: domain.RoleViewer, Members: []string{"group:a"}}, expectedField: "bindings[0].members[0]"},
+		{name: "empty identifier", binding: domain.IAMBinding{Role: domain.RoleViewer, Members: []string{"token:"}}, expectedField: "bindings[0].members[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v domain.FieldViolations
+			validateBindings(&v, []domain.IAMBinding{tt.binding})
+
+			if tt.expectedField == "" {
+				assert.Empty(t, v)
+				return
+			}
+			require.NotEmpty(t, v)
+			assert.Equal(t, tt.expectedField, v[0].Field)
+		})
+	}
+}
+
+func TestService_CheckProjectPermission(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "iam"})
+	require.NoError(t, err)
+	_, err = svc.SetProjectIAMPolicy(project.ID, domain.SetIAMPolicyRequest{Bindings: []domain.IAMBinding{
+		{Role: domain.RoleViewer, Members: []string{"token:alice"}},
+		{Role: domain.RoleEditor, Members: []string{"token:alice", "token:bob"}},
+	}})
+	require.NoError(t, err)
+
+	role, err := svc.ProjectRole(project.ID, "token:alice")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleEditor, role, "the highest bound role wins")
+
+	assert.NoError(t, svc.CheckProjectPermission(project.ID, "token:bob", domain.RoleViewer))
+	assert.NoError(t, svc.CheckProjectPermission(project.ID, "token:bob", domain.RoleEditor))
+	assert.True(t, domain.IsPermissionDenied(svc.CheckProjectPermission(project.ID, "token:bob", domain.RoleAdmin)))
+	assert.True(t, domain.IsPermissionDenied(svc.CheckProjectPermission(project.ID, "token:carol", domain.RoleViewer)))
+
+	// Bindings go away with the project
+	require.NoError(t, svc.DeleteProject(project.ID, domain.DeleteProjectOptions{}))
+	bound, err := svc.IsIAMMember("token:alice")
+	require.NoError(t, err)
+	assert.False(t, bound)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDGenerator(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected []string
+	}{
+		{format: IDFormatHex, expected: []string{"0000000000000000000000000000000b", "0000000000000000000000000000000c"}},
+		{format: IDFormatUUID, expected: []string{"00000000-0000-4000-8000-00000000000b", "00000000-0000-4000-8000-00000000000c"}},
+		{format: IDFormatPrefixed, expected: []string{"proj-000000000000000b", "inst-000000000000000c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			random, err := NewIDGenerator(tt.format)
+			require.NoError(t, err)
+			first, err := random.New(kindProject)
+			require.NoError(t, err)
+			second, err := random.New(kindProject)
+			require.NoError(t, err)
+			assert.NotEqual(t, first, second)
+			assert.True(t, random.Matches(first), "random ID %q has the format's shape", first)
+			assert.False(t, random.Matches("web"))
+
+			seeded, err := NewDeterministicIDGenerator(tt.format, 10)
+			require.NoError(t, err)
+			for round := 0; round < 2; round++ {
+				project, err := seeded.New(kindProject)
+				require.NoError(t, err)
+				instance, err := seeded.New(kindInstance)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, []string{project, instance})
+				assert.True(t, seeded.Matches(project))
+
+				seeded.Reset()
+			}
+		})
+	}
+
+	_, err := NewIDGenerator("base64")
+	assert.Error(t, err)
+}
+
+func TestService_DeterministicIDs(t *testing.T) {
+	ids, err := NewDeterministicIDGenerator(IDFormatPrefixed, 0)
+	require.NoError(t, err)
+
+	svc := newTestService(t)
+	svc.config.IDs = ids
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, "proj-0000000000000001", project.ID)
+
+	entry, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/config", Value: "v1"})
+	require.NoError(t, err)
+	assert.Equal(t, "meta-0000000000000002", entry.ID)
+
+	// Lookups tell IDs from names by the configured format
+	id, err := svc.ResolveProjectID("web")
+	require.NoError(t, err)
+	assert.Equal(t, project.ID, id)
+
+	require.NoError(t, svc.ResetData())
+	project, err = svc.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, "proj-0000000000000001", project.ID, "reset restarts the sequence")
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// normalizeMetadataPath returns the normal form of a metadata path: without
+// surrounding whitespace, a leading slash or empty segments. A trailing
+// slash, which marks a folder in the Consul KV API, is kept.
+func normalizeMetadataPath(path string) string {
+	path = strings.TrimSpace(path)
+
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	normalized := strings.Join(segments, "/")
+	if normalized != "" && strings.HasSuffix(path, "/") {
+		normalized += "/"
+	}
+	return normalized
+}
+
+// CheckIntegrity looks for data the API would not have written: rows
+// referring to missing rows, environments listing deleted resources and
+// metadata paths that are not normalized. With repair, it also fixes what it
+// can, all in one transaction.
+func (s *Service) CheckIntegrity(repair bool) (*domain.IntegrityReport, error) {
+	return inTx(s, func(tx *Service) (*domain.IntegrityReport, error) {
+		issues, err := tx.integrityRepo.CheckReferences(repair)
+		if err != nil {
+			return nil, err
+		}
+
+		environmentIssues, err := tx.checkEnvironmentResources(repair)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, environmentIssues...)
+
+		metadataIssues, err := tx.checkMetadataPaths(repair)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, metadataIssues...)
+
+		report := &domain.IntegrityReport{Issues: []domain.IntegrityIssue{}}
+		for _, issue := range issues {
+			if issue.Repaired {
+				report.Repaired++
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+		return report, nil
+	})
+}
+
+// checkEnvironmentResources reports environment resources that no longer
+// exist, removing them from their environment on repair
+func (s *Service) checkEnvironmentResources(repair bool) ([]domain.IntegrityIssue, error) {
+	envs, err := s.environmentRepo.List(domain.EnvironmentListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []domain.IntegrityIssue
+	for _, env := range envs {
+		for _, ref := range env.Resources {
+			issue := domain.IntegrityIssue{
+				Check:        domain.IntegrityDanglingReference,
+				ResourceType: "environment",
+				ResourceID:   env.ID,
+			}
+
+			t, ok := findEnvironmentType(ref.Type)
+			if !ok {
+				issue.Message = fmt.Sprintf("environment %s lists %s %s, which is not a resource type environments can hold", env.ID, ref.Type, ref.ID)
+			} else if err := t.exists(s, ref.ID); domain.IsNotFound(err) {
+				issue.Message = fmt.Sprintf("environment %s lists %s %s, which does not exist", env.ID, ref.Type, ref.ID)
+			} else if err != nil {
+				return nil, err
+			} else {
+				continue
+			}
+
+			issue.Repair = fmt.Sprintf("remove %s %s from the environment", ref.Type, ref.ID)
+			if repair {
+				if err := s.environmentRepo.RemoveResource(env.ID, ref); err != nil {
+					return nil, err
+				}
+				issue.Repaired = true
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
+
+// checkMetadataPaths reports metadata paths that are not normalized,
+// renaming them to their normal form on repair. A path whose normal form is
+// empty or already taken is left for a person to sort out.
+func (s *Service) checkMetadataPaths(repair bool) ([]domain.IntegrityIssue, error) {
+	var entries []*domain.Metadata
+	err := s.metadataRepo.Stream(domain.MetadataListOptions{}, func(m *domain.Metadata) error {
+		if normalizeMetadataPath(m.Path) != m.Path {
+			entries = append(entries, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []domain.IntegrityIssue
+	for _, m := range entries {
+		normalized := normalizeMetadataPath(m.Path)
+		issue := domain.IntegrityIssue{
+			Check:        domain.IntegrityMetadataPath,
+			ResourceType: "metadata",
+			ResourceID:   m.ID,
+		}
+
+		if normalized == "" {
+			issue.Message = fmt.Sprintf("metadata path %q has no segments", m.Path)
+			issues = append(issues, issue)
+			continue
+		}
+
+		_, err := s.metadataRepo.GetByPath(normalized)
+		if err == nil {
+			issue.Message = fmt.Sprintf("metadata path %q is not normalized, and its normal form %q is taken", m.Path, normalized)
+			issues = append(issues, issue)
+			continue
+		}
+		if !domain.IsNotFound(err) {
+			return nil, err
+		}
+
+		issue.Message = fmt.Sprintf("metadata path %q is not normalized", m.Path)
+		issue.Repair = fmt.Sprintf("rename to %q", normalized)
+		if repair {
+			if _, err := s.metadataRepo.Update(m.ID, domain.UpdateMetadataRequest{Path: &normalized}); err != nil {
+				return nil, err
+			}
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
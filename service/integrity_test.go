@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeMetadataPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "app/config", want: "app/config"},
+		{path: "app/", want: "app/"},
+		{path: "/app/config", want: "app/config"},
+		{path: "app//config", want: "app/config"},
+		{path: " app/config\n", want: "app/config"},
+		{path: "//app//", want: "app/"},
+		{path: "/", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeMetadataPath(tt.path))
+		})
+	}
+}
+
+func TestService_CheckIntegrity(t *testing.T) {
+	svc := newTestService(t)
+
+	report, err := svc.CheckIntegrity(false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+
+	for _, path := range []string{"/app/config", "app//taken", "app/taken", "/"} {
+		_, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: path, Value: "v"})
+		require.NoError(t, err)
+	}
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "scenario"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+	env, err := svc.CreateEnvironment(domain.CreateEnvironmentRequest{
+		Name:      "scenario",
+		Resources: []domain.ResourceRef{{Type: "project", ID: project.ID}, {Type: "instance", ID: instance.ID}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteInstance(instance.ID))
+
+	report, err = svc.CheckIntegrity(false)
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 4)
+	assert.Zero(t, report.Repaired)
+
+	report, err = svc.CheckIntegrity(true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Repaired)
+
+	repaired := map[string]bool{}
+	for _, issue := range report.Issues {
+		repaired[issue.Message] = issue.Repaired
+	}
+	assert.Equal(t, map[string]bool{
+		`environment ` + env.ID + ` lists instance ` + instance.ID + `, which does not exist`:    true,
+		`metadata path "/" has no segments`:                                                      false,
+		`metadata path "/app/config" is not normalized`:                                          true,
+		`metadata path "app//taken" is not normalized, and its normal form "app/taken" is taken`: false,
+	}, repaired)
+
+	_, err = svc.GetMetadataByPath("app/config")
+	assert.NoError(t, err)
+	env, err = svc.GetEnvironment(env.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ResourceRef{{Type: "project", ID: project.ID}}, env.Resources)
+
+	report, err = svc.CheckIntegrity(false)
+	require.NoError(t, err)
+	assert.Len(t, report.Issues, 2, "only the issues that need a person are left")
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// looksLikeID reports whether a resource identifier is an ID rather than a
+// name, judging by the shape of the IDs the service generates
+func (s *Service) looksLikeID(idOrName string) bool {
+	return s.ids().Matches(idOrName)
+}
+
+// ResolveProjectID returns the ID of the project addressed by idOrName.
+// Identifiers that look like IDs are returned unchanged without a lookup.
+func (s *Service) ResolveProjectID(idOrName string) (string, error) {
+	if s.looksLikeID(idOrName) {
+		return idOrName, nil
+	}
+
+	projects, err := s.projectRepo.List(domain.ProjectListOptions{Name: idOrName})
+	if err != nil {
+		return "", err
+	}
+	if len(projects) == 0 {
+		return "", domain.NotFoundError("project", idOrName)
+	}
+
+	return projects[0].ID, nil
+}
+
+// ResolveInstanceID returns the ID of the instance addressed by idOrName.
+// Instance names are only unique within a project, so resolving a name needs
+// the project, itself given by ID or name. Identifiers that look like IDs are
+// returned unchanged without a lookup.
+func (s *Service) ResolveInstanceID(idOrName, projectIDOrName string) (string, error) {
+	if s.looksLikeID(idOrName) {
+		return idOrName, nil
+	}
+
+	if projectIDOrName == "" {
+		return "", domain.ValidationError([]domain.FieldViolation{
+			{Field: "project", Message: "is required to look up an instance by name"},
+		})
+	}
+
+	projectID, err := s.ResolveProjectID(projectIDOrName)
+	if err != nil {
+		return "", err
+	}
+
+	instances, err := s.instanceRepo.List(domain.InstanceListOptions{ProjectID: projectID, Name: idOrName})
+	if err != nil {
+		return "", err
+	}
+	switch len(instances) {
+	case 0:
+		return "", domain.NotFoundError("instance", idOrName)
+	case 1:
+		return instances[0].ID, nil
+	default:
+		return "", domain.InvalidInputError("instance name matches more than one instance; address it by ID", map[string]interface{}{
+			"project_id": projectID,
+			"name":       idOrName,
+			"count":      len(instances),
+		})
+	}
+}
+
+// projectScopedTypes are the lookup types whose resources live in a project
+var projectScopedTypes = []string{
+	"instance",
+	"autoscaling_group",
+	"budget",
+	"database",
+	"topic",
+	"subscription",
+	"secret",
+}
+
+// parseSelector parses a comma-separated list of key=value labels
+func parseSelector(selector string) (map[string]string, bool) {
+	labels := map[string]string{}
+	for _, part := range strings.Split(selector, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || key == "" {
+			return nil, false
+		}
+		labels[key] = value
+	}
+	return labels, true
+}
+
+// Lookup finds the one resource of a type with the given name and labels, as
+// terraform import and data sources need. Matching nothing is NOT_FOUND and
+// matching several is AMBIGUOUS_MATCH, listing the matches by ID, rather than
+// picking one. visible, when not nil, reports whether the caller may view a
+// project's resources; resources it may not are left out before counting.
+func (s *Service) Lookup(opts domain.LookupOptions, visible func(projectID string) (bool, error)) (*domain.SearchResult, error) {
+	var v domain.FieldViolations
+	if !containsString(SearchTypes, opts.Type) {
+		v.Add("type", fmt.Sprintf("must be one of %s (got %q)", strings.Join(SearchTypes, ", "), opts.Type))
+	}
+	if opts.Name == "" && opts.Selector == "" {
+		v.Add("name", "is required unless a selector is given")
+	}
+	var selector map[string]string
+	if opts.Selector != "" {
+		var ok bool
+		if selector, ok = parseSelector(opts.Selector); !ok {
+			v.Add("selector", "must be a comma-separated list of key=value labels")
+		}
+	}
+	if opts.Project != "" && !containsString(projectScopedTypes, opts.Type) {
+		v.Add("project", fmt.Sprintf("does not apply to %s resources", opts.Type))
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	projectID := ""
+	if opts.Project != "" {
+		var err error
+		if projectID, err = s.ResolveProjectID(opts.Project); err != nil {
+			return nil, err
+		}
+	}
+
+	found, err := s.searchType(opts.Type, func(name string, labels map[string]string) bool {
+		if opts.Name != "" && name != opts.Name {
+			return false
+		}
+		for key, value := range selector {
+			if got, ok := labels[key]; !ok || got != value {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []domain.SearchResult
+	for _, result := range found {
+		if projectID != "" && result.ProjectID != projectID {
+			continue
+		}
+		if visible != nil {
+			owner := result.ProjectID
+			if result.Type == "project" {
+				owner = result.ID
+			}
+			if owner == "" {
+				continue
+			}
+			ok, err := visible(owner)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		matches = append(matches, result)
+	}
+
+	switch len(matches) {
+	case 0:
+		identifier := opts.Name
+		if identifier == "" {
+			identifier = opts.Selector
+		}
+		return nil, domain.NotFoundError(opts.Type, identifier)
+	case 1:
+		return &matches[0], nil
+	default:
+		sort.Slice(matches, func(i, j int) bool { return matches[i].ID < matches[j].ID })
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		return nil, domain.AmbiguousMatchError(opts.Type, ids)
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_ResolveIDs(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "web"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "frontend", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	unknownID := "0123456789abcdef0123456789abcdef"
+
+	projectTests := []struct {
+		name        string
+		idOrName    string
+		expected    string
+		expectedErr func(error) bool
+	}{
+		{name: "by ID", idOrName: project.ID, expected: project.ID},
+		{name: "by name", idOrName: "web", expected: project.ID},
+		{name: "unknown ID is not looked up", idOrName: unknownID, expected: unknownID},
+		{name: "unknown name", idOrName: "missing", expectedErr: domain.IsNotFound},
+	}
+
+	for _, tt := range projectTests {
+		t.Run("project "+tt.name, func(t *testing.T) {
+			id, err := svc.ResolveProjectID(tt.idOrName)
+			if tt.expectedErr != nil {
+				assert.True(t, tt.expectedErr(err), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, id)
+		})
+	}
+
+	instanceTests := []struct {
+		name        string
+		idOrName    string
+		project     string
+		expected    string
+		expectedErr func(error) bool
+	}{
+		{name: "by ID", idOrName: instance.ID, expected: instance.ID},
+		{name: "by name and project ID", idOrName: "frontend", project: project.ID, expected: instance.ID},
+		{name: "by name and project name", idOrName: "frontend", project: "web", expected: instance.ID},
+		{name: "name without project", idOrName: "frontend", expectedErr: domain.IsInvalidInput},
+		{name: "unknown name", idOrName: "backend", project: "web", expectedErr: domain.IsNotFound},
+		{name: "unknown project", idOrName: "frontend", project: "missing", expectedErr: domain.IsNotFound},
+	}
+
+	for _, tt := range instanceTests {
+		t.Run("instance "+tt.name, func(t *testing.T) {
+			id, err := svc.ResolveInstanceID(tt.idOrName, tt.project)
+			if tt.expectedErr != nil {
+				assert.True(t, tt.expectedErr(err), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, id)
+		})
+	}
+}
+
+func TestService_Lookup(t *testing.T) {
+	svc := newTestService(t)
+
+	web, err := svc.CreateProject(domain.CreateProjectRequest{Name: "web", Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	api, err := svc.CreateProject(domain.CreateProjectRequest{Name: "api", Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	var instances []*domain.Instance
+	for _, p := range []*domain.Project{web, api} {
+		instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: p.ID, Name: "frontend", CPU: 1, MemoryMB: 512, Image: "ubuntu", Labels: map[string]string{"tier": "web"},
+		})
+		require.NoError(t, err)
+		instances = append(instances, instance)
+	}
+
+	result, err := svc.Lookup(domain.LookupOptions{Type: "instance", Project: "api", Name: "frontend"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, instances[1].ID, result.ID)
+
+	result, err = svc.Lookup(domain.LookupOptions{Type: "project", Selector: "env=prod", Name: "web"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, web.ID, result.ID)
+
+	_, err = svc.Lookup(domain.LookupOptions{Type: "instance", Selector: "tier=web"}, nil)
+	require.True(t, domain.IsAmbiguousMatch(err), "got %v", err)
+	ids := []string{instances[0].ID, instances[1].ID}
+	if ids[0] > ids[1] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+	assert.Equal(t, ids, err.(*domain.DirtError).Details["ids"], "matches are listed by ID")
+
+	// Resources the caller cannot view do not count as matches
+	result, err = svc.Lookup(domain.LookupOptions{Type: "instance", Selector: "tier=web"}, func(projectID string) (bool, error) {
+		return projectID == web.ID, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, instances[0].ID, result.ID)
+
+	_, err = svc.Lookup(domain.LookupOptions{Type: "instance", Selector: "tier=db"}, nil)
+	assert.True(t, domain.IsNotFound(err), "got %v", err)
+
+	for _, opts := range []domain.LookupOptions{
+		{Type: "volume", Name: "frontend"},
+		{Type: "instance"},
+		{Type: "instance", Selector: "tier"},
+		{Type: "project", Project: "web", Name: "web"},
+	} {
+		_, err := svc.Lookup(opts, nil)
+		assert.True(t, domain.IsInvalidInput(err), "%+v: got %v", opts, err)
+	}
+}
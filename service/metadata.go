@@ -0,0 +1,254 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// checkMetadataFlags rejects flags the store cannot hold. SQLite integers are
+// signed, so the top bit is unavailable.
+func checkMetadataFlags(v *domain.FieldViolations, field string, flags uint64) {
+	if flags > math.MaxInt64 {
+		v.Add(field, fmt.Sprintf("must be at most %d", uint64(math.MaxInt64)))
+	}
+}
+
+// checkMetadataLimits rejects writing path and value when it would take the
+// metadata store past its limits, failing with QUOTA_EXCEEDED. replacing is
+// the entry being overwritten by an update, nil for a new key.
+func (s *Service) checkMetadataLimits(path, value string, replacing *domain.Metadata) error {
+	limits := s.config.MetadataLimits
+	if limits.MaxKeys == 0 && limits.MaxTotalBytes == 0 {
+		return checkMetadataWrite(limits, domain.MetadataUsage{}, path, value, replacing)
+	}
+
+	usage, err := s.metadataRepo.Usage()
+	if err != nil {
+		return err
+	}
+	return checkMetadataWrite(limits, *usage, path, value, replacing)
+}
+
+// checkMetadataWrite checks a write against limits given the store's usage
+// before it
+func checkMetadataWrite(limits domain.MetadataLimits, usage domain.MetadataUsage, path, value string, replacing *domain.Metadata) error {
+	valueChanged := replacing == nil || value != replacing.Value
+	if limits.MaxValueBytes > 0 && valueChanged && len(value) > limits.MaxValueBytes {
+		return domain.QuotaExceededError("metadata", "max_value_bytes", limits.MaxValueBytes, len(value))
+	}
+
+	keys, bytes := metadataUsageAfter(usage, path, value, replacing)
+
+	// Writes that do not grow the store pass even when it is over a limit
+	// that was lowered, so it can be brought back under
+	if limits.MaxKeys > 0 && keys > limits.MaxKeys && keys > usage.Keys {
+		return domain.QuotaExceededError("metadata", "max_keys", limits.MaxKeys, keys)
+	}
+	if limits.MaxTotalBytes > 0 && bytes > limits.MaxTotalBytes && bytes > usage.TotalBytes {
+		return domain.QuotaExceededError("metadata", "max_total_bytes", limits.MaxTotalBytes, bytes)
+	}
+	return nil
+}
+
+// metadataUsageAfter returns the key count and total bytes of the store
+// once path and value are written
+func metadataUsageAfter(usage domain.MetadataUsage, path, value string, replacing *domain.Metadata) (int, int) {
+	keys, bytes := usage.Keys+1, usage.TotalBytes+len(path)+len(value)
+	if replacing != nil {
+		keys--
+		bytes -= len(replacing.Path) + len(replacing.Value)
+	}
+	return keys, bytes
+}
+
+// GetMetadataUsage reports the size of the metadata store against its limits
+func (s *Service) GetMetadataUsage() (*domain.MetadataUsage, error) {
+	usage, err := s.metadataRepo.Usage()
+	if err != nil {
+		return nil, err
+	}
+	usage.Limits = s.config.MetadataLimits
+	return usage, nil
+}
+
+// ImportMetadata writes entries into the metadata store, creating the paths
+// that do not exist and overwriting the values of those that do. The import
+// is all or nothing: an invalid entry, or one past the store's limits, fails
+// it without writing any.
+func (s *Service) ImportMetadata(entries []domain.CreateMetadataRequest) (*domain.MetadataImportResult, error) {
+	var v domain.FieldViolations
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		field := fmt.Sprintf("entries[%d].path", i)
+		if entry.Path == "" {
+			v.Add(field, "cannot be empty")
+		} else if seen[entry.Path] {
+			v.Add(field, "duplicates an earlier entry")
+		}
+		seen[entry.Path] = true
+		checkMetadataFlags(&v, fmt.Sprintf("entries[%d].flags", i), entry.Flags)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.MetadataImportResult, error) {
+		usage, err := tx.metadataRepo.Usage()
+		if err != nil {
+			return nil, err
+		}
+
+		result := &domain.MetadataImportResult{}
+		for _, entry := range entries {
+			existing, err := tx.metadataRepo.GetByPath(entry.Path)
+			if err != nil && !domain.IsNotFound(err) {
+				return nil, err
+			}
+			if existing != nil && existing.Value == entry.Value && existing.Flags == entry.Flags {
+				result.Unchanged++
+				continue
+			}
+
+			if err := checkMetadataWrite(tx.config.MetadataLimits, *usage, entry.Path, entry.Value, existing); err != nil {
+				return nil, err
+			}
+			usage.Keys, usage.TotalBytes = metadataUsageAfter(*usage, entry.Path, entry.Value, existing)
+
+			if existing != nil {
+				value, flags := entry.Value, entry.Flags
+				if _, err := tx.metadataRepo.Update(existing.ID, domain.UpdateMetadataRequest{Value: &value, Flags: &flags}); err != nil {
+					return nil, err
+				}
+				result.Updated++
+				continue
+			}
+
+			id, err := tx.newID(kindMetadata)
+			if err != nil {
+				return nil, domain.InternalError("failed to generate ID")
+			}
+			if _, err := tx.metadataRepo.Create(id, entry); err != nil {
+				return nil, err
+			}
+			result.Created++
+		}
+		return result, nil
+	})
+}
+
+// GetMetadataByPath retrieves metadata by path
+func (s *Service) GetMetadataByPath(path string) (*domain.Metadata, error) {
+	if path == "" {
+		return nil, domain.InvalidInputError("metadata path cannot be empty", nil)
+	}
+
+	return s.metadataRepo.GetByPath(path)
+}
+
+// casMatches reports whether a conditional write may go ahead given the
+// entry it would replace, nil if there is none
+func casMatches(existing *domain.Metadata, cas *uint64) bool {
+	if cas == nil {
+		return true
+	}
+	if existing == nil {
+		return *cas == 0
+	}
+	return existing.ModifyIndex() == *cas
+}
+
+// PutMetadata writes the value and flags of req at its path, creating the
+// entry or overwriting the one there. When opts.CAS does not match, nothing
+// is written and PutMetadata reports false.
+func (s *Service) PutMetadata(req domain.CreateMetadataRequest, opts domain.MetadataWriteOptions) (bool, error) {
+	var v domain.FieldViolations
+	if req.Path == "" {
+		v.Add("path", "cannot be empty")
+	}
+	checkMetadataFlags(&v, "flags", req.Flags)
+	if err := v.Err(); err != nil {
+		return false, err
+	}
+
+	return inTx(s, func(tx *Service) (bool, error) {
+		return tx.putMetadata(req, opts)
+	})
+}
+
+// putMetadata is PutMetadata for a service already in a transaction
+func (s *Service) putMetadata(req domain.CreateMetadataRequest, opts domain.MetadataWriteOptions) (bool, error) {
+	existing, err := s.metadataRepo.GetByPath(req.Path)
+	if err != nil && !domain.IsNotFound(err) {
+		return false, err
+	}
+	if !casMatches(existing, opts.CAS) {
+		return false, nil
+	}
+	if err := s.checkMetadataLimits(req.Path, req.Value, existing); err != nil {
+		return false, err
+	}
+
+	if existing != nil {
+		value, flags := req.Value, req.Flags
+		_, err := s.metadataRepo.Update(existing.ID, domain.UpdateMetadataRequest{Value: &value, Flags: &flags})
+		return err == nil, err
+	}
+
+	id, err := s.newID(kindMetadata)
+	if err != nil {
+		return false, domain.InternalError("failed to generate ID")
+	}
+	_, err = s.metadataRepo.Create(id, req)
+	return err == nil, err
+}
+
+// DeleteMetadataByPath deletes the entry at path. A path with no entry is
+// already deleted, so that succeeds too. When opts.CAS does not match the
+// entry, nothing is deleted and DeleteMetadataByPath reports false.
+func (s *Service) DeleteMetadataByPath(path string, opts domain.MetadataWriteOptions) (bool, error) {
+	if path == "" {
+		return false, domain.InvalidInputError("metadata path cannot be empty", nil)
+	}
+
+	return inTx(s, func(tx *Service) (bool, error) {
+		return tx.deleteMetadataByPath(path, opts)
+	})
+}
+
+// deleteMetadataByPath is DeleteMetadataByPath for a service already in a
+// transaction
+func (s *Service) deleteMetadataByPath(path string, opts domain.MetadataWriteOptions) (bool, error) {
+	existing, err := s.metadataRepo.GetByPath(path)
+	if domain.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !casMatches(existing, opts.CAS) {
+		return false, nil
+	}
+	if err := s.metadataRepo.Delete(existing.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteMetadataPrefix deletes every entry whose path starts with prefix,
+// all of them when it is empty, and returns how many it deleted
+func (s *Service) DeleteMetadataPrefix(prefix string) (int, error) {
+	return inTx(s, func(tx *Service) (int, error) {
+		entries, err := tx.metadataRepo.List(domain.MetadataListOptions{Prefix: prefix})
+		if err != nil {
+			return 0, err
+		}
+		for _, m := range entries {
+			if err := tx.metadataRepo.Delete(m.ID); err != nil {
+				return 0, err
+			}
+		}
+		return len(entries), nil
+	})
+}
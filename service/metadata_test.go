@@ -0,0 +1,194 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_MetadataLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits domain.MetadataLimits
+		write  func(svc *Service, existing *domain.Metadata) error
+		limit  string
+	}{
+		{
+			name:   "value too large",
+			limits: domain.MetadataLimits{MaxValueBytes: 8},
+			write: func(svc *Service, _ *domain.Metadata) error {
+				_, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/big", Value: "123456789"})
+				return err
+			},
+			limit: "max_value_bytes",
+		},
+		{
+			name:   "value too large on update",
+			limits: domain.MetadataLimits{MaxValueBytes: 8},
+			write: func(svc *Service, existing *domain.Metadata) error {
+				value := strings.Repeat("x", 9)
+				_, err := svc.UpdateMetadata(existing.ID, domain.UpdateMetadataRequest{Value: &value})
+				return err
+			},
+			limit: "max_value_bytes",
+		},
+		{
+			name:   "too many keys",
+			limits: domain.MetadataLimits{MaxKeys: 1},
+			write: func(svc *Service, _ *domain.Metadata) error {
+				_, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/second", Value: "v"})
+				return err
+			},
+			limit: "max_keys",
+		},
+		{
+			name:   "updates do not add keys",
+			limits: domain.MetadataLimits{MaxKeys: 1},
+			write: func(svc *Service, existing *domain.Metadata) error {
+				value := "changed"
+				_, err := svc.UpdateMetadata(existing.ID, domain.UpdateMetadataRequest{Value: &value})
+				return err
+			},
+		},
+		{
+			name:   "store full",
+			limits: domain.MetadataLimits{MaxTotalBytes: 20},
+			write: func(svc *Service, _ *domain.Metadata) error {
+				_, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/b", Value: "12345"})
+				return err
+			},
+			limit: "max_total_bytes",
+		},
+		{
+			name:   "growing past the total on update",
+			limits: domain.MetadataLimits{MaxTotalBytes: 20},
+			write: func(svc *Service, existing *domain.Metadata) error {
+				value := strings.Repeat("x", 20)
+				_, err := svc.UpdateMetadata(existing.ID, domain.UpdateMetadataRequest{Value: &value})
+				return err
+			},
+			limit: "max_total_bytes",
+		},
+		{
+			name:   "shrinking a store over its limit",
+			limits: domain.MetadataLimits{MaxTotalBytes: 5},
+			write: func(svc *Service, existing *domain.Metadata) error {
+				value := "v"
+				_, err := svc.UpdateMetadata(existing.ID, domain.UpdateMetadataRequest{Value: &value})
+				return err
+			},
+		},
+		{
+			name:   "put adding a key",
+			limits: domain.MetadataLimits{MaxKeys: 1},
+			write: func(svc *Service, _ *domain.Metadata) error {
+				_, err := svc.PutMetadata(domain.CreateMetadataRequest{Path: "app/second", Value: "v"}, domain.MetadataWriteOptions{})
+				return err
+			},
+			limit: "max_keys",
+		},
+		{
+			name:   "put overwriting a key",
+			limits: domain.MetadataLimits{MaxKeys: 1},
+			write: func(svc *Service, existing *domain.Metadata) error {
+				_, err := svc.PutMetadata(domain.CreateMetadataRequest{Path: existing.Path, Value: "v"}, domain.MetadataWriteOptions{})
+				return err
+			},
+		},
+		{
+			name: "unlimited",
+			write: func(svc *Service, _ *domain.Metadata) error {
+				_, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/b", Value: strings.Repeat("x", 1<<16)})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t)
+
+			// 15 bytes: a 7 byte path and an 8 byte value
+			existing, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/key", Value: "12345678"})
+			require.NoError(t, err)
+			svc.config.MetadataLimits = tt.limits
+
+			err = tt.write(svc, existing)
+			if tt.limit == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.True(t, domain.IsQuotaExceeded(err), "got %v", err)
+			assert.Equal(t, tt.limit, err.(*domain.DirtError).Details["limit"])
+		})
+	}
+}
+
+func TestService_MetadataFlags(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "a", Flags: 1 << 63})
+	assert.True(t, domain.IsInvalidInput(err), "got %v", err)
+
+	m, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "a", Flags: 7})
+	require.NoError(t, err)
+	flags := uint64(9)
+	_, err = svc.UpdateMetadata(m.ID, domain.UpdateMetadataRequest{Flags: &flags})
+	require.NoError(t, err)
+
+	m, err = svc.GetMetadataByPath("a")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), m.Flags)
+}
+
+func TestService_GetMetadataUsage(t *testing.T) {
+	svc := newTestService(t)
+	svc.config.MetadataLimits = domain.MetadataLimits{MaxKeys: 10}
+
+	usage, err := svc.GetMetadataUsage()
+	require.NoError(t, err)
+	assert.Equal(t, domain.MetadataUsage{Limits: domain.MetadataLimits{MaxKeys: 10}}, *usage)
+
+	_, err = svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/key", Value: "12345678"})
+	require.NoError(t, err)
+	// Bytes, not characters
+	_, err = svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/é", Value: "ü"})
+	require.NoError(t, err)
+
+	usage, err = svc.GetMetadataUsage()
+	require.NoError(t, err)
+	assert.Equal(t, 2, usage.Keys)
+	assert.Equal(t, 15+6+2, usage.TotalBytes)
+}
+
+func TestService_ImportMetadata(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/same", Value: "v"})
+	require.NoError(t, err)
+	_, err = svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/changed", Value: "old"})
+	require.NoError(t, err)
+
+	result, err := svc.ImportMetadata([]domain.CreateMetadataRequest{
+		{Path: "app/same", Value: "v"},
+		{Path: "app/changed", Value: "new"},
+		{Path: "app/new", Value: "v"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.MetadataImportResult{Created: 1, Updated: 1, Unchanged: 1}, *result)
+
+	// An import that would pass a limit writes nothing
+	svc.config.MetadataLimits = domain.MetadataLimits{MaxKeys: 4}
+	_, err = svc.ImportMetadata([]domain.CreateMetadataRequest{
+		{Path: "app/fourth", Value: "v"},
+		{Path: "app/fifth", Value: "v"},
+	})
+	require.True(t, domain.IsQuotaExceeded(err), "got %v", err)
+
+	usage, err := svc.GetMetadataUsage()
+	require.NoError(t, err)
+	assert.Equal(t, 3, usage.Keys)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// preemptions tracks the spot instances that were warned of their
+// preemption, with when they are preempted. It is shared by every copy of
+// the service.
+type preemptions struct {
+	mu  sync.Mutex
+	due map[string]time.Time
+}
+
+func newPreemptions() *preemptions {
+	return &preemptions{due: make(map[string]time.Time)}
+}
+
+// schedule marks instance id for preemption at due, reporting false if it
+// already is
+func (p *preemptions) schedule(id string, due time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.due[id]; ok {
+		return false
+	}
+	p.due[id] = due
+	return true
+}
+
+// takeDue unmarks and returns the instances due for preemption at now
+func (p *preemptions) takeDue(now time.Time) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ids []string
+	for id, due := range p.due {
+		if !now.Before(due) {
+			ids = append(ids, id)
+			delete(p.due, id)
+		}
+	}
+	return ids
+}
+
+// randFloat64 returns a random number in [0.0, 1.0) from the configured
+// source
+func (s *Service) randFloat64() float64 {
+	if s.config.Rand != nil {
+		return s.config.Rand.Float64()
+	}
+	return rand.Float64()
+}
+
+// PreemptSpotInstances warns each running spot instance of its preemption
+// with a probability of the configured preemption rate, and preempts those
+// whose notice ran out at or before now. It returns how many were preempted.
+func (s *Service) PreemptSpotInstances(now time.Time) (int, error) {
+	s = s.WithActor(domain.ActorPreemptor)
+
+	if s.config.SpotPreemptionRate > 0 {
+		running, err := s.instanceRepo.List(domain.InstanceListOptions{Class: domain.InstanceClassSpot, Status: domain.StatusRunning})
+		if err != nil {
+			return 0, err
+		}
+		for _, instance := range running {
+			if s.randFloat64() >= s.config.SpotPreemptionRate {
+				continue
+			}
+			if err := s.warnPreemption(instance, now); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	preempted := 0
+	due := s.preemptions.takeDue(now)
+	for i, id := range due {
+		ok, err := s.preempt(id)
+		if err != nil {
+			// Leave the instances not yet preempted due, so the next pass
+			// retries them
+			for _, id := range due[i:] {
+				s.preemptions.schedule(id, now)
+			}
+			return preempted, err
+		}
+		if ok {
+			preempted++
+		}
+	}
+	return preempted, nil
+}
+
+// warnPreemption schedules the preemption of a spot instance once the
+// configured notice runs out from now, recording a warning event. An
+// instance already warned is left as is.
+func (s *Service) warnPreemption(instance *domain.Instance, now time.Time) error {
+	due := now.Add(s.config.SpotPreemptionNotice)
+	if !s.preemptions.schedule(instance.ID, due) {
+		return nil
+	}
+	return s.runInTx(func(tx *Service) error {
+		tx.recordEvent(domain.EventInstancePreemptionWarning, "instance", instance.ID, instance.ProjectID,
+			fmt.Sprintf("spot instance %s will be preempted at %s", instance.Name, due.Format(time.RFC3339)))
+		return nil
+	})
+}
+
+// preempt stops a spot instance, reporting false if it is gone or no longer
+// running
+func (s *Service) preempt(id string) (bool, error) {
+	preempted := false
+	err := s.runInTx(func(tx *Service) error {
+		instance, err := tx.instanceRepo.GetByID(id)
+		if err != nil {
+			if domain.IsNotFound(err) {
+				return nil // Deleted since the warning
+			}
+			return err
+		}
+		if instance.Status != domain.StatusRunning {
+			return nil
+		}
+
+		stopped := domain.StatusStopped
+		updated, err := tx.instanceRepo.Update(id, domain.UpdateInstanceRequest{Status: &stopped})
+		if err != nil {
+			return err
+		}
+		tx.WithReason(domain.ReasonPreempted).recordStatusChange(updated, instance.Status, updated.Status)
+		tx.recordEvent(domain.EventInstancePreempted, "instance", id, instance.ProjectID,
+			fmt.Sprintf("spot instance %s was preempted", instance.Name))
+		preempted = true
+		return nil
+	})
+	return preempted, err
+}
+
+// PreemptInstance warns a running spot instance of its preemption and
+// preempts it at once, regardless of the preemption rate and notice
+func (s *Service) PreemptInstance(id string) (*domain.Instance, error) {
+	s = s.WithActor(domain.ActorPreemptor)
+
+	instance, err := s.instanceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if instance.Class != domain.InstanceClassSpot {
+		return nil, domain.FailedPreconditionError(fmt.Sprintf("instance %s is not a spot instance", id), map[string]interface{}{
+			"id":    id,
+			"class": instance.Class,
+		})
+	}
+	if instance.Status != domain.StatusRunning {
+		return nil, domain.FailedPreconditionError(fmt.Sprintf("instance %s is not running", id), map[string]interface{}{
+			"id":     id,
+			"status": instance.Status,
+		})
+	}
+
+	now := time.Now().UTC()
+	if err := s.warnPreemption(instance, now); err != nil {
+		return nil, err
+	}
+	s.preemptions.mu.Lock()
+	delete(s.preemptions.due, id)
+	s.preemptions.mu.Unlock()
+
+	if _, err := s.preempt(id); err != nil {
+		return nil, err
+	}
+	return s.instanceRepo.GetByID(id)
+}
+
+// RunPreemptor warns and preempts spot instances every interval until ctx is
+// done
+func (s *Service) RunPreemptor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := s.PreemptSpotInstances(now); err != nil {
+				log.Printf("Spot preemptor failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Spot preemptor preempted %d instance(s)", n)
+			}
+		}
+	}
+}
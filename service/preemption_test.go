@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreemptSpotInstances(t *testing.T) {
+	svc := newTestService(t)
+	svc.config.SpotPreemptionRate = 1
+	svc.config.SpotPreemptionNotice = time.Minute
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "spot"})
+	require.NoError(t, err)
+	create := func(name, class string) *domain.Instance {
+		instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu", Class: class,
+		})
+		require.NoError(t, err)
+		return instance
+	}
+	spot := create("spot", domain.InstanceClassSpot)
+	standard := create("standard", "")
+	assert.Equal(t, domain.InstanceClassStandard, standard.Class)
+
+	// The first pass only warns; the instance is preempted once the notice
+	// runs out
+	now := time.Now()
+	n, err := svc.PreemptSpotInstances(now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceID: spot.ID, Type: domain.EventInstancePreemptionWarning})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.ActorPreemptor, events[0].Actor)
+
+	n, err = svc.PreemptSpotInstances(now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	got, err := svc.GetInstance(spot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopped, got.Status)
+	got, err = svc.GetInstance(standard.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusRunning, got.Status)
+
+	events, err = svc.ListEvents(domain.EventListOptions{ResourceID: spot.ID, Type: domain.EventInstanceStatusChanged})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.ReasonPreempted, events[1].Reason)
+
+	// Stopped instances are not warned again
+	events, err = svc.ListEvents(domain.EventListOptions{ResourceID: spot.ID, Type: domain.EventInstancePreemptionWarning})
+	require.NoError(t, err)
+	_, err = svc.PreemptSpotInstances(now.Add(2 * time.Minute))
+	require.NoError(t, err)
+	again, err := svc.ListEvents(domain.EventListOptions{ResourceID: spot.ID, Type: domain.EventInstancePreemptionWarning})
+	require.NoError(t, err)
+	assert.Len(t, again, len(events))
+
+	_, err = svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "bad", CPU: 1, MemoryMB: 512, Image: "ubuntu", Class: "reserved",
+	})
+	assert.True(t, domain.IsInvalidInput(err))
+}
+
+// failingUpdates fails every instance update while fail is set
+type failingUpdates struct {
+	InstanceRepository
+	fail bool
+}
+
+func (r *failingUpdates) Update(id string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	if r.fail {
+		return nil, errors.New("update failed")
+	}
+	return r.InstanceRepository.Update(id, req)
+}
+
+func TestPreemptSpotInstances_RetriesAfterFailure(t *testing.T) {
+	svc := newTestService(t)
+	svc.config.SpotPreemptionRate = 1
+	svc.config.SpotPreemptionNotice = time.Minute
+	repo := &failingUpdates{InstanceRepository: svc.instanceRepo}
+	svc.instanceRepo = repo
+	svc.uow = nil
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "spot"})
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b"} {
+		_, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu", Class: domain.InstanceClassSpot,
+		})
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+	_, err = svc.PreemptSpotInstances(now)
+	require.NoError(t, err)
+
+	repo.fail = true
+	_, err = svc.PreemptSpotInstances(now.Add(time.Minute))
+	require.Error(t, err)
+
+	// Both instances are still due and preempted on the next pass
+	repo.fail = false
+	n, err := svc.PreemptSpotInstances(now.Add(2 * time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestPreemptInstance(t *testing.T) {
+	svc := newTestService(t)
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "spot"})
+	require.NoError(t, err)
+
+	spot, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "spot", CPU: 1, MemoryMB: 512, Image: "ubuntu", Class: domain.InstanceClassSpot,
+	})
+	require.NoError(t, err)
+	preempted, err := svc.PreemptInstance(spot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopped, preempted.Status)
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceID: spot.ID, Type: domain.EventInstancePreempted})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	_, err = svc.PreemptInstance(spot.ID)
+	assert.True(t, domain.IsFailedPrecondition(err))
+
+	standard, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "standard", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+	_, err = svc.PreemptInstance(standard.ID)
+	assert.True(t, domain.IsFailedPrecondition(err))
+}
+
+func TestUpdateInstance_ImmutableClass(t *testing.T) {
+	svc := newTestService(t)
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "spot"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	same := domain.InstanceClassStandard
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Class: &same})
+	require.NoError(t, err)
+
+	spot := domain.InstanceClassSpot
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Class: &spot})
+	require.True(t, domain.IsImmutableField(err), "got %v", err)
+	assert.Equal(t, "class", err.(*domain.DirtError).Details["field"])
+}
+
+func TestPreemptSpotInstances_Seeded(t *testing.T) {
+	// warned returns the instances a pass warns with the source seeded with seed
+	warned := func(seed int64) []string {
+		ids, err := NewDeterministicIDGenerator(IDFormatPrefixed, 0)
+		require.NoError(t, err)
+		svc := newTestService(t)
+		svc.config.IDs = ids
+		svc.config.SpotPreemptionRate = 0.5
+		svc.config.Rand = rand.New(rand.NewSource(seed))
+
+		project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "spot"})
+		require.NoError(t, err)
+		for i := 0; i < 20; i++ {
+			_, err := svc.CreateInstance(domain.CreateInstanceRequest{
+				ProjectID: project.ID, Name: fmt.Sprintf("vm-%d", i), CPU: 1, MemoryMB: 512, Image: "ubuntu", Class: domain.InstanceClassSpot,
+			})
+			require.NoError(t, err)
+		}
+
+		_, err = svc.PreemptSpotInstances(time.Now())
+		require.NoError(t, err)
+		events, err := svc.ListEvents(domain.EventListOptions{Type: domain.EventInstancePreemptionWarning})
+		require.NoError(t, err)
+		var warned []string
+		for _, event := range events {
+			warned = append(warned, event.ResourceID)
+		}
+		sort.Strings(warned)
+		return warned
+	}
+
+	first := warned(1)
+	assert.NotEmpty(t, first)
+	assert.Less(t, len(first), 20)
+	assert.Equal(t, first, warned(1), "the same seed warns the same instances")
+}
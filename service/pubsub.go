@@ -0,0 +1,260 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Pub/sub limits, after those of common message queues
+const (
+	maxPublishMessages        = 1000
+	maxMessageBytes           = 1 << 20
+	maxPullMessages           = 1000
+	defaultPullMessages       = 10
+	minAckDeadlineSeconds     = 10
+	maxAckDeadlineSeconds     = 600
+	defaultAckDeadlineSeconds = 10
+)
+
+// validateAckDeadline validates the ack deadline of a subscription
+func validateAckDeadline(v *domain.FieldViolations, seconds int) {
+	if seconds < minAckDeadlineSeconds || seconds > maxAckDeadlineSeconds {
+		v.Add("ack_deadline_seconds", fmt.Sprintf("must be between %d and %d (got %d)", minAckDeadlineSeconds, maxAckDeadlineSeconds, seconds))
+	}
+}
+
+// Topic operations
+
+// CreateTopic creates a new topic
+func (s *Service) CreateTopic(req domain.CreateTopicRequest) (*domain.Topic, error) {
+	var v domain.FieldViolations
+	if req.ProjectID == "" {
+		v.Add("project_id", "cannot be empty")
+	}
+	validateName(&v, "name", req.Name)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	id, err := s.newID(kindTopic)
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+
+	topic := &domain.Topic{
+		ID:        id,
+		ProjectID: req.ProjectID,
+		Name:      req.Name,
+	}
+
+	if err := s.topicRepo.Create(topic); err != nil {
+		return nil, err
+	}
+
+	return topic, nil
+}
+
+// GetTopic retrieves a topic by ID
+func (s *Service) GetTopic(id string) (*domain.Topic, error) {
+	return s.topicRepo.GetByID(id)
+}
+
+// ListTopics lists topics with optional filtering
+func (s *Service) ListTopics(opts domain.TopicListOptions) ([]*domain.Topic, error) {
+	return s.topicRepo.List(opts)
+}
+
+// DeleteTopic deletes a topic along with its subscriptions
+func (s *Service) DeleteTopic(id string) error {
+	return s.topicRepo.Delete(id)
+}
+
+// Publish publishes messages to a topic, delivering each to every
+// subscription of the topic, and returns their IDs
+func (s *Service) Publish(topicID string, req domain.PublishRequest) (*domain.PublishResponse, error) {
+	var v domain.FieldViolations
+	if len(req.Messages) == 0 {
+		v.Add("messages", "cannot be empty")
+	}
+	if len(req.Messages) > maxPublishMessages {
+		v.Add("messages", fmt.Sprintf("must have at most %d messages (got %d)", maxPublishMessages, len(req.Messages)))
+	}
+	for i, message := range req.Messages {
+		field := fmt.Sprintf("messages[%d]", i)
+		if message.Data == "" && len(message.Attributes) == 0 {
+			v.Add(field, "must have data or attributes")
+		}
+		if len(message.Data) > maxMessageBytes {
+			v.Add(field+".data", fmt.Sprintf("must be at most %d bytes (got %d)", maxMessageBytes, len(message.Data)))
+		}
+		for key := range message.Attributes {
+			if key == "" {
+				v.Add(field+".attributes", "keys cannot be empty")
+			}
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.PublishResponse, error) {
+		if _, err := tx.topicRepo.GetByID(topicID); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		messages := make([]*domain.Message, len(req.Messages))
+		resp := &domain.PublishResponse{MessageIDs: make([]string, len(req.Messages))}
+		for i, message := range req.Messages {
+			id, err := tx.newID(kindMessage)
+			if err != nil {
+				return nil, domain.InternalError("failed to generate ID")
+			}
+			messages[i] = &domain.Message{ID: id, Data: message.Data, Attributes: message.Attributes, PublishedAt: now}
+			resp.MessageIDs[i] = id
+		}
+
+		if err := tx.topicRepo.Publish(topicID, messages); err != nil {
+			return nil, err
+		}
+
+		return resp, nil
+	})
+}
+
+// Subscription operations
+
+// CreateSubscription creates a new subscription to a topic. It receives the
+// messages published from now on, in the topic's project.
+func (s *Service) CreateSubscription(req domain.CreateSubscriptionRequest) (*domain.Subscription, error) {
+	deadline := req.AckDeadlineSeconds
+	if deadline == 0 {
+		deadline = defaultAckDeadlineSeconds
+	}
+
+	var v domain.FieldViolations
+	if req.TopicID == "" {
+		v.Add("topic_id", "cannot be empty")
+	}
+	validateName(&v, "name", req.Name)
+	validateAckDeadline(&v, deadline)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.Subscription, error) {
+		topic, err := tx.topicRepo.GetByID(req.TopicID)
+		if err != nil {
+			if domain.IsNotFound(err) {
+				return nil, domain.ForeignKeyViolationError("topic", "id", req.TopicID)
+			}
+			return nil, err
+		}
+
+		id, err := tx.newID(kindSubscription)
+		if err != nil {
+			return nil, domain.InternalError("failed to generate ID")
+		}
+
+		subscription := &domain.Subscription{
+			ID:                 id,
+			ProjectID:          topic.ProjectID,
+			TopicID:            topic.ID,
+			Name:               req.Name,
+			AckDeadlineSeconds: deadline,
+		}
+
+		if err := tx.subscriptionRepo.Create(subscription); err != nil {
+			return nil, err
+		}
+
+		return subscription, nil
+	})
+}
+
+// GetSubscription retrieves a subscription by ID
+func (s *Service) GetSubscription(id string) (*domain.Subscription, error) {
+	return s.subscriptionRepo.GetByID(id)
+}
+
+// ListSubscriptions lists subscriptions with optional filtering
+func (s *Service) ListSubscriptions(opts domain.SubscriptionListOptions) ([]*domain.Subscription, error) {
+	return s.subscriptionRepo.List(opts)
+}
+
+// UpdateSubscription updates a subscription. A new ack deadline applies to
+// messages pulled afterwards.
+func (s *Service) UpdateSubscription(id string, req domain.UpdateSubscriptionRequest) (*domain.Subscription, error) {
+	var v domain.FieldViolations
+	if req.AckDeadlineSeconds != nil {
+		validateAckDeadline(&v, *req.AckDeadlineSeconds)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.subscriptionRepo.Update(id, req)
+}
+
+// DeleteSubscription deletes a subscription and its undelivered messages
+func (s *Service) DeleteSubscription(id string) error {
+	return s.subscriptionRepo.Delete(id)
+}
+
+// Pull leases messages of a subscription for its ack deadline. Messages that
+// are not acknowledged in time are delivered again by a later pull. An empty
+// response means no message is available right now.
+func (s *Service) Pull(subscriptionID string, req domain.PullRequest, now time.Time) (*domain.PullResponse, error) {
+	max := req.MaxMessages
+	if max == 0 {
+		max = defaultPullMessages
+	}
+
+	var v domain.FieldViolations
+	if max < 1 || max > maxPullMessages {
+		v.Add("max_messages", fmt.Sprintf("must be between 1 and %d (got %d)", maxPullMessages, max))
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.PullResponse, error) {
+		subscription, err := tx.subscriptionRepo.GetByID(subscriptionID)
+		if err != nil {
+			return nil, err
+		}
+
+		leaseEnd := now.Add(time.Duration(subscription.AckDeadlineSeconds) * time.Second)
+		received, err := tx.subscriptionRepo.Pull(subscriptionID, max, now, leaseEnd)
+		if err != nil {
+			return nil, err
+		}
+		if received == nil {
+			received = []domain.ReceivedMessage{}
+		}
+
+		return &domain.PullResponse{ReceivedMessages: received}, nil
+	})
+}
+
+// Acknowledge removes pulled messages from a subscription. Ack IDs of
+// deliveries superseded by a redelivery are ignored.
+func (s *Service) Acknowledge(subscriptionID string, req domain.AcknowledgeRequest) error {
+	var v domain.FieldViolations
+	if len(req.AckIDs) == 0 {
+		v.Add("ack_ids", "cannot be empty")
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	return s.runInTx(func(tx *Service) error {
+		if _, err := tx.subscriptionRepo.GetByID(subscriptionID); err != nil {
+			return err
+		}
+		_, err := tx.subscriptionRepo.Acknowledge(subscriptionID, req.AckIDs)
+		return err
+	})
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_PublishPull(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "pubsub"})
+	require.NoError(t, err)
+	topic, err := svc.CreateTopic(domain.CreateTopicRequest{ProjectID: project.ID, Name: "orders"})
+	require.NoError(t, err)
+
+	// Messages published before a subscription exists are not delivered to it
+	_, err = svc.Publish(topic.ID, domain.PublishRequest{Messages: []domain.Message{{Data: "early"}}})
+	require.NoError(t, err)
+
+	billing, err := svc.CreateSubscription(domain.CreateSubscriptionRequest{TopicID: topic.ID, Name: "billing"})
+	require.NoError(t, err)
+	assert.Equal(t, project.ID, billing.ProjectID)
+	assert.Equal(t, 10, billing.AckDeadlineSeconds)
+	shipping, err := svc.CreateSubscription(domain.CreateSubscriptionRequest{TopicID: topic.ID, Name: "shipping", AckDeadlineSeconds: 60})
+	require.NoError(t, err)
+
+	published, err := svc.Publish(topic.ID, domain.PublishRequest{Messages: []domain.Message{
+		{Data: "first", Attributes: map[string]string{"kind": "created"}},
+		{Data: "second"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, published.MessageIDs, 2)
+
+	now := time.Now()
+	pulled, err := svc.Pull(billing.ID, domain.PullRequest{MaxMessages: 1}, now)
+	require.NoError(t, err)
+	require.Len(t, pulled.ReceivedMessages, 1)
+	first := pulled.ReceivedMessages[0]
+	assert.Equal(t, published.MessageIDs[0], first.Message.ID)
+	assert.Equal(t, "first", first.Message.Data)
+	assert.Equal(t, map[string]string{"kind": "created"}, first.Message.Attributes)
+	assert.Equal(t, 1, first.DeliveryAttempt)
+
+	// Leased messages are hidden until the ack deadline passes
+	pulled, err = svc.Pull(billing.ID, domain.PullRequest{}, now)
+	require.NoError(t, err)
+	require.Len(t, pulled.ReceivedMessages, 1)
+	assert.Equal(t, "second", pulled.ReceivedMessages[0].Message.Data)
+	require.NoError(t, svc.Acknowledge(billing.ID, domain.AcknowledgeRequest{AckIDs: []string{pulled.ReceivedMessages[0].AckID}}))
+
+	redelivered, err := svc.Pull(billing.ID, domain.PullRequest{}, now.Add(11*time.Second))
+	require.NoError(t, err)
+	require.Len(t, redelivered.ReceivedMessages, 1)
+	assert.Equal(t, first.Message.ID, redelivered.ReceivedMessages[0].Message.ID)
+	assert.Equal(t, 2, redelivered.ReceivedMessages[0].DeliveryAttempt)
+
+	// The ack ID of the expired delivery no longer removes the message
+	require.NoError(t, svc.Acknowledge(billing.ID, domain.AcknowledgeRequest{AckIDs: []string{first.AckID}}))
+	pulled, err = svc.Pull(billing.ID, domain.PullRequest{}, now.Add(22*time.Second))
+	require.NoError(t, err)
+	require.Len(t, pulled.ReceivedMessages, 1)
+	require.NoError(t, svc.Acknowledge(billing.ID, domain.AcknowledgeRequest{AckIDs: []string{pulled.ReceivedMessages[0].AckID}}))
+
+	pulled, err = svc.Pull(billing.ID, domain.PullRequest{}, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, pulled.ReceivedMessages)
+
+	// Every subscription gets its own copy
+	pulled, err = svc.Pull(shipping.ID, domain.PullRequest{}, now)
+	require.NoError(t, err)
+	assert.Len(t, pulled.ReceivedMessages, 2)
+
+	// Deleting the topic deletes its subscriptions
+	require.NoError(t, svc.DeleteTopic(topic.ID))
+	_, err = svc.GetSubscription(shipping.ID)
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestService_PubSubValidation(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "pubsub"})
+	require.NoError(t, err)
+	topic, err := svc.CreateTopic(domain.CreateTopicRequest{ProjectID: project.ID, Name: "events"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		call          func() error
+		expectedField string
+	}{
+		{"empty publish", func() error {
+			_, err := svc.Publish(topic.ID, domain.PublishRequest{})
+			return err
+		}, "messages"},
+		{"empty message", func() error {
+			_, err := svc.Publish(topic.ID, domain.PublishRequest{Messages: []domain.Message{{}}})
+			return err
+		}, "messages[0]"},
+		{"ack deadline too short", func() error {
+			_, err := svc.CreateSubscription(domain.CreateSubscriptionRequest{TopicID: topic.ID, Name: "fast", AckDeadlineSeconds: 5})
+			return err
+		}, "ack_deadline_seconds"},
+		{"too many messages pulled", func() error {
+			_, err := svc.Pull("any", domain.PullRequest{MaxMessages: 5000}, time.Now())
+			return err
+		}, "max_messages"},
+		{"nothing to acknowledge", func() error {
+			return svc.Acknowledge("any", domain.AcknowledgeRequest{})
+		}, "ack_ids"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			require.True(t, domain.IsInvalidInput(err), "expected invalid input, got %v", err)
+			assert.Contains(t, err.Error(), tt.expectedField)
+		})
+	}
+
+	_, err = svc.CreateSubscription(domain.CreateSubscriptionRequest{TopicID: "missing", Name: "orphan"})
+	assert.True(t, domain.IsForeignKeyViolation(err))
+	_, err = svc.Publish("missing", domain.PublishRequest{Messages: []domain.Message{{Data: "x"}}})
+	assert.True(t, domain.IsNotFound(err))
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// ReapExpiredInstances deletes every instance whose expiry is at or before
+// now, recording an event for each, and returns how many were deleted. They
+// are deleted as DeleteInstance would, so with a deletion delay they are
+// only marked deleting, and instances with deletion protection are left
+// until it is turned off. An instance that fails to be deleted is logged and
+// left for the next pass.
+func (s *Service) ReapExpiredInstances(now time.Time) (int, error) {
+	s = s.WithActor(domain.ActorReaper).WithReason(domain.ReasonTTLExpired)
+
+	expired, err := s.instanceRepo.ListExpired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for _, instance := range expired {
+		if instance.DeletionProtection || instance.Status == domain.StatusDeleting {
+			continue
+		}
+
+		// Each deletion commits together with its event
+		err := s.runInTx(func(tx *Service) error {
+			tx.recordEvent(domain.EventInstanceExpired, "instance", instance.ID, instance.ProjectID,
+				fmt.Sprintf("instance %s expired at %s", instance.Name, instance.ExpiresAt.Format(time.RFC3339)))
+			return tx.deleteInstance(instance)
+		})
+		if err != nil {
+			if !domain.IsNotFound(err) { // NotFound: deleted concurrently
+				log.Printf("Instance reaper failed to delete instance %s: %v", instance.ID, err)
+			}
+			continue
+		}
+		reaped++
+	}
+
+	return reaped, nil
+}
+
+// RemoveDeletedInstances removes every deleting instance due for removal at
+// or before now and returns how many were removed
+func (s *Service) RemoveDeletedInstances(now time.Time) (int, error) {
+	s = s.WithActor(domain.ActorReaper)
+
+	deleted, err := s.instanceRepo.ListDeleted(now)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, instance := range deleted {
+		if err := s.removeDeleted(instance); err != nil {
+			if domain.IsNotFound(err) {
+				continue // Removed concurrently
+			}
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// removeDeleted removes a deleting instance and records its termination
+func (s *Service) removeDeleted(instance *domain.Instance) error {
+	return s.runInTx(func(tx *Service) error {
+		if err := tx.instanceRepo.Delete(instance.ID); err != nil {
+			return err
+		}
+		tx.recordStatusChange(instance, instance.Status, domain.StatusTerminated)
+		return nil
+	})
+}
+
+// RunReaper terminates expired instances and removes deleted ones every
+// interval until ctx is done
+func (s *Service) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := s.ReapExpiredInstances(now); err != nil {
+				log.Printf("Instance reaper failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Instance reaper terminated %d expired instance(s)", n)
+			}
+			if n, err := s.RemoveDeletedInstances(now); err != nil {
+				log.Printf("Instance reaper failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Instance reaper removed %d deleted instance(s)", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		ttlSeconds    int
+		expiresAt     *time.Time
+		expected      *time.Time
+		expectedField string
+	}{
+		{
+			name: "no expiry",
+		},
+		{
+			name:       "ttl",
+			ttlSeconds: 60,
+			expected:   timePtr(now.Add(time.Minute)),
+		},
+		{
+			name:      "absolute expiry",
+			expiresAt: &future,
+			expected:  &future,
+		},
+		{
+			name:          "negative ttl",
+			ttlSeconds:    -1,
+			expectedField: "ttl_seconds",
+		},
+		{
+			name:          "expiry in the past",
+			expiresAt:     &past,
+			expectedField: "expires_at",
+		},
+		{
+			name:          "ttl and expiry together",
+			ttlSeconds:    60,
+			expiresAt:     &future,
+			expectedField: "expires_at",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v domain.FieldViolations
+			result := validateExpiry(&v, tt.ttlSeconds, tt.expiresAt, now)
+
+			assert.Equal(t, tt.expected, result)
+			if tt.expectedField != "" {
+				require.Len(t, v, 1)
+				assert.Equal(t, tt.expectedField, v[0].Field)
+			} else {
+				assert.Empty(t, v)
+			}
+		})
+	}
+}
+
+func TestService_ReapExpiredInstances(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "reaper"})
+	require.NoError(t, err)
+
+	create := func(name string, ttl int) *domain.Instance {
+		instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu", TTLSeconds: ttl,
+		})
+		require.NoError(t, err)
+		return instance
+	}
+	short := create("short", 60)
+	long := create("long", 3600)
+	forever := create("forever", 0)
+	assert.Nil(t, forever.ExpiresAt)
+
+	// Clearing the TTL exempts an instance from reaping
+	zero := 0
+	_, err = svc.UpdateInstance(long.ID, domain.UpdateInstanceRequest{TTLSeconds: &zero})
+	require.NoError(t, err)
+
+	reaped, err := svc.ReapExpiredInstances(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, reaped)
+
+	_, err = svc.GetInstance(short.ID)
+	assert.True(t, domain.IsNotFound(err))
+	_, err = svc.GetInstance(long.ID)
+	assert.NoError(t, err)
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceID: short.ID, Type: domain.EventInstanceExpired})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, project.ID, events[0].ProjectID)
+
+	events, err = svc.ListEvents(domain.EventListOptions{ResourceID: short.ID, Reason: domain.ReasonTTLExpired})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.StatusTerminated, events[0].Status)
+
+	// Nothing left to reap
+	reaped, err = svc.ReapExpiredInstances(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, reaped)
+}
+
+func TestService_DeletionDelay(t *testing.T) {
+	svc := newTestService(t)
+	svc.config.DeletionDelay = time.Minute
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "delayed"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeleteInstance(instance.ID))
+	deleting, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err, "a deleting instance can still be read")
+	assert.Equal(t, domain.StatusDeleting, deleting.Status)
+	require.NotNil(t, deleting.DeleteAt)
+
+	// Deleting again does not restart the delay
+	require.NoError(t, svc.DeleteInstance(instance.ID))
+	again, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, deleting.DeleteAt, again.DeleteAt)
+
+	stopped := domain.StatusStopped
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Status: &stopped})
+	assert.True(t, domain.IsFailedPrecondition(err), "got %v", err)
+
+	removed, err := svc.RemoveDeletedInstances(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+	removed, err = svc.RemoveDeletedInstances(deleting.DeleteAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, err = svc.GetInstance(instance.ID)
+	assert.True(t, domain.IsNotFound(err))
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceID: instance.ID, Type: domain.EventInstanceStatusChanged})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, domain.StatusDeleting, events[1].Status)
+	assert.Equal(t, domain.StatusTerminated, events[2].Status)
+	assert.Equal(t, domain.ActorReaper, events[2].Actor)
+
+	t.Run("removed when read after the delay", func(t *testing.T) {
+		svc.config.DeletionDelay = time.Nanosecond
+		instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: "vm-2", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+		})
+		require.NoError(t, err)
+		require.NoError(t, svc.DeleteInstance(instance.ID))
+
+		time.Sleep(time.Millisecond)
+		_, err = svc.GetInstance(instance.ID)
+		assert.True(t, domain.IsNotFound(err))
+		instances, err := svc.ListInstances(domain.InstanceListOptions{ProjectID: project.ID})
+		require.NoError(t, err)
+		assert.Empty(t, instances)
+	})
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// failingDeletes fails to delete the instance with ID id
+type failingDeletes struct {
+	InstanceRepository
+	id string
+}
+
+func (r *failingDeletes) Delete(id string) error {
+	if id == r.id {
+		return errors.New("delete failed")
+	}
+	return r.InstanceRepository.Delete(id)
+}
+
+func TestService_ReapExpiredInstances_Skips(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "reaper"})
+	require.NoError(t, err)
+	create := func(name string, protected bool) *domain.Instance {
+		instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu", TTLSeconds: 60, DeletionProtection: protected,
+		})
+		require.NoError(t, err)
+		return instance
+	}
+	protected := create("protected", true)
+	broken := create("broken", false)
+	other := create("other", false)
+
+	svc.instanceRepo = &failingDeletes{InstanceRepository: svc.instanceRepo, id: broken.ID}
+	svc.uow = nil
+
+	// A failed deletion does not hold up the other expired instances
+	reaped, err := svc.ReapExpiredInstances(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, reaped)
+
+	_, err = svc.GetInstance(other.ID)
+	assert.True(t, domain.IsNotFound(err))
+	_, err = svc.GetInstance(broken.ID)
+	assert.NoError(t, err)
+	_, err = svc.GetInstance(protected.ID)
+	assert.NoError(t, err, "the TTL does not override deletion protection")
+
+	// Expired instances honor the deletion delay
+	svc.config.DeletionDelay = time.Minute
+	svc.instanceRepo = svc.instanceRepo.(*failingDeletes).InstanceRepository
+	reaped, err = svc.ReapExpiredInstances(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, reaped)
+
+	deleting, err := svc.GetInstance(broken.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusDeleting, deleting.Status)
+
+	// and are not reaped again while deleting
+	reaped, err = svc.ReapExpiredInstances(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, reaped)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Replica is a read replica of the repositories that lags behind them, for
+// clients to test how they handle reading stale state
+type Replica interface {
+	// Sync moves the replica forward, replicating the current state to be
+	// served once it is old enough
+	Sync() error
+
+	// Snapshot returns the repositories of the state the replica serves and
+	// when that state was current, or false while it has none
+	Snapshot() (Repositories, time.Time, bool)
+}
+
+// Replica returns a copy of the service reading from its read replica, and
+// when the state it reads was current. It returns false if the service has
+// no replica or the replica has nothing to serve yet. The copy is only meant
+// for reads: the replica rejects writes.
+func (s *Service) Replica() (*Service, time.Time, bool) {
+	if s.replica == nil {
+		return nil, time.Time{}, false
+	}
+	repos, at, ok := s.replica.Snapshot()
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	// Reads need no transactions, which the replica could not begin. The
+	// request log is the server's own rather than replicated state, so
+	// requests are still recorded on the primary.
+	c := *s
+	c.setRepositories(repos)
+	c.uow = nil
+	c.traced = nil
+	c.replica = nil
+	c.requestLogRepo = s.requestLogRepo
+	return &c, at, true
+}
+
+// RunReplication syncs the read replica every interval until ctx is done
+func (s *Service) RunReplication(ctx context.Context, interval time.Duration) {
+	if s.replica == nil {
+		return
+	}
+	if err := s.replica.Sync(); err != nil {
+		log.Printf("Replica sync failed: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.replica.Sync(); err != nil {
+				log.Printf("Replica sync failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Replica(t *testing.T) {
+	_, _, ok := newTestService(t).Replica()
+	assert.False(t, ok, "services without a replica have none to read")
+
+	db, err := sqlite.NewDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	backupDir := t.TempDir()
+	replica := sqlite.NewReplica(db, 300*time.Millisecond, func(snapshot *sqlite.DB) Repositories {
+		return NewSQLiteRepositories(snapshot, backupDir)
+	})
+	t.Cleanup(func() { replica.Close() })
+	repos := NewSQLiteRepositories(db, backupDir)
+	repos.Replica = replica
+	svc := NewService(repos, Config{})
+
+	_, _, ok = svc.Replica()
+	assert.False(t, ok, "the replica serves nothing before it first syncs")
+
+	_, err = svc.CreateProject(domain.CreateProjectRequest{Name: "first"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go svc.RunReplication(ctx, 10*time.Millisecond)
+
+	names := func(s *Service) []string {
+		projects, err := s.ListProjects(domain.ProjectListOptions{})
+		require.NoError(t, err)
+		var names []string
+		for _, p := range projects {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	require.Eventually(t, func() bool {
+		_, _, ok := svc.Replica()
+		return ok
+	}, time.Second, 5*time.Millisecond)
+
+	// Writes show on the replica only once they are as old as its lag
+	written := time.Now()
+	_, err = svc.CreateProject(domain.CreateProjectRequest{Name: "second"})
+	require.NoError(t, err)
+	read, _, _ := svc.Replica()
+	assert.Equal(t, []string{"first"}, names(read))
+
+	require.Eventually(t, func() bool {
+		read, _, _ := svc.Replica()
+		return len(names(read)) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(written), 300*time.Millisecond)
+	assert.Equal(t, []string{"first", "second"}, names(svc))
+
+	// The replica cannot be written to
+	read, _, _ = svc.Replica()
+	_, err = read.CreateProject(domain.CreateProjectRequest{Name: "third"})
+	assert.Error(t, err)
+}
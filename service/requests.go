@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// RecordRequest appends a served request to the request log, which keeps the
+// newest keep entries. A keep of 0 or less logs nothing.
+func (s *Service) RecordRequest(entry *domain.RequestLogEntry, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	return s.requestLogRepo.Append(entry, keep)
+}
+
+// ListRequests lists logged requests, newest first
+func (s *Service) ListRequests(opts domain.RequestLogListOptions) ([]*domain.RequestLogEntry, error) {
+	return s.requestLogRepo.List(opts)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_ResizeInstance(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	tests := []struct {
+		name          string
+		config        Config
+		status        string
+		req           domain.ResizeInstanceRequest
+		expectedCode  string
+		expectedCPU   int
+		expectedMemMB int
+	}{
+		{
+			name:          "stopped instance",
+			status:        domain.StatusStopped,
+			req:           domain.ResizeInstanceRequest{CPU: intPtr(4)},
+			expectedCPU:   4,
+			expectedMemMB: 512,
+		},
+		{
+			name:         "running instance is rejected",
+			status:       domain.StatusRunning,
+			req:          domain.ResizeInstanceRequest{CPU: intPtr(4)},
+			expectedCode: domain.ErrorCodeFailedPrecondition,
+		},
+		{
+			name:          "running instance with online resize",
+			config:        Config{AllowOnlineResize: true},
+			status:        domain.StatusRunning,
+			req:           domain.ResizeInstanceRequest{MemoryMB: intPtr(2048)},
+			expectedCPU:   1,
+			expectedMemMB: 2048,
+		},
+		{
+			name:         "empty request",
+			status:       domain.StatusStopped,
+			expectedCode: domain.ErrorCodeInvalidInput,
+		},
+		{
+			name:         "invalid size",
+			status:       domain.StatusStopped,
+			req:          domain.ResizeInstanceRequest{CPU: intPtr(128)},
+			expectedCode: domain.ErrorCodeInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t)
+			svc.config = tt.config
+
+			project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "resize"})
+			require.NoError(t, err)
+			instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+				ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: tt.status,
+			})
+			require.NoError(t, err)
+
+			resized, err := svc.ResizeInstance(instance.ID, tt.req)
+			if tt.expectedCode != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedCode, err.(*domain.DirtError).Code)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCPU, resized.CPU)
+			assert.Equal(t, tt.expectedMemMB, resized.MemoryMB)
+
+			events, err := svc.ListEvents(domain.EventListOptions{ResourceID: instance.ID, Type: domain.EventInstanceResized})
+			require.NoError(t, err)
+			assert.Len(t, events, 1)
+		})
+	}
+}
+
+func TestService_ResizeInstance_Quota(t *testing.T) {
+	svc := newTestService(t)
+
+	org, err := svc.CreateOrganization(domain.CreateOrganizationRequest{Name: "acme", Quota: &domain.Quota{MaxCPU: 4}})
+	require.NoError(t, err)
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "resize", OrganizationID: org.ID})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 2, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusStopped,
+	})
+	require.NoError(t, err)
+
+	// The instance's current size does not count against its new size
+	cpu := 4
+	_, err = svc.ResizeInstance(instance.ID, domain.ResizeInstanceRequest{CPU: &cpu})
+	require.NoError(t, err)
+
+	cpu = 5
+	_, err = svc.ResizeInstance(instance.ID, domain.ResizeInstanceRequest{CPU: &cpu})
+	require.Error(t, err)
+	assert.Equal(t, domain.ErrorCodeQuotaExceeded, err.(*domain.DirtError).Code)
+}
+
+func TestService_UpdateInstance_Resize(t *testing.T) {
+	svc := newTestService(t)
+
+	org, err := svc.CreateOrganization(domain.CreateOrganizationRequest{Name: "acme", Quota: &domain.Quota{MaxCPU: 4}})
+	require.NoError(t, err)
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "resize", OrganizationID: org.ID})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 2, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	cpu := 4
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{CPU: &cpu})
+	require.Error(t, err)
+	assert.Equal(t, domain.ErrorCodeFailedPrecondition, err.(*domain.DirtError).Code)
+
+	stopped := domain.StatusStopped
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Status: &stopped})
+	require.NoError(t, err)
+	updated, err := svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{CPU: &cpu})
+	require.NoError(t, err)
+	assert.Equal(t, 4, updated.CPU)
+
+	cpu = 5
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{CPU: &cpu})
+	require.Error(t, err)
+	assert.Equal(t, domain.ErrorCodeQuotaExceeded, err.(*domain.DirtError).Code)
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// SearchTypes lists the resource types a search covers, in result order
+var SearchTypes = []string{
+	"organization",
+	"folder",
+	"project",
+	"instance",
+	"instance_template",
+	"autoscaling_group",
+	"budget",
+	"database",
+	"topic",
+	"subscription",
+	"secret",
+}
+
+// searchMatcher reports whether a resource with the given name and labels
+// matches a search query
+type searchMatcher func(name string, labels map[string]string) bool
+
+// newSearchMatcher compiles a search query. Patterns match case-insensitively;
+// a pattern without wildcards matches names containing it.
+func newSearchMatcher(query string) (searchMatcher, error) {
+	pattern := strings.ToLower(query)
+	labelKey := ""
+	if key, value, ok := strings.Cut(query, "="); ok {
+		labelKey, pattern = key, strings.ToLower(value)
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		pattern = "*" + pattern + "*"
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	match := func(s string) bool {
+		ok, _ := path.Match(pattern, strings.ToLower(s))
+		return ok
+	}
+
+	if labelKey != "" {
+		return func(_ string, labels map[string]string) bool {
+			value, ok := labels[labelKey]
+			return ok && match(value)
+		}, nil
+	}
+	return func(name string, _ map[string]string) bool {
+		return match(name)
+	}, nil
+}
+
+// Search finds resources of several types by name or label. Results are
+// ordered by type, as in SearchTypes, then by name.
+func (s *Service) Search(opts domain.SearchOptions) ([]domain.SearchResult, error) {
+	var v domain.FieldViolations
+	if opts.Query == "" {
+		v.Add("q", "cannot be empty")
+	}
+	for _, t := range opts.Types {
+		if !containsString(SearchTypes, t) {
+			v.Add("types", fmt.Sprintf("unknown resource type %q (must be one of %s)", t, strings.Join(SearchTypes, ", ")))
+		}
+	}
+	var match searchMatcher
+	if opts.Query != "" {
+		var err error
+		if match, err = newSearchMatcher(opts.Query); err != nil {
+			v.Add("q", "is not a valid pattern")
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	types := opts.Types
+	if len(types) == 0 {
+		types = SearchTypes
+	}
+
+	results := []domain.SearchResult{}
+	for _, t := range SearchTypes {
+		if !containsString(types, t) {
+			continue
+		}
+		found, err := s.searchType(t, match)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+		results = append(results, found...)
+	}
+
+	return results, nil
+}
+
+// searchType lists the resources of one type that match
+func (s *Service) searchType(resourceType string, match searchMatcher) ([]domain.SearchResult, error) {
+	var results []domain.SearchResult
+	add := func(id, name, projectID string, labels map[string]string, resource interface{}) {
+		if match(name, labels) {
+			results = append(results, domain.SearchResult{
+				Type:      resourceType,
+				ID:        id,
+				Name:      name,
+				ProjectID: projectID,
+				Labels:    labels,
+				Resource:  resource,
+			})
+		}
+	}
+
+	switch resourceType {
+	case "organization":
+		orgs, err := s.ListOrganizations(domain.OrganizationListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range orgs {
+			add(o.ID, o.Name, "", o.Labels, o)
+		}
+	case "folder":
+		folders, err := s.ListFolders(domain.FolderListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range folders {
+			add(f.ID, f.Name, "", f.Labels, f)
+		}
+	case "project":
+		projects, err := s.ListProjects(domain.ProjectListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			add(p.ID, p.Name, "", p.Labels, p)
+		}
+	case "instance":
+		instances, err := s.ListInstances(domain.InstanceListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range instances {
+			add(i.ID, i.Name, i.ProjectID, i.Labels, i)
+		}
+	case "instance_template":
+		templates, err := s.ListInstanceTemplates(domain.InstanceTemplateListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range templates {
+			add(t.ID, t.Name, "", t.Labels, t)
+		}
+	case "autoscaling_group":
+		groups, err := s.ListAutoscalingGroups(domain.AutoscalingGroupListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			add(g.ID, g.Name, g.ProjectID, nil, g)
+		}
+	case "budget":
+		budgets, err := s.ListBudgets(domain.BudgetListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range budgets {
+			add(b.ID, b.Name, b.ProjectID, nil, b)
+		}
+	case "database":
+		databases, err := s.ListDatabases(domain.DatabaseListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range databases {
+			add(d.ID, d.Name, d.ProjectID, nil, d)
+		}
+	case "topic":
+		topics, err := s.ListTopics(domain.TopicListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range topics {
+			add(t.ID, t.Name, t.ProjectID, nil, t)
+		}
+	case "subscription":
+		subscriptions, err := s.ListSubscriptions(domain.SubscriptionListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range subscriptions {
+			add(sub.ID, sub.Name, sub.ProjectID, nil, sub)
+		}
+	case "secret":
+		secrets, err := s.ListSecrets(domain.SecretListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, sec := range secrets {
+			add(sec.ID, sec.Name, sec.ProjectID, nil, sec)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Search(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "web-prod", Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	_, err = svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "web-1", CPU: 1, MemoryMB: 512, Image: "ubuntu", Labels: map[string]string{"env": "Prod"}})
+	require.NoError(t, err)
+	_, err = svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "db-1", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+	_, err = svc.CreateTopic(domain.CreateTopicRequest{ProjectID: project.ID, Name: "web-events"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		opts     domain.SearchOptions
+		expected []string
+	}{
+		{"glob across types", domain.SearchOptions{Query: "web-*"}, []string{"project:web-prod", "instance:web-1", "topic:web-events"}},
+		{"restricted types", domain.SearchOptions{Query: "web-*", Types: []string{"instance", "project"}}, []string{"project:web-prod", "instance:web-1"}},
+		{"substring ignoring case", domain.SearchOptions{Query: "DB"}, []string{"instance:db-1"}},
+		{"label", domain.SearchOptions{Query: "env=prod"}, []string{"project:web-prod", "instance:web-1"}},
+		{"label glob", domain.SearchOptions{Query: "env=st*"}, nil},
+		{"no match", domain.SearchOptions{Query: "api-?"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := svc.Search(tt.opts)
+			require.NoError(t, err)
+
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Type+":"+r.Name)
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := svc.Search(domain.SearchOptions{})
+		assert.True(t, domain.IsInvalidInput(err))
+		_, err = svc.Search(domain.SearchOptions{Query: "web", Types: []string{"volume"}})
+		assert.True(t, domain.IsInvalidInput(err))
+		_, err = svc.Search(domain.SearchOptions{Query: "web-["})
+		assert.True(t, domain.IsInvalidInput(err))
+	})
+}
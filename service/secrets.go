@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Secret limits, after those of common secret managers
+const (
+	maxSecretPayloadBytes    = 64 * 1024
+	minSecretRotationSeconds = 60
+)
+
+// latestSecretVersion names the newest version of a secret
+const latestSecretVersion = "latest"
+
+// validateRotationPeriod validates a secret's rotation period. 0 disables rotation.
+func validateRotationPeriod(v *domain.FieldViolations, seconds int64) {
+	if seconds != 0 && seconds < minSecretRotationSeconds {
+		v.Add("rotation_period_seconds", fmt.Sprintf("must be 0 or at least %d (got %d)", minSecretRotationSeconds, seconds))
+	}
+}
+
+// validateSecretPayload validates the payload of a secret version
+func validateSecretPayload(v *domain.FieldViolations, payload string) {
+	if len(payload) > maxSecretPayloadBytes {
+		v.Add("payload", fmt.Sprintf("must be at most %d bytes (got %d)", maxSecretPayloadBytes, len(payload)))
+	}
+}
+
+// nextRotation returns when a secret with a rotation period rotates next,
+// counting from now, or nil for a secret that does not rotate
+func nextRotation(periodSeconds int64, now time.Time) *time.Time {
+	if periodSeconds == 0 {
+		return nil
+	}
+	next := now.Add(time.Duration(periodSeconds) * time.Second)
+	return &next
+}
+
+// generateSecretPayload returns the value given to a secret when it is rotated
+func generateSecretPayload() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Secret operations
+
+// CreateSecret creates a new secret, with its payload as version 1 if given
+func (s *Service) CreateSecret(req domain.CreateSecretRequest) (*domain.Secret, error) {
+	var v domain.FieldViolations
+	if req.ProjectID == "" {
+		v.Add("project_id", "cannot be empty")
+	}
+	validateName(&v, "name", req.Name)
+	validateRotationPeriod(&v, req.RotationPeriodSeconds)
+	if req.Payload != nil {
+		validateSecretPayload(&v, *req.Payload)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	id, err := s.newID(kindSecret)
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+
+	return inTx(s, func(tx *Service) (*domain.Secret, error) {
+		secret := &domain.Secret{
+			ID:                    id,
+			ProjectID:             req.ProjectID,
+			Name:                  req.Name,
+			RotationPeriodSeconds: req.RotationPeriodSeconds,
+			NextRotationAt:        nextRotation(req.RotationPeriodSeconds, time.Now()),
+		}
+
+		if err := tx.secretRepo.Create(secret); err != nil {
+			return nil, err
+		}
+
+		if req.Payload != nil {
+			if _, err := tx.secretRepo.AddVersion(secret.ID, *req.Payload); err != nil {
+				return nil, err
+			}
+			return tx.secretRepo.GetByID(secret.ID)
+		}
+
+		return secret, nil
+	})
+}
+
+// GetSecret retrieves a secret by ID
+func (s *Service) GetSecret(id string) (*domain.Secret, error) {
+	return s.secretRepo.GetByID(id)
+}
+
+// ListSecrets lists secrets with optional filtering
+func (s *Service) ListSecrets(opts domain.SecretListOptions) ([]*domain.Secret, error) {
+	return s.secretRepo.List(opts)
+}
+
+// UpdateSecret updates a secret. Changing the rotation period restarts the
+// rotation schedule from now.
+func (s *Service) UpdateSecret(id string, req domain.UpdateSecretRequest) (*domain.Secret, error) {
+	var v domain.FieldViolations
+	if req.Name != nil {
+		validateName(&v, "name", *req.Name)
+	}
+	if req.RotationPeriodSeconds != nil {
+		validateRotationPeriod(&v, *req.RotationPeriodSeconds)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.Secret, error) {
+		current, err := tx.secretRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		next := current.NextRotationAt
+		if req.RotationPeriodSeconds != nil && *req.RotationPeriodSeconds != current.RotationPeriodSeconds {
+			next = nextRotation(*req.RotationPeriodSeconds, time.Now())
+		}
+
+		return tx.secretRepo.Update(id, req, next)
+	})
+}
+
+// DeleteSecret deletes a secret and all of its versions
+func (s *Service) DeleteSecret(id string) error {
+	return s.secretRepo.Delete(id)
+}
+
+// AddSecretVersion stores a new version of a secret
+func (s *Service) AddSecretVersion(secretID string, req domain.AddSecretVersionRequest) (*domain.SecretVersion, error) {
+	var v domain.FieldViolations
+	validateSecretPayload(&v, req.Payload)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return inTx(s, func(tx *Service) (*domain.SecretVersion, error) {
+		return tx.secretRepo.AddVersion(secretID, req.Payload)
+	})
+}
+
+// ListSecretVersions lists the versions of a secret, newest first
+func (s *Service) ListSecretVersions(secretID string) ([]*domain.SecretVersion, error) {
+	if _, err := s.secretRepo.GetByID(secretID); err != nil {
+		return nil, err
+	}
+	return s.secretRepo.ListVersions(secretID)
+}
+
+// resolveSecretVersion returns the number of a secret version given as a
+// number or "latest"
+func (s *Service) resolveSecretVersion(secretID, version string) (int, error) {
+	if version == latestSecretVersion {
+		secret, err := s.secretRepo.GetByID(secretID)
+		if err != nil {
+			return 0, err
+		}
+		if secret.LatestVersion == 0 {
+			return 0, domain.NotFoundError("secret version", secretID+"/"+latestSecretVersion)
+		}
+		return secret.LatestVersion, nil
+	}
+
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 1 {
+		var v domain.FieldViolations
+		v.Add("version", fmt.Sprintf("must be a positive number or %q (got %q)", latestSecretVersion, version))
+		return 0, v.Err()
+	}
+	if _, err := s.secretRepo.GetByID(secretID); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetSecretVersion retrieves a secret version, given as a number or "latest"
+func (s *Service) GetSecretVersion(secretID, version string) (*domain.SecretVersion, error) {
+	n, err := s.resolveSecretVersion(secretID, version)
+	if err != nil {
+		return nil, err
+	}
+	return s.secretRepo.GetVersion(secretID, n)
+}
+
+// AccessSecretVersion returns the payload of a secret version, given as a
+// number or "latest". Destroyed versions have no payload.
+func (s *Service) AccessSecretVersion(secretID, version string) (*domain.SecretPayload, error) {
+	n, err := s.resolveSecretVersion(secretID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.secretRepo.GetVersion(secretID, n)
+	if err != nil {
+		return nil, err
+	}
+	if v.State == domain.SecretVersionDestroyed {
+		return nil, domain.FailedPreconditionError(fmt.Sprintf("secret version %d is destroyed", n), map[string]interface{}{
+			"secret_id": secretID,
+			"version":   n,
+		})
+	}
+
+	payload, err := s.secretRepo.GetPayload(secretID, n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SecretPayload{SecretID: secretID, Version: n, Payload: payload}, nil
+}
+
+// DestroySecretVersion irrecoverably erases the payload of a secret version,
+// given as a number or "latest"
+func (s *Service) DestroySecretVersion(secretID, version string) (*domain.SecretVersion, error) {
+	return inTx(s, func(tx *Service) (*domain.SecretVersion, error) {
+		n, err := tx.resolveSecretVersion(secretID, version)
+		if err != nil {
+			return nil, err
+		}
+
+		current, err := tx.secretRepo.GetVersion(secretID, n)
+		if err != nil {
+			return nil, err
+		}
+		if current.State == domain.SecretVersionDestroyed {
+			return nil, domain.FailedPreconditionError(fmt.Sprintf("secret version %d is already destroyed", n), map[string]interface{}{
+				"secret_id": secretID,
+				"version":   n,
+			})
+		}
+
+		return tx.secretRepo.DestroyVersion(secretID, n)
+	})
+}
+
+// RotateDueSecrets gives every secret whose rotation is due at now a new,
+// generated version, recording an event for each, and schedules its next
+// rotation one period after now. It returns how many secrets were rotated.
+func (s *Service) RotateDueSecrets(now time.Time) (int, error) {
+	s = s.WithActor(domain.ActorRotator)
+
+	due, err := s.secretRepo.ListDueForRotation(now)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, secret := range due {
+		payload, err := generateSecretPayload()
+		if err != nil {
+			return rotated, domain.InternalError("failed to generate secret payload")
+		}
+
+		// Each rotation commits together with its event
+		err = s.runInTx(func(tx *Service) error {
+			version, err := tx.secretRepo.AddVersion(secret.ID, payload)
+			if err != nil {
+				return err
+			}
+			if err := tx.secretRepo.SetNextRotation(secret.ID, nextRotation(secret.RotationPeriodSeconds, now)); err != nil {
+				return err
+			}
+			tx.recordEvent(domain.EventSecretRotated, "secret", secret.ID, secret.ProjectID,
+				fmt.Sprintf("secret %s rotated to version %d", secret.Name, version.Version))
+			return nil
+		})
+		if err != nil {
+			if domain.IsNotFound(err) {
+				continue // Deleted concurrently
+			}
+			return rotated, err
+		}
+		rotated++
+	}
+
+	return rotated, nil
+}
+
+// RunSecretRotation rotates due secrets every interval until ctx is done
+func (s *Service) RunSecretRotation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := s.RotateDueSecrets(now); err != nil {
+				log.Printf("Secret rotation failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Secret rotation rotated %d secret(s)", n)
+			}
+		}
+	}
+}
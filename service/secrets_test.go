@@ -0,0 +1,119 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_SecretVersions(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "secrets"})
+	require.NoError(t, err)
+
+	secret, err := svc.CreateSecret(domain.CreateSecretRequest{ProjectID: project.ID, Name: "token"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, secret.LatestVersion)
+	assert.Nil(t, secret.NextRotationAt)
+
+	_, err = svc.AccessSecretVersion(secret.ID, "latest")
+	assert.True(t, domain.IsNotFound(err))
+
+	for _, payload := range []string{"one", "two"} {
+		_, err := svc.AddSecretVersion(secret.ID, domain.AddSecretVersionRequest{Payload: payload})
+		require.NoError(t, err)
+	}
+
+	latest, err := svc.AccessSecretVersion(secret.ID, "latest")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.SecretPayload{SecretID: secret.ID, Version: 2, Payload: "two"}, latest)
+
+	first, err := svc.AccessSecretVersion(secret.ID, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "one", first.Payload)
+
+	destroyed, err := svc.DestroySecretVersion(secret.ID, "1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SecretVersionDestroyed, destroyed.State)
+	assert.NotNil(t, destroyed.DestroyedAt)
+
+	_, err = svc.AccessSecretVersion(secret.ID, "1")
+	assert.True(t, domain.IsFailedPrecondition(err))
+	_, err = svc.DestroySecretVersion(secret.ID, "1")
+	assert.True(t, domain.IsFailedPrecondition(err))
+	_, err = svc.AccessSecretVersion(secret.ID, "3")
+	assert.True(t, domain.IsNotFound(err))
+
+	_, err = svc.CreateSecret(domain.CreateSecretRequest{ProjectID: project.ID, Name: "token"})
+	assert.True(t, domain.IsAlreadyExists(err))
+	_, err = svc.CreateSecret(domain.CreateSecretRequest{ProjectID: "missing", Name: "orphan"})
+	assert.True(t, domain.IsForeignKeyViolation(err))
+}
+
+func TestService_RotateDueSecrets(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "rotation"})
+	require.NoError(t, err)
+
+	payload := "initial"
+	rotating, err := svc.CreateSecret(domain.CreateSecretRequest{ProjectID: project.ID, Name: "rotating", Payload: &payload, RotationPeriodSeconds: 3600})
+	require.NoError(t, err)
+	require.NotNil(t, rotating.NextRotationAt)
+	_, err = svc.CreateSecret(domain.CreateSecretRequest{ProjectID: project.ID, Name: "static", Payload: &payload})
+	require.NoError(t, err)
+
+	// Nothing is due before the first period ends
+	n, err := svc.RotateDueSecrets(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	now := rotating.NextRotationAt.Add(time.Second)
+	n, err = svc.RotateDueSecrets(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	rotated, err := svc.GetSecret(rotating.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rotated.LatestVersion)
+	require.NotNil(t, rotated.NextRotationAt)
+	assert.WithinDuration(t, now.Add(time.Hour), *rotated.NextRotationAt, time.Second)
+
+	value, err := svc.AccessSecretVersion(rotating.ID, "latest")
+	require.NoError(t, err)
+	assert.NotEqual(t, "initial", value.Payload)
+	assert.Len(t, value.Payload, 64)
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceID: rotating.ID})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.EventSecretRotated, events[0].Type)
+	assert.Equal(t, domain.ActorRotator, events[0].Actor)
+
+	// Stopping rotation clears the schedule
+	zero := int64(0)
+	stopped, err := svc.UpdateSecret(rotating.ID, domain.UpdateSecretRequest{RotationPeriodSeconds: &zero})
+	require.NoError(t, err)
+	assert.Nil(t, stopped.NextRotationAt)
+
+	n, err = svc.RotateDueSecrets(now.Add(24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestValidateRotationPeriod(t *testing.T) {
+	for _, seconds := range []int64{0, 60, 86400} {
+		var v domain.FieldViolations
+		validateRotationPeriod(&v, seconds)
+		assert.NoError(t, v.Err(), seconds)
+	}
+	for _, seconds := range []int64{1, 59, -60} {
+		var v domain.FieldViolations
+		validateRotationPeriod(&v, seconds)
+		assert.Error(t, v.Err(), seconds)
+	}
+}
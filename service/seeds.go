@@ -0,0 +1,292 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// SeedTypes are the resource types a seed can hold, in the order they are
+// loaded. Secrets are left out since their payloads are never recorded.
+var SeedTypes = []string{
+	"organization",
+	"folder",
+	"project",
+	"instance_template",
+	"autoscaling_group",
+	"instance",
+	"budget",
+	"database",
+	"topic",
+	"subscription",
+	"metadata",
+}
+
+// seedCollections maps API collections to the seed type of their resources
+var seedCollections = map[string]string{
+	"organizations":      "organization",
+	"folders":            "folder",
+	"projects":           "project",
+	"instance-templates": "instance_template",
+	"autoscaling-groups": "autoscaling_group",
+	"instances":          "instance",
+	"budgets":            "budget",
+	"databases":          "database",
+	"topics":             "topic",
+	"subscriptions":      "subscription",
+	"metadata":           "metadata",
+}
+
+// seedActions are the instance actions whose response is an instance
+var seedActions = map[string]bool{"resize": true, "clone": true}
+
+// seedOwners are the fields naming the owner of resources deleted along with
+// it, by owner type
+var seedOwners = map[string]string{
+	"project":           "project_id",
+	"autoscaling_group": "autoscaling_group_id",
+	"topic":             "topic_id",
+}
+
+// seedKey identifies a resource in recorded traffic
+type seedKey struct {
+	Type string
+	ID   string
+}
+
+// GenerateSeed builds a seed from recorded API traffic. Each resource is
+// taken in its final state: the last successful response that returned it,
+// unless a later request deleted it. List responses are not used, since they
+// can include resources the traffic did not touch. Values the mirror redacted
+// stay redacted.
+func GenerateSeed(traffic []*domain.MirroredRequest) *domain.Seed {
+	resources := make(map[seedKey]json.RawMessage)
+	var order []seedKey
+
+	for _, req := range traffic {
+		if req.Status < 200 || req.Status >= 300 {
+			continue
+		}
+		resourceType, id, ok := seedTarget(req.Path)
+		if !ok {
+			continue
+		}
+
+		if req.Method == http.MethodDelete {
+			if id != "" {
+				delete(resources, seedKey{resourceType, id})
+				deleteOwned(resources, resourceType, id)
+			}
+			continue
+		}
+
+		var body struct {
+			ID string `json:"id"`
+		}
+		if len(req.ResponseBody) == 0 || json.Unmarshal(req.ResponseBody, &body) != nil || body.ID == "" {
+			continue
+		}
+
+		key := seedKey{resourceType, body.ID}
+		if _, seen := resources[key]; !seen {
+			order = append(order, key)
+		}
+		resources[key] = req.ResponseBody
+	}
+
+	seed := &domain.Seed{Resources: []domain.SeedResource{}}
+	for _, resourceType := range SeedTypes {
+		var keys []seedKey
+		for _, key := range order {
+			if _, ok := resources[key]; ok && key.Type == resourceType {
+				keys = append(keys, key)
+			}
+		}
+		if resourceType == "folder" {
+			keys = parentsFirst(keys, resources)
+		}
+		for _, key := range keys {
+			seed.Resources = append(seed.Resources, domain.SeedResource{Type: key.Type, Resource: resources[key]})
+		}
+	}
+	return seed
+}
+
+// deleteOwned drops the resources deleted along with their owner
+func deleteOwned(resources map[seedKey]json.RawMessage, ownerType, ownerID string) {
+	field, ok := seedOwners[ownerType]
+	if !ok {
+		return
+	}
+	for key, resource := range resources {
+		var owners map[string]interface{}
+		if json.Unmarshal(resource, &owners) == nil && owners[field] == ownerID {
+			delete(resources, key)
+		}
+	}
+}
+
+// seedTarget returns the seed type of the resources a request path returns,
+// and the resource ID when the path names one
+func seedTarget(path string) (resourceType, id string, ok bool) {
+	segments := strings.Split(strings.TrimPrefix(path, "/v1/"), "/")
+	collection, _, _ := strings.Cut(segments[0], ":")
+	if resourceType, ok = seedCollections[collection]; !ok {
+		return "", "", false
+	}
+
+	switch {
+	case len(segments) == 1:
+		return resourceType, "", true
+	case len(segments) == 2:
+		id, _, _ = strings.Cut(segments[1], ":")
+		return resourceType, id, true
+	case len(segments) == 3 && resourceType == "instance" && seedActions[segments[2]]:
+		return resourceType, segments[1], true
+	}
+	return "", "", false
+}
+
+// parentsFirst orders folders so that each comes after its parent
+func parentsFirst(keys []seedKey, resources map[seedKey]json.RawMessage) []seedKey {
+	parents := make(map[string]string, len(keys))
+	for _, key := range keys {
+		var folder domain.Folder
+		if json.Unmarshal(resources[key], &folder) == nil {
+			parents[key.ID] = folder.ParentID
+		}
+	}
+
+	ordered := make([]seedKey, 0, len(keys))
+	placed := make(map[string]bool, len(keys))
+	var place func(key seedKey)
+	place = func(key seedKey) {
+		if placed[key.ID] {
+			return
+		}
+		placed[key.ID] = true
+		if parent := parents[key.ID]; parent != "" {
+			if _, ok := parents[parent]; ok {
+				place(seedKey{key.Type, parent})
+			}
+		}
+		ordered = append(ordered, key)
+	}
+	for _, key := range keys {
+		place(key)
+	}
+	return ordered
+}
+
+// LoadSeed creates the resources of a seed with the IDs they were recorded
+// with. The seed is loaded in one transaction, so a resource that conflicts
+// with existing data leaves nothing loaded.
+func (s *Service) LoadSeed(seed domain.Seed) error {
+	var v domain.FieldViolations
+	for i, r := range seed.Resources {
+		if !containsString(SeedTypes, r.Type) {
+			v.Add(fmt.Sprintf("resources[%d].type", i), fmt.Sprintf("unknown resource type %q (must be one of %s)", r.Type, strings.Join(SeedTypes, ", ")))
+		}
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	return s.runInTx(func(tx *Service) error {
+		for i, r := range seed.Resources {
+			if err := tx.loadSeedResource(r); err != nil {
+				if domain.IsInvalidInput(err) {
+					return domain.ValidationError([]domain.FieldViolation{
+						{Field: fmt.Sprintf("resources[%d].resource", i), Message: err.Error()},
+					})
+				}
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loadSeedResource stores one seed resource as it was recorded
+func (s *Service) loadSeedResource(r domain.SeedResource) error {
+	decode := func(v interface{}) error {
+		if err := json.Unmarshal(r.Resource, v); err != nil {
+			return domain.InvalidInputError("not a valid "+r.Type, nil)
+		}
+		return nil
+	}
+
+	switch r.Type {
+	case "organization":
+		var org domain.Organization
+		if err := decode(&org); err != nil {
+			return err
+		}
+		return s.organizationRepo.Create(&org)
+	case "folder":
+		var folder domain.Folder
+		if err := decode(&folder); err != nil {
+			return err
+		}
+		return s.folderRepo.Create(&folder)
+	case "project":
+		var project domain.Project
+		if err := decode(&project); err != nil {
+			return err
+		}
+		return s.projectRepo.Create(&project)
+	case "instance_template":
+		var tmpl domain.InstanceTemplate
+		if err := decode(&tmpl); err != nil {
+			return err
+		}
+		return s.templateRepo.Create(&tmpl)
+	case "autoscaling_group":
+		var group domain.AutoscalingGroup
+		if err := decode(&group); err != nil {
+			return err
+		}
+		return s.groupRepo.Create(&group)
+	case "instance":
+		var instance domain.Instance
+		if err := decode(&instance); err != nil {
+			return err
+		}
+		return s.instanceRepo.Create(&instance)
+	case "budget":
+		var budget domain.Budget
+		if err := decode(&budget); err != nil {
+			return err
+		}
+		return s.budgetRepo.Create(&budget)
+	case "database":
+		var database domain.Database
+		if err := decode(&database); err != nil {
+			return err
+		}
+		return s.databaseRepo.Create(&database)
+	case "topic":
+		var topic domain.Topic
+		if err := decode(&topic); err != nil {
+			return err
+		}
+		return s.topicRepo.Create(&topic)
+	case "subscription":
+		var subscription domain.Subscription
+		if err := decode(&subscription); err != nil {
+			return err
+		}
+		return s.subscriptionRepo.Create(&subscription)
+	case "metadata":
+		var metadata domain.Metadata
+		if err := decode(&metadata); err != nil {
+			return err
+		}
+		_, err := s.metadataRepo.Create(metadata.ID, domain.CreateMetadataRequest{Path: metadata.Path, Value: metadata.Value})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorded returns a mirrored request whose response is resource
+func recorded(t *testing.T, method, path string, status int, resource interface{}) *domain.MirroredRequest {
+	req := &domain.MirroredRequest{Method: method, Path: path, Status: status}
+	if resource != nil {
+		body, err := json.Marshal(resource)
+		require.NoError(t, err)
+		req.ResponseBody = body
+	}
+	return req
+}
+
+func seedIDs(t *testing.T, seed *domain.Seed) []string {
+	var ids []string
+	for _, r := range seed.Resources {
+		var resource struct {
+			ID string `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(r.Resource, &resource))
+		ids = append(ids, r.Type+"/"+resource.ID)
+	}
+	return ids
+}
+
+func TestGenerateSeed(t *testing.T) {
+	org := &domain.Organization{ID: "org1", Name: "acme"}
+	parent := &domain.Folder{ID: "f1", OrganizationID: "org1", Name: "parent"}
+	child := &domain.Folder{ID: "f2", OrganizationID: "org1", ParentID: "f1", Name: "child"}
+	project := &domain.Project{ID: "p1", Name: "web", FolderID: "f2"}
+	renamed := &domain.Project{ID: "p1", Name: "web-renamed", FolderID: "f2"}
+	kept := &domain.Instance{ID: "i1", ProjectID: "p1", Name: "kept"}
+	deleted := &domain.Instance{ID: "i2", ProjectID: "p1", Name: "deleted"}
+	gone := &domain.Project{ID: "p2", Name: "gone"}
+	orphan := &domain.Topic{ID: "t1", ProjectID: "p2", Name: "orphan"}
+
+	seed := GenerateSeed([]*domain.MirroredRequest{
+		// The child folder is seen before its parent and the instance
+		// before its project
+		recorded(t, "GET", "/v1/folders/f2", 200, child),
+		recorded(t, "POST", "/v1/instances", 201, kept),
+		recorded(t, "POST", "/v1/organizations", 201, org),
+		recorded(t, "POST", "/v1/folders", 201, parent),
+		recorded(t, "POST", "/v1/projects", 201, project),
+		recorded(t, "PATCH", "/v1/projects/p1", 200, renamed),
+		recorded(t, "POST", "/v1/instances:fromTemplate", 201, deleted),
+		recorded(t, "DELETE", "/v1/instances/i2", 204, nil),
+		recorded(t, "POST", "/v1/projects", 201, gone),
+		recorded(t, "POST", "/v1/topics", 201, orphan),
+		recorded(t, "DELETE", "/v1/projects/p2", 204, nil),
+		recorded(t, "GET", "/v1/projects", 200, []*domain.Project{gone}),
+		recorded(t, "GET", "/v1/projects/p1/ancestry", 200, &domain.ProjectAncestry{Project: project}),
+		recorded(t, "POST", "/v1/projects", 409, &domain.Project{ID: "p3"}),
+	})
+
+	assert.Equal(t, []string{"organization/org1", "folder/f1", "folder/f2", "project/p1", "instance/i1"}, seedIDs(t, seed))
+	assert.Contains(t, string(seed.Resources[3].Resource), "web-renamed")
+}
+
+func TestService_LoadSeed(t *testing.T) {
+	source := newTestService(t)
+	project, err := source.CreateProject(domain.CreateProjectRequest{Name: "seeded", Labels: map[string]string{"env": "test"}})
+	require.NoError(t, err)
+	instance, err := source.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm", CPU: 2, MemoryMB: 1024, Image: "ubuntu"})
+	require.NoError(t, err)
+	metadata, err := source.CreateMetadata(domain.CreateMetadataRequest{Path: "app/config", Value: "on"})
+	require.NoError(t, err)
+
+	seed := GenerateSeed([]*domain.MirroredRequest{
+		recorded(t, "POST", "/v1/projects", 201, project),
+		recorded(t, "POST", "/v1/instances", 201, instance),
+		recorded(t, "POST", "/v1/metadata", 201, metadata),
+	})
+
+	svc := newTestService(t)
+	require.NoError(t, svc.LoadSeed(*seed))
+
+	loadedProject, err := svc.GetProject(project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, project.Name, loadedProject.Name)
+	assert.Equal(t, project.Labels, loadedProject.Labels)
+
+	loadedInstance, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, instance.Name, loadedInstance.Name)
+	assert.Equal(t, instance.Status, loadedInstance.Status)
+
+	loadedMetadata, err := svc.GetMetadata(metadata.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "on", loadedMetadata.Value)
+
+	t.Run("conflicts load nothing", func(t *testing.T) {
+		other := newTestService(t)
+		_, err := other.CreateMetadata(domain.CreateMetadataRequest{Path: "app/config", Value: "taken"})
+		require.NoError(t, err)
+
+		require.Error(t, other.LoadSeed(*seed))
+		_, err = other.GetProject(project.ID)
+		assert.True(t, domain.IsNotFound(err))
+	})
+}
+
+func TestService_LoadSeed_Invalid(t *testing.T) {
+	svc := newTestService(t)
+
+	tests := []struct {
+		name  string
+		seed  domain.Seed
+		field string
+	}{
+		{"unknown type", domain.Seed{Resources: []domain.SeedResource{{Type: "secret", Resource: json.RawMessage(`{}`)}}}, "resources[0].type"},
+		{"malformed resource", domain.Seed{Resources: []domain.SeedResource{{Type: "project", Resource: json.RawMessage(`[]`)}}}, "resources[0].resource"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.LoadSeed(tt.seed)
+			require.True(t, domain.IsInvalidInput(err), "got %v", err)
+			assert.Contains(t, err.Error(), tt.field)
+		})
+	}
+}
@@ -1,18 +1,172 @@
 package service
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"fmt"
+	"math/rand"
 	"regexp"
+	"time"
 
 	"github.com/hypertf/dirtcloud-server/domain"
 )
 
 // Service provides business logic for DirtCloud operations
 type Service struct {
-	projectRepo  ProjectRepository
-	instanceRepo InstanceRepository
-	metadataRepo MetadataRepository
+	projectRepo      ProjectRepository
+	instanceRepo     InstanceRepository
+	metadataRepo     MetadataRepository
+	organizationRepo OrganizationRepository
+	folderRepo       FolderRepository
+	eventRepo        EventRepository
+	templateRepo     InstanceTemplateRepository
+	groupRepo        AutoscalingGroupRepository
+	iamRepo          IAMRepository
+	backupRepo       BackupRepository
+	usageRepo        UsageRepository
+	budgetRepo       BudgetRepository
+	secretRepo       SecretRepository
+	databaseRepo     DatabaseRepository
+	environmentRepo  EnvironmentRepository
+	aliasRepo        AliasRepository
+	topicRepo        TopicRepository
+	subscriptionRepo SubscriptionRepository
+	requestLogRepo   RequestLogRepository
+	integrityRepo    IntegrityRepository
+
+	// uow runs multi-step operations in one transaction; nil runs each
+	// repository call on its own
+	uow UnitOfWork
+
+	// traced rebuilds the repositories to record their statements; nil
+	// when they cannot be traced
+	traced func(trace *domain.StatementTrace) Repositories
+
+	// replica is the read replica of the repositories; nil when there is
+	// none
+	replica Replica
+
+	config Config
+
+	// actor is recorded on the events this service records
+	actor string
+
+	// reason is recorded as the cause of instance status changes
+	reason string
+
+	// hub notifies event stream subscribers; it is shared by every copy of
+	// the service
+	hub *eventHub
+
+	// outages tracks the zones that are down; it is shared by every copy of
+	// the service
+	outages *zoneOutages
+
+	// capacity holds the capacity pools of the zones; it is shared by every
+	// copy of the service
+	capacity *capacityPools
+
+	// preemptions tracks the spot instances due for preemption; it is shared
+	// by every copy of the service
+	preemptions *preemptions
+
+	// health holds the state of the simulated health probes; it is shared
+	// by every copy of the service
+	health *healthProbes
+}
+
+// Config holds service behavior settings. The zero value models the strictest
+// cloud behavior.
+type Config struct {
+	// AllowOnlineResize lets running instances be resized; by default an
+	// instance must be stopped first
+	AllowOnlineResize bool
+
+	// AllowImageUpdate lets an instance's image be changed in place; by
+	// default changing it fails with IMMUTABLE_FIELD, as on clouds where
+	// the boot image forces a new instance
+	AllowImageUpdate bool
+
+	// AllowDuplicateInstanceNames lets several instances in a project share
+	// a name. The database must be opened with the same setting.
+	AllowDuplicateInstanceNames bool
+
+	// IDs generates resource IDs; nil generates random hex IDs
+	IDs *IDGenerator
+
+	// Prices prices simulated usage; nil uses DefaultPriceSheet
+	Prices *domain.PriceSheet
+
+	// DatabaseProvisioningDelay is how long a new or resized database takes
+	// to become available. Even with no delay a database is only available
+	// once provisioning completes, never in the response creating it.
+	DatabaseProvisioningDelay time.Duration
+
+	// DeletionDelay is how long a deleted instance stays in the deleting
+	// status before it is removed; 0 removes instances immediately
+	DeletionDelay time.Duration
+
+	// Zones are the availability zones instances can be placed in; empty
+	// uses domain.DefaultZones
+	Zones []string
+
+	// ZoneCapacity maps zones to their initial capacity pools; zones left
+	// out have unlimited capacity
+	ZoneCapacity map[string]domain.ZoneCapacity
+
+	// SpotPreemptionRate is the probability, each time the preemptor runs,
+	// that a running spot instance is warned of its preemption; 0 never
+	// preempts. SpotPreemptionNotice is how long after the warning the
+	// instance is preempted.
+	SpotPreemptionRate   float64
+	SpotPreemptionNotice time.Duration
+
+	// ProbeFailureRate is the probability that a simulated health probe of
+	// an instance fails, unless overridden for the instance. An instance
+	// failing UnhealthyThreshold probes in a row, 3 when 0, is unhealthy.
+	ProbeFailureRate   float64
+	UnhealthyThreshold int
+
+	// Rand draws the simulated spot preemptions and probe failures, so a
+	// seeded one makes them reproducible. It must be safe for concurrent
+	// use; nil uses the global source of math/rand.
+	Rand *rand.Rand
+
+	// MetadataLimits caps the metadata store. Unlike the other settings its
+	// zero value is the loosest: every limit left at 0 is unlimited.
+	MetadataLimits domain.MetadataLimits
+}
+
+// Repositories bundles the data stores the service depends on
+type Repositories struct {
+	Projects      ProjectRepository
+	Instances     InstanceRepository
+	Metadata      MetadataRepository
+	Organizations OrganizationRepository
+	Folders       FolderRepository
+	Events        EventRepository
+	Templates     InstanceTemplateRepository
+	Groups        AutoscalingGroupRepository
+	IAM           IAMRepository
+	Backups       BackupRepository
+	Usage         UsageRepository
+	Budgets       BudgetRepository
+	Secrets       SecretRepository
+	Databases     DatabaseRepository
+	Environments  EnvironmentRepository
+	Aliases       AliasRepository
+	Topics        TopicRepository
+	Subscriptions SubscriptionRepository
+	RequestLog    RequestLogRepository
+	Integrity     IntegrityRepository
+
+	// UnitOfWork, when set, makes multi-step operations atomic
+	UnitOfWork UnitOfWork
+
+	// Traced, when set, returns these repositories recording the SQL
+	// statements they run to a trace
+	Traced func(trace *domain.StatementTrace) Repositories
+
+	// Replica, when set, is a lagging read replica of these repositories
+	Replica Replica
 }
 
 // ProjectRepository defines the interface for project data operations
@@ -21,7 +175,9 @@ type ProjectRepository interface {
 	GetByID(id string) (*domain.Project, error)
 	GetByName(name string) (*domain.Project, error)
 	List(opts domain.ProjectListOptions) ([]*domain.Project, error)
+	Stream(opts domain.ProjectListOptions, fn func(*domain.Project) error) error
 	Update(id string, req domain.UpdateProjectRequest) (*domain.Project, error)
+	Move(id string, organizationID string, folderID string) (*domain.Project, error)
 	Delete(id string) error
 }
 
@@ -30,112 +186,338 @@ type InstanceRepository interface {
 	Create(instance *domain.Instance) error
 	GetByID(id string) (*domain.Instance, error)
 	List(opts domain.InstanceListOptions) ([]*domain.Instance, error)
+	Stream(opts domain.InstanceListOptions, fn func(*domain.Instance) error) error
 	Update(id string, req domain.UpdateInstanceRequest) (*domain.Instance, error)
 	Delete(id string) error
+	ListExpired(now time.Time) ([]*domain.Instance, error)
+	MarkDeleting(id string, deleteAt time.Time) (*domain.Instance, error)
+	SetHealth(id string, health string) (*domain.Instance, error)
+	ListDeleted(now time.Time) ([]*domain.Instance, error)
 }
 
 // MetadataRepository defines the interface for metadata data operations
 type MetadataRepository interface {
-	Create(req domain.CreateMetadataRequest) (*domain.Metadata, error)
+	Create(id string, req domain.CreateMetadataRequest) (*domain.Metadata, error)
 	GetByID(id string) (*domain.Metadata, error)
+	GetByPath(path string) (*domain.Metadata, error)
 	Update(id string, req domain.UpdateMetadataRequest) (*domain.Metadata, error)
 	List(opts domain.MetadataListOptions) ([]*domain.Metadata, error)
+	Stream(opts domain.MetadataListOptions, fn func(*domain.Metadata) error) error
+	Delete(id string) error
+
+	// Usage counts the entries and their bytes; it leaves Limits unset
+	Usage() (*domain.MetadataUsage, error)
+}
+
+// OrganizationRepository defines the interface for organization data operations
+type OrganizationRepository interface {
+	Create(org *domain.Organization) error
+	GetByID(id string) (*domain.Organization, error)
+	List(opts domain.OrganizationListOptions) ([]*domain.Organization, error)
+	Update(id string, req domain.UpdateOrganizationRequest) (*domain.Organization, error)
+	Delete(id string) error
+}
+
+// FolderRepository defines the interface for folder data operations
+type FolderRepository interface {
+	Create(folder *domain.Folder) error
+	GetByID(id string) (*domain.Folder, error)
+	List(opts domain.FolderListOptions) ([]*domain.Folder, error)
+	Update(id string, req domain.UpdateFolderRequest) (*domain.Folder, error)
+	Move(id string, parentID string) (*domain.Folder, error)
+	Delete(id string) error
+}
+
+// InstanceTemplateRepository defines the interface for instance template data operations
+type InstanceTemplateRepository interface {
+	Create(tmpl *domain.InstanceTemplate) error
+	GetByID(id string) (*domain.InstanceTemplate, error)
+	List(opts domain.InstanceTemplateListOptions) ([]*domain.InstanceTemplate, error)
+	Update(id string, req domain.UpdateInstanceTemplateRequest) (*domain.InstanceTemplate, error)
+	Delete(id string) error
+}
+
+// AutoscalingGroupRepository defines the interface for autoscaling group data operations
+type AutoscalingGroupRepository interface {
+	Create(group *domain.AutoscalingGroup) error
+	GetByID(id string) (*domain.AutoscalingGroup, error)
+	List(opts domain.AutoscalingGroupListOptions) ([]*domain.AutoscalingGroup, error)
+	Update(id string, req domain.UpdateAutoscalingGroupRequest) (*domain.AutoscalingGroup, error)
+	Delete(id string) error
+}
+
+// IAMRepository defines the interface for project IAM binding data operations
+type IAMRepository interface {
+	GetBindings(projectID string) ([]domain.IAMBinding, error)
+	SetBindings(projectID string, bindings []domain.IAMBinding) error
+	ListRoles(projectID, member string) ([]string, error)
+	HasMember(member string) (bool, error)
+}
+
+// BackupRepository defines the interface for database snapshot operations
+type BackupRepository interface {
+	Create() (*domain.Backup, error)
+	List() ([]*domain.Backup, error)
+	Restore(name string) error
+	Reset() error
+	Delete(name string) error
+}
+
+// UsageRepository defines the interface for simulated usage data operations
+type UsageRepository interface {
+	Add(usage *domain.Usage) error
+	Get(projectID, period string) (*domain.Usage, error)
+	List(period string) ([]*domain.Usage, error)
+}
+
+// BudgetRepository defines the interface for budget data operations
+type BudgetRepository interface {
+	Create(budget *domain.Budget) error
+	GetByID(id string) (*domain.Budget, error)
+	List(opts domain.BudgetListOptions) ([]*domain.Budget, error)
+	Update(id string, req domain.UpdateBudgetRequest) (*domain.Budget, error)
+	SetState(id string, state domain.BudgetState) error
+	Delete(id string) error
+}
+
+// SecretRepository defines the interface for secret and secret version data
+// operations
+type SecretRepository interface {
+	Create(secret *domain.Secret) error
+	GetByID(id string) (*domain.Secret, error)
+	List(opts domain.SecretListOptions) ([]*domain.Secret, error)
+	ListDueForRotation(now time.Time) ([]*domain.Secret, error)
+	Update(id string, req domain.UpdateSecretRequest, nextRotationAt *time.Time) (*domain.Secret, error)
+	SetNextRotation(id string, next *time.Time) error
+	Delete(id string) error
+
+	AddVersion(secretID, payload string) (*domain.SecretVersion, error)
+	GetVersion(secretID string, version int) (*domain.SecretVersion, error)
+	ListVersions(secretID string) ([]*domain.SecretVersion, error)
+	GetPayload(secretID string, version int) (string, error)
+	DestroyVersion(secretID string, version int) (*domain.SecretVersion, error)
+}
+
+// DatabaseRepository defines the interface for managed database data operations
+type DatabaseRepository interface {
+	Create(database *domain.Database) error
+	GetByID(id string) (*domain.Database, error)
+	List(opts domain.DatabaseListOptions) ([]*domain.Database, error)
+	ListReady(now time.Time) ([]*domain.Database, error)
+	Update(id string, req domain.UpdateDatabaseRequest) (*domain.Database, error)
+	SetStatus(id, status string, readyAt *time.Time) error
 	Delete(id string) error
 }
 
+// EnvironmentRepository defines the interface for environment data operations
+type EnvironmentRepository interface {
+	Create(env *domain.Environment) error
+	GetByID(id string) (*domain.Environment, error)
+	List(opts domain.EnvironmentListOptions) ([]*domain.Environment, error)
+	Update(id string, req domain.UpdateEnvironmentRequest) (*domain.Environment, error)
+	EnvironmentOf(ref domain.ResourceRef) (string, error)
+	AddResource(id string, ref domain.ResourceRef) error
+	RemoveResource(id string, ref domain.ResourceRef) error
+	Delete(id string) error
+}
+
+// AliasRepository defines the interface for alias data operations
+type AliasRepository interface {
+	Create(alias *domain.Alias) error
+	Get(externalID string) (*domain.Alias, error)
+	List(opts domain.AliasListOptions) ([]*domain.Alias, error)
+	Delete(externalID string) error
+}
+
+// TopicRepository defines the interface for pub/sub topic data operations
+type TopicRepository interface {
+	Create(topic *domain.Topic) error
+	GetByID(id string) (*domain.Topic, error)
+	List(opts domain.TopicListOptions) ([]*domain.Topic, error)
+	Delete(id string) error
+	Publish(topicID string, messages []*domain.Message) error
+}
+
+// SubscriptionRepository defines the interface for pub/sub subscription and
+// message data operations
+type SubscriptionRepository interface {
+	Create(subscription *domain.Subscription) error
+	GetByID(id string) (*domain.Subscription, error)
+	List(opts domain.SubscriptionListOptions) ([]*domain.Subscription, error)
+	Update(id string, req domain.UpdateSubscriptionRequest) (*domain.Subscription, error)
+	Delete(id string) error
+	Pull(subscriptionID string, max int, now, leaseEnd time.Time) ([]domain.ReceivedMessage, error)
+	Acknowledge(subscriptionID string, ackIDs []string) (int, error)
+}
+
+// RequestLogRepository defines the interface for request log data operations
+type RequestLogRepository interface {
+	Append(entry *domain.RequestLogEntry, keep int) error
+	List(opts domain.RequestLogListOptions) ([]*domain.RequestLogEntry, error)
+}
+
+// IntegrityRepository finds, and on repair fixes, rows referring to missing
+// rows, and keeps quarantined resources
+type IntegrityRepository interface {
+	CheckReferences(repair bool) ([]domain.IntegrityIssue, error)
+	Quarantine(q *domain.QuarantinedResource) error
+	ListQuarantined() ([]*domain.QuarantinedResource, error)
+}
+
+// EventRepository defines the interface for event data operations
+type EventRepository interface {
+	Create(event *domain.Event) error
+	List(opts domain.EventListOptions) ([]*domain.Event, error)
+}
+
 // NewService creates a new service instance
-func NewService(projectRepo ProjectRepository, instanceRepo InstanceRepository, metadataRepo MetadataRepository) *Service {
-	return &Service{
-		projectRepo:  projectRepo,
-		instanceRepo: instanceRepo,
-		metadataRepo: metadataRepo,
-	}
+func NewService(repos Repositories, config Config) *Service {
+	s := &Service{config: config, hub: newEventHub(), outages: newZoneOutages(), capacity: newCapacityPools(config.ZoneCapacity), preemptions: newPreemptions(), health: newHealthProbes()}
+	s.setRepositories(repos)
+	return s
 }
 
-// generateID generates a random hex ID
-func generateID() (string, error) {
-	bytes := make([]byte, 16)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
+// setRepositories points the service at a set of repositories
+func (s *Service) setRepositories(repos Repositories) {
+	s.projectRepo = repos.Projects
+	s.instanceRepo = repos.Instances
+	s.metadataRepo = repos.Metadata
+	s.organizationRepo = repos.Organizations
+	s.folderRepo = repos.Folders
+	s.eventRepo = repos.Events
+	s.templateRepo = repos.Templates
+	s.groupRepo = repos.Groups
+	s.iamRepo = repos.IAM
+	s.backupRepo = repos.Backups
+	s.usageRepo = repos.Usage
+	s.budgetRepo = repos.Budgets
+	s.secretRepo = repos.Secrets
+	s.databaseRepo = repos.Databases
+	s.environmentRepo = repos.Environments
+	s.aliasRepo = repos.Aliases
+	s.topicRepo = repos.Topics
+	s.subscriptionRepo = repos.Subscriptions
+	s.requestLogRepo = repos.RequestLog
+	s.integrityRepo = repos.Integrity
+	s.uow = repos.UnitOfWork
+	s.traced = repos.Traced
+	if repos.Replica != nil {
+		s.replica = repos.Replica
 	}
-	return hex.EncodeToString(bytes), nil
 }
 
-// validateProjectName validates a project name
-func validateProjectName(name string) error {
-	if name == "" {
-		return domain.InvalidInputError("project name cannot be empty", nil)
+// WithTrace returns a service that records the SQL statements it runs to
+// trace. A service whose repositories cannot be traced records nothing.
+func (s *Service) WithTrace(trace *domain.StatementTrace) *Service {
+	if s.traced == nil {
+		return s
 	}
-	if len(name) > 255 {
-		return domain.InvalidInputError("project name too long", map[string]interface{}{
-			"max_length": 255,
-			"actual":     len(name),
-		})
-	}
-	// Simple alphanumeric + dash/underscore validation
-	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(name) {
-		return domain.InvalidInputError("project name can only contain alphanumeric characters, dashes, and underscores", nil)
+	c := *s
+	c.setRepositories(s.traced(trace))
+	return &c
+}
+
+// ids returns the service's ID generator
+func (s *Service) ids() *IDGenerator {
+	if s.config.IDs == nil {
+		return defaultIDs
 	}
-	return nil
+	return s.config.IDs
+}
+
+// newID generates the ID of a new resource of kind
+func (s *Service) newID(kind string) (string, error) {
+	return s.ids().New(kind)
 }
 
-// validateInstanceName validates an instance name
-func validateInstanceName(name string) error {
+// namePattern restricts resource names to alphanumerics, dashes and underscores
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// validateName validates a resource name, recording violations against field
+func validateName(v *domain.FieldViolations, field string, name string) {
 	if name == "" {
-		return domain.InvalidInputError("instance name cannot be empty", nil)
+		v.Add(field, "cannot be empty")
+		return
 	}
 	if len(name) > 255 {
-		return domain.InvalidInputError("instance name too long", map[string]interface{}{
-			"max_length": 255,
-			"actual":     len(name),
-		})
+		v.Add(field, fmt.Sprintf("must be at most 255 characters (got %d)", len(name)))
 	}
-	// Simple alphanumeric + dash/underscore validation
-	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(name) {
-		return domain.InvalidInputError("instance name can only contain alphanumeric characters, dashes, and underscores", nil)
+	if !namePattern.MatchString(name) {
+		v.Add(field, "can only contain alphanumeric characters, dashes, and underscores")
+	}
+}
+
+// labelKeyPattern restricts label keys to lowercase identifiers
+var labelKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+
+// validateLabels validates label keys and values
+func validateLabels(v *domain.FieldViolations, labels map[string]string) {
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			v.Add("labels."+key, "key must start with a lowercase letter and contain only lowercase letters, digits, dashes, and underscores")
+		}
+		if len(value) > 63 {
+			v.Add("labels."+key, fmt.Sprintf("value must be at most 63 characters (got %d)", len(value)))
+		}
 	}
-	return nil
 }
 
 // validateInstanceSpecs validates instance specifications
-func validateInstanceSpecs(cpu int, memoryMB int, image string) error {
+func validateInstanceSpecs(v *domain.FieldViolations, cpu int, memoryMB int, image string) {
 	if cpu <= 0 {
-		return domain.InvalidInputError("CPU must be positive", map[string]interface{}{"cpu": cpu})
-	}
-	if cpu > 64 {
-		return domain.InvalidInputError("CPU too high", map[string]interface{}{
-			"max_cpu": 64,
-			"actual":  cpu,
-		})
+		v.Add("cpu", "must be positive")
+	} else if cpu > 64 {
+		v.Add("cpu", fmt.Sprintf("must be at most 64 (got %d)", cpu))
 	}
 	if memoryMB <= 0 {
-		return domain.InvalidInputError("memory must be positive", map[string]interface{}{"memory_mb": memoryMB})
-	}
-	if memoryMB > 512*1024 { // 512GB
-		return domain.InvalidInputError("memory too high", map[string]interface{}{
-			"max_memory_mb": 512 * 1024,
-			"actual":        memoryMB,
-		})
+		v.Add("memory_mb", "must be positive")
+	} else if memoryMB > 512*1024 { // 512GB
+		v.Add("memory_mb", fmt.Sprintf("must be at most %d (got %d)", 512*1024, memoryMB))
 	}
 	if image == "" {
-		return domain.InvalidInputError("image cannot be empty", nil)
-	}
-	if len(image) > 255 {
-		return domain.InvalidInputError("image name too long", map[string]interface{}{
-			"max_length": 255,
-			"actual":     len(image),
-		})
+		v.Add("image", "cannot be empty")
+	} else if len(image) > 255 {
+		v.Add("image", fmt.Sprintf("must be at most 255 characters (got %d)", len(image)))
 	}
-	return nil
 }
 
 // validateInstanceStatus validates instance status
-func validateInstanceStatus(status string) error {
+func validateInstanceStatus(v *domain.FieldViolations, status string) {
 	if status != domain.StatusRunning && status != domain.StatusStopped {
-		return domain.InvalidInputError("invalid status", map[string]interface{}{
-			"valid_statuses": []string{domain.StatusRunning, domain.StatusStopped},
-			"actual":         status,
-		})
+		v.Add("status", fmt.Sprintf("must be one of %s, %s (got %q)", domain.StatusRunning, domain.StatusStopped, status))
+	}
+}
+
+// validateInstanceClass validates instance class
+func validateInstanceClass(v *domain.FieldViolations, class string) {
+	if class != domain.InstanceClassStandard && class != domain.InstanceClassSpot {
+		v.Add("class", fmt.Sprintf("must be one of %s, %s (got %q)", domain.InstanceClassStandard, domain.InstanceClassSpot, class))
+	}
+}
+
+// validateExpiry validates an instance TTL or absolute expiry and returns the
+// resulting expiry time in UTC, or nil if the instance does not expire
+func validateExpiry(v *domain.FieldViolations, ttlSeconds int, expiresAt *time.Time, now time.Time) *time.Time {
+	if ttlSeconds < 0 {
+		v.Add("ttl_seconds", fmt.Sprintf("must not be negative (got %d)", ttlSeconds))
+		return nil
+	}
+	if ttlSeconds > 0 && expiresAt != nil {
+		v.Add("expires_at", "cannot be combined with ttl_seconds")
+		return nil
+	}
+
+	if ttlSeconds > 0 {
+		t := now.Add(time.Duration(ttlSeconds) * time.Second).UTC()
+		return &t
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			v.Add("expires_at", "must be in the future")
+			return nil
+		}
+		t := expiresAt.UTC()
+		return &t
 	}
 	return nil
 }
@@ -144,25 +526,41 @@ func validateInstanceStatus(status string) error {
 
 // CreateProject creates a new project
 func (s *Service) CreateProject(req domain.CreateProjectRequest) (*domain.Project, error) {
-	if err := validateProjectName(req.Name); err != nil {
-		return nil, err
-	}
+	return inTx(s, func(tx *Service) (*domain.Project, error) {
+		var v domain.FieldViolations
+		validateName(&v, "name", req.Name)
+		validateLabels(&v, req.Labels)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
 
-	id, err := generateID()
-	if err != nil {
-		return nil, domain.InternalError("failed to generate ID")
-	}
+		orgID, folderID, err := tx.resolveParent(req.OrganizationID, req.FolderID, false)
+		if err != nil {
+			return nil, err
+		}
 
-	project := &domain.Project{
-		ID:   id,
-		Name: req.Name,
-	}
+		id, err := tx.newID(kindProject)
+		if err != nil {
+			return nil, domain.InternalError("failed to generate ID")
+		}
 
-	if err := s.projectRepo.Create(project); err != nil {
-		return nil, err
-	}
+		project := &domain.Project{
+			ID:             id,
+			Name:           req.Name,
+			OrganizationID: orgID,
+			FolderID:       folderID,
+			Labels:         req.Labels,
+			ChaosProfile:   req.ChaosProfile,
 
-	return project, nil
+			DeletionProtection: req.DeletionProtection,
+		}
+
+		if err := tx.projectRepo.Create(project); err != nil {
+			return nil, err
+		}
+
+		return project, nil
+	})
 }
 
 // GetProject retrieves a project by ID
@@ -175,74 +573,198 @@ func (s *Service) ListProjects(opts domain.ProjectListOptions) ([]*domain.Projec
 	return s.projectRepo.List(opts)
 }
 
+// StreamProjects calls fn with each project ListProjects would return as it
+// is read from storage, stopping at the first error fn returns
+func (s *Service) StreamProjects(opts domain.ProjectListOptions, fn func(*domain.Project) error) error {
+	return s.projectRepo.Stream(opts, fn)
+}
+
 // UpdateProject updates an existing project
 func (s *Service) UpdateProject(id string, req domain.UpdateProjectRequest) (*domain.Project, error) {
-	if err := validateProjectName(req.Name); err != nil {
+	var v domain.FieldViolations
+	if req.Name != nil {
+		validateName(&v, "name", *req.Name)
+	}
+	validateLabels(&v, req.Labels)
+	if err := v.Err(); err != nil {
 		return nil, err
 	}
 
 	return s.projectRepo.Update(id, req)
 }
 
-// DeleteProject deletes a project
-func (s *Service) DeleteProject(id string) error {
-	return s.projectRepo.Delete(id)
-}
+// DeleteProject deletes a project. With opts.Cascade its autoscaling groups
+// and instances are deleted along with it in the same transaction; otherwise
+// a project that still has any fails with FAILED_PRECONDITION, as does a
+// project with deletion protection.
+func (s *Service) DeleteProject(id string, opts domain.DeleteProjectOptions) error {
+	return s.runInTx(func(tx *Service) error {
+		project, err := tx.projectRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if project.DeletionProtection {
+			return domain.DeletionProtectedError("project", id)
+		}
 
-// Instance operations
+		if opts.Cascade {
+			if err := tx.deleteProjectChildren(id); err != nil {
+				return err
+			}
+		}
+		return tx.projectRepo.Delete(id)
+	})
+}
 
-// CreateInstance creates a new instance
-func (s *Service) CreateInstance(req domain.CreateInstanceRequest) (*domain.Instance, error) {
-	if err := validateInstanceName(req.Name); err != nil {
-		return nil, err
+// deleteProjectChildren deletes a project's autoscaling groups, which takes
+// their members with them, and then its remaining instances. Nothing is
+// deleted if any of the instances has deletion protection.
+func (s *Service) deleteProjectChildren(projectID string) error {
+	instances, err := s.instanceRepo.List(domain.InstanceListOptions{ProjectID: projectID})
+	if err != nil {
+		return err
 	}
-
-	if err := validateInstanceSpecs(req.CPU, req.MemoryMB, req.Image); err != nil {
-		return nil, err
+	for _, instance := range instances {
+		if instance.DeletionProtection {
+			return domain.DeletionProtectedError("instance", instance.ID)
+		}
 	}
 
-	status := req.Status
-	if status == "" {
-		status = domain.StatusRunning
+	groups, err := s.groupRepo.List(domain.AutoscalingGroupListOptions{ProjectID: projectID})
+	if err != nil {
+		return err
 	}
-	if err := validateInstanceStatus(status); err != nil {
-		return nil, err
+	for _, group := range groups {
+		if err := s.groupRepo.Delete(group.ID); err != nil {
+			return err
+		}
 	}
 
-	// Verify project exists
-	_, err := s.projectRepo.GetByID(req.ProjectID)
-	if err != nil {
-		if domain.IsNotFound(err) {
-			return nil, domain.ForeignKeyViolationError("project", "id", req.ProjectID)
+	for _, instance := range instances {
+		if instance.AutoscalingGroupID == "" {
+			if err := s.instanceRepo.Delete(instance.ID); err != nil {
+				return err
+			}
 		}
-		return nil, err
+		s.recordStatusChange(instance, instance.Status, domain.StatusTerminated)
 	}
 
-	id, err := generateID()
-	if err != nil {
-		return nil, domain.InternalError("failed to generate ID")
-	}
+	return nil
+}
 
-	instance := &domain.Instance{
-		ID:        id,
-		ProjectID: req.ProjectID,
-		Name:      req.Name,
-		CPU:       req.CPU,
-		MemoryMB:  req.MemoryMB,
-		Image:     req.Image,
-		Status:    status,
-	}
+// Instance operations
 
-	if err := s.instanceRepo.Create(instance); err != nil {
-		return nil, err
-	}
+// CreateInstance creates a new instance
+func (s *Service) CreateInstance(req domain.CreateInstanceRequest) (*domain.Instance, error) {
+	return s.createInstance(req, "")
+}
 
-	return instance, nil
+// createInstance creates an instance, optionally as a member of an autoscaling group
+func (s *Service) createInstance(req domain.CreateInstanceRequest, groupID string) (*domain.Instance, error) {
+	return inTx(s, func(tx *Service) (*domain.Instance, error) {
+		status := req.Status
+		if status == "" {
+			status = domain.StatusRunning
+		}
+		class := req.Class
+		if class == "" {
+			class = domain.InstanceClassStandard
+		}
+
+		var v domain.FieldViolations
+		if req.ProjectID == "" {
+			v.Add("project_id", "cannot be empty")
+		}
+		validateName(&v, "name", req.Name)
+		validateInstanceSpecs(&v, req.CPU, req.MemoryMB, req.Image)
+		validateInstanceStatus(&v, status)
+		validateLabels(&v, req.Labels)
+		expiresAt := validateExpiry(&v, req.TTLSeconds, req.ExpiresAt, time.Now())
+		tx.validateZone(&v, req.Zone)
+		validateInstanceClass(&v, class)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+
+		// Verify project exists
+		project, err := tx.projectRepo.GetByID(req.ProjectID)
+		if err != nil {
+			if domain.IsNotFound(err) {
+				return nil, domain.ForeignKeyViolationError("project", "id", req.ProjectID)
+			}
+			return nil, err
+		}
+
+		zone := req.Zone
+		if zone == "" {
+			if zone, err = tx.placeInstance(req.CPU, req.MemoryMB); err != nil {
+				return nil, err
+			}
+		}
+		if tx.outages.isDown(zone) {
+			return nil, domain.ZoneUnavailableError("instance", zone)
+		}
+		if err := tx.checkZoneCapacity(zone, req.CPU, req.MemoryMB, ""); err != nil {
+			return nil, err
+		}
+
+		if err := tx.checkInstanceName(req.ProjectID, req.Name, ""); err != nil {
+			return nil, err
+		}
+
+		if err := tx.checkInstanceQuota(project, req.CPU, req.MemoryMB, ""); err != nil {
+			return nil, err
+		}
+
+		id, err := tx.newID(kindInstance)
+		if err != nil {
+			return nil, domain.InternalError("failed to generate ID")
+		}
+
+		instance := &domain.Instance{
+			ID:        id,
+			ProjectID: req.ProjectID,
+			Name:      req.Name,
+			CPU:       req.CPU,
+			MemoryMB:  req.MemoryMB,
+			Image:     req.Image,
+			Status:    status,
+			Labels:    req.Labels,
+			Zone:      zone,
+			Class:     class,
+			Health:    domain.HealthHealthy,
+			ExpiresAt: expiresAt,
+
+			AutoscalingGroupID: groupID,
+			DeletionProtection: req.DeletionProtection,
+			AutoRepair:         req.AutoRepair,
+		}
+
+		if err := tx.instanceRepo.Create(instance); err != nil {
+			return nil, err
+		}
+		tx.recordStatusChange(instance, "", instance.Status)
+
+		return instance, nil
+	})
 }
 
-// GetInstance retrieves an instance by ID
+// GetInstance retrieves an instance by ID, first removing it if it is
+// deleting and due for removal
 func (s *Service) GetInstance(id string) (*domain.Instance, error) {
-	return s.instanceRepo.GetByID(id)
+	instance, err := s.instanceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.DeleteAt != nil && !time.Now().Before(*instance.DeleteAt) {
+		if err := s.WithActor(domain.ActorReaper).removeDeleted(instance); err != nil {
+			return nil, err
+		}
+		return nil, domain.NotFoundError("instance", id)
+	}
+
+	return instance, nil
 }
 
 // ListInstances lists instances with optional filtering
@@ -250,63 +772,284 @@ func (s *Service) ListInstances(opts domain.InstanceListOptions) ([]*domain.Inst
 	return s.instanceRepo.List(opts)
 }
 
+// StreamInstances is ListInstances one instance at a time, for lists too
+// large to load at once
+func (s *Service) StreamInstances(opts domain.InstanceListOptions, fn func(*domain.Instance) error) error {
+	return s.instanceRepo.Stream(opts, fn)
+}
+
 // UpdateInstance updates an existing instance
 func (s *Service) UpdateInstance(id string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
-	if req.Name != nil {
-		if err := validateInstanceName(*req.Name); err != nil {
+	return inTx(s, func(tx *Service) (*domain.Instance, error) {
+		var v domain.FieldViolations
+
+		if req.Name != nil {
+			validateName(&v, "name", *req.Name)
+		}
+
+		if req.CPU != nil || req.MemoryMB != nil || req.Image != nil {
+			// Get current instance to validate complete specs
+			current, err := tx.instanceRepo.GetByID(id)
+			if err != nil {
+				return nil, err
+			}
+
+			cpu := current.CPU
+			memory := current.MemoryMB
+			image := current.Image
+
+			if req.CPU != nil {
+				cpu = *req.CPU
+			}
+			if req.MemoryMB != nil {
+				memory = *req.MemoryMB
+			}
+			if req.Image != nil {
+				image = *req.Image
+			}
+
+			validateInstanceSpecs(&v, cpu, memory, image)
+		}
+
+		if req.Status != nil {
+			validateInstanceStatus(&v, *req.Status)
+		}
+
+		validateLabels(&v, req.Labels)
+
+		if req.TTLSeconds != nil || req.ExpiresAt != nil {
+			ttl := 0
+			if req.TTLSeconds != nil {
+				ttl = *req.TTLSeconds
+			}
+			if req.TTLSeconds != nil && req.ExpiresAt != nil {
+				v.Add("expires_at", "cannot be combined with ttl_seconds")
+			} else if expiresAt := validateExpiry(&v, ttl, req.ExpiresAt, time.Now()); expiresAt != nil {
+				// The repository stores absolute expiries; a zero TTL clears it
+				req.ExpiresAt = expiresAt
+				req.TTLSeconds = nil
+			}
+		}
+
+		if err := v.Err(); err != nil {
 			return nil, err
 		}
-	}
 
-	if req.CPU != nil || req.MemoryMB != nil {
-		// Get current instance to validate complete specs
-		current, err := s.instanceRepo.GetByID(id)
+		current, err := tx.instanceRepo.GetByID(id)
 		if err != nil {
 			return nil, err
 		}
+		if err := checkNotDeleting(current); err != nil {
+			return nil, err
+		}
+		if err := tx.checkZoneUp(current); err != nil {
+			return nil, err
+		}
+		if req.Image != nil && *req.Image != current.Image && !tx.config.AllowImageUpdate {
+			return nil, domain.ImmutableFieldError("instance", id, "image")
+		}
+		if req.Zone != nil && *req.Zone != current.Zone {
+			return nil, domain.ImmutableFieldError("instance", id, "zone")
+		}
+		if req.Class != nil && *req.Class != current.Class {
+			return nil, domain.ImmutableFieldError("instance", id, "class")
+		}
 
-		cpu := current.CPU
-		memory := current.MemoryMB
-		image := current.Image
-
+		// Resizing through an update is held to the rules of ResizeInstance
+		cpu, memory := current.CPU, current.MemoryMB
 		if req.CPU != nil {
 			cpu = *req.CPU
 		}
 		if req.MemoryMB != nil {
 			memory = *req.MemoryMB
 		}
-		if req.Image != nil {
-			image = *req.Image
+		if cpu != current.CPU || memory != current.MemoryMB {
+			if err := tx.checkResize(current, cpu, memory); err != nil {
+				return nil, err
+			}
 		}
 
-		if err := validateInstanceSpecs(cpu, memory, image); err != nil {
+		if req.Name != nil {
+			if err := tx.checkInstanceName(current.ProjectID, *req.Name, id); err != nil {
+				return nil, err
+			}
+		}
+
+		instance, err := tx.instanceRepo.Update(id, req)
+		if err != nil {
 			return nil, err
 		}
+		tx.recordStatusChange(instance, current.Status, instance.Status)
+
+		return instance, nil
+	})
+}
+
+// checkInstanceName returns ALREADY_EXISTS if an instance other than exceptID
+// in the project already has name, unless duplicate names are allowed
+func (s *Service) checkInstanceName(projectID, name, exceptID string) error {
+	if s.config.AllowDuplicateInstanceNames {
+		return nil
+	}
+
+	existing, err := s.instanceRepo.List(domain.InstanceListOptions{ProjectID: projectID, Name: name})
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range existing {
+		if instance.ID != exceptID {
+			return domain.InstanceNameConflictError(projectID, name, instance.ID)
+		}
+	}
+
+	return nil
+}
+
+// checkNotDeleting returns FAILED_PRECONDITION for an instance that is being
+// deleted, which can no longer be changed
+func checkNotDeleting(instance *domain.Instance) error {
+	if instance.Status != domain.StatusDeleting {
+		return nil
 	}
+	return domain.FailedPreconditionError(fmt.Sprintf("instance %s is being deleted", instance.Name), map[string]interface{}{
+		"instance_id": instance.ID,
+		"status":      instance.Status,
+		"delete_at":   instance.DeleteAt,
+	})
+}
+
+// ResizeInstance changes the CPU and memory of an instance. Unless online
+// resize is enabled the instance must be stopped.
+func (s *Service) ResizeInstance(id string, req domain.ResizeInstanceRequest) (*domain.Instance, error) {
+	return inTx(s, func(tx *Service) (*domain.Instance, error) {
+		current, err := tx.instanceRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkNotDeleting(current); err != nil {
+			return nil, err
+		}
+		if err := tx.checkZoneUp(current); err != nil {
+			return nil, err
+		}
+
+		cpu, memory := current.CPU, current.MemoryMB
+		if req.CPU != nil {
+			cpu = *req.CPU
+		}
+		if req.MemoryMB != nil {
+			memory = *req.MemoryMB
+		}
+
+		var v domain.FieldViolations
+		if req.CPU == nil && req.MemoryMB == nil {
+			v.Add("cpu", "cpu or memory_mb is required")
+		}
+		validateInstanceSpecs(&v, cpu, memory, current.Image)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
 
-	if req.Status != nil {
-		if err := validateInstanceStatus(*req.Status); err != nil {
+		if err := tx.checkResize(current, cpu, memory); err != nil {
 			return nil, err
 		}
+
+		instance, err := tx.instanceRepo.Update(id, domain.UpdateInstanceRequest{CPU: &cpu, MemoryMB: &memory})
+		if err != nil {
+			return nil, err
+		}
+
+		tx.recordEvent(domain.EventInstanceResized, "instance", id, instance.ProjectID,
+			fmt.Sprintf("instance %s resized from %d CPU/%d MB to %d CPU/%d MB", instance.Name, current.CPU, current.MemoryMB, cpu, memory))
+
+		return instance, nil
+	})
+}
+
+// checkResize checks that an instance can be resized to cpu and memory: it
+// must be stopped unless online resizes are allowed, and the new size must
+// fit its project's quotas and its zone's capacity
+func (s *Service) checkResize(current *domain.Instance, cpu, memory int) error {
+	if current.Status != domain.StatusStopped && !s.config.AllowOnlineResize {
+		return domain.FailedPreconditionError("instance must be stopped to resize", map[string]interface{}{
+			"instance_id":     current.ID,
+			"status":          current.Status,
+			"required_status": domain.StatusStopped,
+		})
 	}
 
-	return s.instanceRepo.Update(id, req)
+	project, err := s.projectRepo.GetByID(current.ProjectID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkInstanceQuota(project, cpu, memory, current.ID); err != nil {
+		return err
+	}
+	return s.checkZoneCapacity(current.Zone, cpu, memory, current.ID)
 }
 
-// DeleteInstance deletes an instance
+// DeleteInstance deletes an instance unless it has deletion protection. With
+// a deletion delay the instance is only marked deleting, and stays readable
+// until the reaper removes it; deleting it again does not restart the delay.
 func (s *Service) DeleteInstance(id string) error {
-	return s.instanceRepo.Delete(id)
+	return s.runInTx(func(tx *Service) error {
+		instance, err := tx.instanceRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if instance.DeletionProtection {
+			return domain.DeletionProtectedError("instance", id)
+		}
+		if instance.Status == domain.StatusDeleting {
+			return nil
+		}
+		return tx.deleteInstance(instance)
+	})
+}
+
+// deleteInstance deletes an instance, or with a deletion delay marks it
+// deleting until the reaper removes it, recording its status change
+func (s *Service) deleteInstance(instance *domain.Instance) error {
+	if s.config.DeletionDelay > 0 {
+		deleting, err := s.instanceRepo.MarkDeleting(instance.ID, time.Now().Add(s.config.DeletionDelay).UTC())
+		if err != nil {
+			return err
+		}
+		s.recordStatusChange(deleting, instance.Status, deleting.Status)
+		return nil
+	}
+	if err := s.instanceRepo.Delete(instance.ID); err != nil {
+		return err
+	}
+	s.recordStatusChange(instance, instance.Status, domain.StatusTerminated)
+	return nil
 }
 
 // Metadata operations
 
 // CreateMetadata creates new metadata
 func (s *Service) CreateMetadata(req domain.CreateMetadataRequest) (*domain.Metadata, error) {
+	var v domain.FieldViolations
 	if req.Path == "" {
-		return nil, domain.InvalidInputError("metadata path cannot be empty", nil)
+		v.Add("path", "cannot be empty")
+	}
+	checkMetadataFlags(&v, "flags", req.Flags)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	id, err := s.newID(kindMetadata)
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
 	}
 
-	return s.metadataRepo.Create(req)
+	return inTx(s, func(tx *Service) (*domain.Metadata, error) {
+		if err := tx.checkMetadataLimits(req.Path, req.Value, nil); err != nil {
+			return nil, err
+		}
+		return tx.metadataRepo.Create(id, req)
+	})
 }
 
 // GetMetadata retrieves metadata by ID
@@ -323,8 +1066,33 @@ func (s *Service) UpdateMetadata(id string, req domain.UpdateMetadataRequest) (*
 	if id == "" {
 		return nil, domain.InvalidInputError("metadata ID cannot be empty", nil)
 	}
+	if req.Flags != nil {
+		var v domain.FieldViolations
+		checkMetadataFlags(&v, "flags", *req.Flags)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+	}
 
-	return s.metadataRepo.Update(id, req)
+	return inTx(s, func(tx *Service) (*domain.Metadata, error) {
+		if tx.config.MetadataLimits != (domain.MetadataLimits{}) {
+			existing, err := tx.metadataRepo.GetByID(id)
+			if err != nil {
+				return nil, err
+			}
+			path, value := existing.Path, existing.Value
+			if req.Path != nil {
+				path = *req.Path
+			}
+			if req.Value != nil {
+				value = *req.Value
+			}
+			if err := tx.checkMetadataLimits(path, value, existing); err != nil {
+				return nil, err
+			}
+		}
+		return tx.metadataRepo.Update(id, req)
+	})
 }
 
 // ListMetadata lists metadata with optional prefix filtering
@@ -332,6 +1100,12 @@ func (s *Service) ListMetadata(opts domain.MetadataListOptions) ([]*domain.Metad
 	return s.metadataRepo.List(opts)
 }
 
+// StreamMetadata calls fn with each metadata entry matching opts, in path
+// order, as it is read
+func (s *Service) StreamMetadata(opts domain.MetadataListOptions, fn func(*domain.Metadata) error) error {
+	return s.metadataRepo.Stream(opts, fn)
+}
+
 // DeleteMetadata deletes metadata by ID
 func (s *Service) DeleteMetadata(id string) error {
 	if id == "" {
@@ -339,4 +1113,4 @@ func (s *Service) DeleteMetadata(id string) error {
 	}
 
 	return s.metadataRepo.Delete(id)
-}
\ No newline at end of file
+}
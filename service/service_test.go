@@ -0,0 +1,248 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService creates a service backed by an in-memory SQLite database
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db, err := sqlite.NewDB(":memory:")
+	require.NoError(t, err, "Failed to create test database")
+	t.Cleanup(func() { db.Close() })
+
+	return NewService(NewSQLiteRepositories(db, t.TempDir()), Config{})
+}
+
+func TestValidateInstanceSpecs_AggregatesViolations(t *testing.T) {
+	var v domain.FieldViolations
+	validateName(&v, "name", "bad name!")
+	validateInstanceSpecs(&v, 0, 600*1024, "")
+	validateInstanceStatus(&v, "paused")
+
+	fields := make([]string, 0, len(v))
+	for _, violation := range v {
+		fields = append(fields, violation.Field)
+	}
+	assert.Equal(t, []string{"name", "cpu", "memory_mb", "image", "status"}, fields)
+}
+
+func TestValidateInstanceSpecs_Valid(t *testing.T) {
+	var v domain.FieldViolations
+	validateName(&v, "name", "web-1")
+	validateInstanceSpecs(&v, 2, 2048, "ubuntu:22.04")
+	validateInstanceStatus(&v, domain.StatusStopped)
+
+	assert.Empty(t, v)
+	assert.NoError(t, v.Err())
+}
+
+func TestService_DeleteProject(t *testing.T) {
+	tests := []struct {
+		name         string
+		withChildren bool
+		cascade      bool
+		expectErr    bool
+	}{
+		{name: "empty project", withChildren: false},
+		{name: "children without cascade", withChildren: true, expectErr: true},
+		{name: "children with cascade", withChildren: true, cascade: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t)
+
+			project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "doomed"})
+			require.NoError(t, err)
+
+			if tt.withChildren {
+				_, err = svc.CreateInstance(domain.CreateInstanceRequest{
+					ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+				})
+				require.NoError(t, err)
+
+				tmpl, err := svc.CreateInstanceTemplate(domain.CreateInstanceTemplateRequest{
+					Name: "worker", CPU: 1, MemoryMB: 512, Image: "alpine",
+				})
+				require.NoError(t, err)
+				_, err = svc.CreateAutoscalingGroup(domain.CreateAutoscalingGroupRequest{
+					ProjectID: project.ID, Name: "workers", TemplateID: tmpl.ID, MinSize: 1, MaxSize: 2,
+				})
+				require.NoError(t, err)
+				_, err = svc.ReconcileAutoscalingGroups()
+				require.NoError(t, err)
+			}
+
+			err = svc.DeleteProject(project.ID, domain.DeleteProjectOptions{Cascade: tt.cascade})
+			if tt.expectErr {
+				require.True(t, domain.IsFailedPrecondition(err), "got %v", err)
+				details := err.(*domain.DirtError).Details
+				assert.Equal(t, 2, details["instance_count"], "group members count as instances")
+				assert.Equal(t, 1, details["autoscaling_group_count"])
+
+				_, err = svc.GetProject(project.ID)
+				assert.NoError(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			_, err = svc.GetProject(project.ID)
+			assert.True(t, domain.IsNotFound(err))
+			instances, err := svc.ListInstances(domain.InstanceListOptions{ProjectID: project.ID})
+			require.NoError(t, err)
+			assert.Empty(t, instances)
+			groups, err := svc.ListAutoscalingGroups(domain.AutoscalingGroupListOptions{ProjectID: project.ID})
+			require.NoError(t, err)
+			assert.Empty(t, groups)
+		})
+	}
+
+	t.Run("missing project", func(t *testing.T) {
+		svc := newTestService(t)
+		err := svc.DeleteProject("missing", domain.DeleteProjectOptions{Cascade: true})
+		assert.True(t, domain.IsNotFound(err))
+	})
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestService_DeletionProtection(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "prod", DeletionProtection: true})
+	require.NoError(t, err)
+	assert.True(t, project.DeletionProtection)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "db", CPU: 1, MemoryMB: 512, Image: "postgres", DeletionProtection: true,
+	})
+	require.NoError(t, err)
+
+	err = svc.DeleteInstance(instance.ID)
+	require.True(t, domain.IsFailedPrecondition(err), "got %v", err)
+	err = svc.DeleteProject(project.ID, domain.DeleteProjectOptions{Cascade: true})
+	require.True(t, domain.IsFailedPrecondition(err), "got %v", err)
+	assert.Equal(t, "project", err.(*domain.DirtError).Details["resource"])
+
+	// Cascading cannot delete a protected instance either
+	_, err = svc.UpdateProject(project.ID, domain.UpdateProjectRequest{DeletionProtection: boolPtr(false)})
+	require.NoError(t, err)
+	err = svc.DeleteProject(project.ID, domain.DeleteProjectOptions{Cascade: true})
+	require.True(t, domain.IsFailedPrecondition(err), "got %v", err)
+	assert.Equal(t, instance.ID, err.(*domain.DirtError).Details["id"])
+	_, err = svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+
+	updated, err := svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{DeletionProtection: boolPtr(false)})
+	require.NoError(t, err)
+	assert.False(t, updated.DeletionProtection)
+	require.NoError(t, svc.DeleteProject(project.ID, domain.DeleteProjectOptions{Cascade: true}))
+}
+
+func TestService_ImmutableImage(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		image     string
+		immutable bool
+	}{
+		{name: "changed image", image: "debian", immutable: true},
+		{name: "unchanged image", image: "ubuntu"},
+		{name: "image updates allowed", config: Config{AllowImageUpdate: true}, image: "debian"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t)
+			svc.config.AllowImageUpdate = tt.config.AllowImageUpdate
+
+			project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "images"})
+			require.NoError(t, err)
+			instance, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusStopped})
+			require.NoError(t, err)
+
+			updated, err := svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Image: &tt.image, CPU: intPtr(2)})
+			if tt.immutable {
+				require.True(t, domain.IsImmutableField(err), "got %v", err)
+				assert.Equal(t, "image", err.(*domain.DirtError).Details["field"])
+
+				// The rejected update changes nothing
+				current, err := svc.GetInstance(instance.ID)
+				require.NoError(t, err)
+				assert.Equal(t, "ubuntu", current.Image)
+				assert.Equal(t, 1, current.CPU)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.image, updated.Image)
+			assert.Equal(t, 2, updated.CPU)
+		})
+	}
+}
+
+func TestService_InstanceNames(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "names"})
+	require.NoError(t, err)
+	other, err := svc.CreateProject(domain.CreateProjectRequest{Name: "other"})
+	require.NoError(t, err)
+
+	create := func(projectID, name string) (*domain.Instance, error) {
+		return svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: projectID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu",
+		})
+	}
+
+	web, err := create(project.ID, "web")
+	require.NoError(t, err)
+	db, err := create(project.ID, "db")
+	require.NoError(t, err)
+
+	_, err = create(project.ID, "web")
+	require.True(t, domain.IsAlreadyExists(err), "got %v", err)
+	details := err.(*domain.DirtError).Details
+	assert.Equal(t, project.ID, details["project_id"])
+	assert.Equal(t, web.ID, details["existing_id"])
+
+	// Names only need to be unique within a project
+	_, err = create(other.ID, "web")
+	assert.NoError(t, err)
+
+	name := "web"
+	_, err = svc.UpdateInstance(db.ID, domain.UpdateInstanceRequest{Name: &name})
+	assert.True(t, domain.IsAlreadyExists(err), "renaming onto a taken name, got %v", err)
+	_, err = svc.UpdateInstance(web.ID, domain.UpdateInstanceRequest{Name: &name})
+	assert.NoError(t, err, "keeping the current name")
+
+	_, err = svc.CloneInstance(web.ID, domain.CloneInstanceRequest{Name: "db"})
+	assert.True(t, domain.IsAlreadyExists(err), "got %v", err)
+}
+
+func TestService_AllowDuplicateInstanceNames(t *testing.T) {
+	db, err := sqlite.NewDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.SetUniqueInstanceNames(false))
+
+	svc := NewService(NewSQLiteRepositories(db, t.TempDir()), Config{AllowDuplicateInstanceNames: true})
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "names"})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+		})
+		require.NoError(t, err)
+	}
+
+	instances, err := svc.ListInstances(domain.InstanceListOptions{ProjectID: project.ID, Name: "web"})
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+)
+
+// NewSQLiteRepositories creates the repositories of a service on a SQLite
+// database, keeping backups in backupDir. Units of work and traces rebuild
+// them on each transaction or trace.
+func NewSQLiteRepositories(db *sqlite.DB, backupDir string) Repositories {
+	return Repositories{
+		Projects:      sqlite.NewProjectRepository(db),
+		Instances:     sqlite.NewInstanceRepository(db),
+		Metadata:      sqlite.NewMetadataRepository(db),
+		Organizations: sqlite.NewOrganizationRepository(db),
+		Folders:       sqlite.NewFolderRepository(db),
+		Events:        sqlite.NewEventRepository(db),
+		Templates:     sqlite.NewInstanceTemplateRepository(db),
+		Groups:        sqlite.NewAutoscalingGroupRepository(db),
+		IAM:           sqlite.NewIAMRepository(db),
+		Backups:       sqlite.NewBackupRepository(db, backupDir),
+		Usage:         sqlite.NewUsageRepository(db),
+		Budgets:       sqlite.NewBudgetRepository(db),
+		Secrets:       sqlite.NewSecretRepository(db),
+		Databases:     sqlite.NewDatabaseRepository(db),
+		Environments:  sqlite.NewEnvironmentRepository(db),
+		Aliases:       sqlite.NewAliasRepository(db),
+		Topics:        sqlite.NewTopicRepository(db),
+		Subscriptions: sqlite.NewSubscriptionRepository(db),
+		RequestLog:    sqlite.NewRequestLogRepository(db),
+		Integrity:     sqlite.NewIntegrityRepository(db),
+		UnitOfWork: sqlite.NewUnitOfWork(db, func(tx *sqlite.DB) Repositories {
+			return NewSQLiteRepositories(tx, backupDir)
+		}),
+		Traced: func(trace *domain.StatementTrace) Repositories {
+			return NewSQLiteRepositories(db.WithTrace(trace), backupDir)
+		},
+	}
+}
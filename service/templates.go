@@ -0,0 +1,165 @@
+package service
+
+import (
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Instance template operations
+
+// CreateInstanceTemplate creates a new instance template
+func (s *Service) CreateInstanceTemplate(req domain.CreateInstanceTemplateRequest) (*domain.InstanceTemplate, error) {
+	var v domain.FieldViolations
+	validateName(&v, "name", req.Name)
+	validateInstanceSpecs(&v, req.CPU, req.MemoryMB, req.Image)
+	validateLabels(&v, req.Labels)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	id, err := s.newID(kindTemplate)
+	if err != nil {
+		return nil, domain.InternalError("failed to generate ID")
+	}
+
+	tmpl := &domain.InstanceTemplate{
+		ID:       id,
+		Name:     req.Name,
+		CPU:      req.CPU,
+		MemoryMB: req.MemoryMB,
+		Image:    req.Image,
+		Labels:   req.Labels,
+	}
+
+	if err := s.templateRepo.Create(tmpl); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// GetInstanceTemplate retrieves an instance template by ID
+func (s *Service) GetInstanceTemplate(id string) (*domain.InstanceTemplate, error) {
+	return s.templateRepo.GetByID(id)
+}
+
+// ListInstanceTemplates lists instance templates with optional filtering
+func (s *Service) ListInstanceTemplates(opts domain.InstanceTemplateListOptions) ([]*domain.InstanceTemplate, error) {
+	return s.templateRepo.List(opts)
+}
+
+// UpdateInstanceTemplate updates an existing instance template. Instances
+// already created from the template are not affected.
+func (s *Service) UpdateInstanceTemplate(id string, req domain.UpdateInstanceTemplateRequest) (*domain.InstanceTemplate, error) {
+	return inTx(s, func(tx *Service) (*domain.InstanceTemplate, error) {
+		var v domain.FieldViolations
+		if req.Name != nil {
+			validateName(&v, "name", *req.Name)
+		}
+
+		if req.CPU != nil || req.MemoryMB != nil || req.Image != nil {
+			current, err := tx.templateRepo.GetByID(id)
+			if err != nil {
+				return nil, err
+			}
+
+			cpu, memory, image := current.CPU, current.MemoryMB, current.Image
+			if req.CPU != nil {
+				cpu = *req.CPU
+			}
+			if req.MemoryMB != nil {
+				memory = *req.MemoryMB
+			}
+			if req.Image != nil {
+				image = *req.Image
+			}
+
+			validateInstanceSpecs(&v, cpu, memory, image)
+		}
+
+		validateLabels(&v, req.Labels)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+
+		return tx.templateRepo.Update(id, req)
+	})
+}
+
+// DeleteInstanceTemplate deletes an instance template
+func (s *Service) DeleteInstanceTemplate(id string) error {
+	return s.templateRepo.Delete(id)
+}
+
+// CreateInstanceFromTemplate creates an instance using a template's shape
+func (s *Service) CreateInstanceFromTemplate(req domain.CreateInstanceFromTemplateRequest) (*domain.Instance, error) {
+	return inTx(s, func(tx *Service) (*domain.Instance, error) {
+		if req.TemplateID == "" {
+			return nil, domain.ValidationError([]domain.FieldViolation{{Field: "template_id", Message: "cannot be empty"}})
+		}
+
+		tmpl, err := tx.templateRepo.GetByID(req.TemplateID)
+		if err != nil {
+			if domain.IsNotFound(err) {
+				return nil, domain.ForeignKeyViolationError("instance template", "id", req.TemplateID)
+			}
+			return nil, err
+		}
+
+		return tx.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID:  req.ProjectID,
+			Name:       req.Name,
+			CPU:        tmpl.CPU,
+			MemoryMB:   tmpl.MemoryMB,
+			Image:      tmpl.Image,
+			Status:     req.Status,
+			Labels:     mergeLabels(tmpl.Labels, req.Labels),
+			TTLSeconds: req.TTLSeconds,
+			Zone:       req.Zone,
+			Class:      req.Class,
+		})
+	})
+}
+
+// CloneInstance creates a new instance with the same shape, class and labels
+// as an existing one. The expiry of the source is not copied.
+func (s *Service) CloneInstance(id string, req domain.CloneInstanceRequest) (*domain.Instance, error) {
+	return inTx(s, func(tx *Service) (*domain.Instance, error) {
+		source, err := tx.instanceRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		projectID := req.ProjectID
+		if projectID == "" {
+			projectID = source.ProjectID
+		}
+
+		return tx.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: projectID,
+			Name:      req.Name,
+			CPU:       source.CPU,
+			MemoryMB:  source.MemoryMB,
+			Image:     source.Image,
+			Status:    req.Status,
+			Labels:    mergeLabels(source.Labels, nil),
+			Zone:      req.Zone,
+			Class:     source.Class,
+		})
+	})
+}
+
+// mergeLabels returns a new label map with overrides applied over base
+func mergeLabels(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CreateInstanceFromTemplate(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "templates"})
+	require.NoError(t, err)
+	tmpl, err := svc.CreateInstanceTemplate(domain.CreateInstanceTemplateRequest{
+		Name: "small", CPU: 2, MemoryMB: 1024, Image: "ubuntu", Labels: map[string]string{"tier": "web", "env": "dev"},
+	})
+	require.NoError(t, err)
+
+	instance, err := svc.CreateInstanceFromTemplate(domain.CreateInstanceFromTemplateRequest{
+		TemplateID: tmpl.ID, ProjectID: project.ID, Name: "web-1", Labels: map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, instance.CPU)
+	assert.Equal(t, 1024, instance.MemoryMB)
+	assert.Equal(t, "ubuntu", instance.Image)
+	assert.Equal(t, map[string]string{"tier": "web", "env": "prod"}, instance.Labels)
+
+	// Changing the template does not affect existing instances
+	cpu := 8
+	_, err = svc.UpdateInstanceTemplate(tmpl.ID, domain.UpdateInstanceTemplateRequest{CPU: &cpu})
+	require.NoError(t, err)
+	stored, err := svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.CPU)
+
+	_, err = svc.CreateInstanceFromTemplate(domain.CreateInstanceFromTemplateRequest{
+		TemplateID: "missing", ProjectID: project.ID, Name: "web-2",
+	})
+	assert.True(t, domain.IsForeignKeyViolation(err))
+}
+
+func TestService_CloneInstance(t *testing.T) {
+	svc := newTestService(t)
+
+	source, err := svc.CreateProject(domain.CreateProjectRequest{Name: "source"})
+	require.NoError(t, err)
+	target, err := svc.CreateProject(domain.CreateProjectRequest{Name: "target"})
+	require.NoError(t, err)
+	original, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: source.ID, Name: "db", CPU: 4, MemoryMB: 8192, Image: "postgres",
+		Labels: map[string]string{"role": "db"}, TTLSeconds: 3600,
+	})
+	require.NoError(t, err)
+
+	clone, err := svc.CloneInstance(original.ID, domain.CloneInstanceRequest{Name: "db-copy"})
+	require.NoError(t, err)
+	assert.Equal(t, source.ID, clone.ProjectID)
+	assert.Equal(t, 4, clone.CPU)
+	assert.Equal(t, 8192, clone.MemoryMB)
+	assert.Equal(t, "postgres", clone.Image)
+	assert.Equal(t, map[string]string{"role": "db"}, clone.Labels)
+	assert.Nil(t, clone.ExpiresAt)
+
+	moved, err := svc.CloneInstance(original.ID, domain.CloneInstanceRequest{Name: "db", ProjectID: target.ID})
+	require.NoError(t, err)
+	assert.Equal(t, target.ID, moved.ProjectID)
+
+	// Names are unique within a project
+	_, err = svc.CloneInstance(original.ID, domain.CloneInstanceRequest{Name: "db"})
+	assert.True(t, domain.IsAlreadyExists(err))
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Terraform states and their locks are kept in the metadata store, under
+// tfstate/{name}/state and tfstate/{name}/lock, so they count against its
+// limits and show up in its listings and exports.
+const tfStatePrefix = "tfstate/"
+
+func tfStatePath(name string) string {
+	return tfStatePrefix + name + "/state"
+}
+
+func tfStateLockPath(name string) string {
+	return tfStatePrefix + name + "/lock"
+}
+
+func validateTFStateName(name string) error {
+	var v domain.FieldViolations
+	if name == "" {
+		v.Add("name", "cannot be empty")
+	} else if strings.Contains(name, "/") {
+		v.Add("name", "cannot contain /")
+	}
+	return v.Err()
+}
+
+// parseTFStateLock reads the lock ID from Terraform lock info
+func parseTFStateLock(info string) (*domain.TFStateLock, error) {
+	var fields struct {
+		ID string `json:"ID"`
+	}
+	if err := json.Unmarshal([]byte(info), &fields); err != nil {
+		return nil, domain.InvalidInputError("lock info must be a JSON object", nil)
+	}
+	if fields.ID == "" {
+		var v domain.FieldViolations
+		v.Add("ID", "cannot be empty")
+		return nil, v.Err()
+	}
+	return &domain.TFStateLock{ID: fields.ID, Info: info}, nil
+}
+
+// tfStateLock returns the lock held on the state, nil if there is none
+func (s *Service) tfStateLock(name string) (*domain.TFStateLock, error) {
+	m, err := s.metadataRepo.GetByPath(tfStateLockPath(name))
+	if domain.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseTFStateLock(m.Value)
+}
+
+// checkTFStateLock fails with the holder's lock when the state is locked
+// under an ID other than lockID
+func (s *Service) checkTFStateLock(name, lockID string) error {
+	lock, err := s.tfStateLock(name)
+	if err != nil {
+		return err
+	}
+	if lock != nil && lock.ID != lockID {
+		return domain.TFStateLockedError(name, lock)
+	}
+	return nil
+}
+
+// GetTFState returns the Terraform state stored under name
+func (s *Service) GetTFState(name string) (string, error) {
+	if err := validateTFStateName(name); err != nil {
+		return "", err
+	}
+
+	m, err := s.metadataRepo.GetByPath(tfStatePath(name))
+	if domain.IsNotFound(err) {
+		return "", domain.NotFoundError("terraform state", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return m.Value, nil
+}
+
+// PutTFState stores a Terraform state under name. While the state is locked
+// only the lock's holder, passing its lockID, may write it.
+func (s *Service) PutTFState(name, state, lockID string) error {
+	if err := validateTFStateName(name); err != nil {
+		return err
+	}
+
+	return s.runInTx(func(tx *Service) error {
+		if err := tx.checkTFStateLock(name, lockID); err != nil {
+			return err
+		}
+		_, err := tx.putMetadata(domain.CreateMetadataRequest{Path: tfStatePath(name), Value: state}, domain.MetadataWriteOptions{})
+		return err
+	})
+}
+
+// DeleteTFState deletes the Terraform state stored under name, subject to
+// its lock as PutTFState is. The lock itself is left for its holder to
+// release.
+func (s *Service) DeleteTFState(name, lockID string) error {
+	if err := validateTFStateName(name); err != nil {
+		return err
+	}
+
+	return s.runInTx(func(tx *Service) error {
+		if err := tx.checkTFStateLock(name, lockID); err != nil {
+			return err
+		}
+		_, err := tx.deleteMetadataByPath(tfStatePath(name), domain.MetadataWriteOptions{})
+		return err
+	})
+}
+
+// LockTFState locks the Terraform state under name with the lock info
+// Terraform sent. Taking a lock again under the same ID succeeds; any other
+// lock fails with the one held.
+func (s *Service) LockTFState(name, info string) error {
+	if err := validateTFStateName(name); err != nil {
+		return err
+	}
+	lock, err := parseTFStateLock(info)
+	if err != nil {
+		return err
+	}
+
+	return s.runInTx(func(tx *Service) error {
+		if err := tx.checkTFStateLock(name, lock.ID); err != nil {
+			return err
+		}
+		_, err := tx.putMetadata(domain.CreateMetadataRequest{Path: tfStateLockPath(name), Value: lock.Info}, domain.MetadataWriteOptions{})
+		return err
+	})
+}
+
+// UnlockTFState releases the lock on the Terraform state under name, given
+// the lock info it was taken with. Without lock info it releases whatever
+// lock is held, as force-unlock does. Releasing a state that is not locked
+// succeeds.
+func (s *Service) UnlockTFState(name, info string) error {
+	if err := validateTFStateName(name); err != nil {
+		return err
+	}
+	var lockID string
+	if info != "" {
+		lock, err := parseTFStateLock(info)
+		if err != nil {
+			return err
+		}
+		lockID = lock.ID
+	}
+
+	return s.runInTx(func(tx *Service) error {
+		lock, err := tx.tfStateLock(name)
+		if err != nil || lock == nil {
+			return err
+		}
+		if lockID != "" && lock.ID != lockID {
+			return domain.TFStateLockedError(name, lock)
+		}
+		_, err = tx.deleteMetadataByPath(tfStateLockPath(name), domain.MetadataWriteOptions{})
+		return err
+	})
+}
@@ -0,0 +1,37 @@
+package service
+
+// UnitOfWork runs a function against repositories bound to a single
+// transaction, committing if the function returns nil and rolling back
+// otherwise. The repositories it hands out carry a unit of work that joins
+// the same transaction, so transactional operations compose.
+type UnitOfWork interface {
+	Do(fn func(repos Repositories) error) error
+}
+
+// inTx runs fn with a copy of s whose repositories share one transaction, so
+// an operation that fails part way leaves no partial state behind. Without a
+// unit of work fn runs against s directly.
+func inTx[T any](s *Service, fn func(tx *Service) (T, error)) (T, error) {
+	if s.uow == nil {
+		return fn(s)
+	}
+
+	var result T
+	err := s.uow.Do(func(repos Repositories) error {
+		tx := *s
+		tx.setRepositories(repos)
+
+		var err error
+		result, err = fn(&tx)
+		return err
+	})
+	return result, err
+}
+
+// runInTx is inTx for operations that return only an error
+func (s *Service) runInTx(fn func(tx *Service) error) error {
+	_, err := inTx(s, func(tx *Service) (struct{}, error) {
+		return struct{}{}, fn(tx)
+	})
+	return err
+}
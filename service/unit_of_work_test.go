@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInTx(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	tests := []struct {
+		name      string
+		fail      bool
+		committed bool
+	}{
+		{name: "commits on success", committed: true},
+		{name: "rolls back on error", fail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t)
+
+			project, err := inTx(svc, func(tx *Service) (*domain.Project, error) {
+				project, err := tx.CreateProject(domain.CreateProjectRequest{Name: "web"})
+				require.NoError(t, err)
+
+				_, err = tx.CreateInstance(domain.CreateInstanceRequest{
+					ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+				})
+				require.NoError(t, err)
+
+				if tt.fail {
+					return nil, errAbort
+				}
+				return project, nil
+			})
+
+			projects, listErr := svc.ListProjects(domain.ProjectListOptions{})
+			require.NoError(t, listErr)
+			instances, listErr := svc.ListInstances(domain.InstanceListOptions{})
+			require.NoError(t, listErr)
+
+			if !tt.committed {
+				assert.ErrorIs(t, err, errAbort)
+				assert.Empty(t, projects, "nested operations should roll back with the outer one")
+				assert.Empty(t, instances)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, projects, 1)
+			assert.Equal(t, project.ID, projects[0].ID)
+			assert.Len(t, instances, 1)
+		})
+	}
+}
+
+func TestCreateInstance_MissingProjectLeavesNoState(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: "missing", Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.Error(t, err)
+
+	instances, err := svc.ListInstances(domain.InstanceListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+
+	events, err := svc.ListEvents(domain.EventListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// bootVolumeGB is the size of the boot volume every instance is billed for
+const bootVolumeGB = 10
+
+// DefaultPriceSheet prices usage when the service is configured without one
+var DefaultPriceSheet = domain.PriceSheet{
+	Currency:     "USD",
+	InstanceHour: 0.01,
+	VCPUHour:     0.02,
+	MemoryGBHour: 0.003,
+	VolumeGBHour: 0.0001,
+}
+
+// prices returns the service's price sheet
+func (s *Service) prices() domain.PriceSheet {
+	if s.config.Prices == nil {
+		return DefaultPriceSheet
+	}
+	return *s.config.Prices
+}
+
+// instanceUsage returns the usage an instance accrues in hours
+func instanceUsage(instance *domain.Instance, hours float64) domain.Usage {
+	usage := domain.Usage{VolumeGBHours: bootVolumeGB * hours}
+	if instance.Status == domain.StatusRunning {
+		usage.InstanceHours = hours
+		usage.VCPUHours = float64(instance.CPU) * hours
+		usage.MemoryGBHours = float64(instance.MemoryMB) / 1024 * hours
+	}
+	return usage
+}
+
+// usageCost prices usage
+func usageCost(usage domain.Usage, prices domain.PriceSheet) float64 {
+	return usage.InstanceHours*prices.InstanceHour +
+		usage.VCPUHours*prices.VCPUHour +
+		usage.MemoryGBHours*prices.MemoryGBHour +
+		usage.VolumeGBHours*prices.VolumeGBHour
+}
+
+// MeterUsage adds the usage every instance accrued over elapsed, as of now,
+// to its project's total for the month of now
+func (s *Service) MeterUsage(elapsed time.Duration, now time.Time) error {
+	if elapsed <= 0 {
+		return nil
+	}
+
+	instances, err := s.instanceRepo.List(domain.InstanceListOptions{})
+	if err != nil {
+		return err
+	}
+
+	period := now.UTC().Format(domain.UsagePeriodLayout)
+	byProject := make(map[string]*domain.Usage)
+	var projectIDs []string
+	for _, instance := range instances {
+		total, ok := byProject[instance.ProjectID]
+		if !ok {
+			total = &domain.Usage{ProjectID: instance.ProjectID, Period: period, UpdatedAt: now}
+			byProject[instance.ProjectID] = total
+			projectIDs = append(projectIDs, instance.ProjectID)
+		}
+
+		usage := instanceUsage(instance, elapsed.Hours())
+		total.InstanceHours += usage.InstanceHours
+		total.VCPUHours += usage.VCPUHours
+		total.MemoryGBHours += usage.MemoryGBHours
+		total.VolumeGBHours += usage.VolumeGBHours
+	}
+
+	return s.runInTx(func(tx *Service) error {
+		for _, projectID := range projectIDs {
+			if err := tx.usageRepo.Add(byProject[projectID]); err != nil {
+				if domain.IsForeignKeyViolation(err) {
+					continue // Project deleted concurrently
+				}
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RunUsageMeter meters instance usage and evaluates budgets against it every
+// interval until ctx is done
+func (s *Service) RunUsageMeter(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.MeterUsage(now.Sub(last), now); err != nil {
+				log.Printf("Usage meter failed: %v", err)
+			}
+			last = now
+
+			if err := s.EvaluateBudgets(now); err != nil {
+				log.Printf("Budget evaluation failed: %v", err)
+			}
+		}
+	}
+}
+
+// GetProjectUsage reports a project's usage in period, a month such as
+// "2024-03", with its cost. An empty period is the month of now.
+func (s *Service) GetProjectUsage(projectID, period string, now time.Time) (*domain.UsageReport, error) {
+	now = now.UTC()
+	if period == "" {
+		period = now.Format(domain.UsagePeriodLayout)
+	}
+	start, err := time.Parse(domain.UsagePeriodLayout, period)
+	if err != nil {
+		var v domain.FieldViolations
+		v.Add("period", fmt.Sprintf("must be a month such as 2024-03 (got %q)", period))
+		return nil, v.Err()
+	}
+	end := start.AddDate(0, 1, 0)
+
+	if _, err := s.projectRepo.GetByID(projectID); err != nil {
+		return nil, err
+	}
+
+	usage, err := s.usageRepo.Get(projectID, period)
+	if domain.IsNotFound(err) {
+		usage = &domain.Usage{ProjectID: projectID, Period: period}
+	} else if err != nil {
+		return nil, err
+	}
+
+	instances, err := s.instanceRepo.List(domain.InstanceListOptions{ProjectID: projectID})
+	if err != nil {
+		return nil, err
+	}
+
+	prices := s.prices()
+	report := &domain.UsageReport{
+		Usage:       *usage,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Prices:      prices,
+		Cost:        usageCost(*usage, prices),
+	}
+	for _, instance := range instances {
+		report.HourlyRate += usageCost(instanceUsage(instance, 1), prices)
+	}
+
+	// The current instances keep running for whatever is left of the period
+	from := now
+	if from.Before(start) {
+		from = start
+	}
+	report.EstimatedMonthly = report.Cost
+	if remaining := end.Sub(from); remaining > 0 {
+		report.EstimatedMonthly += report.HourlyRate * remaining.Hours()
+	}
+
+	return report, nil
+}
+
+// ListUsage retrieves every project's usage in period
+func (s *Service) ListUsage(period string) ([]*domain.Usage, error) {
+	return s.usageRepo.List(period)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_MeterUsage(t *testing.T) {
+	svc := newTestService(t)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "billing"})
+	require.NoError(t, err)
+
+	create := func(name string, cpu, memoryMB int) *domain.Instance {
+		instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: cpu, MemoryMB: memoryMB, Image: "ubuntu",
+		})
+		require.NoError(t, err)
+		return instance
+	}
+	create("web", 2, 2048)
+	stopped := create("batch", 4, 1024)
+	status := domain.StatusStopped
+	_, err = svc.UpdateInstance(stopped.ID, domain.UpdateInstanceRequest{Status: &status})
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, svc.MeterUsage(2*time.Hour, now))
+	require.NoError(t, svc.MeterUsage(time.Hour, now.Add(time.Hour)))
+
+	// Stopped instances only accrue their boot volume
+	report, err := svc.GetProjectUsage(project.ID, "2026-03", now)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03", report.Period)
+	assert.InDelta(t, 3, report.InstanceHours, 1e-9)
+	assert.InDelta(t, 6, report.VCPUHours, 1e-9)
+	assert.InDelta(t, 6, report.MemoryGBHours, 1e-9)
+	assert.InDelta(t, 2*3*bootVolumeGB, report.VolumeGBHours, 1e-9)
+
+	prices := DefaultPriceSheet
+	cost := 3*prices.InstanceHour + 6*prices.VCPUHour + 6*prices.MemoryGBHour + 60*prices.VolumeGBHour
+	rate := prices.InstanceHour + 2*prices.VCPUHour + 2*prices.MemoryGBHour + 2*bootVolumeGB*prices.VolumeGBHour
+	assert.InDelta(t, cost, report.Cost, 1e-9)
+	assert.InDelta(t, rate, report.HourlyRate, 1e-9)
+
+	// The estimate runs the current instances until the end of the month
+	remaining := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC).Sub(now).Hours()
+	assert.InDelta(t, cost+rate*remaining, report.EstimatedMonthly, 1e-9)
+
+	// A finished month's estimate is its cost
+	report, err = svc.GetProjectUsage(project.ID, "2026-02", now)
+	require.NoError(t, err)
+	assert.Zero(t, report.Cost)
+	assert.Zero(t, report.EstimatedMonthly)
+
+	_, err = svc.GetProjectUsage(project.ID, "March", now)
+	assert.True(t, domain.IsInvalidInput(err))
+
+	_, err = svc.GetProjectUsage("missing", "", now)
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestService_GetProjectUsagePrices(t *testing.T) {
+	svc := newTestService(t)
+	svc.config.Prices = &domain.PriceSheet{Currency: "EUR", InstanceHour: 1}
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "priced"})
+	require.NoError(t, err)
+	_, err = svc.CreateInstance(domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, svc.MeterUsage(90*time.Minute, now))
+
+	report, err := svc.GetProjectUsage(project.ID, "", now)
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", report.Prices.Currency)
+	assert.InDelta(t, 1.5, report.Cost, 1e-9)
+	assert.InDelta(t, 1, report.HourlyRate, 1e-9)
+}
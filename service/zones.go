@@ -0,0 +1,242 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// zoneOutages tracks the availability zones that are down. It is shared by
+// every copy of the service.
+type zoneOutages struct {
+	mu sync.Mutex
+
+	// down maps the zones that are down to when they went down
+	down map[string]time.Time
+
+	// previous holds, by zone, the statuses of the instances outages made
+	// unavailable, to return them to when their zone recovers
+	previous map[string]map[string]string
+}
+
+func newZoneOutages() *zoneOutages {
+	return &zoneOutages{down: make(map[string]time.Time), previous: make(map[string]map[string]string)}
+}
+
+// isDown reports whether zone is down
+func (z *zoneOutages) isDown(zone string) bool {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	_, down := z.down[zone]
+	return down
+}
+
+// zones returns the availability zones instances can be placed in
+func (s *Service) zones() []string {
+	if len(s.config.Zones) == 0 {
+		return domain.DefaultZones
+	}
+	return s.config.Zones
+}
+
+// hasZone reports whether zone is one of the service's zones
+func (s *Service) hasZone(zone string) bool {
+	for _, z := range s.zones() {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// validateZone validates the zone an instance is requested in
+func (s *Service) validateZone(v *domain.FieldViolations, zone string) {
+	if zone != "" && !s.hasZone(zone) {
+		v.Add("zone", fmt.Sprintf("must be one of %s (got %q)", strings.Join(s.zones(), ", "), zone))
+	}
+}
+
+// zone describes a zone as it is now
+func (s *Service) zone(name string) (*domain.Zone, error) {
+	used, err := s.zoneUsage(name, "")
+	if err != nil {
+		return nil, err
+	}
+	zone := &domain.Zone{Name: name, Status: domain.ZoneStatusUp, Used: used}
+	if capacity, ok := s.capacity.pool(name); ok {
+		zone.Capacity = &capacity
+	}
+
+	s.outages.mu.Lock()
+	defer s.outages.mu.Unlock()
+	if since, down := s.outages.down[name]; down {
+		zone.Status = domain.ZoneStatusDown
+		zone.OutageStartedAt = &since
+	}
+	return zone, nil
+}
+
+// ListZones lists the availability zones in their configured order
+func (s *Service) ListZones() ([]*domain.Zone, error) {
+	var zones []*domain.Zone
+	for _, name := range s.zones() {
+		zone, err := s.zone(name)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
+
+// GetZone retrieves an availability zone by name
+func (s *Service) GetZone(name string) (*domain.Zone, error) {
+	if !s.hasZone(name) {
+		return nil, domain.NotFoundError("zone", name)
+	}
+	return s.zone(name)
+}
+
+// placeInstance returns the zone an instance of the given size is placed in
+// when none is requested: the first zone that is up and has room for it. When
+// none has, it returns the first zone that is up, or else the first zone, so
+// that the create fails with the zone's stockout or outage.
+func (s *Service) placeInstance(cpu, memoryMB int) (string, error) {
+	var fallback string
+	for _, zone := range s.zones() {
+		if s.outages.isDown(zone) {
+			continue
+		}
+		if fallback == "" {
+			fallback = zone
+		}
+		resource, _, err := s.zoneShortfall(zone, cpu, memoryMB, "")
+		if err != nil {
+			return "", err
+		}
+		if resource == "" {
+			return zone, nil
+		}
+	}
+	if fallback == "" {
+		fallback = s.zones()[0]
+	}
+	return fallback, nil
+}
+
+// checkZoneUp returns UNAVAILABLE for an instance in a zone that is down,
+// which can be neither created nor changed
+func (s *Service) checkZoneUp(instance *domain.Instance) error {
+	if instance.Zone == "" || !s.outages.isDown(instance.Zone) {
+		return nil
+	}
+	return domain.ZoneUnavailableError("instance", instance.Zone)
+}
+
+// StartZoneOutage takes an availability zone down. Every instance in it
+// becomes unavailable, and until the outage ends instances can neither be
+// created in the zone nor changed, though they can still be deleted.
+// Starting an outage in a zone that is already down changes nothing.
+func (s *Service) StartZoneOutage(name string) (*domain.Zone, error) {
+	if !s.hasZone(name) {
+		return nil, domain.NotFoundError("zone", name)
+	}
+
+	s.outages.mu.Lock()
+	if _, down := s.outages.down[name]; down {
+		s.outages.mu.Unlock()
+		return s.zone(name)
+	}
+	s.outages.down[name] = time.Now().UTC()
+	s.outages.previous[name] = make(map[string]string)
+	s.outages.mu.Unlock()
+
+	err := s.runInTx(func(tx *Service) error {
+		instances, err := tx.instanceRepo.List(domain.InstanceListOptions{Zone: name})
+		if err != nil {
+			return err
+		}
+
+		unavailable, affected := domain.StatusUnavailable, 0
+		for _, instance := range instances {
+			if instance.Status == domain.StatusDeleting || instance.Status == domain.StatusUnavailable {
+				continue
+			}
+			updated, err := tx.instanceRepo.Update(instance.ID, domain.UpdateInstanceRequest{Status: &unavailable})
+			if err != nil {
+				return err
+			}
+			tx.WithReason(domain.ReasonZoneOutage).recordStatusChange(updated, instance.Status, updated.Status)
+
+			s.outages.mu.Lock()
+			s.outages.previous[name][instance.ID] = instance.Status
+			s.outages.mu.Unlock()
+			affected++
+		}
+
+		tx.recordEvent(domain.EventZoneOutageStarted, "zone", name, "",
+			fmt.Sprintf("zone %s went down; %d instances became unavailable", name, affected))
+		return nil
+	})
+	if err != nil {
+		s.outages.mu.Lock()
+		delete(s.outages.down, name)
+		delete(s.outages.previous, name)
+		s.outages.mu.Unlock()
+		return nil, err
+	}
+
+	return s.zone(name)
+}
+
+// EndZoneOutage brings an availability zone back up, returning its
+// unavailable instances to the status they had before the outage, or to
+// running when that is not known. Ending the outage of a zone that is up
+// changes nothing.
+func (s *Service) EndZoneOutage(name string) (*domain.Zone, error) {
+	if !s.hasZone(name) {
+		return nil, domain.NotFoundError("zone", name)
+	}
+	if !s.outages.isDown(name) {
+		return s.zone(name)
+	}
+
+	err := s.runInTx(func(tx *Service) error {
+		instances, err := tx.instanceRepo.List(domain.InstanceListOptions{Zone: name, Status: domain.StatusUnavailable})
+		if err != nil {
+			return err
+		}
+
+		for _, instance := range instances {
+			s.outages.mu.Lock()
+			status, ok := s.outages.previous[name][instance.ID]
+			s.outages.mu.Unlock()
+			if !ok {
+				status = domain.StatusRunning
+			}
+
+			updated, err := tx.instanceRepo.Update(instance.ID, domain.UpdateInstanceRequest{Status: &status})
+			if err != nil {
+				return err
+			}
+			tx.WithReason(domain.ReasonZoneRecovery).recordStatusChange(updated, instance.Status, updated.Status)
+		}
+
+		tx.recordEvent(domain.EventZoneOutageEnded, "zone", name, "",
+			fmt.Sprintf("zone %s recovered; %d instances became available", name, len(instances)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outages.mu.Lock()
+	delete(s.outages.down, name)
+	delete(s.outages.previous, name)
+	s.outages.mu.Unlock()
+
+	return s.zone(name)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateInstance_Zone(t *testing.T) {
+	svc := newTestService(t)
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "zoned"})
+	require.NoError(t, err)
+
+	req := domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm", CPU: 1, MemoryMB: 512, Image: "ubuntu"}
+	instance, err := svc.CreateInstance(req)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DefaultZones[0], instance.Zone)
+
+	req.Name, req.Zone = "vm-b", domain.DefaultZones[1]
+	instance, err = svc.CreateInstance(req)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DefaultZones[1], instance.Zone)
+
+	req.Name, req.Zone = "vm-x", "mars-1a"
+	_, err = svc.CreateInstance(req)
+	assert.True(t, domain.IsInvalidInput(err))
+}
+
+func TestZoneOutage(t *testing.T) {
+	svc := newTestService(t)
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "dr"})
+	require.NoError(t, err)
+
+	zone := domain.DefaultZones[0]
+	create := func(name, zone, status string) *domain.Instance {
+		instance, err := svc.CreateInstance(domain.CreateInstanceRequest{
+			ProjectID: project.ID, Name: name, CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: status, Zone: zone,
+		})
+		require.NoError(t, err)
+		return instance
+	}
+	running := create("running", zone, domain.StatusRunning)
+	stopped := create("stopped", zone, domain.StatusStopped)
+	elsewhere := create("elsewhere", domain.DefaultZones[1], domain.StatusRunning)
+
+	down, err := svc.StartZoneOutage(zone)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ZoneStatusDown, down.Status)
+	require.NotNil(t, down.OutageStartedAt)
+
+	status := func(id string) string {
+		instance, err := svc.GetInstance(id)
+		require.NoError(t, err)
+		return instance.Status
+	}
+	assert.Equal(t, domain.StatusUnavailable, status(running.ID))
+	assert.Equal(t, domain.StatusUnavailable, status(stopped.ID))
+	assert.Equal(t, domain.StatusRunning, status(elsewhere.ID))
+
+	// The zone takes no new instances, and those in it cannot change, but
+	// other zones carry on and new instances are placed in them
+	_, err = svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "new", CPU: 1, MemoryMB: 512, Image: "ubuntu", Zone: zone})
+	require.Error(t, err)
+	dirtErr, ok := domain.AsDirtError(err)
+	require.True(t, ok)
+	assert.Equal(t, domain.ReasonZoneUnavailable, dirtErr.Reason)
+	assert.True(t, dirtErr.Retryable)
+
+	stop := domain.StatusStopped
+	_, err = svc.UpdateInstance(running.ID, domain.UpdateInstanceRequest{Status: &stop})
+	assert.True(t, errors.Is(err, domain.ErrServiceUnavailable))
+
+	placed := create("placed", "", "")
+	assert.Equal(t, domain.DefaultZones[1], placed.Zone)
+
+	// Recovery returns instances to the status they had before
+	up, err := svc.EndZoneOutage(zone)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ZoneStatusUp, up.Status)
+	assert.Equal(t, domain.StatusRunning, status(running.ID))
+	assert.Equal(t, domain.StatusStopped, status(stopped.ID))
+
+	events, err := svc.ListEvents(domain.EventListOptions{ResourceID: running.ID, Type: domain.EventInstanceStatusChanged})
+	require.NoError(t, err)
+	var reasons []string
+	for _, event := range events {
+		reasons = append(reasons, event.Reason)
+	}
+	assert.Equal(t, []string{domain.ReasonUserRequest, domain.ReasonZoneOutage, domain.ReasonZoneRecovery}, reasons)
+
+	events, err = svc.ListEvents(domain.EventListOptions{ResourceType: "zone", ResourceID: zone})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.EventZoneOutageStarted, events[0].Type)
+	assert.Equal(t, domain.EventZoneOutageEnded, events[1].Type)
+
+	_, err = svc.StartZoneOutage("mars-1a")
+	assert.True(t, domain.IsNotFound(err))
+}
+
+func TestUpdateInstance_ImmutableZone(t *testing.T) {
+	svc := newTestService(t)
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "zoned"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	// Restating the zone is allowed; moving it is not
+	same := instance.Zone
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Zone: &same})
+	require.NoError(t, err)
+
+	other := domain.DefaultZones[1]
+	_, err = svc.UpdateInstance(instance.ID, domain.UpdateInstanceRequest{Zone: &other})
+	require.True(t, domain.IsImmutableField(err), "got %v", err)
+	assert.Equal(t, "zone", err.(*domain.DirtError).Details["field"])
+}
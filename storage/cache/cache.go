@@ -0,0 +1,233 @@
+// Package cache keeps an in-process read cache in front of the service
+// repositories, so repeated reads of the same resources do not go back to
+// the database. Streamed lists go straight to the database: they are meant
+// for results too large to keep.
+package cache
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+)
+
+// Tables whose reads are cached, named after the repositories reading them
+const (
+	tableProjects  = "projects"
+	tableInstances = "instances"
+	tableMetadata  = "metadata"
+)
+
+// Cache counts the reads of the repositories it wraps
+type Cache struct {
+	mu    sync.Mutex
+	stats map[string]*domain.CacheStats
+}
+
+// New creates a cache with no reads counted
+func New() *Cache {
+	return &Cache{stats: make(map[string]*domain.CacheStats)}
+}
+
+// Wrap returns repos with the project, instance and metadata reads cached.
+// Each set of repositories wrapped is cached on its own, so one cache can
+// serve several databases; its statistics cover them all.
+//
+// Cached reads are dropped whenever their table is written to, and again
+// when the transaction writing to it ends. Reads inside a transaction
+// always go to the database, as they may see writes not yet committed.
+func (c *Cache) Wrap(repos service.Repositories) service.Repositories {
+	return wrap(repos, &reader{cache: c, store: newStore()})
+}
+
+// Stats returns the reads counted for each cached repository, ordered by
+// repository
+func (c *Cache) Stats() []domain.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make([]domain.CacheStats, 0, len(c.stats))
+	for _, s := range c.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Repository < stats[j].Repository
+	})
+	return stats
+}
+
+// count updates the statistics of a table's repository
+func (c *Cache) count(table string, update func(s *domain.CacheStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.stats[table]
+	if !ok {
+		s = &domain.CacheStats{Repository: table}
+		c.stats[table] = s
+	}
+	update(s)
+}
+
+// store holds the cached reads of one set of repositories by table. A
+// table's generation advances whenever it is invalidated, so a read that
+// raced a write is not cached.
+type store struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+type table struct {
+	generation uint64
+	entries    map[string]interface{}
+}
+
+func newStore() *store {
+	return &store{tables: make(map[string]*table)}
+}
+
+func (s *store) table(name string) *table {
+	t, ok := s.tables[name]
+	if !ok {
+		t = &table{entries: make(map[string]interface{})}
+		s.tables[name] = t
+	}
+	return t
+}
+
+// get returns a cached read, or the generation a read made now belongs to
+func (s *store) get(name, key string) (interface{}, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(name)
+	value, ok := t.entries[key]
+	return value, t.generation, ok
+}
+
+// put caches a read unless its table was invalidated since it was made
+func (s *store) put(name, key string, generation uint64, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(name)
+	if t.generation == generation {
+		t.entries[key] = value
+	}
+}
+
+// invalidate drops the cached reads of tables. It reports how many tables
+// had reads to drop.
+func (s *store) invalidate(names ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropped := 0
+	for _, name := range names {
+		t := s.table(name)
+		if len(t.entries) > 0 {
+			t.entries = make(map[string]interface{})
+			dropped++
+		}
+		t.generation++
+	}
+	return dropped
+}
+
+// reader reads through a store, bypassing it inside a transaction
+type reader struct {
+	cache *Cache
+	store *store
+
+	// written collects the tables written to inside a transaction, to be
+	// invalidated once it ends; nil outside transactions
+	written *writeSet
+}
+
+// invalidate drops the cached reads of tables after a write to them
+func (r *reader) invalidate(names ...string) {
+	if r.written != nil {
+		r.written.add(names...)
+	}
+	for _, name := range names {
+		if r.store.invalidate(name) > 0 {
+			r.cache.count(name, func(s *domain.CacheStats) { s.Invalidations++ })
+		}
+	}
+}
+
+// flush drops every cached read, as after the database is replaced
+func (r *reader) flush() {
+	r.invalidate(tableProjects, tableInstances, tableMetadata)
+}
+
+// cached returns the read of key from table, loading and caching it on a
+// miss. Values are cloned on the way in and out, so callers may modify
+// what they get back. Errors are not cached.
+func cached[T any](r *reader, name, key string, clone func(T) T, load func() (T, error)) (T, error) {
+	if r.written != nil {
+		return load()
+	}
+
+	value, generation, ok := r.store.get(name, key)
+	if ok {
+		r.cache.count(name, func(s *domain.CacheStats) { s.Hits++ })
+		return clone(value.(T)), nil
+	}
+	r.cache.count(name, func(s *domain.CacheStats) { s.Misses++ })
+
+	loaded, err := load()
+	if err != nil {
+		return loaded, err
+	}
+	r.store.put(name, key, generation, clone(loaded))
+	return loaded, nil
+}
+
+// writeSet is the set of tables written to in a transaction
+type writeSet struct {
+	mu     sync.Mutex
+	tables map[string]bool
+}
+
+func (w *writeSet) add(names ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, name := range names {
+		w.tables[name] = true
+	}
+}
+
+func (w *writeSet) names() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	names := make([]string, 0, len(w.tables))
+	for name := range w.tables {
+		names = append(names, name)
+	}
+	return names
+}
+
+// unitOfWork wraps the repositories of each transaction, invalidating the
+// tables written to once the transaction ends. Reads cached while it was
+// open may predate a write that has since committed.
+type unitOfWork struct {
+	inner  service.UnitOfWork
+	reader *reader
+}
+
+// Do runs fn with wrapped transactional repositories
+func (u *unitOfWork) Do(fn func(repos service.Repositories) error) error {
+	written := u.reader.written
+	if written == nil {
+		written = &writeSet{tables: make(map[string]bool)}
+	}
+	tx := &reader{cache: u.reader.cache, store: u.reader.store, written: written}
+
+	err := u.inner.Do(func(repos service.Repositories) error {
+		return fn(wrap(repos, tx))
+	})
+	u.reader.invalidate(written.names()...)
+	return err
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService creates a service whose repositories are cached by c
+func newTestService(t *testing.T, c *Cache) *service.Service {
+	t.Helper()
+
+	db, err := sqlite.NewDB("file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_fk=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return service.NewService(c.Wrap(service.NewSQLiteRepositories(db, t.TempDir())), service.Config{})
+}
+
+// stats returns the statistics of one repository
+func stats(c *Cache, repository string) domain.CacheStats {
+	for _, s := range c.Stats() {
+		if s.Repository == repository {
+			return s
+		}
+	}
+	return domain.CacheStats{Repository: repository}
+}
+
+func TestCache_HitsAndInvalidation(t *testing.T) {
+	c := New()
+	svc := newTestService(t, c)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "cached", Labels: map[string]string{"env": "dev"}})
+	require.NoError(t, err)
+
+	_, err = svc.GetProject(project.ID)
+	require.NoError(t, err)
+	before := stats(c, tableProjects)
+	got, err := svc.GetProject(project.ID)
+	require.NoError(t, err)
+	after := stats(c, tableProjects)
+	assert.Equal(t, before.Hits+1, after.Hits)
+	assert.Equal(t, before.Misses, after.Misses)
+
+	// Changing what a read returned leaves the cached read alone
+	got.Labels["env"] = "changed"
+	got, err = svc.GetProject(project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", got.Labels["env"])
+
+	// Updates, which run in a transaction, are seen by the next read
+	name := "renamed"
+	_, err = svc.UpdateProject(project.ID, domain.UpdateProjectRequest{Name: &name})
+	require.NoError(t, err)
+	got, err = svc.GetProject(project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", got.Name)
+	assert.Greater(t, stats(c, tableProjects).Invalidations, before.Invalidations)
+
+	projects, err := svc.ListProjects(domain.ProjectListOptions{})
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, "renamed", projects[0].Name)
+}
+
+func TestCache_CascadingDeletes(t *testing.T) {
+	c := New()
+	svc := newTestService(t, c)
+
+	project, err := svc.CreateProject(domain.CreateProjectRequest{Name: "cascade"})
+	require.NoError(t, err)
+	instance, err := svc.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	instances, err := svc.ListInstances(domain.InstanceListOptions{ProjectID: project.ID})
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	_, err = svc.GetInstance(instance.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeleteProject(project.ID, domain.DeleteProjectOptions{Cascade: true}))
+
+	_, err = svc.GetInstance(instance.ID)
+	assert.True(t, domain.IsNotFound(err), "got %v", err)
+	instances, err = svc.ListInstances(domain.InstanceListOptions{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
+
+func TestCache_ResetFlushes(t *testing.T) {
+	c := New()
+	svc := newTestService(t, c)
+
+	_, err := svc.CreateMetadata(domain.CreateMetadataRequest{Path: "app/key", Value: "v"})
+	require.NoError(t, err)
+	metadata, err := svc.ListMetadata(domain.MetadataListOptions{})
+	require.NoError(t, err)
+	require.Len(t, metadata, 1)
+
+	require.NoError(t, svc.ResetData())
+
+	metadata, err = svc.ListMetadata(domain.MetadataListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, metadata)
+}
+
+func TestStore_DiscardsReadsRacingWrites(t *testing.T) {
+	s := newStore()
+
+	_, generation, ok := s.get(tableInstances, "id:1")
+	require.False(t, ok)
+
+	// A write lands between the read and caching it
+	s.invalidate(tableInstances)
+	s.put(tableInstances, "id:1", generation, "stale")
+
+	_, _, ok = s.get(tableInstances, "id:1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+)
+
+// wrap caches the reads of repos through r
+func wrap(repos service.Repositories, r *reader) service.Repositories {
+	repos.Projects = &projectRepository{ProjectRepository: repos.Projects, reader: r}
+	repos.Instances = &instanceRepository{InstanceRepository: repos.Instances, reader: r}
+	repos.Metadata = &metadataRepository{MetadataRepository: repos.Metadata, reader: r}
+	repos.Groups = &groupRepository{AutoscalingGroupRepository: repos.Groups, reader: r}
+	repos.Backups = &backupRepository{BackupRepository: repos.Backups, reader: r}
+	repos.Integrity = &integrityRepository{IntegrityRepository: repos.Integrity, reader: r}
+	if repos.UnitOfWork != nil {
+		repos.UnitOfWork = &unitOfWork{inner: repos.UnitOfWork, reader: r}
+	}
+	if traced := repos.Traced; traced != nil {
+		// Traced repositories share the cache, so their writes invalidate it
+		repos.Traced = func(trace *domain.StatementTrace) service.Repositories {
+			return wrap(traced(trace), r)
+		}
+	}
+	return repos
+}
+
+// listKey is the cache key of a list read
+func listKey(opts interface{}) string {
+	return fmt.Sprintf("list:%+v", opts)
+}
+
+// projectRepository caches project reads
+type projectRepository struct {
+	service.ProjectRepository
+	reader *reader
+}
+
+func (r *projectRepository) GetByID(id string) (*domain.Project, error) {
+	return cached(r.reader, tableProjects, "id:"+id, cloneProject, func() (*domain.Project, error) {
+		return r.ProjectRepository.GetByID(id)
+	})
+}
+
+func (r *projectRepository) GetByName(name string) (*domain.Project, error) {
+	return cached(r.reader, tableProjects, "name:"+name, cloneProject, func() (*domain.Project, error) {
+		return r.ProjectRepository.GetByName(name)
+	})
+}
+
+func (r *projectRepository) List(opts domain.ProjectListOptions) ([]*domain.Project, error) {
+	return cached(r.reader, tableProjects, listKey(opts), cloneAll(cloneProject), func() ([]*domain.Project, error) {
+		return r.ProjectRepository.List(opts)
+	})
+}
+
+func (r *projectRepository) Create(project *domain.Project) error {
+	defer r.reader.invalidate(tableProjects)
+	return r.ProjectRepository.Create(project)
+}
+
+func (r *projectRepository) Update(id string, req domain.UpdateProjectRequest) (*domain.Project, error) {
+	defer r.reader.invalidate(tableProjects)
+	return r.ProjectRepository.Update(id, req)
+}
+
+func (r *projectRepository) Move(id string, organizationID string, folderID string) (*domain.Project, error) {
+	defer r.reader.invalidate(tableProjects)
+	return r.ProjectRepository.Move(id, organizationID, folderID)
+}
+
+// Delete also drops cached instances, which are deleted with their project
+func (r *projectRepository) Delete(id string) error {
+	defer r.reader.invalidate(tableProjects, tableInstances)
+	return r.ProjectRepository.Delete(id)
+}
+
+// instanceRepository caches instance reads. Listing expired and deleted
+// instances depends on the time, so those reads are not cached.
+type instanceRepository struct {
+	service.InstanceRepository
+	reader *reader
+}
+
+func (r *instanceRepository) GetByID(id string) (*domain.Instance, error) {
+	return cached(r.reader, tableInstances, "id:"+id, cloneInstance, func() (*domain.Instance, error) {
+		return r.InstanceRepository.GetByID(id)
+	})
+}
+
+func (r *instanceRepository) List(opts domain.InstanceListOptions) ([]*domain.Instance, error) {
+	return cached(r.reader, tableInstances, listKey(opts), cloneAll(cloneInstance), func() ([]*domain.Instance, error) {
+		return r.InstanceRepository.List(opts)
+	})
+}
+
+func (r *instanceRepository) Create(instance *domain.Instance) error {
+	defer r.reader.invalidate(tableInstances)
+	return r.InstanceRepository.Create(instance)
+}
+
+func (r *instanceRepository) Update(id string, req domain.UpdateInstanceRequest) (*domain.Instance, error) {
+	defer r.reader.invalidate(tableInstances)
+	return r.InstanceRepository.Update(id, req)
+}
+
+func (r *instanceRepository) Delete(id string) error {
+	defer r.reader.invalidate(tableInstances)
+	return r.InstanceRepository.Delete(id)
+}
+
+func (r *instanceRepository) MarkDeleting(id string, deleteAt time.Time) (*domain.Instance, error) {
+	defer r.reader.invalidate(tableInstances)
+	return r.InstanceRepository.MarkDeleting(id, deleteAt)
+}
+
+func (r *instanceRepository) SetHealth(id string, health string) (*domain.Instance, error) {
+	defer r.reader.invalidate(tableInstances)
+	return r.InstanceRepository.SetHealth(id, health)
+}
+
+// metadataRepository caches metadata reads
+type metadataRepository struct {
+	service.MetadataRepository
+	reader *reader
+}
+
+func (r *metadataRepository) GetByID(id string) (*domain.Metadata, error) {
+	return cached(r.reader, tableMetadata, "id:"+id, cloneMetadata, func() (*domain.Metadata, error) {
+		return r.MetadataRepository.GetByID(id)
+	})
+}
+
+func (r *metadataRepository) List(opts domain.MetadataListOptions) ([]*domain.Metadata, error) {
+	return cached(r.reader, tableMetadata, listKey(opts), cloneAll(cloneMetadata), func() ([]*domain.Metadata, error) {
+		return r.MetadataRepository.List(opts)
+	})
+}
+
+func (r *metadataRepository) Create(id string, req domain.CreateMetadataRequest) (*domain.Metadata, error) {
+	defer r.reader.invalidate(tableMetadata)
+	return r.MetadataRepository.Create(id, req)
+}
+
+func (r *metadataRepository) Update(id string, req domain.UpdateMetadataRequest) (*domain.Metadata, error) {
+	defer r.reader.invalidate(tableMetadata)
+	return r.MetadataRepository.Update(id, req)
+}
+
+func (r *metadataRepository) Delete(id string) error {
+	defer r.reader.invalidate(tableMetadata)
+	return r.MetadataRepository.Delete(id)
+}
+
+// groupRepository drops cached instances when a group is deleted, as its
+// instances are deleted with it
+type groupRepository struct {
+	service.AutoscalingGroupRepository
+	reader *reader
+}
+
+func (r *groupRepository) Delete(id string) error {
+	defer r.reader.invalidate(tableInstances)
+	return r.AutoscalingGroupRepository.Delete(id)
+}
+
+// backupRepository drops every cached read when the database is restored
+// or reset
+type backupRepository struct {
+	service.BackupRepository
+	reader *reader
+}
+
+func (r *backupRepository) Restore(name string) error {
+	defer r.reader.flush()
+	return r.BackupRepository.Restore(name)
+}
+
+func (r *backupRepository) Reset() error {
+	defer r.reader.flush()
+	return r.BackupRepository.Reset()
+}
+
+// integrityRepository drops every cached read after a repair, which can
+// delete or change rows of any table
+type integrityRepository struct {
+	service.IntegrityRepository
+	reader *reader
+}
+
+func (r *integrityRepository) CheckReferences(repair bool) ([]domain.IntegrityIssue, error) {
+	if repair {
+		defer r.reader.flush()
+	}
+	return r.IntegrityRepository.CheckReferences(repair)
+}
+
+// cloneAll clones each resource of a list read
+func cloneAll[T any](clone func(*T) *T) func([]*T) []*T {
+	return func(items []*T) []*T {
+		if items == nil {
+			return nil
+		}
+		clones := make([]*T, len(items))
+		for i, item := range items {
+			clones[i] = clone(item)
+		}
+		return clones
+	}
+}
+
+func cloneProject(p *domain.Project) *domain.Project {
+	c := *p
+	c.Labels = cloneLabels(p.Labels)
+	return &c
+}
+
+func cloneInstance(i *domain.Instance) *domain.Instance {
+	c := *i
+	c.Labels = cloneLabels(i.Labels)
+	c.ExpiresAt = cloneTime(i.ExpiresAt)
+	c.DeleteAt = cloneTime(i.DeleteAt)
+	return &c
+}
+
+func cloneMetadata(m *domain.Metadata) *domain.Metadata {
+	c := *m
+	return &c
+}
+
+func cloneLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+	return c
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// AliasRepository handles alias data operations
+type AliasRepository struct {
+	db *DB
+}
+
+// NewAliasRepository creates a new alias repository
+func NewAliasRepository(db *DB) *AliasRepository {
+	return &AliasRepository{db: db}
+}
+
+const aliasSelect = `SELECT external_id, resource_type, resource_id, created_at FROM resource_aliases`
+
+// scanAlias scans a single alias row
+func scanAlias(row interface{ Scan(...interface{}) error }) (*domain.Alias, error) {
+	alias := &domain.Alias{}
+	err := row.Scan(&alias.ExternalID, &alias.ResourceType, &alias.ResourceID, &alias.CreatedAt)
+	return alias, err
+}
+
+// Create creates a new alias
+func (r *AliasRepository) Create(alias *domain.Alias) error {
+	alias.CreatedAt = time.Now()
+
+	query := `INSERT INTO resource_aliases (external_id, resource_type, resource_id, created_at) VALUES (?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, alias.ExternalID, alias.ResourceType, alias.ResourceID, alias.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: resource_aliases.external_id") {
+			return domain.AlreadyExistsError("alias", "external_id", alias.ExternalID).WithCause(err)
+		}
+		return fmt.Errorf("failed to create alias: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves an alias by external ID
+func (r *AliasRepository) Get(externalID string) (*domain.Alias, error) {
+	alias, err := scanAlias(r.db.queryRowStmt(aliasSelect+` WHERE external_id = ?`, externalID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("alias", externalID)
+		}
+		return nil, fmt.Errorf("failed to get alias: %w", err)
+	}
+	return alias, nil
+}
+
+// List retrieves aliases with optional filtering, by external ID
+func (r *AliasRepository) List(opts domain.AliasListOptions) ([]*domain.Alias, error) {
+	var conditions []string
+	var args []interface{}
+
+	if opts.ResourceType != "" {
+		conditions = append(conditions, "resource_type = ?")
+		args = append(args, opts.ResourceType)
+	}
+	if opts.ResourceID != "" {
+		conditions = append(conditions, "resource_id = ?")
+		args = append(args, opts.ResourceID)
+	}
+
+	query := aliasSelect
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY external_id"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := []*domain.Alias{}
+	for rows.Next() {
+		alias, err := scanAlias(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alias: %w", err)
+		}
+		aliases = append(aliases, alias)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating aliases: %w", err)
+	}
+
+	return aliases, nil
+}
+
+// Delete deletes an alias by external ID, leaving its resource in place
+func (r *AliasRepository) Delete(externalID string) error {
+	result, err := r.db.execStmt(`DELETE FROM resource_aliases WHERE external_id = ?`, externalID)
+	if err != nil {
+		return fmt.Errorf("failed to delete alias: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete alias: %w", err)
+	}
+	if n == 0 {
+		return domain.NotFoundError("alias", externalID)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// AutoscalingGroupRepository handles autoscaling group data operations
+type AutoscalingGroupRepository struct {
+	db *DB
+}
+
+// NewAutoscalingGroupRepository creates a new autoscaling group repository
+func NewAutoscalingGroupRepository(db *DB) *AutoscalingGroupRepository {
+	return &AutoscalingGroupRepository{db: db}
+}
+
+const autoscalingGroupSelect = `SELECT id, project_id, name, template_id, min_size, max_size, desired_size, created_at, updated_at FROM autoscaling_groups`
+
+// scanAutoscalingGroup scans a single autoscaling group row
+func scanAutoscalingGroup(row interface{ Scan(...interface{}) error }) (*domain.AutoscalingGroup, error) {
+	group := &domain.AutoscalingGroup{}
+	err := row.Scan(
+		&group.ID,
+		&group.ProjectID,
+		&group.Name,
+		&group.TemplateID,
+		&group.MinSize,
+		&group.MaxSize,
+		&group.DesiredSize,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	return group, err
+}
+
+// Create creates a new autoscaling group
+func (r *AutoscalingGroupRepository) Create(group *domain.AutoscalingGroup) error {
+	now := time.Now()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+
+	query := `INSERT INTO autoscaling_groups (id, project_id, name, template_id, min_size, max_size, desired_size, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, group.ID, group.ProjectID, group.Name, group.TemplateID, group.MinSize, group.MaxSize, group.DesiredSize, group.CreatedAt, group.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: autoscaling_groups.project_id, autoscaling_groups.name") {
+			return domain.AlreadyExistsError("autoscaling group", "name", group.Name).WithCause(err)
+		}
+		return fmt.Errorf("failed to create autoscaling group: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an autoscaling group by ID
+func (r *AutoscalingGroupRepository) GetByID(id string) (*domain.AutoscalingGroup, error) {
+	group, err := scanAutoscalingGroup(r.db.queryRowStmt(autoscalingGroupSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("autoscaling group", id)
+		}
+		return nil, fmt.Errorf("failed to get autoscaling group: %w", err)
+	}
+
+	return group, nil
+}
+
+// List retrieves autoscaling groups with optional filtering
+func (r *AutoscalingGroupRepository) List(opts domain.AutoscalingGroupListOptions) ([]*domain.AutoscalingGroup, error) {
+	var groups []*domain.AutoscalingGroup
+	var args []interface{}
+
+	query := autoscalingGroupSelect
+	var conditions []string
+
+	if opts.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, opts.ProjectID)
+	}
+
+	if opts.Name != "" {
+		conditions = append(conditions, "name = ?")
+		args = append(args, opts.Name)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY name, id"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list autoscaling groups: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group, err := scanAutoscalingGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan autoscaling group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating autoscaling groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// Update updates an existing autoscaling group
+func (r *AutoscalingGroupRepository) Update(id string, req domain.UpdateAutoscalingGroupRequest) (*domain.AutoscalingGroup, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.TemplateID != nil {
+		existing.TemplateID = *req.TemplateID
+	}
+	if req.MinSize != nil {
+		existing.MinSize = *req.MinSize
+	}
+	if req.MaxSize != nil {
+		existing.MaxSize = *req.MaxSize
+	}
+	if req.DesiredSize != nil {
+		existing.DesiredSize = *req.DesiredSize
+	}
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE autoscaling_groups SET template_id = ?, min_size = ?, max_size = ?, desired_size = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.TemplateID, existing.MinSize, existing.MaxSize, existing.DesiredSize, existing.UpdatedAt, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return nil, domain.ForeignKeyViolationError("instance template", "id", existing.TemplateID).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to update autoscaling group: %w", err)
+	}
+
+	return existing, nil
+}
+
+// Delete deletes an autoscaling group and its member instances
+func (r *AutoscalingGroupRepository) Delete(id string) error {
+	return r.db.WithTx(func(tx *DB) error {
+		if _, err := tx.execStmt(`DELETE FROM instances WHERE autoscaling_group_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete autoscaling group members: %w", err)
+		}
+		return tx.deleteByID("autoscaling_groups", "autoscaling group", id)
+	})
+}
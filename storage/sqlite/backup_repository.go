@@ -0,0 +1,197 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/mattn/go-sqlite3"
+)
+
+// backupPagesPerStep is how many pages are copied per backup step. Copying in
+// steps lets writers make progress while a backup runs.
+const backupPagesPerStep = 256
+
+// backupTimeFormat is the timestamp format of snapshot file names
+const backupTimeFormat = "20060102T150405.000Z"
+
+// BackupRepository writes and restores database snapshots in a directory
+type BackupRepository struct {
+	db  *DB
+	dir string
+}
+
+// NewBackupRepository creates a new backup repository storing snapshots in dir
+func NewBackupRepository(db *DB, dir string) *BackupRepository {
+	return &BackupRepository{db: db, dir: dir}
+}
+
+// Create writes a timestamped snapshot of the database using SQLite's online
+// backup API
+func (r *BackupRepository) Create() (*domain.Backup, error) {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now().UTC()
+	name := "dirt-" + strings.Replace(now.Format(backupTimeFormat), ".", "", 1) + ".db"
+	path := filepath.Join(r.dir, name)
+
+	snapshot, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+	defer snapshot.Close()
+
+	if err := copyDatabase(snapshot, r.db.DB); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	return r.get(name)
+}
+
+// List retrieves the snapshots in the backup directory, oldest first
+func (r *BackupRepository) List() ([]*domain.Backup, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []*domain.Backup
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "dirt-") || filepath.Ext(entry.Name()) != ".db" {
+			continue
+		}
+		backup, err := r.get(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name < backups[j].Name })
+	return backups, nil
+}
+
+// Restore replaces the contents of the database with a snapshot and migrates
+// the restored schema to the latest version
+func (r *BackupRepository) Restore(name string) error {
+	if _, err := r.get(name); err != nil {
+		return err
+	}
+
+	snapshot, err := sql.Open("sqlite3", "file:"+filepath.Join(r.dir, name)+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer snapshot.Close()
+
+	if err := copyDatabase(r.db.DB, snapshot); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	if err := r.db.Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate restored backup: %w", err)
+	}
+
+	return nil
+}
+
+// Reset replaces the contents of the database with an empty database and
+// migrates it to the latest schema
+func (r *BackupRepository) Reset() error {
+	empty, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return fmt.Errorf("failed to reset database: %w", err)
+	}
+	defer empty.Close()
+
+	if err := copyDatabase(r.db.DB, empty); err != nil {
+		return fmt.Errorf("failed to reset database: %w", err)
+	}
+
+	if err := r.db.Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate reset database: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a snapshot
+func (r *BackupRepository) Delete(name string) error {
+	if _, err := r.get(name); err != nil {
+		return err
+	}
+
+	if err := os.Remove(filepath.Join(r.dir, name)); err != nil {
+		return fmt.Errorf("failed to delete backup: %w", err)
+	}
+
+	return nil
+}
+
+// get describes a snapshot in the backup directory
+func (r *BackupRepository) get(name string) (*domain.Backup, error) {
+	info, err := os.Stat(filepath.Join(r.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, domain.NotFoundError("backup", name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to get backup: %w", err)
+	}
+
+	return &domain.Backup{
+		Name:      name,
+		SizeBytes: info.Size(),
+		CreatedAt: info.ModTime().UTC(),
+	}, nil
+}
+
+// copyDatabase copies the main database of src into dst with the online backup API
+func copyDatabase(dst, src *sql.DB) error {
+	ctx := context.Background()
+
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(dstDriver interface{}) error {
+		return srcConn.Raw(func(srcDriver interface{}) error {
+			backup, err := dstDriver.(*sqlite3.SQLiteConn).Backup("main", srcDriver.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+
+			for {
+				done, err := backup.Step(backupPagesPerStep)
+				if err != nil {
+					backup.Close()
+					return err
+				}
+				if done {
+					break
+				}
+			}
+
+			return backup.Finish()
+		})
+	})
+}
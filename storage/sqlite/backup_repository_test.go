@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupBackupTest creates a file database and a backup repository writing to
+// a temporary directory. Backups need a file database: every pooled
+// connection to ":memory:" is a separate database.
+func setupBackupTest(t *testing.T) (*DB, *BackupRepository) {
+	t.Helper()
+
+	dir := t.TempDir()
+	db, err := NewDB("file:" + filepath.Join(dir, "dirt.db") + "?_fk=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db, NewBackupRepository(db, filepath.Join(dir, "backups"))
+}
+
+func createBackupTestProject(t *testing.T, db *DB, id, name string) {
+	t.Helper()
+
+	now := time.Now()
+	require.NoError(t, NewProjectRepository(db).Create(&domain.Project{ID: id, Name: name, CreatedAt: now, UpdatedAt: now}))
+}
+
+func TestBackupRepository_CreateAndRestore(t *testing.T) {
+	db, repo := setupBackupTest(t)
+	projects := NewProjectRepository(db)
+
+	createBackupTestProject(t, db, "proj-1", "before-backup")
+
+	backup, err := repo.Create()
+	require.NoError(t, err)
+	assert.Regexp(t, `^dirt-\d{8}T\d{9}Z\.db$`, backup.Name)
+	assert.Positive(t, backup.SizeBytes)
+
+	createBackupTestProject(t, db, "proj-2", "after-backup")
+
+	require.NoError(t, repo.Restore(backup.Name))
+
+	_, err = projects.GetByID("proj-1")
+	assert.NoError(t, err)
+	_, err = projects.GetByID("proj-2")
+	assert.True(t, domain.IsNotFound(err), "project created after the backup should be gone")
+
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	latest, err := LatestSchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+}
+
+func TestBackupRepository_List(t *testing.T) {
+	_, repo := setupBackupTest(t)
+
+	backups, err := repo.List()
+	require.NoError(t, err)
+	assert.Empty(t, backups, "a missing backup directory has no backups")
+
+	first, err := repo.Create()
+	require.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	second, err := repo.Create()
+	require.NoError(t, err)
+
+	// Unrelated files are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(repo.dir, "notes.txt"), []byte("x"), 0o644))
+
+	backups, err = repo.List()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, first.Name, backups[0].Name)
+	assert.Equal(t, second.Name, backups[1].Name)
+
+	require.NoError(t, repo.Delete(first.Name))
+	backups, err = repo.List()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, second.Name, backups[0].Name)
+}
+
+func TestBackupRepository_NotFound(t *testing.T) {
+	_, repo := setupBackupTest(t)
+
+	assert.True(t, domain.IsNotFound(repo.Restore("dirt-missing.db")))
+	assert.True(t, domain.IsNotFound(repo.Delete("dirt-missing.db")))
+}
+
+func TestBackupRepository_Reset(t *testing.T) {
+	db, repo := setupBackupTest(t)
+	projects := NewProjectRepository(db)
+
+	createBackupTestProject(t, db, "proj-1", "before-reset")
+	backup, err := repo.Create()
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Reset())
+
+	list, err := projects.List(domain.ProjectListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	latest, err := LatestSchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+
+	// Snapshots survive a reset
+	require.NoError(t, repo.Restore(backup.Name))
+	_, err = projects.GetByID("proj-1")
+	assert.NoError(t, err)
+}
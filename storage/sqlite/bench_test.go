@@ -0,0 +1,191 @@
+package sqlite
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// benchmarkInstances is how many instances the benchmarks seed, spread over
+// benchmarkProjects projects
+const (
+	benchmarkInstances = 5000
+	benchmarkProjects  = 50
+)
+
+// setupBenchmarkDB creates a file database seeded with projects, instances,
+// events and metadata. A file database, unlike an in-memory one, is what the
+// server runs against.
+func setupBenchmarkDB(b *testing.B) *DB {
+	b.Helper()
+
+	db, err := NewDB("file:" + filepath.Join(b.TempDir(), "bench.db") + "?_fk=1")
+	require.NoError(b, err)
+	b.Cleanup(func() { db.Close() })
+
+	now := time.Now()
+	statuses := []string{domain.StatusRunning, domain.StatusStopped}
+
+	err = db.WithTx(func(tx *DB) error {
+		projects, instances, metadata, events := NewProjectRepository(tx), NewInstanceRepository(tx), NewMetadataRepository(tx), NewEventRepository(tx)
+		for p := 0; p < benchmarkProjects; p++ {
+			if err := projects.Create(&domain.Project{ID: fmt.Sprintf("p%d", p), Name: fmt.Sprintf("project-%d", p), CreatedAt: now, UpdatedAt: now}); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < benchmarkInstances; i++ {
+			err := instances.Create(&domain.Instance{
+				ID: fmt.Sprintf("i%d", i), ProjectID: fmt.Sprintf("p%d", i%benchmarkProjects), Name: fmt.Sprintf("vm-%d", i),
+				CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: statuses[i%len(statuses)], CreatedAt: now, UpdatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+			err = events.Create(&domain.Event{
+				ID: fmt.Sprintf("e%d", i), Type: "instance.created", ResourceType: "instance", ResourceID: fmt.Sprintf("i%d", i),
+				ProjectID: fmt.Sprintf("p%d", i%benchmarkProjects), Message: "created", CreatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := metadata.Create(fmt.Sprintf("m%d", i), domain.CreateMetadataRequest{Path: fmt.Sprintf("/app/%d/key-%d", i%benchmarkProjects, i), Value: "v"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(b, err)
+
+	return db
+}
+
+func BenchmarkInstanceRepository_GetByID(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewInstanceRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.GetByID(fmt.Sprintf("i%d", i%benchmarkInstances)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInstanceRepository_List(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewInstanceRepository(db)
+
+	benchmarks := []struct {
+		name string
+		opts domain.InstanceListOptions
+	}{
+		{"by project", domain.InstanceListOptions{ProjectID: "p7"}},
+		{"by name", domain.InstanceListOptions{Name: "vm-4242"}},
+		{"by project and status", domain.InstanceListOptions{ProjectID: "p7", Status: domain.StatusStopped}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.List(bm.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMetadataRepository_ListPrefix(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewMetadataRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.List(domain.MetadataListOptions{Prefix: "/app/7/"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInstanceRepository_CreateDelete(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewInstanceRepository(db)
+	now := time.Now()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		instance := &domain.Instance{ID: "bench", ProjectID: "p0", Name: "bench", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusRunning, CreatedAt: now, UpdatedAt: now}
+		if err := repo.Create(instance); err != nil {
+			b.Fatal(err)
+		}
+		if err := repo.Delete(instance.ID); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProjectRepository_GetByID(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewProjectRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.GetByID(fmt.Sprintf("p%d", i%benchmarkProjects)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProjectRepository_List(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewProjectRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.List(domain.ProjectListOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProjectRepository_Update(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewProjectRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		labels := map[string]string{"seq": fmt.Sprint(i)}
+		if _, err := repo.Update(fmt.Sprintf("p%d", i%benchmarkProjects), domain.UpdateProjectRequest{Labels: labels}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInstanceRepository_Update(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewInstanceRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		labels := map[string]string{"seq": fmt.Sprint(i)}
+		if _, err := repo.Update(fmt.Sprintf("i%d", i%benchmarkInstances), domain.UpdateInstanceRequest{Labels: labels}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEventRepository_ListByProject(b *testing.B) {
+	db := setupBenchmarkDB(b)
+	repo := NewEventRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.List(domain.EventListOptions{ProjectID: fmt.Sprintf("p%d", i%benchmarkProjects)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
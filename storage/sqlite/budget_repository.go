@@ -0,0 +1,178 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// BudgetRepository handles budget data operations
+type BudgetRepository struct {
+	db *DB
+}
+
+// NewBudgetRepository creates a new budget repository
+func NewBudgetRepository(db *DB) *BudgetRepository {
+	return &BudgetRepository{db: db}
+}
+
+const budgetSelect = `SELECT id, project_id, name, amount, thresholds, webhook_url, status, spend, period, crossed_thresholds, created_at, updated_at FROM budgets`
+
+// scanBudget scans a single budget row
+func scanBudget(row interface{ Scan(...interface{}) error }) (*domain.Budget, error) {
+	budget := &domain.Budget{}
+	err := row.Scan(
+		&budget.ID,
+		&budget.ProjectID,
+		&budget.Name,
+		&budget.Amount,
+		jsonColumn{&budget.Thresholds},
+		&budget.WebhookURL,
+		&budget.Status,
+		&budget.Spend,
+		&budget.Period,
+		jsonColumn{&budget.CrossedThresholds},
+		&budget.CreatedAt,
+		&budget.UpdatedAt,
+	)
+	return budget, err
+}
+
+// Create creates a new budget
+func (r *BudgetRepository) Create(budget *domain.Budget) error {
+	now := time.Now()
+	budget.CreatedAt = now
+	budget.UpdatedAt = now
+
+	query := `INSERT INTO budgets (id, project_id, name, amount, thresholds, webhook_url, status, spend, period, crossed_thresholds, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, budget.ID, budget.ProjectID, budget.Name, budget.Amount, jsonColumn{budget.Thresholds}, budget.WebhookURL,
+		budget.Status, budget.Spend, budget.Period, jsonColumn{budget.CrossedThresholds}, budget.CreatedAt, budget.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: budgets.project_id, budgets.name") {
+			return domain.AlreadyExistsError("budget", "name", budget.Name).WithCause(err)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.ForeignKeyViolationError("project", "id", budget.ProjectID).WithCause(err)
+		}
+		return fmt.Errorf("failed to create budget: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a budget by ID
+func (r *BudgetRepository) GetByID(id string) (*domain.Budget, error) {
+	budget, err := scanBudget(r.db.queryRowStmt(budgetSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("budget", id)
+		}
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	return budget, nil
+}
+
+// List retrieves budgets with optional filtering
+func (r *BudgetRepository) List(opts domain.BudgetListOptions) ([]*domain.Budget, error) {
+	var budgets []*domain.Budget
+	var args []interface{}
+
+	query := budgetSelect
+	var conditions []string
+
+	if opts.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, opts.ProjectID)
+	}
+
+	if opts.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, opts.Status)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY name, id"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, budget)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating budgets: %w", err)
+	}
+
+	return budgets, nil
+}
+
+// Update updates the settings of an existing budget
+func (r *BudgetRepository) Update(id string, req domain.UpdateBudgetRequest) (*domain.Budget, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Amount != nil {
+		existing.Amount = *req.Amount
+	}
+	if req.Thresholds != nil {
+		existing.Thresholds = req.Thresholds
+	}
+	if req.WebhookURL != nil {
+		existing.WebhookURL = *req.WebhookURL
+	}
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE budgets SET name = ?, amount = ?, thresholds = ?, webhook_url = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, existing.Amount, jsonColumn{existing.Thresholds}, existing.WebhookURL, existing.UpdatedAt, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: budgets.project_id, budgets.name") {
+			return nil, domain.AlreadyExistsError("budget", "name", existing.Name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to update budget: %w", err)
+	}
+
+	return existing, nil
+}
+
+// SetState records the evaluated state of a budget
+func (r *BudgetRepository) SetState(id string, state domain.BudgetState) error {
+	query := `UPDATE budgets SET status = ?, spend = ?, period = ?, crossed_thresholds = ?, updated_at = ? WHERE id = ?`
+
+	result, err := r.db.execStmt(query, state.Status, state.Spend, state.Period, jsonColumn{state.CrossedThresholds}, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to set budget state: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.NotFoundError("budget", id)
+	}
+
+	return nil
+}
+
+// Delete deletes a budget by ID
+func (r *BudgetRepository) Delete(id string) error {
+	return r.db.deleteByID("budgets", "budget", id)
+}
@@ -0,0 +1,214 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// columnSet is an allowlist of fields that may be selected or sorted on for a
+// resource, mapped to their SQL columns. User input never reaches the query
+// text except through this mapping.
+type columnSet struct {
+	resource string
+	fields   []string          // field names in default select order
+	columns  map[string]string // field name -> SQL column
+}
+
+// newColumnSet creates a column set where field names match column names
+func newColumnSet(resource string, fields ...string) columnSet {
+	columns := make(map[string]string, len(fields))
+	for _, f := range fields {
+		columns[f] = f
+	}
+	return columnSet{resource: resource, fields: fields, columns: columns}
+}
+
+// selectFields validates the requested fields and returns them in default
+// order. The id field is always included; no fields selects all of them.
+func (c columnSet) selectFields(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return c.fields, nil
+	}
+
+	wanted := map[string]bool{"id": true}
+	var v domain.FieldViolations
+	for _, f := range requested {
+		if _, ok := c.columns[f]; !ok {
+			v.Add("fields", "unknown field '"+f+"' for "+c.resource)
+			continue
+		}
+		wanted[f] = true
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, f := range c.fields {
+		if wanted[f] {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// selectList returns the SQL select list for the given fields
+func (c columnSet) selectList(fields []string) string {
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		cols[i] = c.columns[f]
+	}
+	return strings.Join(cols, ", ")
+}
+
+// sortKey validates the requested sort fields, falling back to the given
+// field when none are requested, and returns the fields results are ordered
+// by. It always includes id, so that the order is total.
+func (c columnSet) sortKey(sort []domain.SortField, fallback string) ([]domain.SortField, error) {
+	if len(sort) == 0 {
+		sort = []domain.SortField{{Field: fallback}}
+	}
+
+	var v domain.FieldViolations
+	var key []domain.SortField
+	hasID := false
+	for _, s := range sort {
+		if _, ok := c.columns[s.Field]; !ok {
+			v.Add("sort", "unknown sort field '"+s.Field+"' for "+c.resource)
+			continue
+		}
+		key = append(key, s)
+		hasID = hasID || s.Field == "id"
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	// Break ties on id so ordering is stable
+	if !hasID {
+		key = append(key, domain.SortField{Field: "id"})
+	}
+	return key, nil
+}
+
+// orderBy builds the ORDER BY clause of a sort key
+func (c columnSet) orderBy(key []domain.SortField) string {
+	terms := make([]string, len(key))
+	for i, s := range key {
+		if s.Desc {
+			terms[i] = c.columns[s.Field] + " DESC"
+		} else {
+			terms[i] = c.columns[s.Field] + " ASC"
+		}
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// after builds the condition selecting the rows that come after the result
+// a page token was made from, in the order of key. ptrs are the field
+// pointers of the resource the token was decoded into, and fields the key
+// fields it holds. NULLs sort first, as SQLite orders them.
+func (c columnSet) after(key []domain.SortField, ptrs map[string]interface{}, fields []string) (string, []interface{}, error) {
+	held := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		held[f] = true
+	}
+
+	values := make([]interface{}, len(key))
+	for i, s := range key {
+		if !held[s.Field] {
+			return "", nil, domain.ValidationError([]domain.FieldViolation{
+				{Field: "page_token", Message: "does not match the sort order of the listing"},
+			})
+		}
+		value, err := fieldValue(ptrs[s.Field])
+		if err != nil {
+			return "", nil, err
+		}
+		values[i] = value
+	}
+
+	// Rows equal to the token on the first fields of the key and after it
+	// on the next one
+	var alternatives []string
+	var args []interface{}
+	for i, s := range key {
+		col := c.columns[s.Field]
+		var terms []string
+		var termArgs []interface{}
+		for j := 0; j < i; j++ {
+			terms = append(terms, c.columns[key[j].Field]+" IS ?")
+			termArgs = append(termArgs, values[j])
+		}
+
+		switch {
+		case !s.Desc && values[i] == nil:
+			terms = append(terms, col+" IS NOT NULL")
+		case !s.Desc:
+			terms = append(terms, col+" > ?")
+			termArgs = append(termArgs, values[i])
+		case values[i] == nil:
+			// Nothing sorts after NULL in descending order
+			continue
+		default:
+			terms = append(terms, "("+col+" < ? OR "+col+" IS NULL)")
+			termArgs = append(termArgs, values[i])
+		}
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+		args = append(args, termArgs...)
+	}
+	if len(alternatives) == 0 {
+		return "0", nil, nil
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// fieldValue returns the value a field pointer holds as a query argument
+func fieldValue(ptr interface{}) (interface{}, error) {
+	if valuer, ok := ptr.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	v := reflect.ValueOf(ptr).Elem()
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface(), nil
+}
+
+// scanTargets returns scan destinations for the given fields
+func scanTargets(ptrs map[string]interface{}, fields []string) []interface{} {
+	targets := make([]interface{}, len(fields))
+	for i, f := range fields {
+		targets[i] = ptrs[f]
+	}
+	return targets
+}
+
+// likeEscaper escapes LIKE wildcards; patterns using it need ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern returns a LIKE pattern matching values that contain s
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// limitOffset builds a LIMIT/OFFSET clause; a non-positive limit returns
+// every row after the offset
+func limitOffset(limit, offset int) (string, []interface{}) {
+	if limit <= 0 && offset <= 0 {
+		return "", nil
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return " LIMIT ? OFFSET ?", []interface{}{limit, offset}
+}
@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// DatabaseRepository handles managed database data operations
+type DatabaseRepository struct {
+	db *DB
+}
+
+// NewDatabaseRepository creates a new database repository
+func NewDatabaseRepository(db *DB) *DatabaseRepository {
+	return &DatabaseRepository{db: db}
+}
+
+const databaseSelect = `SELECT id, project_id, name, engine, version, size, status, host, port, database_name, username, password, connection_string, ready_at, created_at, updated_at FROM databases`
+
+// scanDatabase scans a single database row
+func scanDatabase(row interface{ Scan(...interface{}) error }) (*domain.Database, error) {
+	database := &domain.Database{}
+	err := row.Scan(
+		&database.ID,
+		&database.ProjectID,
+		&database.Name,
+		&database.Engine,
+		&database.Version,
+		&database.Size,
+		&database.Status,
+		&database.Host,
+		&database.Port,
+		&database.DatabaseName,
+		&database.Username,
+		&database.Password,
+		&database.ConnectionString,
+		&database.ReadyAt,
+		&database.CreatedAt,
+		&database.UpdatedAt,
+	)
+	return database, err
+}
+
+// queryDatabases runs a database query and scans every row
+func (r *DatabaseRepository) queryDatabases(query string, args ...interface{}) ([]*domain.Database, error) {
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+	defer rows.Close()
+
+	var databases []*domain.Database
+	for rows.Next() {
+		database, err := scanDatabase(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan database: %w", err)
+		}
+		databases = append(databases, database)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating databases: %w", err)
+	}
+
+	return databases, nil
+}
+
+// Create creates a new database
+func (r *DatabaseRepository) Create(database *domain.Database) error {
+	now := time.Now()
+	database.CreatedAt = now
+	database.UpdatedAt = now
+
+	query := `INSERT INTO databases (id, project_id, name, engine, version, size, status, host, port, database_name, username, password, connection_string, ready_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, database.ID, database.ProjectID, database.Name, database.Engine, database.Version, database.Size, database.Status,
+		database.Host, database.Port, database.DatabaseName, database.Username, database.Password, database.ConnectionString,
+		database.ReadyAt, database.CreatedAt, database.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: databases.project_id, databases.name") {
+			return domain.AlreadyExistsError("database", "name", database.Name).WithCause(err)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.ForeignKeyViolationError("project", "id", database.ProjectID).WithCause(err)
+		}
+		return fmt.Errorf("failed to create database: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a database by ID
+func (r *DatabaseRepository) GetByID(id string) (*domain.Database, error) {
+	database, err := scanDatabase(r.db.queryRowStmt(databaseSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("database", id)
+		}
+		return nil, fmt.Errorf("failed to get database: %w", err)
+	}
+
+	return database, nil
+}
+
+// List retrieves databases with optional filtering
+func (r *DatabaseRepository) List(opts domain.DatabaseListOptions) ([]*domain.Database, error) {
+	var args []interface{}
+
+	query := databaseSelect
+	var conditions []string
+
+	if opts.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, opts.ProjectID)
+	}
+
+	if opts.Engine != "" {
+		conditions = append(conditions, "engine = ?")
+		args = append(args, opts.Engine)
+	}
+
+	if opts.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, opts.Status)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY name, id"
+
+	return r.queryDatabases(query, args...)
+}
+
+// ListReady retrieves the databases whose provisioning completes at or before now
+func (r *DatabaseRepository) ListReady(now time.Time) ([]*domain.Database, error) {
+	return r.queryDatabases(databaseSelect+` WHERE ready_at IS NOT NULL AND ready_at <= ? ORDER BY ready_at, id`, now)
+}
+
+// Update updates the name and size of an existing database
+func (r *DatabaseRepository) Update(id string, req domain.UpdateDatabaseRequest) (*domain.Database, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Size != nil {
+		existing.Size = *req.Size
+	}
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE databases SET name = ?, size = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, existing.Size, existing.UpdatedAt, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: databases.project_id, databases.name") {
+			return nil, domain.AlreadyExistsError("database", "name", existing.Name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to update database: %w", err)
+	}
+
+	return existing, nil
+}
+
+// SetStatus sets the status of a database and when it becomes available
+func (r *DatabaseRepository) SetStatus(id, status string, readyAt *time.Time) error {
+	result, err := r.db.execStmt(`UPDATE databases SET status = ?, ready_at = ?, updated_at = ? WHERE id = ?`, status, readyAt, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to set database status: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.NotFoundError("database", id)
+	}
+
+	return nil
+}
+
+// Delete deletes a database by ID
+func (r *DatabaseRepository) Delete(id string) error {
+	return r.db.deleteByID("databases", "database", id)
+}
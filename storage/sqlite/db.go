@@ -3,7 +3,9 @@ package sqlite
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
+	"github.com/hypertf/dirtcloud-server/domain"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -14,15 +16,44 @@ const (
 // DB wraps the SQLite database connection
 type DB struct {
 	*sql.DB
+
+	// tx is set on handles bound to a transaction by WithTx
+	tx *sql.Tx
+
+	// stmts holds the statements prepared by the repositories
+	stmts *statements
+
+	// trace, when set, records the statements run through the handle
+	trace *domain.StatementTrace
+
+	// duplicateInstanceNames drops the unique index on instance names in
+	// each project; see SetUniqueInstanceNames
+	duplicateInstanceNames bool
 }
 
-// NewDB creates a new SQLite database connection and initializes the schema
+// NewDB creates a new SQLite database connection and migrates the schema to
+// the latest version
 func NewDB(dsn string) (*DB, error) {
+	db, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	return db, nil
+}
+
+// Open creates a new SQLite database connection without touching the schema
+func Open(dsn string) (*DB, error) {
 	if dsn == "" {
 		dsn = defaultDSN
 	}
 
-	db, err := sql.Open("sqlite3", dsn)
+	db, err := sql.Open("sqlite3", withDefaultParams(dsn))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -46,53 +77,129 @@ func NewDB(dsn string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	sqliteDB := &DB{DB: db}
+	return &DB{DB: db, stmts: newStatements()}, nil
+}
 
-	// Initialize schema
-	if err := sqliteDB.initSchema(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+// withDefaultParams adds the connection parameters transactions rely on unless
+// the DSN sets them. Transactions take the write lock when they begin so two
+// of them cannot deadlock upgrading from a read, and writers wait for the lock
+// rather than failing at once.
+func withDefaultParams(dsn string) string {
+	var params []string
+	if !strings.Contains(dsn, "_txlock=") {
+		params = append(params, "_txlock=immediate")
+	}
+	if !strings.Contains(dsn, "_busy_timeout=") && !strings.Contains(dsn, "_timeout=") {
+		params = append(params, "_busy_timeout=5000")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + strings.Join(params, "&")
+}
+
+// ensureColumn adds a column to an existing table if it is not already present
+func (db *DB) ensureColumn(table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan column info: %w", err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
 	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
 
-	return sqliteDB, nil
+// SetUniqueInstanceNames chooses whether the schema rejects two instances with
+// the same name in one project. Migrate reapplies the choice, so it survives
+// restoring a backup or resetting the database. Turning uniqueness back on
+// fails while duplicate names exist.
+func (db *DB) SetUniqueInstanceNames(unique bool) error {
+	previous := db.duplicateInstanceNames
+	db.duplicateInstanceNames = !unique
+	if err := db.applyInstanceNameIndex(); err != nil {
+		db.duplicateInstanceNames = previous
+		return err
+	}
+	return nil
 }
 
-// initSchema creates the necessary tables if they don't exist
-func (db *DB) initSchema() error {
-	schemas := []string{
-		`CREATE TABLE IF NOT EXISTS projects (
-			id TEXT PRIMARY KEY,
-			name TEXT UNIQUE NOT NULL,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS instances (
-			id TEXT PRIMARY KEY,
-			project_id TEXT NOT NULL,
-			name TEXT NOT NULL,
-			cpu INTEGER NOT NULL,
-			memory_mb INTEGER NOT NULL,
-			image TEXT NOT NULL,
-			status TEXT NOT NULL DEFAULT 'running',
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
-			UNIQUE(project_id, name)
-		)`,
-		`CREATE TABLE IF NOT EXISTS metadata (
-			id TEXT PRIMARY KEY,
-			path TEXT NOT NULL UNIQUE,
-			value TEXT NOT NULL,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-		)`,
-	}
-
-	for _, schema := range schemas {
-		if _, err := db.Exec(schema); err != nil {
-			return fmt.Errorf("failed to create table: %w", err)
+// applyInstanceNameIndex recreates the instance name index if it does not
+// match the configured uniqueness
+func (db *DB) applyInstanceNameIndex() error {
+	var definition string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'index' AND name = 'idx_instances_project_name'`).Scan(&definition)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to inspect instance name index: %w", err)
+	}
+
+	create := "CREATE UNIQUE INDEX"
+	if db.duplicateInstanceNames {
+		create = "CREATE INDEX"
+	}
+	if strings.HasPrefix(definition, create+" ") {
+		return nil
+	}
+
+	return db.WithTx(func(tx *DB) error {
+		if _, err := tx.Exec(`DROP INDEX IF EXISTS idx_instances_project_name`); err != nil {
+			return fmt.Errorf("failed to drop instance name index: %w", err)
 		}
+		if _, err := tx.Exec(create + ` idx_instances_project_name ON instances(project_id, name)`); err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return domain.FailedPreconditionError("instances with duplicate names exist in a project", nil).WithCause(err)
+			}
+			return fmt.Errorf("failed to create instance name index: %w", err)
+		}
+		return nil
+	})
+}
+
+// deleteByID deletes the row of table with id, reporting resource as not
+// found when there is none. Checking the rows affected spares a lookup
+// before deleting.
+func (db *DB) deleteByID(table, resource, id string) error {
+	result, err := db.execStmt(`DELETE FROM `+table+` WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", resource, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", resource, err)
+	}
+	if n == 0 {
+		return domain.NotFoundError(resource, id)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_SetUniqueInstanceNames(t *testing.T) {
+	db := setupFileDB(t)
+	require.NoError(t, NewProjectRepository(db).Create(&domain.Project{ID: "p1", Name: "one"}))
+	instances := NewInstanceRepository(db)
+
+	create := func(id string) error {
+		return instances.Create(&domain.Instance{ID: id, ProjectID: "p1", Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusRunning})
+	}
+
+	require.NoError(t, create("i1"))
+	err := create("i2")
+	require.True(t, domain.IsAlreadyExists(err), "names are unique by default, got %v", err)
+	assert.Equal(t, "p1", err.(*domain.DirtError).Details["project_id"])
+
+	require.NoError(t, db.SetUniqueInstanceNames(false))
+	require.NoError(t, create("i2"))
+
+	// The setting survives a migration, as run after a restore or reset
+	require.NoError(t, db.Migrate())
+	require.NoError(t, create("i3"))
+
+	err = db.SetUniqueInstanceNames(true)
+	assert.True(t, domain.IsFailedPrecondition(err), "duplicates block uniqueness, got %v", err)
+
+	_, err = db.Exec(`DELETE FROM instances WHERE id != 'i1'`)
+	require.NoError(t, err)
+	require.NoError(t, db.SetUniqueInstanceNames(true))
+	assert.True(t, domain.IsAlreadyExists(create("i4")))
+}
@@ -0,0 +1,222 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// EnvironmentRepository handles environment data operations
+type EnvironmentRepository struct {
+	db *DB
+}
+
+// NewEnvironmentRepository creates a new environment repository
+func NewEnvironmentRepository(db *DB) *EnvironmentRepository {
+	return &EnvironmentRepository{db: db}
+}
+
+const environmentSelect = `SELECT id, name, labels, created_at, updated_at FROM environments`
+
+// scanEnvironment scans a single environment row, without its resources
+func scanEnvironment(row interface{ Scan(...interface{}) error }) (*domain.Environment, error) {
+	env := &domain.Environment{}
+	err := row.Scan(
+		&env.ID,
+		&env.Name,
+		jsonColumn{&env.Labels},
+		&env.CreatedAt,
+		&env.UpdatedAt,
+	)
+	return env, err
+}
+
+// Create creates a new environment. Its resources are added separately.
+func (r *EnvironmentRepository) Create(env *domain.Environment) error {
+	now := time.Now()
+	env.CreatedAt = now
+	env.UpdatedAt = now
+	env.Resources = []domain.ResourceRef{}
+
+	query := `INSERT INTO environments (id, name, labels, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, env.ID, env.Name, jsonColumn{env.Labels}, env.CreatedAt, env.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: environments.name") {
+			return domain.AlreadyExistsError("environment", "name", env.Name).WithCause(err)
+		}
+		return fmt.Errorf("failed to create environment: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an environment by ID, with its resources
+func (r *EnvironmentRepository) GetByID(id string) (*domain.Environment, error) {
+	env, err := scanEnvironment(r.db.queryRowStmt(environmentSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("environment", id)
+		}
+		return nil, fmt.Errorf("failed to get environment: %w", err)
+	}
+
+	if env.Resources, err = r.resources(id); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// List retrieves environments with optional filtering, with their resources
+func (r *EnvironmentRepository) List(opts domain.EnvironmentListOptions) ([]*domain.Environment, error) {
+	var envs []*domain.Environment
+	var args []interface{}
+
+	query := environmentSelect
+	if opts.Name != "" {
+		query += " WHERE name = ?"
+		args = append(args, opts.Name)
+	}
+	query += " ORDER BY name"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		env, err := scanEnvironment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan environment: %w", err)
+		}
+		envs = append(envs, env)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating environments: %w", err)
+	}
+	rows.Close()
+
+	for _, env := range envs {
+		if env.Resources, err = r.resources(env.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return envs, nil
+}
+
+// resources lists the resources of an environment, by type and then ID
+func (r *EnvironmentRepository) resources(id string) ([]domain.ResourceRef, error) {
+	query := `SELECT resource_type, resource_id FROM environment_resources WHERE environment_id = ? ORDER BY resource_type, resource_id`
+
+	rows, err := r.db.queryStmt(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environment resources: %w", err)
+	}
+	defer rows.Close()
+
+	refs := []domain.ResourceRef{}
+	for rows.Next() {
+		var ref domain.ResourceRef
+		if err := rows.Scan(&ref.Type, &ref.ID); err != nil {
+			return nil, fmt.Errorf("failed to scan environment resource: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating environment resources: %w", err)
+	}
+
+	return refs, nil
+}
+
+// Update updates an existing environment
+func (r *EnvironmentRepository) Update(id string, req domain.UpdateEnvironmentRequest) (*domain.Environment, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Labels != nil {
+		existing.Labels = req.Labels
+	}
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE environments SET name = ?, labels = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, jsonColumn{existing.Labels}, existing.UpdatedAt, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: environments.name") {
+			return nil, domain.AlreadyExistsError("environment", "name", existing.Name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to update environment: %w", err)
+	}
+
+	return existing, nil
+}
+
+// EnvironmentOf returns the ID of the environment a resource is in, or ""
+func (r *EnvironmentRepository) EnvironmentOf(ref domain.ResourceRef) (string, error) {
+	var id string
+	err := r.db.queryRowStmt(`SELECT environment_id FROM environment_resources WHERE resource_type = ? AND resource_id = ?`, ref.Type, ref.ID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get environment of resource: %w", err)
+	}
+	return id, nil
+}
+
+// AddResource adds a resource to an environment
+func (r *EnvironmentRepository) AddResource(id string, ref domain.ResourceRef) error {
+	query := `INSERT INTO environment_resources (environment_id, resource_type, resource_id, added_at) VALUES (?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, id, ref.Type, ref.ID, time.Now())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: environment_resources") {
+			return domain.AlreadyExistsError("environment resource", "id", ref.ID).WithCause(err)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.NotFoundError("environment", id).WithCause(err)
+		}
+		return fmt.Errorf("failed to add environment resource: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveResource removes a resource from an environment, leaving the
+// resource itself in place
+func (r *EnvironmentRepository) RemoveResource(id string, ref domain.ResourceRef) error {
+	query := `DELETE FROM environment_resources WHERE environment_id = ? AND resource_type = ? AND resource_id = ?`
+
+	result, err := r.db.execStmt(query, id, ref.Type, ref.ID)
+	if err != nil {
+		return fmt.Errorf("failed to remove environment resource: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to remove environment resource: %w", err)
+	}
+	if n == 0 {
+		return domain.NotFoundError(ref.Type, ref.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes an environment by ID, and with it the list of its
+// resources
+func (r *EnvironmentRepository) Delete(id string) error {
+	return r.db.deleteByID("environments", "environment", id)
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// EventRepository handles event data operations
+type EventRepository struct {
+	db *DB
+}
+
+// NewEventRepository creates a new event repository
+func NewEventRepository(db *DB) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+// Create records a new event
+func (r *EventRepository) Create(event *domain.Event) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO events (id, type, resource_type, resource_id, project_id, message, actor, previous_status, status, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := r.db.execStmt(query, event.ID, event.Type, event.ResourceType, event.ResourceID, event.ProjectID, event.Message, event.Actor,
+		event.PreviousStatus, event.Status, event.Reason, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+
+	// The rowid orders events by insertion and serves as their sequence
+	event.Sequence, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read event sequence: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves events in the order they were recorded, with optional filtering
+func (r *EventRepository) List(opts domain.EventListOptions) ([]*domain.Event, error) {
+	var events []*domain.Event
+	var args []interface{}
+
+	query := `SELECT id, type, resource_type, resource_id, project_id, message, actor, previous_status, status, reason, created_at, rowid FROM events`
+	var conditions []string
+
+	if opts.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, opts.Type)
+	}
+
+	if opts.ResourceType != "" {
+		conditions = append(conditions, "resource_type = ?")
+		args = append(args, opts.ResourceType)
+	}
+
+	if opts.ResourceID != "" {
+		conditions = append(conditions, "resource_id = ?")
+		args = append(args, opts.ResourceID)
+	}
+
+	if opts.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, opts.ProjectID)
+	}
+
+	if opts.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, opts.Actor)
+	}
+
+	if opts.Reason != "" {
+		conditions = append(conditions, "reason = ?")
+		args = append(args, opts.Reason)
+	}
+
+	// Timestamps are stored as text with their zone offset, so compare them
+	// as instants rather than as strings
+	if !opts.Since.IsZero() {
+		conditions = append(conditions, "julianday(created_at) >= julianday(?)")
+		args = append(args, opts.Since)
+	}
+
+	if !opts.Until.IsZero() {
+		conditions = append(conditions, "julianday(created_at) < julianday(?)")
+		args = append(args, opts.Until)
+	}
+
+	if opts.AfterSequence > 0 {
+		conditions = append(conditions, "rowid > ?")
+		args = append(args, opts.AfterSequence)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY julianday(created_at), rowid"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event := &domain.Event{}
+		err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.ResourceType,
+			&event.ResourceID,
+			&event.ProjectID,
+			&event.Message,
+			&event.Actor,
+			&event.PreviousStatus,
+			&event.Status,
+			&event.Reason,
+			&event.CreatedAt,
+			&event.Sequence,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRepository_ListFilters(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewEventRepository(db)
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// Stored in a zone other than UTC, as time.Now would on a non-UTC host
+	zone := time.FixedZone("PST", -8*3600)
+	for _, e := range []*domain.Event{
+		{ID: "e1", Type: domain.EventInstanceResized, ResourceType: "instance", ResourceID: "i1", Actor: "admin", CreatedAt: base},
+		{ID: "e2", Type: domain.EventInstanceExpired, ResourceType: "instance", ResourceID: "i1", Actor: domain.ActorReaper, CreatedAt: base.Add(time.Hour).In(zone)},
+		{ID: "e3", Type: domain.EventAutoscalingScaleOut, ResourceType: "autoscaling_group", ResourceID: "g1", Actor: domain.ActorAutoscaler, CreatedAt: base.Add(2 * time.Hour)},
+	} {
+		require.NoError(t, repo.Create(e))
+	}
+
+	ids := func(events []*domain.Event) []string {
+		var out []string
+		for _, e := range events {
+			out = append(out, e.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		opts     domain.EventListOptions
+		expected []string
+	}{
+		{name: "all", expected: []string{"e1", "e2", "e3"}},
+		{name: "actor", opts: domain.EventListOptions{Actor: domain.ActorReaper}, expected: []string{"e2"}},
+		{name: "since is inclusive", opts: domain.EventListOptions{Since: base.Add(time.Hour)}, expected: []string{"e2", "e3"}},
+		{name: "until is exclusive", opts: domain.EventListOptions{Until: base.Add(time.Hour)}, expected: []string{"e1"}},
+		{name: "range compares instants across zones", opts: domain.EventListOptions{Since: base.Add(30 * time.Minute), Until: base.Add(90 * time.Minute)}, expected: []string{"e2"}},
+		{name: "resource type and actor", opts: domain.EventListOptions{ResourceType: "instance", Actor: "admin"}, expected: []string{"e1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := repo.List(tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ids(events))
+		})
+	}
+
+	events, err := repo.List(domain.EventListOptions{ResourceID: "g1"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.ActorAutoscaler, events[0].Actor)
+}
+
+func TestEventRepository_Sequence(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewEventRepository(db)
+	var created []*domain.Event
+	for _, id := range []string{"e1", "e2", "e3"} {
+		event := &domain.Event{ID: id, Type: domain.EventInstanceResized, ResourceType: "instance", ResourceID: "i1"}
+		require.NoError(t, repo.Create(event))
+		created = append(created, event)
+	}
+	assert.Less(t, created[0].Sequence, created[1].Sequence)
+	assert.Less(t, created[1].Sequence, created[2].Sequence)
+
+	events, err := repo.List(domain.EventListOptions{AfterSequence: created[0].Sequence})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "e2", events[0].ID)
+	assert.Equal(t, created[1].Sequence, events[0].Sequence)
+	assert.Equal(t, "e3", events[1].ID)
+}
@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// FolderRepository handles folder data operations
+type FolderRepository struct {
+	db *DB
+}
+
+// NewFolderRepository creates a new folder repository
+func NewFolderRepository(db *DB) *FolderRepository {
+	return &FolderRepository{db: db}
+}
+
+const folderSelect = `SELECT id, organization_id, parent_id, name, labels, quota, created_at, updated_at FROM folders`
+
+// scanFolder scans a single folder row
+func scanFolder(row interface{ Scan(...interface{}) error }) (*domain.Folder, error) {
+	folder := &domain.Folder{}
+	err := row.Scan(
+		&folder.ID,
+		&folder.OrganizationID,
+		&folder.ParentID,
+		&folder.Name,
+		jsonColumn{&folder.Labels},
+		jsonColumn{&folder.Quota},
+		&folder.CreatedAt,
+		&folder.UpdatedAt,
+	)
+	return folder, err
+}
+
+// isFolderNameConflict reports whether err is a folder name uniqueness violation
+func isFolderNameConflict(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed: folders.organization_id, folders.parent_id, folders.name")
+}
+
+// Create creates a new folder
+func (r *FolderRepository) Create(folder *domain.Folder) error {
+	now := time.Now()
+	folder.CreatedAt = now
+	folder.UpdatedAt = now
+
+	query := `INSERT INTO folders (id, organization_id, parent_id, name, labels, quota, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, folder.ID, folder.OrganizationID, folder.ParentID, folder.Name,
+		jsonColumn{folder.Labels}, jsonColumn{folder.Quota}, folder.CreatedAt, folder.UpdatedAt)
+	if err != nil {
+		if isFolderNameConflict(err) {
+			return domain.AlreadyExistsError("folder", "name", folder.Name).WithCause(err)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.ForeignKeyViolationError("organization", "id", folder.OrganizationID).WithCause(err)
+		}
+		return fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a folder by ID
+func (r *FolderRepository) GetByID(id string) (*domain.Folder, error) {
+	folder, err := scanFolder(r.db.queryRowStmt(folderSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("folder", id)
+		}
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+
+	return folder, nil
+}
+
+// List retrieves folders with optional filtering
+func (r *FolderRepository) List(opts domain.FolderListOptions) ([]*domain.Folder, error) {
+	var folders []*domain.Folder
+	var args []interface{}
+
+	query := folderSelect
+	var conditions []string
+
+	if opts.OrganizationID != "" {
+		conditions = append(conditions, "organization_id = ?")
+		args = append(args, opts.OrganizationID)
+	}
+
+	if opts.ParentID != "" {
+		conditions = append(conditions, "parent_id = ?")
+		args = append(args, opts.ParentID)
+	}
+
+	if opts.Name != "" {
+		conditions = append(conditions, "name = ?")
+		args = append(args, opts.Name)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY name"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan folder: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating folders: %w", err)
+	}
+
+	return folders, nil
+}
+
+// Update updates an existing folder
+func (r *FolderRepository) Update(id string, req domain.UpdateFolderRequest) (*domain.Folder, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Labels != nil {
+		existing.Labels = req.Labels
+	}
+	if req.Quota != nil {
+		existing.Quota = req.Quota
+	}
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE folders SET name = ?, labels = ?, quota = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, jsonColumn{existing.Labels}, jsonColumn{existing.Quota}, existing.UpdatedAt, id)
+	if err != nil {
+		if isFolderNameConflict(err) {
+			return nil, domain.AlreadyExistsError("folder", "name", existing.Name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to update folder: %w", err)
+	}
+
+	return existing, nil
+}
+
+// Move reparents a folder under another folder, or the organization root
+// when parentID is empty
+func (r *FolderRepository) Move(id string, parentID string) (*domain.Folder, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.ParentID = parentID
+	existing.UpdatedAt = time.Now()
+
+	_, err = r.db.execStmt(`UPDATE folders SET parent_id = ?, updated_at = ? WHERE id = ?`, existing.ParentID, existing.UpdatedAt, id)
+	if err != nil {
+		if isFolderNameConflict(err) {
+			return nil, domain.AlreadyExistsError("folder", "name", existing.Name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to move folder: %w", err)
+	}
+
+	return existing, nil
+}
+
+// Delete deletes a folder by ID
+func (r *FolderRepository) Delete(id string) error {
+	var folderCount, projectCount int
+	err := r.db.queryRowStmt(`SELECT
+		(SELECT COUNT(*) FROM folders WHERE parent_id = ?),
+		(SELECT COUNT(*) FROM projects WHERE folder_id = ?)`, id, id).Scan(&folderCount, &projectCount)
+	if err != nil {
+		return fmt.Errorf("failed to check folder children: %w", err)
+	}
+
+	if folderCount > 0 || projectCount > 0 {
+		return domain.InvalidInputError("cannot delete folder with existing folders or projects", map[string]interface{}{
+			"folder_id":     id,
+			"folder_count":  folderCount,
+			"project_count": projectCount,
+		})
+	}
+
+	return r.db.deleteByID("folders", "folder", id)
+}
@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// IAMRepository handles project IAM binding data operations
+type IAMRepository struct {
+	db *DB
+}
+
+// NewIAMRepository creates a new IAM repository
+func NewIAMRepository(db *DB) *IAMRepository {
+	return &IAMRepository{db: db}
+}
+
+// GetBindings retrieves the role bindings of a project, one per role
+func (r *IAMRepository) GetBindings(projectID string) ([]domain.IAMBinding, error) {
+	rows, err := r.db.queryStmt(`SELECT role, member FROM iam_bindings WHERE project_id = ? ORDER BY role, member`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM bindings: %w", err)
+	}
+	defer rows.Close()
+
+	var bindings []domain.IAMBinding
+	for rows.Next() {
+		var role, member string
+		if err := rows.Scan(&role, &member); err != nil {
+			return nil, fmt.Errorf("failed to scan IAM binding: %w", err)
+		}
+		if n := len(bindings); n > 0 && bindings[n-1].Role == role {
+			bindings[n-1].Members = append(bindings[n-1].Members, member)
+			continue
+		}
+		bindings = append(bindings, domain.IAMBinding{Role: role, Members: []string{member}})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating IAM bindings: %w", err)
+	}
+
+	return bindings, nil
+}
+
+// SetBindings replaces the role bindings of a project
+func (r *IAMRepository) SetBindings(projectID string, bindings []domain.IAMBinding) error {
+	return r.db.WithTx(func(tx *DB) error {
+		if _, err := tx.execStmt(`DELETE FROM iam_bindings WHERE project_id = ?`, projectID); err != nil {
+			return fmt.Errorf("failed to clear IAM bindings: %w", err)
+		}
+
+		for _, binding := range bindings {
+			for _, member := range binding.Members {
+				_, err := tx.execStmt(`INSERT OR IGNORE INTO iam_bindings (project_id, role, member) VALUES (?, ?, ?)`, projectID, binding.Role, member)
+				if err != nil {
+					return fmt.Errorf("failed to set IAM binding: %w", err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// ListRoles retrieves the roles a member holds on a project
+func (r *IAMRepository) ListRoles(projectID, member string) ([]string, error) {
+	rows, err := r.db.queryStmt(`SELECT role FROM iam_bindings WHERE project_id = ? AND member = ?`, projectID, member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IAM roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("failed to scan IAM role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating IAM roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// HasMember reports whether a member is bound to any role on any project
+func (r *IAMRepository) HasMember(member string) (bool, error) {
+	var exists bool
+	err := r.db.queryRowStmt(`SELECT EXISTS(SELECT 1 FROM iam_bindings WHERE member = ?)`, member).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up IAM member: %w", err)
+	}
+	return exists, nil
+}
@@ -25,15 +25,15 @@ func (r *InstanceRepository) Create(instance *domain.Instance) error {
 	instance.CreatedAt = now
 	instance.UpdatedAt = now
 
-	query := `INSERT INTO instances (id, project_id, name, cpu, memory_mb, image, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	
-	_, err := r.db.Exec(query, instance.ID, instance.ProjectID, instance.Name, instance.CPU, instance.MemoryMB, instance.Image, instance.Status, instance.CreatedAt, instance.UpdatedAt)
+	query := `INSERT INTO instances (id, project_id, name, cpu, memory_mb, image, status, labels, zone, class, health, auto_repair, autoscaling_group_id, expires_at, deletion_protection, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, instance.ID, instance.ProjectID, instance.Name, instance.CPU, instance.MemoryMB, instance.Image, instance.Status, jsonColumn{instance.Labels}, instance.Zone, instance.Class, instance.Health, instance.AutoRepair, instance.AutoscalingGroupID, instance.ExpiresAt, instance.DeletionProtection, instance.CreatedAt, instance.UpdatedAt)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed: instances.project_id, instances.name") {
-			return domain.AlreadyExistsError("instance", "name", instance.Name)
+			return domain.InstanceNameConflictError(instance.ProjectID, instance.Name, "").WithCause(err)
 		}
 		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
-			return domain.ForeignKeyViolationError("project", "id", instance.ProjectID)
+			return domain.ForeignKeyViolationError("project", "id", instance.ProjectID).WithCause(err)
 		}
 		return fmt.Errorf("failed to create instance: %w", err)
 	}
@@ -44,19 +44,9 @@ func (r *InstanceRepository) Create(instance *domain.Instance) error {
 // GetByID retrieves an instance by ID
 func (r *InstanceRepository) GetByID(id string) (*domain.Instance, error) {
 	instance := &domain.Instance{}
-	query := `SELECT id, project_id, name, cpu, memory_mb, image, status, created_at, updated_at FROM instances WHERE id = ?`
-	
-	err := r.db.QueryRow(query, id).Scan(
-		&instance.ID,
-		&instance.ProjectID,
-		&instance.Name,
-		&instance.CPU,
-		&instance.MemoryMB,
-		&instance.Image,
-		&instance.Status,
-		&instance.CreatedAt,
-		&instance.UpdatedAt,
-	)
+	query := `SELECT ` + instanceColumns.selectList(instanceColumns.fields) + ` FROM instances WHERE id = ?`
+
+	err := r.db.queryRowStmt(query, id).Scan(scanTargets(instanceFieldPtrs(instance), instanceColumns.fields)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain.NotFoundError("instance", id)
@@ -67,12 +57,62 @@ func (r *InstanceRepository) GetByID(id string) (*domain.Instance, error) {
 	return instance, nil
 }
 
+// instanceColumns lists the instance fields that can be selected or sorted on
+var instanceColumns = newColumnSet("instance",
+	"id", "project_id", "name", "cpu", "memory_mb", "image", "status", "labels", "zone", "class", "health", "auto_repair", "autoscaling_group_id", "expires_at", "deletion_protection", "delete_at", "created_at", "updated_at")
+
+// instanceFieldPtrs maps instance fields to scan destinations
+func instanceFieldPtrs(i *domain.Instance) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                   &i.ID,
+		"project_id":           &i.ProjectID,
+		"name":                 &i.Name,
+		"cpu":                  &i.CPU,
+		"memory_mb":            &i.MemoryMB,
+		"image":                &i.Image,
+		"status":               &i.Status,
+		"labels":               jsonColumn{&i.Labels},
+		"zone":                 &i.Zone,
+		"class":                &i.Class,
+		"health":               &i.Health,
+		"auto_repair":          &i.AutoRepair,
+		"autoscaling_group_id": &i.AutoscalingGroupID,
+		"expires_at":           &i.ExpiresAt,
+		"deletion_protection":  &i.DeletionProtection,
+		"delete_at":            &i.DeleteAt,
+		"created_at":           &i.CreatedAt,
+		"updated_at":           &i.UpdatedAt,
+	}
+}
+
 // List retrieves instances with optional filtering
 func (r *InstanceRepository) List(opts domain.InstanceListOptions) ([]*domain.Instance, error) {
 	var instances []*domain.Instance
+	err := r.Stream(opts, func(instance *domain.Instance) error {
+		instances = append(instances, instance)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// Stream calls fn with each instance List would return, as it is read,
+// stopping at the first error fn returns
+func (r *InstanceRepository) Stream(opts domain.InstanceListOptions, fn func(*domain.Instance) error) error {
 	var args []interface{}
-	
-	query := `SELECT id, project_id, name, cpu, memory_mb, image, status, created_at, updated_at FROM instances`
+
+	fields, err := instanceColumns.selectFields(opts.Fields)
+	if err != nil {
+		return err
+	}
+	key, err := instanceColumns.sortKey(opts.Sort, domain.DefaultSortField)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT ` + instanceColumns.selectList(fields) + ` FROM instances`
 	var conditions []string
 
 	if opts.ProjectID != "" {
@@ -85,47 +125,76 @@ func (r *InstanceRepository) List(opts domain.InstanceListOptions) ([]*domain.In
 		args = append(args, opts.Name)
 	}
 
+	if opts.Search != "" {
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, containsPattern(opts.Search))
+	}
+
 	if opts.Status != "" {
 		conditions = append(conditions, "status = ?")
 		args = append(args, opts.Status)
 	}
 
+	if opts.AutoscalingGroupID != "" {
+		conditions = append(conditions, "autoscaling_group_id = ?")
+		args = append(args, opts.AutoscalingGroupID)
+	}
+
+	if opts.Zone != "" {
+		conditions = append(conditions, "zone = ?")
+		args = append(args, opts.Zone)
+	}
+
+	if opts.Class != "" {
+		conditions = append(conditions, "class = ?")
+		args = append(args, opts.Class)
+	}
+
+	if opts.PageToken != "" {
+		last := &domain.Instance{}
+		fields, err := domain.DecodePageToken(opts.PageToken, last)
+		if err != nil {
+			return err
+		}
+		after, afterArgs, err := instanceColumns.after(key, instanceFieldPtrs(last), fields)
+		if err != nil {
+			return err
+		}
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += " ORDER BY name"
+	query += instanceColumns.orderBy(key)
+
+	page, pageArgs := limitOffset(opts.Limit, opts.Offset)
+	query += page
+	args = append(args, pageArgs...)
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.queryStmt(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list instances: %w", err)
+		return fmt.Errorf("failed to list instances: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		instance := &domain.Instance{}
-		err := rows.Scan(
-			&instance.ID,
-			&instance.ProjectID,
-			&instance.Name,
-			&instance.CPU,
-			&instance.MemoryMB,
-			&instance.Image,
-			&instance.Status,
-			&instance.CreatedAt,
-			&instance.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan instance: %w", err)
+		if err := rows.Scan(scanTargets(instanceFieldPtrs(instance), fields)...); err != nil {
+			return fmt.Errorf("failed to scan instance: %w", err)
+		}
+		if err := fn(instance); err != nil {
+			return err
 		}
-		instances = append(instances, instance)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating instances: %w", err)
+		return fmt.Errorf("error iterating instances: %w", err)
 	}
 
-	return instances, nil
+	return nil
 }
 
 // Update updates an existing instance
@@ -152,14 +221,28 @@ func (r *InstanceRepository) Update(id string, req domain.UpdateInstanceRequest)
 	if req.Status != nil {
 		existing.Status = *req.Status
 	}
+	if req.Labels != nil {
+		existing.Labels = req.Labels
+	}
+	if req.ExpiresAt != nil {
+		existing.ExpiresAt = req.ExpiresAt
+	} else if req.TTLSeconds != nil && *req.TTLSeconds == 0 {
+		existing.ExpiresAt = nil
+	}
+	if req.DeletionProtection != nil {
+		existing.DeletionProtection = *req.DeletionProtection
+	}
+	if req.AutoRepair != nil {
+		existing.AutoRepair = *req.AutoRepair
+	}
 	existing.UpdatedAt = time.Now()
 
-	query := `UPDATE instances SET name = ?, cpu = ?, memory_mb = ?, image = ?, status = ?, updated_at = ? WHERE id = ?`
-	
-	_, err = r.db.Exec(query, existing.Name, existing.CPU, existing.MemoryMB, existing.Image, existing.Status, existing.UpdatedAt, id)
+	query := `UPDATE instances SET name = ?, cpu = ?, memory_mb = ?, image = ?, status = ?, labels = ?, expires_at = ?, deletion_protection = ?, auto_repair = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, existing.CPU, existing.MemoryMB, existing.Image, existing.Status, jsonColumn{existing.Labels}, existing.ExpiresAt, existing.DeletionProtection, existing.AutoRepair, existing.UpdatedAt, id)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed: instances.project_id, instances.name") {
-			return nil, domain.AlreadyExistsError("instance", "name", existing.Name)
+			return nil, domain.InstanceNameConflictError(existing.ProjectID, existing.Name, "").WithCause(err)
 		}
 		return nil, fmt.Errorf("failed to update instance: %w", err)
 	}
@@ -169,18 +252,99 @@ func (r *InstanceRepository) Update(id string, req domain.UpdateInstanceRequest)
 
 // Delete deletes an instance by ID
 func (r *InstanceRepository) Delete(id string) error {
-	// First check if instance exists
-	_, err := r.GetByID(id)
+	return r.db.deleteByID("instances", "instance", id)
+}
+
+// ListExpired retrieves instances whose expiry is at or before the given time
+func (r *InstanceRepository) ListExpired(now time.Time) ([]*domain.Instance, error) {
+	query := `SELECT ` + instanceColumns.selectList(instanceColumns.fields) + ` FROM instances WHERE expires_at IS NOT NULL AND expires_at <= ? ORDER BY expires_at, id`
+
+	rows, err := r.db.queryStmt(query, now.UTC())
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to list expired instances: %w", err)
+	}
+	defer rows.Close()
+
+	var instances []*domain.Instance
+	for rows.Next() {
+		instance := &domain.Instance{}
+		if err := rows.Scan(scanTargets(instanceFieldPtrs(instance), instanceColumns.fields)...); err != nil {
+			return nil, fmt.Errorf("failed to scan instance: %w", err)
+		}
+		instances = append(instances, instance)
 	}
 
-	query := `DELETE FROM instances WHERE id = ?`
-	
-	_, err = r.db.Exec(query, id)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating instances: %w", err)
+	}
+
+	return instances, nil
+}
+
+// MarkDeleting puts an instance into the deleting status until deleteAt
+func (r *InstanceRepository) MarkDeleting(id string, deleteAt time.Time) (*domain.Instance, error) {
+	existing, err := r.GetByID(id)
 	if err != nil {
-		return fmt.Errorf("failed to delete instance: %w", err)
+		return nil, err
 	}
 
-	return nil
-}
\ No newline at end of file
+	existing.Status = domain.StatusDeleting
+	existing.DeleteAt = &deleteAt
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE instances SET status = ?, delete_at = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Status, existing.DeleteAt, existing.UpdatedAt, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark instance deleting: %w", err)
+	}
+
+	return existing, nil
+}
+
+// SetHealth sets the health of an instance
+func (r *InstanceRepository) SetHealth(id string, health string) (*domain.Instance, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.Health = health
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE instances SET health = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Health, existing.UpdatedAt, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set instance health: %w", err)
+	}
+
+	return existing, nil
+}
+
+// ListDeleted retrieves deleting instances due for removal at or before the
+// given time
+func (r *InstanceRepository) ListDeleted(now time.Time) ([]*domain.Instance, error) {
+	query := `SELECT ` + instanceColumns.selectList(instanceColumns.fields) + ` FROM instances WHERE delete_at IS NOT NULL AND delete_at <= ? ORDER BY delete_at, id`
+
+	rows, err := r.db.queryStmt(query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted instances: %w", err)
+	}
+	defer rows.Close()
+
+	var instances []*domain.Instance
+	for rows.Next() {
+		instance := &domain.Instance{}
+		if err := rows.Scan(scanTargets(instanceFieldPtrs(instance), instanceColumns.fields)...); err != nil {
+			return nil, fmt.Errorf("failed to scan instance: %w", err)
+		}
+		instances = append(instances, instance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating instances: %w", err)
+	}
+
+	return instances, nil
+}
@@ -0,0 +1,266 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// IntegrityRepository finds rows that refer to missing rows, and keeps the
+// resources quarantined for breaking validation rules. The API never writes
+// either kind of row, but older databases and rows written by hand can hold
+// them.
+type IntegrityRepository struct {
+	db *DB
+}
+
+// NewIntegrityRepository creates a new integrity repository
+func NewIntegrityRepository(db *DB) *IntegrityRepository {
+	return &IntegrityRepository{db: db}
+}
+
+// integrityTable is how issues name the rows of a table: the resource type
+// and the SQL expression identifying a row. Tables without an ID column are
+// identified by their primary key.
+type integrityTable struct {
+	resource string
+	key      string
+}
+
+var integrityTables = map[string]integrityTable{
+	"instances":             {resource: "instance", key: "id"},
+	"folders":               {resource: "folder", key: "id"},
+	"autoscaling_groups":    {resource: "autoscaling_group", key: "id"},
+	"iam_bindings":          {resource: "iam_binding", key: "role || ':' || member"},
+	"project_usage":         {resource: "usage", key: "period"},
+	"budgets":               {resource: "budget", key: "id"},
+	"secrets":               {resource: "secret", key: "id"},
+	"secret_versions":       {resource: "secret_version", key: "secret_id || '/' || version"},
+	"databases":             {resource: "database", key: "id"},
+	"topics":                {resource: "topic", key: "id"},
+	"subscriptions":         {resource: "subscription", key: "id"},
+	"subscription_messages": {resource: "subscription_message", key: "message_id"},
+	"environment_resources": {resource: "environment_resource", key: "resource_type || '/' || resource_id"},
+}
+
+// softReference is a column referring to another table's IDs that the schema
+// does not enforce. An empty column refers to nothing.
+type softReference struct {
+	table    string
+	resource string
+	column   string
+	parent   string
+}
+
+var softReferences = []softReference{
+	{table: "projects", resource: "project", column: "organization_id", parent: "organizations"},
+	{table: "projects", resource: "project", column: "folder_id", parent: "folders"},
+	{table: "folders", resource: "folder", column: "parent_id", parent: "folders"},
+	{table: "instances", resource: "instance", column: "autoscaling_group_id", parent: "autoscaling_groups"},
+}
+
+// foreignKeyViolation is a row of PRAGMA foreign_key_check
+type foreignKeyViolation struct {
+	table  string
+	rowid  int64
+	parent string
+	fkid   int
+}
+
+// CheckReferences finds rows whose foreign key refers to a missing row, which
+// are deleted on repair, and soft references to missing rows, which are
+// cleared on repair
+func (r *IntegrityRepository) CheckReferences(repair bool) ([]domain.IntegrityIssue, error) {
+	issues, err := r.checkForeignKeys(repair)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range softReferences {
+		found, err := r.checkSoftReference(ref, repair)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+
+	return issues, nil
+}
+
+// checkForeignKeys reports the rows failing SQLite's foreign key check,
+// deleting them on repair
+func (r *IntegrityRepository) checkForeignKeys(repair bool) ([]domain.IntegrityIssue, error) {
+	rows, err := r.db.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []foreignKeyViolation
+	for rows.Next() {
+		var v foreignKeyViolation
+		var rowid sql.NullInt64
+		if err := rows.Scan(&v.table, &rowid, &v.parent, &v.fkid); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key violation: %w", err)
+		}
+		v.rowid = rowid.Int64
+		violations = append(violations, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign key violations: %w", err)
+	}
+	rows.Close()
+
+	var issues []domain.IntegrityIssue
+	for _, v := range violations {
+		table, ok := integrityTables[v.table]
+		if !ok {
+			table = integrityTable{resource: v.table, key: "rowid"}
+		}
+		column, err := r.foreignKeyColumn(v.table, v.fkid)
+		if err != nil {
+			return nil, err
+		}
+
+		var id, parentID string
+		query := fmt.Sprintf(`SELECT CAST(%s AS TEXT), CAST(%s AS TEXT) FROM %s WHERE rowid = ?`, table.key, column, v.table)
+		if err := r.db.QueryRow(query, v.rowid).Scan(&id, &parentID); err != nil {
+			return nil, fmt.Errorf("failed to get %s row: %w", v.table, err)
+		}
+
+		issue := domain.IntegrityIssue{
+			Check:        domain.IntegrityForeignKey,
+			ResourceType: table.resource,
+			ResourceID:   id,
+			Message:      fmt.Sprintf("%s %s refers to %s %s, which does not exist", table.resource, id, column, parentID),
+			Repair:       fmt.Sprintf("delete the %s", table.resource),
+		}
+		if repair {
+			if _, err := r.db.Exec(`DELETE FROM `+v.table+` WHERE rowid = ?`, v.rowid); err != nil {
+				return nil, fmt.Errorf("failed to delete %s row: %w", v.table, err)
+			}
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// foreignKeyColumn returns the child column of a table's foreign key
+func (r *IntegrityRepository) foreignKeyColumn(table string, fkid int) (string, error) {
+	rows, err := r.db.Query(`PRAGMA foreign_key_list(` + table + `)`)
+	if err != nil {
+		return "", fmt.Errorf("failed to list foreign keys of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	column := ""
+	for rows.Next() {
+		var id, seq int
+		var parent, from, to, onUpdate, onDelete, match string
+		if err := rows.Scan(&id, &seq, &parent, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return "", fmt.Errorf("failed to scan foreign key of %s: %w", table, err)
+		}
+		if id == fkid {
+			column = from
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating foreign keys of %s: %w", table, err)
+	}
+	if column == "" {
+		return "", fmt.Errorf("foreign key %d of %s not found", fkid, table)
+	}
+
+	return column, nil
+}
+
+// checkSoftReference reports the rows whose soft reference names a missing
+// row, clearing the reference on repair
+func (r *IntegrityRepository) checkSoftReference(ref softReference, repair bool) ([]domain.IntegrityIssue, error) {
+	query := fmt.Sprintf(`SELECT id, %[2]s FROM %[1]s WHERE %[2]s != '' AND %[2]s NOT IN (SELECT id FROM %[3]s) ORDER BY id`, ref.table, ref.column, ref.parent)
+
+	rows, err := r.db.queryStmt(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s.%s: %w", ref.table, ref.column, err)
+	}
+	defer rows.Close()
+
+	var issues []domain.IntegrityIssue
+	for rows.Next() {
+		var id, parentID string
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", ref.table, err)
+		}
+		issues = append(issues, domain.IntegrityIssue{
+			Check:        domain.IntegrityDanglingReference,
+			ResourceType: ref.resource,
+			ResourceID:   id,
+			Message:      fmt.Sprintf("%s %s refers to %s %s, which does not exist", ref.resource, id, ref.column, parentID),
+			Repair:       fmt.Sprintf("clear %s", ref.column),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s: %w", ref.table, err)
+	}
+	rows.Close()
+
+	if !repair {
+		return issues, nil
+	}
+	for i := range issues {
+		update := fmt.Sprintf(`UPDATE %s SET %s = '' WHERE id = ?`, ref.table, ref.column)
+		if _, err := r.db.execStmt(update, issues[i].ResourceID); err != nil {
+			return nil, fmt.Errorf("failed to clear %s.%s: %w", ref.table, ref.column, err)
+		}
+		issues[i].Repaired = true
+	}
+
+	return issues, nil
+}
+
+// Quarantine stores a quarantined resource, replacing an earlier copy. The
+// caller removes the resource itself.
+func (r *IntegrityRepository) Quarantine(q *domain.QuarantinedResource) error {
+	q.QuarantinedAt = time.Now()
+
+	query := `INSERT OR REPLACE INTO quarantined_resources (resource_type, resource_id, data, problems, quarantined_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, q.ResourceType, q.ResourceID, string(q.Data), jsonColumn{q.Problems}, q.QuarantinedAt)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine %s: %w", q.ResourceType, err)
+	}
+
+	return nil
+}
+
+// ListQuarantined retrieves the quarantined resources, oldest first
+func (r *IntegrityRepository) ListQuarantined() ([]*domain.QuarantinedResource, error) {
+	query := `SELECT resource_type, resource_id, data, problems, quarantined_at FROM quarantined_resources ORDER BY quarantined_at, resource_type, resource_id`
+
+	rows, err := r.db.queryStmt(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quarantined resources: %w", err)
+	}
+	defer rows.Close()
+
+	quarantined := []*domain.QuarantinedResource{}
+	for rows.Next() {
+		q := &domain.QuarantinedResource{}
+		var data string
+		if err := rows.Scan(&q.ResourceType, &q.ResourceID, &data, jsonColumn{&q.Problems}, &q.QuarantinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan quarantined resource: %w", err)
+		}
+		q.Data = []byte(data)
+		quarantined = append(quarantined, q)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating quarantined resources: %w", err)
+	}
+
+	return quarantined, nil
+}
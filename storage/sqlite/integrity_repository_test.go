@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// execWithoutForeignKeys runs statements with foreign keys turned off, the
+// way rows get written by hand
+func execWithoutForeignKeys(t *testing.T, db *DB, statements ...string) {
+	t.Helper()
+
+	ctx := context.Background()
+	conn, err := db.DB.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF")
+	require.NoError(t, err)
+	for _, stmt := range statements {
+		_, err := conn.ExecContext(ctx, stmt)
+		require.NoError(t, err, stmt)
+	}
+	_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+}
+
+func TestIntegrityRepository_CheckReferences(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := NewIntegrityRepository(db)
+
+	now := time.Now()
+	require.NoError(t, NewProjectRepository(db).Create(&domain.Project{ID: "proj-1", Name: "kept", CreatedAt: now, UpdatedAt: now}))
+	require.NoError(t, NewInstanceRepository(db).Create(&domain.Instance{ID: "inst-1", ProjectID: "proj-1", Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusRunning}))
+
+	issues, err := repo.CheckReferences(false)
+	require.NoError(t, err)
+	assert.Empty(t, issues, "data written through the repositories has no issues")
+
+	execWithoutForeignKeys(t, db,
+		`INSERT INTO instances (id, project_id, name, cpu, memory_mb, image, status, created_at, updated_at) VALUES ('inst-2', 'proj-gone', 'orphan', 1, 512, 'ubuntu', 'running', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		`UPDATE instances SET autoscaling_group_id = 'asg-gone' WHERE id = 'inst-1'`,
+		`UPDATE projects SET organization_id = 'org-gone' WHERE id = 'proj-1'`,
+	)
+
+	issues, err = repo.CheckReferences(false)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.IntegrityIssue{
+		{
+			Check:        domain.IntegrityForeignKey,
+			ResourceType: "instance",
+			ResourceID:   "inst-2",
+			Message:      "instance inst-2 refers to project_id proj-gone, which does not exist",
+			Repair:       "delete the instance",
+		},
+		{
+			Check:        domain.IntegrityDanglingReference,
+			ResourceType: "project",
+			ResourceID:   "proj-1",
+			Message:      "project proj-1 refers to organization_id org-gone, which does not exist",
+			Repair:       "clear organization_id",
+		},
+		{
+			Check:        domain.IntegrityDanglingReference,
+			ResourceType: "instance",
+			ResourceID:   "inst-1",
+			Message:      "instance inst-1 refers to autoscaling_group_id asg-gone, which does not exist",
+			Repair:       "clear autoscaling_group_id",
+		},
+	}, issues)
+
+	issues, err = repo.CheckReferences(true)
+	require.NoError(t, err)
+	require.Len(t, issues, 3)
+	for _, issue := range issues {
+		assert.True(t, issue.Repaired, issue.Message)
+	}
+
+	issues, err = repo.CheckReferences(false)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	_, err = NewInstanceRepository(db).GetByID("inst-2")
+	assert.True(t, domain.IsNotFound(err), "the orphaned instance is deleted")
+	instance, err := NewInstanceRepository(db).GetByID("inst-1")
+	require.NoError(t, err)
+	assert.Empty(t, instance.AutoscalingGroupID)
+}
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonColumn stores a Go value as JSON in a TEXT column. Wrap a pointer to
+// scan into it, or a value to write it.
+type jsonColumn struct {
+	v interface{}
+}
+
+// Scan implements sql.Scanner
+func (j jsonColumn) Scan(src interface{}) error {
+	var data []byte
+	switch s := src.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(s)
+	case []byte:
+		data = s
+	default:
+		return fmt.Errorf("unsupported JSON column type %T", src)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, j.v)
+}
+
+// Value implements driver.Valuer
+func (j jsonColumn) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
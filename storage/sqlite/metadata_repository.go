@@ -6,7 +6,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/hypertf/dirtcloud-server/domain"
 )
 
@@ -20,31 +19,31 @@ func NewMetadataRepository(db *DB) *MetadataRepository {
 	return &MetadataRepository{db: db}
 }
 
-// Create creates new metadata
-func (r *MetadataRepository) Create(req domain.CreateMetadataRequest) (*domain.Metadata, error) {
+// Create creates new metadata with the given ID
+func (r *MetadataRepository) Create(id string, req domain.CreateMetadataRequest) (*domain.Metadata, error) {
 	// Check if path already exists
 	exists, err := r.pathExists(req.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check path existence: %w", err)
 	}
 	if exists {
-		return nil, domain.AlreadyExistsError("metadata with path", req.Path)
+		return nil, domain.AlreadyExistsError("metadata", "path", req.Path)
 	}
 
-	id := uuid.New().String()
 	now := time.Now()
 
 	metadata := &domain.Metadata{
 		ID:        id,
 		Path:      req.Path,
 		Value:     req.Value,
+		Flags:     req.Flags,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	query := `INSERT INTO metadata (id, path, value, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+	query := `INSERT INTO metadata (id, path, value, flags, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
 	
-	_, err = r.db.Exec(query, metadata.ID, metadata.Path, metadata.Value, metadata.CreatedAt, metadata.UpdatedAt)
+	_, err = r.db.execStmt(query, metadata.ID, metadata.Path, metadata.Value, metadata.Flags, metadata.CreatedAt, metadata.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create metadata: %w", err)
 	}
@@ -55,12 +54,13 @@ func (r *MetadataRepository) Create(req domain.CreateMetadataRequest) (*domain.M
 // GetByID retrieves metadata by ID
 func (r *MetadataRepository) GetByID(id string) (*domain.Metadata, error) {
 	metadata := &domain.Metadata{}
-	query := `SELECT id, path, value, created_at, updated_at FROM metadata WHERE id = ?`
+	query := `SELECT id, path, value, flags, created_at, updated_at FROM metadata WHERE id = ?`
 	
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.queryRowStmt(query, id).Scan(
 		&metadata.ID,
 		&metadata.Path,
 		&metadata.Value,
+		&metadata.Flags,
 		&metadata.CreatedAt,
 		&metadata.UpdatedAt,
 	)
@@ -74,6 +74,29 @@ func (r *MetadataRepository) GetByID(id string) (*domain.Metadata, error) {
 	return metadata, nil
 }
 
+// GetByPath retrieves metadata by path
+func (r *MetadataRepository) GetByPath(path string) (*domain.Metadata, error) {
+	metadata := &domain.Metadata{}
+	query := `SELECT id, path, value, flags, created_at, updated_at FROM metadata WHERE path = ?`
+
+	err := r.db.queryRowStmt(query, path).Scan(
+		&metadata.ID,
+		&metadata.Path,
+		&metadata.Value,
+		&metadata.Flags,
+		&metadata.CreatedAt,
+		&metadata.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("metadata", path)
+		}
+		return nil, fmt.Errorf("failed to get metadata by path: %w", err)
+	}
+
+	return metadata, nil
+}
+
 // Update updates existing metadata
 func (r *MetadataRepository) Update(id string, req domain.UpdateMetadataRequest) (*domain.Metadata, error) {
 	// First get the existing metadata
@@ -89,7 +112,7 @@ func (r *MetadataRepository) Update(id string, req domain.UpdateMetadataRequest)
 			return nil, fmt.Errorf("failed to check path existence: %w", err)
 		}
 		if exists {
-			return nil, domain.AlreadyExistsError("metadata with path", *req.Path)
+			return nil, domain.AlreadyExistsError("metadata", "path", *req.Path)
 		}
 	}
 
@@ -100,11 +123,14 @@ func (r *MetadataRepository) Update(id string, req domain.UpdateMetadataRequest)
 	if req.Value != nil {
 		existing.Value = *req.Value
 	}
+	if req.Flags != nil {
+		existing.Flags = *req.Flags
+	}
 	existing.UpdatedAt = time.Now()
 
-	query := `UPDATE metadata SET path = ?, value = ?, updated_at = ? WHERE id = ?`
+	query := `UPDATE metadata SET path = ?, value = ?, flags = ?, updated_at = ? WHERE id = ?`
 	
-	_, err = r.db.Exec(query, existing.Path, existing.Value, existing.UpdatedAt, id)
+	_, err = r.db.execStmt(query, existing.Path, existing.Value, existing.Flags, existing.UpdatedAt, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update metadata: %w", err)
 	}
@@ -115,15 +141,31 @@ func (r *MetadataRepository) Update(id string, req domain.UpdateMetadataRequest)
 // List retrieves metadata entries with optional prefix filtering
 func (r *MetadataRepository) List(opts domain.MetadataListOptions) ([]*domain.Metadata, error) {
 	var metadata []*domain.Metadata
+	err := r.Stream(opts, func(m *domain.Metadata) error {
+		metadata = append(metadata, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// Stream calls fn with each entry List would return, in path order, without
+// holding the whole list in memory
+func (r *MetadataRepository) Stream(opts domain.MetadataListOptions, fn func(*domain.Metadata) error) error {
 	var args []interface{}
 	
-	query := `SELECT id, path, value, created_at, updated_at FROM metadata`
+	query := `SELECT id, path, value, flags, created_at, updated_at FROM metadata`
 	var conditions []string
 
 	if opts.Prefix != "" {
-		// For prefix matching, we want paths that start with the prefix
-		conditions = append(conditions, "path LIKE ?")
-		args = append(args, opts.Prefix+"%")
+		// A range over the path index rather than LIKE, which cannot use it
+		// and would treat % and _ in the prefix as wildcards. No UTF-8 text
+		// contains the byte 0xff, so every path starting with the prefix
+		// sorts before the upper bound.
+		conditions = append(conditions, "path >= ? AND path < ?")
+		args = append(args, opts.Prefix, opts.Prefix+"\xff")
 	}
 
 	if len(conditions) > 0 {
@@ -132,44 +174,45 @@ func (r *MetadataRepository) List(opts domain.MetadataListOptions) ([]*domain.Me
 
 	query += " ORDER BY path"
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.queryStmt(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list metadata: %w", err)
+		return fmt.Errorf("failed to list metadata: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		m := &domain.Metadata{}
-		err := rows.Scan(&m.ID, &m.Path, &m.Value, &m.CreatedAt, &m.UpdatedAt)
+		err := rows.Scan(&m.ID, &m.Path, &m.Value, &m.Flags, &m.CreatedAt, &m.UpdatedAt)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan metadata: %w", err)
+			return fmt.Errorf("failed to scan metadata: %w", err)
+		}
+		if err := fn(m); err != nil {
+			return err
 		}
-		metadata = append(metadata, m)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating metadata: %w", err)
+		return fmt.Errorf("error iterating metadata: %w", err)
 	}
 
-	return metadata, nil
+	return nil
 }
 
 // Delete deletes metadata by ID
 func (r *MetadataRepository) Delete(id string) error {
-	// First check if metadata exists
-	_, err := r.GetByID(id)
-	if err != nil {
-		return err
-	}
+	return r.db.deleteByID("metadata", "metadata", id)
+}
 
-	query := `DELETE FROM metadata WHERE id = ?`
-	
-	_, err = r.db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete metadata: %w", err)
+// Usage counts the metadata entries and the bytes of their paths and values
+func (r *MetadataRepository) Usage() (*domain.MetadataUsage, error) {
+	usage := &domain.MetadataUsage{}
+	query := `SELECT COUNT(*), COALESCE(SUM(length(CAST(path AS BLOB)) + length(CAST(value AS BLOB))), 0) FROM metadata`
+
+	if err := r.db.queryRowStmt(query).Scan(&usage.Keys, &usage.TotalBytes); err != nil {
+		return nil, fmt.Errorf("failed to measure metadata: %w", err)
 	}
 
-	return nil
+	return usage, nil
 }
 
 // pathExists checks if a path already exists in the database
@@ -177,7 +220,7 @@ func (r *MetadataRepository) pathExists(path string) (bool, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM metadata WHERE path = ?`
 	
-	err := r.db.QueryRow(query, path).Scan(&count)
+	err := r.db.queryRowStmt(query, path).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/hypertf/dirtcloud-server/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -57,7 +58,7 @@ func TestMetadataRepository_Create(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			metadata, err := repo.Create(tt.req)
+			metadata, err := repo.Create(uuid.New().String(), tt.req)
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -91,7 +92,7 @@ func TestMetadataRepository_GetByID(t *testing.T) {
 		Path:  "/config/app.yaml",
 		Value: "database: localhost",
 	}
-	created, err := repo.Create(req)
+	created, err := repo.Create(uuid.New().String(), req)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -154,14 +155,14 @@ func TestMetadataRepository_Update(t *testing.T) {
 		Path:  "/config/app.yaml",
 		Value: "database: localhost",
 	}
-	created1, err := repo.Create(req1)
+	created1, err := repo.Create(uuid.New().String(), req1)
 	require.NoError(t, err)
 
 	req2 := domain.CreateMetadataRequest{
 		Path:  "/config/other.yaml",
 		Value: "other: value",
 	}
-	created2, err := repo.Create(req2)
+	created2, err := repo.Create(uuid.New().String(), req2)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -272,7 +273,7 @@ func TestMetadataRepository_List(t *testing.T) {
 
 	var createdMetadata []*domain.Metadata
 	for _, req := range testData {
-		metadata, err := repo.Create(req)
+		metadata, err := repo.Create(uuid.New().String(), req)
 		require.NoError(t, err)
 		createdMetadata = append(createdMetadata, metadata)
 	}
@@ -382,7 +383,7 @@ func TestMetadataRepository_Delete(t *testing.T) {
 		Path:  "/config/app.yaml",
 		Value: "database: localhost",
 	}
-	created, err := repo.Create(req)
+	created, err := repo.Create(uuid.New().String(), req)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -446,7 +447,7 @@ func TestMetadataRepository_PathUniqueness(t *testing.T) {
 		Path:  path,
 		Value: "first value",
 	}
-	metadata1, err := repo.Create(req1)
+	metadata1, err := repo.Create(uuid.New().String(), req1)
 	require.NoError(t, err)
 
 	// Try to create another with same path
@@ -454,7 +455,7 @@ func TestMetadataRepository_PathUniqueness(t *testing.T) {
 		Path:  path,
 		Value: "second value",
 	}
-	_, err = repo.Create(req2)
+	_, err = repo.Create(uuid.New().String(), req2)
 	require.Error(t, err)
 	assert.True(t, domain.IsAlreadyExists(err))
 
@@ -485,7 +486,7 @@ func TestMetadataRepository_pathExists(t *testing.T) {
 		Path:  path,
 		Value: "test value",
 	}
-	_, err = repo.Create(req)
+	_, err = repo.Create(uuid.New().String(), req)
 	require.NoError(t, err)
 
 	// Now should exist
@@ -0,0 +1,258 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFilePattern matches migration file names such as 0002_add_widgets.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// loadMigrations reads the migrations in fsys, ordered by version. Every
+// migration needs an up script; down scripts are optional.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, migration.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// embeddedMigrations returns the migrations built into the binary
+func embeddedMigrations() ([]Migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return loadMigrations(sub)
+}
+
+// LatestSchemaVersion returns the version of the newest built-in migration
+func LatestSchemaVersion() (int, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Migrate applies all pending migrations and the instance name uniqueness
+// chosen with SetUniqueInstanceNames
+func (db *DB) Migrate() error {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if err := db.MigrateTo(latest); err != nil {
+		return err
+	}
+	return db.applyInstanceNameIndex()
+}
+
+// MigrateTo applies or reverts migrations until the schema is at version.
+// Each migration runs in its own transaction.
+func (db *DB) MigrateTo(version int) error {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return err
+	}
+
+	if err := db.ensureMigrationsTable(migrations); err != nil {
+		return err
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if version > current {
+		for _, m := range migrations {
+			if m.Version > current && m.Version <= version {
+				if err := db.runMigration(m.Version, m.Name, m.Up, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version > version && m.Version <= current {
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted: no down script", m.Version, m.Name)
+			}
+			if err := db.runMigration(m.Version, m.Name, m.Down, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the newest applied migration
+func (db *DB) SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// runMigration runs one migration script and records the result
+func (db *DB) runMigration(version int, name, script string, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d_%s: %w", version, name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("failed to migrate %s %d_%s: %w", direction, version, name, err)
+	}
+
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, version, name)
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", version, name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %w", version, name, err)
+	}
+
+	return nil
+}
+
+// ensureMigrationsTable creates the schema_migrations table. A database that
+// predates versioned migrations has its columns brought up to date and is
+// recorded at the baseline version.
+func (db *DB) ensureMigrationsTable(migrations []Migration) error {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	var legacy bool
+	err = db.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'projects')`).Scan(&legacy)
+	if err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	if legacy && len(migrations) > 0 {
+		if err := db.upgradeLegacySchema(); err != nil {
+			return err
+		}
+		baseline := migrations[0]
+		if err := db.runMigration(baseline.Version, baseline.Name, baseline.Up, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// upgradeLegacySchema adds the columns that were added to the original tables
+// before versioned migrations existed, so the baseline migration can apply
+func (db *DB) upgradeLegacySchema() error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"projects", "organization_id", "TEXT NOT NULL DEFAULT ''"},
+		{"projects", "folder_id", "TEXT NOT NULL DEFAULT ''"},
+		{"projects", "labels", "TEXT NOT NULL DEFAULT '{}'"},
+		{"projects", "chaos_profile", "TEXT NOT NULL DEFAULT ''"},
+		{"instances", "expires_at", "DATETIME"},
+		{"instances", "labels", "TEXT NOT NULL DEFAULT '{}'"},
+		{"instances", "autoscaling_group_id", "TEXT NOT NULL DEFAULT ''"},
+	}
+
+	for _, c := range columns {
+		if err := db.ensureColumn(c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            fstest.MapFS
+		expectedVersions []int
+		expectError      bool
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"0002_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id TEXT)")},
+				"0002_widgets.down.sql": {Data: []byte("DROP TABLE widgets")},
+				"0001_initial.up.sql":   {Data: []byte("CREATE TABLE things (id TEXT)")},
+			},
+			expectedVersions: []int{1, 2},
+		},
+		{
+			name:        "invalid file name",
+			files:       fstest.MapFS{"widgets.sql": {Data: []byte("SELECT 1")}},
+			expectError: true,
+		},
+		{
+			name:        "missing up script",
+			files:       fstest.MapFS{"0001_initial.down.sql": {Data: []byte("SELECT 1")}},
+			expectError: true,
+		},
+		{
+			name: "conflicting names for one version",
+			files: fstest.MapFS{
+				"0001_initial.up.sql": {Data: []byte("SELECT 1")},
+				"0001_other.down.sql": {Data: []byte("SELECT 1")},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.files)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var versions []int
+			for _, m := range migrations {
+				versions = append(versions, m.Version)
+			}
+			assert.Equal(t, tt.expectedVersions, versions)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := embeddedMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "migration versions must be contiguous")
+		assert.NotEmpty(t, m.Down, "migration %d_%s needs a down script", m.Version, m.Name)
+	}
+}
+
+// tableExists reports whether a table exists in db
+func tableExists(t *testing.T, db *DB, table string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`, table).Scan(&exists)
+	require.NoError(t, err)
+	return exists
+}
+
+func TestDB_MigrateTo(t *testing.T) {
+	db, err := NewDB("file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_fk=1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	latest, err := LatestSchemaVersion()
+	require.NoError(t, err)
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+	assert.True(t, tableExists(t, db, "projects"))
+
+	// Revert everything
+	require.NoError(t, db.MigrateTo(0))
+	version, err = db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.False(t, tableExists(t, db, "projects"))
+
+	// And apply it again
+	require.NoError(t, db.Migrate())
+	version, err = db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+	assert.True(t, tableExists(t, db, "projects"))
+}
+
+func TestDB_Migrate_LegacySchema(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_fk=1"
+
+	// A data file created before versioned migrations and most added columns
+	legacy, err := Open(dsn)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+		CREATE TABLE projects (
+			id TEXT PRIMARY KEY,
+			name TEXT UNIQUE NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE instances (
+			id TEXT PRIMARY KEY,
+			project_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			cpu INTEGER NOT NULL,
+			memory_mb INTEGER NOT NULL,
+			image TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'running',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+			UNIQUE(project_id, name)
+		);
+		INSERT INTO projects (id, name) VALUES ('p1', 'legacy');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := NewDB(dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	project, err := NewProjectRepository(db).GetByID("p1")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", project.Name)
+
+	latest, err := LatestSchemaVersion()
+	require.NoError(t, err)
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+	assert.True(t, tableExists(t, db, "iam_bindings"))
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS iam_bindings;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS autoscaling_groups;
+DROP TABLE IF EXISTS instance_templates;
+DROP TABLE IF EXISTS folders;
+DROP TABLE IF EXISTS organizations;
+DROP TABLE IF EXISTS metadata;
+DROP TABLE IF EXISTS instances;
+DROP TABLE IF EXISTS projects;
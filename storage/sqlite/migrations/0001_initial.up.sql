@@ -0,0 +1,111 @@
+-- Baseline schema. Statements are idempotent so that databases created
+-- before versioned migrations existed can adopt this version in place.
+
+CREATE TABLE IF NOT EXISTS projects (
+	id TEXT PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	organization_id TEXT NOT NULL DEFAULT '',
+	folder_id TEXT NOT NULL DEFAULT '',
+	labels TEXT NOT NULL DEFAULT '{}',
+	chaos_profile TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS instances (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	cpu INTEGER NOT NULL,
+	memory_mb INTEGER NOT NULL,
+	image TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'running',
+	expires_at DATETIME,
+	labels TEXT NOT NULL DEFAULT '{}',
+	autoscaling_group_id TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	UNIQUE(project_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_instances_expires_at ON instances(expires_at);
+CREATE INDEX IF NOT EXISTS idx_instances_autoscaling_group_id ON instances(autoscaling_group_id);
+
+CREATE TABLE IF NOT EXISTS metadata (
+	id TEXT PRIMARY KEY,
+	path TEXT NOT NULL UNIQUE,
+	value TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organizations (
+	id TEXT PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	labels TEXT NOT NULL DEFAULT '{}',
+	quota TEXT NOT NULL DEFAULT 'null',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS folders (
+	id TEXT PRIMARY KEY,
+	organization_id TEXT NOT NULL,
+	parent_id TEXT NOT NULL DEFAULT '',
+	name TEXT NOT NULL,
+	labels TEXT NOT NULL DEFAULT '{}',
+	quota TEXT NOT NULL DEFAULT 'null',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (organization_id) REFERENCES organizations(id),
+	UNIQUE(organization_id, parent_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS instance_templates (
+	id TEXT PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	cpu INTEGER NOT NULL,
+	memory_mb INTEGER NOT NULL,
+	image TEXT NOT NULL,
+	labels TEXT NOT NULL DEFAULT '{}',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS autoscaling_groups (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	template_id TEXT NOT NULL,
+	min_size INTEGER NOT NULL,
+	max_size INTEGER NOT NULL,
+	desired_size INTEGER NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id),
+	FOREIGN KEY (template_id) REFERENCES instance_templates(id),
+	UNIQUE(project_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS events (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	project_id TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_resource ON events(resource_type, resource_id);
+
+CREATE TABLE IF NOT EXISTS iam_bindings (
+	project_id TEXT NOT NULL,
+	role TEXT NOT NULL,
+	member TEXT NOT NULL,
+	PRIMARY KEY (project_id, role, member),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_iam_bindings_member ON iam_bindings(member);
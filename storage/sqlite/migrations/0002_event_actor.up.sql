@@ -0,0 +1,2 @@
+-- Record who caused each event
+ALTER TABLE events ADD COLUMN actor TEXT NOT NULL DEFAULT '';
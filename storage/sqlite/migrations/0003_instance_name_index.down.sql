@@ -0,0 +1,25 @@
+CREATE TABLE instances_old (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	cpu INTEGER NOT NULL,
+	memory_mb INTEGER NOT NULL,
+	image TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'running',
+	expires_at DATETIME,
+	labels TEXT NOT NULL DEFAULT '{}',
+	autoscaling_group_id TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	UNIQUE(project_id, name)
+);
+
+INSERT INTO instances_old (id, project_id, name, cpu, memory_mb, image, status, expires_at, labels, autoscaling_group_id, created_at, updated_at)
+	SELECT id, project_id, name, cpu, memory_mb, image, status, expires_at, labels, autoscaling_group_id, created_at, updated_at FROM instances;
+
+DROP TABLE instances;
+ALTER TABLE instances_old RENAME TO instances;
+
+CREATE INDEX idx_instances_expires_at ON instances(expires_at);
+CREATE INDEX idx_instances_autoscaling_group_id ON instances(autoscaling_group_id);
@@ -0,0 +1,12 @@
+-- Simulated usage accrued by each project's instances, per calendar month
+CREATE TABLE project_usage (
+	project_id TEXT NOT NULL,
+	period TEXT NOT NULL,
+	instance_hours REAL NOT NULL DEFAULT 0,
+	vcpu_hours REAL NOT NULL DEFAULT 0,
+	memory_gb_hours REAL NOT NULL DEFAULT 0,
+	volume_gb_hours REAL NOT NULL DEFAULT 0,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (project_id, period),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
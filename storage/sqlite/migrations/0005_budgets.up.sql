@@ -0,0 +1,17 @@
+-- Monthly spending budgets of projects
+CREATE TABLE budgets (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	amount REAL NOT NULL,
+	thresholds TEXT NOT NULL DEFAULT '[]',
+	webhook_url TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'ok',
+	spend REAL NOT NULL DEFAULT 0,
+	period TEXT NOT NULL DEFAULT '',
+	crossed_thresholds TEXT NOT NULL DEFAULT '[]',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	UNIQUE(project_id, name)
+);
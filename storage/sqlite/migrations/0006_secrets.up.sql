@@ -0,0 +1,26 @@
+-- Versioned secrets of projects
+CREATE TABLE secrets (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	latest_version INTEGER NOT NULL DEFAULT 0,
+	rotation_period_seconds INTEGER NOT NULL DEFAULT 0,
+	next_rotation_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	UNIQUE(project_id, name)
+);
+
+CREATE INDEX idx_secrets_next_rotation_at ON secrets(next_rotation_at);
+
+CREATE TABLE secret_versions (
+	secret_id TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	state TEXT NOT NULL DEFAULT 'enabled',
+	payload TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	destroyed_at DATETIME,
+	PRIMARY KEY (secret_id, version),
+	FOREIGN KEY (secret_id) REFERENCES secrets(id) ON DELETE CASCADE
+);
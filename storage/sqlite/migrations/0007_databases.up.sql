@@ -0,0 +1,23 @@
+-- Simulated managed databases of projects
+CREATE TABLE databases (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	engine TEXT NOT NULL,
+	version TEXT NOT NULL,
+	size TEXT NOT NULL,
+	status TEXT NOT NULL,
+	host TEXT NOT NULL,
+	port INTEGER NOT NULL,
+	database_name TEXT NOT NULL,
+	username TEXT NOT NULL,
+	password TEXT NOT NULL,
+	connection_string TEXT NOT NULL,
+	ready_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	UNIQUE(project_id, name)
+);
+
+CREATE INDEX idx_databases_ready_at ON databases(ready_at);
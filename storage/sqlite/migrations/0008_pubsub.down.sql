@@ -0,0 +1,3 @@
+DROP TABLE subscription_messages;
+DROP TABLE subscriptions;
+DROP TABLE topics;
@@ -0,0 +1,39 @@
+-- Pub/sub topics of projects
+CREATE TABLE topics (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	UNIQUE(project_id, name)
+);
+
+CREATE TABLE subscriptions (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	topic_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	ack_deadline_seconds INTEGER NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	FOREIGN KEY (topic_id) REFERENCES topics(id) ON DELETE CASCADE,
+	UNIQUE(project_id, name)
+);
+
+CREATE INDEX idx_subscriptions_topic_id ON subscriptions(topic_id);
+
+-- Each published message is stored once per subscription until acknowledged
+CREATE TABLE subscription_messages (
+	subscription_id TEXT NOT NULL,
+	message_id TEXT NOT NULL,
+	data TEXT NOT NULL DEFAULT '',
+	attributes TEXT NOT NULL DEFAULT '{}',
+	published_at DATETIME NOT NULL,
+	delivery_attempt INTEGER NOT NULL DEFAULT 0,
+	ack_id TEXT NOT NULL DEFAULT '',
+	visible_at DATETIME NOT NULL,
+	PRIMARY KEY (subscription_id, message_id),
+	FOREIGN KEY (subscription_id) REFERENCES subscriptions(id) ON DELETE CASCADE
+);
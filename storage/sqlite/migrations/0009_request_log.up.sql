@@ -0,0 +1,13 @@
+-- Recent API requests, trimmed to a fixed number of entries as new ones are
+-- logged
+CREATE TABLE request_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	query TEXT NOT NULL DEFAULT '',
+	status INTEGER NOT NULL,
+	latency_ms REAL NOT NULL,
+	actor TEXT NOT NULL DEFAULT '',
+	chaos TEXT NOT NULL DEFAULT '[]',
+	created_at DATETIME NOT NULL
+);
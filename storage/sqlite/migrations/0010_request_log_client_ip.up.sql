@@ -0,0 +1,2 @@
+-- Record where each logged request came from
+ALTER TABLE request_log ADD COLUMN client_ip TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE events DROP COLUMN reason;
+ALTER TABLE events DROP COLUMN status;
+ALTER TABLE events DROP COLUMN previous_status;
@@ -0,0 +1,4 @@
+-- Record the status transition and its reason on status change events
+ALTER TABLE events ADD COLUMN previous_status TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN status TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE instances DROP COLUMN deletion_protection;
+ALTER TABLE projects DROP COLUMN deletion_protection;
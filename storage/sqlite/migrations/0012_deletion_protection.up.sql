@@ -0,0 +1,3 @@
+-- Projects and instances with deletion protection cannot be deleted
+ALTER TABLE projects ADD COLUMN deletion_protection BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE instances ADD COLUMN deletion_protection BOOLEAN NOT NULL DEFAULT 0;
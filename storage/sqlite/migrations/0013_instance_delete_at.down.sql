@@ -0,0 +1 @@
+ALTER TABLE instances DROP COLUMN delete_at;
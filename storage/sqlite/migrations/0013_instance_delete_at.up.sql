@@ -0,0 +1,2 @@
+-- When an instance whose deletion is delayed is removed
+ALTER TABLE instances ADD COLUMN delete_at DATETIME;
@@ -0,0 +1,4 @@
+DROP INDEX idx_events_project_id;
+DROP INDEX idx_instances_delete_at;
+DROP INDEX idx_instances_status;
+DROP INDEX idx_instances_name;
@@ -0,0 +1,6 @@
+-- Indexes for the filters list queries use most. Instances are already
+-- indexed by project and name together, and metadata by path.
+CREATE INDEX idx_instances_name ON instances(name);
+CREATE INDEX idx_instances_status ON instances(status);
+CREATE INDEX idx_instances_delete_at ON instances(delete_at);
+CREATE INDEX idx_events_project_id ON events(project_id);
@@ -0,0 +1 @@
+ALTER TABLE metadata DROP COLUMN flags;
@@ -0,0 +1,2 @@
+-- Opaque flags a client can store with a metadata entry, as Consul KV does
+ALTER TABLE metadata ADD COLUMN flags INTEGER NOT NULL DEFAULT 0;
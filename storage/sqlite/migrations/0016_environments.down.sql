@@ -0,0 +1,2 @@
+DROP TABLE environment_resources;
+DROP TABLE environments;
@@ -0,0 +1,21 @@
+-- Environments group resources of any type for summaries and teardown. A
+-- resource is in at most one environment; member rows are not removed when
+-- the resource itself is deleted.
+CREATE TABLE environments (
+	id TEXT PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	labels TEXT NOT NULL DEFAULT '{}',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE environment_resources (
+	environment_id TEXT NOT NULL,
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	added_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (resource_type, resource_id),
+	FOREIGN KEY (environment_id) REFERENCES environments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_environment_resources_environment_id ON environment_resources(environment_id);
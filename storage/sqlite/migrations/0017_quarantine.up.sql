@@ -0,0 +1,10 @@
+-- Resources taken out of service at startup because they break the current
+-- validation rules. data is the resource as it was stored, as JSON.
+CREATE TABLE quarantined_resources (
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	data TEXT NOT NULL,
+	problems TEXT NOT NULL DEFAULT '[]',
+	quarantined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (resource_type, resource_id)
+);
@@ -0,0 +1,11 @@
+-- Aliases map identifiers from outside DirtCloud, such as another cloud's
+-- IDs, to resources of any type. An external ID names exactly one resource;
+-- aliases are not removed when the resource itself is deleted.
+CREATE TABLE resource_aliases (
+	external_id TEXT PRIMARY KEY,
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_resource_aliases_resource ON resource_aliases(resource_type, resource_id);
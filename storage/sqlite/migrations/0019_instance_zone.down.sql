@@ -0,0 +1,2 @@
+DROP INDEX idx_instances_zone;
+ALTER TABLE instances DROP COLUMN zone;
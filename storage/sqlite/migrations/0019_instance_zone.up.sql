@@ -0,0 +1,3 @@
+-- The availability zone an instance runs in
+ALTER TABLE instances ADD COLUMN zone TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_instances_zone ON instances(zone);
@@ -0,0 +1,2 @@
+DROP INDEX idx_instances_class;
+ALTER TABLE instances DROP COLUMN class;
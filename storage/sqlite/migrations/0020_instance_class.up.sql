@@ -0,0 +1,3 @@
+-- The class of an instance: standard, or spot for preemptible instances
+ALTER TABLE instances ADD COLUMN class TEXT NOT NULL DEFAULT 'standard';
+CREATE INDEX idx_instances_class ON instances(class);
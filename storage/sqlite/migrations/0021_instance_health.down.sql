@@ -0,0 +1,2 @@
+ALTER TABLE instances DROP COLUMN auto_repair;
+ALTER TABLE instances DROP COLUMN health;
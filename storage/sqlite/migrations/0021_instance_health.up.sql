@@ -0,0 +1,4 @@
+-- The simulated health of an instance, and whether unhealthy instances are
+-- replaced
+ALTER TABLE instances ADD COLUMN health TEXT NOT NULL DEFAULT 'healthy';
+ALTER TABLE instances ADD COLUMN auto_repair BOOLEAN NOT NULL DEFAULT 0;
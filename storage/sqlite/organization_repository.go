@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// OrganizationRepository handles organization data operations
+type OrganizationRepository struct {
+	db *DB
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+const organizationSelect = `SELECT id, name, labels, quota, created_at, updated_at FROM organizations`
+
+// scanOrganization scans a single organization row
+func scanOrganization(row interface{ Scan(...interface{}) error }) (*domain.Organization, error) {
+	org := &domain.Organization{}
+	err := row.Scan(
+		&org.ID,
+		&org.Name,
+		jsonColumn{&org.Labels},
+		jsonColumn{&org.Quota},
+		&org.CreatedAt,
+		&org.UpdatedAt,
+	)
+	return org, err
+}
+
+// Create creates a new organization
+func (r *OrganizationRepository) Create(org *domain.Organization) error {
+	now := time.Now()
+	org.CreatedAt = now
+	org.UpdatedAt = now
+
+	query := `INSERT INTO organizations (id, name, labels, quota, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, org.ID, org.Name, jsonColumn{org.Labels}, jsonColumn{org.Quota}, org.CreatedAt, org.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: organizations.name") {
+			return domain.AlreadyExistsError("organization", "name", org.Name).WithCause(err)
+		}
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an organization by ID
+func (r *OrganizationRepository) GetByID(id string) (*domain.Organization, error) {
+	org, err := scanOrganization(r.db.queryRowStmt(organizationSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("organization", id)
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// List retrieves organizations with optional filtering
+func (r *OrganizationRepository) List(opts domain.OrganizationListOptions) ([]*domain.Organization, error) {
+	var orgs []*domain.Organization
+	var args []interface{}
+
+	query := organizationSelect
+	if opts.Name != "" {
+		query += " WHERE name = ?"
+		args = append(args, opts.Name)
+	}
+	query += " ORDER BY name"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// Update updates an existing organization
+func (r *OrganizationRepository) Update(id string, req domain.UpdateOrganizationRequest) (*domain.Organization, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Labels != nil {
+		existing.Labels = req.Labels
+	}
+	if req.Quota != nil {
+		existing.Quota = req.Quota
+	}
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE organizations SET name = ?, labels = ?, quota = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, jsonColumn{existing.Labels}, jsonColumn{existing.Quota}, existing.UpdatedAt, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: organizations.name") {
+			return nil, domain.AlreadyExistsError("organization", "name", existing.Name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	return existing, nil
+}
+
+// Delete deletes an organization by ID
+func (r *OrganizationRepository) Delete(id string) error {
+	var folderCount, projectCount int
+	err := r.db.queryRowStmt(`SELECT
+		(SELECT COUNT(*) FROM folders WHERE organization_id = ?),
+		(SELECT COUNT(*) FROM projects WHERE organization_id = ?)`, id, id).Scan(&folderCount, &projectCount)
+	if err != nil {
+		return fmt.Errorf("failed to check organization children: %w", err)
+	}
+
+	if folderCount > 0 || projectCount > 0 {
+		return domain.InvalidInputError("cannot delete organization with existing folders or projects", map[string]interface{}{
+			"organization_id": id,
+			"folder_count":    folderCount,
+			"project_count":   projectCount,
+		})
+	}
+
+	return r.db.deleteByID("organizations", "organization", id)
+}
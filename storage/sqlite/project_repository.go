@@ -25,12 +25,12 @@ func (r *ProjectRepository) Create(project *domain.Project) error {
 	project.CreatedAt = now
 	project.UpdatedAt = now
 
-	query := `INSERT INTO projects (id, name, created_at, updated_at) VALUES (?, ?, ?, ?)`
-	
-	_, err := r.db.Exec(query, project.ID, project.Name, project.CreatedAt, project.UpdatedAt)
+	query := `INSERT INTO projects (id, name, organization_id, folder_id, labels, chaos_profile, deletion_protection, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, project.ID, project.Name, project.OrganizationID, project.FolderID, jsonColumn{project.Labels}, project.ChaosProfile, project.DeletionProtection, project.CreatedAt, project.UpdatedAt)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed: projects.name") {
-			return domain.AlreadyExistsError("project", "name", project.Name)
+			return domain.AlreadyExistsError("project", "name", project.Name).WithCause(err)
 		}
 		return fmt.Errorf("failed to create project: %w", err)
 	}
@@ -41,14 +41,9 @@ func (r *ProjectRepository) Create(project *domain.Project) error {
 // GetByID retrieves a project by ID
 func (r *ProjectRepository) GetByID(id string) (*domain.Project, error) {
 	project := &domain.Project{}
-	query := `SELECT id, name, created_at, updated_at FROM projects WHERE id = ?`
-	
-	err := r.db.QueryRow(query, id).Scan(
-		&project.ID,
-		&project.Name,
-		&project.CreatedAt,
-		&project.UpdatedAt,
-	)
+	query := `SELECT ` + projectColumns.selectList(projectColumns.fields) + ` FROM projects WHERE id = ?`
+
+	err := r.db.queryRowStmt(query, id).Scan(scanTargets(projectFieldPtrs(project), projectColumns.fields)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain.NotFoundError("project", id)
@@ -62,14 +57,9 @@ func (r *ProjectRepository) GetByID(id string) (*domain.Project, error) {
 // GetByName retrieves a project by name
 func (r *ProjectRepository) GetByName(name string) (*domain.Project, error) {
 	project := &domain.Project{}
-	query := `SELECT id, name, created_at, updated_at FROM projects WHERE name = ?`
-	
-	err := r.db.QueryRow(query, name).Scan(
-		&project.ID,
-		&project.Name,
-		&project.CreatedAt,
-		&project.UpdatedAt,
-	)
+	query := `SELECT ` + projectColumns.selectList(projectColumns.fields) + ` FROM projects WHERE name = ?`
+
+	err := r.db.queryRowStmt(query, name).Scan(scanTargets(projectFieldPtrs(project), projectColumns.fields)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain.NotFoundError("project", name)
@@ -80,12 +70,54 @@ func (r *ProjectRepository) GetByName(name string) (*domain.Project, error) {
 	return project, nil
 }
 
+// projectColumns lists the project fields that can be selected or sorted on
+var projectColumns = newColumnSet("project",
+	"id", "name", "organization_id", "folder_id", "labels", "chaos_profile", "deletion_protection", "created_at", "updated_at")
+
+// projectFieldPtrs maps project fields to scan destinations
+func projectFieldPtrs(p *domain.Project) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                  &p.ID,
+		"name":                &p.Name,
+		"organization_id":     &p.OrganizationID,
+		"folder_id":           &p.FolderID,
+		"labels":              jsonColumn{&p.Labels},
+		"chaos_profile":       &p.ChaosProfile,
+		"deletion_protection": &p.DeletionProtection,
+		"created_at":          &p.CreatedAt,
+		"updated_at":          &p.UpdatedAt,
+	}
+}
+
 // List retrieves projects with optional filtering
 func (r *ProjectRepository) List(opts domain.ProjectListOptions) ([]*domain.Project, error) {
 	var projects []*domain.Project
+	err := r.Stream(opts, func(project *domain.Project) error {
+		projects = append(projects, project)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// Stream calls fn with each project List would return, as it is read. The
+// query stays open until fn has seen every row or returns an error, which
+// Stream then returns.
+func (r *ProjectRepository) Stream(opts domain.ProjectListOptions, fn func(*domain.Project) error) error {
 	var args []interface{}
-	
-	query := `SELECT id, name, created_at, updated_at FROM projects`
+
+	fields, err := projectColumns.selectFields(opts.Fields)
+	if err != nil {
+		return err
+	}
+	key, err := projectColumns.sortKey(opts.Sort, domain.DefaultSortField)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT ` + projectColumns.selectList(fields) + ` FROM projects`
 	var conditions []string
 
 	if opts.Name != "" {
@@ -93,37 +125,66 @@ func (r *ProjectRepository) List(opts domain.ProjectListOptions) ([]*domain.Proj
 		args = append(args, opts.Name)
 	}
 
+	if opts.Search != "" {
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, containsPattern(opts.Search))
+	}
+
+	if opts.OrganizationID != "" {
+		conditions = append(conditions, "organization_id = ?")
+		args = append(args, opts.OrganizationID)
+	}
+
+	if opts.FolderID != "" {
+		conditions = append(conditions, "folder_id = ?")
+		args = append(args, opts.FolderID)
+	}
+
+	if opts.PageToken != "" {
+		last := &domain.Project{}
+		fields, err := domain.DecodePageToken(opts.PageToken, last)
+		if err != nil {
+			return err
+		}
+		after, afterArgs, err := projectColumns.after(key, projectFieldPtrs(last), fields)
+		if err != nil {
+			return err
+		}
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += " ORDER BY name"
+	query += projectColumns.orderBy(key)
 
-	rows, err := r.db.Query(query, args...)
+	page, pageArgs := limitOffset(opts.Limit, opts.Offset)
+	query += page
+	args = append(args, pageArgs...)
+
+	rows, err := r.db.queryStmt(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list projects: %w", err)
+		return fmt.Errorf("failed to list projects: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		project := &domain.Project{}
-		err := rows.Scan(
-			&project.ID,
-			&project.Name,
-			&project.CreatedAt,
-			&project.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan project: %w", err)
+		if err := rows.Scan(scanTargets(projectFieldPtrs(project), fields)...); err != nil {
+			return fmt.Errorf("failed to scan project: %w", err)
+		}
+		if err := fn(project); err != nil {
+			return err
 		}
-		projects = append(projects, project)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating projects: %w", err)
+		return fmt.Errorf("error iterating projects: %w", err)
 	}
 
-	return projects, nil
+	return nil
 }
 
 // Update updates an existing project
@@ -134,15 +195,26 @@ func (r *ProjectRepository) Update(id string, req domain.UpdateProjectRequest) (
 		return nil, err
 	}
 
-	existing.Name = req.Name
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Labels != nil {
+		existing.Labels = req.Labels
+	}
+	if req.ChaosProfile != nil {
+		existing.ChaosProfile = *req.ChaosProfile
+	}
+	if req.DeletionProtection != nil {
+		existing.DeletionProtection = *req.DeletionProtection
+	}
 	existing.UpdatedAt = time.Now()
 
-	query := `UPDATE projects SET name = ?, updated_at = ? WHERE id = ?`
-	
-	_, err = r.db.Exec(query, existing.Name, existing.UpdatedAt, id)
+	query := `UPDATE projects SET name = ?, labels = ?, chaos_profile = ?, deletion_protection = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, jsonColumn{existing.Labels}, existing.ChaosProfile, existing.DeletionProtection, existing.UpdatedAt, id)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed: projects.name") {
-			return nil, domain.AlreadyExistsError("project", "name", existing.Name)
+			return nil, domain.AlreadyExistsError("project", "name", existing.Name).WithCause(err)
 		}
 		return nil, fmt.Errorf("failed to update project: %w", err)
 	}
@@ -150,34 +222,46 @@ func (r *ProjectRepository) Update(id string, req domain.UpdateProjectRequest) (
 	return existing, nil
 }
 
-// Delete deletes a project by ID
-func (r *ProjectRepository) Delete(id string) error {
-	// First check if project exists
-	_, err := r.GetByID(id)
+// Move reparents a project under an organization root or a folder
+func (r *ProjectRepository) Move(id string, organizationID string, folderID string) (*domain.Project, error) {
+	existing, err := r.GetByID(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check if project has instances (enforced by FK constraint, but we want specific error)
-	var instanceCount int
-	err = r.db.QueryRow("SELECT COUNT(*) FROM instances WHERE project_id = ?", id).Scan(&instanceCount)
+	existing.OrganizationID = organizationID
+	existing.FolderID = folderID
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE projects SET organization_id = ?, folder_id = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.OrganizationID, existing.FolderID, existing.UpdatedAt, id)
 	if err != nil {
-		return fmt.Errorf("failed to check project instances: %w", err)
+		return nil, fmt.Errorf("failed to move project: %w", err)
 	}
 
-	if instanceCount > 0 {
-		return domain.InvalidInputError("cannot delete project with existing instances", map[string]interface{}{
-			"project_id":      id,
-			"instance_count": instanceCount,
-		})
-	}
+	return existing, nil
+}
 
-	query := `DELETE FROM projects WHERE id = ?`
-	
-	_, err = r.db.Exec(query, id)
+// Delete deletes a project by ID
+func (r *ProjectRepository) Delete(id string) error {
+	// Instances would be removed by the FK cascade, but deleting them has to
+	// be an explicit choice of the caller
+	var instanceCount, groupCount int
+	err := r.db.queryRowStmt(`SELECT
+		(SELECT COUNT(*) FROM instances WHERE project_id = ?),
+		(SELECT COUNT(*) FROM autoscaling_groups WHERE project_id = ?)`, id, id).Scan(&instanceCount, &groupCount)
 	if err != nil {
-		return fmt.Errorf("failed to delete project: %w", err)
+		return fmt.Errorf("failed to check project children: %w", err)
 	}
 
-	return nil
-}
\ No newline at end of file
+	if instanceCount > 0 || groupCount > 0 {
+		return domain.FailedPreconditionError("cannot delete project with existing instances or autoscaling groups", map[string]interface{}{
+			"project_id":              id,
+			"instance_count":          instanceCount,
+			"autoscaling_group_count": groupCount,
+		}).WithReason(domain.ReasonResourceInUse)
+	}
+
+	return r.db.deleteByID("projects", "project", id)
+}
@@ -0,0 +1,217 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectRepository_ListSortAndFields(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewProjectRepository(db)
+	for _, p := range []struct{ id, name string }{
+		{"p1", "bravo"},
+		{"p2", "alpha"},
+		{"p3", "charlie"},
+	} {
+		require.NoError(t, repo.Create(&domain.Project{ID: p.id, Name: p.name}))
+	}
+
+	names := func(projects []*domain.Project) []string {
+		var out []string
+		for _, p := range projects {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+
+	t.Run("default order is by name", func(t *testing.T) {
+		projects, err := repo.List(domain.ProjectListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alpha", "bravo", "charlie"}, names(projects))
+	})
+
+	t.Run("descending sort", func(t *testing.T) {
+		projects, err := repo.List(domain.ProjectListOptions{
+			Sort: []domain.SortField{{Field: "name", Desc: true}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"charlie", "bravo", "alpha"}, names(projects))
+	})
+
+	t.Run("unknown sort field is rejected", func(t *testing.T) {
+		_, err := repo.List(domain.ProjectListOptions{
+			Sort: []domain.SortField{{Field: "name; DROP TABLE projects"}},
+		})
+		require.Error(t, err)
+		assert.True(t, domain.IsInvalidInput(err))
+	})
+
+	t.Run("field selection only populates requested columns", func(t *testing.T) {
+		projects, err := repo.List(domain.ProjectListOptions{Fields: []string{"name"}})
+		require.NoError(t, err)
+		require.Len(t, projects, 3)
+		assert.Equal(t, "p2", projects[0].ID)
+		assert.Equal(t, "alpha", projects[0].Name)
+		assert.True(t, projects[0].CreatedAt.IsZero())
+	})
+
+	t.Run("unknown field is rejected", func(t *testing.T) {
+		_, err := repo.List(domain.ProjectListOptions{Fields: []string{"secret"}})
+		require.Error(t, err)
+		assert.True(t, domain.IsInvalidInput(err))
+	})
+}
+
+func TestProjectRepository_ListSearchAndPage(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewProjectRepository(db)
+	for _, p := range []struct{ id, name string }{
+		{"p1", "web-alpha"},
+		{"p2", "Web-bravo"},
+		{"p3", "db-charlie"},
+		{"p4", "web_delta"},
+		{"p5", "web%echo"},
+	} {
+		require.NoError(t, repo.Create(&domain.Project{ID: p.id, Name: p.name}))
+	}
+
+	names := func(projects []*domain.Project) []string {
+		var out []string
+		for _, p := range projects {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		opts     domain.ProjectListOptions
+		expected []string
+	}{
+		{name: "search ignores case", opts: domain.ProjectListOptions{Search: "WEB-"}, expected: []string{"Web-bravo", "web-alpha"}},
+		{name: "search matches anywhere", opts: domain.ProjectListOptions{Search: "char"}, expected: []string{"db-charlie"}},
+		{name: "underscore is literal", opts: domain.ProjectListOptions{Search: "_"}, expected: []string{"web_delta"}},
+		{name: "percent is literal", opts: domain.ProjectListOptions{Search: "%"}, expected: []string{"web%echo"}},
+		{name: "limit", opts: domain.ProjectListOptions{Limit: 2}, expected: []string{"Web-bravo", "db-charlie"}},
+		{name: "limit and offset", opts: domain.ProjectListOptions{Limit: 2, Offset: 2}, expected: []string{"web%echo", "web-alpha"}},
+		{name: "offset without limit", opts: domain.ProjectListOptions{Offset: 4}, expected: []string{"web_delta"}},
+		{name: "offset past the end", opts: domain.ProjectListOptions{Limit: 2, Offset: 10}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, err := repo.List(tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names(projects))
+		})
+	}
+}
+
+func TestProjectRepository_ListPageToken(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewProjectRepository(db)
+	for _, p := range []struct{ id, name string }{
+		{"p1", "alpha"},
+		{"p2", "bravo"},
+		{"p3", "charlie"},
+		{"p4", "delta"},
+		{"p5", "echo"},
+		{"p6", "foxtrot"},
+	} {
+		require.NoError(t, repo.Create(&domain.Project{ID: p.id, Name: p.name}))
+	}
+
+	page := func(opts domain.ProjectListOptions) ([]string, string) {
+		t.Helper()
+		projects, err := repo.List(opts)
+		require.NoError(t, err)
+		var names []string
+		for _, p := range projects {
+			names = append(names, p.Name)
+		}
+		token, err := domain.NewPageToken(projects[len(projects)-1], domain.PageKey(opts.Sort))
+		require.NoError(t, err)
+		return names, token
+	}
+
+	names, token := page(domain.ProjectListOptions{Limit: 2})
+	assert.Equal(t, []string{"alpha", "bravo"}, names)
+
+	// Deleting the last result seen, one before it and one after it, and
+	// creating one before it, neither skips nor repeats the others
+	require.NoError(t, repo.Delete("p1"))
+	require.NoError(t, repo.Delete("p2"))
+	require.NoError(t, repo.Delete("p4"))
+	require.NoError(t, repo.Create(&domain.Project{ID: "p7", Name: "aardvark"}))
+
+	names, token = page(domain.ProjectListOptions{Limit: 2, PageToken: token})
+	assert.Equal(t, []string{"charlie", "echo"}, names)
+	names, _ = page(domain.ProjectListOptions{Limit: 2, PageToken: token})
+	assert.Equal(t, []string{"foxtrot"}, names)
+
+	// Descending sorts page the other way
+	names, token = page(domain.ProjectListOptions{Limit: 2, Sort: []domain.SortField{{Field: "name", Desc: true}}})
+	assert.Equal(t, []string{"foxtrot", "echo"}, names)
+	names, _ = page(domain.ProjectListOptions{Limit: 2, PageToken: token, Sort: []domain.SortField{{Field: "name", Desc: true}}})
+	assert.Equal(t, []string{"charlie", "aardvark"}, names)
+
+	// Ties on the sort field are broken by id
+	protected := true
+	for _, id := range []string{"p3", "p5", "p6"} {
+		_, err := repo.Update(id, domain.UpdateProjectRequest{DeletionProtection: &protected})
+		require.NoError(t, err)
+	}
+	sort := []domain.SortField{{Field: "deletion_protection", Desc: true}}
+	names, token = page(domain.ProjectListOptions{Limit: 2, Sort: sort})
+	assert.Equal(t, []string{"charlie", "echo"}, names)
+	names, _ = page(domain.ProjectListOptions{Limit: 2, PageToken: token, Sort: sort})
+	assert.Equal(t, []string{"foxtrot", "aardvark"}, names)
+
+	// Tokens only continue listings in the order they were made for
+	_, err := repo.List(domain.ProjectListOptions{PageToken: token})
+	assert.True(t, domain.IsInvalidInput(err))
+	_, err = repo.List(domain.ProjectListOptions{PageToken: "not a token"})
+	assert.True(t, domain.IsInvalidInput(err))
+}
+
+func TestProjectRepository_Stream(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewProjectRepository(db)
+	for _, name := range []string{"charlie", "alpha", "bravo"} {
+		require.NoError(t, repo.Create(&domain.Project{ID: name, Name: name}))
+	}
+
+	var names []string
+	err := repo.Stream(domain.ProjectListOptions{}, func(p *domain.Project) error {
+		names = append(names, p.Name)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "bravo", "charlie"}, names)
+
+	// An error from fn stops the stream and is returned as is
+	stop := errors.New("stop")
+	names = nil
+	err = repo.Stream(domain.ProjectListOptions{}, func(p *domain.Project) error {
+		names = append(names, p.Name)
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []string{"alpha"}, names)
+
+	// The connection is released once the stream stops
+	_, err = repo.GetByID("alpha")
+	require.NoError(t, err)
+}
@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Replica simulates a read replica of a database that lags it. Each Sync
+// copies the database into an in-memory snapshot; the replica serves the
+// newest snapshot taken at least its lag ago, so its reads see the database
+// as it was between lag and lag plus the sync interval ago.
+type Replica[R any] struct {
+	primary *DB
+	lag     time.Duration
+	bind    func(db *DB) R
+
+	mu sync.Mutex
+
+	// snapshots are ordered oldest first. Snapshots replication has moved
+	// past are kept until the next sync, so that reads still using them can
+	// finish.
+	snapshots []replicaSnapshot
+}
+
+// replicaSnapshot is a copy of the primary taken at a point in time
+type replicaSnapshot struct {
+	at time.Time
+	db *DB
+}
+
+// NewReplica creates a replica of primary lagging it by lag, serving reads
+// through the repositories bind builds on a snapshot. It serves nothing
+// until its first Sync.
+func NewReplica[R any](primary *DB, lag time.Duration, bind func(db *DB) R) *Replica[R] {
+	return &Replica[R]{primary: primary, lag: lag, bind: bind}
+}
+
+// Sync snapshots the primary, moving the replica forward to the newest
+// snapshot at least its lag old, and closes the snapshots it no longer needs
+func (r *Replica[R]) Sync() error {
+	snapshot, err := r.snapshot()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.snapshots = append(r.snapshots, replicaSnapshot{at: time.Now(), db: snapshot})
+	served := r.served(time.Now())
+	var closed []replicaSnapshot
+	if served > 1 {
+		closed = append(closed, r.snapshots[:served-1]...)
+		r.snapshots = append([]replicaSnapshot(nil), r.snapshots[served-1:]...)
+	}
+	r.mu.Unlock()
+
+	for _, s := range closed {
+		if err := s.db.Close(); err != nil {
+			log.Printf("Failed to close replica snapshot: %v", err)
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the repositories of the snapshot the replica serves and
+// when it was taken. It returns false before the first Sync.
+func (r *Replica[R]) Snapshot() (R, time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.snapshots) == 0 {
+		var none R
+		return none, time.Time{}, false
+	}
+	s := r.snapshots[r.served(time.Now())]
+	return r.bind(s.db), s.at, true
+}
+
+// Close closes every snapshot
+func (r *Replica[R]) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.snapshots {
+		s.db.Close()
+	}
+	r.snapshots = nil
+	return nil
+}
+
+// served returns the index of the snapshot served at now: the newest one at
+// least lag old, or the oldest while none is that old yet. r.mu must be held
+// and there must be a snapshot.
+func (r *Replica[R]) served(now time.Time) int {
+	served := 0
+	for i, s := range r.snapshots {
+		if now.Sub(s.at) >= r.lag {
+			served = i
+		}
+	}
+	return served
+}
+
+// snapshot copies the primary into a new read-only in-memory database
+func (r *Replica[R]) snapshot() (*DB, error) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replica snapshot: %w", err)
+	}
+
+	// Every connection to :memory: opens a database of its own, so the
+	// snapshot must stay on one
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	if err := copyDatabase(db, r.primary.DB); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to copy replica snapshot: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA query_only = ON"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to configure replica snapshot: %w", err)
+	}
+	return &DB{DB: db, stmts: newStatements(), duplicateInstanceNames: r.primary.duplicateInstanceNames}, nil
+}
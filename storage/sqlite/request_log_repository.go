@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// RequestLogRepository handles request log data operations
+type RequestLogRepository struct {
+	db *DB
+}
+
+// NewRequestLogRepository creates a new request log repository
+func NewRequestLogRepository(db *DB) *RequestLogRepository {
+	return &RequestLogRepository{db: db}
+}
+
+// Append logs a request and drops the oldest entries beyond the newest keep
+func (r *RequestLogRepository) Append(entry *domain.RequestLogEntry, keep int) error {
+	chaos := entry.Chaos
+	if chaos == nil {
+		chaos = []string{}
+	}
+
+	query := `INSERT INTO request_log (method, path, query, status, latency_ms, actor, client_ip, chaos, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := r.db.execStmt(query, entry.Method, entry.Path, entry.Query, entry.Status, entry.LatencyMS,
+		entry.Actor, entry.ClientIP, jsonColumn{chaos}, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to log request: %w", err)
+	}
+
+	entry.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read request log ID: %w", err)
+	}
+
+	if _, err := r.db.execStmt(`DELETE FROM request_log WHERE id <= ?`, entry.ID-int64(keep)); err != nil {
+		return fmt.Errorf("failed to trim request log: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves logged requests, newest first, with optional filtering
+func (r *RequestLogRepository) List(opts domain.RequestLogListOptions) ([]*domain.RequestLogEntry, error) {
+	var entries []*domain.RequestLogEntry
+	var args []interface{}
+
+	query := `SELECT id, method, path, query, status, latency_ms, actor, client_ip, chaos, created_at FROM request_log`
+	var conditions []string
+
+	if opts.Method != "" {
+		conditions = append(conditions, "method = ?")
+		args = append(args, strings.ToUpper(opts.Method))
+	}
+
+	if opts.Path != "" {
+		conditions = append(conditions, "substr(path, 1, ?) = ?")
+		args = append(args, len(opts.Path), opts.Path)
+	}
+
+	if opts.Status != 0 {
+		conditions = append(conditions, "status = ?")
+		args = append(args, opts.Status)
+	}
+
+	if opts.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, opts.Actor)
+	}
+
+	if opts.Chaos {
+		conditions = append(conditions, "chaos != '[]'")
+	}
+
+	if !opts.Since.IsZero() {
+		conditions = append(conditions, "julianday(created_at) >= julianday(?)")
+		args = append(args, opts.Since)
+	}
+
+	if !opts.Until.IsZero() {
+		conditions = append(conditions, "julianday(created_at) < julianday(?)")
+		args = append(args, opts.Until)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY id DESC"
+
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list requests: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := &domain.RequestLogEntry{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Method,
+			&entry.Path,
+			&entry.Query,
+			&entry.Status,
+			&entry.LatencyMS,
+			&entry.Actor,
+			&entry.ClientIP,
+			jsonColumn{&entry.Chaos},
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan request: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating requests: %w", err)
+	}
+
+	return entries, nil
+}
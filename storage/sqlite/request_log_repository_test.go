@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogRepository_AppendTrims(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewRequestLogRepository(db)
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, entry := range []*domain.RequestLogEntry{
+		{Method: "GET", Path: "/v1/projects", Status: 200, Actor: "admin"},
+		{Method: "POST", Path: "/v1/projects", Status: 500, Actor: "admin", ClientIP: "198.51.100.1", Chaos: []string{"error"}},
+		{Method: "GET", Path: "/v1/projects/p1", Status: 404, Actor: "token:alice"},
+		{Method: "DELETE", Path: "/v1/instances/i1", Status: 204, Actor: "admin", Chaos: []string{"latency"}},
+	} {
+		entry.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, repo.Append(entry, 3))
+	}
+
+	paths := func(entries []*domain.RequestLogEntry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Method+" "+e.Path)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		opts     domain.RequestLogListOptions
+		expected []string
+	}{
+		{"oldest entry trimmed", domain.RequestLogListOptions{}, []string{"DELETE /v1/instances/i1", "GET /v1/projects/p1", "POST /v1/projects"}},
+		{"method", domain.RequestLogListOptions{Method: "get"}, []string{"GET /v1/projects/p1"}},
+		{"path prefix", domain.RequestLogListOptions{Path: "/v1/projects"}, []string{"GET /v1/projects/p1", "POST /v1/projects"}},
+		{"status", domain.RequestLogListOptions{Status: 500}, []string{"POST /v1/projects"}},
+		{"actor", domain.RequestLogListOptions{Actor: "token:alice"}, []string{"GET /v1/projects/p1"}},
+		{"chaos", domain.RequestLogListOptions{Chaos: true}, []string{"DELETE /v1/instances/i1", "POST /v1/projects"}},
+		{"time range", domain.RequestLogListOptions{Since: base.Add(2 * time.Minute), Until: base.Add(3 * time.Minute)}, []string{"GET /v1/projects/p1"}},
+		{"limit", domain.RequestLogListOptions{Limit: 1}, []string{"DELETE /v1/instances/i1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := repo.List(tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, paths(entries))
+		})
+	}
+
+	entries, err := repo.List(domain.RequestLogListOptions{Status: 500})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, []string{"error"}, entries[0].Chaos)
+	assert.Equal(t, "198.51.100.1", entries[0].ClientIP)
+}
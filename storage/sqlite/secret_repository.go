@@ -0,0 +1,294 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// SecretRepository handles secret and secret version data operations
+type SecretRepository struct {
+	db *DB
+}
+
+// NewSecretRepository creates a new secret repository
+func NewSecretRepository(db *DB) *SecretRepository {
+	return &SecretRepository{db: db}
+}
+
+const secretSelect = `SELECT id, project_id, name, latest_version, rotation_period_seconds, next_rotation_at, created_at, updated_at FROM secrets`
+
+const secretVersionSelect = `SELECT secret_id, version, state, created_at, destroyed_at FROM secret_versions`
+
+// scanSecret scans a single secret row
+func scanSecret(row interface{ Scan(...interface{}) error }) (*domain.Secret, error) {
+	secret := &domain.Secret{}
+	err := row.Scan(
+		&secret.ID,
+		&secret.ProjectID,
+		&secret.Name,
+		&secret.LatestVersion,
+		&secret.RotationPeriodSeconds,
+		&secret.NextRotationAt,
+		&secret.CreatedAt,
+		&secret.UpdatedAt,
+	)
+	return secret, err
+}
+
+// scanSecretVersion scans a single secret version row
+func scanSecretVersion(row interface{ Scan(...interface{}) error }) (*domain.SecretVersion, error) {
+	version := &domain.SecretVersion{}
+	err := row.Scan(
+		&version.SecretID,
+		&version.Version,
+		&version.State,
+		&version.CreatedAt,
+		&version.DestroyedAt,
+	)
+	return version, err
+}
+
+// querySecrets runs a secret query and scans every row
+func (r *SecretRepository) querySecrets(query string, args ...interface{}) ([]*domain.Secret, error) {
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	defer rows.Close()
+
+	var secrets []*domain.Secret
+	for rows.Next() {
+		secret, err := scanSecret(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan secret: %w", err)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating secrets: %w", err)
+	}
+
+	return secrets, nil
+}
+
+// Create creates a new secret
+func (r *SecretRepository) Create(secret *domain.Secret) error {
+	now := time.Now()
+	secret.CreatedAt = now
+	secret.UpdatedAt = now
+
+	query := `INSERT INTO secrets (id, project_id, name, latest_version, rotation_period_seconds, next_rotation_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, secret.ID, secret.ProjectID, secret.Name, secret.LatestVersion, secret.RotationPeriodSeconds,
+		secret.NextRotationAt, secret.CreatedAt, secret.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: secrets.project_id, secrets.name") {
+			return domain.AlreadyExistsError("secret", "name", secret.Name).WithCause(err)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.ForeignKeyViolationError("project", "id", secret.ProjectID).WithCause(err)
+		}
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a secret by ID
+func (r *SecretRepository) GetByID(id string) (*domain.Secret, error) {
+	secret, err := scanSecret(r.db.queryRowStmt(secretSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("secret", id)
+		}
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// List retrieves secrets with optional filtering
+func (r *SecretRepository) List(opts domain.SecretListOptions) ([]*domain.Secret, error) {
+	var args []interface{}
+
+	query := secretSelect
+	var conditions []string
+
+	if opts.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, opts.ProjectID)
+	}
+
+	if opts.Name != "" {
+		conditions = append(conditions, "name = ?")
+		args = append(args, opts.Name)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY name, id"
+
+	return r.querySecrets(query, args...)
+}
+
+// ListDueForRotation retrieves the secrets whose next rotation is at or before now
+func (r *SecretRepository) ListDueForRotation(now time.Time) ([]*domain.Secret, error) {
+	return r.querySecrets(secretSelect+` WHERE next_rotation_at IS NOT NULL AND next_rotation_at <= ? ORDER BY next_rotation_at, id`, now)
+}
+
+// Update updates the name and rotation schedule of an existing secret
+func (r *SecretRepository) Update(id string, req domain.UpdateSecretRequest, nextRotationAt *time.Time) (*domain.Secret, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.RotationPeriodSeconds != nil {
+		existing.RotationPeriodSeconds = *req.RotationPeriodSeconds
+	}
+	existing.NextRotationAt = nextRotationAt
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE secrets SET name = ?, rotation_period_seconds = ?, next_rotation_at = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, existing.RotationPeriodSeconds, existing.NextRotationAt, existing.UpdatedAt, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: secrets.project_id, secrets.name") {
+			return nil, domain.AlreadyExistsError("secret", "name", existing.Name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to update secret: %w", err)
+	}
+
+	return existing, nil
+}
+
+// SetNextRotation reschedules the next rotation of a secret
+func (r *SecretRepository) SetNextRotation(id string, next *time.Time) error {
+	result, err := r.db.execStmt(`UPDATE secrets SET next_rotation_at = ?, updated_at = ? WHERE id = ?`, next, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule secret rotation: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.NotFoundError("secret", id)
+	}
+
+	return nil
+}
+
+// Delete deletes a secret and all of its versions
+func (r *SecretRepository) Delete(id string) error {
+	return r.db.deleteByID("secrets", "secret", id)
+}
+
+// AddVersion stores payload as the next version of a secret
+func (r *SecretRepository) AddVersion(secretID, payload string) (*domain.SecretVersion, error) {
+	secret, err := r.GetByID(secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &domain.SecretVersion{
+		SecretID:  secretID,
+		Version:   secret.LatestVersion + 1,
+		State:     domain.SecretVersionEnabled,
+		CreatedAt: time.Now(),
+	}
+
+	query := `INSERT INTO secret_versions (secret_id, version, state, payload, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	if _, err := r.db.execStmt(query, version.SecretID, version.Version, version.State, payload, version.CreatedAt); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: secret_versions.secret_id, secret_versions.version") {
+			return nil, domain.AlreadyExistsError("secret version", "version", fmt.Sprint(version.Version)).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to add secret version: %w", err)
+	}
+
+	_, err = r.db.execStmt(`UPDATE secrets SET latest_version = ?, updated_at = ? WHERE id = ?`, version.Version, version.CreatedAt, secretID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add secret version: %w", err)
+	}
+
+	return version, nil
+}
+
+// GetVersion retrieves a version of a secret
+func (r *SecretRepository) GetVersion(secretID string, version int) (*domain.SecretVersion, error) {
+	v, err := scanSecretVersion(r.db.queryRowStmt(secretVersionSelect+` WHERE secret_id = ? AND version = ?`, secretID, version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("secret version", fmt.Sprintf("%s/%d", secretID, version))
+		}
+		return nil, fmt.Errorf("failed to get secret version: %w", err)
+	}
+
+	return v, nil
+}
+
+// ListVersions retrieves the versions of a secret, newest first
+func (r *SecretRepository) ListVersions(secretID string) ([]*domain.SecretVersion, error) {
+	rows, err := r.db.queryStmt(secretVersionSelect+` WHERE secret_id = ? ORDER BY version DESC`, secretID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secret versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*domain.SecretVersion
+	for rows.Next() {
+		version, err := scanSecretVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan secret version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating secret versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetPayload retrieves the payload of a secret version
+func (r *SecretRepository) GetPayload(secretID string, version int) (string, error) {
+	var payload string
+	err := r.db.queryRowStmt(`SELECT payload FROM secret_versions WHERE secret_id = ? AND version = ?`, secretID, version).Scan(&payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", domain.NotFoundError("secret version", fmt.Sprintf("%s/%d", secretID, version))
+		}
+		return "", fmt.Errorf("failed to get secret payload: %w", err)
+	}
+
+	return payload, nil
+}
+
+// DestroyVersion marks a secret version destroyed and erases its payload
+func (r *SecretRepository) DestroyVersion(secretID string, version int) (*domain.SecretVersion, error) {
+	existing, err := r.GetVersion(secretID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	existing.State = domain.SecretVersionDestroyed
+	existing.DestroyedAt = &now
+
+	query := `UPDATE secret_versions SET state = ?, payload = '', destroyed_at = ? WHERE secret_id = ? AND version = ?`
+
+	if _, err := r.db.execStmt(query, existing.State, existing.DestroyedAt, secretID, version); err != nil {
+		return nil, fmt.Errorf("failed to destroy secret version: %w", err)
+	}
+
+	return existing, nil
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// maxPreparedStatements caps how many statements a database keeps prepared.
+// List queries vary with their filters, sort order and fields, so past the
+// cap further queries run unprepared rather than growing the cache.
+const maxPreparedStatements = 256
+
+// statements caches prepared statements by query, shared by a database and
+// the transaction handles made from it
+type statements struct {
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStatements() *statements {
+	return &statements{stmts: make(map[string]*sql.Stmt)}
+}
+
+// lookup returns the statement prepared for query, if any
+func (s *statements) lookup(query string) *sql.Stmt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stmts[query]
+}
+
+// prepared returns the statement prepared for query, preparing it on first
+// use, or nil once the cache is full
+func (s *statements) prepared(db *sql.DB, query string) (*sql.Stmt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stmt, ok := s.stmts[query]; ok {
+		return stmt, nil
+	}
+	if len(s.stmts) >= maxPreparedStatements {
+		return nil, nil
+	}
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	s.stmts[query] = stmt
+	return stmt, nil
+}
+
+// close closes every prepared statement
+func (s *statements) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for query, stmt := range s.stmts {
+		stmt.Close()
+		delete(s.stmts, query)
+	}
+}
+
+// stmt returns the prepared statement for a single-statement query, or nil
+// when the query has to run unprepared.
+//
+// Statements are only prepared outside transactions: preparing one takes a
+// connection from the pool, and while a transaction holds the only
+// connection to an in-memory database a new one would open an empty
+// database. Transactions reuse the statements already prepared.
+func (db *DB) stmt(query string) (*sql.Stmt, error) {
+	if db.stmts == nil {
+		return nil, nil
+	}
+
+	if db.tx != nil {
+		stmt := db.stmts.lookup(query)
+		if stmt == nil {
+			return nil, nil
+		}
+		// Closed with the transaction
+		return db.tx.Stmt(stmt), nil
+	}
+	return db.stmts.prepared(db.DB, query)
+}
+
+// execStmt is Exec with a prepared statement. Repositories use it, and
+// queryStmt and queryRowStmt, for their queries; scripts of several
+// statements, such as migrations, have to use Exec.
+func (db *DB) execStmt(query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := db.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return db.Exec(query, args...)
+	}
+	start := time.Now()
+	result, err := stmt.Exec(args...)
+	db.traced(query, start, err)
+	return result, err
+}
+
+// queryStmt is Query with a prepared statement
+func (db *DB) queryStmt(query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := db.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return db.Query(query, args...)
+	}
+	start := time.Now()
+	rows, err := stmt.Query(args...)
+	db.traced(query, start, err)
+	return rows, err
+}
+
+// queryRowStmt is QueryRow with a prepared statement. A query that cannot
+// be prepared runs unprepared, so its error is reported by Scan.
+func (db *DB) queryRowStmt(query string, args ...interface{}) *sql.Row {
+	stmt, err := db.stmt(query)
+	if err != nil || stmt == nil {
+		return db.QueryRow(query, args...)
+	}
+	start := time.Now()
+	row := stmt.QueryRow(args...)
+	db.traced(query, start, row.Err())
+	return row
+}
+
+// Close closes the prepared statements and the database
+func (db *DB) Close() error {
+	if db.stmts != nil {
+		db.stmts.close()
+	}
+	return db.DB.Close()
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatements_Reused(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewProjectRepository(db)
+	require.NoError(t, repo.Create(&domain.Project{ID: "p1", Name: "one"}))
+
+	_, err := repo.GetByID("p1")
+	require.NoError(t, err)
+	prepared := len(db.stmts.stmts)
+	_, err = repo.GetByID("p1")
+	require.NoError(t, err)
+	assert.Equal(t, prepared, len(db.stmts.stmts), "the second read reuses the first's statement")
+
+	// Transactions use the statements prepared outside them
+	err = db.WithTx(func(tx *DB) error {
+		_, err := NewProjectRepository(tx).GetByID("p1")
+		return err
+	})
+	require.NoError(t, err)
+
+	// Once the cache is full, queries still run, unprepared
+	for i := 0; len(db.stmts.stmts) < maxPreparedStatements; i++ {
+		_, err := db.stmt(fmt.Sprintf("SELECT %d", i))
+		require.NoError(t, err)
+	}
+	_, err = repo.List(domain.ProjectListOptions{Search: "on"})
+	require.NoError(t, err)
+	assert.Equal(t, maxPreparedStatements, len(db.stmts.stmts))
+}
+
+func TestDeleteByID(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	projects := NewProjectRepository(db)
+	instances := NewInstanceRepository(db)
+	require.NoError(t, projects.Create(&domain.Project{ID: "p1", Name: "one", CreatedAt: now, UpdatedAt: now}))
+	require.NoError(t, instances.Create(&domain.Instance{ID: "i1", ProjectID: "p1", Name: "vm", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusRunning}))
+
+	err := projects.Delete("p1")
+	assert.True(t, domain.IsFailedPrecondition(err), "got %v", err)
+
+	require.NoError(t, instances.Delete("i1"))
+	err = instances.Delete("i1")
+	assert.True(t, domain.IsNotFound(err), "got %v", err)
+
+	require.NoError(t, projects.Delete("p1"))
+	err = projects.Delete("p1")
+	assert.True(t, domain.IsNotFound(err), "got %v", err)
+}
+
+func TestQueryPlans_UseIndexes(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tests := []struct {
+		query string
+		index string
+	}{
+		{`SELECT id FROM instances WHERE project_id = ? ORDER BY name`, "idx_instances_project_name"},
+		{`SELECT id FROM instances WHERE name = ?`, "idx_instances_name"},
+		{`SELECT id FROM instances WHERE status = ?`, "idx_instances_status"},
+		{`SELECT id FROM instances WHERE delete_at IS NOT NULL AND delete_at <= ?`, "idx_instances_delete_at"},
+		{`SELECT id FROM events WHERE project_id = ?`, "idx_events_project_id"},
+		{`SELECT id FROM metadata WHERE path >= ? AND path < ? ORDER BY path`, "sqlite_autoindex_metadata"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			args := []interface{}{"a", "b"}[:strings.Count(tt.query, "?")]
+			rows, err := db.Query(`EXPLAIN QUERY PLAN `+tt.query, args...)
+			require.NoError(t, err)
+			defer rows.Close()
+
+			var plan string
+			for rows.Next() {
+				var id, parent, notUsed int
+				var detail string
+				require.NoError(t, rows.Scan(&id, &parent, &notUsed, &detail))
+				plan += detail + "\n"
+			}
+			require.NoError(t, rows.Err())
+			assert.Contains(t, plan, tt.index)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// SubscriptionRepository handles pub/sub subscription and message data
+// operations
+type SubscriptionRepository struct {
+	db *DB
+}
+
+// NewSubscriptionRepository creates a new subscription repository
+func NewSubscriptionRepository(db *DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+const subscriptionSelect = `SELECT id, project_id, topic_id, name, ack_deadline_seconds, created_at, updated_at FROM subscriptions`
+
+// scanSubscription scans a single subscription row
+func scanSubscription(row interface{ Scan(...interface{}) error }) (*domain.Subscription, error) {
+	subscription := &domain.Subscription{}
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.ProjectID,
+		&subscription.TopicID,
+		&subscription.Name,
+		&subscription.AckDeadlineSeconds,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	return subscription, err
+}
+
+// Create creates a new subscription
+func (r *SubscriptionRepository) Create(subscription *domain.Subscription) error {
+	now := time.Now()
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+
+	query := `INSERT INTO subscriptions (id, project_id, topic_id, name, ack_deadline_seconds, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, subscription.ID, subscription.ProjectID, subscription.TopicID, subscription.Name,
+		subscription.AckDeadlineSeconds, subscription.CreatedAt, subscription.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: subscriptions.project_id, subscriptions.name") {
+			return domain.AlreadyExistsError("subscription", "name", subscription.Name).WithCause(err)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.ForeignKeyViolationError("topic", "id", subscription.TopicID).WithCause(err)
+		}
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a subscription by ID
+func (r *SubscriptionRepository) GetByID(id string) (*domain.Subscription, error) {
+	subscription, err := scanSubscription(r.db.queryRowStmt(subscriptionSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("subscription", id)
+		}
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// List retrieves subscriptions with optional filtering
+func (r *SubscriptionRepository) List(opts domain.SubscriptionListOptions) ([]*domain.Subscription, error) {
+	var subscriptions []*domain.Subscription
+	var args []interface{}
+
+	query := subscriptionSelect
+	var conditions []string
+
+	if opts.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, opts.ProjectID)
+	}
+
+	if opts.TopicID != "" {
+		conditions = append(conditions, "topic_id = ?")
+		args = append(args, opts.TopicID)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY name, id"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// Update updates an existing subscription
+func (r *SubscriptionRepository) Update(id string, req domain.UpdateSubscriptionRequest) (*domain.Subscription, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.AckDeadlineSeconds != nil {
+		existing.AckDeadlineSeconds = *req.AckDeadlineSeconds
+	}
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE subscriptions SET ack_deadline_seconds = ?, updated_at = ? WHERE id = ?`
+
+	if _, err := r.db.execStmt(query, existing.AckDeadlineSeconds, existing.UpdatedAt, id); err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	return existing, nil
+}
+
+// Delete deletes a subscription and its undelivered messages
+func (r *SubscriptionRepository) Delete(id string) error {
+	return r.db.deleteByID("subscriptions", "subscription", id)
+}
+
+// Pull leases up to max messages of a subscription that are visible at now,
+// oldest first, hiding them from other pulls until leaseEnd
+func (r *SubscriptionRepository) Pull(subscriptionID string, max int, now, leaseEnd time.Time) ([]domain.ReceivedMessage, error) {
+	query := `SELECT message_id, data, attributes, published_at, delivery_attempt FROM subscription_messages
+		WHERE subscription_id = ? AND visible_at <= ? ORDER BY published_at, rowid LIMIT ?`
+
+	rows, err := r.db.queryStmt(query, subscriptionID, now, max)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull messages: %w", err)
+	}
+
+	var received []domain.ReceivedMessage
+	for rows.Next() {
+		var m domain.ReceivedMessage
+		if err := rows.Scan(&m.Message.ID, &m.Message.Data, jsonColumn{&m.Message.Attributes}, &m.Message.PublishedAt, &m.DeliveryAttempt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		received = append(received, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
+	}
+
+	// Each delivery gets its own ack ID, so acknowledging an expired lease
+	// does not remove a message that was redelivered
+	for i := range received {
+		m := &received[i]
+		m.DeliveryAttempt++
+		m.AckID = fmt.Sprintf("%s:%d", m.Message.ID, m.DeliveryAttempt)
+
+		_, err := r.db.execStmt(`UPDATE subscription_messages SET delivery_attempt = ?, ack_id = ?, visible_at = ? WHERE subscription_id = ? AND message_id = ?`,
+			m.DeliveryAttempt, m.AckID, leaseEnd, subscriptionID, m.Message.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lease message: %w", err)
+		}
+	}
+
+	return received, nil
+}
+
+// Acknowledge removes the messages of a subscription whose current delivery
+// has one of ackIDs and returns how many were removed. Unknown and stale ack
+// IDs are ignored.
+func (r *SubscriptionRepository) Acknowledge(subscriptionID string, ackIDs []string) (int, error) {
+	acked := 0
+	for _, ackID := range ackIDs {
+		result, err := r.db.execStmt(`DELETE FROM subscription_messages WHERE subscription_id = ? AND ack_id = ?`, subscriptionID, ackID)
+		if err != nil {
+			return acked, fmt.Errorf("failed to acknowledge message: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			acked += int(n)
+		}
+	}
+
+	return acked, nil
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// InstanceTemplateRepository handles instance template data operations
+type InstanceTemplateRepository struct {
+	db *DB
+}
+
+// NewInstanceTemplateRepository creates a new instance template repository
+func NewInstanceTemplateRepository(db *DB) *InstanceTemplateRepository {
+	return &InstanceTemplateRepository{db: db}
+}
+
+const templateSelect = `SELECT id, name, cpu, memory_mb, image, labels, created_at, updated_at FROM instance_templates`
+
+// scanTemplate scans a single instance template row
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*domain.InstanceTemplate, error) {
+	tmpl := &domain.InstanceTemplate{}
+	err := row.Scan(
+		&tmpl.ID,
+		&tmpl.Name,
+		&tmpl.CPU,
+		&tmpl.MemoryMB,
+		&tmpl.Image,
+		jsonColumn{&tmpl.Labels},
+		&tmpl.CreatedAt,
+		&tmpl.UpdatedAt,
+	)
+	return tmpl, err
+}
+
+// Create creates a new instance template
+func (r *InstanceTemplateRepository) Create(tmpl *domain.InstanceTemplate) error {
+	now := time.Now()
+	tmpl.CreatedAt = now
+	tmpl.UpdatedAt = now
+
+	query := `INSERT INTO instance_templates (id, name, cpu, memory_mb, image, labels, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, tmpl.ID, tmpl.Name, tmpl.CPU, tmpl.MemoryMB, tmpl.Image, jsonColumn{tmpl.Labels}, tmpl.CreatedAt, tmpl.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: instance_templates.name") {
+			return domain.AlreadyExistsError("instance template", "name", tmpl.Name).WithCause(err)
+		}
+		return fmt.Errorf("failed to create instance template: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an instance template by ID
+func (r *InstanceTemplateRepository) GetByID(id string) (*domain.InstanceTemplate, error) {
+	tmpl, err := scanTemplate(r.db.queryRowStmt(templateSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("instance template", id)
+		}
+		return nil, fmt.Errorf("failed to get instance template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// List retrieves instance templates with optional filtering
+func (r *InstanceTemplateRepository) List(opts domain.InstanceTemplateListOptions) ([]*domain.InstanceTemplate, error) {
+	var templates []*domain.InstanceTemplate
+	var args []interface{}
+
+	query := templateSelect
+	if opts.Name != "" {
+		query += " WHERE name = ?"
+		args = append(args, opts.Name)
+	}
+	query += " ORDER BY name"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instance templates: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		tmpl, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan instance template: %w", err)
+		}
+		templates = append(templates, tmpl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating instance templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// Update updates an existing instance template
+func (r *InstanceTemplateRepository) Update(id string, req domain.UpdateInstanceTemplateRequest) (*domain.InstanceTemplate, error) {
+	existing, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.CPU != nil {
+		existing.CPU = *req.CPU
+	}
+	if req.MemoryMB != nil {
+		existing.MemoryMB = *req.MemoryMB
+	}
+	if req.Image != nil {
+		existing.Image = *req.Image
+	}
+	if req.Labels != nil {
+		existing.Labels = req.Labels
+	}
+	existing.UpdatedAt = time.Now()
+
+	query := `UPDATE instance_templates SET name = ?, cpu = ?, memory_mb = ?, image = ?, labels = ?, updated_at = ? WHERE id = ?`
+
+	_, err = r.db.execStmt(query, existing.Name, existing.CPU, existing.MemoryMB, existing.Image, jsonColumn{existing.Labels}, existing.UpdatedAt, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: instance_templates.name") {
+			return nil, domain.AlreadyExistsError("instance template", "name", existing.Name).WithCause(err)
+		}
+		return nil, fmt.Errorf("failed to update instance template: %w", err)
+	}
+
+	return existing, nil
+}
+
+// Delete deletes an instance template by ID
+func (r *InstanceTemplateRepository) Delete(id string) error {
+	var groupCount int
+	err := r.db.queryRowStmt("SELECT COUNT(*) FROM autoscaling_groups WHERE template_id = ?", id).Scan(&groupCount)
+	if err != nil {
+		return fmt.Errorf("failed to check instance template usage: %w", err)
+	}
+
+	if groupCount > 0 {
+		return domain.InvalidInputError("cannot delete instance template used by autoscaling groups", map[string]interface{}{
+			"template_id":             id,
+			"autoscaling_group_count": groupCount,
+		})
+	}
+
+	return r.db.deleteByID("instance_templates", "instance template", id)
+}
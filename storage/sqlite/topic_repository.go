@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// TopicRepository handles pub/sub topic data operations
+type TopicRepository struct {
+	db *DB
+}
+
+// NewTopicRepository creates a new topic repository
+func NewTopicRepository(db *DB) *TopicRepository {
+	return &TopicRepository{db: db}
+}
+
+const topicSelect = `SELECT id, project_id, name, created_at, updated_at FROM topics`
+
+// scanTopic scans a single topic row
+func scanTopic(row interface{ Scan(...interface{}) error }) (*domain.Topic, error) {
+	topic := &domain.Topic{}
+	err := row.Scan(
+		&topic.ID,
+		&topic.ProjectID,
+		&topic.Name,
+		&topic.CreatedAt,
+		&topic.UpdatedAt,
+	)
+	return topic, err
+}
+
+// Create creates a new topic
+func (r *TopicRepository) Create(topic *domain.Topic) error {
+	now := time.Now()
+	topic.CreatedAt = now
+	topic.UpdatedAt = now
+
+	query := `INSERT INTO topics (id, project_id, name, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := r.db.execStmt(query, topic.ID, topic.ProjectID, topic.Name, topic.CreatedAt, topic.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: topics.project_id, topics.name") {
+			return domain.AlreadyExistsError("topic", "name", topic.Name).WithCause(err)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.ForeignKeyViolationError("project", "id", topic.ProjectID).WithCause(err)
+		}
+		return fmt.Errorf("failed to create topic: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a topic by ID
+func (r *TopicRepository) GetByID(id string) (*domain.Topic, error) {
+	topic, err := scanTopic(r.db.queryRowStmt(topicSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("topic", id)
+		}
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	return topic, nil
+}
+
+// List retrieves topics with optional filtering
+func (r *TopicRepository) List(opts domain.TopicListOptions) ([]*domain.Topic, error) {
+	var topics []*domain.Topic
+	var args []interface{}
+
+	query := topicSelect
+	var conditions []string
+
+	if opts.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, opts.ProjectID)
+	}
+
+	if opts.Name != "" {
+		conditions = append(conditions, "name = ?")
+		args = append(args, opts.Name)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY name, id"
+
+	rows, err := r.db.queryStmt(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		topic, err := scanTopic(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan topic: %w", err)
+		}
+		topics = append(topics, topic)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating topics: %w", err)
+	}
+
+	return topics, nil
+}
+
+// Delete deletes a topic by ID, along with its subscriptions and their
+// undelivered messages
+func (r *TopicRepository) Delete(id string) error {
+	return r.db.deleteByID("topics", "topic", id)
+}
+
+// Publish stores messages for every subscription of a topic. Messages
+// published to a topic without subscriptions are dropped.
+func (r *TopicRepository) Publish(topicID string, messages []*domain.Message) error {
+	query := `INSERT INTO subscription_messages (subscription_id, message_id, data, attributes, published_at, visible_at)
+		SELECT id, ?, ?, ?, ?, ? FROM subscriptions WHERE topic_id = ?`
+
+	for _, message := range messages {
+		_, err := r.db.execStmt(query, message.ID, message.Data, jsonColumn{message.Attributes}, message.PublishedAt, message.PublishedAt, topicID)
+		if err != nil {
+			return fmt.Errorf("failed to publish message: %w", err)
+		}
+	}
+
+	return nil
+}
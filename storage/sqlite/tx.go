@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// WithTrace returns a handle on the same database that records the
+// statements run through it, and through the transactions it begins, to
+// trace
+func (db *DB) WithTrace(trace *domain.StatementTrace) *DB {
+	traced := *db
+	traced.trace = trace
+	return &traced
+}
+
+// traced records a statement started at start on the handle's trace, if it
+// has one
+func (db *DB) traced(query string, start time.Time, err error) {
+	if db.trace != nil {
+		db.trace.Add(query, time.Since(start), err)
+	}
+}
+
+// Exec executes a statement, inside the handle's transaction if it has one
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	var result sql.Result
+	var err error
+	if db.tx != nil {
+		result, err = db.tx.Exec(query, args...)
+	} else {
+		result, err = db.DB.Exec(query, args...)
+	}
+	db.traced(query, start, err)
+	return result, err
+}
+
+// Query runs a query, inside the handle's transaction if it has one
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	var rows *sql.Rows
+	var err error
+	if db.tx != nil {
+		rows, err = db.tx.Query(query, args...)
+	} else {
+		rows, err = db.DB.Query(query, args...)
+	}
+	db.traced(query, start, err)
+	return rows, err
+}
+
+// QueryRow runs a query returning at most one row, inside the handle's
+// transaction if it has one
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	var row *sql.Row
+	if db.tx != nil {
+		row = db.tx.QueryRow(query, args...)
+	} else {
+		row = db.DB.QueryRow(query, args...)
+	}
+	db.traced(query, start, row.Err())
+	return row
+}
+
+// WithTx runs fn with a handle bound to a new transaction, committing it if fn
+// returns nil and rolling it back otherwise. On a handle that is already bound
+// to a transaction fn joins that transaction instead, leaving the outcome to
+// the outermost caller.
+func (db *DB) WithTx(fn func(tx *DB) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&DB{DB: db.DB, tx: tx, stmts: db.stmts, trace: db.trace}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UnitOfWork runs work against a set of repositories bound to one transaction.
+// R is the caller's repository bundle, built for each transaction by bind.
+type UnitOfWork[R any] struct {
+	db   *DB
+	bind func(tx *DB) R
+}
+
+// NewUnitOfWork creates a unit of work on db that builds the repositories for
+// each transaction with bind
+func NewUnitOfWork[R any](db *DB, bind func(tx *DB) R) *UnitOfWork[R] {
+	return &UnitOfWork[R]{db: db, bind: bind}
+}
+
+// Do runs fn with repositories bound to a transaction, committing if fn
+// returns nil and rolling back otherwise
+func (u *UnitOfWork[R]) Do(fn func(repos R) error) error {
+	return u.db.WithTx(func(tx *DB) error {
+		return fn(u.bind(tx))
+	})
+}
@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupFileDB creates a file database so that statements outside a
+// transaction see the same data on every pooled connection
+func setupFileDB(t *testing.T) *DB {
+	t.Helper()
+
+	db, err := NewDB("file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_fk=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestDB_WithTx(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	tests := []struct {
+		name      string
+		fn        func(tx *DB) error
+		expectErr error
+		committed bool
+	}{
+		{
+			name: "commits on success",
+			fn: func(tx *DB) error {
+				return NewProjectRepository(tx).Create(&domain.Project{ID: "p1", Name: "one"})
+			},
+			committed: true,
+		},
+		{
+			name: "rolls back on error",
+			fn: func(tx *DB) error {
+				require.NoError(t, NewProjectRepository(tx).Create(&domain.Project{ID: "p1", Name: "one"}))
+				return errAbort
+			},
+			expectErr: errAbort,
+		},
+		{
+			name: "nested transactions join the outer one",
+			fn: func(tx *DB) error {
+				err := tx.WithTx(func(inner *DB) error {
+					return NewProjectRepository(inner).Create(&domain.Project{ID: "p1", Name: "one"})
+				})
+				require.NoError(t, err)
+				return errAbort
+			},
+			expectErr: errAbort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupFileDB(t)
+
+			err := db.WithTx(tt.fn)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			_, err = NewProjectRepository(db).GetByID("p1")
+			if tt.committed {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, domain.IsNotFound(err), "rolled back project should not exist")
+			}
+		})
+	}
+}
+
+func TestUnitOfWork_Do(t *testing.T) {
+	db := setupFileDB(t)
+	uow := NewUnitOfWork(db, NewProjectRepository)
+
+	err := uow.Do(func(projects *ProjectRepository) error {
+		require.NoError(t, projects.Create(&domain.Project{ID: "p1", Name: "one"}))
+
+		// Not visible outside the transaction until it commits
+		_, err := NewProjectRepository(db).GetByID("p1")
+		assert.True(t, domain.IsNotFound(err))
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = NewProjectRepository(db).GetByID("p1")
+	assert.NoError(t, err)
+}
+
+func TestWithDefaultParams(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		expected string
+	}{
+		{dsn: ":memory:", expected: ":memory:?_txlock=immediate&_busy_timeout=5000"},
+		{dsn: "file:dirt.db?_fk=1", expected: "file:dirt.db?_fk=1&_txlock=immediate&_busy_timeout=5000"},
+		{dsn: "file:dirt.db?_busy_timeout=100&_txlock=deferred", expected: "file:dirt.db?_busy_timeout=100&_txlock=deferred"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			assert.Equal(t, tt.expected, withDefaultParams(tt.dsn))
+		})
+	}
+}
+
+func TestDB_WithTrace(t *testing.T) {
+	db := setupFileDB(t)
+	trace := &domain.StatementTrace{}
+	traced := db.WithTrace(trace)
+
+	require.NoError(t, traced.WithTx(func(tx *DB) error {
+		return NewProjectRepository(tx).Create(&domain.Project{ID: "p1", Name: "one"})
+	}))
+	_, err := NewProjectRepository(traced).GetByID("missing")
+	assert.True(t, domain.IsNotFound(err))
+
+	// The handle it was made from records nothing
+	_, err = NewProjectRepository(db).GetByID("p1")
+	require.NoError(t, err)
+
+	statements := trace.Statements()
+	require.Len(t, statements, 2)
+	assert.Contains(t, statements[0].SQL, "INSERT INTO projects")
+	assert.Contains(t, statements[1].SQL, "WHERE id = ?")
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// UsageRepository handles simulated project usage
+type UsageRepository struct {
+	db *DB
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(db *DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+const usageSelect = `SELECT project_id, period, instance_hours, vcpu_hours, memory_gb_hours, volume_gb_hours, updated_at FROM project_usage`
+
+// Add adds usage to a project's total for the usage period
+func (r *UsageRepository) Add(usage *domain.Usage) error {
+	query := `INSERT INTO project_usage (project_id, period, instance_hours, vcpu_hours, memory_gb_hours, volume_gb_hours, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (project_id, period) DO UPDATE SET
+			instance_hours = instance_hours + excluded.instance_hours,
+			vcpu_hours = vcpu_hours + excluded.vcpu_hours,
+			memory_gb_hours = memory_gb_hours + excluded.memory_gb_hours,
+			volume_gb_hours = volume_gb_hours + excluded.volume_gb_hours,
+			updated_at = excluded.updated_at`
+
+	_, err := r.db.execStmt(query, usage.ProjectID, usage.Period, usage.InstanceHours, usage.VCPUHours,
+		usage.MemoryGBHours, usage.VolumeGBHours, usage.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return domain.ForeignKeyViolationError("project", "id", usage.ProjectID).WithCause(err)
+		}
+		return fmt.Errorf("failed to add usage: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves a project's usage for a period
+func (r *UsageRepository) Get(projectID, period string) (*domain.Usage, error) {
+	usage := &domain.Usage{}
+	err := r.db.queryRowStmt(usageSelect+` WHERE project_id = ? AND period = ?`, projectID, period).Scan(usageFieldPtrs(usage)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFoundError("usage", projectID+"/"+period)
+		}
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// List retrieves every project's usage for a period, ordered by project ID
+func (r *UsageRepository) List(period string) ([]*domain.Usage, error) {
+	rows, err := r.db.queryStmt(usageSelect+` WHERE period = ? ORDER BY project_id`, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usages []*domain.Usage
+	for rows.Next() {
+		usage := &domain.Usage{}
+		if err := rows.Scan(usageFieldPtrs(usage)...); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		usages = append(usages, usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage: %w", err)
+	}
+
+	return usages, nil
+}
+
+// usageFieldPtrs returns scan destinations in usageSelect order
+func usageFieldPtrs(u *domain.Usage) []interface{} {
+	return []interface{}{&u.ProjectID, &u.Period, &u.InstanceHours, &u.VCPUHours, &u.MemoryGBHours, &u.VolumeGBHours, &u.UpdatedAt}
+}
@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageRepository(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	projects := NewProjectRepository(db)
+	for _, id := range []string{"p1", "p2"} {
+		require.NoError(t, projects.Create(&domain.Project{ID: id, Name: id}))
+	}
+
+	repo := NewUsageRepository(db)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	_, err := repo.Get("p1", "2026-03")
+	assert.True(t, domain.IsNotFound(err))
+
+	// Usage adds up within a period
+	for i := 0; i < 2; i++ {
+		require.NoError(t, repo.Add(&domain.Usage{
+			ProjectID: "p1", Period: "2026-03", InstanceHours: 1, VCPUHours: 2, MemoryGBHours: 0.5, VolumeGBHours: 10, UpdatedAt: now,
+		}))
+	}
+	require.NoError(t, repo.Add(&domain.Usage{ProjectID: "p2", Period: "2026-03", VolumeGBHours: 5, UpdatedAt: now}))
+	require.NoError(t, repo.Add(&domain.Usage{ProjectID: "p1", Period: "2026-02", InstanceHours: 7, UpdatedAt: now}))
+
+	usage, err := repo.Get("p1", "2026-03")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, usage.InstanceHours)
+	assert.Equal(t, 4.0, usage.VCPUHours)
+	assert.Equal(t, 1.0, usage.MemoryGBHours)
+	assert.Equal(t, 20.0, usage.VolumeGBHours)
+	assert.True(t, now.Equal(usage.UpdatedAt))
+
+	usages, err := repo.List("2026-03")
+	require.NoError(t, err)
+	require.Len(t, usages, 2)
+	assert.Equal(t, "p1", usages[0].ProjectID)
+	assert.Equal(t, "p2", usages[1].ProjectID)
+
+	err = repo.Add(&domain.Usage{ProjectID: "missing", Period: "2026-03", UpdatedAt: now})
+	assert.True(t, domain.IsForeignKeyViolation(err))
+}
@@ -0,0 +1,180 @@
+package tfharness
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// Check functions return an error so they can be wrapped in Terraform
+// TestCheckFuncs; Assert functions report failures on the harness's test.
+
+// CheckProjectExists returns an error unless the project exists
+func (h *Harness) CheckProjectExists(id string) error {
+	if _, err := h.service.GetProject(id); err != nil {
+		return fmt.Errorf("project %s: %w", id, err)
+	}
+	return nil
+}
+
+// CheckProjectDestroyed returns an error if the project still exists
+func (h *Harness) CheckProjectDestroyed(id string) error {
+	_, err := h.service.GetProject(id)
+	return destroyed("project", id, err)
+}
+
+// CheckInstanceExists returns an error unless the instance exists
+func (h *Harness) CheckInstanceExists(id string) error {
+	if _, err := h.service.GetInstance(id); err != nil {
+		return fmt.Errorf("instance %s: %w", id, err)
+	}
+	return nil
+}
+
+// CheckInstanceDestroyed returns an error if the instance still exists
+func (h *Harness) CheckInstanceDestroyed(id string) error {
+	_, err := h.service.GetInstance(id)
+	return destroyed("instance", id, err)
+}
+
+// CheckInstanceStatus returns an error unless the instance has the given status
+func (h *Harness) CheckInstanceStatus(id, status string) error {
+	instance, err := h.service.GetInstance(id)
+	if err != nil {
+		return fmt.Errorf("instance %s: %w", id, err)
+	}
+	if instance.Status != status {
+		return fmt.Errorf("instance %s: expected status %q, got %q", id, status, instance.Status)
+	}
+	return nil
+}
+
+// CheckMetadataExists returns an error unless the metadata entry exists
+func (h *Harness) CheckMetadataExists(id string) error {
+	if _, err := h.service.GetMetadata(id); err != nil {
+		return fmt.Errorf("metadata %s: %w", id, err)
+	}
+	return nil
+}
+
+// CheckMetadataDestroyed returns an error if the metadata entry still exists
+func (h *Harness) CheckMetadataDestroyed(id string) error {
+	_, err := h.service.GetMetadata(id)
+	return destroyed("metadata", id, err)
+}
+
+// CheckNoProjects returns an error if any project remains, for CheckDestroy
+func (h *Harness) CheckNoProjects() error {
+	projects, err := h.service.ListProjects(domain.ProjectListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(projects) > 0 {
+		return fmt.Errorf("%d project(s) still exist", len(projects))
+	}
+	return nil
+}
+
+// CheckNoInstances returns an error if any instance remains, for CheckDestroy
+func (h *Harness) CheckNoInstances() error {
+	instances, err := h.service.ListInstances(domain.InstanceListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(instances) > 0 {
+		return fmt.Errorf("%d instance(s) still exist", len(instances))
+	}
+	return nil
+}
+
+// CheckAssertions returns an error describing every declarative assertion
+// that does not hold, as POST /v1/admin/assert evaluates them
+func (h *Harness) CheckAssertions(assertions ...domain.Assertion) error {
+	resp, err := h.service.Assert(domain.AssertRequest{Assertions: assertions})
+	if err != nil {
+		return err
+	}
+	if resp.Passed {
+		return nil
+	}
+
+	var failures []string
+	for _, result := range resp.Results {
+		label := fmt.Sprintf("assertion %d", result.Index)
+		if result.Name != "" {
+			label = result.Name
+		}
+		for _, failure := range result.Failures {
+			failures = append(failures, label+": "+failure)
+		}
+	}
+	return errors.New(strings.Join(failures, "; "))
+}
+
+// AssertProjectExists fails the test unless the project exists
+func (h *Harness) AssertProjectExists(id string) {
+	h.t.Helper()
+	h.assert(h.CheckProjectExists(id))
+}
+
+// AssertProjectDestroyed fails the test if the project still exists
+func (h *Harness) AssertProjectDestroyed(id string) {
+	h.t.Helper()
+	h.assert(h.CheckProjectDestroyed(id))
+}
+
+// AssertInstanceExists fails the test unless the instance exists
+func (h *Harness) AssertInstanceExists(id string) {
+	h.t.Helper()
+	h.assert(h.CheckInstanceExists(id))
+}
+
+// AssertInstanceDestroyed fails the test if the instance still exists
+func (h *Harness) AssertInstanceDestroyed(id string) {
+	h.t.Helper()
+	h.assert(h.CheckInstanceDestroyed(id))
+}
+
+// AssertInstanceStatus fails the test unless the instance has the given status
+func (h *Harness) AssertInstanceStatus(id, status string) {
+	h.t.Helper()
+	h.assert(h.CheckInstanceStatus(id, status))
+}
+
+// AssertMetadataExists fails the test unless the metadata entry exists
+func (h *Harness) AssertMetadataExists(id string) {
+	h.t.Helper()
+	h.assert(h.CheckMetadataExists(id))
+}
+
+// AssertMetadataDestroyed fails the test if the metadata entry still exists
+func (h *Harness) AssertMetadataDestroyed(id string) {
+	h.t.Helper()
+	h.assert(h.CheckMetadataDestroyed(id))
+}
+
+// AssertAll fails the test unless every declarative assertion holds
+func (h *Harness) AssertAll(assertions ...domain.Assertion) {
+	h.t.Helper()
+	h.assert(h.CheckAssertions(assertions...))
+}
+
+func (h *Harness) assert(err error) {
+	h.t.Helper()
+	if err != nil {
+		h.t.Errorf("tfharness: %v", err)
+	}
+}
+
+// destroyed turns the error from a lookup into a destroy check
+func destroyed(resource, id string, err error) error {
+	if err == nil {
+		return fmt.Errorf("%s %s still exists", resource, id)
+	}
+	if !domain.IsNotFound(err) {
+		return fmt.Errorf("%s %s: %w", resource, id, err)
+	}
+	return nil
+}
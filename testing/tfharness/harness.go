@@ -0,0 +1,142 @@
+// Package tfharness runs an in-process DirtCloud server for Terraform
+// provider acceptance tests. A provider repository can depend on it directly:
+//
+//	func TestAccInstance(t *testing.T) {
+//		h := tfharness.New(t)
+//		resource.Test(t, resource.TestCase{
+//			Steps: []resource.TestStep{{
+//				Config: h.Config(`resource "dirt_instance" "web" { ... }`),
+//			}},
+//			CheckDestroy: func(*terraform.State) error { return h.CheckNoInstances() },
+//		})
+//	}
+package tfharness
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/api"
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/service/chaos"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+)
+
+// Harness is a running DirtCloud server backed by a throwaway database
+type Harness struct {
+	// URL is the server base URL, suitable for the provider "endpoint"
+	URL string
+	// Token is the bearer token the server requires, if any
+	Token string
+
+	t       testing.TB
+	server  *httptest.Server
+	db      *sqlite.DB
+	service *service.Service
+}
+
+// Options configures a harness
+type Options struct {
+	// Token requires bearer authentication when set
+	Token string
+	// Service configures service behavior such as online resize
+	Service service.Config
+}
+
+// New boots a server and registers its teardown with t.Cleanup
+func New(t testing.TB) *Harness {
+	t.Helper()
+	return NewWithOptions(t, Options{})
+}
+
+// NewWithOptions boots a server with the given options and registers its
+// teardown with t.Cleanup
+func NewWithOptions(t testing.TB, opts Options) *Harness {
+	t.Helper()
+
+	// A file-backed database keeps every pooled connection on the same data
+	dsn := "file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_busy_timeout=5000&_fk=1"
+	db, err := sqlite.NewDB(dsn)
+	if err != nil {
+		t.Fatalf("tfharness: failed to initialize database: %v", err)
+	}
+	if err := db.SetUniqueInstanceNames(!opts.Service.AllowDuplicateInstanceNames); err != nil {
+		db.Close()
+		t.Fatalf("tfharness: failed to configure database: %v", err)
+	}
+
+	svc := service.NewService(service.NewSQLiteRepositories(db, filepath.Join(t.TempDir(), "backups")), opts.Service)
+
+	handler := api.NewHandler(svc, chaos.NewChaosService(), api.Config{Token: opts.Token})
+
+	h := &Harness{
+		Token:   opts.Token,
+		t:       t,
+		server:  httptest.NewServer(api.SetupRouter(handler)),
+		db:      db,
+		service: svc,
+	}
+	h.URL = h.server.URL
+
+	t.Cleanup(h.Close)
+
+	return h
+}
+
+// Close stops the server and closes the database. It is safe to call more
+// than once.
+func (h *Harness) Close() {
+	if h.server == nil {
+		return
+	}
+	h.server.Close()
+	h.db.Close()
+	h.server = nil
+}
+
+// Service returns the service layer backing the server, for seeding data or
+// writing custom checks without going through HTTP
+func (h *Harness) Service() *service.Service {
+	return h.service
+}
+
+// Seed loads a seed file, such as one generated from traffic recorded by the
+// request mirror, failing the test if it cannot be loaded
+func (h *Harness) Seed(path string) {
+	h.t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("tfharness: %v", err)
+	}
+	var seed domain.Seed
+	if err := json.Unmarshal(data, &seed); err != nil {
+		h.t.Fatalf("tfharness: seed %s: %v", path, err)
+	}
+	if err := h.service.LoadSeed(seed); err != nil {
+		h.t.Fatalf("tfharness: seed %s: %v", path, err)
+	}
+}
+
+// ProviderConfig returns a provider block pointing at the harness
+func (h *Harness) ProviderConfig() string {
+	var b strings.Builder
+	b.WriteString("provider \"dirt\" {\n")
+	fmt.Fprintf(&b, "  endpoint = %q\n", h.URL)
+	if h.Token != "" {
+		fmt.Fprintf(&b, "  token    = %q\n", h.Token)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Config returns the provider block followed by the given HCL snippets
+func (h *Harness) Config(snippets ...string) string {
+	return h.ProviderConfig() + "\n" + strings.Join(snippets, "\n")
+}
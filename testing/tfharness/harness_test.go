@@ -0,0 +1,113 @@
+package tfharness
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTB captures assertion failures instead of failing the test
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestHarness_Lifecycle(t *testing.T) {
+	h := NewWithOptions(t, Options{Token: "secret"})
+	c := client.NewClient(client.Config{BaseURL: h.URL, Token: h.Token, RetryMax: 1, RetryInitialBackoffMs: 1})
+	ctx := context.Background()
+
+	project, err := c.Projects.Create(ctx, domain.CreateProjectRequest{Name: "acc"})
+	require.NoError(t, err)
+	instance, err := c.Instances.Create(ctx, domain.CreateInstanceRequest{
+		ProjectID: project.ID, Name: "web", CPU: 1, MemoryMB: 512, Image: "ubuntu",
+	})
+	require.NoError(t, err)
+
+	h.AssertProjectExists(project.ID)
+	h.AssertInstanceExists(instance.ID)
+	h.AssertInstanceStatus(instance.ID, domain.StatusRunning)
+	assert.Error(t, h.CheckNoInstances())
+
+	require.NoError(t, c.Instances.Delete(ctx, instance.ID))
+	require.NoError(t, c.Projects.Delete(ctx, project.ID, domain.DeleteProjectOptions{}))
+
+	h.AssertInstanceDestroyed(instance.ID)
+	h.AssertProjectDestroyed(project.ID)
+	assert.NoError(t, h.CheckNoInstances())
+	assert.NoError(t, h.CheckNoProjects())
+}
+
+func TestHarness_AssertReportsFailures(t *testing.T) {
+	rec := &recordingTB{TB: t}
+	h := New(rec)
+
+	h.AssertInstanceExists("missing")
+	h.AssertInstanceDestroyed("missing")
+
+	require.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], "instance missing")
+}
+
+func TestHarness_AssertAll(t *testing.T) {
+	rec := &recordingTB{TB: t}
+	h := New(rec)
+
+	project, err := h.Service().CreateProject(domain.CreateProjectRequest{Name: "acc"})
+	require.NoError(t, err)
+
+	none := 0
+	h.AssertAll(
+		domain.Assertion{Type: "project", Where: map[string]interface{}{"id": project.ID}, Equals: map[string]interface{}{"name": "acc"}},
+		domain.Assertion{Type: "instance", Count: &none},
+	)
+	assert.Empty(t, rec.errors)
+
+	h.AssertAll(domain.Assertion{Name: "renamed", Type: "project", Equals: map[string]interface{}{"name": "other"}})
+	require.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], `renamed: project `+project.ID+` has name "acc", expected "other"`)
+}
+
+func TestHarness_ProviderConfig(t *testing.T) {
+	h := New(t)
+	assert.Equal(t, fmt.Sprintf("provider \"dirt\" {\n  endpoint = %q\n}\n", h.URL), h.ProviderConfig())
+
+	h = NewWithOptions(t, Options{Token: "secret"})
+	config := h.Config(`resource "dirt_project" "p" { name = "acc" }`)
+	assert.Contains(t, config, `token    = "secret"`)
+	assert.Contains(t, config, `resource "dirt_project" "p"`)
+}
+
+func TestHarness_Close(t *testing.T) {
+	h := New(t)
+	h.Close()
+	h.Close()
+
+	c := client.NewClient(client.Config{BaseURL: h.URL, RetryMax: 1, RetryInitialBackoffMs: 1})
+	_, err := c.Projects.List(context.Background(), domain.ProjectListOptions{})
+	assert.Error(t, err)
+}
+
+func TestHarness_Seed(t *testing.T) {
+	h := New(t)
+
+	path := filepath.Join(t.TempDir(), "seed.json")
+	seed := `{"resources":[{"type":"project","resource":{"id":"proj-seeded","name":"seeded"}},` +
+		`{"type":"metadata","resource":{"id":"meta-seeded","path":"app/mode","value":"fixture"}}]}`
+	require.NoError(t, os.WriteFile(path, []byte(seed), 0o644))
+
+	h.Seed(path)
+	h.AssertProjectExists("proj-seeded")
+	h.AssertMetadataExists("meta-seeded")
+}
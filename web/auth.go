@@ -0,0 +1,167 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// SessionCookie is the name of the console session cookie
+	SessionCookie = "dirt_session"
+
+	// CSRFHeader carries the CSRF token of htmx requests
+	CSRFHeader = "X-CSRF-Token"
+
+	// csrfField carries the CSRF token of plain form posts
+	csrfField = "csrf_token"
+
+	// LoginRoute names the login routes, which need no session
+	LoginRoute = "web-login"
+)
+
+// RequireSession is middleware that lets a request through only with a valid
+// session and, for mutating methods, a matching CSRF token. Without a token
+// configured there are no credentials to check, so a session is started on
+// the first visit to keep CSRF protection in place.
+func (h *Handler) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil && route.GetName() == LoginRoute {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sess := h.currentSession(r)
+		if sess == nil {
+			if h.token != "" || !isSafeMethod(r.Method) {
+				h.redirectToLogin(w, r)
+				return
+			}
+
+			var err error
+			if sess, err = h.startSession(w, r); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if !isSafeMethod(r.Method) && !validCSRFToken(r, sess) {
+			http.Error(w, "invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withSession(r.Context(), sess)))
+	})
+}
+
+// LoginForm shows the login page
+func (h *Handler) LoginForm(w http.ResponseWriter, r *http.Request) {
+	h.renderLogin(w, http.StatusOK, "")
+}
+
+// Login checks the submitted token and starts a session
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" {
+		token := r.FormValue("token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			h.renderLogin(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+	}
+
+	if old := h.currentSession(r); old != nil {
+		h.sessions.delete(old.id)
+	}
+	if _, err := h.startSession(w, r); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/web/", http.StatusSeeOther)
+}
+
+// Logout ends the session
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if sess := sessionFromContext(r.Context()); sess != nil {
+		h.sessions.delete(sess.id)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/web",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteStrictMode,
+	})
+	h.redirectToLogin(w, r)
+}
+
+// currentSession returns the session named by the request cookie
+func (h *Handler) currentSession(r *http.Request) *session {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil
+	}
+	sess, ok := h.sessions.get(cookie.Value)
+	if !ok {
+		return nil
+	}
+	return sess
+}
+
+// startSession creates a session and sets its cookie
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request) (*session, error) {
+	sess, err := h.sessions.create()
+	if err != nil {
+		return nil, err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    sess.id,
+		Path:     "/web",
+		Expires:  sess.expiresAt,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return sess, nil
+}
+
+// redirectToLogin sends the browser to the login page. htmx requests are
+// redirected through the HX-Redirect header so the whole page navigates.
+func (h *Handler) redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/web/login")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, "/web/login", http.StatusSeeOther)
+}
+
+// renderLogin writes the login page with an optional error message
+func (h *Handler) renderLogin(w http.ResponseWriter, status int, message string) {
+	data := struct {
+		Message       string
+		TokenRequired bool
+	}{message, h.token != ""}
+
+	h.render(w, status, "login", data)
+}
+
+// validCSRFToken checks the CSRF token of a request against its session
+func validCSRFToken(r *http.Request, sess *session) bool {
+	token := r.Header.Get(CSRFHeader)
+	if token == "" {
+		token = r.PostFormValue(csrfField)
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(sess.csrfToken)) == 1
+}
+
+// isSafeMethod reports whether a method does not change state
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
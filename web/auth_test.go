@@ -0,0 +1,176 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthTestRouter routes the session endpoints, the dashboard and a
+// mutating endpoint through RequireSession
+func newAuthTestRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	webRouter := router.PathPrefix("/web").Subrouter()
+	webRouter.Use(h.RequireSession)
+	webRouter.HandleFunc("/login", h.LoginForm).Methods("GET").Name(LoginRoute)
+	webRouter.HandleFunc("/login", h.Login).Methods("POST").Name(LoginRoute)
+	webRouter.HandleFunc("/logout", h.Logout).Methods("POST")
+	webRouter.HandleFunc("/", h.Dashboard).Methods("GET")
+	webRouter.HandleFunc("/projects", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}).Methods("POST")
+	return router
+}
+
+func serve(router http.Handler, r *http.Request, cookie *http.Cookie) *httptest.ResponseRecorder {
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func login(t *testing.T, router http.Handler, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest("POST", "/web/login", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return serve(router, r, nil)
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+
+	for _, c := range w.Result().Cookies() {
+		if c.Name == SessionCookie {
+			return c
+		}
+	}
+	t.Fatalf("no %s cookie set", SessionCookie)
+	return nil
+}
+
+func TestRequireSession_Login(t *testing.T) {
+	h := NewHandler(nil, Config{Token: "secret"})
+	router := newAuthTestRouter(h)
+
+	w := serve(router, httptest.NewRequest("GET", "/web/", nil), nil)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/web/login", w.Header().Get("Location"))
+
+	r := httptest.NewRequest("GET", "/web/", nil)
+	r.Header.Set("HX-Request", "true")
+	w = serve(router, r, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "/web/login", w.Header().Get("HX-Redirect"))
+
+	w = serve(router, httptest.NewRequest("GET", "/web/login", nil), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = login(t, router, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+
+	w = login(t, router, "secret")
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	cookie := sessionCookie(t, w)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+
+	w = serve(router, httptest.NewRequest("GET", "/web/", nil), cookie)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	sess, ok := h.sessions.get(cookie.Value)
+	require.True(t, ok)
+	assert.Contains(t, w.Body.String(), sess.csrfToken)
+}
+
+func TestRequireSession_CSRF(t *testing.T) {
+	h := NewHandler(nil, Config{Token: "secret"})
+	router := newAuthTestRouter(h)
+
+	cookie := sessionCookie(t, login(t, router, "secret"))
+	sess, ok := h.sessions.get(cookie.Value)
+	require.True(t, ok)
+
+	tests := []struct {
+		name     string
+		header   string
+		form     string
+		expected int
+	}{
+		{name: "missing token", expected: http.StatusForbidden},
+		{name: "wrong header token", header: "nope", expected: http.StatusForbidden},
+		{name: "header token", header: sess.csrfToken, expected: http.StatusCreated},
+		{name: "form token", form: sess.csrfToken, expected: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/web/projects", strings.NewReader(url.Values{"csrf_token": {tt.form}}.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.header != "" {
+				r.Header.Set(CSRFHeader, tt.header)
+			}
+			w := serve(router, r, cookie)
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestRequireSession_Logout(t *testing.T) {
+	h := NewHandler(nil, Config{Token: "secret"})
+	router := newAuthTestRouter(h)
+
+	cookie := sessionCookie(t, login(t, router, "secret"))
+	sess, _ := h.sessions.get(cookie.Value)
+
+	r := httptest.NewRequest("POST", "/web/logout", strings.NewReader(url.Values{"csrf_token": {sess.csrfToken}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := serve(router, r, cookie)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, -1, sessionCookie(t, w).MaxAge)
+
+	w = serve(router, httptest.NewRequest("GET", "/web/", nil), cookie)
+	assert.Equal(t, http.StatusSeeOther, w.Code, "the session ends at logout")
+}
+
+func TestRequireSession_NoToken(t *testing.T) {
+	h := NewHandler(nil, Config{InsecureCookies: true})
+	router := newAuthTestRouter(h)
+
+	w := serve(router, httptest.NewRequest("GET", "/web/", nil), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	cookie := sessionCookie(t, w)
+	assert.False(t, cookie.Secure)
+
+	// Mutations still need the CSRF token of the session
+	w = serve(router, httptest.NewRequest("POST", "/web/projects", nil), cookie)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = serve(router, httptest.NewRequest("POST", "/web/projects", nil), nil)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+}
+
+func TestSessionStore_Expiry(t *testing.T) {
+	store := newSessionStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	sess, err := store.create()
+	require.NoError(t, err)
+	_, ok := store.get(sess.id)
+	assert.True(t, ok)
+
+	now = now.Add(sessionTTL)
+	_, ok = store.get(sess.id)
+	assert.False(t, ok)
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// recentEventLimit is how many events or activity entries a detail page
+// shows
+const recentEventLimit = 10
+
+type instanceDetail struct {
+	Instance *domain.Instance
+	Project  *domain.Project
+	Activity []*domain.ActivityEntry
+}
+
+type projectDetail struct {
+	Project   *domain.Project
+	Instances []*domain.Instance
+	Events    []*domain.Event
+}
+
+// InstanceDetail shows every attribute of an instance with its recent
+// activity
+func (h *Handler) InstanceDetail(w http.ResponseWriter, r *http.Request) {
+	h.renderInstanceDetail(w, mux.Vars(r)["id"])
+}
+
+// StartInstance starts a stopped instance and shows its detail page
+func (h *Handler) StartInstance(w http.ResponseWriter, r *http.Request) {
+	h.setInstanceStatus(w, r, domain.StatusRunning)
+}
+
+// StopInstance stops a running instance and shows its detail page
+func (h *Handler) StopInstance(w http.ResponseWriter, r *http.Request) {
+	h.setInstanceStatus(w, r, domain.StatusStopped)
+}
+
+func (h *Handler) setInstanceStatus(w http.ResponseWriter, r *http.Request, status string) {
+	id := mux.Vars(r)["id"]
+
+	if _, err := h.service.UpdateInstance(id, domain.UpdateInstanceRequest{Status: &status}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderInstanceDetail(w, id)
+}
+
+func (h *Handler) renderInstanceDetail(w http.ResponseWriter, id string) {
+	instance, err := h.service.GetInstance(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// The project is only context; the page still renders without it
+	project, _ := h.service.GetProject(instance.ProjectID)
+
+	activity, err := h.service.ListActivity(domain.ActivityListOptions{
+		ResourceType: "instance",
+		ResourceID:   id,
+		RequestPath:  "/v1/instances/" + id,
+		Limit:        recentEventLimit,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Newest first, like the events of other pages
+	for i, j := 0, len(activity)-1; i < j; i, j = i+1, j-1 {
+		activity[i], activity[j] = activity[j], activity[i]
+	}
+
+	h.render(w, http.StatusOK, "instance_detail", instanceDetail{Instance: instance, Project: project, Activity: activity})
+}
+
+// ProjectDetail shows every attribute of a project with its instances and
+// recent events
+func (h *Handler) ProjectDetail(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	project, err := h.service.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	instances, err := h.service.ListInstances(domain.InstanceListOptions{ProjectID: id})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	events, err := h.recentEvents(domain.EventListOptions{ProjectID: id})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, http.StatusOK, "project_detail", projectDetail{Project: project, Instances: instances, Events: events})
+}
+
+// recentEvents returns the newest matching events, newest first
+func (h *Handler) recentEvents(opts domain.EventListOptions) ([]*domain.Event, error) {
+	events, err := h.service.ListEvents(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) > recentEventLimit {
+		events = events[len(events)-recentEventLimit:]
+	}
+	recent := make([]*domain.Event, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		recent = append(recent, events[i])
+	}
+	return recent, nil
+}
+
+// targetsContent reports whether an htmx request swaps the main content pane,
+// as opposed to a single table row
+func targetsContent(r *http.Request) bool {
+	return r.Header.Get("HX-Target") == "content"
+}
@@ -0,0 +1,111 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/hypertf/dirtcloud-server/service"
+	"github.com/hypertf/dirtcloud-server/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newServiceTestHandler(t *testing.T) (*Handler, *mux.Router) {
+	t.Helper()
+
+	db, err := sqlite.NewDB("file:" + filepath.Join(t.TempDir(), "dirt.db") + "?_fk=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	svc := service.NewService(service.Repositories{
+		Projects:  sqlite.NewProjectRepository(db),
+		Instances: sqlite.NewInstanceRepository(db),
+		Events:    sqlite.NewEventRepository(db),
+	}, service.Config{})
+
+	h := NewHandler(svc, Config{})
+	router := mux.NewRouter()
+	router.HandleFunc("/web/instances", h.ListInstances).Methods("GET")
+	router.HandleFunc("/web/instances/{id}", h.InstanceDetail).Methods("GET")
+	router.HandleFunc("/web/instances/{id}", h.DeleteInstance).Methods("DELETE")
+	router.HandleFunc("/web/instances/{id}/start", h.StartInstance).Methods("POST")
+	router.HandleFunc("/web/instances/{id}/stop", h.StopInstance).Methods("POST")
+	router.HandleFunc("/web/projects/{id}", h.ProjectDetail).Methods("GET")
+	return h, router
+}
+
+func TestInstanceDetail(t *testing.T) {
+	h, router := newServiceTestHandler(t)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "detail"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/web/instances/"+instance.ID, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "Instance vm-1")
+	assert.Contains(t, w.Body.String(), "detail", "the project is named")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/web/instances/"+instance.ID+"/stop", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "/start")
+
+	stopped, err := h.service.GetInstance(instance.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusStopped, stopped.Status)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/web/instances/"+instance.ID+"/start", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "/stop")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/web/projects/"+project.ID, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "/web/instances/"+instance.ID)
+
+	// Deleting from the detail page swaps the content pane back to the list
+	r := httptest.NewRequest("DELETE", "/web/instances/"+instance.ID, nil)
+	r.Header.Set("HX-Target", "content")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "<h2>Instances</h2>")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/web/instances/"+instance.ID, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRecentEvents(t *testing.T) {
+	h, _ := newServiceTestHandler(t)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "events"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusStopped})
+	require.NoError(t, err)
+
+	// Each resize records an event
+	for cpu := 2; cpu < recentEventLimit+4; cpu++ {
+		cpu := cpu
+		_, err := h.service.ResizeInstance(instance.ID, domain.ResizeInstanceRequest{CPU: &cpu})
+		require.NoError(t, err)
+	}
+
+	all, err := h.service.ListEvents(domain.EventListOptions{ResourceID: instance.ID})
+	require.NoError(t, err)
+	require.Greater(t, len(all), recentEventLimit)
+
+	recent, err := h.recentEvents(domain.EventListOptions{ResourceID: instance.ID})
+	require.NoError(t, err)
+	require.Len(t, recent, recentEventLimit)
+	assert.Equal(t, all[len(all)-1].ID, recent[0].ID, "newest first")
+	assert.Equal(t, all[len(all)-recentEventLimit].ID, recent[recentEventLimit-1].ID)
+}
@@ -0,0 +1,149 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// eventResourceTypes are the resource types offered by the events filter
+var eventResourceTypes = []string{"project", "instance", "autoscaling_group"}
+
+// eventTimeLayouts are the accepted formats of the events time range, tried in
+// order. Times without a zone are UTC.
+var eventTimeLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05", time.RFC3339}
+
+// eventFilter is the filter of the events page, read from the query string
+type eventFilter struct {
+	ResourceType string
+	Actor        string
+	Since        string
+	Until        string
+	Live         bool
+	Page         int
+}
+
+// eventsPage is one page of the events table, newest first
+type eventsPage struct {
+	Filter        eventFilter
+	ResourceTypes []string
+	Events        []*domain.Event
+	Pager         pager
+	URL           string
+	Error         string
+}
+
+// ListEvents shows the event log with filters. An htmx request targeting the
+// results refreshes only the table, which is how the live view polls.
+func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := eventFilter{
+		ResourceType: query.Get("resource_type"),
+		Actor:        strings.TrimSpace(query.Get("actor")),
+		Since:        query.Get("since"),
+		Until:        query.Get("until"),
+		Live:         query.Get("live") != "",
+		Page:         1,
+	}
+	if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 1 {
+		filter.Page = page
+	}
+
+	data := eventsPage{Filter: filter, ResourceTypes: eventResourceTypes, URL: filter.url(filter.Page)}
+
+	opts, err := filter.options()
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		events, err := h.service.ListEvents(opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data.Events, data.Pager = filter.page(events)
+	}
+
+	if r.Header.Get("HX-Target") == "event-results" {
+		h.render(w, http.StatusOK, "event_results", data)
+		return
+	}
+	h.render(w, http.StatusOK, "events", data)
+}
+
+// options converts the filter to list options
+func (f eventFilter) options() (domain.EventListOptions, error) {
+	opts := domain.EventListOptions{ResourceType: f.ResourceType, Actor: f.Actor}
+
+	var v domain.FieldViolations
+	opts.Since = parseEventTime(&v, f.Since, "since")
+	opts.Until = parseEventTime(&v, f.Until, "until")
+	return opts, v.Err()
+}
+
+// page orders events newest first and cuts out the filter's page
+func (f eventFilter) page(events []*domain.Event) ([]*domain.Event, pager) {
+	newest := make([]*domain.Event, len(events))
+	for i, event := range events {
+		newest[len(events)-1-i] = event
+	}
+
+	p := pager{Page: f.Page}
+	start := (f.Page - 1) * pageSize
+	if start > len(newest) {
+		start = len(newest)
+	}
+	end := start + pageSize
+	if end > len(newest) {
+		end = len(newest)
+	}
+	if f.Page > 1 {
+		p.PrevURL = f.url(f.Page - 1)
+	}
+	if end < len(newest) {
+		p.NextURL = f.url(f.Page + 1)
+	}
+	return newest[start:end], p
+}
+
+// url returns the events page URL for the filter at another page
+func (f eventFilter) url(page int) string {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"resource_type": f.ResourceType,
+		"actor":         f.Actor,
+		"since":         f.Since,
+		"until":         f.Until,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if f.Live {
+		values.Set("live", "1")
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	if len(values) == 0 {
+		return "/web/events"
+	}
+	return "/web/events?" + values.Encode()
+}
+
+// parseEventTime parses an optional time of the events time range
+func parseEventTime(v *domain.FieldViolations, value, field string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range eventTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	v.Add(field, "must be a time such as 2006-01-02T15:04")
+	return time.Time{}
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventFilter_Options(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      eventFilter
+		expected    domain.EventListOptions
+		expectError bool
+	}{
+		{name: "empty", expected: domain.EventListOptions{}},
+		{
+			name:   "datetime-local range",
+			filter: eventFilter{ResourceType: "instance", Actor: "admin", Since: "2026-01-02T03:04", Until: "2026-01-02T05:00:30"},
+			expected: domain.EventListOptions{
+				ResourceType: "instance",
+				Actor:        "admin",
+				Since:        time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC),
+				Until:        time.Date(2026, 1, 2, 5, 0, 30, 0, time.UTC),
+			},
+		},
+		{name: "invalid time", filter: eventFilter{Since: "yesterday"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := tt.filter.options()
+			if tt.expectError {
+				require.Error(t, err)
+				assert.True(t, domain.IsInvalidInput(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, opts)
+		})
+	}
+}
+
+func TestEventFilter_Page(t *testing.T) {
+	var events []*domain.Event
+	for i := 0; i < pageSize+5; i++ {
+		events = append(events, &domain.Event{ID: fmt.Sprintf("e%03d", i)})
+	}
+
+	page, p := eventFilter{Actor: "admin", Page: 1}.page(events)
+	require.Len(t, page, pageSize)
+	assert.Equal(t, fmt.Sprintf("e%03d", pageSize+4), page[0].ID, "newest first")
+	assert.Empty(t, p.PrevURL)
+	assert.Equal(t, "/web/events?actor=admin&page=2", p.NextURL)
+
+	page, p = eventFilter{Actor: "admin", Page: 2}.page(events)
+	require.Len(t, page, 5)
+	assert.Equal(t, "e000", page[4].ID)
+	assert.Equal(t, "/web/events?actor=admin", p.PrevURL)
+	assert.Empty(t, p.NextURL)
+
+	page, _ = eventFilter{Page: 9}.page(events)
+	assert.Empty(t, page)
+}
+
+func TestListEvents(t *testing.T) {
+	h, _ := newServiceTestHandler(t)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "events"})
+	require.NoError(t, err)
+	instance, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: "vm-1", CPU: 1, MemoryMB: 512, Image: "ubuntu", Status: domain.StatusStopped})
+	require.NoError(t, err)
+	cpu := 2
+	_, err = h.service.WithActor("token:ci").ResizeInstance(instance.ID, domain.ResizeInstanceRequest{CPU: &cpu})
+	require.NoError(t, err)
+
+	get := func(url, target string) string {
+		r := httptest.NewRequest("GET", url, nil)
+		if target != "" {
+			r.Header.Set("HX-Target", target)
+		}
+		w := httptest.NewRecorder()
+		h.ListEvents(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	body := get("/web/events?actor=token:ci", "")
+	assert.Contains(t, body, "<h2>Events</h2>")
+	assert.Contains(t, body, domain.EventInstanceResized)
+
+	body = get("/web/events?actor=someone-else", "event-results")
+	assert.NotContains(t, body, "<h2>Events</h2>", "polling renders only the results")
+	assert.Contains(t, body, "No events match the filters.")
+
+	body = get("/web/events?until=2000-01-01T00:00", "event-results")
+	assert.Contains(t, body, "No events match the filters.")
+
+	body = get("/web/events?since=soon", "")
+	assert.Contains(t, body, "since must be a time")
+}
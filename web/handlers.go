@@ -1,7 +1,6 @@
 package web
 
 import (
-	"html/template"
 	"net/http"
 	"strconv"
 
@@ -11,127 +10,78 @@ import (
 )
 
 type Handler struct {
-	service *service.Service
+	service       *service.Service
+	token         string
+	secureCookies bool
+	sessions      *sessionStore
 }
 
-func NewHandler(svc *service.Service) *Handler {
+// Config holds web console configuration
+type Config struct {
+	// Token is the credential that logs in to the console; empty means
+	// anyone may use it
+	Token string
+
+	// InsecureCookies drops the Secure attribute from session cookies so the
+	// console works over plain HTTP on hosts other than localhost
+	InsecureCookies bool
+}
+
+func NewHandler(svc *service.Service, config Config) *Handler {
 	return &Handler{
-		service: svc,
+		service:       svc,
+		token:         config.Token,
+		secureCookies: !config.InsecureCookies,
+		sessions:      newSessionStore(),
 	}
 }
 
 // Dashboard shows the main dashboard
 func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
-	tmpl := `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>DirtCloud Console</title>
-    <script src="https://unpkg.com/htmx.org@1.9.6"></script>
-    <style>
-        body { font-family: Arial, sans-serif; margin: 20px; }
-        .nav { margin-bottom: 20px; }
-        .nav a { margin-right: 20px; text-decoration: none; color: #007bff; }
-        .nav a:hover { text-decoration: underline; }
-        .content { margin-top: 20px; }
-        table { border-collapse: collapse; width: 100%; }
-        th, td { border: 1px solid #ddd; padding: 8px; text-align: left; }
-        th { background-color: #f2f2f2; }
-        .btn { padding: 8px 16px; margin: 4px; background: #007bff; color: white; border: none; cursor: pointer; }
-        .btn:hover { background: #0056b3; }
-        .btn-danger { background: #dc3545; }
-        .btn-danger:hover { background: #c82333; }
-        .form-group { margin: 10px 0; }
-        .form-group label { display: block; margin-bottom: 5px; }
-        .form-group input, .form-group select { width: 100%; padding: 8px; border: 1px solid #ddd; }
-        .modal { display: none; position: fixed; z-index: 1; left: 0; top: 0; width: 100%; height: 100%; background-color: rgba(0,0,0,0.4); }
-        .modal-content { background-color: #fefefe; margin: 15% auto; padding: 20px; border: 1px solid #888; width: 50%; }
-        .close { color: #aaa; float: right; font-size: 28px; font-weight: bold; cursor: pointer; }
-        .close:hover { color: black; }
-    </style>
-</head>
-<body>
-    <h1>DirtCloud Console</h1>
-    <div class="nav">
-        <a href="#" hx-get="/web/projects" hx-target="#content">Projects</a>
-        <a href="#" hx-get="/web/instances" hx-target="#content">Instances</a>
-        <a href="#" hx-get="/web/metadata" hx-target="#content">Metadata</a>
-    </div>
-    <div id="content" class="content">
-        <p>Welcome to DirtCloud Console. Select a resource type from the navigation above.</p>
-    </div>
-</body>
-</html>
-`
-	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(tmpl))
+	data := struct{ CSRFToken string }{}
+	if sess := sessionFromContext(r.Context()); sess != nil {
+		data.CSRFToken = sess.csrfToken
+	}
+
+	h.render(w, http.StatusOK, "dashboard", data)
+}
+
+// projectColumns are the sortable columns of the projects table
+var projectColumns = []listColumn{
+	{Label: "ID", Field: "id"},
+	{Label: "Name", Field: "name"},
+	{Label: "Created At", Field: "created_at"},
 }
 
 // Projects handlers
 func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
-	projects, err := h.service.ListProjects(domain.ProjectListOptions{})
+	query := parseListQuery(r, projectColumns)
+	projects, err := h.service.ListProjects(domain.ProjectListOptions{
+		Search: query.Search,
+		Sort:   query.sortFields(),
+		Limit:  query.limit(),
+		Offset: query.offset(),
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	tmpl := `
-<div>
-    <h2>Projects</h2>
-    <button class="btn" hx-get="/web/projects/new" hx-target="#modal-content" onclick="document.getElementById('modal').style.display='block'">New Project</button>
-    <table>
-        <thead>
-            <tr>
-                <th>ID</th>
-                <th>Name</th>
-                <th>Created At</th>
-                <th>Actions</th>
-            </tr>
-        </thead>
-        <tbody>
-            {{range .}}
-            <tr>
-                <td>{{.ID}}</td>
-                <td>{{.Name}}</td>
-                <td>{{.CreatedAt.Format "2006-01-02 15:04:05"}}</td>
-                <td>
-                    <button class="btn" hx-get="/web/projects/{{.ID}}/edit" hx-target="#modal-content" onclick="document.getElementById('modal').style.display='block'">Edit</button>
-                    <button class="btn btn-danger" hx-delete="/web/projects/{{.ID}}" hx-target="closest tr" hx-confirm="Are you sure?">Delete</button>
-                </td>
-            </tr>
-            {{end}}
-        </tbody>
-    </table>
-</div>
-
-<!-- Modal -->
-<div id="modal" class="modal">
-    <div class="modal-content">
-        <span class="close" onclick="document.getElementById('modal').style.display='none'">&times;</span>
-        <div id="modal-content"></div>
-    </div>
-</div>
-`
-
-	t := template.Must(template.New("projects").Parse(tmpl))
-	if err := t.Execute(w, projects); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	data := projectsPage{
+		Query:   query,
+		Headers: query.headers("/web/projects", projectColumns),
+		Pager:   query.pager("/web/projects", len(projects)),
 	}
+	if len(projects) > pageSize {
+		projects = projects[:pageSize]
+	}
+	data.Projects = projects
+
+	h.render(w, http.StatusOK, "projects", data)
 }
 
 func (h *Handler) NewProjectForm(w http.ResponseWriter, r *http.Request) {
-	tmpl := `
-<h3>New Project</h3>
-<form hx-post="/web/projects" hx-target="#content" hx-on-success="document.getElementById('modal').style.display='none'">
-    <div class="form-group">
-        <label for="name">Name:</label>
-        <input type="text" id="name" name="name" required>
-    </div>
-    <button type="submit" class="btn">Create</button>
-    <button type="button" class="btn" onclick="document.getElementById('modal').style.display='none'">Cancel</button>
-</form>
-`
-	w.Write([]byte(tmpl))
+	h.render(w, http.StatusOK, "project_new", nil)
 }
 
 func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
@@ -164,21 +114,7 @@ func (h *Handler) EditProjectForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tmpl := `
-<h3>Edit Project</h3>
-<form hx-put="/web/projects/{{.ID}}" hx-target="#content" hx-on-success="document.getElementById('modal').style.display='none'">
-    <div class="form-group">
-        <label for="name">Name:</label>
-        <input type="text" id="name" name="name" value="{{.Name}}" required>
-    </div>
-    <button type="submit" class="btn">Update</button>
-    <button type="button" class="btn" onclick="document.getElementById('modal').style.display='none'">Cancel</button>
-</form>
-`
-	t := template.Must(template.New("edit-project").Parse(tmpl))
-	if err := t.Execute(w, project); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	h.render(w, http.StatusOK, "project_edit", project)
 }
 
 func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
@@ -190,8 +126,9 @@ func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	name := r.FormValue("name")
 	req := domain.UpdateProjectRequest{
-		Name: r.FormValue("name"),
+		Name: &name,
 	}
 
 	_, err := h.service.UpdateProject(id, req)
@@ -208,87 +145,56 @@ func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	if err := h.service.DeleteProject(id); err != nil {
+	if err := h.service.DeleteProject(id, domain.DeleteProjectOptions{}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Deleting from a detail page returns to the list
+	if targetsContent(r) {
+		h.ListProjects(w, r)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
+// instanceColumns are the sortable columns of the instances table
+var instanceColumns = []listColumn{
+	{Label: "ID", Field: "id"},
+	{Label: "Project ID", Field: "project_id"},
+	{Label: "Name", Field: "name"},
+	{Label: "CPU", Field: "cpu"},
+	{Label: "Memory (MB)", Field: "memory_mb"},
+	{Label: "Image", Field: "image"},
+	{Label: "Status", Field: "status"},
+}
+
 // Instances handlers
 func (h *Handler) ListInstances(w http.ResponseWriter, r *http.Request) {
-	instances, err := h.service.ListInstances(domain.InstanceListOptions{})
+	query := parseListQuery(r, instanceColumns)
+	instances, err := h.service.ListInstances(domain.InstanceListOptions{
+		Search: query.Search,
+		Sort:   query.sortFields(),
+		Limit:  query.limit(),
+		Offset: query.offset(),
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Get projects for the dropdown
-	projects, err := h.service.ListProjects(domain.ProjectListOptions{})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	data := instancesPage{
+		Query:   query,
+		Headers: query.headers("/web/instances", instanceColumns),
+		Pager:   query.pager("/web/instances", len(instances)),
 	}
-
-	tmpl := `
-<div>
-    <h2>Instances</h2>
-    <button class="btn" hx-get="/web/instances/new" hx-target="#modal-content" onclick="document.getElementById('modal').style.display='block'">New Instance</button>
-    <table>
-        <thead>
-            <tr>
-                <th>ID</th>
-                <th>Project ID</th>
-                <th>Name</th>
-                <th>CPU</th>
-                <th>Memory (MB)</th>
-                <th>Image</th>
-                <th>Status</th>
-                <th>Actions</th>
-            </tr>
-        </thead>
-        <tbody>
-            {{range .Instances}}
-            <tr>
-                <td>{{.ID}}</td>
-                <td>{{.ProjectID}}</td>
-                <td>{{.Name}}</td>
-                <td>{{.CPU}}</td>
-                <td>{{.MemoryMB}}</td>
-                <td>{{.Image}}</td>
-                <td>{{.Status}}</td>
-                <td>
-                    <button class="btn" hx-get="/web/instances/{{.ID}}/edit" hx-target="#modal-content" onclick="document.getElementById('modal').style.display='block'">Edit</button>
-                    <button class="btn btn-danger" hx-delete="/web/instances/{{.ID}}" hx-target="closest tr" hx-confirm="Are you sure?">Delete</button>
-                </td>
-            </tr>
-            {{end}}
-        </tbody>
-    </table>
-</div>
-
-<!-- Modal -->
-<div id="modal" class="modal">
-    <div class="modal-content">
-        <span class="close" onclick="document.getElementById('modal').style.display='none'">&times;</span>
-        <div id="modal-content"></div>
-    </div>
-</div>
-`
-
-	data := struct {
-		Instances []*domain.Instance
-		Projects  []*domain.Project
-	}{
-		Instances: instances,
-		Projects:  projects,
+	if len(instances) > pageSize {
+		instances = instances[:pageSize]
 	}
+	data.Instances = instances
 
-	t := template.Must(template.New("instances").Parse(tmpl))
-	if err := t.Execute(w, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	h.render(w, http.StatusOK, "instances", data)
 }
 
 func (h *Handler) NewInstanceForm(w http.ResponseWriter, r *http.Request) {
@@ -298,49 +204,7 @@ func (h *Handler) NewInstanceForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tmpl := `
-<h3>New Instance</h3>
-<form hx-post="/web/instances" hx-target="#content" hx-on-success="document.getElementById('modal').style.display='none'">
-    <div class="form-group">
-        <label for="project_id">Project:</label>
-        <select id="project_id" name="project_id" required>
-            <option value="">Select a project</option>
-            {{range .}}
-            <option value="{{.ID}}">{{.Name}}</option>
-            {{end}}
-        </select>
-    </div>
-    <div class="form-group">
-        <label for="name">Name:</label>
-        <input type="text" id="name" name="name" required>
-    </div>
-    <div class="form-group">
-        <label for="cpu">CPU:</label>
-        <input type="number" id="cpu" name="cpu" value="1" required>
-    </div>
-    <div class="form-group">
-        <label for="memory_mb">Memory (MB):</label>
-        <input type="number" id="memory_mb" name="memory_mb" value="512" required>
-    </div>
-    <div class="form-group">
-        <label for="image">Image:</label>
-        <input type="text" id="image" name="image" value="ubuntu:20.04" required>
-    </div>
-    <div class="form-group">
-        <label for="status">Status:</label>
-        <select id="status" name="status">
-            <option value="running">Running</option>
-            <option value="stopped">Stopped</option>
-        </select>
-    </div>
-    <button type="submit" class="btn">Create</button>
-    <button type="button" class="btn" onclick="document.getElementById('modal').style.display='none'">Cancel</button>
-</form>
-`
-	t := template.Must(template.New("new-instance").Parse(tmpl))
-	if err := t.Execute(w, projects); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	h.render(w, http.StatusOK, "instance_new", projects)
 }
 
 func (h *Handler) CreateInstance(w http.ResponseWriter, r *http.Request) {
@@ -387,45 +251,6 @@ func (h *Handler) EditInstanceForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tmpl := `
-<h3>Edit Instance</h3>
-<form hx-put="/web/instances/{{.Instance.ID}}" hx-target="#content" hx-on-success="document.getElementById('modal').style.display='none'">
-    <div class="form-group">
-        <label for="project_id">Project:</label>
-        <select id="project_id" name="project_id" required>
-            {{range .Projects}}
-            <option value="{{.ID}}" {{if eq .ID $.Instance.ProjectID}}selected{{end}}>{{.Name}}</option>
-            {{end}}
-        </select>
-    </div>
-    <div class="form-group">
-        <label for="name">Name:</label>
-        <input type="text" id="name" name="name" value="{{.Instance.Name}}" required>
-    </div>
-    <div class="form-group">
-        <label for="cpu">CPU:</label>
-        <input type="number" id="cpu" name="cpu" value="{{.Instance.CPU}}" required>
-    </div>
-    <div class="form-group">
-        <label for="memory_mb">Memory (MB):</label>
-        <input type="number" id="memory_mb" name="memory_mb" value="{{.Instance.MemoryMB}}" required>
-    </div>
-    <div class="form-group">
-        <label for="image">Image:</label>
-        <input type="text" id="image" name="image" value="{{.Instance.Image}}" required>
-    </div>
-    <div class="form-group">
-        <label for="status">Status:</label>
-        <select id="status" name="status">
-            <option value="running" {{if eq .Instance.Status "running"}}selected{{end}}>Running</option>
-            <option value="stopped" {{if eq .Instance.Status "stopped"}}selected{{end}}>Stopped</option>
-        </select>
-    </div>
-    <button type="submit" class="btn">Update</button>
-    <button type="button" class="btn" onclick="document.getElementById('modal').style.display='none'">Cancel</button>
-</form>
-`
-
 	data := struct {
 		Instance domain.Instance
 		Projects []*domain.Project
@@ -434,10 +259,7 @@ func (h *Handler) EditInstanceForm(w http.ResponseWriter, r *http.Request) {
 		Projects: projects,
 	}
 
-	t := template.Must(template.New("edit-instance").Parse(tmpl))
-	if err := t.Execute(w, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	h.render(w, http.StatusOK, "instance_edit", data)
 }
 
 func (h *Handler) UpdateInstance(w http.ResponseWriter, r *http.Request) {
@@ -484,101 +306,37 @@ func (h *Handler) DeleteInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Deleting from a detail page returns to the list
+	if targetsContent(r) {
+		h.ListInstances(w, r)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
 // Metadata handlers
 func (h *Handler) ListMetadata(w http.ResponseWriter, r *http.Request) {
 	prefix := r.URL.Query().Get("prefix")
-	paths, err := h.service.ListMetadata(domain.MetadataListOptions{Prefix: prefix})
+	metadata, err := h.service.ListMetadata(domain.MetadataListOptions{Prefix: prefix})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Get full metadata objects for each path
-	var metadata []domain.Metadata
-	for _, path := range paths {
-		meta, err := h.service.GetMetadata(path)
-		if err != nil {
-			continue // Skip if metadata was deleted between list and get
-		}
-		metadata = append(metadata, *meta)
-	}
-
-	tmpl := `
-<div>
-    <h2>Metadata</h2>
-    <div class="form-group">
-        <label for="prefix-filter">Filter by prefix:</label>
-        <input type="text" id="prefix-filter" name="prefix" hx-get="/web/metadata" hx-target="#content" hx-trigger="input changed delay:500ms" value="{{.Prefix}}">
-    </div>
-    <button class="btn" hx-get="/web/metadata/new" hx-target="#modal-content" onclick="document.getElementById('modal').style.display='block'">New Metadata</button>
-    <table>
-        <thead>
-            <tr>
-                <th>Path</th>
-                <th>Value</th>
-                <th>Updated At</th>
-                <th>Actions</th>
-            </tr>
-        </thead>
-        <tbody>
-            {{range .Metadata}}
-            <tr>
-                <td>{{.Path}}</td>
-                <td>{{.Value}}</td>
-                <td>{{.UpdatedAt.Format "2006-01-02 15:04:05"}}</td>
-                <td>
-                    <button class="btn" hx-get="/web/metadata/edit?path={{.Path}}" hx-target="#modal-content" onclick="document.getElementById('modal').style.display='block'">Edit</button>
-                    <button class="btn btn-danger" hx-delete="/web/metadata/delete?path={{.Path}}" hx-target="closest tr" hx-confirm="Are you sure?">Delete</button>
-                </td>
-            </tr>
-            {{end}}
-        </tbody>
-    </table>
-</div>
-
-<!-- Modal -->
-<div id="modal" class="modal">
-    <div class="modal-content">
-        <span class="close" onclick="document.getElementById('modal').style.display='none'">&times;</span>
-        <div id="modal-content"></div>
-    </div>
-</div>
-`
-
 	data := struct {
-		Metadata []domain.Metadata
+		Metadata []*domain.Metadata
 		Prefix   string
 	}{
 		Metadata: metadata,
 		Prefix:   prefix,
 	}
 
-	t := template.Must(template.New("metadata").Parse(tmpl))
-	if err := t.Execute(w, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	h.render(w, http.StatusOK, "metadata", data)
 }
 
 func (h *Handler) NewMetadataForm(w http.ResponseWriter, r *http.Request) {
-	tmpl := `
-<h3>New Metadata</h3>
-<form hx-post="/web/metadata" hx-target="#content" hx-on-success="document.getElementById('modal').style.display='none'">
-    <div class="form-group">
-        <label for="path">Path:</label>
-        <input type="text" id="path" name="path" required>
-    </div>
-    <div class="form-group">
-        <label for="value">Value:</label>
-        <textarea id="value" name="value" rows="4" style="width: 100%; padding: 8px; border: 1px solid #ddd;" required></textarea>
-    </div>
-    <button type="submit" class="btn">Create</button>
-    <button type="button" class="btn" onclick="document.getElementById('modal').style.display='none'">Cancel</button>
-</form>
-`
-	w.Write([]byte(tmpl))
+	h.render(w, http.StatusOK, "metadata_new", nil)
 }
 
 func (h *Handler) CreateMetadata(w http.ResponseWriter, r *http.Request) {
@@ -587,10 +345,12 @@ func (h *Handler) CreateMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := r.FormValue("path")
-	value := r.FormValue("value")
+	req := domain.CreateMetadataRequest{
+		Path:  r.FormValue("path"),
+		Value: r.FormValue("value"),
+	}
 
-	if _, err := h.service.SetMetadata(path, value); err != nil {
+	if _, err := h.service.CreateMetadata(req); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -600,37 +360,19 @@ func (h *Handler) CreateMetadata(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) EditMetadataForm(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Query().Get("path")
-	if path == "" {
-		http.Error(w, "path parameter required", http.StatusBadRequest)
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id parameter required", http.StatusBadRequest)
 		return
 	}
 
-	metadata, err := h.service.GetMetadata(path)
+	metadata, err := h.service.GetMetadata(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	tmpl := `
-<h3>Edit Metadata</h3>
-<form hx-put="/web/metadata/update" hx-target="#content" hx-on-success="document.getElementById('modal').style.display='none'">
-    <div class="form-group">
-        <label for="path">Path:</label>
-        <input type="text" id="path" name="path" value="{{.Path}}" readonly>
-    </div>
-    <div class="form-group">
-        <label for="value">Value:</label>
-        <textarea id="value" name="value" rows="4" style="width: 100%; padding: 8px; border: 1px solid #ddd;" required>{{.Value}}</textarea>
-    </div>
-    <button type="submit" class="btn">Update</button>
-    <button type="button" class="btn" onclick="document.getElementById('modal').style.display='none'">Cancel</button>
-</form>
-`
-	t := template.Must(template.New("edit-metadata").Parse(tmpl))
-	if err := t.Execute(w, metadata); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	h.render(w, http.StatusOK, "metadata_edit", metadata)
 }
 
 func (h *Handler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
@@ -639,10 +381,10 @@ func (h *Handler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := r.FormValue("path")
+	id := r.FormValue("id")
 	value := r.FormValue("value")
 
-	if _, err := h.service.SetMetadata(path, value); err != nil {
+	if _, err := h.service.UpdateMetadata(id, domain.UpdateMetadataRequest{Value: &value}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -652,13 +394,13 @@ func (h *Handler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) DeleteMetadata(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Query().Get("path")
-	if path == "" {
-		http.Error(w, "path parameter required", http.StatusBadRequest)
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id parameter required", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.service.DeleteMetadata(path); err != nil {
+	if err := h.service.DeleteMetadata(id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
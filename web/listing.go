@@ -0,0 +1,140 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+)
+
+// pageSize is how many rows a console table shows per page
+const pageSize = 50
+
+// listColumn is a sortable table column
+type listColumn struct {
+	Label string
+	Field string
+}
+
+// listQuery is the search, sort and page of a console table, read from the
+// query string
+type listQuery struct {
+	Search string
+	Sort   string
+	Page   int
+}
+
+// sortHeader is a column header linking to the table sorted by that column
+type sortHeader struct {
+	Label     string
+	URL       string
+	Indicator string
+}
+
+// pager links to the neighbouring pages of a table
+type pager struct {
+	Page    int
+	PrevURL string
+	NextURL string
+}
+
+// projectsPage is one page of the projects table
+type projectsPage struct {
+	Projects []*domain.Project
+	Query    listQuery
+	Headers  []sortHeader
+	Pager    pager
+}
+
+// instancesPage is one page of the instances table
+type instancesPage struct {
+	Instances []*domain.Instance
+	Query     listQuery
+	Headers   []sortHeader
+	Pager     pager
+}
+
+// parseListQuery reads a table query, ignoring sorts on unknown columns
+func parseListQuery(r *http.Request, columns []listColumn) listQuery {
+	query := r.URL.Query()
+	q := listQuery{Search: strings.TrimSpace(query.Get("q")), Page: 1}
+
+	sort := query.Get("sort")
+	for _, c := range columns {
+		if strings.TrimPrefix(sort, "-") == c.Field {
+			q.Sort = sort
+		}
+	}
+
+	if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 1 {
+		q.Page = page
+	}
+	return q
+}
+
+// sortFields returns the sort of the query for the list options
+func (q listQuery) sortFields() []domain.SortField {
+	if q.Sort == "" {
+		return nil
+	}
+	return []domain.SortField{{Field: strings.TrimPrefix(q.Sort, "-"), Desc: strings.HasPrefix(q.Sort, "-")}}
+}
+
+// limit asks for one row past the page to learn whether there is a next page
+func (q listQuery) limit() int {
+	return pageSize + 1
+}
+
+func (q listQuery) offset() int {
+	return (q.Page - 1) * pageSize
+}
+
+// url returns the table URL for the query with another sort and page
+func (q listQuery) url(base, sort string, page int) string {
+	values := url.Values{}
+	if q.Search != "" {
+		values.Set("q", q.Search)
+	}
+	if sort != "" {
+		values.Set("sort", sort)
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	if len(values) == 0 {
+		return base
+	}
+	return base + "?" + values.Encode()
+}
+
+// headers links each column to the table sorted by it, toggling the direction
+// of the current sort column. Sorting returns to the first page.
+func (q listQuery) headers(base string, columns []listColumn) []sortHeader {
+	headers := make([]sortHeader, len(columns))
+	for i, c := range columns {
+		h := sortHeader{Label: c.Label, URL: q.url(base, c.Field, 1)}
+		switch q.Sort {
+		case c.Field:
+			h.URL = q.url(base, "-"+c.Field, 1)
+			h.Indicator = "▲"
+		case "-" + c.Field:
+			h.Indicator = "▼"
+		}
+		headers[i] = h
+	}
+	return headers
+}
+
+// pager builds the page links given how many rows were fetched with limit
+func (q listQuery) pager(base string, fetched int) pager {
+	p := pager{Page: q.Page}
+	if q.Page > 1 {
+		p.PrevURL = q.url(base, q.Sort, q.Page-1)
+	}
+	if fetched > pageSize {
+		p.NextURL = q.url(base, q.Sort, q.Page+1)
+	}
+	return p
+}
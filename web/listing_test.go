@@ -0,0 +1,102 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected listQuery
+	}{
+		{name: "defaults", url: "/web/instances", expected: listQuery{Page: 1}},
+		{name: "all parameters", url: "/web/instances?q=+web+&sort=-cpu&page=3", expected: listQuery{Search: "web", Sort: "-cpu", Page: 3}},
+		{name: "unknown sort is ignored", url: "/web/instances?sort=labels", expected: listQuery{Page: 1}},
+		{name: "invalid page", url: "/web/instances?page=-2", expected: listQuery{Page: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := parseListQuery(httptest.NewRequest("GET", tt.url, nil), instanceColumns)
+			assert.Equal(t, tt.expected, q)
+		})
+	}
+}
+
+func TestListQuery_Options(t *testing.T) {
+	q := listQuery{Sort: "-cpu", Page: 3}
+	assert.Equal(t, []domain.SortField{{Field: "cpu", Desc: true}}, q.sortFields())
+	assert.Equal(t, pageSize+1, q.limit())
+	assert.Equal(t, 2*pageSize, q.offset())
+
+	assert.Nil(t, listQuery{Page: 1}.sortFields())
+}
+
+func TestListQuery_Headers(t *testing.T) {
+	columns := []listColumn{{Label: "Name", Field: "name"}, {Label: "CPU", Field: "cpu"}}
+
+	headers := listQuery{Search: "web", Sort: "name", Page: 4}.headers("/web/instances", columns)
+	assert.Equal(t, []sortHeader{
+		{Label: "Name", URL: "/web/instances?q=web&sort=-name", Indicator: "▲"},
+		{Label: "CPU", URL: "/web/instances?q=web&sort=cpu"},
+	}, headers)
+
+	headers = listQuery{Sort: "-name", Page: 1}.headers("/web/instances", columns)
+	assert.Equal(t, "/web/instances?sort=name", headers[0].URL)
+	assert.Equal(t, "▼", headers[0].Indicator)
+}
+
+func TestListQuery_Pager(t *testing.T) {
+	q := listQuery{Sort: "name", Page: 2}
+
+	p := q.pager("/web/projects", pageSize+1)
+	assert.Equal(t, pager{Page: 2, PrevURL: "/web/projects?sort=name", NextURL: "/web/projects?page=3&sort=name"}, p)
+
+	p = listQuery{Page: 1}.pager("/web/projects", pageSize)
+	assert.Equal(t, pager{Page: 1}, p, "a full page without an extra row is the last page")
+}
+
+func TestListInstances_Pages(t *testing.T) {
+	h, router := newServiceTestHandler(t)
+
+	project, err := h.service.CreateProject(domain.CreateProjectRequest{Name: "paged"})
+	require.NoError(t, err)
+	for i := 0; i < pageSize+1; i++ {
+		_, err := h.service.CreateInstance(domain.CreateInstanceRequest{ProjectID: project.ID, Name: fmt.Sprintf("vm-%03d", i), CPU: 1, MemoryMB: 512, Image: "ubuntu"})
+		require.NoError(t, err)
+	}
+
+	get := func(url string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	first := get("/web/instances")
+	assert.Contains(t, first, ">vm-000<")
+	assert.Contains(t, first, fmt.Sprintf(">vm-%03d<", pageSize-1))
+	assert.NotContains(t, first, fmt.Sprintf(">vm-%03d<", pageSize))
+	assert.Contains(t, first, "/web/instances?page=2")
+
+	second := get("/web/instances?page=2")
+	assert.Contains(t, second, fmt.Sprintf(">vm-%03d<", pageSize))
+	assert.NotContains(t, second, "page=3")
+
+	sorted := get("/web/instances?sort=-name")
+	assert.Contains(t, sorted, fmt.Sprintf(">vm-%03d<", pageSize))
+	assert.NotContains(t, sorted, ">vm-000<")
+
+	searched := get("/web/instances?q=vm-00")
+	assert.Contains(t, searched, ">vm-009<")
+	assert.NotContains(t, searched, ">vm-010<")
+	assert.NotContains(t, searched, "page=2")
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// sessionTTL is how long a console session lasts after login
+const sessionTTL = 12 * time.Hour
+
+// session is a logged in console user
+type session struct {
+	id        string
+	csrfToken string
+	expiresAt time.Time
+}
+
+// sessionStore keeps console sessions in memory; they do not survive restarts
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	now      func() time.Time
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]*session), now: time.Now}
+}
+
+// create starts a new session
+func (s *sessionStore) create() (*session, error) {
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &session{id: id, csrfToken: csrfToken, expiresAt: s.now().Add(sessionTTL)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = sess
+	s.pruneLocked()
+	return sess, nil
+}
+
+// get returns the live session with the given ID
+func (s *sessionStore) get(id string) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	if !s.now().Before(sess.expiresAt) {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	return sess, true
+}
+
+// delete ends a session
+func (s *sessionStore) delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// pruneLocked drops expired sessions; the caller holds s.mu
+func (s *sessionStore) pruneLocked() {
+	now := s.now()
+	for id, sess := range s.sessions {
+		if !now.Before(sess.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// randomToken returns 32 random bytes, hex encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type sessionContextKey struct{}
+
+// withSession stores the session of a request in its context
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sess)
+}
+
+// sessionFromContext returns the session stored by RequireSession
+func sessionFromContext(ctx context.Context) *session {
+	sess, _ := ctx.Value(sessionContextKey{}).(*session)
+	return sess
+}
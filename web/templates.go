@@ -0,0 +1,96 @@
+package web
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+//go:embed templates static
+var content embed.FS
+
+// templates holds every console template, parsed when the package loads so a
+// broken template stops the server at startup instead of failing a request
+var templates = mustParseTemplates(content)
+
+// templateFuncs are the helper functions available to every template
+var templateFuncs = template.FuncMap{
+	// dict builds a map from alternating keys and values, for passing several
+	// values to a partial
+	"dict": func(pairs ...interface{}) (map[string]interface{}, error) {
+		if len(pairs)%2 != 0 {
+			return nil, fmt.Errorf("dict needs key/value pairs")
+		}
+		m := make(map[string]interface{}, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			key, ok := pairs[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("dict keys must be strings")
+			}
+			m[key] = pairs[i+1]
+		}
+		return m, nil
+	},
+}
+
+// mustParseTemplates parses each template in templates/ together with the
+// base layout and the partials, keyed by file name without extension. A
+// template that defines "body" is a full page rendered through the layout;
+// any other template is an htmx fragment rendered on its own.
+func mustParseTemplates(fsys fs.FS) map[string]*template.Template {
+	base := template.Must(template.New("").Funcs(templateFuncs).ParseFS(fsys, "templates/layout.html", "templates/partials/*.html"))
+
+	pages, err := fs.Glob(fsys, "templates/*.html")
+	if err != nil {
+		panic(err)
+	}
+
+	parsed := make(map[string]*template.Template)
+	for _, page := range pages {
+		name := strings.TrimSuffix(path.Base(page), ".html")
+		if name == "layout" {
+			continue
+		}
+		parsed[name] = template.Must(template.Must(base.Clone()).ParseFS(fsys, page))
+	}
+	return parsed
+}
+
+// render executes a template into a buffer first, so a failing template
+// produces an error response instead of half a page
+func (h *Handler) render(w http.ResponseWriter, status int, name string, data interface{}) {
+	t, ok := templates[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("template %s not found", name), http.StatusInternalServerError)
+		return
+	}
+
+	root := name + ".html"
+	if t.Lookup("body") != nil {
+		root = "layout"
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, root, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// Static serves the console stylesheets and other assets under /web/static/
+func (h *Handler) Static() http.Handler {
+	static, err := fs.Sub(content, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/web/static/", http.FileServer(http.FS(static)))
+}
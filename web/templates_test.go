@@ -0,0 +1,112 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hypertf/dirtcloud-server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	now := time.Now()
+	project := &domain.Project{ID: "proj-1", Name: "web", CreatedAt: now}
+	instance := &domain.Instance{ID: "inst-1", ProjectID: "proj-1", Name: "vm-1", CPU: 2, MemoryMB: 1024, Image: "ubuntu", Status: "running"}
+	metadata := &domain.Metadata{ID: "meta-1", Path: "app/config", Value: "x", UpdatedAt: now}
+
+	tests := []struct {
+		name     string
+		data     interface{}
+		contains string
+	}{
+		{name: "dashboard", data: struct{ CSRFToken string }{"csrf-123"}, contains: "csrf-123"},
+		{name: "login", data: struct {
+			Message       string
+			TokenRequired bool
+		}{"Invalid token", true}, contains: "Invalid token"},
+		{name: "projects", data: projectsPage{
+			Projects: []*domain.Project{project},
+			Headers:  listQuery{Page: 1}.headers("/web/projects", projectColumns),
+			Pager:    pager{Page: 2, PrevURL: "/web/projects"},
+		}, contains: "Previous"},
+		{name: "project_new", contains: "New Project"},
+		{name: "project_edit", data: project, contains: `value="web"`},
+		{name: "instances", data: instancesPage{
+			Instances: []*domain.Instance{instance},
+			Query:     listQuery{Search: "vm", Sort: "-cpu", Page: 1},
+			Headers:   listQuery{Sort: "-cpu", Page: 1}.headers("/web/instances", instanceColumns),
+		}, contains: "vm-1"},
+		{name: "instance_new", data: []*domain.Project{project}, contains: "proj-1"},
+		{name: "instance_edit", data: struct {
+			Instance domain.Instance
+			Projects []*domain.Project
+		}{*instance, []*domain.Project{project}}, contains: "selected"},
+		{name: "instance_detail", data: instanceDetail{Instance: instance, Project: project, Activity: []*domain.ActivityEntry{
+			{Kind: domain.ActivityEvent, Time: now, Summary: "resized", Event: &domain.Event{Type: domain.EventInstanceResized, CreatedAt: now}},
+			{Kind: domain.ActivityRequest, Time: now, Summary: "PATCH /v1/instances/inst-1 returned 200"},
+		}}, contains: "/web/instances/inst-1/stop"},
+		{name: "project_detail", data: projectDetail{Project: project, Instances: []*domain.Instance{instance}}, contains: "No events recorded."},
+		{name: "events", data: eventsPage{
+			Filter:        eventFilter{ResourceType: "instance", Live: true, Page: 1},
+			ResourceTypes: eventResourceTypes,
+			URL:           "/web/events?live=1&resource_type=instance",
+		}, contains: `hx-trigger="every 5s"`},
+		{name: "event_results", data: eventsPage{
+			Events: []*domain.Event{{Type: domain.EventInstanceExpired, ResourceType: "instance", ResourceID: "inst-1", Actor: domain.ActorReaper, CreatedAt: now}},
+		}, contains: domain.ActorReaper},
+		{name: "metadata", data: struct {
+			Metadata []*domain.Metadata
+			Prefix   string
+		}{[]*domain.Metadata{metadata}, "app/"}, contains: "app/config"},
+		{name: "metadata_new", contains: "New Metadata"},
+		{name: "metadata_edit", data: metadata, contains: "meta-1"},
+	}
+
+	h := NewHandler(nil, Config{})
+	require.Len(t, templates, len(tests), "every template is covered")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.render(w, http.StatusOK, tt.name, tt.data)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.contains)
+		})
+	}
+}
+
+func TestTemplates_PagesUseLayout(t *testing.T) {
+	h := NewHandler(nil, Config{})
+
+	w := httptest.NewRecorder()
+	h.render(w, http.StatusOK, "dashboard", struct{ CSRFToken string }{})
+	assert.Contains(t, w.Body.String(), "<!DOCTYPE html>")
+	assert.Contains(t, w.Body.String(), "/web/static/console.css")
+
+	w = httptest.NewRecorder()
+	h.render(w, http.StatusOK, "project_new", nil)
+	assert.NotContains(t, w.Body.String(), "<!DOCTYPE html>", "fragments render without the layout")
+}
+
+func TestMustParseTemplates_FailsFast(t *testing.T) {
+	files := fstest.MapFS{
+		"templates/layout.html":         {Data: []byte(`{{define "layout"}}{{template "body" .}}{{end}}`)},
+		"templates/partials/modal.html": {Data: []byte(`{{define "modal"}}{{end}}`)},
+		"templates/broken.html":         {Data: []byte(`{{if}}`)},
+	}
+
+	assert.Panics(t, func() { mustParseTemplates(files) })
+}
+
+func TestStatic(t *testing.T) {
+	h := NewHandler(nil, Config{})
+
+	w := httptest.NewRecorder()
+	h.Static().ServeHTTP(w, httptest.NewRequest("GET", "/web/static/console.css", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/css")
+}